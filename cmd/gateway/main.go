@@ -36,10 +36,26 @@ func main() {
 		log.Fatalf("Failed to start gateway: %v", err)
 	}
 
-	// 等待退出信号
+	// 等待退出信号，收到升级信号时先启动新进程交接监听器，再排空退出
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	upgrade := make(chan os.Signal, 1)
+	notifyUpgrade(upgrade)
+
+wait:
+	for {
+		select {
+		case <-quit:
+			break wait
+		case <-upgrade:
+			log.Println("Received upgrade signal, handing off listener...")
+			if err := gw.Upgrade(); err != nil {
+				log.Printf("Upgrade failed, keep serving: %v", err)
+				continue
+			}
+			break wait
+		}
+	}
 
 	log.Println("Shutting down gateway...")
 
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"os"
+)
+
+// notifyUpgrade 当前平台不支持监听FD交接
+func notifyUpgrade(ch chan<- os.Signal) {}
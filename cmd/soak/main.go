@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/llm-aware-gateway/pkg/gateway/soak"
+)
+
+func main() {
+	// 命令行参数
+	captureFile := flag.String("file", "soak-capture.jsonl", "流量形态录制文件")
+	target := flag.String("target", "http://localhost:8080", "回放目标网关地址")
+	speed := flag.Float64("speed", 1.0, "回放倍速")
+	flag.Parse()
+
+	replayer, err := soak.NewReplayer(*captureFile, *target, *speed)
+	if err != nil {
+		log.Fatalf("Failed to create replayer: %v", err)
+	}
+
+	// 收到退出信号时中止回放
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	stats, err := replayer.Run(ctx)
+	if err != nil {
+		log.Printf("Replay interrupted: %v", err)
+	}
+
+	log.Printf("Replay stats: sent=%d failed=%d mismatched=%d", stats.Sent, stats.Failed, stats.Mismatched)
+	if stats.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,260 @@
+// Package client 供应用服务嵌入的错误事件上报SDK
+//
+// 应用服务可以上报网关无法从状态码观察到的错误（带堆栈和上下文），
+// 事件通过控制面的 /v1/events 接入端点进入与网关采样相同的聚类管道。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// Config SDK配置
+type Config struct {
+	Endpoint      string        // 控制面地址，如 http://control-plane:8081
+	APIKey        string        // 控制面API密钥
+	ServiceName   string        // 上报服务名
+	BatchSize     int           // 达到该数量立即发送，默认100
+	FlushInterval time.Duration // 定期发送间隔，默认2秒
+	QueueSize     int           // 本地队列长度，满时丢弃，默认10000
+	MaxFrames     int           // 堆栈最大帧数，默认20
+	HTTPClient    *http.Client
+}
+
+// Event 上报事件的可选上下文
+type Event struct {
+	TraceID     string
+	SpanID      string
+	RequestPath string
+	Method      string
+	StatusCode  int
+	Tenant      string
+}
+
+// Client 错误事件上报客户端，事件在后台批量发送
+type Client struct {
+	config  Config
+	queue   chan *types.ErrorEvent
+	flushCh chan chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// New 创建上报客户端并启动后台发送
+func New(config Config) (*Client, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+	if config.ServiceName == "" {
+		return nil, fmt.Errorf("service name is required")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 2 * time.Second
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 10000
+	}
+	if config.MaxFrames <= 0 {
+		config.MaxFrames = 20
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	c := &Client{
+		config:  config,
+		queue:   make(chan *types.ErrorEvent, config.QueueSize),
+		flushCh: make(chan chan struct{}),
+		stopCh:  make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	go c.loop()
+
+	return c, nil
+}
+
+// Report 上报错误，堆栈从调用处采集，不阻塞调用方
+func (c *Client) Report(err error, event *Event) bool {
+	if err == nil {
+		return false
+	}
+
+	errorEvent := &types.ErrorEvent{
+		EventID:      utils.GenerateID(),
+		ServiceName:  c.config.ServiceName,
+		ErrorMessage: err.Error(),
+		StackTrace:   callers(3, c.config.MaxFrames),
+		Timestamp:    time.Now(),
+	}
+
+	if event != nil {
+		errorEvent.TraceID = event.TraceID
+		errorEvent.SpanID = event.SpanID
+		errorEvent.RequestPath = event.RequestPath
+		errorEvent.Method = event.Method
+		errorEvent.StatusCode = event.StatusCode
+		errorEvent.Tenant = event.Tenant
+	}
+
+	return c.ReportEvent(errorEvent)
+}
+
+// ReportEvent 上报完整的错误事件，队列满时丢弃并返回false
+func (c *Client) ReportEvent(event *types.ErrorEvent) bool {
+	select {
+	case c.queue <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// Recover 捕获panic并上报后重新抛出，用法：defer client.Recover(nil)
+func (c *Client) Recover(event *Event) {
+	if r := recover(); r != nil {
+		c.Report(fmt.Errorf("panic: %v", r), event)
+		c.Flush(context.Background())
+		panic(r)
+	}
+}
+
+// Flush 立即发送队列中的事件
+func (c *Client) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case c.flushCh <- done:
+	case <-c.stopCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 发送剩余事件并停止客户端
+func (c *Client) Close() error {
+	c.once.Do(func() {
+		close(c.stopCh)
+	})
+	c.wg.Wait()
+	return nil
+}
+
+// loop 后台批量发送
+func (c *Client) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*types.ErrorEvent, 0, c.config.BatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := c.send(batch); err != nil {
+			log.Printf("Failed to report %d error events: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event := <-c.queue:
+			batch = append(batch, event)
+			if len(batch) >= c.config.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-c.flushCh:
+			c.drain(&batch, send)
+			close(done)
+		case <-c.stopCh:
+			c.drain(&batch, send)
+			return
+		}
+	}
+}
+
+// drain 发送队列中所有事件
+func (c *Client) drain(batch *[]*types.ErrorEvent, send func()) {
+	for {
+		select {
+		case event := <-c.queue:
+			*batch = append(*batch, event)
+			if len(*batch) >= c.config.BatchSize {
+				send()
+			}
+		default:
+			send()
+			return
+		}
+	}
+}
+
+// send 发送一批事件到控制面
+func (c *Client) send(events []*types.ErrorEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.config.Endpoint+"/v1/events", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// callers 采集调用堆栈
+func callers(skip, maxFrames int) []string {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	traces := make([]string, 0, n)
+	for {
+		frame, more := frames.Next()
+		traces = append(traces, fmt.Sprintf("%s:%d %s", frame.File, frame.Line, frame.Function))
+		if !more {
+			break
+		}
+	}
+	return traces
+}
@@ -0,0 +1,179 @@
+package alerting
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// newChannel 按类型创建通知渠道
+func newChannel(config *types.AlertChannelConfig) (channel, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook url is empty")
+	}
+
+	switch config.Type {
+	case types.AlertChannelSlack:
+		return &slackChannel{url: config.WebhookURL}, nil
+	case types.AlertChannelDingTalk:
+		return &dingTalkChannel{url: config.WebhookURL, secret: config.Secret}, nil
+	case types.AlertChannelFeishu:
+		return &feishuChannel{url: config.WebhookURL, secret: config.Secret}, nil
+	case types.AlertChannelWeCom:
+		return &weComChannel{url: config.WebhookURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", config.Type)
+	}
+}
+
+// slackChannel Slack Incoming Webhook
+type slackChannel struct {
+	url string
+}
+
+// send 发送文本消息
+func (sc *slackChannel) send(client *http.Client, event *types.AlertEvent, text string) error {
+	resp, err := postJSON(client, sc.url, map[string]interface{}{"text": text})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// dingTalkChannel 钉钉自定义机器人，配置密钥时按加签方式在URL上附加timestamp和sign
+type dingTalkChannel struct {
+	url    string
+	secret string
+}
+
+// send 发送markdown消息
+func (dc *dingTalkChannel) send(client *http.Client, event *types.AlertEvent, text string) error {
+	target := dc.url
+	if dc.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(dc.secret))
+		mac.Write([]byte(timestamp + "\n" + dc.secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+
+	resp, err := postJSON(client, target, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": event.Title,
+			"text":  text,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp)
+}
+
+// feishuChannel 飞书自定义机器人，配置密钥时在消息体中附加timestamp和sign
+type feishuChannel struct {
+	url    string
+	secret string
+}
+
+// send 发送文本消息
+func (fc *feishuChannel) send(client *http.Client, event *types.AlertEvent, text string) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": text},
+	}
+	if fc.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		// 飞书以"timestamp\nsecret"为密钥对空消息签名
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+fc.secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	resp, err := postJSON(client, fc.url, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&result); err != nil {
+		return nil
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("feishu returned code %d: %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// weComChannel 企业微信群机器人
+type weComChannel struct {
+	url string
+}
+
+// send 发送markdown消息
+func (wc *weComChannel) send(client *http.Client, event *types.AlertEvent, text string) error {
+	resp, err := postJSON(client, wc.url, map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": text},
+	})
+	if err != nil {
+		return err
+	}
+	return checkErrCode(resp)
+}
+
+// postJSON 发送JSON请求，非2xx状态码视为失败
+func postJSON(client *http.Client, target string, payload interface{}) (*http.Response, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp, nil
+}
+
+// checkErrCode 钉钉和企业微信以errcode表示发送结果
+func checkErrCode(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&result); err != nil {
+		return nil
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("webhook returned errcode %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package alerting
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultTemplate 未配置模板时的消息格式
+const defaultTemplate = `[{{.Severity}}] {{.Title}}
+{{.Message}}{{range $k, $v := .Labels}}
+{{$k}}: {{$v}}{{end}}
+{{.Time.Format "2006-01-02 15:04:05"}}`
+
+// severityRank 告警级别排序
+var severityRank = map[string]int{
+	types.AlertSeverityInfo:     0,
+	types.AlertSeverityWarning:  1,
+	types.AlertSeverityCritical: 2,
+}
+
+// channel 通知渠道
+type channel interface {
+	send(client *http.Client, event *types.AlertEvent, text string) error
+}
+
+// subscription 渠道及其订阅条件
+type subscription struct {
+	name        string
+	channel     channel
+	events      map[string]bool
+	minSeverity int
+}
+
+// Notifier 告警通知器，事件按类型渲染模板后异步发送到订阅的渠道，队列满时丢弃
+type Notifier struct {
+	subscriptions []*subscription
+	templates     map[string]*template.Template
+	client        *http.Client
+	queue         chan *types.AlertEvent
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+	once          sync.Once
+	sent          int64
+	failed        int64
+	dropped       int64
+}
+
+// NewNotifier 创建告警通知器
+func NewNotifier(config *types.AlertingConfig) (*Notifier, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = 256
+	}
+
+	n := &Notifier{
+		templates: make(map[string]*template.Template),
+		client:    &http.Client{Timeout: timeout},
+		queue:     make(chan *types.AlertEvent, queueSize),
+		stopCh:    make(chan struct{}),
+	}
+
+	fallback, err := template.New("default").Parse(defaultTemplate)
+	if err != nil {
+		return nil, err
+	}
+	n.templates["default"] = fallback
+	for eventType, text := range config.Templates {
+		tmpl, err := template.New(eventType).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert template %s: %v", eventType, err)
+		}
+		n.templates[eventType] = tmpl
+	}
+
+	for i, cfg := range config.Channels {
+		ch, err := newChannel(&cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert channel %d: %v", i, err)
+		}
+
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", cfg.Type, i)
+		}
+
+		minSeverity, ok := severityRank[cfg.MinSeverity]
+		if cfg.MinSeverity != "" && !ok {
+			return nil, fmt.Errorf("alert channel %s: unknown severity %q", name, cfg.MinSeverity)
+		}
+
+		sub := &subscription{name: name, channel: ch, minSeverity: minSeverity}
+		if len(cfg.Events) > 0 {
+			sub.events = make(map[string]bool, len(cfg.Events))
+			for _, eventType := range cfg.Events {
+				sub.events[eventType] = true
+			}
+		}
+		n.subscriptions = append(n.subscriptions, sub)
+	}
+
+	return n, nil
+}
+
+// Start 启动发送协程
+func (n *Notifier) Start() {
+	n.wg.Add(1)
+	go n.sendLoop()
+}
+
+// Stop 发送队列中剩余的事件后停止
+func (n *Notifier) Stop() {
+	n.once.Do(func() {
+		close(n.stopCh)
+		n.wg.Wait()
+	})
+}
+
+// Notify 提交告警事件，不阻塞调用方
+func (n *Notifier) Notify(event *types.AlertEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Severity == "" {
+		event.Severity = types.AlertSeverityInfo
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		if atomic.AddInt64(&n.dropped, 1)%100 == 1 {
+			log.Printf("Alert queue full, dropping %s event", event.Type)
+		}
+	}
+}
+
+// Stats 获取发送统计
+func (n *Notifier) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"channels": len(n.subscriptions),
+		"sent":     atomic.LoadInt64(&n.sent),
+		"failed":   atomic.LoadInt64(&n.failed),
+		"dropped":  atomic.LoadInt64(&n.dropped),
+	}
+}
+
+// sendLoop 发送队列中的事件，停止时发送剩余事件
+func (n *Notifier) sendLoop() {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case event := <-n.queue:
+			n.deliver(event)
+		case <-n.stopCh:
+			for {
+				select {
+				case event := <-n.queue:
+					n.deliver(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver 渲染消息并发送到订阅该事件的所有渠道
+func (n *Notifier) deliver(event *types.AlertEvent) {
+	text, err := n.Render(event)
+	if err != nil {
+		log.Printf("Failed to render %s alert: %v", event.Type, err)
+		return
+	}
+
+	for _, sub := range n.subscriptions {
+		if !sub.accepts(event) {
+			continue
+		}
+		if err := sub.channel.send(n.client, event, text); err != nil {
+			atomic.AddInt64(&n.failed, 1)
+			log.Printf("Failed to send %s alert to channel %s: %v", event.Type, sub.name, err)
+			continue
+		}
+		atomic.AddInt64(&n.sent, 1)
+	}
+}
+
+// Render 按事件类型的模板渲染消息，未配置时使用缺省模板
+func (n *Notifier) Render(event *types.AlertEvent) (string, error) {
+	tmpl, exists := n.templates[event.Type]
+	if !exists {
+		tmpl = n.templates["default"]
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// accepts 判断渠道是否订阅事件
+func (s *subscription) accepts(event *types.AlertEvent) bool {
+	if s.events != nil && !s.events[event.Type] {
+		return false
+	}
+	return severityRank[event.Severity] >= s.minSeverity
+}
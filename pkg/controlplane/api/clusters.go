@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// clusterSummary 簇列表项，不返回质心向量和成员列表
+type clusterSummary struct {
+	ID          string  `json:"id"`
+	Description string  `json:"description"`
+	ErrorCount  int64   `json:"error_count"`
+	Severity    float64 `json:"severity"`
+	Members     int     `json:"members"`
+}
+
+// maxPageSize 分页获取簇列表时单页上限
+const maxPageSize = 1000
+
+// listClusters 获取簇列表，按错误数降序；指定limit或cursor时按簇ID分页返回
+func (s *Server) listClusters(c *gin.Context) {
+	if c.Query("limit") != "" || c.Query("cursor") != "" {
+		s.listClusterPage(c)
+		return
+	}
+
+	clusters, err := s.engine.GetAllClusters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	summaries := make([]clusterSummary, 0, len(clusters))
+	for _, cluster := range clusters {
+		summaries = append(summaries, summarizeCluster(cluster))
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ErrorCount > summaries[j].ErrorCount
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters": summaries,
+		"count":    len(summaries),
+	})
+}
+
+// getCluster 获取簇详情及相似度解释，top参数控制返回的邻居和成员数量
+func (s *Server) getCluster(c *gin.Context) {
+	topN, _ := strconv.Atoi(c.DefaultQuery("top", "3"))
+
+	explanation, err := s.engine.ExplainCluster(c.Param("id"), topN)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cluster":              summarizeCluster(explanation.Cluster),
+		"similarity_threshold": explanation.Threshold,
+		"neighbors":            explanation.Neighbors,
+		"central_members":      explanation.Central,
+		"peripheral_members":   explanation.Peripheral,
+		"sampled_members":      explanation.SampledMembers,
+	})
+}
+
+// explainIncident 汇总簇成员、近期指标和已下发的策略，返回LLM生成的事件说明
+func (s *Server) explainIncident(c *gin.Context) {
+	if s.narrator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "incident narratives are disabled"})
+		return
+	}
+
+	clusterID := c.Param("id")
+	if _, err := s.engine.GetCluster(clusterID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	narrative, err := s.narrator.Explain(clusterID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, narrative)
+}
+
+// listClusterPage 按簇ID分页获取簇列表，next_cursor为空表示已到末尾
+func (s *Server) listClusterPage(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	clusters, next, err := s.engine.ListClusters(c.Query("cursor"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	summaries := make([]clusterSummary, 0, len(clusters))
+	for _, cluster := range clusters {
+		summaries = append(summaries, summarizeCluster(cluster))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"clusters":    summaries,
+		"count":       len(summaries),
+		"next_cursor": next,
+	})
+}
+
+// summarizeCluster 构造簇摘要
+func summarizeCluster(cluster *types.Cluster) clusterSummary {
+	return clusterSummary{
+		ID:          cluster.ID,
+		Description: cluster.Description,
+		ErrorCount:  cluster.ErrorCount,
+		Severity:    cluster.Severity,
+		Members:     len(cluster.Members),
+	}
+}
+
+// getReclusterReport 获取最近一次重聚类选择的K及各候选K的评分
+func (s *Server) getReclusterReport(c *gin.Context) {
+	report := s.engine.ReclusterReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no re-clustering run yet"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// getThresholdCalibration 获取各范围最近一次的相似度阈值校准结果和校准曲线，scope指定服务，*为全局
+func (s *Server) getThresholdCalibration(c *gin.Context) {
+	results := s.engine.ThresholdCalibrations()
+	if scope := c.Query("scope"); scope != "" {
+		filtered := make([]*types.ThresholdCalibration, 0, 1)
+		for _, result := range results {
+			if result.Scope == scope {
+				filtered = append(filtered, result)
+			}
+		}
+		results = filtered
+	}
+	if len(results) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no threshold calibration run yet"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"calibrations": results})
+}
+
+// calibrateThreshold 立即执行一次阈值校准，apply=true时应用推荐阈值，否则只返回推荐
+func (s *Server) calibrateThreshold(c *gin.Context) {
+	apply, _ := strconv.ParseBool(c.Query("apply"))
+
+	results, err := s.engine.CalibrateThresholds(apply)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"calibrations": results})
+}
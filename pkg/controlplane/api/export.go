@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// 可导出的数据种类
+const (
+	exportClusters = "clusters"
+	exportPolicies = "policies"
+	exportEvents   = "events"
+)
+
+// exportRecord 导出的一行记录，cursor用于断点续传
+type exportRecord struct {
+	Cursor string      `json:"cursor"`
+	Data   interface{} `json:"data"`
+}
+
+// exportTrailer 导出结束时的最后一行，complete为false时next_cursor为续传起点
+type exportTrailer struct {
+	Kind       string `json:"kind"`
+	Count      int    `json:"count"`
+	Complete   bool   `json:"complete"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// policyRecord 导出的策略及其键
+type policyRecord struct {
+	Key        string        `json:"key"`
+	Region     string        `json:"region,omitempty"`
+	ClusterKey string        `json:"cluster_key"`
+	Policy     *types.Policy `json:"policy"`
+}
+
+// eventRecord 导出的事件，seq为接收序号
+type eventRecord struct {
+	Seq   uint64            `json:"seq"`
+	Event *types.ErrorEvent `json:"event"`
+}
+
+// exportPager 读取从cursor之后开始的一页记录，返回下一页的cursor，为空表示已到末尾
+type exportPager func(cursor string, limit int) ([]exportRecord, string, error)
+
+// exporter 管理端批量导出，按页读取并流式写出，同时进行的导出数有上限
+type exporter struct {
+	config types.ExportConfig
+	slots  chan struct{}
+	events *eventLog
+}
+
+// newExporter 创建批量导出
+func newExporter(config *types.ExportConfig) *exporter {
+	cfg := *config
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = 200
+	}
+	if cfg.PageSize > maxPageSize {
+		cfg.PageSize = maxPageSize
+	}
+	if cfg.RatePerSecond <= 0 {
+		cfg.RatePerSecond = 1000
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 2
+	}
+	if cfg.EventBuffer <= 0 {
+		cfg.EventBuffer = 10000
+	}
+
+	return &exporter{
+		config: cfg,
+		slots:  make(chan struct{}, cfg.MaxConcurrent),
+		events: newEventLog(cfg.EventBuffer),
+	}
+}
+
+// SetConfigStore 设置配置中心，用于导出已下发的策略，未设置时策略导出返回503
+func (s *Server) SetConfigStore(store interfaces.ConfigStore) {
+	s.store = store
+}
+
+// ObserveEvent 记录归入簇的事件供导出，需通过clustering.AttachEventObserver接入，导出未启用时忽略
+func (s *Server) ObserveEvent(event *types.ErrorEvent) {
+	if s.export != nil {
+		s.export.events.append(event)
+	}
+}
+
+// exportData 以NDJSON流式导出簇、策略或最近事件，每行一条记录，最后一行为导出结果；
+// 客户端断开时停止读取，中断后可用最后一条记录的cursor续传
+func (s *Server) exportData(c *gin.Context) {
+	if s.export == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "data export is disabled"})
+		return
+	}
+
+	kind := c.Param("kind")
+	var pager exportPager
+	switch kind {
+	case exportClusters:
+		pager = s.clusterPager
+	case exportPolicies:
+		if s.store == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "config store is not configured"})
+			return
+		}
+		pager = s.policyPager
+	case exportEvents:
+		pager = s.export.events.page
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown export kind %q", kind)})
+		return
+	}
+
+	select {
+	case s.export.slots <- struct{}{}:
+		defer func() { <-s.export.slots }()
+	default:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many exports in progress"})
+		return
+	}
+
+	// 先读取第一页，游标无效等错误仍可返回正常的状态码
+	cursor := c.Query("cursor")
+	page, next, err := pager(cursor, s.export.config.PageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	trailer, err := s.streamExport(c.Request.Context(), c.Writer, kind, page, next, pager)
+	if err != nil {
+		log.Printf("Export of %s stopped after %d records: %v", kind, trailer.Count, err)
+		return
+	}
+	if trailer.Error != "" {
+		log.Printf("Export of %s failed after %d records: %s", kind, trailer.Count, trailer.Error)
+	}
+}
+
+// streamExport 逐页写出记录，每页写完后刷新并按速率上限等待；写出阻塞时不会读取下一页，
+// 内存中只保留当前页。返回的错误表示客户端已断开，无法再写出结果行
+func (s *Server) streamExport(ctx context.Context, w gin.ResponseWriter, kind string,
+	page []exportRecord, next string, pager exportPager) (exportTrailer, error) {
+	encoder := json.NewEncoder(w)
+	trailer := exportTrailer{Kind: kind}
+	start := time.Now()
+
+	for {
+		for _, record := range page {
+			if err := encoder.Encode(record); err != nil {
+				return trailer, err
+			}
+		}
+		trailer.Count += len(page)
+		w.Flush()
+
+		if next == "" {
+			trailer.Complete = true
+			break
+		}
+		trailer.NextCursor = next
+
+		if err := s.export.pace(ctx, start, trailer.Count); err != nil {
+			return trailer, err
+		}
+
+		var err error
+		page, next, err = pager(next, s.export.config.PageSize)
+		if err != nil {
+			trailer.Error = err.Error()
+			break
+		}
+	}
+
+	if trailer.Complete {
+		trailer.NextCursor = ""
+	}
+	if err := encoder.Encode(trailer); err != nil {
+		return trailer, err
+	}
+	w.Flush()
+	return trailer, nil
+}
+
+// pace 按速率上限等待，已写出count条记录时不早于start+count/rate读取下一页
+func (e *exporter) pace(ctx context.Context, start time.Time, count int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	due := start.Add(time.Duration(float64(count) / e.config.RatePerSecond * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// clusterPager 按簇ID分页导出完整的簇，每页在聚类引擎中单独深拷贝
+func (s *Server) clusterPager(cursor string, limit int) ([]exportRecord, string, error) {
+	clusters, next, err := s.engine.ListClusters(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	records := make([]exportRecord, 0, len(clusters))
+	for _, cluster := range clusters {
+		records = append(records, exportRecord{Cursor: cluster.ID, Data: cluster})
+	}
+	return records, next, nil
+}
+
+// policyPager 按策略键分页导出全局和各区域的策略；配置中心不支持分页读取，
+// 每页重新读取键值并只解析当前页的策略
+func (s *Server) policyPager(cursor string, limit int) ([]exportRecord, string, error) {
+	values := make(map[string]string)
+	for _, prefix := range []string{utils.GlobalPolicyPrefix, utils.RegionPrefix} {
+		kvs, err := s.store.GetWithPrefix(prefix)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list policies: %v", err)
+		}
+		for key, value := range kvs {
+			if _, _, ok := utils.ParsePolicyKey(key); ok && key > cursor {
+				values[key] = value
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	next := ""
+	if len(keys) > limit {
+		keys = keys[:limit]
+		next = keys[limit-1]
+	}
+
+	records := make([]exportRecord, 0, len(keys))
+	for _, key := range keys {
+		var policy types.Policy
+		if err := json.Unmarshal([]byte(values[key]), &policy); err != nil {
+			log.Printf("Skipping undecodable policy %s in export: %v", key, err)
+			continue
+		}
+		region, clusterKey, _ := utils.ParsePolicyKey(key)
+		records = append(records, exportRecord{
+			Cursor: key,
+			Data:   policyRecord{Key: key, Region: region, ClusterKey: clusterKey, Policy: &policy},
+		})
+	}
+	return records, next, nil
+}
+
+// eventLog 最近归入簇的事件，环形缓冲区按接收序号保留固定数量
+type eventLog struct {
+	events []types.ErrorEvent
+	next   uint64 // 下一个事件的序号，从1开始
+	mutex  sync.RWMutex
+}
+
+// newEventLog 创建事件缓冲区
+func newEventLog(capacity int) *eventLog {
+	return &eventLog{events: make([]types.ErrorEvent, capacity), next: 1}
+}
+
+// append 记录事件副本，缓冲区满时覆盖最早的事件
+func (l *eventLog) append(event *types.ErrorEvent) {
+	l.mutex.Lock()
+	l.events[l.next%uint64(len(l.events))] = *event
+	l.next++
+	l.mutex.Unlock()
+}
+
+// page 读取序号大于cursor的一页事件，已被覆盖的事件跳过；导出期间新到达的事件也会被导出
+func (l *eventLog) page(cursor string, limit int) ([]exportRecord, string, error) {
+	var after uint64
+	if cursor != "" {
+		value, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid event cursor %q", cursor)
+		}
+		after = value
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	capacity := uint64(len(l.events))
+	seq := after + 1
+	if l.next > capacity && seq < l.next-capacity {
+		seq = l.next - capacity
+	}
+
+	records := make([]exportRecord, 0, limit)
+	for ; seq < l.next && len(records) < limit; seq++ {
+		event := l.events[seq%capacity]
+		records = append(records, exportRecord{
+			Cursor: strconv.FormatUint(seq, 10),
+			Data:   eventRecord{Seq: seq, Event: &event},
+		})
+	}
+
+	if seq < l.next {
+		return records, strconv.FormatUint(seq-1, 10), nil
+	}
+	return records, "", nil
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// ingestor HTTP事件接入，事件进入有界队列后由工作协程送入聚类管道
+type ingestor struct {
+	engine       interfaces.ClusteringEngine
+	desensitizer interfaces.Desensitizer
+	queue        chan *types.ErrorEvent
+	workers      int
+	maxBatchSize int
+	maxBodyBytes int64
+	accepted     int64
+	dropped      int64
+	wg           sync.WaitGroup
+}
+
+// eventBatch 批量事件请求体
+type eventBatch struct {
+	Events []*types.ErrorEvent `json:"events"`
+}
+
+// newIngestor 创建事件接入
+func newIngestor(config *types.ControlPlaneAPIConfig, engine interfaces.ClusteringEngine) *ingestor {
+	queueSize := config.IngestQueueSize
+	if queueSize <= 0 {
+		queueSize = 10000
+	}
+
+	workers := config.IngestWorkers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	maxBatchSize := config.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = 500
+	}
+
+	return &ingestor{
+		engine:       engine,
+		desensitizer: utils.NewDesensitizer(),
+		queue:        make(chan *types.ErrorEvent, queueSize),
+		workers:      workers,
+		maxBatchSize: maxBatchSize,
+		maxBodyBytes: 4 << 20,
+	}
+}
+
+// start 启动工作协程
+func (in *ingestor) start() {
+	for i := 0; i < in.workers; i++ {
+		in.wg.Add(1)
+		go in.worker()
+	}
+}
+
+// stop 关闭队列并等待剩余事件处理完成
+func (in *ingestor) stop() {
+	close(in.queue)
+	in.wg.Wait()
+
+	log.Printf("Event ingestion stopped: accepted=%d dropped=%d",
+		atomic.LoadInt64(&in.accepted), atomic.LoadInt64(&in.dropped))
+}
+
+// worker 处理队列中的事件
+func (in *ingestor) worker() {
+	defer in.wg.Done()
+
+	for event := range in.queue {
+		if err := in.engine.ProcessErrorEvent(event); err != nil {
+			log.Printf("Failed to process ingested event %s: %v", event.EventID, err)
+		}
+	}
+}
+
+// handleEvents 接收批量错误事件，请求体为事件数组或{"events": [...]}
+func (in *ingestor) handleEvents(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, in.maxBodyBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read body: %v", err)})
+		return
+	}
+	if int64(len(body)) > in.maxBodyBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+		return
+	}
+
+	events, err := decodeEvents(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no events in request"})
+		return
+	}
+	if len(events) > in.maxBatchSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("batch size %d exceeds limit %d", len(events), in.maxBatchSize),
+		})
+		return
+	}
+
+	accepted, invalid, dropped := 0, 0, 0
+	for _, event := range events {
+		if !in.normalize(event) {
+			invalid++
+			continue
+		}
+
+		select {
+		case in.queue <- event:
+			accepted++
+		default:
+			dropped++
+		}
+	}
+
+	atomic.AddInt64(&in.accepted, int64(accepted))
+	atomic.AddInt64(&in.dropped, int64(dropped))
+
+	status := http.StatusAccepted
+	if accepted == 0 && dropped > 0 {
+		// 队列已满，提示客户端退避重试
+		status = http.StatusTooManyRequests
+	}
+
+	c.JSON(status, gin.H{
+		"accepted": accepted,
+		"invalid":  invalid,
+		"dropped":  dropped,
+	})
+}
+
+// normalize 校验并补全事件字段，消息缺失的事件视为无效
+func (in *ingestor) normalize(event *types.ErrorEvent) bool {
+	if event == nil || event.ErrorMessage == "" {
+		return false
+	}
+
+	if event.EventID == "" {
+		event.EventID = utils.GenerateID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.ServiceName == "" {
+		event.ServiceName = "unknown"
+	}
+
+	// 推送方可能未脱敏，与网关采样保持一致
+	event.ErrorMessage = in.desensitizer.Desensitize(event.ErrorMessage)
+	event.ClusterID = ""
+
+	return true
+}
+
+// decodeEvents 解析事件数组或包装对象
+func decodeEvents(body []byte) ([]*types.ErrorEvent, error) {
+	var events []*types.ErrorEvent
+	if err := json.Unmarshal(body, &events); err == nil {
+		return events, nil
+	}
+
+	var batch eventBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("invalid event batch: %v", err)
+	}
+	return batch.Events, nil
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ingestMetrics 接收网关推送的计数快照，重发的快照同样返回202
+func (s *Server) ingestMetrics(c *gin.Context) {
+	if s.gatewayMetrics == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "gateway metrics ingestion is disabled"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, s.ingest.maxBodyBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read body: %v", err)})
+		return
+	}
+	if int64(len(body)) > s.ingest.maxBodyBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+		return
+	}
+
+	var snapshot types.MetricsSnapshot
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid metrics snapshot: %v", err)})
+		return
+	}
+	if snapshot.Instance == "" || snapshot.Sequence == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "instance and seq are required"})
+		return
+	}
+
+	accepted := s.gatewayMetrics.Ingest(&snapshot)
+	c.JSON(http.StatusAccepted, gin.H{
+		"accepted":  accepted,
+		"duplicate": !accepted,
+	})
+}
+
+// getGatewayMetrics 获取推送快照的网关实例，指定簇ID时同时返回窗口内的簇速率，窗口默认5分钟
+func (s *Server) getGatewayMetrics(c *gin.Context) {
+	if s.gatewayMetrics == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "gateway metrics ingestion is disabled"})
+		return
+	}
+
+	response := gin.H{"instances": s.gatewayMetrics.Instances()}
+
+	if clusterID := c.Param("cluster_id"); clusterID != "" {
+		window := 5 * time.Minute
+		if value := c.Query("window"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid window: %s", value)})
+				return
+			}
+			window = parsed
+		}
+
+		rates, ok := s.gatewayMetrics.ClusterRates(clusterID, window)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no gateway metrics for cluster %s", clusterID)})
+			return
+		}
+		response["cluster_id"] = clusterID
+		response["window"] = window.String()
+		response["rates"] = rates
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// getBreakerHistory 获取网关上报的簇熔断历史：熔断次数、开启时长和平均恢复时长，窗口默认1小时
+func (s *Server) getBreakerHistory(c *gin.Context) {
+	if s.gatewayMetrics == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "gateway metrics ingestion is disabled"})
+		return
+	}
+
+	window := time.Hour
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid window: %s", value)})
+			return
+		}
+		window = parsed
+	}
+
+	clusterID := c.Param("cluster_id")
+	history, ok := s.gatewayMetrics.BreakerHistory(clusterID, window)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no breaker stats for cluster %s", clusterID)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cluster_id": clusterID,
+		"breakers":   history,
+	})
+}
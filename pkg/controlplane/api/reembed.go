@@ -0,0 +1,62 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// getPreprocessRules 获取当前预处理规则集版本
+func (s *Server) getPreprocessRules(c *gin.Context) {
+	if s.embed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "embedding service not configured"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ruleset_version": s.embed.RulesetVersion()})
+}
+
+// updatePreprocessRules 替换预处理规则，新事件立即使用新规则，已有成员需调用重新向量化迁移
+func (s *Server) updatePreprocessRules(c *gin.Context) {
+	if s.embed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "embedding service not configured"})
+		return
+	}
+
+	var rules []types.PreprocessRule
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := s.embed.RulesetVersion()
+	if err := s.embed.SetPreprocessRules(rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"previous_version": previous,
+		"ruleset_version":  s.embed.RulesetVersion(),
+	})
+}
+
+// reEmbed 在当前规则下重新向量化所有成员，dry_run=true时只返回簇的变化
+func (s *Server) reEmbed(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	report, err := s.engine.ReEmbed(dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !dryRun && len(report.Remapped) > 0 {
+		log.Printf("Re-embedding merged %d clusters, policies keyed by merged cluster IDs may need migration", len(report.Remapped))
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listPolicyReports 获取策略效果报告，簇ID可通过路径或cluster_id查询参数指定
+func (s *Server) listPolicyReports(c *gin.Context) {
+	if s.reports == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "policy effectiveness reporting is disabled"})
+		return
+	}
+
+	clusterID := c.Param("cluster_id")
+	if clusterID == "" {
+		clusterID = c.Query("cluster_id")
+	}
+
+	reports := s.reports.Reports(clusterID)
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// listPolicyTemplates 获取策略模板库，按严重度区间升序
+func (s *Server) listPolicyTemplates(c *gin.Context) {
+	if s.policyTemplates == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "policy engine is disabled"})
+		return
+	}
+
+	templates := s.policyTemplates.Templates()
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// listEscalations 获取策略升级事件，簇ID可通过路径或cluster_id查询参数指定
+func (s *Server) listEscalations(c *gin.Context) {
+	if s.escalations == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "policy escalation is disabled"})
+		return
+	}
+
+	clusterID := c.Param("cluster_id")
+	if clusterID == "" {
+		clusterID = c.Query("cluster_id")
+	}
+
+	events := s.escalations.Events(clusterID)
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// getPolicyAudit 获取最近一次策略巡检结果，kind参数按问题类型过滤
+func (s *Server) getPolicyAudit(c *gin.Context) {
+	if s.audit == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "policy audit is disabled"})
+		return
+	}
+
+	report := s.audit.LatestAudit()
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no policy audit run yet"})
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind == "" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	filtered := *report
+	filtered.Gaps = nil
+	for _, gap := range report.Gaps {
+		if gap.Kind == kind {
+			filtered.Gaps = append(filtered.Gaps, gap)
+		}
+	}
+	c.JSON(http.StatusOK, filtered)
+}
+
+// getPolicyHistory 查询策略历史：指定at（RFC3339）时返回该时刻生效的策略，region参数按区域解析；
+// 否则返回from到to之间的变更。簇ID可通过路径或cluster_id查询参数指定，namespace参数限定路由命名空间
+func (s *Server) getPolicyHistory(c *gin.Context) {
+	if s.history == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "policy history is disabled"})
+		return
+	}
+
+	clusterKey := c.Param("cluster_id")
+	if clusterKey == "" {
+		clusterKey = c.Query("cluster_id")
+	}
+	if namespace := c.Query("namespace"); namespace != "" {
+		if clusterKey == "" {
+			clusterKey = namespace
+		} else {
+			clusterKey = namespace + "/" + clusterKey
+		}
+	}
+
+	times := make(map[string]time.Time)
+	for _, name := range []string{"at", "from", "to"} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %v", name, err)})
+			return
+		}
+		times[name] = t
+	}
+
+	if at, ok := times["at"]; ok {
+		policies := s.history.ActiveAt(clusterKey, c.Query("region"), at)
+		c.JSON(http.StatusOK, gin.H{
+			"at":       at,
+			"policies": policies,
+			"count":    len(policies),
+		})
+		return
+	}
+
+	entries := s.history.Timeline(clusterKey, times["from"], times["to"])
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// listCanaryVerdicts 获取各路由最近一次金丝雀分析的结论
+func (s *Server) listCanaryVerdicts(c *gin.Context) {
+	if s.canary == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "canary analysis is disabled"})
+		return
+	}
+
+	verdicts := s.canary.Verdicts()
+	c.JSON(http.StatusOK, gin.H{
+		"verdicts": verdicts,
+		"count":    len(verdicts),
+	})
+}
+
+// getCanaryVerdict 立即分析路由并返回结论，持续部署系统按verdict字段决定推进或回滚
+func (s *Server) getCanaryVerdict(c *gin.Context) {
+	if s.canary == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "canary analysis is disabled"})
+		return
+	}
+
+	verdict, err := s.canary.Analyze(c.Param("route"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, verdict)
+}
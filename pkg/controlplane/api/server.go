@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/grpcadmin"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Server 控制面HTTP服务
+type Server struct {
+	config          *types.ControlPlaneAPIConfig
+	engine          interfaces.ClusteringEngine
+	reports         interfaces.PolicyEffectivenessReporter
+	policyTemplates interfaces.PolicyTemplateProvider
+	escalations     interfaces.PolicyEscalationReporter
+	embed           interfaces.EmbeddingService
+	gatewayMetrics  interfaces.GatewayMetricsStore
+	canary          interfaces.CanaryReporter
+	narrator        interfaces.IncidentNarrator
+	audit           interfaces.PolicyAuditReporter
+	history         interfaces.PolicyHistoryReporter
+	store           interfaces.ConfigStore
+	jobs            interfaces.JobScheduler
+	export          *exporter         // 未启用导出时为nil
+	grpcAdmin       *grpcadmin.Server // 未启用gRPC管理端口时为nil
+	router          *gin.Engine
+	server          *http.Server
+	ingest          *ingestor
+	wg              sync.WaitGroup
+}
+
+// NewServer 创建控制面HTTP服务
+func NewServer(config *types.ControlPlaneAPIConfig, engine interfaces.ClusteringEngine) *Server {
+	gin.SetMode(gin.ReleaseMode)
+
+	s := &Server{
+		config: config,
+		engine: engine,
+		router: gin.New(),
+		ingest: newIngestor(config, engine),
+	}
+	if config.Export.Enabled {
+		s.export = newExporter(&config.Export)
+	}
+	if config.GRPC.Enabled {
+		s.grpcAdmin = grpcadmin.NewServer("controlplane", &config.GRPC, s.components)
+	}
+
+	s.router.Use(gin.Recovery())
+	s.setupRoutes()
+
+	return s
+}
+
+// setupRoutes 设置路由
+func (s *Server) setupRoutes() {
+	s.router.GET("/health", s.health)
+
+	v1 := s.router.Group("/v1", s.authenticate())
+	{
+		v1.POST("/events", s.ingest.handleEvents)
+		v1.GET("/clusters", s.listClusters)
+		v1.GET("/clusters/:id", s.getCluster)
+		v1.GET("/policy-reports", s.listPolicyReports)
+		v1.GET("/policy-reports/:cluster_id", s.listPolicyReports)
+		v1.GET("/policy-templates", s.listPolicyTemplates)
+		v1.GET("/escalations", s.listEscalations)
+		v1.GET("/escalations/:cluster_id", s.listEscalations)
+		v1.GET("/policy-audit", s.getPolicyAudit)
+		v1.GET("/policy-history", s.getPolicyHistory)
+		v1.GET("/policy-history/:cluster_id", s.getPolicyHistory)
+		v1.GET("/preprocess-rules", s.getPreprocessRules)
+		v1.PUT("/preprocess-rules", s.updatePreprocessRules)
+		v1.POST("/reembed", s.reEmbed)
+		v1.GET("/recluster", s.getReclusterReport)
+		v1.POST("/metrics", s.ingestMetrics)
+		v1.GET("/gateway-metrics", s.getGatewayMetrics)
+		v1.GET("/gateway-metrics/:cluster_id", s.getGatewayMetrics)
+		v1.GET("/gateway-metrics/:cluster_id/breakers", s.getBreakerHistory)
+		v1.GET("/canary", s.listCanaryVerdicts)
+		v1.GET("/canary/:route", s.getCanaryVerdict)
+	}
+
+	admin := s.router.Group("/admin", s.authenticate())
+	{
+		admin.GET("/clusters/:id/explain", s.explainIncident)
+		admin.GET("/export/:kind", s.exportData)
+		admin.GET("/calibration", s.getThresholdCalibration)
+		admin.POST("/calibration", s.calibrateThreshold)
+	}
+}
+
+// SetEmbeddingService 设置向量化服务，用于运行时调整预处理规则
+func (s *Server) SetEmbeddingService(embed interfaces.EmbeddingService) {
+	s.embed = embed
+}
+
+// SetEffectivenessReporter 设置策略效果评估器，未设置时报告接口返回503
+func (s *Server) SetEffectivenessReporter(reports interfaces.PolicyEffectivenessReporter) {
+	s.reports = reports
+}
+
+// SetPolicyTemplates 设置策略模板库，未设置时模板接口返回503
+func (s *Server) SetPolicyTemplates(templates interfaces.PolicyTemplateProvider) {
+	s.policyTemplates = templates
+}
+
+// SetEscalationReporter 设置策略升级事件来源，未设置时升级事件接口返回503
+func (s *Server) SetEscalationReporter(escalations interfaces.PolicyEscalationReporter) {
+	s.escalations = escalations
+}
+
+// SetGatewayMetrics 设置网关计数快照存储，未设置时快照接入接口返回503
+func (s *Server) SetGatewayMetrics(store interfaces.GatewayMetricsStore) {
+	s.gatewayMetrics = store
+}
+
+// SetCanaryReporter 设置金丝雀分析，未设置时金丝雀接口返回503
+func (s *Server) SetCanaryReporter(canary interfaces.CanaryReporter) {
+	s.canary = canary
+}
+
+// SetNarrator 设置簇事件说明生成，未设置时事件说明接口返回503
+func (s *Server) SetNarrator(narrator interfaces.IncidentNarrator) {
+	s.narrator = narrator
+}
+
+// SetPolicyAuditor 设置策略巡检，未设置时巡检结果接口返回503
+func (s *Server) SetPolicyAuditor(audit interfaces.PolicyAuditReporter) {
+	s.audit = audit
+}
+
+// SetPolicyHistory 设置策略历史，未设置时策略历史接口返回503
+func (s *Server) SetPolicyHistory(history interfaces.PolicyHistoryReporter) {
+	s.history = history
+}
+
+// SetScheduler 设置控制面任务调度，设置后健康检查返回各周期任务的执行状态
+func (s *Server) SetScheduler(jobs interfaces.JobScheduler) {
+	s.jobs = jobs
+}
+
+// health 健康检查，设置任务调度时附带各周期任务的最近一次和下一次执行时间
+func (s *Server) health(c *gin.Context) {
+	response := gin.H{"status": "healthy"}
+	if s.jobs != nil {
+		response["jobs"] = s.jobs.Jobs()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Start 启动HTTP服务
+func (s *Server) Start() error {
+	if len(s.config.APIKeys) == 0 {
+		log.Println("Warning: no control plane API keys configured, authenticated endpoints will reject all requests")
+	}
+
+	s.ingest.start()
+
+	s.server = &http.Server{
+		Addr:        net.JoinHostPort(strings.Trim(s.config.Host, "[]"), strconv.Itoa(s.config.Port)),
+		Handler:     s.router,
+		ReadTimeout: 30 * time.Second,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Control plane API server error: %v", err)
+		}
+	}()
+
+	if s.grpcAdmin != nil {
+		if err := s.grpcAdmin.Start(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Control plane API started on %s", s.server.Addr)
+	return nil
+}
+
+// Stop 停止HTTP服务，处理完已接收的事件
+func (s *Server) Stop() error {
+	if s.grpcAdmin != nil {
+		s.grpcAdmin.Stop()
+	}
+
+	if s.server != nil {
+		// 等待进行中的请求完成，避免向已关闭的接入队列写入
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.server.Shutdown(ctx); err != nil {
+			log.Printf("Failed to shutdown control plane API server: %v", err)
+		}
+	}
+	s.wg.Wait()
+
+	s.ingest.stop()
+
+	log.Println("Control plane API stopped")
+	return nil
+}
+
+// components gRPC健康检查的组件，可选组件只在设置时列出
+func (s *Server) components() map[string]interface{} {
+	components := map[string]interface{}{
+		"clustering_engine": s.engine,
+	}
+	if s.store != nil {
+		components["config_store"] = s.store
+	}
+	if s.jobs != nil {
+		components["scheduler"] = s.jobs
+	}
+	return components
+}
+
+// Router 获取HTTP路由，供测试和嵌入使用
+func (s *Server) Router() *gin.Engine {
+	return s.router
+}
+
+// authenticate API密钥认证，支持Authorization: Bearer和X-API-Key
+func (s *Server) authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if auth := c.GetHeader("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if key == "" || !s.validKey(key) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or missing API key",
+				"code":  "UNAUTHORIZED",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// validKey 常量时间比较API密钥
+func (s *Server) validKey(key string) bool {
+	valid := false
+	for _, expected := range s.config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(expected)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
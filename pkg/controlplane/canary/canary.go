@@ -0,0 +1,362 @@
+package canary
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// observation 归入簇的错误事件
+type observation struct {
+	at        time.Time
+	clusterID string
+}
+
+// Analyzer 金丝雀分析：按路由收集基线版本和金丝雀版本错误事件所属的簇，比较两个版本的簇构成，
+// 质心相近的簇视为同一类错误；网关推送计数快照时同时检查金丝雀错误率是否超出错误预算
+type Analyzer struct {
+	config   types.CanaryConfig
+	routes   map[string]types.CanaryRouteConfig
+	engine   interfaces.ClusteringEngine
+	gateways interfaces.GatewayMetricsStore // 可选，提供按版本的错误率
+	events   map[string][]observation       // 路由名/版本 -> 窗口内的事件，按接收顺序
+	verdicts map[string]*types.CanaryVerdict
+	mutex    sync.Mutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewAnalyzer 创建金丝雀分析，事件需通过clustering.AttachEventObserver接入Observe
+func NewAnalyzer(config *types.CanaryConfig, engine interfaces.ClusteringEngine) (*Analyzer, error) {
+	cfg := *config
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	if cfg.MinEvents <= 0 {
+		cfg.MinEvents = 20
+	}
+	if cfg.MaxNovelShare <= 0 {
+		cfg.MaxNovelShare = 0.2
+	}
+	if cfg.MaxDistance <= 0 {
+		cfg.MaxDistance = 0.5
+	}
+	if cfg.MatchThreshold <= 0 {
+		cfg.MatchThreshold = 0.9
+	}
+	if cfg.ErrorBudget <= 0 {
+		cfg.ErrorBudget = 0.01
+	}
+	if cfg.MaxEvents <= 0 {
+		cfg.MaxEvents = 10000
+	}
+
+	routes := make(map[string]types.CanaryRouteConfig, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		if route.Route == "" {
+			return nil, fmt.Errorf("canary route requires a route name")
+		}
+		if route.Baseline == "" {
+			route.Baseline = "stable"
+		}
+		if route.Canary == "" {
+			route.Canary = "canary"
+		}
+		if route.Baseline == route.Canary {
+			return nil, fmt.Errorf("canary route %s has the same baseline and canary version %s", route.Route, route.Canary)
+		}
+		if _, exists := routes[route.Route]; exists {
+			return nil, fmt.Errorf("duplicate canary route %s", route.Route)
+		}
+		routes[route.Route] = route
+	}
+
+	return &Analyzer{
+		config:   cfg,
+		routes:   routes,
+		engine:   engine,
+		events:   make(map[string][]observation),
+		verdicts: make(map[string]*types.CanaryVerdict),
+		stopCh:   make(chan struct{}),
+	}, nil
+}
+
+// SetGatewayMetrics 设置网关计数快照存储，用于比较版本错误率
+func (a *Analyzer) SetGatewayMetrics(store interfaces.GatewayMetricsStore) {
+	a.gateways = store
+}
+
+// Observe 记录归入簇的错误事件，只保留参与分析的路由上基线和金丝雀版本的事件
+func (a *Analyzer) Observe(event *types.ErrorEvent) {
+	route, ok := a.routes[event.Route]
+	if !ok || event.ClusterID == "" || (event.Version != route.Baseline && event.Version != route.Canary) {
+		return
+	}
+
+	key := versionKey(event.Route, event.Version)
+	a.mutex.Lock()
+	events := append(a.events[key], observation{at: time.Now(), clusterID: event.ClusterID})
+	if len(events) > a.config.MaxEvents {
+		events = events[len(events)-a.config.MaxEvents:]
+	}
+	a.events[key] = events
+	a.mutex.Unlock()
+}
+
+// Analyze 分析路由并保存结论
+func (a *Analyzer) Analyze(routeName string) (*types.CanaryVerdict, error) {
+	route, ok := a.routes[routeName]
+	if !ok {
+		return nil, fmt.Errorf("route %s is not configured for canary analysis", routeName)
+	}
+
+	now := time.Now()
+	baseline, baselineTotal := a.composition(versionKey(route.Route, route.Baseline), now)
+	canary, canaryTotal := a.composition(versionKey(route.Route, route.Canary), now)
+
+	verdict := &types.CanaryVerdict{
+		Route:          route.Route,
+		Baseline:       route.Baseline,
+		Canary:         route.Canary,
+		BaselineEvents: baselineTotal,
+		CanaryEvents:   canaryTotal,
+		Window:         a.config.Window.String(),
+		AnalyzedAt:     now,
+	}
+	failed := false
+
+	// 错误预算：金丝雀错误率相对基线的增量
+	budgetChecked := false
+	if a.gateways != nil {
+		baselineRate, ok1 := a.errorRate(route.Route, route.Baseline)
+		canaryRate, ok2 := a.errorRate(route.Route, route.Canary)
+		if ok1 && ok2 {
+			budgetChecked = true
+			verdict.BaselineErrorRate = &baselineRate
+			verdict.CanaryErrorRate = &canaryRate
+			if canaryRate-baselineRate > a.config.ErrorBudget {
+				failed = true
+				verdict.Reasons = append(verdict.Reasons, fmt.Sprintf(
+					"canary error rate %.4f exceeds baseline %.4f by more than the error budget %.4f",
+					canaryRate, baselineRate, a.config.ErrorBudget))
+			}
+		}
+	}
+
+	// 簇构成：金丝雀事件足够时比较
+	compared := false
+	if canaryTotal >= int64(a.config.MinEvents) {
+		compared = true
+		mapped, novel := a.matchClusters(canary, baseline)
+		verdict.Distance = distance(baseline, baselineTotal, mapped, canaryTotal)
+
+		var novelCount int64
+		for _, share := range novel {
+			novelCount += share.Count
+		}
+		verdict.NovelShare = float64(novelCount) / float64(canaryTotal)
+		verdict.NovelClusters = novel
+
+		if verdict.NovelShare > a.config.MaxNovelShare {
+			failed = true
+			verdict.Reasons = append(verdict.Reasons, fmt.Sprintf(
+				"%.0f%% of canary errors fall in %d clusters not seen in baseline (limit %.0f%%)",
+				verdict.NovelShare*100, len(novel), a.config.MaxNovelShare*100))
+		}
+		if baselineTotal > 0 && verdict.Distance > a.config.MaxDistance {
+			failed = true
+			verdict.Reasons = append(verdict.Reasons, fmt.Sprintf(
+				"error cluster distribution differs from baseline by %.2f (limit %.2f)",
+				verdict.Distance, a.config.MaxDistance))
+		}
+	}
+
+	switch {
+	case failed:
+		verdict.Verdict = types.CanaryFail
+	case compared || budgetChecked:
+		verdict.Verdict = types.CanaryPass
+	default:
+		verdict.Verdict = types.CanaryInconclusive
+		verdict.Reasons = append(verdict.Reasons, fmt.Sprintf(
+			"only %d canary error events (need %d) and no gateway error rates", canaryTotal, a.config.MinEvents))
+	}
+
+	a.mutex.Lock()
+	previous := a.verdicts[route.Route]
+	a.verdicts[route.Route] = verdict
+	a.mutex.Unlock()
+
+	if previous != nil && previous.Verdict != verdict.Verdict {
+		log.Printf("Canary verdict for route %s changed: %s -> %s", route.Route, previous.Verdict, verdict.Verdict)
+	}
+	return verdict, nil
+}
+
+// Verdicts 获取各路由最近一次分析的结论，按路由名排序
+func (a *Analyzer) Verdicts() []types.CanaryVerdict {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	result := make([]types.CanaryVerdict, 0, len(a.verdicts))
+	for _, verdict := range a.verdicts {
+		result = append(result, *verdict)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Route < result[j].Route
+	})
+	return result
+}
+
+// Start 启动定期分析
+func (a *Analyzer) Start() error {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				for name := range a.routes {
+					if _, err := a.Analyze(name); err != nil {
+						log.Printf("Canary analysis failed for route %s: %v", name, err)
+					}
+				}
+			case <-a.stopCh:
+				return
+			}
+		}
+	}()
+
+	log.Printf("Canary analysis started (routes=%d, window=%v)", len(a.routes), a.config.Window)
+	return nil
+}
+
+// Stop 停止定期分析
+func (a *Analyzer) Stop() error {
+	close(a.stopCh)
+	a.wg.Wait()
+	return nil
+}
+
+// composition 统计窗口内各簇的事件数，同时清理窗口外的事件
+func (a *Analyzer) composition(key string, now time.Time) (map[string]int64, int64) {
+	from := now.Add(-a.config.Window)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	events := a.events[key]
+	start := sort.Search(len(events), func(i int) bool {
+		return !events[i].at.Before(from)
+	})
+	events = events[start:]
+	a.events[key] = events
+
+	counts := make(map[string]int64)
+	for _, event := range events {
+		counts[event.clusterID]++
+	}
+	return counts, int64(len(events))
+}
+
+// errorRate 版本在窗口内的错误率（错误数/请求数）
+func (a *Analyzer) errorRate(route, version string) (float64, bool) {
+	rates, ok := a.gateways.VersionRates(route, version, a.config.Window)
+	if !ok || rates.Requests <= 0 {
+		return 0, false
+	}
+	return rates.Errors / rates.Requests, true
+}
+
+// matchClusters 将金丝雀的簇映射到基线的簇：同一簇直接对应，否则取质心最相近且达到阈值的基线簇，
+// 返回映射后的簇分布和未能对应的新簇（按事件数降序）
+func (a *Analyzer) matchClusters(canary, baseline map[string]int64) (map[string]int64, []types.CanaryClusterShare) {
+	var total int64
+	for _, n := range canary {
+		total += n
+	}
+
+	centroids := make(map[string][]float32, len(baseline))
+	for clusterID := range baseline {
+		if cluster, err := a.engine.GetCluster(clusterID); err == nil {
+			centroids[clusterID] = cluster.Centroid
+		}
+	}
+
+	mapped := make(map[string]int64, len(canary))
+	novel := make([]types.CanaryClusterShare, 0)
+	for clusterID, n := range canary {
+		if baseline[clusterID] > 0 {
+			mapped[clusterID] += n
+			continue
+		}
+
+		cluster, err := a.engine.GetCluster(clusterID)
+		if err == nil && len(cluster.Centroid) > 0 {
+			best, bestSimilarity := "", 0.0
+			for baselineID, centroid := range centroids {
+				if similarity := utils.CosineSimilarity(cluster.Centroid, centroid); similarity > bestSimilarity {
+					best, bestSimilarity = baselineID, similarity
+				}
+			}
+			if best != "" && bestSimilarity >= a.config.MatchThreshold {
+				mapped[best] += n
+				continue
+			}
+		}
+
+		share := types.CanaryClusterShare{ClusterID: clusterID, Count: n, Share: float64(n) / float64(total)}
+		if err == nil {
+			share.Description = cluster.Description
+		}
+		novel = append(novel, share)
+		mapped[clusterID] += n
+	}
+
+	sort.Slice(novel, func(i, j int) bool {
+		if novel[i].Count != novel[j].Count {
+			return novel[i].Count > novel[j].Count
+		}
+		return novel[i].ClusterID < novel[j].ClusterID
+	})
+	return mapped, novel
+}
+
+// distance 两个簇分布的总变差距离
+func distance(baseline map[string]int64, baselineTotal int64, canary map[string]int64, canaryTotal int64) float64 {
+	if baselineTotal == 0 || canaryTotal == 0 {
+		if baselineTotal == canaryTotal {
+			return 0
+		}
+		return 1
+	}
+
+	sum := 0.0
+	for clusterID, n := range baseline {
+		sum += math.Abs(float64(n)/float64(baselineTotal) - float64(canary[clusterID])/float64(canaryTotal))
+	}
+	for clusterID, n := range canary {
+		if _, exists := baseline[clusterID]; !exists {
+			sum += float64(n) / float64(canaryTotal)
+		}
+	}
+	return sum / 2
+}
+
+// versionKey 路由版本的键
+func versionKey(route, version string) string {
+	return route + "/" + version
+}
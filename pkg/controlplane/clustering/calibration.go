@@ -0,0 +1,310 @@
+package clustering
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// calibrationConfig 填充阈值校准配置的默认值
+func calibrationConfig(config *types.ThresholdCalibrationConfig) types.ThresholdCalibrationConfig {
+	cfg := *config
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.SamplePairs <= 0 {
+		cfg.SamplePairs = 500
+	}
+	if cfg.MinPairs <= 0 {
+		cfg.MinPairs = 20
+	}
+	if cfg.Step <= 0 {
+		cfg.Step = 0.01
+	}
+	if cfg.MinThreshold <= 0 {
+		cfg.MinThreshold = 0.5
+	}
+	if cfg.MaxThreshold <= 0 || cfg.MaxThreshold > 1 {
+		cfg.MaxThreshold = 0.99
+	}
+	if cfg.MaxThreshold < cfg.MinThreshold {
+		cfg.MaxThreshold = cfg.MinThreshold
+	}
+	if cfg.MaxChange == 0 {
+		cfg.MaxChange = 0.05
+	}
+	return cfg
+}
+
+// similarityThreshold 服务生效的相似度阈值：服务校准阈值、全局校准阈值、配置阈值依次回落
+func (ce *clusteringEngine) similarityThreshold(service string) float64 {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+	return ce.thresholdLocked(service)
+}
+
+// thresholdLocked 同similarityThreshold，调用方需持有锁
+func (ce *clusteringEngine) thresholdLocked(service string) float64 {
+	if threshold, exists := ce.thresholds[service]; exists && service != "" {
+		return threshold
+	}
+	if threshold, exists := ce.thresholds[types.CalibrationScopeGlobal]; exists {
+		return threshold
+	}
+	return ce.config.SimilarityThreshold
+}
+
+// CalibrateThresholds 按全局和各服务抽取同簇、异簇向量对，计算各候选阈值的精确率和召回率，推荐F1最高的阈值；
+// apply为true时将推荐阈值应用到后续事件的归簇判断，每次调整不超过MaxChange
+func (ce *clusteringEngine) CalibrateThresholds(apply bool) ([]*types.ThresholdCalibration, error) {
+	cfg := ce.calibration
+
+	// 读锁下按范围收集簇成员，向量读取和统计在锁外进行
+	ce.mutex.RLock()
+	scopes := map[string]map[string][]string{types.CalibrationScopeGlobal: {}}
+	for clusterID, cluster := range ce.clusters {
+		for _, memberID := range cluster.Members {
+			scopes[types.CalibrationScopeGlobal][clusterID] = append(scopes[types.CalibrationScopeGlobal][clusterID], memberID)
+
+			record, exists := ce.records[memberID]
+			if !exists || record.service == "" {
+				continue
+			}
+			if scopes[record.service] == nil {
+				scopes[record.service] = make(map[string][]string)
+			}
+			scopes[record.service][clusterID] = append(scopes[record.service][clusterID], memberID)
+		}
+	}
+	current := make(map[string]float64, len(scopes))
+	for scope := range scopes {
+		current[scope] = ce.thresholdLocked(scope)
+	}
+	ce.mutex.RUnlock()
+
+	names := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		names = append(names, scope)
+	}
+	sort.Strings(names)
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	vectors := make(map[string][]float32)
+	results := make([]*types.ThresholdCalibration, 0, len(names))
+	for _, scope := range names {
+		same, diff := ce.samplePairs(scopes[scope], cfg.SamplePairs, rng, vectors)
+		result := &types.ThresholdCalibration{
+			Scope:          scope,
+			Current:        current[scope],
+			SamePairs:      len(same),
+			DiffPairs:      len(diff),
+			SameSimilarity: mean(same),
+			DiffSimilarity: mean(diff),
+			Time:           time.Now(),
+		}
+		if len(same) < cfg.MinPairs || len(diff) < cfg.MinPairs {
+			result.Reason = fmt.Sprintf("not enough pairs: %d same-cluster, %d different-cluster, need %d",
+				len(same), len(diff), cfg.MinPairs)
+		} else {
+			result.Curve = calibrationCurve(same, diff, &cfg)
+			result.Recommended = recommendThreshold(result.Curve)
+		}
+		results = append(results, result)
+	}
+
+	ce.mutex.Lock()
+	for _, result := range results {
+		if apply && result.Recommended > 0 {
+			ce.applyThreshold(result, cfg.MaxChange)
+		}
+		ce.calibrations[result.Scope] = result
+	}
+	ce.mutex.Unlock()
+
+	return results, nil
+}
+
+// ThresholdCalibrations 各范围最近一次的校准结果，按范围排序，全局在前
+func (ce *clusteringEngine) ThresholdCalibrations() []*types.ThresholdCalibration {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+
+	results := make([]*types.ThresholdCalibration, 0, len(ce.calibrations))
+	for _, result := range ce.calibrations {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Scope < results[j].Scope
+	})
+	return results
+}
+
+// scheduledCalibration 定期校准，按配置决定是否自动应用
+func (ce *clusteringEngine) scheduledCalibration() error {
+	results, err := ce.CalibrateThresholds(ce.calibration.AutoApply)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Recommended > 0 && !result.Applied {
+			log.Printf("Similarity threshold for scope %s: current %.2f, recommended %.2f", result.Scope, result.Current, result.Recommended)
+		}
+	}
+	return nil
+}
+
+// applyThreshold 应用推荐阈值，调用方需持有锁
+func (ce *clusteringEngine) applyThreshold(result *types.ThresholdCalibration, maxChange float64) {
+	threshold := result.Recommended
+	if maxChange > 0 {
+		threshold = math.Max(result.Current-maxChange, math.Min(result.Current+maxChange, threshold))
+	}
+	threshold = math.Round(threshold*1e4) / 1e4
+	ce.thresholds[result.Scope] = threshold
+	result.Applied = true
+
+	if threshold != result.Current {
+		log.Printf("Applied similarity threshold %.4f for scope %s (was %.4f, recommended %.4f)",
+			threshold, result.Scope, result.Current, result.Recommended)
+	}
+}
+
+// samplePairs 从范围内的簇成员中随机抽取同簇和异簇向量对，返回两组余弦相似度
+func (ce *clusteringEngine) samplePairs(members map[string][]string, n int, rng *rand.Rand, vectors map[string][]float32) ([]float64, []float64) {
+	clusterIDs := make([]string, 0, len(members))
+	multi := make([]string, 0, len(members))
+	for clusterID, ids := range members {
+		clusterIDs = append(clusterIDs, clusterID)
+		if len(ids) > 1 {
+			multi = append(multi, clusterID)
+		}
+	}
+	sort.Strings(clusterIDs)
+	sort.Strings(multi)
+
+	same := make([]float64, 0, n)
+	diff := make([]float64, 0, n)
+	// 成员的向量可能已从向量库淘汰，尝试次数有上限
+	for attempt := 0; len(multi) > 0 && len(same) < n && attempt < 2*n; attempt++ {
+		ids := members[multi[rng.Intn(len(multi))]]
+		i := rng.Intn(len(ids))
+		j := rng.Intn(len(ids) - 1)
+		if j >= i {
+			j++
+		}
+		if similarity, ok := ce.pairSimilarity(ids[i], ids[j], vectors); ok {
+			same = append(same, similarity)
+		}
+	}
+	for attempt := 0; len(clusterIDs) > 1 && len(diff) < n && attempt < 2*n; attempt++ {
+		a := rng.Intn(len(clusterIDs))
+		b := rng.Intn(len(clusterIDs) - 1)
+		if b >= a {
+			b++
+		}
+		left, right := members[clusterIDs[a]], members[clusterIDs[b]]
+		if similarity, ok := ce.pairSimilarity(left[rng.Intn(len(left))], right[rng.Intn(len(right))], vectors); ok {
+			diff = append(diff, similarity)
+		}
+	}
+	return same, diff
+}
+
+// pairSimilarity 两个成员向量的余弦相似度，向量在一次校准内缓存
+func (ce *clusteringEngine) pairSimilarity(a, b string, vectors map[string][]float32) (float64, bool) {
+	va, ok := ce.memberVector(a, vectors)
+	if !ok {
+		return 0, false
+	}
+	vb, ok := ce.memberVector(b, vectors)
+	if !ok {
+		return 0, false
+	}
+	return utils.CosineSimilarity(va, vb), true
+}
+
+// memberVector 读取成员向量，读取失败的成员记为nil避免重复查询
+func (ce *clusteringEngine) memberVector(id string, vectors map[string][]float32) ([]float32, bool) {
+	if vector, exists := vectors[id]; exists {
+		return vector, vector != nil
+	}
+	vector, err := ce.vectorDB.GetVector(id)
+	if err != nil {
+		vector = nil
+	}
+	vectors[id] = vector
+	return vector, vector != nil
+}
+
+// calibrationCurve 在[MinThreshold, MaxThreshold]内按步长计算各候选阈值的指标，
+// 相似度不低于阈值的同簇对为真阳性，异簇对为假阳性
+func calibrationCurve(same, diff []float64, cfg *types.ThresholdCalibrationConfig) []types.CalibrationPoint {
+	steps := int(math.Floor((cfg.MaxThreshold-cfg.MinThreshold)/cfg.Step + 1e-9))
+	curve := make([]types.CalibrationPoint, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		threshold := math.Round((cfg.MinThreshold+float64(i)*cfg.Step)*1e4) / 1e4
+		tp, fp := countAtLeast(same, threshold), countAtLeast(diff, threshold)
+
+		point := types.CalibrationPoint{
+			Threshold:      threshold,
+			Recall:         float64(tp) / float64(len(same)),
+			FalseMergeRate: float64(fp) / float64(len(diff)),
+		}
+		if tp+fp > 0 {
+			point.Precision = float64(tp) / float64(tp+fp)
+		}
+		if point.Precision+point.Recall > 0 {
+			point.F1 = 2 * point.Precision * point.Recall / (point.Precision + point.Recall)
+		}
+		curve = append(curve, point)
+	}
+	return curve
+}
+
+// recommendThreshold F1最高的阈值；多个相邻阈值F1相同时取区间中点，离两类分布都留有余量
+func recommendThreshold(curve []types.CalibrationPoint) float64 {
+	best := -1
+	for i, point := range curve {
+		if point.F1 > 0 && (best < 0 || point.F1 > curve[best].F1) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0
+	}
+
+	last := best
+	for last+1 < len(curve) && curve[last+1].F1 == curve[best].F1 {
+		last++
+	}
+	return curve[(best+last)/2].Threshold
+}
+
+// countAtLeast 不低于阈值的相似度个数
+func countAtLeast(similarities []float64, threshold float64) int {
+	count := 0
+	for _, similarity := range similarities {
+		if similarity >= threshold {
+			count++
+		}
+	}
+	return count
+}
+
+// mean 平均值，空切片为0
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
@@ -3,9 +3,13 @@ package clustering
 import (
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/llm-aware-gateway/pkg/controlplane/scheduler"
 	"github.com/llm-aware-gateway/pkg/interfaces"
 	"github.com/llm-aware-gateway/pkg/types"
 	"github.com/llm-aware-gateway/pkg/utils"
@@ -16,11 +20,24 @@ type clusteringEngine struct {
 	config            *types.ClusteringConfig
 	embeddingService  interfaces.EmbeddingService
 	vectorDB          interfaces.VectorDB
+	timeSeries        interfaces.TimeSeriesStore // 可选，记录簇事件速率
 	clusters          map[string]*types.Cluster
 	memberToCluster   map[string]string // 成员ID到簇ID的映射
+	signatures        map[string]string // 成员ID到错误特征，用于簇解释
+	records           map[string]*memberRecord // 成员ID到原始特征，用于重新向量化
+	reembedding       int32
+	lastRecluster     *types.ReclusterReport
+	jobLock           interfaces.JobLock // 可选，多实例部署时定期重聚类只在一个实例上执行
+	scheduler         interfaces.JobScheduler
+	stopRecluster     func()
+	observers         []func(event *types.ErrorEvent) // 事件归入簇后回调，如金丝雀分析
+	summarizer        *summarizer                     // 可选，LLM生成簇摘要
+	calibration       types.ThresholdCalibrationConfig
+	thresholds        map[string]float64                      // 校准后应用的相似度阈值，按服务，*为全局
+	calibrations      map[string]*types.ThresholdCalibration // 各范围最近一次的校准结果
+	stopCalibration   func()
 	mutex             sync.RWMutex
 	stopCh            chan struct{}
-	reclusterTicker   *time.Ticker
 }
 
 // NewClusteringEngine 创建聚类引擎
@@ -28,21 +45,41 @@ func NewClusteringEngine(
 	config *types.ClusteringConfig,
 	embeddingService interfaces.EmbeddingService,
 	vectorDB interfaces.VectorDB,
+	timeSeries interfaces.TimeSeriesStore,
 ) interfaces.ClusteringEngine {
-	return &clusteringEngine{
+	ce := &clusteringEngine{
 		config:           config,
 		embeddingService: embeddingService,
 		vectorDB:         vectorDB,
+		timeSeries:       timeSeries,
 		clusters:         make(map[string]*types.Cluster),
 		memberToCluster:  make(map[string]string),
+		signatures:       make(map[string]string),
+		records:          make(map[string]*memberRecord),
+		calibration:      calibrationConfig(&config.Calibration),
+		thresholds:       make(map[string]float64),
+		calibrations:     make(map[string]*types.ThresholdCalibration),
+		scheduler:        scheduler.New(),
 		stopCh:           make(chan struct{}),
 	}
+
+	if config.Summary.Enabled {
+		s, err := newSummarizer(&config.Summary)
+		if err != nil {
+			log.Printf("Cluster summaries disabled: %v", err)
+		} else {
+			ce.summarizer = s
+		}
+	}
+
+	return ce
 }
 
 // ProcessErrorEvent 处理错误事件
 func (ce *clusteringEngine) ProcessErrorEvent(event *types.ErrorEvent) error {
 	// 构建错误特征文本
 	errorText := ce.buildErrorSignature(event)
+	event.RulesetVersion = ce.embeddingService.RulesetVersion()
 
 	// 生成向量
 	vector, err := ce.embeddingService.EmbedText(errorText)
@@ -57,7 +94,7 @@ func (ce *clusteringEngine) ProcessErrorEvent(event *types.ErrorEvent) error {
 	}
 
 	// 判断是否创建新簇或加入现有簇
-	if clusterID == "" || similarity < ce.config.SimilarityThreshold {
+	if clusterID == "" || similarity < ce.similarityThreshold(event.ServiceName) {
 		// 创建新簇
 		newClusterID, err := ce.CreateNewCluster(event, vector)
 		if err != nil {
@@ -75,6 +112,27 @@ func (ce *clusteringEngine) ProcessErrorEvent(event *types.ErrorEvent) error {
 		log.Printf("Added event %s to existing cluster %s (similarity: %.4f)", event.EventID, clusterID, similarity)
 	}
 
+	ce.mutex.Lock()
+	ce.rememberSignature(event.ClusterID, event.EventID, errorText)
+	ce.records[event.EventID] = &memberRecord{signature: errorText, ruleset: event.RulesetVersion, service: event.ServiceName}
+	ce.mutex.Unlock()
+
+	// 记录簇事件速率
+	if ce.timeSeries != nil {
+		at := event.Timestamp
+		if at.IsZero() {
+			at = time.Now()
+		}
+		ce.timeSeries.Record(event.ClusterID, at, 1)
+	}
+
+	ce.mutex.RLock()
+	observers := ce.observers
+	ce.mutex.RUnlock()
+	for _, observe := range observers {
+		observe(event)
+	}
+
 	return nil
 }
 
@@ -148,22 +206,7 @@ func (ce *clusteringEngine) GetCluster(clusterID string) (*types.Cluster, error)
 		return nil, fmt.Errorf("cluster not found: %s", clusterID)
 	}
 
-	// 深拷贝簇信息
-	clusterCopy := &types.Cluster{
-		ID:          cluster.ID,
-		Centroid:    make([]float32, len(cluster.Centroid)),
-		Members:     make([]string, len(cluster.Members)),
-		ErrorCount:  cluster.ErrorCount,
-		CreateTime:  cluster.CreateTime,
-		UpdateTime:  cluster.UpdateTime,
-		Severity:    cluster.Severity,
-		Description: cluster.Description,
-	}
-
-	copy(clusterCopy.Centroid, cluster.Centroid)
-	copy(clusterCopy.Members, cluster.Members)
-
-	return clusterCopy, nil
+	return copyCluster(cluster), nil
 }
 
 // GetAllClusters 获取所有簇
@@ -174,24 +217,69 @@ func (ce *clusteringEngine) GetAllClusters() (map[string]*types.Cluster, error)
 	clusters := make(map[string]*types.Cluster)
 
 	for clusterID, cluster := range ce.clusters {
-		clusterCopy := &types.Cluster{
-			ID:          cluster.ID,
-			Centroid:    make([]float32, len(cluster.Centroid)),
-			Members:     make([]string, len(cluster.Members)),
-			ErrorCount:  cluster.ErrorCount,
-			CreateTime:  cluster.CreateTime,
-			UpdateTime:  cluster.UpdateTime,
-			Severity:    cluster.Severity,
-			Description: cluster.Description,
+		clusters[clusterID] = copyCluster(cluster)
+	}
+
+	return clusters, nil
+}
+
+// ListClusters 按簇ID顺序分页获取簇，cursor为上一页最后一个簇ID，返回下一页的cursor，为空表示已到末尾。
+// 读锁下只收集簇ID，排序在锁外进行，只深拷贝当前页，遍历期间新增或删除的簇不会导致重复返回
+func (ce *clusteringEngine) ListClusters(cursor string, limit int) ([]*types.Cluster, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	ce.mutex.RLock()
+	ids := make([]string, 0)
+	for clusterID := range ce.clusters {
+		if clusterID > cursor {
+			ids = append(ids, clusterID)
 		}
+	}
+	ce.mutex.RUnlock()
+
+	sort.Strings(ids)
 
-		copy(clusterCopy.Centroid, cluster.Centroid)
-		copy(clusterCopy.Members, cluster.Members)
+	clusters := make([]*types.Cluster, 0, limit)
+	next := ""
+
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
 
-		clusters[clusterID] = clusterCopy
+	for _, clusterID := range ids {
+		if len(clusters) == limit {
+			next = clusters[len(clusters)-1].ID
+			break
+		}
+		// 收集ID后被删除的簇跳过
+		if cluster, exists := ce.clusters[clusterID]; exists {
+			clusters = append(clusters, copyCluster(cluster))
+		}
 	}
 
-	return clusters, nil
+	return clusters, next, nil
+}
+
+// copyCluster 深拷贝簇信息
+func copyCluster(cluster *types.Cluster) *types.Cluster {
+	clusterCopy := &types.Cluster{
+		ID:          cluster.ID,
+		Centroid:    make([]float32, len(cluster.Centroid)),
+		Members:     make([]string, len(cluster.Members)),
+		ErrorCount:  cluster.ErrorCount,
+		CreateTime:  cluster.CreateTime,
+		UpdateTime:  cluster.UpdateTime,
+		Severity:    cluster.Severity,
+		Description: cluster.Description,
+		Summary:     cluster.Summary,
+		SummarySize: cluster.SummarySize,
+	}
+
+	copy(clusterCopy.Centroid, cluster.Centroid)
+	copy(clusterCopy.Members, cluster.Members)
+
+	return clusterCopy
 }
 
 // ReCluster 重新聚类
@@ -219,8 +307,12 @@ func (ce *clusteringEngine) ReCluster() error {
 		return nil
 	}
 
-	// 使用K-means算法重新聚类
-	newClusters := ce.kMeansCluster(vectors, eventIDs, len(ce.clusters))
+	// 按配置选择K后使用K-means算法重新聚类
+	k, report := ce.selectK(vectors, len(ce.clusters))
+	newClusters := ce.kMeansCluster(vectors, eventIDs, k)
+	report.Clusters = len(newClusters)
+	report.Time = time.Now()
+	ce.lastRecluster = report
 
 	// 更新簇信息
 	ce.clusters = newClusters
@@ -232,38 +324,141 @@ func (ce *clusteringEngine) ReCluster() error {
 		}
 	}
 
-	log.Printf("Re-clustering completed: %d clusters", len(ce.clusters))
+	log.Printf("Re-clustering completed: %d clusters (k=%d, method=%s)", len(ce.clusters), k, report.Method)
 	return nil
 }
 
+// AttachJobLock 设置任务锁，定期重聚类在获取锁后执行
+func AttachJobLock(engine interfaces.ClusteringEngine, lock interfaces.JobLock) error {
+	ce, ok := engine.(*clusteringEngine)
+	if !ok {
+		return fmt.Errorf("clustering engine does not support job lock")
+	}
+
+	ce.mutex.Lock()
+	ce.jobLock = lock
+	ce.mutex.Unlock()
+	return nil
+}
+
+// AttachEventObserver 注册事件归入簇后的回调，回调在处理事件的协程中同步执行
+func AttachEventObserver(engine interfaces.ClusteringEngine, observe func(event *types.ErrorEvent)) error {
+	ce, ok := engine.(*clusteringEngine)
+	if !ok {
+		return fmt.Errorf("clustering engine does not support event observers")
+	}
+
+	ce.mutex.Lock()
+	ce.observers = append(ce.observers, observe)
+	ce.mutex.Unlock()
+	return nil
+}
+
+// AttachScheduler 设置任务调度，与其他控制面任务共用时可在健康检查接口查看重聚类的执行状态，需在Start之前调用
+func AttachScheduler(engine interfaces.ClusteringEngine, jobs interfaces.JobScheduler) error {
+	ce, ok := engine.(*clusteringEngine)
+	if !ok {
+		return fmt.Errorf("clustering engine does not support job scheduler")
+	}
+
+	ce.mutex.Lock()
+	ce.scheduler = jobs
+	ce.mutex.Unlock()
+	return nil
+}
+
+// scheduledRecluster 定期重聚类，锁被其他实例持有时跳过本轮
+func (ce *clusteringEngine) scheduledRecluster() error {
+	ce.mutex.RLock()
+	lock := ce.jobLock
+	ce.mutex.RUnlock()
+
+	if lock == nil {
+		return ce.ReCluster()
+	}
+	ran, err := lock.Run("recluster", ce.ReCluster)
+	if err == nil && !ran {
+		log.Println("Skipping re-clustering: job is running on another instance")
+	}
+	return err
+}
+
 // Start 启动聚类引擎
 func (ce *clusteringEngine) Start() error {
 	// 启动定期重聚类
-	ce.reclusterTicker = time.NewTicker(ce.config.ReclusteringInterval)
-
-	go func() {
-		for {
-			select {
-			case <-ce.reclusterTicker.C:
-				if err := ce.ReCluster(); err != nil {
-					log.Printf("Re-clustering failed: %v", err)
-				}
-			case <-ce.stopCh:
-				return
-			}
+	ce.mutex.RLock()
+	jobs := ce.scheduler
+	ce.mutex.RUnlock()
+
+	schedule := &ce.config.ReclusteringSchedule
+	stop, err := jobs.Schedule("recluster", scheduler.Spec(schedule, ce.config.ReclusteringInterval),
+		schedule.Jitter, ce.scheduledRecluster)
+	if err != nil {
+		return fmt.Errorf("failed to schedule re-clustering: %v", err)
+	}
+	ce.stopRecluster = stop
+
+	if ce.calibration.Enabled {
+		schedule := &ce.calibration.Schedule
+		stop, err := jobs.Schedule("threshold-calibration", scheduler.Spec(schedule, ce.calibration.Interval),
+			schedule.Jitter, ce.scheduledCalibration)
+		if err != nil {
+			ce.stopRecluster()
+			return fmt.Errorf("failed to schedule threshold calibration: %v", err)
 		}
-	}()
+		ce.stopCalibration = stop
+	}
+
+	if ce.summarizer != nil {
+		go ce.summaryLoop()
+	}
 
 	log.Println("Clustering engine started")
 	return nil
 }
 
+// Health 健康状态，停止后不可用
+func (ce *clusteringEngine) Health() types.ComponentHealth {
+	select {
+	case <-ce.stopCh:
+		return types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "clustering engine stopped"}
+	default:
+	}
+	if atomic.LoadInt32(&ce.reembedding) == 1 {
+		return types.ComponentHealth{Status: types.ComponentHealthy, Message: "re-embedding in progress"}
+	}
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+// Stats 获取聚类统计
+func (ce *clusteringEngine) Stats() map[string]interface{} {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+
+	stats := map[string]interface{}{
+		"clusters":    len(ce.clusters),
+		"members":     len(ce.memberToCluster),
+		"observers":   len(ce.observers),
+		"summaries":   ce.summarizer != nil,
+		"reembedding": atomic.LoadInt32(&ce.reembedding) == 1,
+		"thresholds":  len(ce.thresholds),
+	}
+	if ce.lastRecluster != nil {
+		stats["last_recluster"] = ce.lastRecluster.Time
+		stats["last_recluster_k"] = ce.lastRecluster.K
+	}
+	return stats
+}
+
 // Stop 停止聚类引擎
 func (ce *clusteringEngine) Stop() error {
 	close(ce.stopCh)
 
-	if ce.reclusterTicker != nil {
-		ce.reclusterTicker.Stop()
+	if ce.stopRecluster != nil {
+		ce.stopRecluster()
+	}
+	if ce.stopCalibration != nil {
+		ce.stopCalibration()
 	}
 
 	log.Println("Clustering engine stopped")
@@ -318,6 +513,11 @@ func (ce *clusteringEngine) buildErrorSignature(event *types.ErrorEvent) string
 		event.ErrorMessage,
 	)
 
+	// Schema校验失败等特殊信号与普通错误分开聚类
+	if event.SignalType != "" {
+		signature = "signal:" + event.SignalType + " " + signature
+	}
+
 	// 添加堆栈信息前两帧
 	if len(event.StackTrace) > 0 {
 		signature += " stack:" + event.StackTrace[0]
@@ -344,33 +544,66 @@ func (ce *clusteringEngine) kMeansCluster(vectors [][]float32, eventIDs []string
 		return make(map[string]*types.Cluster)
 	}
 
-	// 简化的K-means实现
-	// 初始化质心
+	centroids, assignments := runKMeans(vectors, k)
+
+	// 构建簇
+	clusters := make(map[string]*types.Cluster)
+	for i := 0; i < k; i++ {
+		clusterID := utils.GenerateClusterID()
+		cluster := &types.Cluster{
+			ID:         clusterID,
+			Centroid:   centroids[i],
+			Members:    []string{},
+			ErrorCount: 0,
+			CreateTime: time.Now(),
+			UpdateTime: time.Now(),
+			Severity:   0.0,
+		}
+
+		// 添加属于这个簇的成员
+		for j := range vectors {
+			if assignments[j] == i {
+				cluster.Members = append(cluster.Members, eventIDs[j])
+				cluster.ErrorCount++
+			}
+		}
+
+		if len(cluster.Members) > 0 {
+			clusters[clusterID] = cluster
+		}
+	}
+
+	return clusters
+}
+
+// runKMeans 简化的K-means实现，返回质心和每个向量所属质心的下标
+func runKMeans(vectors [][]float32, k int) ([][]float32, []int) {
+	// 最远点初始化质心，避免多个初始质心落在同一组内导致不同K的结果不可比
 	centroids := make([][]float32, k)
+	nearest := make([]float64, len(vectors))
+	for i := range nearest {
+		nearest[i] = math.Inf(1)
+	}
+	next := 0
 	for i := 0; i < k; i++ {
 		centroids[i] = make([]float32, len(vectors[0]))
-		copy(centroids[i], vectors[i%len(vectors)])
+		copy(centroids[i], vectors[next])
+
+		farthest := -1.0
+		for j, vector := range vectors {
+			nearest[j] = math.Min(nearest[j], utils.EuclideanDistance(vector, centroids[i]))
+			if nearest[j] > farthest {
+				farthest = nearest[j]
+				next = j
+			}
+		}
 	}
 
 	// 迭代优化
 	maxIterations := 10
 	for iter := 0; iter < maxIterations; iter++ {
 		// 分配点到最近的质心
-		assignments := make([]int, len(vectors))
-		for i, vector := range vectors {
-			bestCluster := 0
-			bestDistance := utils.EuclideanDistance(vector, centroids[0])
-
-			for j := 1; j < k; j++ {
-				distance := utils.EuclideanDistance(vector, centroids[j])
-				if distance < bestDistance {
-					bestDistance = distance
-					bestCluster = j
-				}
-			}
-
-			assignments[i] = bestCluster
-		}
+		assignments := assignNearest(vectors, centroids)
 
 		// 更新质心
 		newCentroids := make([][]float32, k)
@@ -398,43 +631,25 @@ func (ce *clusteringEngine) kMeansCluster(vectors [][]float32, eventIDs []string
 		}
 	}
 
-	// 构建簇
-	clusters := make(map[string]*types.Cluster)
-	for i := 0; i < k; i++ {
-		clusterID := utils.GenerateClusterID()
-		cluster := &types.Cluster{
-			ID:         clusterID,
-			Centroid:   centroids[i],
-			Members:    []string{},
-			ErrorCount: 0,
-			CreateTime: time.Now(),
-			UpdateTime: time.Now(),
-			Severity:   0.0,
-		}
-
-		// 添加属于这个簇的成员
-		for j, vector := range vectors {
-			bestCluster := 0
-			bestDistance := utils.EuclideanDistance(vector, centroids[0])
-
-			for l := 1; l < k; l++ {
-				distance := utils.EuclideanDistance(vector, centroids[l])
-				if distance < bestDistance {
-					bestDistance = distance
-					bestCluster = l
-				}
-			}
+	return centroids, assignNearest(vectors, centroids)
+}
 
-			if bestCluster == i {
-				cluster.Members = append(cluster.Members, eventIDs[j])
-				cluster.ErrorCount++
+// assignNearest 将每个向量分配到欧氏距离最近的质心
+func assignNearest(vectors [][]float32, centroids [][]float32) []int {
+	assignments := make([]int, len(vectors))
+	for i, vector := range vectors {
+		bestCluster := 0
+		bestDistance := utils.EuclideanDistance(vector, centroids[0])
+
+		for j := 1; j < len(centroids); j++ {
+			distance := utils.EuclideanDistance(vector, centroids[j])
+			if distance < bestDistance {
+				bestDistance = distance
+				bestCluster = j
 			}
 		}
 
-		if len(cluster.Members) > 0 {
-			clusters[clusterID] = cluster
-		}
+		assignments[i] = bestCluster
 	}
-
-	return clusters
+	return assignments
 }
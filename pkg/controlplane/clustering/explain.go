@@ -0,0 +1,120 @@
+package clustering
+
+import (
+	"sort"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// maxSignatures 每个簇保留的成员特征数量上限
+const maxSignatures = 1000
+
+// ExplainCluster 解释簇的构成：最近邻簇及相似度，离质心最近/最远的成员特征
+func (ce *clusteringEngine) ExplainCluster(clusterID string, topN int) (*types.ClusterExplanation, error) {
+	if topN <= 0 {
+		topN = 3
+	}
+
+	cluster, err := ce.GetCluster(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &types.ClusterExplanation{
+		Cluster:   cluster,
+		Threshold: ce.similarityThreshold(types.CalibrationScopeGlobal),
+		Neighbors: ce.nearestClusters(cluster, topN),
+	}
+
+	members := ce.memberSimilarities(cluster)
+	explanation.SampledMembers = len(members)
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Similarity > members[j].Similarity
+	})
+
+	for i := 0; i < len(members) && i < topN; i++ {
+		explanation.Central = append(explanation.Central, members[i])
+	}
+	for i := len(members) - 1; i >= 0 && len(explanation.Peripheral) < topN; i-- {
+		if i < topN {
+			break // 成员数不足时不与最中心成员重复
+		}
+		explanation.Peripheral = append(explanation.Peripheral, members[i])
+	}
+
+	return explanation, nil
+}
+
+// nearestClusters 按质心相似度查找最近的簇
+func (ce *clusteringEngine) nearestClusters(cluster *types.Cluster, topN int) []types.ClusterNeighbor {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+
+	neighbors := make([]types.ClusterNeighbor, 0, len(ce.clusters))
+	for id, other := range ce.clusters {
+		if id == cluster.ID || len(other.Centroid) == 0 {
+			continue
+		}
+
+		neighbors = append(neighbors, types.ClusterNeighbor{
+			ClusterID:   id,
+			Similarity:  utils.CosineSimilarity(cluster.Centroid, other.Centroid),
+			Description: other.Description,
+			ErrorCount:  other.ErrorCount,
+		})
+	}
+
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i].Similarity > neighbors[j].Similarity
+	})
+
+	if len(neighbors) > topN {
+		neighbors = neighbors[:topN]
+	}
+	return neighbors
+}
+
+// memberSimilarities 计算成员与质心的相似度，只统计保留了特征的成员
+func (ce *clusteringEngine) memberSimilarities(cluster *types.Cluster) []types.MemberSimilarity {
+	ce.mutex.RLock()
+	signatures := make(map[string]string, len(cluster.Members))
+	for _, memberID := range cluster.Members {
+		if signature, exists := ce.signatures[memberID]; exists {
+			signatures[memberID] = signature
+		}
+	}
+	ce.mutex.RUnlock()
+
+	members := make([]types.MemberSimilarity, 0, len(signatures))
+	for memberID, signature := range signatures {
+		vector, err := ce.vectorDB.GetVector(memberID)
+		if err != nil {
+			continue
+		}
+
+		members = append(members, types.MemberSimilarity{
+			EventID:    memberID,
+			Signature:  signature,
+			Similarity: utils.CosineSimilarity(vector, cluster.Centroid),
+		})
+	}
+
+	return members
+}
+
+// rememberSignature 保存成员特征供解释使用，调用方需持有写锁
+func (ce *clusteringEngine) rememberSignature(clusterID, eventID, signature string) {
+	cluster, exists := ce.clusters[clusterID]
+	if !exists {
+		return
+	}
+
+	// 大簇只保留最早的一批特征，避免内存无限增长
+	if len(cluster.Members) > maxSignatures {
+		return
+	}
+
+	ce.signatures[eventID] = utils.Truncate(signature, 512)
+}
@@ -0,0 +1,181 @@
+package clustering
+
+import (
+	"log"
+	"math"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// maxSilhouetteSamples 计算轮廓系数时的最大采样数，轮廓系数的复杂度为O(n²)
+const maxSilhouetteSamples = 1000
+
+// selectK 按配置选择重聚类的K，fixed沿用当前簇数，自动选择时在[MinK, MaxK]内评估
+func (ce *clusteringEngine) selectK(vectors [][]float32, current int) (int, *types.ReclusterReport) {
+	report := &types.ReclusterReport{
+		Method:  ce.config.KSelection,
+		Vectors: len(vectors),
+	}
+	if report.Method == "" {
+		report.Method = types.KSelectionFixed
+	}
+
+	minK, maxK := ce.kBounds(len(vectors))
+
+	switch report.Method {
+	case types.KSelectionElbow, types.KSelectionSilhouette:
+		if minK < maxK {
+			report.Scores = make(map[int]float64, maxK-minK+1)
+			report.K = ce.autoK(report.Method, vectors, minK, maxK, report.Scores)
+			return report.K, report
+		}
+	case types.KSelectionFixed:
+	default:
+		log.Printf("Unknown k selection method %q, keeping current cluster count", report.Method)
+		report.Method = types.KSelectionFixed
+	}
+
+	// 固定K同样受上下界约束
+	k := current
+	if k < minK {
+		k = minK
+	}
+	if k > maxK {
+		k = maxK
+	}
+	report.K = k
+	return k, report
+}
+
+// kBounds 计算K的上下界，上界不超过向量数和最大簇数
+func (ce *clusteringEngine) kBounds(vectors int) (int, int) {
+	minK := ce.config.MinK
+	if minK <= 0 {
+		minK = 2
+	}
+	maxK := ce.config.MaxK
+	if maxK <= 0 || (ce.config.MaxClusters > 0 && maxK > ce.config.MaxClusters) {
+		maxK = ce.config.MaxClusters
+	}
+	if maxK <= 0 || maxK > vectors {
+		maxK = vectors
+	}
+	if minK > maxK {
+		minK = maxK
+	}
+	return minK, maxK
+}
+
+// autoK 对每个候选K运行K-means并评分
+func (ce *clusteringEngine) autoK(method string, vectors [][]float32, minK, maxK int, scores map[int]float64) int {
+	samples := vectors
+	if method == types.KSelectionSilhouette && len(samples) > maxSilhouetteSamples {
+		// 等间隔采样，保持结果可复现
+		samples = make([][]float32, 0, maxSilhouetteSamples)
+		step := float64(len(vectors)) / maxSilhouetteSamples
+		for i := 0; i < maxSilhouetteSamples; i++ {
+			samples = append(samples, vectors[int(float64(i)*step)])
+		}
+	}
+
+	for k := minK; k <= maxK; k++ {
+		centroids, assignments := runKMeans(samples, k)
+		if method == types.KSelectionElbow {
+			scores[k] = inertia(samples, centroids, assignments)
+		} else {
+			scores[k] = silhouette(samples, assignments, k)
+		}
+	}
+
+	if method == types.KSelectionElbow {
+		return elbow(scores, minK, maxK)
+	}
+
+	best := minK
+	for k := minK; k <= maxK; k++ {
+		if scores[k] > scores[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// inertia 簇内平方和
+func inertia(vectors [][]float32, centroids [][]float32, assignments []int) float64 {
+	var sum float64
+	for i, vector := range vectors {
+		distance := utils.EuclideanDistance(vector, centroids[assignments[i]])
+		sum += distance * distance
+	}
+	return sum
+}
+
+// elbow 取簇内平方和曲线上距首尾连线最远的点作为拐点
+func elbow(scores map[int]float64, minK, maxK int) int {
+	x1, y1 := float64(minK), scores[minK]
+	x2, y2 := float64(maxK), scores[maxK]
+	norm := math.Hypot(x2-x1, y2-y1)
+	if norm == 0 {
+		return minK
+	}
+
+	best, bestDistance := minK, -1.0
+	for k := minK; k <= maxK; k++ {
+		distance := math.Abs((y2-y1)*float64(k)-(x2-x1)*scores[k]+x2*y1-y2*x1) / norm
+		if distance > bestDistance {
+			best, bestDistance = k, distance
+		}
+	}
+	return best
+}
+
+// silhouette 平均轮廓系数，取值[-1, 1]，越大说明簇内越紧密、簇间越分离
+func silhouette(vectors [][]float32, assignments []int, k int) float64 {
+	if len(vectors) < 2 {
+		return 0
+	}
+
+	var total float64
+	for i, vector := range vectors {
+		sums := make([]float64, k)
+		counts := make([]int, k)
+		for j, other := range vectors {
+			if i == j {
+				continue
+			}
+			sums[assignments[j]] += utils.EuclideanDistance(vector, other)
+			counts[assignments[j]]++
+		}
+
+		own := assignments[i]
+		// 单成员簇的轮廓系数定义为0
+		if counts[own] == 0 {
+			continue
+		}
+		a := sums[own] / float64(counts[own])
+
+		b := math.Inf(1)
+		for c := 0; c < k; c++ {
+			if c != own && counts[c] > 0 {
+				b = math.Min(b, sums[c]/float64(counts[c]))
+			}
+		}
+		if math.IsInf(b, 1) {
+			continue
+		}
+
+		if m := math.Max(a, b); m > 0 {
+			total += (b - a) / m
+		}
+	}
+
+	return total / float64(len(vectors))
+}
+
+// ReclusterReport 获取最近一次重聚类的结果
+func (ce *clusteringEngine) ReclusterReport() *types.ReclusterReport {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+	return ce.lastRecluster
+}
@@ -0,0 +1,235 @@
+package clustering
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// pcaIterations 幂迭代求每个主成分的最大迭代次数
+const pcaIterations = 200
+
+// reducer 质心降维：按配置生成投影矩阵并投影质心，控制面内部的聚类仍使用全精度向量
+type reducer struct {
+	config *types.VectorReductionConfig
+	random [][]float32 // random方法按输入维度缓存的投影矩阵
+}
+
+// newReducer 校验降维配置并补全默认值
+func newReducer(config *types.VectorReductionConfig) (*reducer, error) {
+	cfg := *config
+	if cfg.Method == "" {
+		cfg.Method = types.VectorReductionPCA
+	}
+	if cfg.Method != types.VectorReductionPCA && cfg.Method != types.VectorReductionRandom {
+		return nil, fmt.Errorf("unsupported vector reduction method %q", cfg.Method)
+	}
+	if cfg.Dimension < 0 {
+		return nil, fmt.Errorf("vector reduction dimension must not be negative")
+	}
+	if cfg.Dimension == 0 {
+		cfg.Dimension = 128
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = 1
+	}
+	return &reducer{config: &cfg}, nil
+}
+
+// reduce 投影快照中的质心并返回投影矩阵；降维后维度不小于原维度或没有可投影的质心时返回nil，快照保持全精度
+func (r *reducer) reduce(clusters []*types.Cluster, inputDim int) *types.VectorProjection {
+	var centroids [][]float32
+	for _, cluster := range clusters {
+		if len(cluster.Centroid) == inputDim {
+			centroids = append(centroids, cluster.Centroid)
+		}
+	}
+	if len(centroids) == 0 || r.config.Dimension >= inputDim {
+		return nil
+	}
+
+	var matrix [][]float32
+	if r.config.Method == types.VectorReductionRandom {
+		matrix = r.randomMatrix(inputDim)
+	} else {
+		matrix = principalComponents(centroids, r.config.Dimension)
+	}
+	if len(matrix) == 0 {
+		return nil
+	}
+
+	for _, cluster := range clusters {
+		if len(cluster.Centroid) == inputDim {
+			cluster.Centroid = project(matrix, cluster.Centroid)
+		}
+	}
+
+	flat := make([]float32, 0, len(matrix)*inputDim)
+	for _, row := range matrix {
+		flat = append(flat, row...)
+	}
+	return &types.VectorProjection{
+		Method:    r.config.Method,
+		InputDim:  inputDim,
+		OutputDim: len(matrix),
+		Matrix:    utils.EncodeFloat32s(flat),
+	}
+}
+
+// randomMatrix 高斯随机投影矩阵，元素按1/sqrt(k)缩放使投影前后的向量范数在期望上一致；
+// 种子固定，相同维度下各次发布的矩阵相同
+func (r *reducer) randomMatrix(inputDim int) [][]float32 {
+	if len(r.random) > 0 && len(r.random[0]) == inputDim {
+		return r.random
+	}
+
+	rng := rand.New(rand.NewSource(r.config.Seed))
+	scale := 1 / math.Sqrt(float64(r.config.Dimension))
+	matrix := make([][]float32, r.config.Dimension)
+	for i := range matrix {
+		matrix[i] = make([]float32, inputDim)
+		for j := range matrix[i] {
+			matrix[i][j] = float32(rng.NormFloat64() * scale)
+		}
+	}
+	r.random = matrix
+	return matrix
+}
+
+// principalComponents 求质心矩阵（不去均值）的前k个主成分，以保持质心间及与查询向量的点积。
+// 簇数通常远小于向量维度，在簇数×簇数的Gram矩阵上幂迭代求特征向量再映射回原空间；
+// 主成分数不超过质心矩阵的秩
+func principalComponents(centroids [][]float32, k int) [][]float32 {
+	n := len(centroids)
+	gram := make([][]float64, n)
+	for i := range gram {
+		gram[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			gram[i][j] = dot64(centroids[i], centroids[j])
+			gram[j][i] = gram[i][j]
+		}
+	}
+
+	var components [][]float32
+	var largest float64
+	for len(components) < k && len(components) < n {
+		u, lambda := powerIteration(gram, len(components))
+		if lambda <= 0 || (largest > 0 && lambda < largest*1e-9) {
+			break
+		}
+		if largest == 0 {
+			largest = lambda
+		}
+
+		// 主成分 v = Cᵀu / sqrt(λ)，与已有主成分正交化以抵消数值误差
+		dim := len(centroids[0])
+		v := make([]float64, dim)
+		for i, weight := range u {
+			for j, value := range centroids[i] {
+				v[j] += weight * float64(value)
+			}
+		}
+		for _, c := range components {
+			var projection float64
+			for j := range v {
+				projection += v[j] * float64(c[j])
+			}
+			for j := range v {
+				v[j] -= projection * float64(c[j])
+			}
+		}
+		var norm float64
+		for _, x := range v {
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			break
+		}
+		component := make([]float32, dim)
+		for j := range v {
+			component[j] = float32(v[j] / norm)
+		}
+		components = append(components, component)
+
+		// 从Gram矩阵中减去该特征分量
+		for i := range gram {
+			for j := range gram[i] {
+				gram[i][j] -= lambda * u[i] * u[j]
+			}
+		}
+	}
+	return components
+}
+
+// powerIteration 求对称矩阵的最大特征值和单位特征向量，初始向量由序号确定，保证结果可复现
+func powerIteration(matrix [][]float64, index int) ([]float64, float64) {
+	n := len(matrix)
+	u := make([]float64, n)
+	for i := range u {
+		u[i] = 1 + float64((i+index)%7)/10
+	}
+	normalize64(u)
+
+	var lambda float64
+	next := make([]float64, n)
+	for iter := 0; iter < pcaIterations; iter++ {
+		for i := range next {
+			next[i] = 0
+			for j, value := range matrix[i] {
+				next[i] += value * u[j]
+			}
+		}
+		var estimate float64
+		for i := range next {
+			estimate += next[i] * u[i]
+		}
+		if normalize64(next) == 0 {
+			return u, 0
+		}
+		u, next = next, u
+		if math.Abs(estimate-lambda) <= 1e-10*math.Abs(estimate) {
+			lambda = estimate
+			break
+		}
+		lambda = estimate
+	}
+	return u, lambda
+}
+
+// project 用投影矩阵投影向量
+func project(matrix [][]float32, vector []float32) []float32 {
+	projected := make([]float32, len(matrix))
+	for i, row := range matrix {
+		projected[i] = float32(dot64(row, vector))
+	}
+	return projected
+}
+
+// dot64 以float64累加的点积
+func dot64(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// normalize64 原地归一化并返回原范数
+func normalize64(v []float64) float64 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return 0
+	}
+	for i := range v {
+		v[i] /= norm
+	}
+	return norm
+}
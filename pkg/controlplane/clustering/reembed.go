@@ -0,0 +1,230 @@
+package clustering
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// memberRecord 成员的原始错误特征（未经预处理）及生成向量时的规则集版本
+type memberRecord struct {
+	signature string
+	ruleset   string
+	service   string // 校准阈值时按服务划分范围
+}
+
+// ReEmbed 在当前预处理规则下重新向量化所有成员：按原簇重建质心，质心趋同的簇合并到成员更多的簇，
+// 成员重新分配到最近的质心。dryRun为true时只计算结果不修改簇
+func (ce *clusteringEngine) ReEmbed(dryRun bool) (*types.ReEmbedReport, error) {
+	if !atomic.CompareAndSwapInt32(&ce.reembedding, 0, 1) {
+		return nil, fmt.Errorf("re-embedding already in progress")
+	}
+	defer atomic.StoreInt32(&ce.reembedding, 0)
+
+	version := ce.embeddingService.RulesetVersion()
+	report := &types.ReEmbedReport{
+		RulesetVersion: version,
+		DryRun:         dryRun,
+		Remapped:       make(map[string]string),
+		Removed:        make([]string, 0),
+		StartTime:      time.Now(),
+	}
+
+	// 快照成员特征，向量化在锁外进行
+	ce.mutex.RLock()
+	ids := make([]string, 0, len(ce.records))
+	texts := make([]string, 0, len(ce.records))
+	for id, record := range ce.records {
+		if _, exists := ce.memberToCluster[id]; !exists {
+			continue
+		}
+		ids = append(ids, id)
+		texts = append(texts, record.signature)
+		if record.ruleset != version {
+			report.Stale++
+		}
+	}
+	ce.mutex.RUnlock()
+
+	log.Printf("Re-embedding %d members under ruleset %s (%d stale, dry_run=%v)", len(ids), version, report.Stale, dryRun)
+
+	vectors, err := ce.embeddingService.EmbedBatch(texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-embed signatures: %v", err)
+	}
+	report.Members = len(ids)
+
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+
+	// 任务期间被重聚类移除的成员不再处理
+	original := make(map[string]string, len(ids))
+	for i := 0; i < len(ids); i++ {
+		clusterID, exists := ce.memberToCluster[ids[i]]
+		if !exists || ce.clusters[clusterID] == nil {
+			continue
+		}
+		original[ids[i]] = clusterID
+	}
+
+	centroids := rebuildCentroids(ids, vectors, original)
+	survivors := ce.mergeConvergedClusters(centroids, report.Remapped)
+
+	// 成员重新分配到最近的保留质心
+	assignment := make(map[string]string, len(original))
+	for i, id := range ids {
+		if _, exists := original[id]; !exists {
+			continue
+		}
+		best, bestSimilarity := "", -2.0
+		for _, clusterID := range survivors {
+			if similarity := utils.CosineSimilarity(vectors[i], centroids[clusterID]); similarity > bestSimilarity {
+				best, bestSimilarity = clusterID, similarity
+			}
+		}
+		assignment[id] = best
+		if best != original[id] {
+			report.Moved++
+		}
+	}
+
+	// 最终质心按新分配重新计算
+	centroids = rebuildCentroids(ids, vectors, assignment)
+	for _, clusterID := range survivors {
+		if _, exists := centroids[clusterID]; !exists {
+			report.Removed = append(report.Removed, clusterID)
+		}
+	}
+	sort.Strings(report.Removed)
+
+	if !dryRun {
+		ce.applyReEmbed(ids, vectors, assignment, centroids, report, version)
+	}
+
+	report.EndTime = time.Now()
+	log.Printf("Re-embedding finished: %d moved, %d clusters merged, %d removed (dry_run=%v)",
+		report.Moved, len(report.Remapped), len(report.Removed), dryRun)
+	return report, nil
+}
+
+// applyReEmbed 写入新向量并更新簇成员和质心，调用方需持有写锁
+func (ce *clusteringEngine) applyReEmbed(ids []string, vectors [][]float32, assignment map[string]string,
+	centroids map[string][]float32, report *types.ReEmbedReport, version string) {
+	// 旧簇ID解析到保留的簇，任务期间新加入的成员沿用原簇
+	resolve := func(clusterID string) string {
+		if target, merged := report.Remapped[clusterID]; merged {
+			return target
+		}
+		return clusterID
+	}
+
+	members := make(map[string][]string)
+	errorCounts := make(map[string]int64)
+	for clusterID, cluster := range ce.clusters {
+		target := resolve(clusterID)
+		errorCounts[target] += cluster.ErrorCount
+		for _, memberID := range cluster.Members {
+			destination := target
+			if newID, exists := assignment[memberID]; exists && newID != target {
+				errorCounts[target]--
+				errorCounts[newID]++
+				destination = newID
+			}
+			members[destination] = append(members[destination], memberID)
+			ce.memberToCluster[memberID] = destination
+		}
+	}
+
+	for clusterID, cluster := range ce.clusters {
+		if len(members[clusterID]) == 0 {
+			delete(ce.clusters, clusterID)
+			continue
+		}
+		cluster.Members = members[clusterID]
+		cluster.ErrorCount = errorCounts[clusterID]
+		if centroid, exists := centroids[clusterID]; exists {
+			cluster.Centroid = centroid
+		}
+		cluster.UpdateTime = time.Now()
+	}
+
+	for i, id := range ids {
+		if _, exists := assignment[id]; !exists {
+			continue
+		}
+		if err := ce.vectorDB.AddVector(id, vectors[i]); err != nil {
+			log.Printf("Failed to store re-embedded vector %s: %v", id, err)
+		}
+		if record, exists := ce.records[id]; exists {
+			record.ruleset = version
+		}
+	}
+}
+
+// mergeConvergedClusters 新质心相似度达到阈值的簇合并到成员更多的簇，返回保留的簇ID
+func (ce *clusteringEngine) mergeConvergedClusters(centroids map[string][]float32, remapped map[string]string) []string {
+	order := make([]string, 0, len(centroids))
+	for clusterID := range centroids {
+		order = append(order, clusterID)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		ci, cj := len(ce.clusters[order[i]].Members), len(ce.clusters[order[j]].Members)
+		if ci != cj {
+			return ci > cj
+		}
+		return order[i] < order[j]
+	})
+
+	survivors := make([]string, 0, len(order))
+	for _, clusterID := range order {
+		merged := false
+		for _, survivor := range survivors {
+			if utils.CosineSimilarity(centroids[clusterID], centroids[survivor]) >= ce.thresholdLocked(types.CalibrationScopeGlobal) {
+				remapped[clusterID] = survivor
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			survivors = append(survivors, clusterID)
+		}
+	}
+
+	return survivors
+}
+
+// rebuildCentroids 按成员分配计算各簇质心
+func rebuildCentroids(ids []string, vectors [][]float32, assignment map[string]string) map[string][]float32 {
+	sums := make(map[string][]float32)
+	counts := make(map[string]int)
+	for i, id := range ids {
+		clusterID, exists := assignment[id]
+		if !exists {
+			continue
+		}
+		sum, exists := sums[clusterID]
+		if !exists {
+			sum = make([]float32, len(vectors[i]))
+			sums[clusterID] = sum
+		}
+		if len(sum) != len(vectors[i]) {
+			continue
+		}
+		for j, value := range vectors[i] {
+			sum[j] += value
+		}
+		counts[clusterID]++
+	}
+
+	for clusterID, sum := range sums {
+		for j := range sum {
+			sum[j] /= float32(counts[clusterID])
+		}
+	}
+	return sums
+}
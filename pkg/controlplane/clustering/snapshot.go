@@ -0,0 +1,160 @@
+package clustering
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// SnapshotPublisher 定期将簇快照连同嵌入模型信息发布到配置中心，供网关加载
+type SnapshotPublisher struct {
+	engine   interfaces.ClusteringEngine
+	embedder interfaces.EmbeddingService
+	store    interfaces.ConfigStore
+	interval time.Duration
+	reducer  *reducer // 未开启质心降维时为nil
+	last     []byte   // 上次发布的簇内容，不含生成时间
+	mutex    sync.Mutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSnapshotPublisher 创建簇快照发布器
+func NewSnapshotPublisher(engine interfaces.ClusteringEngine, embedder interfaces.EmbeddingService, store interfaces.ConfigStore, interval time.Duration) *SnapshotPublisher {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &SnapshotPublisher{
+		engine:   engine,
+		embedder: embedder,
+		store:    store,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetReduction 开启质心降维，快照携带投影矩阵和降维后的质心，需在Start之前调用
+func (sp *SnapshotPublisher) SetReduction(config *types.VectorReductionConfig) error {
+	if !config.Enabled {
+		sp.reducer = nil
+		return nil
+	}
+	reducer, err := newReducer(config)
+	if err != nil {
+		return err
+	}
+	sp.reducer = reducer
+	return nil
+}
+
+// Start 立即发布一次并开始定期发布
+func (sp *SnapshotPublisher) Start() error {
+	if err := sp.Publish(); err != nil {
+		log.Printf("Failed to publish cluster snapshot: %v", err)
+	}
+
+	sp.wg.Add(1)
+	go sp.publishLoop()
+
+	log.Printf("Cluster snapshot publisher started (interval=%v)", sp.interval)
+	return nil
+}
+
+// Stop 停止发布
+func (sp *SnapshotPublisher) Stop() error {
+	close(sp.stopCh)
+	sp.wg.Wait()
+	return nil
+}
+
+// publishLoop 定期发布
+func (sp *SnapshotPublisher) publishLoop() {
+	defer sp.wg.Done()
+
+	ticker := time.NewTicker(sp.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sp.Publish(); err != nil {
+				log.Printf("Failed to publish cluster snapshot: %v", err)
+			}
+		case <-sp.stopCh:
+			return
+		}
+	}
+}
+
+// Publish 构建并发布簇快照，簇和嵌入模型均未变化时跳过
+func (sp *SnapshotPublisher) Publish() error {
+	snapshot, err := sp.Build()
+	if err != nil {
+		return err
+	}
+
+	generatedAt := snapshot.GeneratedAt
+	snapshot.GeneratedAt = time.Time{}
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster snapshot: %v", err)
+	}
+
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if sp.last != nil && string(sp.last) == string(content) {
+		return nil
+	}
+
+	snapshot.GeneratedAt = generatedAt
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster snapshot: %v", err)
+	}
+	if err := sp.store.Put(types.ClusterSnapshotKey, string(data)); err != nil {
+		return fmt.Errorf("failed to put cluster snapshot: %v", err)
+	}
+	sp.last = content
+
+	reduced := snapshot.Embedding.Dimension
+	if snapshot.Projection != nil {
+		reduced = snapshot.Projection.OutputDim
+	}
+	log.Printf("Published cluster snapshot with %d clusters (model=%s/%s, dim=%d, published_dim=%d)",
+		len(snapshot.Clusters), snapshot.Embedding.Model, snapshot.Embedding.Version, snapshot.Embedding.Dimension, reduced)
+	return nil
+}
+
+// Build 构建簇快照，不含成员列表，按簇ID排序；开启降维时质心为投影后的向量
+func (sp *SnapshotPublisher) Build() (*types.ClusterSnapshot, error) {
+	clusters, err := sp.engine.GetAllClusters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clusters: %v", err)
+	}
+
+	snapshot := &types.ClusterSnapshot{
+		Embedding:      sp.embedder.ModelInfo(),
+		RulesetVersion: sp.embedder.RulesetVersion(),
+		Clusters:       make([]*types.Cluster, 0, len(clusters)),
+		GeneratedAt:    time.Now(),
+	}
+	for _, cluster := range clusters {
+		c := *cluster
+		c.Members = nil
+		snapshot.Clusters = append(snapshot.Clusters, &c)
+	}
+	sort.Slice(snapshot.Clusters, func(i, j int) bool {
+		return snapshot.Clusters[i].ID < snapshot.Clusters[j].ID
+	})
+	if sp.reducer != nil {
+		snapshot.Projection = sp.reducer.reduce(snapshot.Clusters, snapshot.Embedding.Dimension)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,162 @@
+package clustering
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/llmclient"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// summarySystemPrompt 生成簇摘要的系统提示词
+const summarySystemPrompt = "You are an SRE assistant. Given representative error signatures from one cluster of " +
+	"LLM gateway errors, explain the most likely root cause in two or three sentences. " +
+	"Be specific and do not repeat the raw signatures."
+
+// summarizer 簇摘要生成
+type summarizer struct {
+	config types.ClusterSummaryConfig
+	client *llmclient.Client
+}
+
+// newSummarizer 创建簇摘要生成
+func newSummarizer(config *types.ClusterSummaryConfig) (*summarizer, error) {
+	cfg := *config
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 20
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 5
+	}
+	if cfg.RefreshGrowth == 0 {
+		cfg.RefreshGrowth = 2
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+
+	client, err := llmclient.New(&cfg.LLM)
+	if err != nil {
+		return nil, err
+	}
+	return &summarizer{config: cfg, client: client}, nil
+}
+
+// needsSummary 簇达到规模且没有摘要，或成员数增长到上次生成时的RefreshGrowth倍
+func (s *summarizer) needsSummary(cluster *types.Cluster) bool {
+	size := len(cluster.Members)
+	if size < s.config.MinSize {
+		return false
+	}
+	if cluster.Summary == "" {
+		return true
+	}
+	return s.config.RefreshGrowth > 1 && float64(size) >= float64(cluster.SummarySize)*s.config.RefreshGrowth
+}
+
+// SummarizeClusters 为需要摘要的簇调用LLM生成根因摘要，返回生成的数量；未启用时返回错误
+func (ce *clusteringEngine) SummarizeClusters() (int, error) {
+	if ce.summarizer == nil {
+		return 0, fmt.Errorf("cluster summaries are not enabled")
+	}
+
+	ce.mutex.RLock()
+	candidates := make([]*types.Cluster, 0)
+	for _, cluster := range ce.clusters {
+		if ce.summarizer.needsSummary(cluster) {
+			candidates = append(candidates, copyCluster(cluster))
+		}
+	}
+	ce.mutex.RUnlock()
+
+	generated := 0
+	var lastErr error
+	for _, cluster := range candidates {
+		summary, err := ce.summarizeCluster(cluster)
+		if err != nil {
+			log.Printf("Failed to summarize cluster %s: %v", cluster.ID, err)
+			lastErr = err
+			continue
+		}
+
+		ce.mutex.Lock()
+		// 重聚类后簇可能已不存在
+		if current, exists := ce.clusters[cluster.ID]; exists {
+			current.Summary = summary
+			current.SummarySize = len(cluster.Members)
+			generated++
+		}
+		ce.mutex.Unlock()
+	}
+
+	if generated == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+	return generated, nil
+}
+
+// summarizeCluster 以离质心最近的不同错误特征作为代表样本请求LLM
+func (ce *clusteringEngine) summarizeCluster(cluster *types.Cluster) (string, error) {
+	members := ce.memberSimilarities(cluster)
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Similarity > members[j].Similarity
+	})
+
+	samples := make([]string, 0, ce.summarizer.config.Samples)
+	seen := make(map[string]bool)
+	for _, member := range members {
+		if len(samples) >= ce.summarizer.config.Samples {
+			break
+		}
+		if seen[member.Signature] {
+			continue
+		}
+		seen[member.Signature] = true
+		samples = append(samples, member.Signature)
+	}
+	if len(samples) == 0 {
+		return "", fmt.Errorf("no member signatures available")
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Cluster: %s\nErrors: %d\nMembers: %d\n", cluster.Description, cluster.ErrorCount, len(cluster.Members))
+	prompt.WriteString("Representative error signatures:\n")
+	for i, sample := range samples {
+		fmt.Fprintf(&prompt, "%d. %s\n", i+1, sample)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ce.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ce.summarizer.client.Complete(ctx, summarySystemPrompt, prompt.String())
+}
+
+// summaryLoop 定期生成簇摘要
+func (ce *clusteringEngine) summaryLoop() {
+	ticker := time.NewTicker(ce.summarizer.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if generated, err := ce.SummarizeClusters(); err != nil {
+				log.Printf("Cluster summarization failed: %v", err)
+			} else if generated > 0 {
+				log.Printf("Generated summaries for %d clusters", generated)
+			}
+		case <-ce.stopCh:
+			return
+		}
+	}
+}
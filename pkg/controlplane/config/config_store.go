@@ -96,8 +96,9 @@ func (ecs *etcdConfigStore) Watch(prefix string) (<-chan *interfaces.ConfigChang
 		for watchResp := range watchChan {
 			for _, event := range watchResp.Events {
 				changeEvent := &interfaces.ConfigChangeEvent{
-					Key:   string(event.Kv.Key),
-					Value: string(event.Kv.Value),
+					Key:      string(event.Kv.Key),
+					Value:    string(event.Kv.Value),
+					Revision: event.Kv.ModRevision,
 				}
 
 				switch event.Type {
@@ -0,0 +1,185 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/IBM/sarama"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultConcurrency 未配置时每个topic的并发处理数
+const defaultConcurrency = 4
+
+// EventConsumer 错误事件消费者，每个topic独立消费组会话和并发度
+// 关键服务的topic可配置更高并发，避免被普通事件积压拖慢
+type EventConsumer struct {
+	config *types.KafkaConfig
+	engine interfaces.ClusteringEngine
+	groups []sarama.ConsumerGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEventConsumer 创建错误事件消费者
+func NewEventConsumer(config *types.KafkaConfig, engine interfaces.ClusteringEngine) *EventConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &EventConsumer{
+		config: config,
+		engine: engine,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start 为每个topic启动消费组
+func (ec *EventConsumer) Start() error {
+	for _, topicConfig := range ec.topics() {
+		saramaConfig := sarama.NewConfig()
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+		saramaConfig.Consumer.Return.Errors = true
+
+		group, err := sarama.NewConsumerGroup(ec.config.Brokers, ec.config.GroupID, saramaConfig)
+		if err != nil {
+			ec.Stop()
+			return fmt.Errorf("failed to create consumer group for topic %s: %v", topicConfig.Topic, err)
+		}
+		ec.groups = append(ec.groups, group)
+
+		handler := &topicHandler{
+			topic:       topicConfig.Topic,
+			engine:      ec.engine,
+			concurrency: topicConfig.Concurrency,
+		}
+
+		ec.wg.Add(2)
+		go ec.consume(group, handler)
+		go ec.logErrors(group, topicConfig.Topic)
+
+		log.Printf("Consuming topic %s with concurrency %d", topicConfig.Topic, topicConfig.Concurrency)
+	}
+
+	return nil
+}
+
+// Stop 停止所有消费组
+func (ec *EventConsumer) Stop() error {
+	ec.cancel()
+
+	for _, group := range ec.groups {
+		if err := group.Close(); err != nil {
+			log.Printf("Failed to close consumer group: %v", err)
+		}
+	}
+	ec.wg.Wait()
+
+	log.Println("Event consumer stopped")
+	return nil
+}
+
+// topics 获取消费的topic配置，未配置时只消费默认topic
+func (ec *EventConsumer) topics() []types.TopicConsumerConfig {
+	topics := ec.config.Consumers
+	if len(topics) == 0 {
+		topics = []types.TopicConsumerConfig{{Topic: ec.config.Topic}}
+	}
+
+	result := make([]types.TopicConsumerConfig, 0, len(topics))
+	for _, topic := range topics {
+		if topic.Concurrency <= 0 {
+			topic.Concurrency = defaultConcurrency
+		}
+		result = append(result, topic)
+	}
+	return result
+}
+
+// consume 持续消费直到停止，重平衡后重新加入
+func (ec *EventConsumer) consume(group sarama.ConsumerGroup, handler *topicHandler) {
+	defer ec.wg.Done()
+
+	for {
+		if err := group.Consume(ec.ctx, []string{handler.topic}, handler); err != nil {
+			log.Printf("Consumer error on topic %s: %v", handler.topic, err)
+		}
+		if ec.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// logErrors 记录消费组错误
+func (ec *EventConsumer) logErrors(group sarama.ConsumerGroup, topic string) {
+	defer ec.wg.Done()
+
+	for err := range group.Errors() {
+		log.Printf("Consumer group error on topic %s: %v", topic, err)
+	}
+}
+
+// topicHandler 单个topic的消费处理器
+type topicHandler struct {
+	topic       string
+	engine      interfaces.ClusteringEngine
+	concurrency int
+}
+
+// Setup 会话开始
+func (th *topicHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup 会话结束
+func (th *topicHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim 以topic配置的并发度处理分区消息
+func (th *topicHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	sem := make(chan struct{}, th.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(message *sarama.ConsumerMessage) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				th.process(message)
+				session.MarkMessage(message, "")
+			}(message)
+
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// process 解析并处理单条错误事件
+func (th *topicHandler) process(message *sarama.ConsumerMessage) {
+	var event types.ErrorEvent
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		log.Printf("Skipping invalid error event on topic %s: %v", th.topic, err)
+		return
+	}
+
+	if err := th.engine.ProcessErrorEvent(&event); err != nil {
+		log.Printf("Failed to process error event %s: %v", event.EventID, err)
+	}
+}
@@ -0,0 +1,121 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/IBM/sarama"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// SnapshotConsumer 网关计数快照消费者，网关按实例标识作为消息键，同一实例的快照按序处理
+type SnapshotConsumer struct {
+	config *types.KafkaConfig
+	topic  string
+	store  interfaces.GatewayMetricsStore
+	group  sarama.ConsumerGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSnapshotConsumer 创建计数快照消费者
+func NewSnapshotConsumer(config *types.KafkaConfig, topic string, store interfaces.GatewayMetricsStore) *SnapshotConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SnapshotConsumer{
+		config: config,
+		topic:  topic,
+		store:  store,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start 启动消费组
+func (sc *SnapshotConsumer) Start() error {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	saramaConfig.Consumer.Return.Errors = true
+
+	group, err := sarama.NewConsumerGroup(sc.config.Brokers, sc.config.GroupID, saramaConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer group for topic %s: %v", sc.topic, err)
+	}
+	sc.group = group
+
+	sc.wg.Add(2)
+	go func() {
+		defer sc.wg.Done()
+		for {
+			if err := group.Consume(sc.ctx, []string{sc.topic}, sc); err != nil {
+				log.Printf("Consumer error on topic %s: %v", sc.topic, err)
+			}
+			if sc.ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer sc.wg.Done()
+		for err := range group.Errors() {
+			log.Printf("Consumer group error on topic %s: %v", sc.topic, err)
+		}
+	}()
+
+	log.Printf("Consuming gateway metrics snapshots from topic %s", sc.topic)
+	return nil
+}
+
+// Stop 停止消费
+func (sc *SnapshotConsumer) Stop() error {
+	sc.cancel()
+
+	if sc.group != nil {
+		if err := sc.group.Close(); err != nil {
+			log.Printf("Failed to close consumer group: %v", err)
+		}
+	}
+	sc.wg.Wait()
+
+	log.Println("Snapshot consumer stopped")
+	return nil
+}
+
+// Setup 会话开始
+func (sc *SnapshotConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup 会话结束
+func (sc *SnapshotConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim 顺序处理分区内的快照
+func (sc *SnapshotConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			var snapshot types.MetricsSnapshot
+			if err := json.Unmarshal(message.Value, &snapshot); err != nil || snapshot.Instance == "" {
+				log.Printf("Skipping invalid metrics snapshot on topic %s: %v", sc.topic, err)
+			} else {
+				sc.store.Ingest(&snapshot)
+			}
+			session.MarkMessage(message, "")
+
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
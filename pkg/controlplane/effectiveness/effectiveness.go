@@ -0,0 +1,446 @@
+package effectiveness
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+const (
+	// reportPrefix 效果报告持久化键前缀，键为"/policy-reports/<cluster_id>/<start_unix>"，区域策略追加"@<region>"
+	reportPrefix = "/policy-reports/"
+
+	// 判定阈值：生效期间错误速率下降超过该比例视为有效
+	effectiveReduction = 0.3
+)
+
+// 报告结论
+const (
+	VerdictEffective    = "effective"
+	VerdictIneffective  = "ineffective"
+	VerdictInconclusive = "inconclusive"
+)
+
+// EventPolicyIneffective 策略判定无效的告警事件类型
+const EventPolicyIneffective = "policy_ineffective"
+
+// trackedPolicy 等待评估的策略
+type trackedPolicy struct {
+	key     string // 策略键中的簇ID，带命名空间
+	region  string // 区域策略所属区域，全局策略为空
+	policy  types.Policy
+	endTime time.Time
+	ended   bool // 已被删除或已到期
+	traffic *TrafficStats
+}
+
+// TrafficStats 网关上报的策略期间请求量
+type TrafficStats struct {
+	Total   int64
+	Blocked int64
+}
+
+// Reporter 策略效果评估器：策略失效后等待一个对比窗口，计算生效前、生效期间、失效后的错误速率，
+// 结合网关上报的拦截量生成报告并持久化
+type Reporter struct {
+	config   *types.EffectivenessConfig
+	store    interfaces.ConfigStore
+	series   interfaces.TimeSeriesStore
+	client   *http.Client
+	notifier interfaces.Notifier
+	tracked  map[string]*trackedPolicy
+	reports  []*types.PolicyEffectivenessReport
+	mutex    sync.RWMutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewReporter 创建策略效果评估器
+func NewReporter(config *types.EffectivenessConfig, store interfaces.ConfigStore, series interfaces.TimeSeriesStore) *Reporter {
+	cfg := *config
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 30 * time.Second
+	}
+	if cfg.MaxReports <= 0 {
+		cfg.MaxReports = 500
+	}
+
+	return &Reporter{
+		config:  &cfg,
+		store:   store,
+		series:  series,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		tracked: make(map[string]*trackedPolicy),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// SetNotifier 设置告警通知器，策略判定无效时发送告警
+func (r *Reporter) SetNotifier(notifier interfaces.Notifier) {
+	r.notifier = notifier
+}
+
+// Start 加载已持久化的报告和当前策略，开始监听策略变更
+func (r *Reporter) Start() error {
+	if err := r.loadReports(); err != nil {
+		log.Printf("Failed to load policy reports: %v", err)
+	}
+
+	// 全局策略和各区域策略分别位于两个前缀下
+	tracked := 0
+	for _, prefix := range []string{utils.GlobalPolicyPrefix, utils.RegionPrefix} {
+		policies, err := r.store.GetWithPrefix(prefix)
+		if err != nil {
+			return fmt.Errorf("failed to load policies: %v", err)
+		}
+		for key, value := range policies {
+			r.onPolicyPut(key, value)
+		}
+		tracked += len(policies)
+
+		events, err := r.store.Watch(prefix)
+		if err != nil {
+			return fmt.Errorf("failed to watch policies: %v", err)
+		}
+		r.wg.Add(1)
+		go r.watchLoop(events)
+	}
+
+	r.wg.Add(1)
+	go r.evaluateLoop()
+
+	log.Printf("Policy effectiveness reporter started (window=%v, tracked=%d)", r.config.Window, tracked)
+	return nil
+}
+
+// Stop 停止评估
+func (r *Reporter) Stop() error {
+	close(r.stopCh)
+	r.wg.Wait()
+	return nil
+}
+
+// Reports 获取效果报告，clusterID为空时返回全部，按生成时间倒序
+func (r *Reporter) Reports(clusterID string) []*types.PolicyEffectivenessReport {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	reports := make([]*types.PolicyEffectivenessReport, 0)
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		report := r.reports[i]
+		if clusterID == "" || report.ClusterID == clusterID || report.PolicyKey == clusterID {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// watchLoop 处理策略变更事件
+func (r *Reporter) watchLoop(events <-chan *interfaces.ConfigChangeEvent) {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case interfaces.ConfigChangeTypePut:
+				r.onPolicyPut(event.Key, event.Value)
+			case interfaces.ConfigChangeTypeDelete:
+				r.onPolicyDelete(event.Key)
+			}
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// onPolicyPut 记录新策略，同一策略的更新只刷新到期时间
+func (r *Reporter) onPolicyPut(key, value string) {
+	var policy types.Policy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		log.Printf("Ignoring invalid policy %s: %v", key, err)
+		return
+	}
+
+	region, clusterKey, ok := utils.ParsePolicyKey(key)
+	if !ok {
+		return
+	}
+	if policy.ClusterID == "" {
+		policy.ClusterID = clusterKey
+	}
+	if policy.CreateTime.IsZero() {
+		policy.CreateTime = time.Now()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if tracked, exists := r.tracked[key]; exists && !tracked.ended {
+		tracked.policy.ExpireTime = policy.ExpireTime
+		tracked.endTime = policy.ExpireTime
+		return
+	}
+
+	r.tracked[key] = &trackedPolicy{
+		key:     clusterKey,
+		region:  region,
+		policy:  policy,
+		endTime: policy.ExpireTime,
+	}
+}
+
+// onPolicyDelete 策略被提前删除时以删除时间作为失效时间
+func (r *Reporter) onPolicyDelete(key string) {
+	now := time.Now()
+
+	r.mutex.Lock()
+	tracked, exists := r.tracked[key]
+	if exists && !tracked.ended && (tracked.endTime.IsZero() || now.Before(tracked.endTime)) {
+		tracked.endTime = now
+	}
+	r.mutex.Unlock()
+
+	if exists {
+		r.markEnded(tracked)
+	}
+}
+
+// evaluateLoop 定期检查到期策略并生成报告
+func (r *Reporter) evaluateLoop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.evaluate(time.Now())
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// evaluate 处理已到期的策略：到期时采集拦截量，对比窗口结束后生成报告
+func (r *Reporter) evaluate(now time.Time) {
+	r.mutex.RLock()
+	candidates := make(map[string]*trackedPolicy)
+	for key, tracked := range r.tracked {
+		if !tracked.endTime.IsZero() && now.After(tracked.endTime) {
+			candidates[key] = tracked
+		}
+	}
+	r.mutex.RUnlock()
+
+	for key, tracked := range candidates {
+		if !tracked.ended {
+			r.markEnded(tracked)
+		}
+		if now.Before(tracked.endTime.Add(r.config.Window)) {
+			continue
+		}
+
+		report := BuildReport(r.series, &tracked.policy, tracked.endTime, r.config.Window, tracked.traffic)
+		report.PolicyKey = tracked.key
+		report.Region = tracked.region
+		r.saveReport(report)
+
+		r.mutex.Lock()
+		if current, exists := r.tracked[key]; exists && current == tracked {
+			delete(r.tracked, key)
+		}
+		r.mutex.Unlock()
+	}
+}
+
+// markEnded 标记策略失效并从网关采集策略期间的拦截量
+func (r *Reporter) markEnded(tracked *trackedPolicy) {
+	traffic := r.collectTraffic(tracked.key)
+
+	r.mutex.Lock()
+	tracked.ended = true
+	tracked.traffic = traffic
+	r.mutex.Unlock()
+}
+
+// collectTraffic 汇总各网关该簇限流器的请求量和拒绝量
+func (r *Reporter) collectTraffic(clusterKey string) *TrafficStats {
+	if len(r.config.GatewayAdminURLs) == 0 {
+		return nil
+	}
+
+	traffic := &TrafficStats{}
+	for _, base := range r.config.GatewayAdminURLs {
+		endpoint := strings.TrimSuffix(base, "/") + "/admin/stats?cluster_id=" + url.QueryEscape(clusterKey)
+		resp, err := r.client.Get(endpoint)
+		if err != nil {
+			log.Printf("Failed to collect policy traffic from %s: %v", base, err)
+			continue
+		}
+
+		var body struct {
+			Stats types.ClusterStats `json:"stats"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+				traffic.Total += body.Stats.TotalRequests
+				traffic.Blocked += body.Stats.RejectedRequests
+			}
+		}
+		resp.Body.Close()
+	}
+
+	return traffic
+}
+
+// BuildReport 根据时序数据计算策略效果报告
+func BuildReport(series interfaces.TimeSeriesStore, policy *types.Policy, endTime time.Time, window time.Duration, traffic *TrafficStats) *types.PolicyEffectivenessReport {
+	start := policy.CreateTime
+	if endTime.Before(start) {
+		endTime = start
+	}
+
+	before, _ := errorRate(series, policy.ClusterID, start.Add(-window), start)
+	during, duringCount := errorRate(series, policy.ClusterID, start, endTime)
+	after, _ := errorRate(series, policy.ClusterID, endTime, endTime.Add(window))
+
+	report := &types.PolicyEffectivenessReport{
+		ClusterID:       policy.ClusterID,
+		PolicyType:      policy.PolicyType,
+		PolicyVersion:   policy.Version,
+		Severity:        policy.Severity,
+		StartTime:       start,
+		EndTime:         endTime,
+		ErrorRateBefore: before,
+		ErrorRateDuring: during,
+		ErrorRateAfter:  after,
+		Verdict:         VerdictInconclusive,
+		GeneratedAt:     time.Now(),
+	}
+
+	if before > 0 {
+		report.Reduction = 1 - during/before
+		report.Recurrence = after / before
+		if avoided := int64(before*endTime.Sub(start).Seconds()) - duringCount; avoided > 0 {
+			report.ErrorsAvoided = avoided
+		}
+		if report.Reduction >= effectiveReduction {
+			report.Verdict = VerdictEffective
+		} else {
+			report.Verdict = VerdictIneffective
+		}
+	}
+
+	if traffic != nil {
+		report.TrafficTotal = traffic.Total
+		report.TrafficBlocked = traffic.Blocked
+		if traffic.Total > 0 {
+			report.EstimatedUserImpact = float64(traffic.Blocked) / float64(traffic.Total)
+		}
+	}
+
+	return report
+}
+
+// errorRate 计算时间范围内的平均错误速率和事件总数
+func errorRate(series interfaces.TimeSeriesStore, clusterID string, from, to time.Time) (float64, int64) {
+	seconds := to.Sub(from).Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+
+	// Range包含两端时间桶，结束时间前移避免相邻区间重复计数
+	var total int64
+	for _, point := range series.Range(clusterID, from, to.Add(-time.Nanosecond)) {
+		total += point.Count
+	}
+
+	return float64(total) / seconds, total
+}
+
+// saveReport 保存报告到内存并持久化到配置存储
+func (r *Reporter) saveReport(report *types.PolicyEffectivenessReport) {
+	r.mutex.Lock()
+	r.reports = append(r.reports, report)
+	if len(r.reports) > r.config.MaxReports {
+		r.reports = r.reports[len(r.reports)-r.config.MaxReports:]
+	}
+	r.mutex.Unlock()
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Failed to marshal policy report: %v", err)
+		return
+	}
+
+	key := fmt.Sprintf("%s%s/%d", reportPrefix, report.PolicyKey, report.StartTime.Unix())
+	if report.Region != "" {
+		key += "@" + report.Region
+	}
+	if err := r.store.Put(key, string(data)); err != nil {
+		log.Printf("Failed to persist policy report %s: %v", key, err)
+	}
+
+	log.Printf("Policy effectiveness for cluster %s: verdict=%s reduction=%.2f recurrence=%.2f blocked=%d",
+		report.PolicyKey, report.Verdict, report.Reduction, report.Recurrence, report.TrafficBlocked)
+
+	if r.notifier != nil && report.Verdict == VerdictIneffective {
+		labels := map[string]string{"cluster_id": report.PolicyKey}
+		if report.Region != "" {
+			labels["region"] = report.Region
+		}
+		r.notifier.Notify(&types.AlertEvent{
+			Type:     EventPolicyIneffective,
+			Severity: types.AlertSeverityWarning,
+			Title:    fmt.Sprintf("Policy for cluster %s was ineffective", report.PolicyKey),
+			Message: fmt.Sprintf("%s policy reduced the error rate by %.0f%% (%.2f/s before, %.2f/s during)",
+				report.PolicyType, report.Reduction*100, report.ErrorRateBefore, report.ErrorRateDuring),
+			Labels: labels,
+		})
+	}
+}
+
+// loadReports 加载已持久化的报告
+func (r *Reporter) loadReports() error {
+	values, err := r.store.GetWithPrefix(reportPrefix)
+	if err != nil {
+		return err
+	}
+
+	reports := make([]*types.PolicyEffectivenessReport, 0, len(values))
+	for key, value := range values {
+		var report types.PolicyEffectivenessReport
+		if err := json.Unmarshal([]byte(value), &report); err != nil {
+			log.Printf("Ignoring invalid policy report %s: %v", key, err)
+			continue
+		}
+		reports = append(reports, &report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].GeneratedAt.Before(reports[j].GeneratedAt) })
+	if len(reports) > r.config.MaxReports {
+		reports = reports[len(reports)-r.config.MaxReports:]
+	}
+
+	r.mutex.Lock()
+	r.reports = reports
+	r.mutex.Unlock()
+	return nil
+}
@@ -3,8 +3,6 @@ package embedding
 import (
 	"fmt"
 	"log"
-	"regexp"
-	"strings"
 	"sync"
 
 	"github.com/llm-aware-gateway/pkg/interfaces"
@@ -16,30 +14,89 @@ import (
 type embeddingService struct {
 	config    *types.EmbeddingConfig
 	cache     interfaces.Cache
-	model     *MockBGEModel // 使用模拟模型
+	model     encoder
+	modelName string
+	ruleset   *ruleset
 	batchSize int
+	pipeline  *batchPipeline // 未启用异步批量时为nil
 	mutex     sync.RWMutex
 }
 
+// encoder 向量化后端，按输入顺序返回向量
+type encoder interface {
+	EncodeBatch(texts []string) ([][]float32, error)
+}
+
+// defaultModelName 未配置模型名时使用的名称
+const defaultModelName = "mock-bge"
+
 // MockBGEModel 模拟BGE模型
 type MockBGEModel struct {
 	dimension int
 }
 
-// NewEmbeddingService 创建嵌入服务
+// NewEmbeddingService 创建嵌入服务，按provider选择向量化后端；
+// openai或onnx配置无效时回退到模拟模型，模型名随之变为mock-bge，网关会因与簇快照不一致而拒绝加载
 func NewEmbeddingService(config *types.EmbeddingConfig) interfaces.EmbeddingService {
 	cache := utils.NewCache(config.CacheSize)
 
-	model := &MockBGEModel{
-		dimension: config.Dimension,
+	var model encoder = &MockBGEModel{dimension: config.Dimension}
+	modelName := config.ModelName
+	switch config.Provider {
+	case ProviderOpenAI:
+		openai, err := newOpenAIEncoder(&config.OpenAI, config.Dimension)
+		if err != nil {
+			log.Printf("Falling back to mock embedding model: %v", err)
+			modelName = defaultModelName
+			break
+		}
+		model = openai
+		if modelName == "" {
+			modelName = config.OpenAI.Model
+		}
+	case ProviderONNX:
+		local, err := newONNXEncoder(config)
+		if err != nil {
+			log.Printf("Falling back to mock embedding model: %v", err)
+			modelName = defaultModelName
+			break
+		}
+		model = local
+		if modelName == "" {
+			modelName = onnxModelName(config.ModelPath)
+		}
+	case "", "mock":
+	default:
+		log.Printf("Unknown embedding provider %q, using mock embedding model", config.Provider)
+		modelName = defaultModelName
+	}
+	if modelName == "" {
+		modelName = defaultModelName
+	}
+
+	rs, err := newRuleset(config.PreprocessRules)
+	if err != nil {
+		log.Printf("Falling back to default preprocess rules: %v", err)
+		rs, _ = newRuleset(nil)
+	}
+
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
 	}
 
-	return &embeddingService{
+	es := &embeddingService{
 		config:    config,
 		cache:     cache,
 		model:     model,
-		batchSize: config.BatchSize,
+		modelName: modelName,
+		ruleset:   rs,
+		batchSize: batchSize,
 	}
+	if config.Pipeline.Enabled {
+		es.pipeline = newBatchPipeline(model, batchSize, &config.Pipeline)
+	}
+	return es
 }
 
 // EmbedText 文本向量化
@@ -48,27 +105,11 @@ func (es *embeddingService) EmbedText(text string) ([]float32, error) {
 		return nil, fmt.Errorf("empty text")
 	}
 
-	// 检查缓存
-	cacheKey := fmt.Sprintf("embed:%s", text)
-	if cached, found := es.cache.Get(cacheKey); found {
-		if vector, ok := cached.([]float32); ok {
-			return vector, nil
-		}
-	}
-
-	// 预处理文本
-	processedText := es.PreprocessText(text)
-
-	// 生成向量
-	vector, err := es.model.Encode(processedText)
+	vectors, err := es.processBatch([]string{text})
 	if err != nil {
 		return nil, err
 	}
-
-	// 缓存结果
-	es.cache.Set(cacheKey, vector, 300) // TTL 5分钟
-
-	return vector, nil
+	return vectors[0], nil
 }
 
 // EmbedBatch 批量向量化
@@ -77,6 +118,11 @@ func (es *embeddingService) EmbedBatch(texts []string) ([][]float32, error) {
 		return nil, nil
 	}
 
+	// 异步批量时整体提交，由流水线拆分并与其他调用方的请求合并
+	if es.pipeline != nil {
+		return es.processBatch(texts)
+	}
+
 	vectors := make([][]float32, len(texts))
 
 	// 分批处理
@@ -100,52 +146,106 @@ func (es *embeddingService) EmbedBatch(texts []string) ([][]float32, error) {
 
 // PreprocessText 预处理文本
 func (es *embeddingService) PreprocessText(text string) string {
-	if text == "" {
-		return text
-	}
+	es.mutex.RLock()
+	rs := es.ruleset
+	es.mutex.RUnlock()
+
+	return rs.apply(text)
+}
 
-	// 转换为小写
-	text = strings.ToLower(text)
+// RulesetVersion 获取当前预处理规则集版本
+func (es *embeddingService) RulesetVersion() string {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+	return es.ruleset.version
+}
 
-	// 模板化处理：将变量替换为占位符
-	patterns := map[string]string{
-		`\b\d{11}\b`:                                              "[PHONE]",
-		`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`:    "[EMAIL]",
-		`\b[A-Za-z0-9]{20,}\b`:                                    "[TOKEN]",
-		`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`:                 "[IP]",
-		`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`: "[UUID]",
-		`\b\d{4}[- ]?\d{4}[- ]?\d{4}[- ]?\d{4}\b`:                 "[CARD]",
-		`\b\d+\b`:                                                 "[NUMBER]",
-		`/[a-zA-Z0-9/._-]+`:                                       "[PATH]",
+// ModelInfo 获取嵌入模型标识
+func (es *embeddingService) ModelInfo() types.EmbeddingModelInfo {
+	return types.EmbeddingModelInfo{
+		Model:     es.modelName,
+		Version:   es.config.ModelVersion,
+		Dimension: es.config.Dimension,
 	}
+}
 
-	for pattern, replacement := range patterns {
-		re := regexp.MustCompile(pattern)
-		text = re.ReplaceAllString(text, replacement)
+// SetPreprocessRules 替换预处理规则，已有向量需通过重新向量化任务迁移
+func (es *embeddingService) SetPreprocessRules(rules []types.PreprocessRule) error {
+	rs, err := newRuleset(rules)
+	if err != nil {
+		return err
 	}
 
-	// 清理多余空格
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
-	text = strings.TrimSpace(text)
+	es.mutex.Lock()
+	previous := es.ruleset.version
+	es.ruleset = rs
+	es.mutex.Unlock()
 
-	return text
+	if previous != rs.version {
+		log.Printf("Preprocess ruleset changed: %s -> %s", previous, rs.version)
+	}
+	return nil
 }
 
-// processBatch 处理批次
+// processBatch 处理批次，缓存未命中的文本预处理后一次交给后端向量化
 func (es *embeddingService) processBatch(texts []string) ([][]float32, error) {
 	vectors := make([][]float32, len(texts))
+	version := es.RulesetVersion()
 
+	var missing []int
+	var inputs []string
 	for i, text := range texts {
-		vector, err := es.EmbedText(text)
-		if err != nil {
-			return nil, err
+		if text == "" {
+			return nil, fmt.Errorf("empty text")
 		}
-		vectors[i] = vector
+
+		// 检查缓存，规则集变更后旧结果失效
+		if cached, found := es.cache.Get(cacheKey(version, text)); found {
+			if vector, ok := cached.([]float32); ok {
+				vectors[i] = vector
+				continue
+			}
+		}
+		missing = append(missing, i)
+		inputs = append(inputs, es.PreprocessText(text))
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	encoded, err := es.encode(inputs)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range missing {
+		vectors[i] = encoded[j]
+		es.cache.Set(cacheKey(version, texts[i]), encoded[j], 300) // TTL 5分钟
 	}
 
 	return vectors, nil
 }
 
+// encode 向量化预处理后的文本，启用异步批量时经流水线合并
+func (es *embeddingService) encode(inputs []string) ([][]float32, error) {
+	if es.pipeline != nil {
+		return es.pipeline.encode(inputs)
+	}
+	return es.model.EncodeBatch(inputs)
+}
+
+// Close 停止异步批量向量化，等待中的请求返回错误
+func (es *embeddingService) Close() error {
+	if es.pipeline != nil {
+		es.pipeline.close()
+	}
+	return nil
+}
+
+// cacheKey 向量缓存键
+func cacheKey(version, text string) string {
+	return fmt.Sprintf("embed:%s:%s", version, text)
+}
+
 // Encode 模拟BGE模型编码
 func (m *MockBGEModel) Encode(text string) ([]float32, error) {
 	// 这是一个简化的模拟实现
@@ -0,0 +1,199 @@
+package embedding
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// ProviderONNX 本地ONNX Runtime推理
+const ProviderONNX = "onnx"
+
+// 池化方式
+const (
+	PoolingCLS  = "cls"  // 取[CLS]位置的隐藏状态，BGE系列
+	PoolingMean = "mean" // 按注意力掩码求平均，MiniLM等sentence-transformers模型
+)
+
+// onnxRunner 执行一次推理，输入为按批展平的ID和掩码，返回展平的输出及其形状；
+// 实现由onnx构建标签决定，未启用时创建失败
+type onnxRunner interface {
+	Run(ids, mask []int64, batch, seqLen int) ([]float32, []int64, error)
+}
+
+// onnxEncoder 本地分词并调用ONNX Runtime推理
+type onnxEncoder struct {
+	tokenizer *WordPieceTokenizer
+	runner    onnxRunner
+	pooling   string
+	dimension int
+	mutex     sync.Mutex
+}
+
+// newONNXEncoder 加载模型和词表，modelPath为包含model.onnx和vocab.txt的目录或.onnx文件
+func newONNXEncoder(config *types.EmbeddingConfig) (*onnxEncoder, error) {
+	modelFile, vocabFile, err := onnxModelFiles(config.ModelPath, config.ONNX.VocabPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pooling := strings.ToLower(config.ONNX.Pooling)
+	switch pooling {
+	case "":
+		pooling = PoolingCLS
+	case PoolingCLS, PoolingMean:
+	default:
+		return nil, fmt.Errorf("unsupported pooling %q", config.ONNX.Pooling)
+	}
+
+	maxLength := config.ONNX.MaxSeqLength
+	if maxLength <= 0 {
+		maxLength = 512
+	}
+	tokenizer, err := NewWordPieceTokenizer(vocabFile, maxLength, !config.ONNX.Cased)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := newONNXRunner(modelFile, config.ONNX.RuntimeLibrary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &onnxEncoder{
+		tokenizer: tokenizer,
+		runner:    runner,
+		pooling:   pooling,
+		dimension: config.Dimension,
+	}, nil
+}
+
+// onnxModelFiles 解析模型文件和词表路径
+func onnxModelFiles(modelPath, vocabPath string) (string, string, error) {
+	if modelPath == "" {
+		return "", "", fmt.Errorf("model_path is required for onnx embedding")
+	}
+
+	info, err := os.Stat(modelPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to stat model path: %v", err)
+	}
+
+	modelFile, dir := modelPath, filepath.Dir(modelPath)
+	if info.IsDir() {
+		modelFile, dir = filepath.Join(modelPath, "model.onnx"), modelPath
+	}
+	if vocabPath == "" {
+		vocabPath = filepath.Join(dir, "vocab.txt")
+	}
+	return modelFile, vocabPath, nil
+}
+
+// onnxModelName 未配置模型名时使用模型目录名
+func onnxModelName(modelPath string) string {
+	if info, err := os.Stat(modelPath); err == nil && !info.IsDir() {
+		modelPath = filepath.Dir(modelPath)
+	}
+	return filepath.Base(filepath.Clean(modelPath))
+}
+
+// EncodeBatch 分词后按批内最长序列填充，一次推理得到全部向量
+func (e *onnxEncoder) EncodeBatch(texts []string) ([][]float32, error) {
+	encoded := make([][]int64, len(texts))
+	seqLen := 0
+	for i, text := range texts {
+		encoded[i] = e.tokenizer.Encode(text)
+		if len(encoded[i]) > seqLen {
+			seqLen = len(encoded[i])
+		}
+	}
+
+	batch := len(texts)
+	ids := make([]int64, batch*seqLen)
+	mask := make([]int64, batch*seqLen)
+	for i, tokens := range encoded {
+		row := ids[i*seqLen : (i+1)*seqLen]
+		for j := range row {
+			if j < len(tokens) {
+				row[j] = tokens[j]
+				mask[i*seqLen+j] = 1
+			} else {
+				row[j] = e.tokenizer.PadID()
+			}
+		}
+	}
+
+	e.mutex.Lock()
+	output, shape, err := e.runner.Run(ids, mask, batch, seqLen)
+	e.mutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run onnx model: %v", err)
+	}
+
+	vectors, err := PoolOutput(output, shape, mask, e.pooling)
+	if err != nil {
+		return nil, err
+	}
+	for i, vector := range vectors {
+		if e.dimension > 0 && len(vector) != e.dimension {
+			return nil, fmt.Errorf("embedding dimension %d does not match configured %d", len(vector), e.dimension)
+		}
+		vectors[i] = utils.NormalizeVector(vector)
+	}
+	return vectors, nil
+}
+
+// PoolOutput 将模型输出转换为句向量：[batch, hidden]直接使用，
+// [batch, seq, hidden]按池化方式取[CLS]或按掩码求平均
+func PoolOutput(output []float32, shape []int64, mask []int64, pooling string) ([][]float32, error) {
+	switch len(shape) {
+	case 2:
+		batch, hidden := int(shape[0]), int(shape[1])
+		if len(output) != batch*hidden {
+			return nil, fmt.Errorf("onnx output size %d does not match shape %v", len(output), shape)
+		}
+		vectors := make([][]float32, batch)
+		for b := range vectors {
+			vectors[b] = append([]float32(nil), output[b*hidden:(b+1)*hidden]...)
+		}
+		return vectors, nil
+
+	case 3:
+		batch, seqLen, hidden := int(shape[0]), int(shape[1]), int(shape[2])
+		if len(output) != batch*seqLen*hidden || len(mask) != batch*seqLen {
+			return nil, fmt.Errorf("onnx output size %d does not match shape %v", len(output), shape)
+		}
+		vectors := make([][]float32, batch)
+		for b := range vectors {
+			vector := make([]float32, hidden)
+			if pooling == PoolingCLS {
+				copy(vector, output[b*seqLen*hidden:b*seqLen*hidden+hidden])
+			} else {
+				var count float32
+				for s := 0; s < seqLen; s++ {
+					if mask[b*seqLen+s] == 0 {
+						continue
+					}
+					offset := (b*seqLen + s) * hidden
+					for h := 0; h < hidden; h++ {
+						vector[h] += output[offset+h]
+					}
+					count++
+				}
+				if count > 0 {
+					for h := range vector {
+						vector[h] /= count
+					}
+				}
+			}
+			vectors[b] = vector
+		}
+		return vectors, nil
+	}
+	return nil, fmt.Errorf("unsupported onnx output shape %v", shape)
+}
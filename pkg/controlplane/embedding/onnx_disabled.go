@@ -0,0 +1,10 @@
+//go:build !onnx
+
+package embedding
+
+import "fmt"
+
+// newONNXRunner 未使用onnx构建标签时不支持本地推理
+func newONNXRunner(modelFile, runtimeLibrary string) (onnxRunner, error) {
+	return nil, fmt.Errorf("onnx embedding requires building with -tags onnx")
+}
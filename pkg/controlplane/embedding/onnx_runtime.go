@@ -0,0 +1,118 @@
+//go:build onnx
+
+package embedding
+
+import (
+	"fmt"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ONNX Runtime环境全局只初始化一次
+var (
+	ortOnce sync.Once
+	ortErr  error
+)
+
+// ortRunner 基于ONNX Runtime会话的推理，通过cgo调用，构建时需要github.com/yalue/onnxruntime_go依赖，
+// 运行时需要与之版本匹配的onnxruntime动态库
+type ortRunner struct {
+	session   *ort.DynamicAdvancedSession
+	typeIDs   bool  // 模型是否需要token_type_ids输入
+	outputDim int   // 输出维度数，2为句向量，3为逐token隐藏状态
+	hidden    int64 // 隐藏层维度
+}
+
+// newONNXRunner 初始化运行时并加载模型，按模型声明的输入决定是否提供token_type_ids
+func newONNXRunner(modelFile, runtimeLibrary string) (onnxRunner, error) {
+	ortOnce.Do(func() {
+		if runtimeLibrary != "" {
+			ort.SetSharedLibraryPath(runtimeLibrary)
+		}
+		ortErr = ort.InitializeEnvironment()
+	})
+	if ortErr != nil {
+		return nil, fmt.Errorf("failed to initialize onnx runtime: %v", ortErr)
+	}
+
+	inputs, outputs, err := ort.GetInputOutputInfo(modelFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read onnx model info: %v", err)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("onnx model has no outputs")
+	}
+
+	inputNames := []string{"input_ids", "attention_mask"}
+	typeIDs := false
+	for _, input := range inputs {
+		if input.Name == "token_type_ids" {
+			typeIDs = true
+			inputNames = append(inputNames, input.Name)
+		}
+	}
+
+	output := outputs[0]
+	dims := output.Dimensions
+	if len(dims) != 2 && len(dims) != 3 {
+		return nil, fmt.Errorf("unsupported onnx output %s with shape %v", output.Name, dims)
+	}
+	hidden := dims[len(dims)-1]
+	if hidden <= 0 {
+		return nil, fmt.Errorf("onnx output %s has dynamic hidden size", output.Name)
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(modelFile, inputNames, []string{output.Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load onnx model: %v", err)
+	}
+
+	return &ortRunner{
+		session:   session,
+		typeIDs:   typeIDs,
+		outputDim: len(dims),
+		hidden:    hidden,
+	}, nil
+}
+
+// Run 执行一次推理
+func (r *ortRunner) Run(ids, mask []int64, batch, seqLen int) ([]float32, []int64, error) {
+	inputShape := ort.NewShape(int64(batch), int64(seqLen))
+
+	values := make([]ort.Value, 0, 3)
+	defer func() {
+		for _, value := range values {
+			value.Destroy()
+		}
+	}()
+
+	inputData := [][]int64{ids, mask}
+	if r.typeIDs {
+		inputData = append(inputData, make([]int64, len(ids)))
+	}
+	for _, data := range inputData {
+		tensor, err := ort.NewTensor(inputShape, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create input tensor: %v", err)
+		}
+		values = append(values, tensor)
+	}
+
+	outputShape := ort.NewShape(int64(batch), r.hidden)
+	if r.outputDim == 3 {
+		outputShape = ort.NewShape(int64(batch), int64(seqLen), r.hidden)
+	}
+	output, err := ort.NewEmptyTensor[float32](outputShape)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create output tensor: %v", err)
+	}
+	defer output.Destroy()
+
+	if err := r.session.Run(values, []ort.Value{output}); err != nil {
+		return nil, nil, err
+	}
+
+	data := append([]float32(nil), output.GetData()...)
+	return data, []int64(outputShape), nil
+}
@@ -0,0 +1,158 @@
+package embedding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// ProviderOpenAI OpenAI兼容向量化接口
+const ProviderOpenAI = "openai"
+
+// maxEmbeddingResponseBytes 向量化响应体大小上限
+const maxEmbeddingResponseBytes = 64 << 20
+
+// openAIEncoder 调用OpenAI兼容的/v1/embeddings接口生成向量
+type openAIEncoder struct {
+	config    types.OpenAIEmbeddingConfig
+	apiKey    string
+	dimension int
+	client    *http.Client
+}
+
+// retryableError 可重试的接口错误
+type retryableError struct {
+	err error
+}
+
+// Error 实现error接口
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// newOpenAIEncoder 创建OpenAI兼容向量化后端，dimension大于0时校验返回向量的维度
+func newOpenAIEncoder(config *types.OpenAIEmbeddingConfig, dimension int) (*openAIEncoder, error) {
+	cfg := *config
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("openai embedding endpoint is required")
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("openai embedding model is required")
+	}
+	if cfg.SendDimensions && dimension <= 0 {
+		return nil, fmt.Errorf("dimension is required when send_dimensions is enabled")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+
+	return &openAIEncoder{
+		config:    cfg,
+		apiKey:    os.ExpandEnv(cfg.APIKey),
+		dimension: dimension,
+		client:    &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// EncodeBatch 一次请求向量化多条文本，网络错误、429和5xx按退避重试
+func (e *openAIEncoder) EncodeBatch(texts []string) ([][]float32, error) {
+	payload := map[string]interface{}{
+		"model": e.config.Model,
+		"input": texts,
+	}
+	if e.config.SendDimensions {
+		payload["dimensions"] = e.dimension
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %v", err)
+	}
+
+	backoff := e.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		vectors, err := e.request(body, len(texts))
+		if err == nil {
+			return vectors, nil
+		}
+		if _, ok := err.(*retryableError); !ok || attempt >= e.config.MaxRetries {
+			return nil, err
+		}
+
+		log.Printf("Embedding request failed (attempt %d/%d), retrying in %v: %v", attempt+1, e.config.MaxRetries+1, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// request 发送一次向量化请求，返回按输入顺序排列的向量
+func (e *openAIEncoder) request(body []byte, count int) ([][]float32, error) {
+	req, err := http.NewRequest(http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, &retryableError{fmt.Errorf("failed to call embedding endpoint: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEmbeddingResponseBytes))
+	if err != nil {
+		return nil, &retryableError{fmt.Errorf("failed to read embedding response: %v", err)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("embedding endpoint returned %d: %s", resp.StatusCode, utils.Truncate(string(data), 200))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, &retryableError{err}
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response: %v", err)
+	}
+	if len(result.Data) != count {
+		return nil, fmt.Errorf("embedding endpoint returned %d vectors for %d inputs", len(result.Data), count)
+	}
+
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	vectors := make([][]float32, count)
+	for i, item := range result.Data {
+		if item.Index != i {
+			return nil, fmt.Errorf("embedding response is missing index %d", i)
+		}
+		if e.dimension > 0 && len(item.Embedding) != e.dimension {
+			return nil, fmt.Errorf("embedding dimension %d does not match configured %d", len(item.Embedding), e.dimension)
+		}
+		vectors[i] = utils.NormalizeVector(item.Embedding)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,200 @@
+package embedding
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// embedRequest 一个调用方提交的待向量化文本，完成后通过done通知，相当于该调用方的future
+type embedRequest struct {
+	inputs  []string
+	vectors [][]float32
+	err     error
+	done    chan struct{}
+}
+
+// wait 等待向量化完成
+func (r *embedRequest) wait() ([][]float32, error) {
+	<-r.done
+	return r.vectors, r.err
+}
+
+// finish 设置结果并通知调用方
+func (r *embedRequest) finish(vectors [][]float32, err error) {
+	r.vectors, r.err = vectors, err
+	close(r.done)
+}
+
+// batchPipeline 异步批量向量化：请求进入有界队列，工作协程取出第一个请求后在maxWait内继续收集，
+// 凑满batchSize或超时后一次交给后端，再按请求拆分结果
+type batchPipeline struct {
+	model     encoder
+	batchSize int
+	maxWait   time.Duration
+	queue     chan *embedRequest
+	closed    bool
+	mutex     sync.RWMutex
+	stopCh    chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+// newBatchPipeline 创建并启动异步批量向量化
+func newBatchPipeline(model encoder, batchSize int, config *types.EmbeddingPipelineConfig) *batchPipeline {
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	maxWait := config.MaxWait
+	if maxWait <= 0 {
+		maxWait = 5 * time.Millisecond
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	p := &batchPipeline{
+		model:     model,
+		batchSize: batchSize,
+		maxWait:   maxWait,
+		queue:     make(chan *embedRequest, queueSize),
+		stopCh:    make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.run()
+	}
+	return p
+}
+
+// submit 提交待向量化文本，队列满时阻塞，单个请求不应超过batchSize
+func (p *batchPipeline) submit(inputs []string) *embedRequest {
+	req := &embedRequest{inputs: inputs, done: make(chan struct{})}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		req.finish(nil, fmt.Errorf("embedding pipeline is closed"))
+		return req
+	}
+
+	select {
+	case p.queue <- req:
+	case <-p.stopCh:
+		req.finish(nil, fmt.Errorf("embedding pipeline is closed"))
+	}
+	return req
+}
+
+// encode 按batchSize拆分后全部提交，再依次等待结果，多个分片可与其他调用方的请求并行合并
+func (p *batchPipeline) encode(inputs []string) ([][]float32, error) {
+	requests := make([]*embedRequest, 0, (len(inputs)+p.batchSize-1)/p.batchSize)
+	for i := 0; i < len(inputs); i += p.batchSize {
+		end := i + p.batchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		requests = append(requests, p.submit(inputs[i:end]))
+	}
+
+	vectors := make([][]float32, 0, len(inputs))
+	var firstErr error
+	for _, req := range requests {
+		result, err := req.wait()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		vectors = append(vectors, result...)
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return vectors, nil
+}
+
+// run 工作协程，放不进当前批次的请求留到下一批
+func (p *batchPipeline) run() {
+	defer p.wg.Done()
+
+	var carry *embedRequest
+	for {
+		first := carry
+		carry = nil
+		if first == nil {
+			select {
+			case first = <-p.queue:
+			case <-p.stopCh:
+				return
+			}
+		}
+
+		batch := []*embedRequest{first}
+		size := len(first.inputs)
+		timer := time.NewTimer(p.maxWait)
+	collect:
+		for size < p.batchSize {
+			select {
+			case req := <-p.queue:
+				if size+len(req.inputs) > p.batchSize {
+					carry = req
+					break collect
+				}
+				batch = append(batch, req)
+				size += len(req.inputs)
+			case <-timer.C:
+				break collect
+			case <-p.stopCh:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		p.process(batch, size)
+	}
+}
+
+// process 一次向量化整批文本并按请求拆分结果，失败时批内所有请求返回同一错误
+func (p *batchPipeline) process(batch []*embedRequest, size int) {
+	inputs := make([]string, 0, size)
+	for _, req := range batch {
+		inputs = append(inputs, req.inputs...)
+	}
+
+	vectors, err := p.model.EncodeBatch(inputs)
+	if err == nil && len(vectors) != len(inputs) {
+		err = fmt.Errorf("embedding backend returned %d vectors for %d texts", len(vectors), len(inputs))
+	}
+
+	offset := 0
+	for _, req := range batch {
+		if err != nil {
+			req.finish(nil, err)
+			continue
+		}
+		req.finish(vectors[offset:offset+len(req.inputs)], nil)
+		offset += len(req.inputs)
+	}
+}
+
+// close 停止接收请求，工作协程处理完当前批次后退出，仍在队列中的请求返回错误；
+// 先关闭stopCh唤醒阻塞在满队列上的提交方，再等待进行中的提交结束
+func (p *batchPipeline) close() {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	p.wg.Wait()
+	for {
+		select {
+		case req := <-p.queue:
+			req.finish(nil, fmt.Errorf("embedding pipeline is closed"))
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package embedding
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// DefaultPreprocessRules 内置模板化规则，具体的模式在前，避免被通用的数字、路径规则提前替换
+func DefaultPreprocessRules() []types.PreprocessRule {
+	return []types.PreprocessRule{
+		{Pattern: `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`, Replacement: "[EMAIL]"},
+		{Pattern: `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, Replacement: "[UUID]"},
+		{Pattern: `\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`, Replacement: "[IP]"},
+		{Pattern: `\b\d{4}[- ]?\d{4}[- ]?\d{4}[- ]?\d{4}\b`, Replacement: "[CARD]"},
+		{Pattern: `\b\d{11}\b`, Replacement: "[PHONE]"},
+		{Pattern: `\b[A-Za-z0-9]{20,}\b`, Replacement: "[TOKEN]"},
+		{Pattern: `/[a-zA-Z0-9/._-]+`, Replacement: "[PATH]"},
+		{Pattern: `\b\d+\b`, Replacement: "[NUMBER]"},
+	}
+}
+
+// compiledRule 编译后的模板化规则
+type compiledRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// ruleset 预处理规则集，版本为规则内容的摘要，规则相同则版本相同
+type ruleset struct {
+	rules   []compiledRule
+	version string
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// newRuleset 编译预处理规则集
+func newRuleset(rules []types.PreprocessRule) (*ruleset, error) {
+	if len(rules) == 0 {
+		rules = DefaultPreprocessRules()
+	}
+
+	rs := &ruleset{rules: make([]compiledRule, 0, len(rules))}
+	hash := sha256.New()
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preprocess rule %d: %v", i, err)
+		}
+		rs.rules = append(rs.rules, compiledRule{re: re, replacement: rule.Replacement})
+		fmt.Fprintf(hash, "%s\x00%s\x00", rule.Pattern, rule.Replacement)
+	}
+	rs.version = hex.EncodeToString(hash.Sum(nil))[:12]
+
+	return rs, nil
+}
+
+// apply 小写化后按顺序替换变量并压缩空白
+func (rs *ruleset) apply(text string) string {
+	if text == "" {
+		return text
+	}
+
+	text = strings.ToLower(text)
+	for _, rule := range rs.rules {
+		text = rule.re.ReplaceAllString(text, rule.replacement)
+	}
+
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
@@ -0,0 +1,205 @@
+package embedding
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxWordRunes 超过该长度的单词直接记为[UNK]
+const maxWordRunes = 100
+
+// WordPieceTokenizer BERT系列模型（BGE、MiniLM）使用的WordPiece分词器
+type WordPieceTokenizer struct {
+	vocab     map[string]int64
+	tokens    []string
+	maxLength int
+	lowercase bool
+	cls       int64
+	sep       int64
+	unk       int64
+	pad       int64
+}
+
+// NewWordPieceTokenizer 从vocab.txt加载词表，每行一个词，行号即ID；maxLength包含[CLS]和[SEP]
+func NewWordPieceTokenizer(vocabPath string, maxLength int, lowercase bool) (*WordPieceTokenizer, error) {
+	if maxLength < 3 {
+		return nil, fmt.Errorf("max sequence length %d is too short", maxLength)
+	}
+
+	file, err := os.Open(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocab file: %v", err)
+	}
+	defer file.Close()
+
+	vocab := make(map[string]int64)
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		token := strings.TrimRight(scanner.Text(), "\r")
+		if _, exists := vocab[token]; !exists {
+			vocab[token] = int64(len(tokens))
+		}
+		tokens = append(tokens, token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocab file: %v", err)
+	}
+
+	t := &WordPieceTokenizer{vocab: vocab, tokens: tokens, maxLength: maxLength, lowercase: lowercase}
+	for token, target := range map[string]*int64{"[CLS]": &t.cls, "[SEP]": &t.sep, "[UNK]": &t.unk, "[PAD]": &t.pad} {
+		value, exists := vocab[token]
+		if !exists {
+			return nil, fmt.Errorf("vocab is missing special token %s", token)
+		}
+		*target = value
+	}
+	return t, nil
+}
+
+// PadID 填充标记ID
+func (t *WordPieceTokenizer) PadID() int64 {
+	return t.pad
+}
+
+// Encode 分词并转换为ID，以[CLS]开头、[SEP]结尾，超出最大长度时截断
+func (t *WordPieceTokenizer) Encode(text string) []int64 {
+	ids := make([]int64, 0, 32)
+	ids = append(ids, t.cls)
+	limit := t.maxLength - 1
+
+	for _, word := range t.basicTokenize(text) {
+		for _, id := range t.wordPiece(word) {
+			if len(ids) >= limit {
+				return append(ids, t.sep)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return append(ids, t.sep)
+}
+
+// Tokens 分词结果的文本形式，不含[CLS]和[SEP]
+func (t *WordPieceTokenizer) Tokens(text string) []string {
+	ids := t.Encode(text)
+	tokens := make([]string, 0, len(ids)-2)
+	for _, id := range ids[1 : len(ids)-1] {
+		tokens = append(tokens, t.tokens[id])
+	}
+	return tokens
+}
+
+// basicTokenize 清理控制字符，按空白和标点切分，中日韩字符单独成词，按需转小写并去除重音
+func (t *WordPieceTokenizer) basicTokenize(text string) []string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar:
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+		case unicode.IsControl(r):
+		case isCJK(r):
+			b.WriteByte(' ')
+			b.WriteRune(r)
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	var words []string
+	for _, token := range strings.Fields(b.String()) {
+		if t.lowercase {
+			token = stripAccents(strings.ToLower(token))
+		}
+		words = append(words, splitPunctuation(token)...)
+	}
+	return words
+}
+
+// wordPiece 贪心最长匹配切分单词，后续片段带"##"前缀，无法切分时整个单词记为[UNK]
+func (t *WordPieceTokenizer) wordPiece(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordRunes {
+		return []int64{t.unk}
+	}
+
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var id int64 = -1
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if value, exists := t.vocab[piece]; exists {
+				id = value
+				break
+			}
+		}
+		if id < 0 {
+			return []int64{t.unk}
+		}
+		ids = append(ids, id)
+		start = end
+	}
+	return ids
+}
+
+// stripAccents 分解后去除组合音标
+func stripAccents(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitPunctuation 标点单独成词
+func splitPunctuation(token string) []string {
+	var words []string
+	var current []rune
+	for _, r := range token {
+		if isPunctuation(r) {
+			if len(current) > 0 {
+				words = append(words, string(current))
+				current = current[:0]
+			}
+			words = append(words, string(r))
+			continue
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	return words
+}
+
+// isPunctuation 与BERT一致，ASCII中非字母数字的可见字符均视为标点
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isCJK 中日韩统一表意文字
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
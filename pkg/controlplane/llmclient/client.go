@@ -0,0 +1,113 @@
+package llmclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// maxResponseBytes 响应体大小上限
+const maxResponseBytes = 1 << 20
+
+// Client OpenAI兼容chat/completions接口客户端，控制面用于生成簇摘要等文本
+type Client struct {
+	url       string
+	apiKey    string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+// New 创建客户端
+func New(config *types.LLMClientConfig) (*Client, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("llm endpoint is required")
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("llm model is required")
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	maxTokens := config.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 300
+	}
+
+	return &Client{
+		url:       config.Endpoint,
+		apiKey:    os.ExpandEnv(config.APIKey),
+		model:     config.Model,
+		maxTokens: maxTokens,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Complete 以系统提示词和用户消息调用模型，返回首个候选的文本
+func (c *Client) Complete(ctx context.Context, system, prompt string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": c.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens":  c.maxTokens,
+		"temperature": 0,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal completion request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create completion request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call llm: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read completion response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("llm returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("failed to parse completion response: %v", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("llm returned no choices")
+	}
+
+	content := strings.TrimSpace(result.Choices[0].Message.Content)
+	if content == "" {
+		return "", fmt.Errorf("llm returned empty content")
+	}
+	return content, nil
+}
@@ -0,0 +1,171 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultKeyPrefix 默认锁键前缀
+const defaultKeyPrefix = "controlplane:lock:"
+
+// releaseScript 只释放自己持有的锁
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// renewScript 只续期自己持有的锁
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// RedisLock 基于Redis的控制面任务互斥锁：SET NX PX获取，任务执行期间按TTL的三分之一续期，
+// 进程崩溃时锁在TTL后自动过期
+type RedisLock struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+	owner  string
+}
+
+// NewRedisLock 创建Redis任务锁
+func NewRedisLock(config *types.JobLockConfig) *RedisLock {
+	prefix := config.KeyPrefix
+	if prefix == "" {
+		prefix = defaultKeyPrefix
+	}
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:       config.Redis.Addresses,
+		Password:    config.Redis.Password,
+		DB:          config.Redis.DB,
+		PoolSize:    config.Redis.PoolSize,
+		DialTimeout: config.Redis.Timeout,
+	})
+
+	hostname, _ := os.Hostname()
+	return &RedisLock{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+		owner:  fmt.Sprintf("%s-%s", hostname, utils.RandomID()[:8]),
+	}
+}
+
+// Run 获取锁后执行任务，锁被其他实例持有时跳过；Redis不可用时返回错误且不执行任务
+func (rl *RedisLock) Run(job string, fn func() error) (bool, error) {
+	key := rl.prefix + job
+	token := rl.owner + "-" + utils.RandomID()[:8]
+
+	ctx := context.Background()
+	acquired, err := rl.client.SetNX(ctx, key, token, rl.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %v", key, err)
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	stopRenew := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rl.renewLoop(key, token, stopRenew)
+	}()
+
+	defer func() {
+		close(stopRenew)
+		wg.Wait()
+		if err := releaseScript.Run(ctx, rl.client, []string{key}, token).Err(); err != nil {
+			log.Printf("Failed to release lock %s: %v", key, err)
+		}
+	}()
+
+	return true, fn()
+}
+
+// renewLoop 任务执行期间续期
+func (rl *RedisLock) renewLoop(key, token string, stopCh chan struct{}) {
+	ticker := time.NewTicker(rl.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			renewed, err := renewScript.Run(context.Background(), rl.client, []string{key}, token, rl.ttl.Milliseconds()).Int()
+			if err != nil {
+				log.Printf("Failed to renew lock %s: %v", key, err)
+				continue
+			}
+			if renewed == 0 {
+				log.Printf("Lost lock %s while job is still running", key)
+				return
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Close 关闭Redis连接
+func (rl *RedisLock) Close() error {
+	return rl.client.Close()
+}
+
+// LocalLock 进程内任务锁，未配置分布式锁时只防止同一实例内任务重叠执行
+type LocalLock struct {
+	running map[string]bool
+	mutex   sync.Mutex
+}
+
+// NewLocalLock 创建进程内任务锁
+func NewLocalLock() *LocalLock {
+	return &LocalLock{running: make(map[string]bool)}
+}
+
+// Run 任务未在执行时执行，否则跳过
+func (ll *LocalLock) Run(job string, fn func() error) (bool, error) {
+	ll.mutex.Lock()
+	if ll.running[job] {
+		ll.mutex.Unlock()
+		return false, nil
+	}
+	ll.running[job] = true
+	ll.mutex.Unlock()
+
+	defer func() {
+		ll.mutex.Lock()
+		delete(ll.running, job)
+		ll.mutex.Unlock()
+	}()
+
+	return true, fn()
+}
+
+// NewJobLock 按配置创建任务锁，未启用时使用进程内锁
+func NewJobLock(config *types.JobLockConfig) interfaces.JobLock {
+	if config.Enabled {
+		return NewRedisLock(config)
+	}
+	return NewLocalLock()
+}
@@ -0,0 +1,210 @@
+package narrative
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/llmclient"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// systemPrompt 生成事件说明的系统提示词
+const systemPrompt = "You are an SRE assistant writing for the on-call engineer of an LLM API gateway. " +
+	"Given an error cluster, its recent metrics, representative errors and the mitigation policies applied, " +
+	"write a short incident narrative: what is failing, how fast it is changing, what has been done so far " +
+	"and what to check next. Use plain prose, at most five sentences."
+
+// cachedNarrative 缓存的事件说明
+type cachedNarrative struct {
+	narrative *types.IncidentNarrative
+	expires   time.Time
+}
+
+// Narrator 汇总簇成员、近期指标和已下发的策略，调用LLM生成事件说明
+type Narrator struct {
+	config      types.IncidentNarrativeConfig
+	client      *llmclient.Client
+	engine      interfaces.ClusteringEngine
+	series      interfaces.TimeSeriesStore
+	store       interfaces.ConfigStore              // 可选，读取已下发的策略
+	gateways    interfaces.GatewayMetricsStore      // 可选，网关上报的簇速率
+	escalations interfaces.PolicyEscalationReporter // 可选，策略升级事件
+	cache       map[string]*cachedNarrative
+	mutex       sync.Mutex
+}
+
+// NewNarrator 创建事件说明生成
+func NewNarrator(config *types.IncidentNarrativeConfig, engine interfaces.ClusteringEngine, series interfaces.TimeSeriesStore) (*Narrator, error) {
+	cfg := *config
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 5
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Minute
+	}
+
+	client, err := llmclient.New(&cfg.LLM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create narrative llm client: %v", err)
+	}
+
+	return &Narrator{
+		config: cfg,
+		client: client,
+		engine: engine,
+		series: series,
+		cache:  make(map[string]*cachedNarrative),
+	}, nil
+}
+
+// SetConfigStore 设置配置中心，用于读取簇当前下发的策略
+func (n *Narrator) SetConfigStore(store interfaces.ConfigStore) {
+	n.store = store
+}
+
+// SetGatewayMetrics 设置网关计数快照存储，优先使用网关上报的簇速率
+func (n *Narrator) SetGatewayMetrics(store interfaces.GatewayMetricsStore) {
+	n.gateways = store
+}
+
+// SetEscalationReporter 设置策略升级事件来源
+func (n *Narrator) SetEscalationReporter(escalations interfaces.PolicyEscalationReporter) {
+	n.escalations = escalations
+}
+
+// Explain 生成簇的事件说明，缓存有效期内直接返回上次结果
+func (n *Narrator) Explain(clusterID string) (*types.IncidentNarrative, error) {
+	now := time.Now()
+	n.mutex.Lock()
+	if cached, ok := n.cache[clusterID]; ok && now.Before(cached.expires) {
+		n.mutex.Unlock()
+		return cached.narrative, nil
+	}
+	n.mutex.Unlock()
+
+	result, err := n.gather(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := n.client.Complete(context.Background(), systemPrompt, prompt(result))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate narrative: %v", err)
+	}
+	result.Narrative = text
+	result.GeneratedAt = now
+
+	n.mutex.Lock()
+	for id, cached := range n.cache {
+		if now.After(cached.expires) {
+			delete(n.cache, id)
+		}
+	}
+	n.cache[clusterID] = &cachedNarrative{narrative: result, expires: now.Add(n.config.CacheTTL)}
+	n.mutex.Unlock()
+
+	return result, nil
+}
+
+// gather 收集簇信息、近期指标、代表性错误、当前策略和升级事件
+func (n *Narrator) gather(clusterID string) (*types.IncidentNarrative, error) {
+	explanation, err := n.engine.ExplainCluster(clusterID, n.config.Samples)
+	if err != nil {
+		return nil, err
+	}
+	cluster := explanation.Cluster
+
+	result := &types.IncidentNarrative{
+		ClusterID:   cluster.ID,
+		Description: cluster.Description,
+		Summary:     cluster.Summary,
+		ErrorCount:  cluster.ErrorCount,
+		Members:     len(cluster.Members),
+		Window:      n.config.Window.String(),
+		Samples:     make([]string, 0, len(explanation.Central)),
+	}
+	for _, member := range explanation.Central {
+		result.Samples = append(result.Samples, member.Signature)
+	}
+
+	if rates, ok := n.gatewayRates(clusterID); ok {
+		result.ErrorRate = rates.Errors
+		result.GrowthRate = rates.ErrorGrowth
+		result.RequestRate = &rates.Requests
+	} else if n.series != nil {
+		result.ErrorRate = n.series.Rate(clusterID, n.config.Window)
+		result.GrowthRate = n.series.GrowthRate(clusterID, n.config.Window)
+	}
+
+	if n.store != nil {
+		if value, err := n.store.Get(utils.PolicyKey("", clusterID)); err == nil && value != "" {
+			var policy types.Policy
+			if json.Unmarshal([]byte(value), &policy) == nil {
+				result.Policy = &policy
+			}
+		}
+	}
+	if n.escalations != nil {
+		result.Escalations = n.escalations.Events(clusterID)
+	}
+
+	return result, nil
+}
+
+// gatewayRates 获取网关上报的簇速率
+func (n *Narrator) gatewayRates(clusterID string) (types.ClusterRates, bool) {
+	if n.gateways == nil {
+		return types.ClusterRates{}, false
+	}
+	return n.gateways.ClusterRates(clusterID, n.config.Window)
+}
+
+// prompt 将上下文整理为提示词
+func prompt(result *types.IncidentNarrative) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cluster %s: %s\n", result.ClusterID, result.Description)
+	if result.Summary != "" {
+		fmt.Fprintf(&b, "Root cause summary: %s\n", result.Summary)
+	}
+	fmt.Fprintf(&b, "Total errors: %d, members: %d\n", result.ErrorCount, result.Members)
+	fmt.Fprintf(&b, "Last %s: %.2f errors/s, growth %.2f", result.Window, result.ErrorRate, result.GrowthRate)
+	if result.RequestRate != nil {
+		fmt.Fprintf(&b, ", %.2f requests/s", *result.RequestRate)
+	}
+	b.WriteString("\n")
+
+	if len(result.Samples) > 0 {
+		b.WriteString("Representative errors:\n")
+		for i, sample := range result.Samples {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, sample)
+		}
+	}
+
+	if policy := result.Policy; policy != nil {
+		fmt.Fprintf(&b, "Applied policy: %s (template %s, severity %.2f, expires %s)",
+			policy.PolicyType, policy.Template, policy.Severity, policy.ExpireTime.Format(time.RFC3339))
+		if policy.RateLimit != nil {
+			fmt.Fprintf(&b, ", limit rate %.2f", policy.RateLimit.LimitRate)
+		}
+		if policy.CircuitBreak != nil {
+			fmt.Fprintf(&b, ", break %v", policy.CircuitBreak.BreakDuration)
+		}
+		b.WriteString("\n")
+	} else {
+		b.WriteString("No mitigation policy is applied\n")
+	}
+
+	for _, event := range result.Escalations {
+		fmt.Fprintf(&b, "Escalation %s at %s: stage %d %s\n", event.Action, event.Time.Format(time.RFC3339), event.Stage, event.Template)
+	}
+	return b.String()
+}
@@ -0,0 +1,351 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/monitoring"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// EventPolicyGap 策略控制环路问题告警事件类型
+const EventPolicyGap = "policy_gap"
+
+// Auditor 策略控制环路巡检：定期比对配置中心中的策略和聚类引擎中的簇，
+// 发现陈旧策略、孤立策略和缺少策略的高严重度簇，按类型更新指标，问题持续超过grace时告警一次
+type Auditor struct {
+	config   *types.PolicyAuditConfig
+	engine   interfaces.ClusteringEngine
+	store    interfaces.ConfigStore
+	severity func(clusterID string) float64 // 按当前错误速率和增长率计算簇的严重度
+	jobLock  interfaces.JobLock             // 可选，多实例部署时只在一个实例上巡检，避免重复告警
+	notifier interfaces.Notifier
+	metrics  *monitoring.MetricsCollector
+	since    map[string]time.Time // 问题键 -> 首次发现时间
+	alerted  map[string]bool      // 已告警的问题键，问题消失后清除
+	latest   *types.PolicyAuditReport
+	mutex    sync.RWMutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewAuditor 创建策略巡检，severity计算簇当前的严重度，与策略引擎触发策略时使用的算法一致
+func NewAuditor(config *types.PolicyAuditConfig, engine interfaces.ClusteringEngine, store interfaces.ConfigStore,
+	severity func(clusterID string) float64) *Auditor {
+	cfg := *config
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = time.Hour
+	}
+	if cfg.SeverityThreshold <= 0 {
+		cfg.SeverityThreshold = 0.5
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = 2 * time.Minute
+	}
+
+	return &Auditor{
+		config:   &cfg,
+		engine:   engine,
+		store:    store,
+		severity: severity,
+		metrics:  monitoring.NewMetricsCollector(),
+		since:    make(map[string]time.Time),
+		alerted:  make(map[string]bool),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetNotifier 设置告警通知器
+func (a *Auditor) SetNotifier(notifier interfaces.Notifier) {
+	a.notifier = notifier
+}
+
+// SetJobLock 设置任务锁，定期巡检在获取锁后执行
+func (a *Auditor) SetJobLock(lock interfaces.JobLock) {
+	a.jobLock = lock
+}
+
+// Start 启动定期巡检
+func (a *Auditor) Start() error {
+	a.wg.Add(1)
+	go a.loop()
+
+	log.Printf("Policy auditor started (interval=%v, max_age=%v, severity_threshold=%.2f)",
+		a.config.Interval, a.config.MaxAge, a.config.SeverityThreshold)
+	return nil
+}
+
+// Stop 停止巡检
+func (a *Auditor) Stop() error {
+	close(a.stopCh)
+	a.wg.Wait()
+	return nil
+}
+
+// LatestAudit 获取最近一次巡检结果，尚未巡检时为nil
+func (a *Auditor) LatestAudit() *types.PolicyAuditReport {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.latest
+}
+
+// loop 定期巡检
+func (a *Auditor) loop() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.scheduledAudit(); err != nil {
+				log.Printf("Policy audit failed: %v", err)
+			}
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// scheduledAudit 定期巡检，锁被其他实例持有时跳过本轮
+func (a *Auditor) scheduledAudit() error {
+	audit := func() error {
+		_, err := a.Audit(time.Now())
+		return err
+	}
+	if a.jobLock == nil {
+		return audit()
+	}
+	_, err := a.jobLock.Run("policy-audit", audit)
+	return err
+}
+
+// auditedPolicy 巡检读取的策略
+type auditedPolicy struct {
+	key        string
+	region     string
+	clusterKey string
+	policy     types.Policy
+}
+
+// Audit 执行一次巡检
+func (a *Auditor) Audit(now time.Time) (*types.PolicyAuditReport, error) {
+	policies, err := a.loadPolicies()
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := a.engine.GetAllClusters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clusters: %v", err)
+	}
+
+	var gaps []types.PolicyGap
+	covered := make(map[string]bool)
+	for _, p := range policies {
+		clusterID, exists := resolveCluster(clusters, p)
+		covered[clusterID] = true
+
+		if !exists {
+			gaps = append(gaps, types.PolicyGap{
+				Kind:      types.PolicyGapOrphaned,
+				ClusterID: clusterID,
+				PolicyKey: p.key,
+				Region:    p.region,
+				Detail:    fmt.Sprintf("%s policy references cluster %s which no longer exists", p.policy.PolicyType, clusterID),
+			})
+		}
+
+		if !p.policy.IsActive {
+			continue
+		}
+		if age := now.Sub(p.policy.CreateTime); !p.policy.CreateTime.IsZero() && age > a.config.MaxAge {
+			gaps = append(gaps, types.PolicyGap{
+				Kind:      types.PolicyGapStale,
+				ClusterID: clusterID,
+				PolicyKey: p.key,
+				Region:    p.region,
+				Severity:  p.policy.Severity,
+				Detail:    fmt.Sprintf("%s policy has been active for %v", p.policy.PolicyType, age.Round(time.Second)),
+			})
+		} else if !p.policy.ExpireTime.IsZero() && now.After(p.policy.ExpireTime) {
+			gaps = append(gaps, types.PolicyGap{
+				Kind:      types.PolicyGapStale,
+				ClusterID: clusterID,
+				PolicyKey: p.key,
+				Region:    p.region,
+				Severity:  p.policy.Severity,
+				Detail:    fmt.Sprintf("%s policy expired %v ago but is still marked active", p.policy.PolicyType, now.Sub(p.policy.ExpireTime).Round(time.Second)),
+			})
+		}
+	}
+
+	for clusterID, cluster := range clusters {
+		if covered[clusterID] {
+			continue
+		}
+		severity := a.severity(clusterID)
+		if severity < a.config.SeverityThreshold {
+			continue
+		}
+		gaps = append(gaps, types.PolicyGap{
+			Kind:      types.PolicyGapUncovered,
+			ClusterID: clusterID,
+			Severity:  severity,
+			Detail:    fmt.Sprintf("cluster has severity %.2f and %d errors but no policy", severity, cluster.ErrorCount),
+		})
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Kind != gaps[j].Kind {
+			return gaps[i].Kind < gaps[j].Kind
+		}
+		return gapKey(&gaps[i]) < gapKey(&gaps[j])
+	})
+
+	report := &types.PolicyAuditReport{
+		CheckedAt: now,
+		Policies:  len(policies),
+		Clusters:  len(clusters),
+		Gaps:      gaps,
+	}
+	alerts := a.track(report, now)
+
+	counts := map[string]int{types.PolicyGapStale: 0, types.PolicyGapOrphaned: 0, types.PolicyGapUncovered: 0}
+	for _, gap := range gaps {
+		counts[gap.Kind]++
+	}
+	for kind, count := range counts {
+		a.metrics.RecordPolicyGaps(kind, count)
+	}
+
+	for _, gap := range alerts {
+		a.notify(gap, now)
+	}
+	return report, nil
+}
+
+// track 记录各问题的首次发现时间，返回持续超过grace且尚未告警的问题，清除已消失的问题
+func (a *Auditor) track(report *types.PolicyAuditReport, now time.Time) []types.PolicyGap {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	current := make(map[string]bool, len(report.Gaps))
+	var alerts []types.PolicyGap
+	for i := range report.Gaps {
+		gap := &report.Gaps[i]
+		key := gapKey(gap)
+		current[key] = true
+
+		since, exists := a.since[key]
+		if !exists {
+			since = now
+			a.since[key] = now
+		}
+		gap.Since = since
+
+		if !a.alerted[key] && now.Sub(since) >= a.config.Grace {
+			a.alerted[key] = true
+			alerts = append(alerts, *gap)
+		}
+	}
+
+	for key := range a.since {
+		if !current[key] {
+			delete(a.since, key)
+			delete(a.alerted, key)
+		}
+	}
+
+	a.latest = report
+	return alerts
+}
+
+// notify 发送告警，缺少策略的高严重度簇为critical，其余为warning
+func (a *Auditor) notify(gap types.PolicyGap, now time.Time) {
+	log.Printf("Policy control gap: kind=%s cluster=%s key=%s: %s", gap.Kind, gap.ClusterID, gap.PolicyKey, gap.Detail)
+	if a.notifier == nil {
+		return
+	}
+
+	severity := types.AlertSeverityWarning
+	if gap.Kind == types.PolicyGapUncovered {
+		severity = types.AlertSeverityCritical
+	}
+	labels := map[string]string{"kind": gap.Kind, "cluster_id": gap.ClusterID}
+	if gap.PolicyKey != "" {
+		labels["policy_key"] = gap.PolicyKey
+	}
+	if gap.Region != "" {
+		labels["region"] = gap.Region
+	}
+
+	a.notifier.Notify(&types.AlertEvent{
+		Type:     EventPolicyGap,
+		Severity: severity,
+		Title:    fmt.Sprintf("Policy control gap (%s) for cluster %s", gap.Kind, gap.ClusterID),
+		Message:  fmt.Sprintf("%s, first seen %v ago", gap.Detail, now.Sub(gap.Since).Round(time.Second)),
+		Labels:   labels,
+		Time:     now,
+	})
+}
+
+// loadPolicies 读取全局和各区域的簇策略，WAF规则集不关联簇，不参与巡检
+func (a *Auditor) loadPolicies() ([]auditedPolicy, error) {
+	var policies []auditedPolicy
+	for _, prefix := range []string{utils.GlobalPolicyPrefix, utils.RegionPrefix} {
+		values, err := a.store.GetWithPrefix(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load policies: %v", err)
+		}
+		for key, value := range values {
+			region, clusterKey, ok := utils.ParsePolicyKey(key)
+			if !ok {
+				continue
+			}
+
+			var policy types.Policy
+			if err := json.Unmarshal([]byte(value), &policy); err != nil {
+				log.Printf("Skipping undecodable policy %s in audit: %v", key, err)
+				continue
+			}
+			if policy.PolicyType == types.WAF || policy.WAF != nil {
+				continue
+			}
+			policies = append(policies, auditedPolicy{key: key, region: region, clusterKey: clusterKey, policy: policy})
+		}
+	}
+	return policies, nil
+}
+
+// resolveCluster 确定策略对应的簇ID，簇键带路由命名空间时按去掉命名空间后的簇ID查找
+func resolveCluster(clusters map[string]*types.Cluster, p auditedPolicy) (string, bool) {
+	clusterID := p.policy.ClusterID
+	if clusterID == "" {
+		clusterID = p.clusterKey
+	}
+	if _, exists := clusters[clusterID]; exists {
+		return clusterID, true
+	}
+	if idx := strings.LastIndex(clusterID, "/"); idx >= 0 {
+		if _, exists := clusters[clusterID[idx+1:]]; exists {
+			return clusterID[idx+1:], true
+		}
+	}
+	return clusterID, false
+}
+
+// gapKey 问题的唯一键
+func gapKey(gap *types.PolicyGap) string {
+	return gap.Kind + "|" + gap.ClusterID + "|" + gap.PolicyKey
+}
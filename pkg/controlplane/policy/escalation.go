@@ -0,0 +1,272 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// 升级动作
+const (
+	ActionStart      = "start"
+	ActionEscalate   = "escalate"
+	ActionDeescalate = "deescalate"
+	ActionPage       = "page"
+	ActionResolve    = "resolve"
+)
+
+// EventPolicyEscalation 策略升级告警事件类型
+const EventPolicyEscalation = "policy_escalation"
+
+// escalationState 单个簇的升级状态
+type escalationState struct {
+	stage        int
+	stageStart   time.Time
+	stageRate    float64 // 进入当前阶段时的错误速率
+	recoverSince time.Time
+	paged        bool
+	policy       *types.Policy
+}
+
+// Escalator 策略逐级升级：首次触发时应用第一阶段模板，阶段持续stage_duration后错误速率仍高于进入时则升级，
+// 最后阶段仍在增长时通知值班人员；指标低于阈值持续recovery_duration后降级一个阶段，降到底时删除策略
+type Escalator struct {
+	config     *types.EscalationConfig
+	templates  *TemplateRegistry
+	store      interfaces.ConfigStore
+	defaultTTL time.Duration
+	notifier   interfaces.Notifier
+	states     map[string]*escalationState
+	events     []types.EscalationEvent
+	mutex      sync.Mutex
+}
+
+// NewEscalator 创建策略升级器，阶段引用的模板必须存在于模板库
+func NewEscalator(config *types.EscalationConfig, templates *TemplateRegistry, store interfaces.ConfigStore, defaultTTL time.Duration) (*Escalator, error) {
+	cfg := *config
+	if len(cfg.Stages) == 0 {
+		cfg.Stages = []string{TemplateSoftThrottle, TemplateHardThrottle, TemplateShortBreak}
+	}
+	if cfg.StageDuration <= 0 {
+		cfg.StageDuration = 5 * time.Minute
+	}
+	if cfg.RecoveryDuration <= 0 {
+		cfg.RecoveryDuration = 5 * time.Minute
+	}
+	if cfg.MaxEvents <= 0 {
+		cfg.MaxEvents = 1000
+	}
+
+	for _, name := range cfg.Stages {
+		if _, ok := templates.Get(name); !ok {
+			return nil, fmt.Errorf("escalation stage references unknown policy template %s", name)
+		}
+	}
+
+	return &Escalator{
+		config:     &cfg,
+		templates:  templates,
+		store:      store,
+		defaultTTL: defaultTTL,
+		states:     make(map[string]*escalationState),
+	}, nil
+}
+
+// SetNotifier 设置告警通知器，升级、降级和值班通知都会发送告警
+func (e *Escalator) SetNotifier(notifier interfaces.Notifier) {
+	e.notifier = notifier
+}
+
+// Observe 根据簇的最新指标推进升级状态，elevated表示指标仍处于需要缓解的水平
+func (e *Escalator) Observe(clusterID string, errorRate, growthRate, severity float64, elevated bool, now time.Time) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	state, exists := e.states[clusterID]
+	if !exists {
+		if !elevated {
+			return nil
+		}
+		state = &escalationState{}
+		e.states[clusterID] = state
+		return e.enterStage(clusterID, state, 0, ActionStart, errorRate, growthRate, severity, now)
+	}
+
+	if elevated {
+		state.recoverSince = time.Time{}
+
+		if now.Sub(state.stageStart) < e.config.StageDuration || errorRate <= state.stageRate {
+			return e.refresh(state, now)
+		}
+
+		if state.stage < len(e.config.Stages)-1 {
+			return e.enterStage(clusterID, state, state.stage+1, ActionEscalate, errorRate, growthRate, severity, now)
+		}
+
+		if e.config.Page && !state.paged {
+			state.paged = true
+			e.record(clusterID, ActionPage, state.stage, errorRate, growthRate, now)
+		}
+		return e.refresh(state, now)
+	}
+
+	if state.recoverSince.IsZero() {
+		state.recoverSince = now
+	}
+	if now.Sub(state.recoverSince) < e.config.RecoveryDuration {
+		return e.refresh(state, now)
+	}
+
+	if state.stage == 0 {
+		delete(e.states, clusterID)
+		if err := e.store.Delete(utils.PolicyKey("", clusterID)); err != nil {
+			return fmt.Errorf("failed to delete policy: %v", err)
+		}
+		e.record(clusterID, ActionResolve, -1, errorRate, growthRate, now)
+		return nil
+	}
+
+	state.recoverSince = now
+	state.paged = false
+	return e.enterStage(clusterID, state, state.stage-1, ActionDeescalate, errorRate, growthRate, severity, now)
+}
+
+// Stage 获取簇当前所处阶段，未升级时返回-1
+func (e *Escalator) Stage(clusterID string) int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if state, ok := e.states[clusterID]; ok {
+		return state.stage
+	}
+	return -1
+}
+
+// Events 获取升级事件，按时间升序，簇ID为空时返回全部
+func (e *Escalator) Events(clusterID string) []types.EscalationEvent {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	events := make([]types.EscalationEvent, 0, len(e.events))
+	for _, event := range e.events {
+		if clusterID == "" || event.ClusterID == clusterID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// enterStage 应用阶段模板并记录事件（需要加锁调用）
+func (e *Escalator) enterStage(clusterID string, state *escalationState, stage int, action string, errorRate, growthRate, severity float64, now time.Time) error {
+	tpl, _ := e.templates.Get(e.config.Stages[stage])
+
+	// 升级到的阶段严重度不低于模板区间下界，保证熔断模板能立即生效
+	if severity < tpl.MinSeverity {
+		severity = tpl.MinSeverity
+	}
+
+	policy := InstantiateTemplate(tpl, clusterID, severity, e.defaultTTL, now)
+	if err := e.put(policy); err != nil {
+		return err
+	}
+
+	state.stage = stage
+	state.stageStart = now
+	state.stageRate = errorRate
+	state.policy = policy
+
+	e.record(clusterID, action, stage, errorRate, growthRate, now)
+	return nil
+}
+
+// refresh 当前阶段策略有效期过半时续期，避免升级过程中策略失效（需要加锁调用）
+func (e *Escalator) refresh(state *escalationState, now time.Time) error {
+	policy := state.policy
+	if policy == nil {
+		return nil
+	}
+	ttl := policy.ExpireTime.Sub(policy.CreateTime)
+	if now.Before(policy.CreateTime.Add(ttl / 2)) {
+		return nil
+	}
+
+	renewed := *policy
+	renewed.CreateTime = now
+	renewed.ExpireTime = now.Add(ttl)
+	if err := e.put(&renewed); err != nil {
+		return err
+	}
+	state.policy = &renewed
+	return nil
+}
+
+// put 写入策略
+func (e *Escalator) put(policy *types.Policy) error {
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+	if err := e.store.Put(utils.PolicyKey("", policy.ClusterID), string(value)); err != nil {
+		return fmt.Errorf("failed to put policy: %v", err)
+	}
+	return nil
+}
+
+// record 记录升级事件并发送告警（需要加锁调用）
+func (e *Escalator) record(clusterID, action string, stage int, errorRate, growthRate float64, now time.Time) {
+	event := types.EscalationEvent{
+		ClusterID: clusterID,
+		Action:    action,
+		Stage:     stage,
+		ErrorRate: errorRate,
+		Growth:    growthRate,
+		Time:      now,
+	}
+	if stage >= 0 {
+		event.Template = e.config.Stages[stage]
+	}
+
+	e.events = append(e.events, event)
+	if len(e.events) > e.config.MaxEvents {
+		e.events = e.events[len(e.events)-e.config.MaxEvents:]
+	}
+
+	log.Printf("Policy escalation for cluster %s: action=%s stage=%d template=%s error_rate=%.2f",
+		clusterID, action, stage, event.Template, errorRate)
+
+	if e.notifier == nil {
+		return
+	}
+
+	severity := types.AlertSeverityInfo
+	switch action {
+	case ActionEscalate:
+		severity = types.AlertSeverityWarning
+	case ActionPage:
+		severity = types.AlertSeverityCritical
+	}
+
+	title := fmt.Sprintf("Policy for cluster %s: %s", clusterID, action)
+	if action == ActionPage {
+		title = fmt.Sprintf("Cluster %s is still degrading at the final mitigation stage", clusterID)
+	}
+
+	e.notifier.Notify(&types.AlertEvent{
+		Type:     EventPolicyEscalation,
+		Severity: severity,
+		Title:    title,
+		Message: fmt.Sprintf("stage %d (%s), error rate %.2f/s, growth %.0f%%",
+			stage, event.Template, errorRate, growthRate*100),
+		Labels: map[string]string{
+			"cluster_id": clusterID,
+			"action":     action,
+		},
+		Time: now,
+	})
+}
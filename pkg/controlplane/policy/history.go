@@ -0,0 +1,399 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// historyPrefix 策略历史持久化键前缀，键为"/policy-history/<cluster_key>/<unix_nano>"，区域策略追加"@<region>"
+const historyPrefix = "/policy-history/"
+
+// History 策略历史：记录全局和各区域策略的每次下发和删除，回答"某一时刻哪些策略在生效"，
+// 用于事后把网关的限流、熔断行为与当时的策略对应起来
+type History struct {
+	config  *types.PolicyHistoryConfig
+	store   interfaces.ConfigStore
+	entries map[string][]types.PolicyHistoryEntry // 策略etcd键 -> 按时间升序的变更记录
+	mutex   sync.RWMutex
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewHistory 创建策略历史
+func NewHistory(config *types.PolicyHistoryConfig, store interfaces.ConfigStore) *History {
+	cfg := *config
+	if cfg.Retention <= 0 {
+		cfg.Retention = 30 * 24 * time.Hour
+	}
+
+	return &History{
+		config:  &cfg,
+		store:   store,
+		entries: make(map[string][]types.PolicyHistoryEntry),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start 加载已持久化的历史，补录与当前策略不一致的变更，开始监听策略变更
+func (h *History) Start() error {
+	if err := h.loadHistory(); err != nil {
+		log.Printf("Failed to load policy history: %v", err)
+	}
+
+	// 停机期间的变更无法还原准确时间，以启动时间补录
+	now := time.Now()
+	current := make(map[string]bool)
+	for _, prefix := range []string{utils.GlobalPolicyPrefix, utils.RegionPrefix} {
+		policies, err := h.store.GetWithPrefix(prefix)
+		if err != nil {
+			return fmt.Errorf("failed to load policies: %v", err)
+		}
+		for key, value := range policies {
+			current[key] = true
+			h.RecordPut(key, value, 0, now)
+		}
+	}
+	h.mutex.RLock()
+	var removed []string
+	for key, entries := range h.entries {
+		if !current[key] && entries[len(entries)-1].Action == types.PolicyHistoryPut {
+			removed = append(removed, key)
+		}
+	}
+	h.mutex.RUnlock()
+	for _, key := range removed {
+		h.RecordDelete(key, 0, now)
+	}
+
+	for _, prefix := range []string{utils.GlobalPolicyPrefix, utils.RegionPrefix} {
+		events, err := h.store.Watch(prefix)
+		if err != nil {
+			return fmt.Errorf("failed to watch policies: %v", err)
+		}
+		h.wg.Add(1)
+		go h.watchLoop(events)
+	}
+
+	h.wg.Add(1)
+	go h.pruneLoop()
+
+	log.Printf("Policy history started (retention=%v, policies=%d)", h.config.Retention, len(current))
+	return nil
+}
+
+// Stop 停止记录
+func (h *History) Stop() error {
+	close(h.stopCh)
+	h.wg.Wait()
+	return nil
+}
+
+// RecordPut 记录策略下发，内容和版本都与该策略最近一次记录相同时忽略；revision为变更的ETCD修订号，策略未携带版本时作为版本
+func (h *History) RecordPut(key, value string, revision int64, at time.Time) {
+	region, clusterKey, ok := utils.ParsePolicyKey(key)
+	if !ok {
+		return
+	}
+
+	var policy types.Policy
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		log.Printf("Ignoring invalid policy %s in history: %v", key, err)
+		return
+	}
+	if policy.PolicyType == types.WAF || policy.WAF != nil {
+		return
+	}
+	if policy.Version == 0 {
+		policy.Version = revision
+	}
+
+	h.mutex.RLock()
+	entries := h.entries[key]
+	h.mutex.RUnlock()
+	if n := len(entries); n > 0 && entries[n-1].Action == types.PolicyHistoryPut {
+		// 启动时读取的策略没有修订号，沿用最近一次记录的版本再比较
+		if policy.Version == 0 && entries[n-1].Policy != nil {
+			policy.Version = entries[n-1].Policy.Version
+		}
+		if samePolicy(entries[n-1].Policy, &policy) {
+			return
+		}
+	}
+
+	h.record(key, types.PolicyHistoryEntry{
+		PolicyKey: clusterKey,
+		Region:    region,
+		Action:    types.PolicyHistoryPut,
+		Version:   policy.Version,
+		Time:      at,
+		Policy:    &policy,
+	})
+}
+
+// RecordDelete 记录策略删除，策略没有生效中的记录时忽略
+func (h *History) RecordDelete(key string, revision int64, at time.Time) {
+	region, clusterKey, ok := utils.ParsePolicyKey(key)
+	if !ok {
+		return
+	}
+
+	h.mutex.RLock()
+	entries := h.entries[key]
+	h.mutex.RUnlock()
+	if n := len(entries); n == 0 || entries[n-1].Action == types.PolicyHistoryDelete {
+		return
+	}
+
+	h.record(key, types.PolicyHistoryEntry{
+		PolicyKey: clusterKey,
+		Region:    region,
+		Action:    types.PolicyHistoryDelete,
+		Version:   revision,
+		Time:      at,
+	})
+}
+
+// ActiveAt 获取某一时刻生效的策略，按策略键排序。region不为空时按网关的解析规则为每个簇选出一个策略：
+// 该区域的策略优先，其次是未限定区域或包含该区域的全局策略；region为空时返回全局和各区域所有生效的策略
+func (h *History) ActiveAt(clusterKey, region string, at time.Time) []types.PolicyHistoryEntry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	global := make(map[string]types.PolicyHistoryEntry)
+	regional := make(map[string]types.PolicyHistoryEntry)
+	var active []types.PolicyHistoryEntry
+	for _, entries := range h.entries {
+		entry, ok := activeEntry(entries, at)
+		if !ok || !matchesClusterKey(entry.PolicyKey, clusterKey) {
+			continue
+		}
+		switch {
+		case region == "":
+			active = append(active, entry)
+		case entry.Region == region:
+			regional[entry.PolicyKey] = entry
+		case entry.Region == "" && appliesTo(entry.Policy, region):
+			global[entry.PolicyKey] = entry
+		}
+	}
+
+	for key, entry := range regional {
+		active = append(active, entry)
+		delete(global, key)
+	}
+	for _, entry := range global {
+		active = append(active, entry)
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].PolicyKey != active[j].PolicyKey {
+			return active[i].PolicyKey < active[j].PolicyKey
+		}
+		return active[i].Region < active[j].Region
+	})
+	return active
+}
+
+// Timeline 获取时间范围内的策略变更，from或to为零时不限制该端，按时间升序
+func (h *History) Timeline(clusterKey string, from, to time.Time) []types.PolicyHistoryEntry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	timeline := make([]types.PolicyHistoryEntry, 0)
+	for _, entries := range h.entries {
+		for _, entry := range entries {
+			if !matchesClusterKey(entry.PolicyKey, clusterKey) {
+				continue
+			}
+			if (!from.IsZero() && entry.Time.Before(from)) || (!to.IsZero() && entry.Time.After(to)) {
+				continue
+			}
+			timeline = append(timeline, entry)
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline
+}
+
+// watchLoop 处理策略变更事件
+func (h *History) watchLoop(events <-chan *interfaces.ConfigChangeEvent) {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case interfaces.ConfigChangeTypePut:
+				h.RecordPut(event.Key, event.Value, event.Revision, time.Now())
+			case interfaces.ConfigChangeTypeDelete:
+				h.RecordDelete(event.Key, event.Revision, time.Now())
+			}
+		case <-h.stopCh:
+			return
+		}
+	}
+}
+
+// pruneLoop 定期清理超过保留时长的记录
+func (h *History) pruneLoop() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.prune(time.Now().Add(-h.config.Retention))
+		case <-h.stopCh:
+			return
+		}
+	}
+}
+
+// prune 删除早于cutoff的记录，每个策略保留cutoff之前的最后一条，使保留期开始时生效的策略仍可查询；
+// 最后一条是删除记录时一并删除
+func (h *History) prune(cutoff time.Time) {
+	var expired []types.PolicyHistoryEntry
+
+	h.mutex.Lock()
+	for key, entries := range h.entries {
+		keep := sort.Search(len(entries), func(i int) bool { return !entries[i].Time.Before(cutoff) })
+		if keep > 0 && entries[keep-1].Action == types.PolicyHistoryPut {
+			keep--
+		}
+		if keep == 0 {
+			continue
+		}
+		expired = append(expired, entries[:keep]...)
+		if keep == len(entries) {
+			delete(h.entries, key)
+		} else {
+			h.entries[key] = append([]types.PolicyHistoryEntry(nil), entries[keep:]...)
+		}
+	}
+	h.mutex.Unlock()
+
+	for i := range expired {
+		if err := h.store.Delete(historyKey(&expired[i])); err != nil {
+			log.Printf("Failed to delete policy history entry: %v", err)
+		}
+	}
+	if len(expired) > 0 {
+		log.Printf("Pruned %d policy history entries older than %v", len(expired), cutoff.Format(time.RFC3339))
+	}
+}
+
+// record 追加并持久化变更记录
+func (h *History) record(key string, entry types.PolicyHistoryEntry) {
+	h.mutex.Lock()
+	entries := h.entries[key]
+	// 时钟回拨时保持记录按时间有序
+	if n := len(entries); n > 0 && entry.Time.Before(entries[n-1].Time) {
+		entry.Time = entries[n-1].Time
+	}
+	h.entries[key] = append(entries, entry)
+	h.mutex.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to marshal policy history entry: %v", err)
+		return
+	}
+	if err := h.store.Put(historyKey(&entry), string(data)); err != nil {
+		log.Printf("Failed to persist policy history entry: %v", err)
+	}
+}
+
+// loadHistory 加载已持久化的变更记录
+func (h *History) loadHistory() error {
+	values, err := h.store.GetWithPrefix(historyPrefix)
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for key, value := range values {
+		var entry types.PolicyHistoryEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			log.Printf("Skipping invalid policy history entry %s: %v", key, err)
+			continue
+		}
+		policyKey := utils.PolicyKey(entry.Region, entry.PolicyKey)
+		h.entries[policyKey] = append(h.entries[policyKey], entry)
+	}
+	for _, entries := range h.entries {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Time.Before(entries[j].Time)
+		})
+	}
+	return nil
+}
+
+// historyKey 变更记录的持久化键
+func historyKey(entry *types.PolicyHistoryEntry) string {
+	key := fmt.Sprintf("%s%s/%d", historyPrefix, entry.PolicyKey, entry.Time.UnixNano())
+	if entry.Region != "" {
+		key += "@" + entry.Region
+	}
+	return key
+}
+
+// activeEntry 获取at时刻仍在生效的记录：at之前最后一条记录是下发且策略在at时尚未到期
+func activeEntry(entries []types.PolicyHistoryEntry, at time.Time) (types.PolicyHistoryEntry, bool) {
+	idx := sort.Search(len(entries), func(i int) bool { return entries[i].Time.After(at) }) - 1
+	if idx < 0 {
+		return types.PolicyHistoryEntry{}, false
+	}
+	entry := entries[idx]
+	if entry.Action != types.PolicyHistoryPut || entry.Policy == nil || !at.Before(entry.Policy.ExpireTime) {
+		return types.PolicyHistoryEntry{}, false
+	}
+	return entry, true
+}
+
+// matchesClusterKey 判断策略键是否匹配查询，查询可以是簇ID、带命名空间的簇键或命名空间，为空时匹配全部
+func matchesClusterKey(policyKey, query string) bool {
+	return query == "" || policyKey == query ||
+		strings.HasSuffix(policyKey, "/"+query) || strings.HasPrefix(policyKey, query+"/")
+}
+
+// appliesTo 判断全局策略的区域标签是否包含region
+func appliesTo(policy *types.Policy, region string) bool {
+	if len(policy.Regions) == 0 {
+		return true
+	}
+	for _, r := range policy.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// samePolicy 比较两个策略的内容
+func samePolicy(a, b *types.Policy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(da) == string(db)
+}
@@ -0,0 +1,409 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/scheduler"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// severitySpan 指标超过阈值多少倍时对应的严重度增量为1
+const severitySpan = 4.0
+
+// PolicyEngine 策略引擎：定期计算各簇的错误速率和增长率，超过阈值时按严重度匹配模板生成策略并写入配置中心
+type PolicyEngine struct {
+	config    *types.PolicyConfig
+	engine    interfaces.ClusteringEngine
+	series    interfaces.TimeSeriesStore
+	store     interfaces.ConfigStore
+	templates *TemplateRegistry
+	escalator *Escalator                     // 启用逐级升级时按阶段生成策略，否则按严重度直接匹配模板
+	applied   map[string]*types.Policy       // 簇ID -> 当前生效的策略
+	jobLock   interfaces.JobLock             // 可选，多实例部署时定期评估只在一个实例上执行
+	gateways  interfaces.GatewayMetricsStore // 可选，网关上报过的簇按未采样的错误计数评估
+	recommend *Recommender                   // 可选，LLM推荐策略，未通过护栏时使用模板策略
+	audit     *Auditor                       // 可选，策略控制环路巡检
+	history   *History                       // 可选，策略历史
+	scheduler interfaces.JobScheduler
+	stopEval  func()
+	mutex     sync.Mutex
+}
+
+// NewPolicyEngine 创建策略引擎
+func NewPolicyEngine(config *types.PolicyConfig, engine interfaces.ClusteringEngine, series interfaces.TimeSeriesStore, store interfaces.ConfigStore) (*PolicyEngine, error) {
+	cfg := *config
+	if cfg.ErrorRateThreshold <= 0 {
+		cfg.ErrorRateThreshold = 5 // 每秒错误数，即50次/10s
+	}
+	if cfg.GrowthRateThreshold <= 0 {
+		cfg.GrowthRateThreshold = 0.5
+	}
+	if cfg.WindowSize <= 0 {
+		cfg.WindowSize = 10 * time.Second
+	}
+	if cfg.PolicyTTL <= 0 {
+		cfg.PolicyTTL = 5 * time.Minute
+	}
+	if cfg.EvaluateInterval <= 0 {
+		cfg.EvaluateInterval = cfg.WindowSize
+	}
+	if cfg.BreakerHistory.Window <= 0 {
+		cfg.BreakerHistory.Window = time.Hour
+	}
+	if cfg.BreakerHistory.MaxBoost <= 0 {
+		cfg.BreakerHistory.MaxBoost = 0.2
+	}
+
+	templates, err := NewTemplateRegistryFromConfig(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy templates: %v", err)
+	}
+
+	pe := &PolicyEngine{
+		config:    &cfg,
+		engine:    engine,
+		series:    series,
+		store:     store,
+		templates: templates,
+		applied:   make(map[string]*types.Policy),
+		scheduler: scheduler.New(),
+	}
+
+	if cfg.Escalation.Enabled {
+		if pe.escalator, err = NewEscalator(&cfg.Escalation, templates, store, cfg.PolicyTTL); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Recommendation.Enabled {
+		if cfg.Escalation.Enabled {
+			log.Println("Policy recommendation is ignored while escalation is enabled")
+		} else if pe.recommend, err = NewRecommender(&cfg.Recommendation, engine, cfg.PolicyTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Audit.Enabled {
+		pe.audit = NewAuditor(&cfg.Audit, engine, store, pe.clusterSeverity)
+	}
+	if cfg.History.Enabled {
+		pe.history = NewHistory(&cfg.History, store)
+	}
+
+	return pe, nil
+}
+
+// Templates 获取策略模板库
+func (pe *PolicyEngine) Templates() *TemplateRegistry {
+	return pe.templates
+}
+
+// Escalator 获取策略升级器，未启用逐级升级时为nil
+func (pe *PolicyEngine) Escalator() *Escalator {
+	return pe.escalator
+}
+
+// Recommender 获取LLM策略推荐，未启用时为nil
+func (pe *PolicyEngine) Recommender() *Recommender {
+	return pe.recommend
+}
+
+// Auditor 获取策略巡检，未启用时为nil
+func (pe *PolicyEngine) Auditor() *Auditor {
+	return pe.audit
+}
+
+// History 获取策略历史，未启用时为nil
+func (pe *PolicyEngine) History() *History {
+	return pe.history
+}
+
+// SetNotifier 设置告警通知器，用于升级事件和策略巡检通知
+func (pe *PolicyEngine) SetNotifier(notifier interfaces.Notifier) {
+	if pe.escalator != nil {
+		pe.escalator.SetNotifier(notifier)
+	}
+	if pe.audit != nil {
+		pe.audit.SetNotifier(notifier)
+	}
+}
+
+// SetJobLock 设置任务锁，定期评估和巡检在获取锁后执行，需在Start之前调用
+func (pe *PolicyEngine) SetJobLock(lock interfaces.JobLock) {
+	pe.jobLock = lock
+	if pe.audit != nil {
+		pe.audit.SetJobLock(lock)
+	}
+}
+
+// SetGatewayMetrics 设置网关计数快照存储，网关上报过的簇按快照中的错误计数计算速率，
+// 其余簇仍按采样事件的时序计算，需在Start之前调用
+func (pe *PolicyEngine) SetGatewayMetrics(store interfaces.GatewayMetricsStore) {
+	pe.gateways = store
+}
+
+// SetScheduler 设置任务调度，与其他控制面任务共用时可在健康检查接口查看策略评估的执行状态，需在Start之前调用
+func (pe *PolicyEngine) SetScheduler(jobs interfaces.JobScheduler) {
+	pe.scheduler = jobs
+}
+
+// Start 启动定期评估
+func (pe *PolicyEngine) Start() error {
+	schedule := &pe.config.EvaluateSchedule
+	stop, err := pe.scheduler.Schedule("policy-evaluation", scheduler.Spec(schedule, pe.config.EvaluateInterval),
+		schedule.Jitter, pe.scheduledEvaluate)
+	if err != nil {
+		return fmt.Errorf("failed to schedule policy evaluation: %v", err)
+	}
+	pe.stopEval = stop
+
+	if pe.audit != nil {
+		if err := pe.audit.Start(); err != nil {
+			return err
+		}
+	}
+	if pe.history != nil {
+		if err := pe.history.Start(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Policy engine started with %d templates", len(pe.templates.Templates()))
+	return nil
+}
+
+// Stop 停止定期评估
+func (pe *PolicyEngine) Stop() error {
+	if pe.stopEval != nil {
+		pe.stopEval()
+	}
+
+	if pe.audit != nil {
+		pe.audit.Stop()
+	}
+	if pe.history != nil {
+		pe.history.Stop()
+	}
+
+	log.Println("Policy engine stopped")
+	return nil
+}
+
+// EvaluatePolicies 评估所有簇，满足触发条件且匹配的模板与当前生效策略不同时下发新策略
+func (pe *PolicyEngine) EvaluatePolicies() error {
+	clusters, err := pe.engine.GetAllClusters()
+	if err != nil {
+		return fmt.Errorf("failed to get clusters: %v", err)
+	}
+
+	windowSeconds := int64(pe.config.WindowSize / time.Second)
+	now := time.Now()
+
+	for clusterID, cluster := range clusters {
+		errorRate, err := pe.CalculateErrorRate(clusterID, windowSeconds)
+		if err != nil {
+			log.Printf("Failed to calculate error rate for cluster %s: %v", clusterID, err)
+			continue
+		}
+		growthRate, err := pe.CalculateGrowthRate(clusterID, windowSeconds)
+		if err != nil {
+			log.Printf("Failed to calculate growth rate for cluster %s: %v", clusterID, err)
+			continue
+		}
+
+		triggered := pe.ShouldTriggerPolicy(errorRate, growthRate)
+		if pe.escalator != nil {
+			// 已在升级中的簇只要错误速率仍高于阈值就不视为恢复，缓解后增长停止是预期结果
+			elevated := triggered || pe.escalator.Stage(clusterID) >= 0 && errorRate >= pe.config.ErrorRateThreshold
+			severity := pe.severity(clusterID, errorRate, growthRate)
+			if err := pe.escalator.Observe(clusterID, errorRate, growthRate, severity, elevated, now); err != nil {
+				log.Printf("Failed to escalate policy for cluster %s: %v", clusterID, err)
+			}
+			continue
+		}
+
+		if !triggered {
+			continue
+		}
+
+		// LLM推荐的策略在有效期内不重新请求推荐
+		pe.mutex.Lock()
+		current := pe.applied[clusterID]
+		pe.mutex.Unlock()
+		if current != nil && current.Template == RecommendationTemplate && now.Before(current.ExpireTime) {
+			continue
+		}
+
+		policy, err := pe.GeneratePolicy(cluster, errorRate, growthRate)
+		if err != nil {
+			log.Printf("Failed to generate policy for cluster %s: %v", clusterID, err)
+			continue
+		}
+
+		// 同一模板的策略仍在有效期内时不重复下发，避免策略抖动
+		if current != nil && current.Template == policy.Template && now.Before(current.ExpireTime) {
+			continue
+		}
+
+		if err := pe.ApplyPolicy(policy); err != nil {
+			log.Printf("Failed to apply policy for cluster %s: %v", clusterID, err)
+			continue
+		}
+
+		log.Printf("Generated policy for cluster %s: template=%s, type=%s, severity=%.2f",
+			clusterID, policy.Template, policy.PolicyType, policy.Severity)
+	}
+
+	return nil
+}
+
+// GeneratePolicy 根据错误速率和增长率计算严重度，并按严重度所在区间的模板生成策略；
+// 启用LLM推荐时优先使用通过护栏校验的推荐策略
+func (pe *PolicyEngine) GeneratePolicy(cluster *types.Cluster, errorRate, growthRate float64) (*types.Policy, error) {
+	severity := pe.severity(cluster.ID, errorRate, growthRate)
+	now := time.Now()
+	policy, err := pe.templates.Instantiate(cluster.ID, severity, pe.config.PolicyTTL, now)
+	if pe.recommend == nil {
+		return policy, err
+	}
+
+	recommended, recErr := pe.recommend.Recommend(cluster, errorRate, growthRate, severity, policy, now)
+	if recErr != nil {
+		log.Printf("Using template policy for cluster %s: %v", cluster.ID, recErr)
+		return policy, err
+	}
+	return recommended, nil
+}
+
+// ApplyPolicy 将策略写入配置中心，下发到数据面
+func (pe *PolicyEngine) ApplyPolicy(policy *types.Policy) error {
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	if err := pe.store.Put(utils.PolicyKey("", policy.ClusterID), string(value)); err != nil {
+		return fmt.Errorf("failed to put policy: %v", err)
+	}
+
+	pe.mutex.Lock()
+	pe.applied[policy.ClusterID] = policy
+	pe.mutex.Unlock()
+
+	return nil
+}
+
+// ShouldTriggerPolicy 错误速率和增长率同时达到阈值时触发策略
+func (pe *PolicyEngine) ShouldTriggerPolicy(errorRate, growthRate float64) bool {
+	return errorRate >= pe.config.ErrorRateThreshold && growthRate >= pe.config.GrowthRateThreshold
+}
+
+// CalculateErrorRate 计算簇在窗口内的错误速率（每秒错误数），窗口单位为秒
+func (pe *PolicyEngine) CalculateErrorRate(clusterID string, windowSize int64) (float64, error) {
+	if windowSize <= 0 {
+		return 0, fmt.Errorf("invalid window size: %d", windowSize)
+	}
+	window := time.Duration(windowSize) * time.Second
+	if rates, ok := pe.gatewayRates(clusterID, window); ok {
+		return rates.Errors, nil
+	}
+	return pe.series.Rate(clusterID, window), nil
+}
+
+// CalculateGrowthRate 计算簇最近窗口相对上一个窗口的错误速率增长比例，窗口单位为秒
+func (pe *PolicyEngine) CalculateGrowthRate(clusterID string, windowSize int64) (float64, error) {
+	if windowSize <= 0 {
+		return 0, fmt.Errorf("invalid window size: %d", windowSize)
+	}
+	window := time.Duration(windowSize) * time.Second
+	if rates, ok := pe.gatewayRates(clusterID, window); ok {
+		return rates.ErrorGrowth, nil
+	}
+	return pe.series.GrowthRate(clusterID, window), nil
+}
+
+// gatewayRates 获取网关上报的簇速率
+func (pe *PolicyEngine) gatewayRates(clusterID string, window time.Duration) (types.ClusterRates, bool) {
+	if pe.gateways == nil {
+		return types.ClusterRates{}, false
+	}
+	return pe.gateways.ClusterRates(clusterID, window)
+}
+
+// scheduledEvaluate 定期评估，锁被其他实例持有时跳过本轮
+func (pe *PolicyEngine) scheduledEvaluate() error {
+	if pe.jobLock == nil {
+		return pe.EvaluatePolicies()
+	}
+	_, err := pe.jobLock.Run("policy-evaluation", pe.EvaluatePolicies)
+	return err
+}
+
+// calculateSeverity 严重度为两项指标超出阈值程度的均值：刚达到阈值时为0，达到阈值的1+severitySpan倍时为1
+func (pe *PolicyEngine) calculateSeverity(errorRate, growthRate float64) float64 {
+	severity := (excess(errorRate, pe.config.ErrorRateThreshold) + excess(growthRate, pe.config.GrowthRateThreshold)) / 2
+	if severity > 1 {
+		return 1
+	}
+	return severity
+}
+
+// clusterSeverity 按当前窗口的错误速率和增长率计算簇的严重度，供策略巡检判断簇是否应有策略
+func (pe *PolicyEngine) clusterSeverity(clusterID string) float64 {
+	windowSeconds := int64(pe.config.WindowSize / time.Second)
+	errorRate, err := pe.CalculateErrorRate(clusterID, windowSeconds)
+	if err != nil {
+		return 0
+	}
+	growthRate, err := pe.CalculateGrowthRate(clusterID, windowSeconds)
+	if err != nil {
+		return 0
+	}
+	return pe.severity(clusterID, errorRate, growthRate)
+}
+
+// severity 簇的严重度：按错误速率和增长率计算，配置了熔断历史权重时按近期熔断次数提高
+func (pe *PolicyEngine) severity(clusterID string, errorRate, growthRate float64) float64 {
+	severity := pe.calculateSeverity(errorRate, growthRate)
+	if boost := pe.breakerBoost(clusterID); boost > 0 {
+		severity += boost
+		if severity > 1 {
+			severity = 1
+		}
+	}
+	return severity
+}
+
+// breakerBoost 按网关上报的近期熔断次数计算严重度增量
+func (pe *PolicyEngine) breakerBoost(clusterID string) float64 {
+	cfg := pe.config.BreakerHistory
+	if cfg.TripBoost <= 0 || pe.gateways == nil {
+		return 0
+	}
+	history, ok := pe.gateways.BreakerHistory(clusterID, cfg.Window)
+	if !ok {
+		return 0
+	}
+	boost := float64(history.Trips) * cfg.TripBoost
+	if boost > cfg.MaxBoost {
+		return cfg.MaxBoost
+	}
+	return boost
+}
+
+// excess 指标超出阈值的程度，取值0-1
+func excess(value, threshold float64) float64 {
+	ratio := (value/threshold - 1) / severitySpan
+	if ratio < 0 {
+		return 0
+	}
+	if ratio > 1 {
+		return 1
+	}
+	return ratio
+}
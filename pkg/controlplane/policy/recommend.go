@@ -0,0 +1,231 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/llmclient"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// RecommendationTemplate LLM推荐策略记录的模板名
+const RecommendationTemplate = "llm_recommendation"
+
+// recommendSystemPrompt 策略推荐的系统提示词
+const recommendSystemPrompt = `You are an SRE assistant for an LLM API gateway. Given statistics and representative errors
+for one error cluster, recommend a mitigation policy. Reply with a single JSON object and nothing else:
+{"policy_type": "rate_limit|degrade|circuit_break", "limit_rate": 0.0-1.0, "duration": "Go duration",
+"break_duration": "Go duration", "recovery_step": 0.0-1.0, "rationale": "one sentence"}.
+limit_rate is the fraction of requests to reject and only applies to rate_limit; break_duration and
+recovery_step only apply to circuit_break. Prefer the least disruptive policy that contains the errors.`
+
+// recommendation LLM返回的推荐
+type recommendation struct {
+	PolicyType    types.PolicyType `json:"policy_type"`
+	LimitRate     float64          `json:"limit_rate"`
+	Duration      string           `json:"duration"`
+	BreakDuration string           `json:"break_duration"`
+	RecoveryStep  float64          `json:"recovery_step"`
+	Rationale     string           `json:"rationale"`
+}
+
+// Recommender LLM辅助策略推荐：将簇的错误速率、增长率和代表性错误发送给LLM，
+// 推荐的策略类型和参数需通过护栏校验后才会使用
+type Recommender struct {
+	config   types.PolicyRecommendationConfig
+	client   *llmclient.Client
+	engine   interfaces.ClusteringEngine
+	allowed  map[types.PolicyType]bool
+	ttl      time.Duration
+	accepted int64
+	rejected int64 // 未通过护栏校验
+	failed   int64 // 调用LLM或解析失败
+}
+
+// NewRecommender 创建策略推荐，policyTTL为推荐策略的有效期
+func NewRecommender(config *types.PolicyRecommendationConfig, engine interfaces.ClusteringEngine, policyTTL time.Duration) (*Recommender, error) {
+	cfg := *config
+	if cfg.Samples <= 0 {
+		cfg.Samples = 5
+	}
+	if len(cfg.AllowedTypes) == 0 {
+		cfg.AllowedTypes = []types.PolicyType{types.RATE_LIMIT, types.DEGRADE, types.CIRCUIT_BREAK}
+	}
+	if cfg.MinLimitRate <= 0 {
+		cfg.MinLimitRate = 0.05
+	}
+	if cfg.MaxLimitRate <= 0 {
+		cfg.MaxLimitRate = 0.9
+	}
+	if cfg.MaxDuration <= 0 {
+		cfg.MaxDuration = policyTTL
+	}
+	if cfg.MinBreakSeverity <= 0 {
+		cfg.MinBreakSeverity = 0.8
+	}
+	if cfg.MinLimitRate > cfg.MaxLimitRate || cfg.MaxLimitRate > 1 {
+		return nil, fmt.Errorf("invalid recommendation limit rate range [%.2f, %.2f]", cfg.MinLimitRate, cfg.MaxLimitRate)
+	}
+
+	allowed := make(map[types.PolicyType]bool, len(cfg.AllowedTypes))
+	for _, policyType := range cfg.AllowedTypes {
+		switch policyType {
+		case types.RATE_LIMIT, types.DEGRADE, types.CIRCUIT_BREAK:
+			allowed[policyType] = true
+		default:
+			return nil, fmt.Errorf("policy type %s cannot be recommended", policyType)
+		}
+	}
+
+	client, err := llmclient.New(&cfg.LLM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recommendation llm client: %v", err)
+	}
+
+	return &Recommender{
+		config:  cfg,
+		client:  client,
+		engine:  engine,
+		allowed: allowed,
+		ttl:     policyTTL,
+	}, nil
+}
+
+// Recommend 请求LLM推荐策略，调用失败或未通过护栏校验时返回错误，调用方使用模板策略；
+// fallback为模板生成的策略，作为参考发送给LLM，可为nil
+func (r *Recommender) Recommend(cluster *types.Cluster, errorRate, growthRate, severity float64, fallback *types.Policy, now time.Time) (*types.Policy, error) {
+	content, err := r.client.Complete(context.Background(), recommendSystemPrompt, r.prompt(cluster, errorRate, growthRate, severity, fallback))
+	if err != nil {
+		atomic.AddInt64(&r.failed, 1)
+		return nil, err
+	}
+
+	rec, err := parseRecommendation(content)
+	if err != nil {
+		atomic.AddInt64(&r.failed, 1)
+		return nil, err
+	}
+
+	policy, err := r.validate(rec, cluster.ID, severity, now)
+	if err != nil {
+		atomic.AddInt64(&r.rejected, 1)
+		return nil, fmt.Errorf("recommendation rejected by guardrails: %v", err)
+	}
+
+	atomic.AddInt64(&r.accepted, 1)
+	return policy, nil
+}
+
+// prompt 簇统计、阈值和代表性错误
+func (r *Recommender) prompt(cluster *types.Cluster, errorRate, growthRate, severity float64, fallback *types.Policy) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cluster: %s\n", cluster.Description)
+	if cluster.Summary != "" {
+		fmt.Fprintf(&b, "Root cause summary: %s\n", cluster.Summary)
+	}
+	fmt.Fprintf(&b, "Error rate: %.2f errors/s\nGrowth rate: %.2f\nSeverity: %.2f (0-1)\nTotal errors: %d\n",
+		errorRate, growthRate, severity, cluster.ErrorCount)
+
+	if explanation, err := r.engine.ExplainCluster(cluster.ID, r.config.Samples); err == nil && len(explanation.Central) > 0 {
+		b.WriteString("Representative errors:\n")
+		for i, member := range explanation.Central {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, member.Signature)
+		}
+	}
+
+	allowed := make([]string, 0, len(r.config.AllowedTypes))
+	for _, policyType := range r.config.AllowedTypes {
+		allowed = append(allowed, string(policyType))
+	}
+	fmt.Fprintf(&b, "Allowed policy types: %s\n", strings.Join(allowed, ", "))
+	fmt.Fprintf(&b, "Limit rate must be between %.2f and %.2f; durations at most %v\n",
+		r.config.MinLimitRate, r.config.MaxLimitRate, r.config.MaxDuration)
+	if fallback != nil {
+		fmt.Fprintf(&b, "Default policy from templates: %s (%s)\n", fallback.Template, fallback.PolicyType)
+	}
+	return b.String()
+}
+
+// parseRecommendation 解析回复中的JSON对象，容忍代码块等包裹内容
+func parseRecommendation(content string) (*recommendation, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("llm reply contains no JSON object")
+	}
+
+	var rec recommendation
+	if err := json.Unmarshal([]byte(content[start:end+1]), &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recommendation: %v", err)
+	}
+	return &rec, nil
+}
+
+// validate 护栏校验：策略类型在允许范围内，限制比例和时长在上下限内，严重度足够高才允许熔断
+func (r *Recommender) validate(rec *recommendation, clusterID string, severity float64, now time.Time) (*types.Policy, error) {
+	if !r.allowed[rec.PolicyType] {
+		return nil, fmt.Errorf("policy type %q is not allowed", rec.PolicyType)
+	}
+
+	policy := &types.Policy{
+		ClusterID:  clusterID,
+		PolicyType: rec.PolicyType,
+		Severity:   severity,
+		CreateTime: now,
+		ExpireTime: now.Add(r.ttl),
+		IsActive:   true,
+		Template:   RecommendationTemplate,
+		Rationale:  rec.Rationale,
+	}
+
+	switch rec.PolicyType {
+	case types.RATE_LIMIT:
+		if rec.LimitRate < r.config.MinLimitRate || rec.LimitRate > r.config.MaxLimitRate {
+			return nil, fmt.Errorf("limit rate %.2f is outside [%.2f, %.2f]", rec.LimitRate, r.config.MinLimitRate, r.config.MaxLimitRate)
+		}
+		duration, err := r.duration("duration", rec.Duration)
+		if err != nil {
+			return nil, err
+		}
+		policy.RateLimit = &types.RateLimitPolicy{LimitRate: rec.LimitRate, Duration: duration}
+	case types.CIRCUIT_BREAK:
+		if severity < r.config.MinBreakSeverity {
+			return nil, fmt.Errorf("circuit break requires severity %.2f, got %.2f", r.config.MinBreakSeverity, severity)
+		}
+		breakDuration, err := r.duration("break_duration", rec.BreakDuration)
+		if err != nil {
+			return nil, err
+		}
+		if rec.RecoveryStep <= 0 || rec.RecoveryStep > 1 {
+			return nil, fmt.Errorf("recovery step %.2f is outside (0, 1]", rec.RecoveryStep)
+		}
+		policy.CircuitBreak = &types.CircuitBreakPolicy{BreakDuration: breakDuration, RecoveryStep: rec.RecoveryStep}
+	}
+
+	return policy, nil
+}
+
+// duration 解析时长并检查上限
+func (r *Recommender) duration(name, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	if duration <= 0 || duration > r.config.MaxDuration {
+		return 0, fmt.Errorf("%s %v is outside (0, %v]", name, duration, r.config.MaxDuration)
+	}
+	return duration, nil
+}
+
+// Stats 获取推荐统计
+func (r *Recommender) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"accepted": atomic.LoadInt64(&r.accepted),
+		"rejected": atomic.LoadInt64(&r.rejected),
+		"failed":   atomic.LoadInt64(&r.failed),
+	}
+}
@@ -0,0 +1,284 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 内置模板名
+const (
+	TemplateLongDegrade  = "long_degrade"
+	TemplateSoftThrottle = "soft_throttle"
+	TemplateHardThrottle = "hard_throttle"
+	TemplateShortBreak   = "short_break"
+)
+
+// templateFile 模板库文件格式
+type templateFile struct {
+	Templates []types.PolicyTemplate `yaml:"templates"`
+}
+
+// DefaultTemplates 内置模板库，严重度区间覆盖[0, 1]
+func DefaultTemplates() []types.PolicyTemplate {
+	return []types.PolicyTemplate{
+		{
+			Name:        TemplateLongDegrade,
+			Description: "轻度异常，长时间降级观察",
+			MinSeverity: 0,
+			MaxSeverity: 0.2,
+			PolicyType:  types.DEGRADE,
+			TTL:         15 * time.Minute,
+		},
+		{
+			Name:         TemplateSoftThrottle,
+			Description:  "温和限流",
+			MinSeverity:  0.2,
+			MaxSeverity:  0.5,
+			PolicyType:   types.RATE_LIMIT,
+			LimitRateMin: 0.2,
+			LimitRateMax: 0.4,
+			Duration:     time.Minute,
+			TTL:          5 * time.Minute,
+		},
+		{
+			Name:         TemplateHardThrottle,
+			Description:  "严格限流",
+			MinSeverity:  0.5,
+			MaxSeverity:  0.8,
+			PolicyType:   types.RATE_LIMIT,
+			LimitRateMin: 0.5,
+			LimitRateMax: 0.8,
+			Duration:     2 * time.Minute,
+			TTL:          5 * time.Minute,
+		},
+		{
+			Name:          TemplateShortBreak,
+			Description:   "立即熔断，短时间后逐步恢复",
+			MinSeverity:   0.8,
+			MaxSeverity:   1.0,
+			PolicyType:    types.CIRCUIT_BREAK,
+			BreakDuration: 30 * time.Second,
+			RecoveryStep:  0.2,
+			TTL:           2 * time.Minute,
+		},
+	}
+}
+
+// LoadTemplateFile 从YAML文件加载模板库
+func LoadTemplateFile(path string) ([]types.PolicyTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy template file: %v", err)
+	}
+
+	var file templateFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy template file: %v", err)
+	}
+
+	return file.Templates, nil
+}
+
+// TemplateRegistry 策略模板库，按严重度区间选择模板
+type TemplateRegistry struct {
+	templates []types.PolicyTemplate // 按区间下界升序
+	mutex     sync.RWMutex
+}
+
+// NewTemplateRegistry 创建模板库，模板校验失败时返回错误
+func NewTemplateRegistry(templates []types.PolicyTemplate) (*TemplateRegistry, error) {
+	tr := &TemplateRegistry{}
+	if err := tr.Replace(templates); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// NewTemplateRegistryFromConfig 按配置创建模板库：内联模板优先，其次模板文件，均未配置时使用内置模板
+func NewTemplateRegistryFromConfig(config *types.PolicyConfig) (*TemplateRegistry, error) {
+	templates := config.Templates
+	if len(templates) == 0 && config.TemplatesFile != "" {
+		loaded, err := LoadTemplateFile(config.TemplatesFile)
+		if err != nil {
+			return nil, err
+		}
+		templates = loaded
+	}
+	if len(templates) == 0 {
+		templates = DefaultTemplates()
+	}
+	return NewTemplateRegistry(templates)
+}
+
+// Replace 校验并整体替换模板
+func (tr *TemplateRegistry) Replace(templates []types.PolicyTemplate) error {
+	sorted := make([]types.PolicyTemplate, len(templates))
+	copy(sorted, templates)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinSeverity < sorted[j].MinSeverity })
+
+	if err := validateTemplates(sorted); err != nil {
+		return err
+	}
+
+	tr.mutex.Lock()
+	tr.templates = sorted
+	tr.mutex.Unlock()
+	return nil
+}
+
+// Templates 获取所有模板，按严重度区间升序
+func (tr *TemplateRegistry) Templates() []types.PolicyTemplate {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
+	templates := make([]types.PolicyTemplate, len(tr.templates))
+	copy(templates, tr.templates)
+	return templates
+}
+
+// Match 获取严重度所在区间的模板
+func (tr *TemplateRegistry) Match(severity float64) (types.PolicyTemplate, bool) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
+	for _, tpl := range tr.templates {
+		if severity >= tpl.MinSeverity && (severity < tpl.MaxSeverity || tpl.MaxSeverity >= 1 && severity <= 1) {
+			return tpl, true
+		}
+	}
+	return types.PolicyTemplate{}, false
+}
+
+// Get 按名称获取模板
+func (tr *TemplateRegistry) Get(name string) (types.PolicyTemplate, bool) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+
+	for _, tpl := range tr.templates {
+		if tpl.Name == name {
+			return tpl, true
+		}
+	}
+	return types.PolicyTemplate{}, false
+}
+
+// Instantiate 按严重度匹配模板并生成簇策略，未配置模板有效期时使用defaultTTL
+func (tr *TemplateRegistry) Instantiate(clusterID string, severity float64, defaultTTL time.Duration, now time.Time) (*types.Policy, error) {
+	tpl, ok := tr.Match(severity)
+	if !ok {
+		return nil, fmt.Errorf("no policy template for severity %.2f", severity)
+	}
+	return InstantiateTemplate(tpl, clusterID, severity, defaultTTL, now), nil
+}
+
+// InstantiateTemplate 使用指定模板生成簇策略，严重度超出模板区间时参数取区间端点
+func InstantiateTemplate(tpl types.PolicyTemplate, clusterID string, severity float64, defaultTTL time.Duration, now time.Time) *types.Policy {
+	ttl := tpl.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	policy := &types.Policy{
+		ClusterID:  clusterID,
+		PolicyType: tpl.PolicyType,
+		Severity:   severity,
+		CreateTime: now,
+		ExpireTime: now.Add(ttl),
+		IsActive:   true,
+		Template:   tpl.Name,
+	}
+
+	switch tpl.PolicyType {
+	case types.RATE_LIMIT:
+		policy.RateLimit = &types.RateLimitPolicy{
+			LimitRate: interpolate(tpl, severity, tpl.LimitRateMin, tpl.LimitRateMax),
+			Duration:  tpl.Duration,
+			Algorithm: tpl.Algorithm,
+			Window:    tpl.Window,
+			PerKey:    tpl.PerKey,
+			Adaptive:  tpl.Adaptive,
+		}
+	case types.CIRCUIT_BREAK:
+		policy.CircuitBreak = &types.CircuitBreakPolicy{
+			BreakDuration: tpl.BreakDuration,
+			RecoveryStep:  tpl.RecoveryStep,
+		}
+	}
+
+	return policy
+}
+
+// interpolate 按严重度在模板区间内的位置对参数线性插值
+func interpolate(tpl types.PolicyTemplate, severity, low, high float64) float64 {
+	width := tpl.MaxSeverity - tpl.MinSeverity
+	if width <= 0 {
+		return low
+	}
+
+	ratio := (severity - tpl.MinSeverity) / width
+	if ratio < 0 {
+		ratio = 0
+	} else if ratio > 1 {
+		ratio = 1
+	}
+	return low + (high-low)*ratio
+}
+
+// validateTemplates 校验模板名唯一、区间合法且互不重叠、参数与策略类型匹配（模板已按区间下界排序）
+func validateTemplates(templates []types.PolicyTemplate) error {
+	if len(templates) == 0 {
+		return fmt.Errorf("policy template registry is empty")
+	}
+
+	names := make(map[string]bool, len(templates))
+	for i, tpl := range templates {
+		if tpl.Name == "" {
+			return fmt.Errorf("policy template %d has no name", i)
+		}
+		if names[tpl.Name] {
+			return fmt.Errorf("duplicate policy template %s", tpl.Name)
+		}
+		names[tpl.Name] = true
+
+		if tpl.MinSeverity < 0 || tpl.MaxSeverity > 1 || tpl.MinSeverity >= tpl.MaxSeverity {
+			return fmt.Errorf("policy template %s has invalid severity band [%.2f, %.2f)", tpl.Name, tpl.MinSeverity, tpl.MaxSeverity)
+		}
+		if i > 0 && tpl.MinSeverity < templates[i-1].MaxSeverity {
+			return fmt.Errorf("policy template %s overlaps %s", tpl.Name, templates[i-1].Name)
+		}
+
+		switch tpl.PolicyType {
+		case types.RATE_LIMIT:
+			if tpl.LimitRateMin < 0 || tpl.LimitRateMax > 1 || tpl.LimitRateMin > tpl.LimitRateMax {
+				return fmt.Errorf("policy template %s has invalid limit rate range", tpl.Name)
+			}
+			switch tpl.Algorithm {
+			case "", types.RateLimitTokenBucket, types.RateLimitSlidingWindow, types.RateLimitLeakyBucket:
+			default:
+				return fmt.Errorf("policy template %s has unsupported rate limit algorithm %s", tpl.Name, tpl.Algorithm)
+			}
+			if tpl.Window < 0 {
+				return fmt.Errorf("policy template %s has negative window", tpl.Name)
+			}
+			if a := tpl.Adaptive; a != nil && (a.Decrease < 0 || a.Decrease >= 1 || a.MaxErrorRate < 0 || a.MaxErrorRate > 1) {
+				return fmt.Errorf("policy template %s has invalid adaptive rate limit", tpl.Name)
+			}
+		case types.CIRCUIT_BREAK:
+			if tpl.BreakDuration <= 0 {
+				return fmt.Errorf("policy template %s has no break duration", tpl.Name)
+			}
+		case types.DEGRADE:
+		default:
+			return fmt.Errorf("policy template %s has unsupported policy type %s", tpl.Name, tpl.PolicyType)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Scheduler 控制面周期任务调度：按cron表达式或固定间隔触发任务，支持随机延迟，
+// 上一次执行未结束时跳过本次触发，记录各任务最近一次执行和下一次执行时间
+type Scheduler struct {
+	jobs  map[string]*job
+	mutex sync.RWMutex
+}
+
+// job 一个周期任务
+type job struct {
+	name     string
+	spec     string
+	schedule schedule
+	jitter   time.Duration
+	run      func() error
+	status   types.JobStatus
+	running  bool
+	mutex    sync.Mutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New 创建任务调度
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*job)}
+}
+
+// Schedule 注册并启动任务，spec为5段cron表达式、@hourly等简写或"@every 30s"；
+// 返回的stop停止触发并等待进行中的执行结束
+func (s *Scheduler) Schedule(name, spec string, jitter time.Duration, run func() error) (func(), error) {
+	sched, err := parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if jitter < 0 {
+		return nil, fmt.Errorf("jitter of job %s must not be negative", name)
+	}
+
+	j := &job{
+		name:     name,
+		spec:     spec,
+		schedule: sched,
+		jitter:   jitter,
+		run:      run,
+		status:   types.JobStatus{Name: name, Schedule: spec},
+		stopCh:   make(chan struct{}),
+	}
+
+	s.mutex.Lock()
+	if _, exists := s.jobs[name]; exists {
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("job %s is already scheduled", name)
+	}
+	s.jobs[name] = j
+	s.mutex.Unlock()
+
+	j.wg.Add(1)
+	go j.loop()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(j.stopCh)
+			j.wg.Wait()
+
+			s.mutex.Lock()
+			delete(s.jobs, name)
+			s.mutex.Unlock()
+		})
+	}
+	return stop, nil
+}
+
+// Jobs 获取所有任务的状态，按名称排序
+func (s *Scheduler) Jobs() []types.JobStatus {
+	s.mutex.RLock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mutex.RUnlock()
+
+	statuses := make([]types.JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		j.mutex.Lock()
+		status := j.status
+		status.Running = j.running
+		j.mutex.Unlock()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+// loop 等待到下一次执行时间，加上随机延迟后触发
+func (j *job) loop() {
+	defer j.wg.Done()
+
+	for {
+		next := j.schedule.next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job %s has no upcoming run for schedule %q, stopping", j.name, j.spec)
+			return
+		}
+		j.mutex.Lock()
+		j.status.NextRun = next
+		j.mutex.Unlock()
+
+		delay := time.Until(next)
+		if j.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(j.jitter)))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			j.trigger()
+		case <-j.stopCh:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// trigger 上一次执行仍未结束时跳过，否则在新协程中执行，不影响下一次触发的计时
+func (j *job) trigger() {
+	j.mutex.Lock()
+	if j.running {
+		j.status.Skipped++
+		j.mutex.Unlock()
+		log.Printf("Skipping job %s: previous run is still in progress", j.name)
+		return
+	}
+	j.running = true
+	j.mutex.Unlock()
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		start := time.Now()
+		err := j.run()
+
+		j.mutex.Lock()
+		j.running = false
+		j.status.Runs++
+		j.status.LastRun = start
+		j.status.LastDuration = time.Since(start)
+		j.status.LastError = ""
+		if err != nil {
+			j.status.Failures++
+			j.status.LastError = err.Error()
+		}
+		j.mutex.Unlock()
+
+		if err != nil {
+			log.Printf("Job %s failed: %v", j.name, err)
+		}
+	}()
+}
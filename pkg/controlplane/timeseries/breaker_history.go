@@ -0,0 +1,273 @@
+package timeseries
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultBreakerRetention 熔断统计历史在PostgreSQL中的默认保留时长
+const defaultBreakerRetention = 30 * 24 * time.Hour
+
+// breakerCleanupInterval 清理过期熔断统计历史的间隔
+const breakerCleanupInterval = time.Hour
+
+// breakerSeries 熔断统计各项各用一个进程内时序存储，时长以毫秒计数
+type breakerSeries struct {
+	trips          interfaces.TimeSeriesStore
+	recoveries     interfaces.TimeSeriesStore
+	rejections     interfaces.TimeSeriesStore
+	openMillis     interfaces.TimeSeriesStore
+	recoveryMillis interfaces.TimeSeriesStore
+	retention      time.Duration
+	known          map[string]bool // 网关上报过熔断统计的簇
+}
+
+// breakerHistoryDB 熔断统计历史的PostgreSQL持久化
+type breakerHistoryDB struct {
+	conn        *sql.DB
+	retention   time.Duration
+	lastCleanup time.Time
+	mutex       sync.Mutex
+}
+
+// newBreakerSeries 创建熔断统计时序
+func newBreakerSeries(config *types.TimeSeriesConfig) *breakerSeries {
+	retention := config.Retention
+	if retention <= 0 {
+		retention = 6 * time.Hour
+	}
+	return &breakerSeries{
+		trips:          NewTimeSeriesStore(config, nil),
+		recoveries:     NewTimeSeriesStore(config, nil),
+		rejections:     NewTimeSeriesStore(config, nil),
+		openMillis:     NewTimeSeriesStore(config, nil),
+		recoveryMillis: NewTimeSeriesStore(config, nil),
+		retention:      retention,
+		known:          make(map[string]bool),
+	}
+}
+
+// record 累加窗口统计
+func (bs *breakerSeries) record(clusterID string, at time.Time, stats *types.BreakerWindowStats) {
+	bs.trips.Record(clusterID, at, stats.Trips)
+	bs.recoveries.Record(clusterID, at, stats.Recoveries)
+	bs.rejections.Record(clusterID, at, stats.Rejections)
+	bs.openMillis.Record(clusterID, at, stats.OpenMillis)
+	bs.recoveryMillis.Record(clusterID, at, stats.RecoveryMillis)
+}
+
+// history 汇总窗口内的熔断统计
+func (bs *breakerSeries) history(clusterID string, window time.Duration) types.BreakerHistory {
+	now := time.Now()
+	from := now.Add(-window)
+	total := func(store interfaces.TimeSeriesStore) int64 {
+		var sum int64
+		for _, point := range store.Range(clusterID, from, now) {
+			sum += point.Count
+		}
+		return sum
+	}
+
+	history := types.BreakerHistory{
+		Window:      window.String(),
+		Trips:       total(bs.trips),
+		Recoveries:  total(bs.recoveries),
+		Rejections:  total(bs.rejections),
+		OpenSeconds: float64(total(bs.openMillis)) / 1000,
+	}
+	if history.Recoveries > 0 {
+		history.MeanRecoverySeconds = float64(total(bs.recoveryMillis)) / 1000 / float64(history.Recoveries)
+	}
+	if hours := window.Hours(); hours > 0 {
+		history.TripsPerHour = float64(history.Trips) / hours
+	}
+	return history
+}
+
+// BreakerHistory 汇总网关上报的簇熔断统计，窗口受内存时序的保留时长限制
+func (gs *gatewayMetricsStore) BreakerHistory(clusterID string, window time.Duration) (types.BreakerHistory, bool) {
+	gs.mutex.Lock()
+	known := gs.breakers.known[clusterID]
+	gs.mutex.Unlock()
+	if !known || window <= 0 {
+		return types.BreakerHistory{}, false
+	}
+	return gs.breakers.history(clusterID, window), true
+}
+
+// recordBreakers 累加快照中的熔断统计，配置了PostgreSQL时同时写入历史表
+func (gs *gatewayMetricsStore) recordBreakers(snapshot *types.MetricsSnapshot, at time.Time) {
+	for clusterID, stats := range snapshot.Breakers {
+		if stats != nil {
+			gs.breakers.record(clusterID, at, stats)
+		}
+	}
+
+	gs.mutex.Lock()
+	db := gs.breakerDB
+	gs.mutex.Unlock()
+	if db == nil {
+		return
+	}
+	if err := db.insert(snapshot, at); err != nil {
+		log.Printf("Failed to persist breaker stats from %s: %v", snapshot.Instance, err)
+	}
+	db.cleanup()
+}
+
+// AttachBreakerHistory 将网关上报的熔断统计持久化到PostgreSQL，并加载内存时序保留期内的历史，
+// 返回的函数用于关闭连接。PostgreSQL不可用时返回错误，熔断统计仍保存在内存
+func AttachBreakerHistory(store interfaces.GatewayMetricsStore, pgConfig *types.PostgreSQLConfig, retention time.Duration) (func() error, error) {
+	gs, ok := store.(*gatewayMetricsStore)
+	if !ok {
+		return nil, fmt.Errorf("gateway metrics store does not support breaker history")
+	}
+	if pgConfig == nil || pgConfig.Host == "" {
+		return nil, fmt.Errorf("postgresql is not configured")
+	}
+
+	conn := connectPostgres(pgConfig)
+	if conn == nil {
+		return nil, fmt.Errorf("failed to connect to postgresql")
+	}
+	if retention <= 0 {
+		retention = defaultBreakerRetention
+	}
+	db := &breakerHistoryDB{conn: conn, retention: retention}
+
+	if err := db.initTables(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := db.load(gs.breakers, gs.markBreaker); err != nil {
+		log.Printf("Failed to load breaker stats history: %v", err)
+	}
+
+	gs.mutex.Lock()
+	gs.breakerDB = db
+	gs.mutex.Unlock()
+
+	return func() error {
+		gs.mutex.Lock()
+		gs.breakerDB = nil
+		gs.mutex.Unlock()
+		return conn.Close()
+	}, nil
+}
+
+// markBreaker 记录上报过熔断统计的簇
+func (gs *gatewayMetricsStore) markBreaker(clusterID string) {
+	gs.mutex.Lock()
+	gs.breakers.known[clusterID] = true
+	gs.mutex.Unlock()
+}
+
+// initTables 初始化熔断统计历史表，每行是一个网关实例推送的一个簇的窗口统计
+func (db *breakerHistoryDB) initTables() error {
+	createTable := `
+		CREATE TABLE IF NOT EXISTS breaker_stats_history (
+			id BIGSERIAL PRIMARY KEY,
+			instance VARCHAR(255) NOT NULL,
+			cluster_id VARCHAR(255) NOT NULL,
+			window_start TIMESTAMPTZ NOT NULL,
+			window_end TIMESTAMPTZ NOT NULL,
+			requests BIGINT NOT NULL DEFAULT 0,
+			failures BIGINT NOT NULL DEFAULT 0,
+			rejections BIGINT NOT NULL DEFAULT 0,
+			trips BIGINT NOT NULL DEFAULT 0,
+			recoveries BIGINT NOT NULL DEFAULT 0,
+			open_ms BIGINT NOT NULL DEFAULT 0,
+			recovery_ms BIGINT NOT NULL DEFAULT 0,
+			state SMALLINT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_breaker_stats_history_cluster
+			ON breaker_stats_history (cluster_id, window_end);
+	`
+
+	if _, err := db.conn.Exec(createTable); err != nil {
+		return fmt.Errorf("failed to create breaker_stats_history table: %v", err)
+	}
+	return nil
+}
+
+// insert 在一个事务中写入快照的所有熔断统计
+func (db *breakerHistoryDB) insert(snapshot *types.MetricsSnapshot, at time.Time) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	start := snapshot.Start
+	if start.IsZero() {
+		start = at
+	}
+	for clusterID, stats := range snapshot.Breakers {
+		if stats == nil {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO breaker_stats_history (instance, cluster_id, window_start, window_end,
+				requests, failures, rejections, trips, recoveries, open_ms, recovery_ms, state)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, snapshot.Instance, clusterID, start, at,
+			stats.Requests, stats.Failures, stats.Rejections, stats.Trips, stats.Recoveries,
+			stats.OpenMillis, stats.RecoveryMillis, int(stats.State))
+		if err != nil {
+			return fmt.Errorf("failed to insert breaker stats for cluster %s: %v", clusterID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// cleanup 按保留时长删除过期的熔断统计历史，每小时最多执行一次
+func (db *breakerHistoryDB) cleanup() {
+	db.mutex.Lock()
+	if time.Since(db.lastCleanup) < breakerCleanupInterval {
+		db.mutex.Unlock()
+		return
+	}
+	db.lastCleanup = time.Now()
+	db.mutex.Unlock()
+
+	cutoff := time.Now().Add(-db.retention)
+	if _, err := db.conn.Exec(`DELETE FROM breaker_stats_history WHERE window_end < $1`, cutoff); err != nil {
+		log.Printf("Failed to delete expired breaker stats history: %v", err)
+	}
+}
+
+// load 将内存时序保留期内的历史累加到熔断统计时序
+func (db *breakerHistoryDB) load(series *breakerSeries, mark func(clusterID string)) error {
+	rows, err := db.conn.Query(`
+		SELECT cluster_id, window_end, rejections, trips, recoveries, open_ms, recovery_ms
+		FROM breaker_stats_history WHERE window_end >= $1
+	`, time.Now().Add(-series.retention))
+	if err != nil {
+		return fmt.Errorf("failed to query breaker stats history: %v", err)
+	}
+	defer rows.Close()
+
+	loaded := 0
+	for rows.Next() {
+		var clusterID string
+		var at time.Time
+		var stats types.BreakerWindowStats
+		if err := rows.Scan(&clusterID, &at, &stats.Rejections, &stats.Trips, &stats.Recoveries,
+			&stats.OpenMillis, &stats.RecoveryMillis); err != nil {
+			return fmt.Errorf("failed to scan breaker stats history: %v", err)
+		}
+		series.record(clusterID, at, &stats)
+		mark(clusterID)
+		loaded++
+	}
+
+	log.Printf("Loaded %d breaker stats history rows", loaded)
+	return rows.Err()
+}
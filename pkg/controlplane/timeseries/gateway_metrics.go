@@ -0,0 +1,167 @@
+package timeseries
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// counterSeries 请求、错误、拒绝计数各用一个进程内时序存储
+type counterSeries struct {
+	requests   interfaces.TimeSeriesStore
+	errors     interfaces.TimeSeriesStore
+	rejections interfaces.TimeSeriesStore
+	known      map[string]bool // 网关上报过的键
+}
+
+// gatewayMetricsStore 汇总各网关实例推送的计数增量，簇计数以簇ID为键，版本计数以"路由名/版本"为键
+type gatewayMetricsStore struct {
+	clusters  *counterSeries
+	versions  *counterSeries
+	breakers  *breakerSeries
+	breakerDB *breakerHistoryDB // 可选，熔断统计历史持久化
+	instances map[string]*types.GatewayInstanceStatus
+	mutex     sync.Mutex
+}
+
+// NewGatewayMetricsStore 创建网关计数快照存储
+func NewGatewayMetricsStore(config *types.GatewayMetricsConfig) interfaces.GatewayMetricsStore {
+	seriesConfig := &types.TimeSeriesConfig{
+		Resolution: config.Resolution,
+		Retention:  config.Retention,
+	}
+
+	return &gatewayMetricsStore{
+		clusters:  newCounterSeries(seriesConfig),
+		versions:  newCounterSeries(seriesConfig),
+		breakers:  newBreakerSeries(seriesConfig),
+		instances: make(map[string]*types.GatewayInstanceStatus),
+	}
+}
+
+// newCounterSeries 创建计数时序
+func newCounterSeries(config *types.TimeSeriesConfig) *counterSeries {
+	return &counterSeries{
+		requests:   NewTimeSeriesStore(config, nil),
+		errors:     NewTimeSeriesStore(config, nil),
+		rejections: NewTimeSeriesStore(config, nil),
+		known:      make(map[string]bool),
+	}
+}
+
+// record 累加计数
+func (cs *counterSeries) record(key string, at time.Time, counters *types.ClusterCounters) {
+	cs.requests.Record(key, at, counters.Requests)
+	cs.errors.Record(key, at, counters.Errors)
+	cs.rejections.Record(key, at, counters.Rejections)
+}
+
+// rates 计算窗口内的速率
+func (cs *counterSeries) rates(key string, window time.Duration) types.ClusterRates {
+	return types.ClusterRates{
+		Requests:    cs.requests.Rate(key, window),
+		Errors:      cs.errors.Rate(key, window),
+		Rejections:  cs.rejections.Rate(key, window),
+		ErrorGrowth: cs.errors.GrowthRate(key, window),
+	}
+}
+
+// versionKey 版本计数的键
+func versionKey(route, version string) string {
+	return route + "/" + version
+}
+
+// Ingest 按实例和序号去重后累加快照中的增量，实例重启（启动时间变化）后序号重新计数
+func (gs *gatewayMetricsStore) Ingest(snapshot *types.MetricsSnapshot) bool {
+	gs.mutex.Lock()
+	status, exists := gs.instances[snapshot.Instance]
+	if !exists || !snapshot.Started.Equal(status.Started) {
+		status = &types.GatewayInstanceStatus{Instance: snapshot.Instance, Started: snapshot.Started}
+		gs.instances[snapshot.Instance] = status
+	}
+
+	if snapshot.Sequence <= status.Sequence {
+		status.Duplicates++
+		gs.mutex.Unlock()
+		return false
+	}
+	if status.Sequence > 0 && snapshot.Sequence > status.Sequence+1 {
+		status.Gaps++
+	}
+	status.Sequence = snapshot.Sequence
+	status.LastSeen = time.Now()
+	status.Snapshots++
+	for clusterID := range snapshot.Clusters {
+		gs.clusters.known[clusterID] = true
+	}
+	for route, versions := range snapshot.Versions {
+		for version := range versions {
+			gs.versions.known[versionKey(route, version)] = true
+		}
+	}
+	for clusterID := range snapshot.Breakers {
+		gs.breakers.known[clusterID] = true
+	}
+	gs.mutex.Unlock()
+
+	at := snapshot.End
+	if at.IsZero() {
+		at = time.Now()
+	}
+	for clusterID, counters := range snapshot.Clusters {
+		if counters != nil {
+			gs.clusters.record(clusterID, at, counters)
+		}
+	}
+	for route, versions := range snapshot.Versions {
+		for version, counters := range versions {
+			if counters != nil {
+				gs.versions.record(versionKey(route, version), at, counters)
+			}
+		}
+	}
+	if len(snapshot.Breakers) > 0 {
+		gs.recordBreakers(snapshot, at)
+	}
+
+	return true
+}
+
+// ClusterRates 计算簇在窗口内的速率
+func (gs *gatewayMetricsStore) ClusterRates(clusterID string, window time.Duration) (types.ClusterRates, bool) {
+	return gs.rates(gs.clusters, clusterID, window)
+}
+
+// VersionRates 计算路由的上游版本在窗口内的速率
+func (gs *gatewayMetricsStore) VersionRates(route, version string, window time.Duration) (types.ClusterRates, bool) {
+	return gs.rates(gs.versions, versionKey(route, version), window)
+}
+
+// rates 上报过的键计算速率
+func (gs *gatewayMetricsStore) rates(cs *counterSeries, key string, window time.Duration) (types.ClusterRates, bool) {
+	gs.mutex.Lock()
+	known := cs.known[key]
+	gs.mutex.Unlock()
+	if !known {
+		return types.ClusterRates{}, false
+	}
+	return cs.rates(key, window), true
+}
+
+// Instances 获取推送过快照的网关实例，按实例标识排序
+func (gs *gatewayMetricsStore) Instances() []types.GatewayInstanceStatus {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	result := make([]types.GatewayInstanceStatus, 0, len(gs.instances))
+	for _, status := range gs.instances {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Instance < result[j].Instance
+	})
+	return result
+}
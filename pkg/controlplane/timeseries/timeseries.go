@@ -0,0 +1,384 @@
+package timeseries
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// series 单个簇的环形缓冲区，每个槽位保存一个时间桶的计数
+type series struct {
+	counts []int64
+	slots  []int64 // 槽位对应的时间桶序号，用于判断槽位是否过期
+}
+
+// timeSeriesStore 进程内簇速率时序存储
+type timeSeriesStore struct {
+	config     *types.TimeSeriesConfig
+	resolution time.Duration
+	size       int
+	pgConn     *sql.DB
+	jobLock    interfaces.JobLock // 可选，多实例部署时快照写入和过期清理只在一个实例上执行
+	series     map[string]*series
+	mutex      sync.RWMutex
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewTimeSeriesStore 创建时序存储，PostgreSQL不可用时仅保存在内存
+func NewTimeSeriesStore(config *types.TimeSeriesConfig, pgConfig *types.PostgreSQLConfig) interfaces.TimeSeriesStore {
+	resolution := config.Resolution
+	if resolution <= 0 {
+		resolution = time.Minute
+	}
+
+	retention := config.Retention
+	if retention <= 0 {
+		retention = 6 * time.Hour
+	}
+
+	size := int(retention / resolution)
+	if size < 2 {
+		size = 2
+	}
+
+	ts := &timeSeriesStore{
+		config:     config,
+		resolution: resolution,
+		size:       size,
+		series:     make(map[string]*series),
+		stopCh:     make(chan struct{}),
+	}
+
+	if pgConfig != nil && pgConfig.Host != "" {
+		ts.pgConn = connectPostgres(pgConfig)
+	}
+
+	return ts
+}
+
+// Record 记录簇在指定时间的事件数
+func (ts *timeSeriesStore) Record(clusterID string, at time.Time, count int64) {
+	slot := ts.slotOf(at)
+
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	s, exists := ts.series[clusterID]
+	if !exists {
+		s = &series{
+			counts: make([]int64, ts.size),
+			slots:  make([]int64, ts.size),
+		}
+		ts.series[clusterID] = s
+	}
+
+	idx := int(slot % int64(ts.size))
+	if s.slots[idx] != slot {
+		// 槽位属于更早的时间桶，覆盖旧数据
+		if s.slots[idx] > slot {
+			return // 超出保留期的迟到数据
+		}
+		s.slots[idx] = slot
+		s.counts[idx] = 0
+	}
+	s.counts[idx] += count
+}
+
+// Range 获取时间范围内的数据点，缺失的时间桶计数为0
+func (ts *timeSeriesStore) Range(clusterID string, from, to time.Time) []types.TimeSeriesPoint {
+	fromSlot := ts.slotOf(from)
+	toSlot := ts.slotOf(to)
+
+	// 限制在保留期内
+	if oldest := toSlot - int64(ts.size) + 1; fromSlot < oldest {
+		fromSlot = oldest
+	}
+	if fromSlot > toSlot {
+		return nil
+	}
+
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	s := ts.series[clusterID]
+	points := make([]types.TimeSeriesPoint, 0, toSlot-fromSlot+1)
+	for slot := fromSlot; slot <= toSlot; slot++ {
+		point := types.TimeSeriesPoint{
+			Timestamp: time.Unix(0, slot*int64(ts.resolution)),
+		}
+		if s != nil {
+			idx := int(slot % int64(ts.size))
+			if s.slots[idx] == slot {
+				point.Count = s.counts[idx]
+			}
+		}
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// Rate 获取最近窗口内的平均速率（每秒事件数）
+func (ts *timeSeriesStore) Rate(clusterID string, window time.Duration) float64 {
+	now := time.Now()
+	return ts.rateBetween(clusterID, now.Add(-window), now, window)
+}
+
+// GrowthRate 计算最近窗口相对上一个窗口的速率增长比例
+func (ts *timeSeriesStore) GrowthRate(clusterID string, window time.Duration) float64 {
+	now := time.Now()
+	current := ts.rateBetween(clusterID, now.Add(-window), now, window)
+	previous := ts.rateBetween(clusterID, now.Add(-2*window), now.Add(-window), window)
+
+	if previous == 0 {
+		if current > 0 {
+			return 1.0
+		}
+		return 0
+	}
+
+	return (current - previous) / previous
+}
+
+// Clusters 获取有时序数据的簇
+func (ts *timeSeriesStore) Clusters() []string {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	clusters := make([]string, 0, len(ts.series))
+	for clusterID := range ts.series {
+		clusters = append(clusters, clusterID)
+	}
+	return clusters
+}
+
+// Start 加载持久化快照并启动定期持久化
+func (ts *timeSeriesStore) Start() error {
+	if ts.pgConn == nil {
+		return nil
+	}
+
+	if err := ts.initTables(); err != nil {
+		return err
+	}
+
+	if err := ts.load(); err != nil {
+		log.Printf("Failed to load time series snapshot: %v", err)
+	}
+
+	interval := ts.config.PersistInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ts.wg.Add(1)
+	go func() {
+		defer ts.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := ts.scheduledPersist(); err != nil {
+					log.Printf("Failed to persist time series: %v", err)
+				}
+			case <-ts.stopCh:
+				return
+			}
+		}
+	}()
+
+	log.Printf("Time series store started (resolution=%v, slots=%d)", ts.resolution, ts.size)
+	return nil
+}
+
+// AttachJobLock 设置任务锁，定期快照写入和过期清理在获取锁后执行，需在Start之前调用
+func AttachJobLock(store interfaces.TimeSeriesStore, lock interfaces.JobLock) error {
+	ts, ok := store.(*timeSeriesStore)
+	if !ok {
+		return fmt.Errorf("time series store does not support job lock")
+	}
+	ts.jobLock = lock
+	return nil
+}
+
+// scheduledPersist 定期写入快照并清理过期簇，锁被其他实例持有时跳过本轮
+func (ts *timeSeriesStore) scheduledPersist() error {
+	if ts.jobLock == nil {
+		return ts.persist()
+	}
+	_, err := ts.jobLock.Run("timeseries-compaction", ts.persist)
+	return err
+}
+
+// Stop 停止并写入最后一次快照
+func (ts *timeSeriesStore) Stop() error {
+	close(ts.stopCh)
+	ts.wg.Wait()
+
+	if ts.pgConn == nil {
+		return nil
+	}
+
+	if err := ts.persist(); err != nil {
+		log.Printf("Failed to persist time series on stop: %v", err)
+	}
+	return ts.pgConn.Close()
+}
+
+// rateBetween 计算时间范围内的平均速率
+func (ts *timeSeriesStore) rateBetween(clusterID string, from, to time.Time, window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+
+	var total int64
+	for _, point := range ts.Range(clusterID, from, to) {
+		total += point.Count
+	}
+
+	return float64(total) / window.Seconds()
+}
+
+// slotOf 计算时间所属的时间桶序号
+func (ts *timeSeriesStore) slotOf(at time.Time) int64 {
+	return at.UnixNano() / int64(ts.resolution)
+}
+
+// initTables 初始化时序快照表
+func (ts *timeSeriesStore) initTables() error {
+	createTable := `
+		CREATE TABLE IF NOT EXISTS cluster_timeseries (
+			cluster_id VARCHAR(255) PRIMARY KEY,
+			resolution_ms BIGINT NOT NULL,
+			points JSONB NOT NULL,
+			updated_at TIMESTAMP DEFAULT NOW()
+		);
+	`
+
+	if _, err := ts.pgConn.Exec(createTable); err != nil {
+		return fmt.Errorf("failed to create cluster_timeseries table: %v", err)
+	}
+	return nil
+}
+
+// persist 将所有簇的非零数据点写入快照表
+func (ts *timeSeriesStore) persist() error {
+	now := time.Now()
+	from := now.Add(-time.Duration(ts.size) * ts.resolution)
+
+	for _, clusterID := range ts.Clusters() {
+		points := make([]types.TimeSeriesPoint, 0)
+		for _, point := range ts.Range(clusterID, from, now) {
+			if point.Count > 0 {
+				points = append(points, point)
+			}
+		}
+
+		if len(points) == 0 {
+			ts.mutex.Lock()
+			delete(ts.series, clusterID)
+			ts.mutex.Unlock()
+			if _, err := ts.pgConn.Exec(`DELETE FROM cluster_timeseries WHERE cluster_id = $1`, clusterID); err != nil {
+				return fmt.Errorf("failed to delete time series for cluster %s: %v", clusterID, err)
+			}
+			continue
+		}
+
+		data, err := json.Marshal(points)
+		if err != nil {
+			return fmt.Errorf("failed to marshal time series: %v", err)
+		}
+
+		_, err = ts.pgConn.Exec(`
+			INSERT INTO cluster_timeseries (cluster_id, resolution_ms, points, updated_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (cluster_id) DO UPDATE SET
+				resolution_ms = $2, points = $3, updated_at = NOW()
+		`, clusterID, ts.resolution.Milliseconds(), string(data))
+		if err != nil {
+			return fmt.Errorf("failed to persist time series for cluster %s: %v", clusterID, err)
+		}
+	}
+
+	return nil
+}
+
+// load 从快照表恢复时序数据
+func (ts *timeSeriesStore) load() error {
+	rows, err := ts.pgConn.Query(`SELECT cluster_id, points FROM cluster_timeseries`)
+	if err != nil {
+		return fmt.Errorf("failed to query time series: %v", err)
+	}
+	defer rows.Close()
+
+	loaded := 0
+	for rows.Next() {
+		var clusterID, data string
+		if err := rows.Scan(&clusterID, &data); err != nil {
+			return fmt.Errorf("failed to scan time series: %v", err)
+		}
+
+		var points []types.TimeSeriesPoint
+		if err := json.Unmarshal([]byte(data), &points); err != nil {
+			log.Printf("Skipping invalid time series for cluster %s: %v", clusterID, err)
+			continue
+		}
+
+		for _, point := range points {
+			ts.Record(clusterID, point.Timestamp, point.Count)
+		}
+		loaded++
+	}
+
+	log.Printf("Loaded time series for %d clusters", loaded)
+	return rows.Err()
+}
+
+// connectPostgres 连接PostgreSQL，失败时返回nil
+func connectPostgres(config *types.PostgreSQLConfig) *sql.DB {
+	sslMode := config.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		config.Host,
+		config.Port,
+		config.Username,
+		config.Password,
+		config.Database,
+		sslMode,
+	)
+
+	pgConn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		log.Printf("Warning: failed to open PostgreSQL, time series will not be persisted: %v", err)
+		return nil
+	}
+
+	if err := pgConn.Ping(); err != nil {
+		log.Printf("Warning: PostgreSQL connection failed, time series will not be persisted: %v", err)
+		pgConn.Close()
+		return nil
+	}
+
+	if config.MaxOpenConns > 0 {
+		pgConn.SetMaxOpenConns(config.MaxOpenConns)
+	}
+	if config.MaxIdleConns > 0 {
+		pgConn.SetMaxIdleConns(config.MaxIdleConns)
+	}
+
+	return pgConn
+}
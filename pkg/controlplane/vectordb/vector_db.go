@@ -1,11 +1,13 @@
 package vectordb
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	_ "github.com/lib/pq"
 
@@ -204,6 +206,34 @@ func (vdb *vectorDB) GetVectorCount() (int64, error) {
 	return count, nil
 }
 
+// Health 健康状态，PostgreSQL不可用时降级为仅内存模式
+func (vdb *vectorDB) Health() types.ComponentHealth {
+	if vdb.pgConn == nil {
+		return types.ComponentHealth{Status: types.ComponentDegraded, Message: "PostgreSQL unavailable, running in memory-only mode"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := vdb.pgConn.PingContext(ctx); err != nil {
+		return types.ComponentHealth{Status: types.ComponentDegraded, Message: fmt.Sprintf("PostgreSQL ping failed: %v", err)}
+	}
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+// Stats 获取向量库统计
+func (vdb *vectorDB) Stats() map[string]interface{} {
+	vdb.mutex.RLock()
+	count := len(vdb.vectors)
+	vdb.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"vectors":    count,
+		"cached":     vdb.cache.Size(),
+		"index_type": vdb.config.IndexType,
+		"persistent": vdb.pgConn != nil,
+	}
+}
+
 // initTables 初始化数据库表
 func (vdb *vectorDB) initTables() error {
 	createVectorsTable := `
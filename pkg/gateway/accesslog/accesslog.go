@@ -0,0 +1,294 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// 日志格式
+const (
+	FormatJSON     = "json"
+	FormatCombined = "combined"
+)
+
+// defaultBufferSize 默认日志缓冲条数
+const defaultBufferSize = 4096
+
+// Sink 访问日志输出端
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// AccessLogger 访问日志记录器，日志行异步写入所有输出端，缓冲满时丢弃
+type AccessLogger struct {
+	format  string
+	fields  []string
+	sinks   []Sink
+	queue   chan []byte
+	stopCh  chan struct{}
+	written int64
+	dropped int64
+	failed  int64
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewAccessLogger 创建访问日志记录器，未配置输出端时输出到标准输出
+func NewAccessLogger(config *types.AccessLogConfig, kafkaConfig *types.KafkaConfig) (*AccessLogger, error) {
+	format := config.Format
+	if format == "" {
+		format = FormatCombined
+	}
+	if format != FormatJSON && format != FormatCombined {
+		return nil, fmt.Errorf("unsupported access log format: %s", format)
+	}
+
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	sinkConfigs := config.Sinks
+	if len(sinkConfigs) == 0 {
+		sinkConfigs = []types.AccessLogSinkConfig{{Type: SinkStdout}}
+	}
+
+	al := &AccessLogger{
+		format: format,
+		fields: config.Fields,
+		queue:  make(chan []byte, bufferSize),
+		stopCh: make(chan struct{}),
+	}
+
+	for _, sinkConfig := range sinkConfigs {
+		sink, err := newSink(&sinkConfig, kafkaConfig)
+		if err != nil {
+			al.closeSinks()
+			return nil, fmt.Errorf("failed to create %s access log sink: %v", sinkConfig.Type, err)
+		}
+		al.sinks = append(al.sinks, sink)
+	}
+
+	al.wg.Add(1)
+	go al.writeLoop()
+
+	return al, nil
+}
+
+// Middleware 访问日志中间件，需放在Tracing之后以便记录trace_id
+func (al *AccessLogger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		entry := buildEntry(c, start)
+		var line []byte
+		if al.format == FormatJSON {
+			line = al.formatJSON(entry)
+		} else {
+			line = formatCombined(entry)
+		}
+
+		select {
+		case al.queue <- line:
+		default:
+			atomic.AddInt64(&al.dropped, 1)
+		}
+	}
+}
+
+// Stats 获取访问日志统计
+func (al *AccessLogger) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"written":        atomic.LoadInt64(&al.written),
+		"dropped":        atomic.LoadInt64(&al.dropped),
+		"write_failures": atomic.LoadInt64(&al.failed),
+	}
+}
+
+// Close 写出缓冲中剩余的日志并关闭输出端
+func (al *AccessLogger) Close() error {
+	al.once.Do(func() {
+		close(al.stopCh)
+		al.wg.Wait()
+		al.closeSinks()
+	})
+	return nil
+}
+
+// writeLoop 将日志行写入所有输出端，停止时写出缓冲中剩余的日志
+func (al *AccessLogger) writeLoop() {
+	defer al.wg.Done()
+
+	for {
+		select {
+		case line := <-al.queue:
+			al.write(line)
+		case <-al.stopCh:
+			for {
+				select {
+				case line := <-al.queue:
+					al.write(line)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write 写入一行到所有输出端
+func (al *AccessLogger) write(line []byte) {
+	for _, sink := range al.sinks {
+		if err := sink.Write(line); err != nil {
+			if atomic.AddInt64(&al.failed, 1)%1000 == 1 {
+				log.Printf("Failed to write access log: %v", err)
+			}
+		}
+	}
+	atomic.AddInt64(&al.written, 1)
+}
+
+// closeSinks 关闭所有输出端
+func (al *AccessLogger) closeSinks() {
+	for _, sink := range al.sinks {
+		if err := sink.Close(); err != nil {
+			log.Printf("Failed to close access log sink: %v", err)
+		}
+	}
+}
+
+// entry 单条访问日志
+type entry struct {
+	values map[string]interface{}
+	start  time.Time
+}
+
+// buildEntry 从请求上下文收集日志字段
+func buildEntry(c *gin.Context, start time.Time) *entry {
+	status := c.Writer.Status()
+	if utils.IsClientCanceled(c) {
+		status = utils.StatusClientClosedRequest
+	}
+
+	size := c.Writer.Size()
+	if size < 0 {
+		size = 0
+	}
+
+	values := map[string]interface{}{
+		"time":        start.Format(time.RFC3339Nano),
+		"remote_ip":   utils.ExtractClientIP(c),
+		"method":      c.Request.Method,
+		"path":        c.Request.URL.Path,
+		"query":       c.Request.URL.RawQuery,
+		"protocol":    c.Request.Proto,
+		"status":      status,
+		"bytes":       size,
+		"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"user_agent":  c.Request.UserAgent(),
+		"referer":     c.Request.Referer(),
+		"request_id":  c.GetString("request_id"),
+		"trace_id":    utils.ExtractTraceID(c),
+		"cluster_id":  c.GetString("cluster_id"),
+		"route":       c.GetString("route_name"),
+		"upstream":    c.GetString("upstream_target"),
+	}
+	if version := c.GetString("upstream_version"); version != "" {
+		values["upstream_version"] = version
+	}
+	if version := c.GetInt64("policy_version"); version != 0 {
+		values["policy_version"] = version
+	}
+	if stage := c.GetString("client_canceled"); stage != "" {
+		values["client_canceled"] = stage
+	}
+	if model := c.GetString("llm_model"); model != "" {
+		values["llm_model"] = model
+		values["prompt_tokens"] = c.GetInt64("llm_prompt_tokens")
+		if completion, ok := c.Get("llm_completion_tokens"); ok {
+			values["completion_tokens"] = completion
+		}
+	}
+	if tags := c.GetStringSlice("waf_tags"); len(tags) > 0 {
+		values["waf_tags"] = tags
+	}
+	// 失败请求附带处理链各阶段的错误和注解，成功请求只有注解时不记录
+	if err := utils.LastRequestError(c); err != nil {
+		values["error"] = err.Error()
+	}
+	if _, failed := values["error"]; failed || status >= 400 {
+		if stages := utils.StageErrors(c); len(stages) > 0 {
+			values["errors"] = stages
+		}
+	}
+
+	return &entry{values: values, start: start}
+}
+
+// formatJSON 按配置的字段输出JSON
+func (al *AccessLogger) formatJSON(e *entry) []byte {
+	values := e.values
+	if len(al.fields) > 0 {
+		values = make(map[string]interface{}, len(al.fields))
+		for _, field := range al.fields {
+			if value, ok := e.values[field]; ok {
+				values[field] = value
+			}
+		}
+	}
+
+	line, err := json.Marshal(values)
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	return line
+}
+
+// formatCombined 输出Apache combined格式，末尾附加请求ID、trace_id、簇ID和耗时
+func formatCombined(e *entry) []byte {
+	v := e.values
+	var b strings.Builder
+	b.WriteString(dash(v["remote_ip"].(string)))
+	b.WriteString(" - - [")
+	b.WriteString(e.start.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString("] \"")
+	b.WriteString(v["method"].(string))
+	b.WriteByte(' ')
+	b.WriteString(v["path"].(string))
+	if query := v["query"].(string); query != "" {
+		b.WriteByte('?')
+		b.WriteString(query)
+	}
+	b.WriteByte(' ')
+	b.WriteString(v["protocol"].(string))
+	b.WriteString("\" ")
+	b.WriteString(strconv.Itoa(v["status"].(int)))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(v["bytes"].(int)))
+	fmt.Fprintf(&b, " %q %q", dash(v["referer"].(string)), dash(v["user_agent"].(string)))
+	fmt.Fprintf(&b, " request_id=%s trace_id=%s cluster_id=%s duration_ms=%.3f",
+		dash(v["request_id"].(string)), dash(v["trace_id"].(string)), dash(v["cluster_id"].(string)), v["duration_ms"].(float64))
+	return []byte(b.String())
+}
+
+// dash 空值输出为"-"
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
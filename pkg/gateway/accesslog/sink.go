@@ -0,0 +1,212 @@
+package accesslog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 输出端类型
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkKafka  = "kafka"
+)
+
+// newSink 按配置创建输出端
+func newSink(config *types.AccessLogSinkConfig, kafkaConfig *types.KafkaConfig) (Sink, error) {
+	switch config.Type {
+	case SinkStdout, "":
+		return &writerSink{file: os.Stdout}, nil
+	case SinkFile:
+		return newFileSink(config)
+	case SinkKafka:
+		return newKafkaSink(config, kafkaConfig)
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", config.Type)
+	}
+}
+
+// writerSink 标准输出
+type writerSink struct {
+	file *os.File
+}
+
+// Write 写入一行
+func (ws *writerSink) Write(line []byte) error {
+	_, err := ws.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close 标准输出无需关闭
+func (ws *writerSink) Close() error {
+	return nil
+}
+
+// fileSink 按大小轮转的文件输出端，轮转后的文件命名为<path>.<时间戳>
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	mutex      sync.Mutex
+}
+
+// newFileSink 创建文件输出端
+func newFileSink(config *types.AccessLogSinkConfig) (*fileSink, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("file sink path is empty")
+	}
+
+	maxSize := int64(config.MaxSizeMB) << 20
+	if maxSize <= 0 {
+		maxSize = 100 << 20
+	}
+
+	fs := &fileSink{
+		path:       config.Path,
+		maxSize:    maxSize,
+		maxBackups: config.MaxBackups,
+	}
+	if err := fs.open(); err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+// Write 写入一行，超过大小上限时先轮转
+func (fs *fileSink) Write(line []byte) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if fs.size+int64(len(line))+1 > fs.maxSize {
+		if err := fs.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := fs.file.Write(append(line, '\n'))
+	fs.size += int64(n)
+	return err
+}
+
+// Close 关闭文件
+func (fs *fileSink) Close() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.file.Close()
+}
+
+// open 以追加方式打开日志文件
+func (fs *fileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+
+	file, err := os.OpenFile(fs.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open access log file: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat access log file: %v", err)
+	}
+
+	fs.file = file
+	fs.size = info.Size()
+	return nil
+}
+
+// rotate 重命名当前文件并打开新文件，清理超出数量的旧文件
+func (fs *fileSink) rotate() error {
+	if err := fs.file.Close(); err != nil {
+		return fmt.Errorf("failed to close access log file: %v", err)
+	}
+
+	backup := fmt.Sprintf("%s.%s", fs.path, time.Now().Format("20060102-150405.000"))
+	if err := os.Rename(fs.path, backup); err != nil {
+		return fmt.Errorf("failed to rotate access log file: %v", err)
+	}
+
+	if err := fs.open(); err != nil {
+		return err
+	}
+
+	if fs.maxBackups > 0 {
+		// 时间戳后缀按字典序即按时间排序
+		backups, err := filepath.Glob(fs.path + ".*")
+		if err == nil && len(backups) > fs.maxBackups {
+			for _, old := range backups[:len(backups)-fs.maxBackups] {
+				os.Remove(old)
+			}
+		}
+	}
+
+	return nil
+}
+
+// kafkaSink Kafka输出端，复用全局Kafka集群配置
+type kafkaSink struct {
+	topic    string
+	producer sarama.AsyncProducer
+	done     chan struct{}
+}
+
+// newKafkaSink 创建Kafka输出端
+func newKafkaSink(config *types.AccessLogSinkConfig, kafkaConfig *types.KafkaConfig) (*kafkaSink, error) {
+	if config.Topic == "" {
+		return nil, fmt.Errorf("kafka sink topic is empty")
+	}
+
+	// 不等待确认时关闭生产者会丢弃尚未发出的缓冲日志，因此等待leader确认
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForLocal
+	saramaConfig.Producer.Return.Errors = true
+	saramaConfig.Producer.Flush.Frequency = 500 * time.Millisecond
+
+	producer, err := sarama.NewAsyncProducer(kafkaConfig.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %v", err)
+	}
+
+	ks := &kafkaSink{
+		topic:    config.Topic,
+		producer: producer,
+		done:     make(chan struct{}),
+	}
+
+	// 发送失败只能丢弃，消费错误通道避免生产者阻塞
+	go func() {
+		defer close(ks.done)
+		for range producer.Errors() {
+		}
+	}()
+
+	return ks, nil
+}
+
+// Write 异步发送一行
+func (ks *kafkaSink) Write(line []byte) error {
+	ks.producer.Input() <- &sarama.ProducerMessage{
+		Topic: ks.topic,
+		Value: sarama.ByteEncoder(line),
+	}
+	return nil
+}
+
+// Close 关闭生产者
+func (ks *kafkaSink) Close() error {
+	err := ks.producer.Close()
+	<-ks.done
+	return err
+}
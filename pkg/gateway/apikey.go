@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/decision"
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// apiKeyLimit 按API密钥限制速率和配额，未携带密钥的请求只受簇限流约束
+func (g *Gateway) apiKeyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := utils.ExtractAPIKey(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		keyID := utils.APIKeyID(key)
+		allowed, reason := g.keyLimiter.Allow(keyID)
+
+		if remaining, resetAt := g.keyLimiter.QuotaRemaining(keyID); remaining >= 0 {
+			c.Header("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+			c.Header("X-Quota-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+		}
+
+		if allowed {
+			c.Next()
+			return
+		}
+
+		code := "API_KEY_RATE_LIMIT_EXCEEDED"
+		message := "API key rate limit exceeded"
+		if reason == limiter.KeyRejectQuota {
+			code = "API_KEY_QUOTA_EXCEEDED"
+			message = "API key quota exceeded"
+		}
+
+		if decision.Enabled(c) {
+			decision.Record(c, decision.Step{
+				Stage:    "rate_limit",
+				Decision: decision.DecisionReject,
+				Reason:   strings.ToLower(message),
+				Details: map[string]interface{}{
+					"limiter": types.RateLimiterAPIKey,
+					"key_id":  keyID,
+				},
+			})
+		}
+
+		if g.metrics != nil {
+			// 密钥数量不可控，不作为指标标签
+			g.metrics.RecordRateLimitHit("api_key", code)
+		}
+		utils.RecordStageError(c, "api_key_limit", fmt.Errorf("%s for key %s", strings.ToLower(message), keyID))
+
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": message,
+			"code":  code,
+		})
+		c.Abort()
+	}
+}
+
+// onKeyLimitUpdate 处理etcd中的API密钥限流覆盖，键格式为"/ratelimits/keys/<密钥摘要>"，删除键时恢复默认限流
+func (g *Gateway) onKeyLimitUpdate(key string, value []byte, deleted bool) {
+	keyID, ok := limiter.KeyIDFromLimitKey(key)
+	if !ok {
+		return
+	}
+
+	var limit *types.APIKeyRateLimit
+	if !deleted {
+		limit = &types.APIKeyRateLimit{}
+		if err := json.Unmarshal(value, limit); err != nil {
+			log.Printf("Invalid rate limit override for api key %s: %v", keyID, err)
+			return
+		}
+	}
+
+	if err := g.rateLimiter.UpdateKeyLimit(keyID, limit); err != nil {
+		log.Printf("Failed to update rate limit override for api key %s: %v", keyID, err)
+		return
+	}
+	g.overrides.setKeyLimit(keyID, limit)
+	g.recordConfigChange("etcd:" + key)
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultBufferedBodyBytes 缓冲模式未配置上限时的默认请求体上限
+const defaultBufferedBodyBytes = 10 << 20
+
+// bodyLimit 请求体中间件，按路由限制请求体大小并选择流式或缓冲模式
+// 流式模式边读边转发，超限在读取时中断；缓冲模式预先读入内存，供错误采样和镜像使用
+func (g *Gateway) bodyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := matchedRoute(c)
+		if route == nil || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		maxBytes := route.Body.MaxBytes
+		buffered := route.Body.Mode == types.BodyModeBuffer
+		if buffered && maxBytes <= 0 {
+			maxBytes = defaultBufferedBodyBytes
+		}
+
+		if maxBytes > 0 {
+			// 声明的长度已超限时无需读取
+			if c.Request.ContentLength > maxBytes {
+				abortBodyTooLarge(c, maxBytes)
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+
+		if buffered {
+			body, err := io.ReadAll(c.Request.Body)
+			c.Request.Body.Close()
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					abortBodyTooLarge(c, maxBytes)
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error": "Failed to read request body",
+					"code":  "INVALID_BODY",
+				})
+				return
+			}
+
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Request.ContentLength = int64(len(body))
+			c.Set("request_body", body)
+		}
+
+		c.Next()
+	}
+}
+
+// abortBodyTooLarge 返回413
+func abortBodyTooLarge(c *gin.Context, maxBytes int64) {
+	c.Header("Connection", "close")
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": fmt.Sprintf("Request body exceeds %d bytes", maxBytes),
+		"code":  "BODY_TOO_LARGE",
+	})
+}
@@ -31,6 +31,13 @@ type clusterBreaker struct {
 	Config        *types.BreakerConfig
 	Stats         *breakerStats
 	mutex         sync.RWMutex
+
+	// 半开阶段的分步恢复状态
+	AdmitRatio    float64   // 当前放行比例
+	admitCredit   float64   // 放行配额累积，保证放行比例平滑
+	stepStart     time.Time // 当前恢复步的开始时间
+	stepSuccesses int64
+	stepFailures  int64
 }
 
 // breakerStats 熔断器统计
@@ -41,6 +48,12 @@ type breakerStats struct {
 	BreakerOpenCount int64
 	LastStateChange  time.Time
 	mutex            sync.RWMutex
+
+	// 推送到控制面的窗口统计
+	window    types.BreakerWindowStats
+	changed   bool      // 窗口内有变化
+	openSince time.Time // 开启时长在窗口内的计算起点，关闭状态时为零值
+	tripStart time.Time // 本次熔断的开启时间，关闭状态时为零值
 }
 
 // NewClusterCircuitBreaker 创建基于簇的熔断器
@@ -72,23 +85,34 @@ func (ccb *clusterCircuitBreaker) Allow(ctx context.Context, clusterID string) b
 	// 记录请求统计
 	breaker.Stats.recordRequest()
 
-	switch breaker.State {
+	if breaker.allow() {
+		return true
+	}
+	breaker.Stats.recordRejection()
+	return false
+}
+
+// allow 按熔断状态决定是否放行
+func (cb *clusterBreaker) allow() bool {
+	switch cb.State {
 	case types.BreakerStateClosed:
 		// 关闭状态：允许请求
 		return true
 
 	case types.BreakerStateOpen:
 		// 开启状态：检查是否可以转换为半开
-		if time.Now().After(breaker.NextRetry) {
-			breaker.setState(types.BreakerStateHalfOpen)
-			log.Printf("Circuit breaker for cluster %s changed to HALF_OPEN", clusterID)
-			return true
+		if cb.expire() {
+			return cb.admit()
 		}
 		return false
 
 	case types.BreakerStateHalfOpen:
-		// 半开状态：允许部分请求
-		return true
+		// 半开状态：按当前恢复比例放行
+		if cb.advanceStep() {
+			log.Printf("Circuit breaker for cluster %s recovered to CLOSED", cb.ClusterID)
+			return true
+		}
+		return cb.admit()
 
 	default:
 		return false
@@ -117,11 +141,9 @@ func (ccb *clusterCircuitBreaker) RecordSuccess(clusterID string) error {
 
 	switch breaker.State {
 	case types.BreakerStateHalfOpen:
-		// 半开状态下的成功，可能转换为关闭状态
-		recoveryThreshold := int64(float64(breaker.Config.FailureThreshold) * breaker.Config.RecoveryIncrement)
-		if breaker.SuccessCount >= recoveryThreshold {
-			breaker.setState(types.BreakerStateClosed)
-			breaker.reset()
+		// 半开状态下的成功计入当前恢复步，步长周期结束后提升放行比例
+		breaker.stepSuccesses++
+		if breaker.advanceStep() {
 			log.Printf("Circuit breaker for cluster %s recovered to CLOSED", clusterID)
 		}
 
@@ -165,11 +187,15 @@ func (ccb *clusterCircuitBreaker) RecordFailure(clusterID string) error {
 		}
 
 	case types.BreakerStateHalfOpen:
-		// 半开状态下的失败，重新开启熔断
-		breaker.setState(types.BreakerStateOpen)
-		breaker.NextRetry = time.Now().Add(breaker.Config.RecoveryTimeout)
-		breaker.Stats.recordBreakerOpen()
-		log.Printf("Circuit breaker for cluster %s re-opened due to failure in half-open state", clusterID)
+		// 半开状态下失败率超过容忍度视为回退，重新开启熔断
+		breaker.stepFailures++
+		if breaker.regressed() {
+			breaker.setState(types.BreakerStateOpen)
+			breaker.NextRetry = time.Now().Add(breaker.Config.RecoveryTimeout)
+			breaker.Stats.recordBreakerOpen()
+			log.Printf("Circuit breaker for cluster %s re-opened due to regression at %.0f%% admission",
+				clusterID, breaker.AdmitRatio*100)
+		}
 	}
 
 	return nil
@@ -189,12 +215,91 @@ func (ccb *clusterCircuitBreaker) GetState(clusterID string) types.BreakerState
 		return types.BreakerStateClosed
 	}
 
-	breaker.mutex.RLock()
-	defer breaker.mutex.RUnlock()
+	// 开启超时到期按时间转换为半开，不记录请求也不消耗放行配额
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
 
+	if breaker.State == types.BreakerStateOpen {
+		breaker.expire()
+	}
 	return breaker.State
 }
 
+// Stats 汇总所有簇的熔断统计
+func (ccb *clusterCircuitBreaker) Stats() map[string]interface{} {
+	ccb.mutex.RLock()
+	defer ccb.mutex.RUnlock()
+
+	var total, success, failed, opened int64
+	var open, halfOpen int
+	for _, breaker := range ccb.clusters {
+		t, s, f, o := breaker.Stats.getStats()
+		total += t
+		success += s
+		failed += f
+		opened += o
+
+		breaker.mutex.RLock()
+		switch breaker.State {
+		case types.BreakerStateOpen:
+			open++
+		case types.BreakerStateHalfOpen:
+			halfOpen++
+		}
+		breaker.mutex.RUnlock()
+	}
+
+	return map[string]interface{}{
+		"clusters":           len(ccb.clusters),
+		"open":               open,
+		"half_open":          halfOpen,
+		"total_requests":     total,
+		"success_requests":   success,
+		"failed_requests":    failed,
+		"breaker_open_count": opened,
+	}
+}
+
+// Health 健康状态，簇熔断是对上游故障的正常保护，不影响熔断器本身的健康，只在消息中说明
+func (ccb *clusterCircuitBreaker) Health() types.ComponentHealth {
+	ccb.mutex.RLock()
+	defer ccb.mutex.RUnlock()
+
+	open := 0
+	for _, breaker := range ccb.clusters {
+		breaker.mutex.RLock()
+		if breaker.State == types.BreakerStateOpen {
+			open++
+		}
+		breaker.mutex.RUnlock()
+	}
+
+	health := types.ComponentHealth{Status: types.ComponentHealthy}
+	if open > 0 {
+		health.Message = fmt.Sprintf("%d of %d cluster breakers open", open, len(ccb.clusters))
+	}
+	return health
+}
+
+// DrainBreakerStats 取出上次调用以来有变化的簇熔断统计，仍处于开启或半开状态的簇每个窗口都会上报开启时长
+func (ccb *clusterCircuitBreaker) DrainBreakerStats() map[string]*types.BreakerWindowStats {
+	ccb.mutex.RLock()
+	defer ccb.mutex.RUnlock()
+
+	result := make(map[string]*types.BreakerWindowStats)
+	now := time.Now()
+	for clusterID, breaker := range ccb.clusters {
+		breaker.mutex.RLock()
+		state := breaker.State
+		breaker.mutex.RUnlock()
+
+		if stats, ok := breaker.Stats.drainWindow(state, now); ok {
+			result[clusterID] = stats
+		}
+	}
+	return result
+}
+
 // UpdatePolicy 更新簇策略
 func (ccb *clusterCircuitBreaker) UpdatePolicy(clusterID string, policy *types.Policy) error {
 	if policy == nil {
@@ -224,9 +329,11 @@ func (ccb *clusterCircuitBreaker) UpdatePolicy(clusterID string, policy *types.P
 		// 更新熔断配置
 		breaker.mutex.Lock()
 		breaker.Config = &types.BreakerConfig{
-			FailureThreshold:  ccb.config.FailureThreshold,
-			RecoveryTimeout:   policy.CircuitBreak.BreakDuration,
-			RecoveryIncrement: policy.CircuitBreak.RecoveryStep,
+			FailureThreshold:     ccb.config.FailureThreshold,
+			RecoveryTimeout:      policy.CircuitBreak.BreakDuration,
+			RecoveryIncrement:    policy.CircuitBreak.RecoveryStep,
+			RecoveryInterval:     ccb.config.RecoveryInterval,
+			RecoveryFailureRatio: ccb.config.RecoveryFailureRatio,
 		}
 
 		// 如果策略要求立即熔断
@@ -251,10 +358,96 @@ func (cb *clusterBreaker) setState(state types.BreakerState) {
 	cb.Stats.recordStateChange()
 }
 
+// enterHalfOpen 进入半开状态，从一个恢复步长的放行比例开始
+func (cb *clusterBreaker) enterHalfOpen() {
+	cb.setState(types.BreakerStateHalfOpen)
+	cb.AdmitRatio = cb.recoveryStep()
+	cb.admitCredit = 0
+	cb.startStep()
+}
+
+// expire 开启超时到期时转换为半开，返回是否发生转换
+func (cb *clusterBreaker) expire() bool {
+	if !time.Now().After(cb.NextRetry) {
+		return false
+	}
+	cb.enterHalfOpen()
+	log.Printf("Circuit breaker for cluster %s changed to HALF_OPEN (admit %.0f%%)", cb.ClusterID, cb.AdmitRatio*100)
+	return true
+}
+
+// admit 按放行比例决定是否放行，配额累积保证放行均匀分布
+func (cb *clusterBreaker) admit() bool {
+	cb.admitCredit += cb.AdmitRatio
+	if cb.admitCredit >= 1 {
+		cb.admitCredit--
+		return true
+	}
+	return false
+}
+
+// advanceStep 恢复步长周期结束且健康时提升放行比例，达到100%时关闭熔断器
+// 周期内没有成功请求时不提升，避免无流量时自动恢复
+func (cb *clusterBreaker) advanceStep() bool {
+	if time.Since(cb.stepStart) < cb.stepInterval() {
+		return false
+	}
+
+	if cb.stepSuccesses == 0 || cb.regressed() {
+		cb.startStep()
+		return false
+	}
+
+	cb.AdmitRatio += cb.recoveryStep()
+	if cb.AdmitRatio >= 1 {
+		cb.setState(types.BreakerStateClosed)
+		cb.Stats.recordRecovery()
+		cb.reset()
+		return true
+	}
+
+	cb.startStep()
+	return false
+}
+
+// regressed 判断当前恢复步的失败率是否超过容忍度
+func (cb *clusterBreaker) regressed() bool {
+	if cb.stepFailures == 0 {
+		return false
+	}
+	total := cb.stepSuccesses + cb.stepFailures
+	return float64(cb.stepFailures)/float64(total) > cb.Config.RecoveryFailureRatio
+}
+
+// startStep 开始新的恢复步
+func (cb *clusterBreaker) startStep() {
+	cb.stepStart = time.Now()
+	cb.stepSuccesses = 0
+	cb.stepFailures = 0
+}
+
+// recoveryStep 每步提升的放行比例
+func (cb *clusterBreaker) recoveryStep() float64 {
+	if cb.Config.RecoveryIncrement <= 0 || cb.Config.RecoveryIncrement > 1 {
+		return 0.2
+	}
+	return cb.Config.RecoveryIncrement
+}
+
+// stepInterval 恢复步长周期
+func (cb *clusterBreaker) stepInterval() time.Duration {
+	if cb.Config.RecoveryInterval <= 0 {
+		return 5 * time.Second
+	}
+	return cb.Config.RecoveryInterval
+}
+
 // reset 重置计数器
 func (cb *clusterBreaker) reset() {
 	cb.FailureCount = 0
 	cb.SuccessCount = 0
+	cb.AdmitRatio = 1
+	cb.admitCredit = 0
 }
 
 // newBreakerStats 创建熔断器统计
@@ -269,6 +462,8 @@ func (bs *breakerStats) recordRequest() {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 	bs.TotalRequests++
+	bs.window.Requests++
+	bs.changed = true
 }
 
 // recordSuccess 记录成功
@@ -283,13 +478,66 @@ func (bs *breakerStats) recordFailure() {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 	bs.FailedRequests++
+	bs.window.Failures++
+	bs.changed = true
 }
 
-// recordBreakerOpen 记录熔断器开启
+// recordRejection 记录熔断器拒绝的请求
+func (bs *breakerStats) recordRejection() {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	bs.window.Rejections++
+	bs.changed = true
+}
+
+// recordBreakerOpen 记录熔断器开启，半开回退后的重新开启计为新的熔断但不重新计算恢复时长
 func (bs *breakerStats) recordBreakerOpen() {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 	bs.BreakerOpenCount++
+	bs.window.Trips++
+	bs.changed = true
+	if bs.tripStart.IsZero() {
+		now := time.Now()
+		bs.tripStart = now
+		bs.openSince = now
+	}
+}
+
+// recordRecovery 记录熔断器恢复到关闭状态
+func (bs *breakerStats) recordRecovery() {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	now := time.Now()
+	bs.window.Recoveries++
+	bs.changed = true
+	if !bs.tripStart.IsZero() {
+		bs.window.OpenMillis += now.Sub(bs.openSince).Milliseconds()
+		bs.window.RecoveryMillis += now.Sub(bs.tripStart).Milliseconds()
+	}
+	bs.tripStart = time.Time{}
+	bs.openSince = time.Time{}
+}
+
+// drainWindow 取出窗口统计并开始新的窗口，窗口内没有变化且熔断器处于关闭状态时返回false
+func (bs *breakerStats) drainWindow(state types.BreakerState, now time.Time) (*types.BreakerWindowStats, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if !bs.openSince.IsZero() {
+		bs.window.OpenMillis += now.Sub(bs.openSince).Milliseconds()
+		bs.openSince = now
+		bs.changed = true
+	}
+	if !bs.changed {
+		return nil, false
+	}
+
+	stats := bs.window
+	stats.State = state
+	bs.window = types.BreakerWindowStats{}
+	bs.changed = false
+	return &stats, true
 }
 
 // recordStateChange 记录状态变更
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/ipfilter"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultClientIPHeaders 默认读取的客户端IP请求头
+var defaultClientIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// configureClientIP 配置可信代理链，c.ClientIP()只在请求来自可信代理时读取IP请求头，
+// 并从X-Forwarded-For右侧跳过可信代理取第一个不可信地址，防止客户端伪造
+func configureClientIP(router *gin.Engine, config *types.ClientIPConfig) error {
+	// 与IP访问控制使用同样的规范形式，IPv6地址可带方括号，IPv4映射网段按IPv4处理
+	proxies := make([]string, 0, len(config.TrustedProxies))
+	for _, proxy := range config.TrustedProxies {
+		prefixes, err := ipfilter.ParsePrefixes([]string{proxy})
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		proxies = append(proxies, prefixes[0].String())
+	}
+
+	// 未配置可信代理时不信任任何请求头，直接使用对端地址
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("failed to set trusted proxies: %v", err)
+	}
+
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = defaultClientIPHeaders
+	}
+	router.RemoteIPHeaders = make([]string, 0, len(headers))
+	for _, header := range headers {
+		router.RemoteIPHeaders = append(router.RemoteIPHeaders, http.CanonicalHeaderKey(header))
+	}
+
+	return nil
+}
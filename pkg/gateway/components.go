@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// components 参与统计汇总和就绪检查的组件，必需组件总是列出，可选组件只在启用时列出
+func (g *Gateway) components() map[string]interface{} {
+	components := map[string]interface{}{
+		"requests":        g.middleware,
+		"rate_limiter":    g.rateLimiter,
+		"circuit_breaker": g.circuitBreaker,
+		"error_sampler":   g.errorSampler,
+		"config_watcher":  g.configWatcher,
+		"vector_agent":    g.vectorAgent,
+		"access_log":      g.accessLog,
+	}
+	if g.keyLimiter != nil {
+		components["api_key_limiter"] = g.keyLimiter
+	}
+	if g.tenantQuotas != nil {
+		components["tenant_quota"] = g.tenantQuotas
+	}
+	if g.concurrency != nil {
+		components["concurrency"] = g.concurrency
+	}
+	if g.llmProxy != nil {
+		components["llm_proxy"] = g.llmProxy
+	}
+	if g.gossip != nil {
+		components["gossip"] = g.gossip
+	}
+	if g.metricsExport != nil {
+		components["metrics_export"] = g.metricsExport
+	}
+	if g.usage != nil {
+		components["usage"] = g.usage
+	}
+	if g.compression != nil {
+		components["compression"] = g.compression
+	}
+	return components
+}
+
+// getComponentsHandler 汇总各组件的健康状态和运行统计，任一组件不可用时整体为unhealthy
+func (g *Gateway) getComponentsHandler(c *gin.Context) {
+	statuses, ready := utils.CollectComponents(g.components())
+
+	overall := types.ComponentHealthy
+	for _, status := range statuses {
+		if status.Health.Status == types.ComponentDegraded {
+			overall = types.ComponentDegraded
+		}
+	}
+	if !ready {
+		overall = types.ComponentUnhealthy
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     overall,
+		"components": statuses,
+		"timestamp":  time.Now().Unix(),
+	})
+}
@@ -0,0 +1,306 @@
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ratioCheckBytes 解压后小于该大小时不检查压缩比，小请求体的压缩比可能很高
+const ratioCheckBytes = 1 << 20
+
+var (
+	// ErrTooLarge 解压后超过大小上限或压缩比上限
+	ErrTooLarge = errors.New("decompressed body exceeds limit")
+	// ErrUnsupported 不支持的内容编码
+	ErrUnsupported = errors.New("unsupported content encoding")
+)
+
+// Handler 内容编码处理：检查前解压gzip/deflate，检查后按客户端Accept-Encoding重新压缩。
+// 标准库没有br解码器，br编码的请求体无法检查，检查响应体时向上游只声明可解压的编码
+type Handler struct {
+	config types.CompressionConfig
+
+	decodedRequests  int64
+	rejectedRequests int64
+	decodedResponses int64
+	encodedResponses int64
+	passthrough      int64 // 无法解压或超限而原样透传的响应
+}
+
+// NewHandler 创建内容编码处理
+func NewHandler(config *types.CompressionConfig) *Handler {
+	cfg := *config
+	if cfg.MaxDecompressedBytes <= 0 {
+		cfg.MaxDecompressedBytes = 10 << 20
+	}
+	if cfg.MaxRatio <= 0 {
+		cfg.MaxRatio = 100
+	}
+	if cfg.MinCompressBytes <= 0 {
+		cfg.MinCompressBytes = 1024
+	}
+	return &Handler{config: cfg}
+}
+
+// Supported 编码是否可以解压和压缩
+func Supported(encoding string) bool {
+	switch normalize(encoding) {
+	case "", "identity", "gzip", "deflate":
+		return true
+	}
+	return false
+}
+
+// normalize 规范化编码名，x-gzip等同gzip
+func normalize(encoding string) string {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "x-gzip" {
+		return "gzip"
+	}
+	return encoding
+}
+
+// encodings 解析Content-Encoding，按应用顺序返回，忽略identity
+func encodings(header string) []string {
+	var result []string
+	for _, part := range strings.Split(header, ",") {
+		if encoding := normalize(part); encoding != "" && encoding != "identity" {
+			result = append(result, encoding)
+		}
+	}
+	return result
+}
+
+// IsEncoded Content-Encoding是否表示压缩内容
+func IsEncoded(header string) bool {
+	return len(encodings(header)) > 0
+}
+
+// Decode 按Content-Encoding逆序解压，解压后大小受maxBytes和压缩比限制
+func (h *Handler) Decode(data []byte, contentEncoding string) ([]byte, error) {
+	applied := encodings(contentEncoding)
+	for _, encoding := range applied {
+		if !Supported(encoding) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, encoding)
+		}
+	}
+
+	for i := len(applied) - 1; i >= 0; i-- {
+		decoded, err := h.decodeOne(data, applied[i])
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+// decodeOne 解压单层编码，读取不超过上限加一字节以判断是否超限
+func (h *Handler) decodeOne(data []byte, encoding string) ([]byte, error) {
+	limit := h.config.MaxDecompressedBytes
+	byRatio := int64(len(data)) * h.config.MaxRatio
+	if byRatio < ratioCheckBytes {
+		byRatio = ratioCheckBytes
+	}
+	if byRatio < limit {
+		limit = byRatio
+	}
+
+	var reader io.ReadCloser
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip body: %v", err)
+		}
+		reader = gz
+	case "deflate":
+		reader = flate.NewReader(bytes.NewReader(data))
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s body: %v", encoding, err)
+	}
+	if int64(len(decoded)) > limit {
+		return nil, ErrTooLarge
+	}
+	return decoded, nil
+}
+
+// Encode 以指定编码压缩
+func Encode(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch normalize(encoding) {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		writer = fw
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, encoding)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress body: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress body: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Negotiate 按Accept-Encoding的q值选择可压缩的编码，客户端不接受压缩时返回空
+func Negotiate(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseCoding(part)
+		if name == "*" {
+			name = "gzip"
+		}
+		if (name == "gzip" || name == "deflate") && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// FilterAcceptEncoding 去掉无法解压的编码，客户端只接受不支持的编码时返回identity
+func FilterAcceptEncoding(acceptEncoding string) string {
+	var kept []string
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseCoding(part)
+		if name == "" || q <= 0 {
+			continue
+		}
+		if name == "gzip" || name == "deflate" || name == "identity" {
+			kept = append(kept, strings.TrimSpace(part))
+		}
+	}
+	if len(kept) == 0 {
+		return "identity"
+	}
+	return strings.Join(kept, ", ")
+}
+
+// parseCoding 解析"gzip;q=0.8"
+func parseCoding(part string) (string, float64) {
+	name, params, _ := strings.Cut(part, ";")
+	q := 1.0
+	if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			q = parsed
+		}
+	}
+	return normalize(name), q
+}
+
+// DecodeResponse 解压上游响应体供改写和校验，返回是否已解压；无法解压或超限时原样保留响应体
+func (h *Handler) DecodeResponse(resp *http.Response) bool {
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	if !IsEncoded(contentEncoding) {
+		return false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.config.MaxDecompressedBytes+1))
+	if err != nil {
+		resp.Body = restore(data, resp.Body)
+		atomic.AddInt64(&h.passthrough, 1)
+		return false
+	}
+	if int64(len(data)) > h.config.MaxDecompressedBytes {
+		resp.Body = restore(data, resp.Body)
+		atomic.AddInt64(&h.passthrough, 1)
+		return false
+	}
+	resp.Body.Close()
+
+	decoded, err := h.Decode(data, contentEncoding)
+	if err != nil {
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		atomic.AddInt64(&h.passthrough, 1)
+		return false
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(decoded))
+	resp.ContentLength = int64(len(decoded))
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(decoded)))
+	atomic.AddInt64(&h.decodedResponses, 1)
+	return true
+}
+
+// EncodeResponse 按客户端Accept-Encoding重新压缩已解压的响应体，过小或客户端不接受压缩时以identity返回
+func (h *Handler) EncodeResponse(resp *http.Response, acceptEncoding string) error {
+	resp.Header.Add("Vary", "Accept-Encoding")
+
+	encoding := Negotiate(acceptEncoding)
+	if encoding == "" || resp.ContentLength < int64(h.config.MinCompressBytes) {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+	encoded, err := Encode(data, encoding)
+	if err != nil {
+		return err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(encoded))
+	resp.ContentLength = int64(len(encoded))
+	resp.Header.Set("Content-Encoding", encoding)
+	resp.Header.Set("Content-Length", strconv.Itoa(len(encoded)))
+	atomic.AddInt64(&h.encodedResponses, 1)
+	return nil
+}
+
+// restore 拼回已读部分
+func restore(data []byte, body io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+}
+
+// RecordRequest 记录请求体解压结果
+func (h *Handler) RecordRequest(decoded bool) {
+	if decoded {
+		atomic.AddInt64(&h.decodedRequests, 1)
+	} else {
+		atomic.AddInt64(&h.rejectedRequests, 1)
+	}
+}
+
+// MaxDecompressedBytes 解压后大小上限
+func (h *Handler) MaxDecompressedBytes() int64 {
+	return h.config.MaxDecompressedBytes
+}
+
+// Stats 获取统计
+func (h *Handler) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"decoded_requests":      atomic.LoadInt64(&h.decodedRequests),
+		"rejected_requests":     atomic.LoadInt64(&h.rejectedRequests),
+		"decoded_responses":     atomic.LoadInt64(&h.decodedResponses),
+		"recompressed":          atomic.LoadInt64(&h.encodedResponses),
+		"passthrough_responses": atomic.LoadInt64(&h.passthrough),
+	}
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// routeConcurrency 路由级并发限制，在途请求达到上限时排队等待，空位在请求处理完成后释放
+func routeConcurrency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := matchedRoute(c)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		release, err := route.AcquireSlot(c.Request.Context())
+		if err != nil {
+			rejectConcurrency(c, "route_concurrency", "route "+route.Name, err)
+			return
+		}
+		defer release()
+
+		annotateQueued(c, "route_concurrency", start)
+		c.Next()
+	}
+}
+
+// clusterConcurrency 按簇的并发限制，需在熔断之后执行以获取识别出的簇
+func (g *Gateway) clusterConcurrency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clusterID := c.GetString("cluster_id")
+		if clusterID == "" {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		release, err := g.concurrency.Acquire(c.Request.Context(), clusterID)
+		if err != nil {
+			if g.metrics != nil && err != c.Request.Context().Err() {
+				g.metrics.RecordRateLimitHit(clusterID, "CONCURRENCY_LIMIT")
+			}
+			rejectConcurrency(c, "concurrency_limit", "cluster "+clusterID, err)
+			return
+		}
+		defer release()
+
+		annotateQueued(c, "concurrency_limit", start)
+		c.Next()
+	}
+}
+
+// rejectConcurrency 拒绝未获得空位的请求，排队期间客户端断开时只记录取消
+func rejectConcurrency(c *gin.Context, stage, scope string, err error) {
+	if err != limiter.ErrConcurrencyQueueFull && err != limiter.ErrConcurrencyQueueTimeout {
+		c.Set("client_canceled", "queued")
+		c.Abort()
+		return
+	}
+
+	utils.RecordStageError(c, stage, fmt.Errorf("%v for %s", err, scope))
+
+	code := "CONCURRENCY_QUEUE_FULL"
+	if err == limiter.ErrConcurrencyQueueTimeout {
+		code = "CONCURRENCY_QUEUE_TIMEOUT"
+	}
+	c.Header("Retry-After", "1")
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": "Too many concurrent requests",
+		"code":  code,
+	})
+	c.Abort()
+}
+
+// annotateQueued 记录请求排队等待空位的时间
+func annotateQueued(c *gin.Context, stage string, start time.Time) {
+	if wait := time.Since(start); wait >= time.Millisecond {
+		utils.AnnotateStage(c, stage, fmt.Sprintf("queued for %s", wait.Round(time.Millisecond)))
+	}
+}
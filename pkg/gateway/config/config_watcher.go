@@ -4,29 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"log"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
 
 	"github.com/llm-aware-gateway/pkg/interfaces"
 	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
 )
 
 // configWatcher 配置监听器实现
 type configWatcher struct {
 	etcdClient *clientv3.Client
-	policies   map[string]*types.Policy
+	region     string
+	policies   map[string]*types.Policy // 生效策略，区域策略优先于全局策略
+	global     map[string]*types.Policy
+	regional   map[string]*types.Policy
 	callbacks  []interfaces.PolicyUpdateCallback
+	watching   bool
+	syncErr    string    // 最近一次加载或监听失败的原因，成功后清除
+	lastSync   time.Time // 最近一次成功加载或收到变更的时间
+	events     int64     // 收到的策略变更事件数
 	mutex      sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
 	stopCh     chan struct{}
 }
 
-// NewConfigWatcher 创建配置监听器
-func NewConfigWatcher(config *types.ETCDConfig) (interfaces.ConfigWatcher, error) {
+// NewConfigWatcher 创建配置监听器，region为空时只接收全局策略
+func NewConfigWatcher(config *types.ETCDConfig, region string) (interfaces.ConfigWatcher, error) {
 	client, err := clientv3.New(clientv3.Config{
 		Endpoints:   config.Endpoints,
 		DialTimeout: config.Timeout,
@@ -41,7 +49,10 @@ func NewConfigWatcher(config *types.ETCDConfig) (interfaces.ConfigWatcher, error
 
 	return &configWatcher{
 		etcdClient: client,
+		region:     region,
 		policies:   make(map[string]*types.Policy),
+		global:     make(map[string]*types.Policy),
+		regional:   make(map[string]*types.Policy),
 		ctx:        ctx,
 		cancel:     cancel,
 		stopCh:     make(chan struct{}),
@@ -55,27 +66,95 @@ func (cw *configWatcher) WatchPolicyUpdates() error {
 	if err != nil {
 		log.Printf("Failed to load existing policies: %v", err)
 	}
+	cw.recordSync(err)
 
-	// 开始监听策略变更
-	watchChan := cw.etcdClient.Watch(cw.ctx, "/policies/", clientv3.WithPrefix())
+	// 开始监听策略变更，只监听全局前缀和本区域前缀，其他区域的策略不会下发到本网关
+	watchChan := cw.etcdClient.Watch(cw.ctx, utils.GlobalPolicyPrefix, clientv3.WithPrefix())
+	var regionChan clientv3.WatchChan
+	if cw.region != "" {
+		regionChan = cw.etcdClient.Watch(cw.ctx, utils.PolicyPrefix(cw.region), clientv3.WithPrefix())
+	}
 
 	go func() {
 		for {
 			select {
 			case watchResp := <-watchChan:
-				for _, event := range watchResp.Events {
-					cw.handleConfigEvent(event)
-				}
+				cw.handleWatchResponse(watchResp)
+			case watchResp := <-regionChan:
+				cw.handleWatchResponse(watchResp)
 			case <-cw.stopCh:
 				return
 			}
 		}
 	}()
 
-	log.Println("Config watcher started")
+	cw.mutex.Lock()
+	cw.watching = true
+	cw.mutex.Unlock()
+
+	log.Printf("Config watcher started (region=%q)", cw.region)
 	return nil
 }
 
+// handleWatchResponse 处理一批策略变更，监听出错时记录原因
+func (cw *configWatcher) handleWatchResponse(watchResp clientv3.WatchResponse) {
+	if err := watchResp.Err(); err != nil {
+		cw.recordSync(err)
+		return
+	}
+	for _, event := range watchResp.Events {
+		cw.handleConfigEvent(event)
+	}
+	if len(watchResp.Events) > 0 {
+		atomic.AddInt64(&cw.events, int64(len(watchResp.Events)))
+		cw.recordSync(nil)
+	}
+}
+
+// recordSync 记录加载或监听结果
+func (cw *configWatcher) recordSync(err error) {
+	cw.mutex.Lock()
+	defer cw.mutex.Unlock()
+	if err != nil {
+		cw.syncErr = err.Error()
+		return
+	}
+	cw.syncErr = ""
+	cw.lastSync = time.Now()
+}
+
+// Health 健康状态：未开始监听时不可用，最近一次加载或监听失败时降级，继续使用已加载的策略
+func (cw *configWatcher) Health() types.ComponentHealth {
+	cw.mutex.RLock()
+	defer cw.mutex.RUnlock()
+
+	switch {
+	case !cw.watching:
+		return types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "not watching policy updates"}
+	case cw.syncErr != "":
+		return types.ComponentHealth{Status: types.ComponentDegraded, Message: cw.syncErr}
+	}
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+// Stats 获取监听统计
+func (cw *configWatcher) Stats() map[string]interface{} {
+	cw.mutex.RLock()
+	defer cw.mutex.RUnlock()
+
+	stats := map[string]interface{}{
+		"policies":          len(cw.policies),
+		"global_policies":   len(cw.global),
+		"regional_policies": len(cw.regional),
+		"callbacks":         len(cw.callbacks),
+		"events":            atomic.LoadInt64(&cw.events),
+	}
+	if !cw.lastSync.IsZero() {
+		stats["last_sync"] = cw.lastSync
+	}
+	return stats
+}
+
 // GetPolicy 获取策略
 func (cw *configWatcher) GetPolicy(clusterID string) (*types.Policy, error) {
 	cw.mutex.RLock()
@@ -103,6 +182,41 @@ func (cw *configWatcher) RegisterCallback(callback interfaces.PolicyUpdateCallba
 	return nil
 }
 
+// WatchPrefix 加载并监听指定前缀下的配置键，用于策略以外的运行时配置
+func (cw *configWatcher) WatchPrefix(prefix string, callback interfaces.KeyUpdateCallback) error {
+	ctx, cancel := context.WithTimeout(cw.ctx, 5*time.Second)
+	resp, err := cw.etcdClient.Get(ctx, prefix, clientv3.WithPrefix())
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range resp.Kvs {
+		callback(string(kv.Key), kv.Value, false)
+	}
+
+	// 从读取时的版本之后开始监听，避免遗漏变更
+	watchChan := cw.etcdClient.Watch(cw.ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+
+	go func() {
+		for {
+			select {
+			case watchResp, ok := <-watchChan:
+				if !ok {
+					return
+				}
+				for _, event := range watchResp.Events {
+					callback(string(event.Kv.Key), event.Kv.Value, event.Type == clientv3.EventTypeDelete)
+				}
+			case <-cw.stopCh:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
 // Start 启动配置监听器
 func (cw *configWatcher) Start() error {
 	return cw.WatchPolicyUpdates()
@@ -113,6 +227,10 @@ func (cw *configWatcher) Stop() error {
 	close(cw.stopCh)
 	cw.cancel()
 
+	cw.mutex.Lock()
+	cw.watching = false
+	cw.mutex.Unlock()
+
 	if cw.etcdClient != nil {
 		cw.etcdClient.Close()
 	}
@@ -123,68 +241,125 @@ func (cw *configWatcher) Stop() error {
 
 // loadExistingPolicies 加载现有策略
 func (cw *configWatcher) loadExistingPolicies() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	resp, err := cw.etcdClient.Get(ctx, "/policies/", clientv3.WithPrefix())
-	if err != nil {
-		return err
+	prefixes := []string{utils.GlobalPolicyPrefix}
+	if cw.region != "" {
+		prefixes = append(prefixes, utils.PolicyPrefix(cw.region))
 	}
 
-	for _, kv := range resp.Kvs {
-		clusterID := strings.TrimPrefix(string(kv.Key), "/policies/")
-
-		var policy types.Policy
-		if err := json.Unmarshal(kv.Value, &policy); err != nil {
-			log.Printf("Failed to unmarshal policy for cluster %s: %v", clusterID, err)
-			continue
+	loaded := 0
+	for _, prefix := range prefixes {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		resp, err := cw.etcdClient.Get(ctx, prefix, clientv3.WithPrefix())
+		cancel()
+		if err != nil {
+			return err
 		}
 
-		cw.mutex.Lock()
-		cw.policies[clusterID] = &policy
-		cw.mutex.Unlock()
-
-		// 通知回调
-		cw.notifyPolicyUpdate(clusterID, &policy)
+		for _, kv := range resp.Kvs {
+			if cw.storePolicy(string(kv.Key), kv.Value, kv.ModRevision) {
+				loaded++
+			}
+		}
 	}
 
-	log.Printf("Loaded %d existing policies", len(resp.Kvs))
+	log.Printf("Loaded %d existing policies", loaded)
 	return nil
 }
 
 // handleConfigEvent 处理配置事件
 func (cw *configWatcher) handleConfigEvent(event *clientv3.Event) {
-	clusterID := strings.TrimPrefix(string(event.Kv.Key), "/policies/")
-
 	switch event.Type {
 	case clientv3.EventTypePut:
-		var policy types.Policy
-		if err := json.Unmarshal(event.Kv.Value, &policy); err != nil {
-			log.Printf("Failed to unmarshal policy for cluster %s: %v", clusterID, err)
+		cw.storePolicy(string(event.Kv.Key), event.Kv.Value, event.Kv.ModRevision)
+
+	case clientv3.EventTypeDelete:
+		region, clusterID, ok := utils.ParsePolicyKey(string(event.Kv.Key))
+		if !ok {
 			return
 		}
 
 		cw.mutex.Lock()
-		cw.policies[clusterID] = &policy
+		if region == "" {
+			delete(cw.global, clusterID)
+		} else {
+			delete(cw.regional, clusterID)
+		}
 		cw.mutex.Unlock()
 
-		// 通知回调
-		cw.notifyPolicyUpdate(clusterID, &policy)
+		cw.resolve(clusterID)
+	}
+}
 
-		log.Printf("Policy updated for cluster: %s", clusterID)
+// storePolicy 解析并保存全局或区域策略，返回是否成功
+func (cw *configWatcher) storePolicy(key string, value []byte, revision int64) bool {
+	region, clusterID, ok := utils.ParsePolicyKey(key)
+	if !ok {
+		return false
+	}
 
-	case clientv3.EventTypeDelete:
-		cw.mutex.Lock()
+	var policy types.Policy
+	if err := json.Unmarshal(value, &policy); err != nil {
+		log.Printf("Failed to unmarshal policy for cluster %s: %v", clusterID, err)
+		return false
+	}
+	if policy.Version == 0 {
+		policy.Version = revision
+	}
+
+	cw.mutex.Lock()
+	if region == "" {
+		cw.global[clusterID] = &policy
+	} else {
+		cw.regional[clusterID] = &policy
+	}
+	cw.mutex.Unlock()
+
+	cw.resolve(clusterID)
+	return true
+}
+
+// resolve 计算簇的生效策略并通知回调：本区域策略优先，其次是未限定区域或包含本区域的全局策略
+func (cw *configWatcher) resolve(clusterID string) {
+	cw.mutex.Lock()
+	var effective *types.Policy
+	if policy, exists := cw.regional[clusterID]; exists && cw.appliesHere(policy) {
+		effective = policy
+	} else if policy, exists := cw.global[clusterID]; exists && cw.appliesHere(policy) {
+		effective = policy
+	}
+
+	_, existed := cw.policies[clusterID]
+	if effective != nil {
+		cw.policies[clusterID] = effective
+	} else {
 		delete(cw.policies, clusterID)
-		cw.mutex.Unlock()
+	}
+	cw.mutex.Unlock()
 
+	if effective != nil {
+		// 通知回调
+		cw.notifyPolicyUpdate(clusterID, effective)
+		log.Printf("Policy updated for cluster: %s", clusterID)
+	} else if existed {
 		// 通知回调
 		cw.notifyPolicyDelete(clusterID)
-
 		log.Printf("Policy deleted for cluster: %s", clusterID)
 	}
 }
 
+// appliesHere 判断策略的区域标签是否包含本网关所在区域
+func (cw *configWatcher) appliesHere(policy *types.Policy) bool {
+	if len(policy.Regions) == 0 {
+		return true
+	}
+	for _, region := range policy.Regions {
+		if region == cw.region {
+			return true
+		}
+	}
+	return false
+}
+
 // notifyPolicyUpdate 通知策略更新
 func (cw *configWatcher) notifyPolicyUpdate(clusterID string, policy *types.Policy) {
 	cw.mutex.RLock()
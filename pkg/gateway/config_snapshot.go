@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// maxConfigRevisions 保留的运行时配置变更记录数
+const maxConfigRevisions = 50
+
+// configHistory 启动时加载的配置和之后的运行时变更记录
+type configHistory struct {
+	loadedAt  time.Time
+	current   map[string]string // 展开后的当前生效配置
+	revisions []types.ConfigRevision
+	mutex     sync.Mutex
+}
+
+// newConfigHistory 以当前生效配置为起点创建变更记录
+func newConfigHistory(tree interface{}) *configHistory {
+	return &configHistory{loadedAt: time.Now(), current: utils.FlattenConfig(tree)}
+}
+
+// record 与上次生效配置比较，有变更时记录一次修订，超过上限时丢弃最早的记录
+func (h *configHistory) record(source string, tree interface{}) *types.ConfigRevision {
+	flat := utils.FlattenConfig(tree)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	changes := utils.DiffConfig(h.current, flat)
+	h.current = flat
+	if len(changes) == 0 {
+		return nil
+	}
+
+	h.revisions = append(h.revisions, types.ConfigRevision{Time: time.Now(), Source: source, Changes: changes})
+	if len(h.revisions) > maxConfigRevisions {
+		h.revisions = h.revisions[len(h.revisions)-maxConfigRevisions:]
+	}
+	return &h.revisions[len(h.revisions)-1]
+}
+
+// since 获取晚于指定时间的修订，按时间先后排列
+func (h *configHistory) since(after time.Time) []types.ConfigRevision {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	revisions := make([]types.ConfigRevision, 0, len(h.revisions))
+	for _, revision := range h.revisions {
+		if revision.Time.After(after) {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions
+}
+
+// runtimeOverrides 通过etcd下发、不在配置文件中的运行时配置，纳入生效配置以记录变更
+type runtimeOverrides struct {
+	policies  map[string]*types.Policy          // 簇ID -> 生效的策略，包括WAF规则集
+	keyLimits map[string]*types.APIKeyRateLimit // 密钥摘要 -> 限流覆盖
+	mutex     sync.Mutex
+}
+
+// newRuntimeOverrides 创建运行时配置
+func newRuntimeOverrides() *runtimeOverrides {
+	return &runtimeOverrides{
+		policies:  make(map[string]*types.Policy),
+		keyLimits: make(map[string]*types.APIKeyRateLimit),
+	}
+}
+
+// setPolicy 记录生效的策略，policy为nil时删除，返回之前的策略
+func (o *runtimeOverrides) setPolicy(clusterID string, policy *types.Policy) *types.Policy {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	previous := o.policies[clusterID]
+	if policy == nil {
+		delete(o.policies, clusterID)
+	} else {
+		o.policies[clusterID] = policy
+	}
+	return previous
+}
+
+// setKeyLimit 记录API密钥的限流覆盖，limit为nil时删除
+func (o *runtimeOverrides) setKeyLimit(keyID string, limit *types.APIKeyRateLimit) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if limit == nil {
+		delete(o.keyLimits, keyID)
+	} else {
+		o.keyLimits[keyID] = limit
+	}
+}
+
+// tree 运行时配置的配置树
+func (o *runtimeOverrides) tree() (interface{}, interface{}) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return utils.ConfigTree(o.policies), utils.ConfigTree(o.keyLimits)
+}
+
+// effectiveConfig 当前生效的完整配置树，凭据已脱敏；路由流量拆分和IP名单可在运行时调整，取实际生效的值，
+// etcd下发的策略和API密钥限流覆盖分别位于policies和api_key_overrides下
+func (g *Gateway) effectiveConfig() interface{} {
+	config := *g.config
+
+	live := make(map[string][]types.RouteSplitConfig)
+	for _, route := range g.routes.Routes() {
+		var splits []types.RouteSplitConfig
+		for _, split := range route.Splits() {
+			splits = append(splits, types.RouteSplitConfig{Upstream: split.Upstream, Version: split.Version, Weight: split.Weight})
+		}
+		live[route.Name] = splits
+	}
+	config.Routes = make([]types.RouteConfig, len(g.config.Routes))
+	for i, route := range g.config.Routes {
+		if splits, exists := live[route.Name]; exists {
+			route.Splits = splits
+		}
+		config.Routes[i] = route
+	}
+
+	if g.ipFilter != nil {
+		config.IPFilter.Allow, config.IPFilter.Deny = g.ipFilter.Lists()
+	}
+
+	tree := utils.ConfigTree(&config).(map[string]interface{})
+	tree["policies"], tree["api_key_overrides"] = g.overrides.tree()
+	return tree
+}
+
+// recordConfigChange 运行时配置调整生效后记录与上次生效配置的差异
+func (g *Gateway) recordConfigChange(source string) {
+	if revision := g.configHistory.record(source, g.effectiveConfig()); revision != nil {
+		log.Printf("Effective configuration changed via %s: %d settings", source, len(revision.Changes))
+	}
+}
+
+// recordPolicyChange 记录策略下发或删除导致的配置差异，WAF规则集单独标注来源
+func (g *Gateway) recordPolicyChange(clusterID string, policy *types.Policy) {
+	source := "policy:" + clusterID
+	if policy != nil && policy.PolicyType == types.PolicyTypeWAF {
+		source = "waf:" + clusterID
+	}
+	g.recordConfigChange(source)
+}
+
+// onIPFilterUpdate 处理etcd中的IP名单变更并记录配置差异
+func (g *Gateway) onIPFilterUpdate(key string, value []byte, deleted bool) {
+	g.ipFilter.OnUpdate(key, value, deleted)
+	g.recordConfigChange("etcd:" + key)
+}
+
+// getConfigHandler 获取当前生效的完整配置（凭据已脱敏）和运行时变更记录，
+// since参数为RFC3339时间时只返回之后的变更
+func (g *Gateway) getConfigHandler(c *gin.Context) {
+	var after time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid since: %v", err)})
+			return
+		}
+		after = parsed
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"loaded_at": g.configHistory.loadedAt,
+		"config":    g.effectiveConfig(),
+		"revisions": g.configHistory.since(after),
+	})
+}
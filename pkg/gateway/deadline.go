@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/router"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// requestStartKey 请求到达时间在Gin上下文中的键
+const requestStartKey = "request_start"
+
+// requestClock 记录请求到达时间，需放在最外层，路由超时从此刻开始计算
+func requestClock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(requestStartKey, time.Now())
+		c.Next()
+	}
+}
+
+// applyDeadline 计算请求截止时间并挂到请求context上：路由超时扣除网关内已消耗的时间，
+// 客户端声明了更短的预算时以客户端为准。context派生自请求context，客户端断开时上游调用随之取消。
+// 预算已耗尽时返回false
+func applyDeadline(c *gin.Context, route *router.Route) (context.CancelFunc, bool) {
+	budget, limited := clientBudget(c.Request)
+
+	if route.Timeout > 0 {
+		remaining := route.Timeout
+		if value, exists := c.Get(requestStartKey); exists {
+			if start, ok := value.(time.Time); ok {
+				remaining -= time.Since(start)
+			}
+		}
+		if !limited || remaining < budget {
+			budget = remaining
+		}
+		limited = true
+	}
+
+	if !limited {
+		return func() {}, true
+	}
+	if budget <= 0 {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), budget)
+	c.Request = c.Request.WithContext(ctx)
+	c.Set("request_budget", budget)
+	return cancel, true
+}
+
+// clientBudget 读取客户端声明的剩余时间，gRPC客户端使用grpc-timeout，其他客户端使用X-Request-Timeout（毫秒）
+func clientBudget(r *http.Request) (time.Duration, bool) {
+	if value := r.Header.Get("Grpc-Timeout"); value != "" {
+		return utils.ParseGRPCTimeout(value)
+	}
+
+	if value := r.Header.Get("X-Request-Timeout"); value != "" {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ms < 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+
+	return 0, false
+}
+
+// abortDeadlineExceeded 转发前预算已耗尽时返回504
+func abortDeadlineExceeded(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{
+		"error": "Request deadline exceeded before forwarding",
+		"code":  "DEADLINE_EXCEEDED",
+	})
+}
@@ -0,0 +1,157 @@
+package decision
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// trailContextKey 决策轨迹在Gin上下文中的键
+const trailContextKey = "decision_trail"
+
+// 决策结果
+const (
+	DecisionAllow  = "allow"
+	DecisionReject = "reject"
+)
+
+// Step 单个决策步骤
+type Step struct {
+	Stage         string                 `json:"stage"`    // rate_limit / circuit_breaker / cluster / route
+	Decision      string                 `json:"decision"` // allow / reject
+	Reason        string                 `json:"reason,omitempty"`
+	ClusterID     string                 `json:"cluster_id,omitempty"`
+	PolicyVersion int64                  `json:"policy_version,omitempty"`
+	Details       map[string]interface{} `json:"details,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+}
+
+// Trail 请求决策轨迹
+type Trail struct {
+	RequestID  string    `json:"request_id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	ClusterID  string    `json:"cluster_id,omitempty"`
+	Similarity float64   `json:"similarity,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Steps      []Step    `json:"steps"`
+	StartTime  time.Time `json:"start_time"`
+	Duration   string    `json:"duration"`
+	mutex      sync.Mutex
+}
+
+// Store 决策轨迹存储，按请求ID短期保存
+type Store struct {
+	cache interfaces.Cache
+	ttl   int64
+}
+
+// NewStore 创建决策轨迹存储
+func NewStore(config *types.DecisionConfig) *Store {
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	ttl := int64(config.TTL.Seconds())
+	if ttl <= 0 {
+		ttl = 300
+	}
+
+	return &Store{
+		cache: utils.NewCache(maxEntries),
+		ttl:   ttl,
+	}
+}
+
+// Middleware 决策轨迹中间件，需放在RequestID之后、限流熔断之前
+func (s *Store) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trail := &Trail{
+			RequestID: c.GetString("request_id"),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			StartTime: time.Now(),
+		}
+		c.Set(trailContextKey, trail)
+
+		c.Next()
+
+		trail.mutex.Lock()
+		trail.StatusCode = c.Writer.Status()
+		trail.Duration = utils.FormatDuration(time.Since(trail.StartTime))
+		trail.Outcome = DecisionAllow
+		for _, step := range trail.Steps {
+			if step.Decision == DecisionReject {
+				trail.Outcome = step.Stage
+				break
+			}
+		}
+		trail.mutex.Unlock()
+
+		if trail.RequestID != "" {
+			s.cache.Set(trail.RequestID, trail, s.ttl)
+		}
+	}
+}
+
+// Get 获取请求的决策轨迹
+func (s *Store) Get(requestID string) (*Trail, bool) {
+	cached, found := s.cache.Get(requestID)
+	if !found {
+		return nil, false
+	}
+
+	trail, ok := cached.(*Trail)
+	return trail, ok
+}
+
+// Record 向当前请求的决策轨迹追加步骤，未启用时为空操作
+func Record(c *gin.Context, step Step) {
+	value, exists := c.Get(trailContextKey)
+	if !exists {
+		return
+	}
+
+	trail, ok := value.(*Trail)
+	if !ok {
+		return
+	}
+
+	step.Timestamp = time.Now()
+
+	trail.mutex.Lock()
+	defer trail.mutex.Unlock()
+
+	trail.Steps = append(trail.Steps, step)
+	if step.ClusterID != "" {
+		trail.ClusterID = step.ClusterID
+	}
+}
+
+// Enabled 判断当前请求是否记录决策轨迹
+func Enabled(c *gin.Context) bool {
+	_, exists := c.Get(trailContextKey)
+	return exists
+}
+
+// RecordSimilarity 记录簇识别的相似度
+func RecordSimilarity(c *gin.Context, clusterID string, similarity float64) {
+	value, exists := c.Get(trailContextKey)
+	if !exists {
+		return
+	}
+
+	if trail, ok := value.(*Trail); ok {
+		trail.mutex.Lock()
+		trail.ClusterID = clusterID
+		trail.Similarity = similarity
+		trail.mutex.Unlock()
+	}
+}
@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 服务发现提供者
+const (
+	ProviderETCD       = "etcd"
+	ProviderKubernetes = "kubernetes"
+)
+
+// defaultETCDPrefix 默认实例注册键前缀
+const defaultETCDPrefix = "/services/"
+
+// etcdDiscovery 基于etcd的服务发现，实例注册在"<prefix><service>/<instance>"键下，
+// 值为{"url": "...", "weight": 1}，也可直接写"host:port"
+type etcdDiscovery struct {
+	watcher interfaces.ConfigWatcher
+	prefix  string
+	stopped bool
+	mutex   sync.Mutex
+}
+
+// NewETCDDiscovery 创建基于etcd的服务发现，复用网关的配置监听器连接
+func NewETCDDiscovery(watcher interfaces.ConfigWatcher, prefix string) interfaces.Discovery {
+	if prefix == "" {
+		prefix = defaultETCDPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &etcdDiscovery{
+		watcher: watcher,
+		prefix:  prefix,
+	}
+}
+
+// Watch 加载服务的全部实例并监听变更，每次变更回调全量实例
+func (ed *etcdDiscovery) Watch(config *types.UpstreamDiscoveryConfig, callback interfaces.DiscoveryCallback) error {
+	if config.Service == "" {
+		return fmt.Errorf("discovery service name is empty")
+	}
+
+	servicePrefix := ed.prefix + config.Service + "/"
+	instances := make(map[string]types.UpstreamTargetConfig)
+	loaded := false
+
+	// 首次加载的键逐个回调，加载完成后统一通知一次
+	err := ed.watcher.WatchPrefix(servicePrefix, func(key string, value []byte, deleted bool) {
+		ed.mutex.Lock()
+		defer ed.mutex.Unlock()
+
+		if ed.stopped {
+			return
+		}
+
+		if deleted {
+			delete(instances, key)
+		} else {
+			target, err := parseInstance(value, config.Scheme)
+			if err != nil {
+				log.Printf("Ignoring invalid instance %s: %v", key, err)
+				return
+			}
+			instances[key] = target
+		}
+
+		if loaded {
+			callback(sortedTargets(instances))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %v", servicePrefix, err)
+	}
+
+	ed.mutex.Lock()
+	loaded = true
+	targets := sortedTargets(instances)
+	ed.mutex.Unlock()
+
+	log.Printf("Discovered %d instances for service %s from etcd", len(targets), config.Service)
+	callback(targets)
+	return nil
+}
+
+// Stop 停止回调，etcd连接由配置监听器负责关闭
+func (ed *etcdDiscovery) Stop() error {
+	ed.mutex.Lock()
+	ed.stopped = true
+	ed.mutex.Unlock()
+	return nil
+}
+
+// parseInstance 解析实例注册值
+func parseInstance(value []byte, scheme string) (types.UpstreamTargetConfig, error) {
+	var target types.UpstreamTargetConfig
+	if err := json.Unmarshal(value, &target); err != nil {
+		address := strings.TrimSpace(string(value))
+		if address == "" {
+			return target, fmt.Errorf("empty instance address")
+		}
+		target.URL = address
+	}
+
+	if target.URL == "" {
+		return target, fmt.Errorf("instance url is empty")
+	}
+	if !strings.Contains(target.URL, "://") {
+		target.URL = withScheme(scheme) + "://" + target.URL
+	}
+
+	return target, nil
+}
+
+// sortedTargets 按URL排序实例，保证回调结果稳定
+func sortedTargets(instances map[string]types.UpstreamTargetConfig) []types.UpstreamTargetConfig {
+	targets := make([]types.UpstreamTargetConfig, 0, len(instances))
+	for _, target := range instances {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].URL < targets[j].URL })
+	return targets
+}
+
+// withScheme 实例URL协议，默认http
+func withScheme(scheme string) string {
+	if scheme == "" {
+		return "http"
+	}
+	return scheme
+}
@@ -0,0 +1,271 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Pod内ServiceAccount默认路径
+const (
+	serviceAccountDir        = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	defaultKubeRetryInterval = 5 * time.Second
+)
+
+// kubernetesDiscovery 基于Kubernetes Endpoints的服务发现，直接调用API Server的list/watch接口
+type kubernetesDiscovery struct {
+	config    *types.KubernetesDiscoveryConfig
+	apiServer string
+	client    *http.Client
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// endpoints Kubernetes Endpoints对象中服务发现需要的字段
+type endpoints struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Subsets []struct {
+		Addresses []struct {
+			IP string `json:"ip"`
+		} `json:"addresses"`
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+// watchEvent Kubernetes watch事件
+type watchEvent struct {
+	Type   string    `json:"type"`
+	Object endpoints `json:"object"`
+}
+
+// NewKubernetesDiscovery 创建基于Kubernetes的服务发现
+func NewKubernetesDiscovery(config *types.KubernetesDiscoveryConfig) (interfaces.Discovery, error) {
+	cfg := *config
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = serviceAccountDir + "token"
+	}
+	if cfg.CAFile == "" {
+		cfg.CAFile = serviceAccountDir + "ca.crt"
+	}
+	if cfg.Namespace == "" {
+		if data, err := os.ReadFile(serviceAccountDir + "namespace"); err == nil {
+			cfg.Namespace = strings.TrimSpace(string(data))
+		} else {
+			cfg.Namespace = "default"
+		}
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultKubeRetryInterval
+	}
+
+	apiServer := strings.TrimSuffix(cfg.APIServer, "/")
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("kubernetes api server not configured and not running in a cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caPEM, err := os.ReadFile(cfg.CAFile); err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse kubernetes ca file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &kubernetesDiscovery{
+		config:    &cfg,
+		apiServer: apiServer,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Watch 列出服务的Endpoints并持续监听，watch断开后重新list
+func (kd *kubernetesDiscovery) Watch(config *types.UpstreamDiscoveryConfig, callback interfaces.DiscoveryCallback) error {
+	if config.Service == "" {
+		return fmt.Errorf("discovery service name is empty")
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = kd.config.Namespace
+	}
+
+	// 首次list同步完成，保证启动后实例列表立即可用
+	version, err := kd.list(namespace, config, callback)
+	if err != nil {
+		return err
+	}
+
+	kd.wg.Add(1)
+	go func() {
+		defer kd.wg.Done()
+		for {
+			if version != "" {
+				if err := kd.watch(namespace, config, version, callback); err != nil && kd.ctx.Err() == nil {
+					log.Printf("Kubernetes watch for %s/%s ended: %v", namespace, config.Service, err)
+				}
+			}
+
+			select {
+			case <-time.After(kd.config.RetryInterval):
+			case <-kd.ctx.Done():
+				return
+			}
+
+			if version, err = kd.list(namespace, config, callback); err != nil {
+				log.Printf("Failed to list endpoints %s/%s: %v", namespace, config.Service, err)
+				version = ""
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop 停止所有watch
+func (kd *kubernetesDiscovery) Stop() error {
+	kd.cancel()
+	kd.wg.Wait()
+	return nil
+}
+
+// list 获取Endpoints并回调，返回资源版本用于后续watch
+func (kd *kubernetesDiscovery) list(namespace string, config *types.UpstreamDiscoveryConfig, callback interfaces.DiscoveryCallback) (string, error) {
+	path := fmt.Sprintf("/api/v1/namespaces/%s/endpoints/%s", url.PathEscape(namespace), url.PathEscape(config.Service))
+	resp, err := kd.get(kd.ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var obj endpoints
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return "", fmt.Errorf("failed to decode endpoints: %v", err)
+	}
+
+	targets := endpointTargets(&obj, config)
+	log.Printf("Discovered %d instances for service %s/%s from kubernetes", len(targets), namespace, config.Service)
+	callback(targets)
+
+	return obj.Metadata.ResourceVersion, nil
+}
+
+// watch 从指定资源版本开始监听Endpoints变更，直到连接断开
+func (kd *kubernetesDiscovery) watch(namespace string, config *types.UpstreamDiscoveryConfig, version string, callback interfaces.DiscoveryCallback) error {
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("resourceVersion", version)
+	query.Set("fieldSelector", "metadata.name="+config.Service)
+	path := fmt.Sprintf("/api/v1/namespaces/%s/endpoints?%s", url.PathEscape(namespace), query.Encode())
+
+	resp, err := kd.get(kd.ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode watch event: %v", err)
+		}
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			callback(endpointTargets(&event.Object, config))
+		case "DELETED":
+			callback(nil)
+		case "ERROR":
+			// 资源版本过旧等错误，交由外层重新list
+			return fmt.Errorf("watch error event")
+		}
+	}
+}
+
+// get 携带ServiceAccount令牌请求API Server，令牌每次读取以支持轮换
+func (kd *kubernetesDiscovery) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, kd.apiServer+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token, err := os.ReadFile(kd.config.TokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := kd.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("kubernetes api returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+// endpointTargets 将就绪地址转换为上游实例，未指定端口名时使用第一个端口
+func endpointTargets(obj *endpoints, config *types.UpstreamDiscoveryConfig) []types.UpstreamTargetConfig {
+	scheme := withScheme(config.Scheme)
+	targets := make([]types.UpstreamTargetConfig, 0)
+
+	for _, subset := range obj.Subsets {
+		port := 0
+		for _, p := range subset.Ports {
+			if config.Port == "" || p.Name == config.Port || strconv.Itoa(p.Port) == config.Port {
+				port = p.Port
+				break
+			}
+		}
+		if port == 0 {
+			continue
+		}
+
+		for _, address := range subset.Addresses {
+			targets = append(targets, types.UpstreamTargetConfig{
+				URL:    scheme + "://" + net.JoinHostPort(address.IP, strconv.Itoa(port)),
+				Weight: 1,
+			})
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].URL < targets[j].URL })
+	return targets
+}
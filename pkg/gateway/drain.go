@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultDrainTimeout 默认排空超时
+const defaultDrainTimeout = 5 * time.Second
+
+// drainStats 排空统计
+type drainStats struct {
+	InFlight int64 // 开始排空时的在途请求数
+	CutOff   int64 // 超时后被强制中断的请求数
+	Duration string
+}
+
+// drainGuard 排空中间件，需放在最外层：统计在途请求，
+// 排空期间响应携带Connection: close，就绪检查返回503使负载均衡摘除实例
+func (g *Gateway) drainGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if atomic.LoadInt32(&g.draining) == 1 {
+			c.Header("Connection", "close")
+			if c.Request.URL.Path == "/ready" {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"status":    "draining",
+					"in_flight": atomic.LoadInt64(&g.inFlight),
+					"timestamp": time.Now().Unix(),
+				})
+				return
+			}
+		}
+
+		atomic.AddInt64(&g.inFlight, 1)
+		defer atomic.AddInt64(&g.inFlight, -1)
+
+		c.Next()
+	}
+}
+
+// drain 停止接收新请求并等待在途请求完成，超时后强制关闭剩余连接
+func (g *Gateway) drain() {
+	atomic.StoreInt32(&g.draining, 1)
+	g.server.SetKeepAlivesEnabled(false)
+
+	start := time.Now()
+	g.drainStats.InFlight = atomic.LoadInt64(&g.inFlight)
+	log.Printf("Draining gateway: %d requests in flight", g.drainStats.InFlight)
+
+	// 先保持监听一段时间，等待负载均衡感知就绪检查失败
+	if delay := g.config.Shutdown.DrainDelay; delay > 0 {
+		time.Sleep(delay)
+	}
+
+	timeout := g.config.Shutdown.DrainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := g.server.Shutdown(ctx); err != nil {
+		g.drainStats.CutOff = atomic.LoadInt64(&g.inFlight)
+		log.Printf("Drain timeout after %v, cutting off %d in-flight requests: %v", timeout, g.drainStats.CutOff, err)
+		if err := g.server.Close(); err != nil {
+			log.Printf("Failed to close server: %v", err)
+		}
+	}
+
+	g.drainStats.Duration = utils.FormatDuration(time.Since(start))
+	log.Printf("Gateway drained in %s (in_flight=%d, cut_off=%d)", g.drainStats.Duration, g.drainStats.InFlight, g.drainStats.CutOff)
+}
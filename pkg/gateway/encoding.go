@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/compression"
+	"github.com/llm-aware-gateway/pkg/gateway/router"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// requestDecoding 请求体解压中间件，后续中间件需要检查请求体时解压gzip/deflate并去掉Content-Encoding，
+// 否则原样透传；无法解压的编码（如br）在需要检查时以415拒绝，避免绕过检查
+func (g *Gateway) requestDecoding() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contentEncoding := c.GetHeader("Content-Encoding")
+		if !compression.IsEncoded(contentEncoding) || c.Request.Body == nil || c.Request.Body == http.NoBody || !g.inspectsRequest(c) {
+			c.Next()
+			return
+		}
+
+		maxBytes := g.compression.MaxDecompressedBytes()
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBytes+1))
+		c.Request.Body.Close()
+		if err == nil && int64(len(data)) > maxBytes {
+			err = compression.ErrTooLarge
+		}
+
+		var decoded []byte
+		if err == nil {
+			decoded, err = g.compression.Decode(data, contentEncoding)
+		}
+		if err != nil {
+			g.compression.RecordRequest(false)
+			utils.RecordStageError(c, "compression", err)
+			switch {
+			case errors.Is(err, compression.ErrUnsupported):
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{
+					"error": fmt.Sprintf("Content-Encoding %q is not supported", contentEncoding),
+					"code":  "UNSUPPORTED_CONTENT_ENCODING",
+				})
+			case errors.Is(err, compression.ErrTooLarge):
+				abortBodyTooLarge(c, maxBytes)
+			default:
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error": "Failed to decompress request body",
+					"code":  "INVALID_BODY",
+				})
+			}
+			return
+		}
+
+		g.compression.RecordRequest(true)
+		c.Request.Body = io.NopCloser(bytes.NewReader(decoded))
+		c.Request.ContentLength = int64(len(decoded))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(decoded)))
+		c.Next()
+	}
+}
+
+// inspectsRequest 请求体是否会被检查：LLM代理等本地处理器、缓冲模式（错误采样）、Schema校验、WAF和提示词检测
+func (g *Gateway) inspectsRequest(c *gin.Context) bool {
+	route := matchedRoute(c)
+	if route == nil {
+		return true
+	}
+	if route.Body.Mode == types.BodyModeBuffer {
+		return true
+	}
+	if requestSchema, _ := route.RequestSchema(); requestSchema != nil {
+		return true
+	}
+	return (g.waf != nil && !route.Skips(router.MiddlewareWAF)) ||
+		(g.promptGuard != nil && !route.Skips(router.MiddlewarePromptGuard))
+}
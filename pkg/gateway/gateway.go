@@ -1,21 +1,45 @@
 package gateway
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/controlplane/vectordb"
+	"github.com/llm-aware-gateway/pkg/gateway/accesslog"
 	"github.com/llm-aware-gateway/pkg/gateway/breaker"
+	"github.com/llm-aware-gateway/pkg/gateway/compression"
 	"github.com/llm-aware-gateway/pkg/gateway/config"
+	"github.com/llm-aware-gateway/pkg/gateway/decision"
+	"github.com/llm-aware-gateway/pkg/gateway/discovery"
+	"github.com/llm-aware-gateway/pkg/gateway/ipfilter"
+	"github.com/llm-aware-gateway/pkg/gateway/isolation"
 	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/gateway/listener"
+	"github.com/llm-aware-gateway/pkg/gateway/llm"
+	"github.com/llm-aware-gateway/pkg/gateway/metricsexport"
 	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/gateway/promptguard"
+	"github.com/llm-aware-gateway/pkg/gateway/respcache"
+	"github.com/llm-aware-gateway/pkg/gateway/router"
 	"github.com/llm-aware-gateway/pkg/gateway/sampler"
+	"github.com/llm-aware-gateway/pkg/gateway/soak"
+	"github.com/llm-aware-gateway/pkg/gateway/testhooks"
+	"github.com/llm-aware-gateway/pkg/gateway/tlsconf"
+	"github.com/llm-aware-gateway/pkg/gateway/upstream"
+	"github.com/llm-aware-gateway/pkg/gateway/usage"
+	"github.com/llm-aware-gateway/pkg/gateway/waf"
 	"github.com/llm-aware-gateway/pkg/gateway/vector"
+	"github.com/llm-aware-gateway/pkg/grpcadmin"
 	"github.com/llm-aware-gateway/pkg/interfaces"
 	"github.com/llm-aware-gateway/pkg/types"
 	"github.com/llm-aware-gateway/pkg/utils"
@@ -33,15 +57,58 @@ type Gateway struct {
 	configWatcher  interfaces.ConfigWatcher
 	metrics        interfaces.MetricsCollector
 	middleware     *middleware.Middleware
+	routes         *router.Router
+	upstreams      *upstream.Manager
+	recorder       *soak.Recorder
+	decisions      *decision.Store
+	handoff        *limiter.StateHandoff
+	keyLimiter     *limiter.KeyLimiter
+	keyPersister   *limiter.KeyStatePersister
+	tenantQuotas   *limiter.QuotaTree          // 未启用租户分级配额时为nil
+	concurrency    *limiter.ClusterConcurrency // 未启用按簇并发限制时为nil
+	grpcAdmin      *grpcadmin.Server           // 未启用gRPC管理端口时为nil
+	responseCache  *respcache.ResponseCache
+	accessLog      *accesslog.AccessLogger
+	ipFilter       *ipfilter.IPFilter
+	waf            *waf.WAF
+	promptGuard    *promptguard.Guard
+	hooks          *testhooks.Hooks
+	llmProxy       *llm.Proxy
+	gossip         *vector.SignatureGossip
+	metricsExport  *metricsexport.Exporter
+	usage          *usage.Ledger // 未启用用量核算时为nil
+	compression    *compression.Handler
+	listener       net.Listener
+	discoveries    []interfaces.Discovery
 	stopCh         chan struct{}
 	wg             sync.WaitGroup
+	startTime      time.Time
+	policyUpdates  int64
+	policyDeletes  int64
+	draining       int32
+	inFlight       int64
+	drainStats     drainStats
+	inflight       *inflightTracker
+	configHistory  *configHistory
+	overrides      *runtimeOverrides
 }
 
 // NewGateway 创建网关实例
-func NewGateway(config *types.GatewayConfig) (*Gateway, error) {
+func NewGateway(cfg *types.GatewayConfig) (*Gateway, error) {
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.New()
+	engine := gin.New()
+	if err := configureClientIP(engine, &cfg.Server.ClientIP); err != nil {
+		return nil, fmt.Errorf("invalid client ip config: %v", err)
+	}
+
+	// 配置请求ID和错误事件ID的生成方式
+	if err := utils.ConfigureIDs(&cfg.IDs); err != nil {
+		return nil, fmt.Errorf("invalid id config: %v", err)
+	}
+
+	// 创建指标收集器
+	metricsCollector := NewMetricsCollector()
 
 	// 创建缓存
 	cache := utils.NewCache(10000)
@@ -49,46 +116,210 @@ func NewGateway(config *types.GatewayConfig) (*Gateway, error) {
 	// 创建向量代理 (暂时不连接嵌入服务)
 	vectorAgent := vector.NewVectorAgent(nil, cache)
 
+	// 请求路径上的簇识别和错误采样加上故障隔离，超时或panic时跳过，不影响转发
+	isolatedAgent := isolation.VectorAgent(vectorAgent, &cfg.Isolation, metricsCollector)
+
 	// 创建限流器
-	rateLimiter := limiter.NewClusterRateLimiter(&config.Limiter, vectorAgent)
+	rateLimiter := limiter.NewClusterRateLimiter(&cfg.Limiter, isolatedAgent)
 
 	// 创建熔断器
-	circuitBreaker := breaker.NewClusterCircuitBreaker(&config.Breaker)
+	circuitBreaker := breaker.NewClusterCircuitBreaker(&cfg.Breaker)
 
 	// 创建错误采样器
-	errorSampler := sampler.NewErrorSampler(&config.Sampler, &config.Kafka)
+	errorSampler := isolation.ErrorSampler(sampler.NewErrorSampler(&cfg.Sampler, &cfg.Kafka), &cfg.Isolation, metricsCollector)
 
 	// 创建配置监听器
-	configWatcher, err := config.NewConfigWatcher(&config.ETCD)
+	configWatcher, err := config.NewConfigWatcher(&cfg.ETCD, cfg.Region)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config watcher: %v", err)
 	}
 
-	// 创建指标收集器
-	metricsCollector := NewMetricsCollector()
+	// 创建上游管理器（实例级熔断器独立于簇熔断器）
+	upstreamBreakerConfig := &cfg.Breaker
+	if cfg.UpstreamBreaker != nil {
+		upstreamBreakerConfig = cfg.UpstreamBreaker
+	}
+	upstreamBreaker := breaker.NewClusterCircuitBreaker(upstreamBreakerConfig)
+	upstreams, err := upstream.NewManager(cfg.Upstreams, upstreamBreaker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upstream manager: %v", err)
+	}
 
 	// 创建中间件管理器
 	middlewareManager := middleware.NewMiddleware(
 		rateLimiter,
 		circuitBreaker,
 		errorSampler,
-		vectorAgent,
+		isolatedAgent,
 		metricsCollector,
 	)
 
 	gateway := &Gateway{
-		config:         config,
-		router:         router,
+		config:         cfg,
+		router:         engine,
 		rateLimiter:    rateLimiter,
 		circuitBreaker: circuitBreaker,
 		errorSampler:   errorSampler,
-		vectorAgent:    vectorAgent,
+		vectorAgent:    isolatedAgent,
 		configWatcher:  configWatcher,
 		metrics:        metricsCollector,
 		middleware:     middlewareManager,
+		routes:         router.NewRouter(cfg.Routes),
+		upstreams:      upstreams,
 		stopCh:         make(chan struct{}),
+		inflight:       newInflightTracker(),
+	}
+	middlewareManager.SetComponents(gateway.components)
+
+	// 创建流量形态录制器
+	if cfg.Soak.CaptureEnabled {
+		recorder, err := soak.NewRecorder(&cfg.Soak)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create soak recorder: %v", err)
+		}
+		gateway.recorder = recorder
+	}
+
+	// 创建限流状态交接
+	if cfg.Limiter.Handoff.Enabled {
+		gateway.handoff = limiter.NewStateHandoff(&cfg.Redis, &cfg.Limiter.Handoff)
+	}
+
+	// 创建API密钥限流，开启持久化时令牌桶和配额消耗跨重启保留
+	if cfg.Limiter.APIKeys.Enabled {
+		gateway.keyLimiter = limiter.NewKeyLimiter(&cfg.Limiter.APIKeys)
+		if cfg.Limiter.APIKeys.Persistence.Enabled {
+			gateway.keyPersister = limiter.NewKeyStatePersister(&cfg.Redis, &cfg.Limiter.APIKeys, gateway.keyLimiter)
+		}
+	}
+
+	// 创建租户分级配额
+	if cfg.Limiter.Tenants.Enabled {
+		tenantQuotas, err := limiter.NewQuotaTree(&cfg.Limiter.Tenants)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant quota config: %v", err)
+		}
+		gateway.tenantQuotas = tenantQuotas
+	}
+
+	// 创建按簇并发限制
+	if cfg.Limiter.Concurrency.Enabled {
+		concurrency, err := limiter.NewClusterConcurrency(&cfg.Limiter.Concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid concurrency config: %v", err)
+		}
+		gateway.concurrency = concurrency
+	}
+
+	// 创建gRPC管理端口，健康状态与/admin/components的组件汇总一致
+	if cfg.AdminGRPC.Enabled {
+		gateway.grpcAdmin = grpcadmin.NewServer("gateway", &cfg.AdminGRPC, gateway.components)
+	}
+
+	// 创建响应缓存
+	if cfg.Cache.Enabled {
+		gateway.responseCache = respcache.NewResponseCache(&cfg.Cache, &cfg.Redis)
+	}
+
+	// 创建决策轨迹存储
+	if cfg.Decision.Enabled {
+		gateway.decisions = decision.NewStore(&cfg.Decision)
+	}
+
+	// 创建IP访问控制
+	if cfg.IPFilter.Enabled {
+		ipFilter, err := ipfilter.NewIPFilter(&cfg.IPFilter, metricsCollector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ip filter: %v", err)
+		}
+		gateway.ipFilter = ipFilter
+	}
+
+	// 创建WAF
+	if cfg.WAF.Enabled {
+		wafEngine, err := waf.NewWAF(&cfg.WAF, metricsCollector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create waf: %v", err)
+		}
+		gateway.waf = wafEngine
+	}
+
+	// 创建提示词注入检测
+	if cfg.PromptGuard.Enabled {
+		guard, err := newPromptGuard(&cfg.PromptGuard, metricsCollector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prompt guard: %v", err)
+		}
+		gateway.promptGuard = guard
+	}
+
+	// 创建副本间簇识别结果共享
+	if cfg.Gossip.Enabled {
+		gateway.gossip = vector.NewSignatureGossip(&cfg.Redis, &cfg.Gossip)
+		if err := vector.AttachGossip(vectorAgent, gateway.gossip); err != nil {
+			return nil, fmt.Errorf("failed to attach cluster signature gossip: %v", err)
+		}
 	}
 
+	// 创建计数快照推送
+	if cfg.MetricsExport.Enabled {
+		exporter, err := metricsexport.NewExporter(&cfg.MetricsExport, &cfg.Kafka)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metrics export: %v", err)
+		}
+		if source, ok := circuitBreaker.(interfaces.BreakerStatsSource); ok {
+			exporter.SetBreakerSource(source)
+		}
+		gateway.metricsExport = exporter
+	}
+
+	// 创建用量核算
+	if cfg.Usage.Enabled {
+		gateway.usage = usage.NewLedger(&cfg.Usage, &cfg.Redis)
+	}
+
+	// 创建请求/响应体压缩处理
+	if cfg.Compression.Enabled {
+		gateway.compression = compression.NewHandler(&cfg.Compression)
+		upstreams.SetCompression(gateway.compression)
+	}
+
+	// 创建OpenAI兼容LLM代理
+	if cfg.LLM.Enabled {
+		llmProxy, err := llm.NewProxy(&cfg.LLM, &cfg.Redis, metricsCollector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create llm proxy: %v", err)
+		}
+		if cfg.LLM.SemanticCache.Enabled {
+			semanticCache, err := newSemanticCache(&cfg.LLM.SemanticCache)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create llm semantic cache: %v", err)
+			}
+			llmProxy.AttachSemanticCache(semanticCache)
+		}
+		gateway.llmProxy = llmProxy
+	}
+
+	// 创建测试钩子，生产构建中始终为nil
+	if cfg.TestHooks.Enabled {
+		hooks, err := testhooks.New(&cfg.TestHooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create test hooks: %v", err)
+		}
+		gateway.hooks = hooks
+	}
+
+	// 创建访问日志
+	accessLog, err := accesslog.NewAccessLogger(&cfg.AccessLog, &cfg.Kafka)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access logger: %v", err)
+	}
+	gateway.accessLog = accessLog
+
+	// 记录启动时的生效配置，之后的运行时调整按差异记录
+	gateway.overrides = newRuntimeOverrides()
+	gateway.configHistory = newConfigHistory(gateway.effectiveConfig())
+
 	// 设置中间件
 	gateway.setupMiddleware()
 
@@ -100,18 +331,102 @@ func NewGateway(config *types.GatewayConfig) (*Gateway, error) {
 
 // setupMiddleware 设置中间件
 func (g *Gateway) setupMiddleware() {
+	g.router.Use(g.drainGuard(), requestClock())
+
+	// 录制放在最外层，限流/熔断拒绝的请求同样计入流量形态
+	if g.recorder != nil {
+		g.router.Use(g.recorder.Middleware())
+	}
+
 	g.router.Use(
 		g.middleware.Recovery(),
-		g.middleware.Logger(),
+		g.middleware.RequestID(),
 		g.middleware.Tracing(),
+		g.accessLog.Middleware(),
 		g.middleware.CORS(),
 		g.middleware.HealthCheck(),
-		g.middleware.Authentication(),
-		g.middleware.RateLimit(),
-		g.middleware.CircuitBreaker(),
-		g.middleware.ErrorSampling(),
-		g.middleware.Metrics(),
 	)
+
+	// 计数快照在所有会拒绝请求的中间件之前，拒绝的请求同样计入
+	if g.metricsExport != nil {
+		g.router.Use(g.metricsExport.Middleware())
+	}
+	if g.usage != nil {
+		g.router.Use(g.usage.Middleware())
+	}
+	// 策略版本在计数快照和访问日志之内记录，两者在请求结束后读取
+	g.router.Use(g.policyVersion())
+
+	// IP访问控制在健康检查之后，负载均衡探活不受名单影响
+	if g.ipFilter != nil {
+		g.router.Use(g.ipFilter.Middleware())
+	}
+
+	g.router.Use(
+		// 路由需在认证之前匹配，路由可配置跳过认证
+		g.routeMatch(),
+		g.inflightGuard(),
+		routeScoped(router.MiddlewareAuth, g.middleware.Authentication()),
+	)
+
+	// 解压在WAF之前，检查请求体的中间件看到的是解压后的内容
+	if g.compression != nil {
+		g.router.Use(g.requestDecoding())
+	}
+
+	// WAF在认证之后，标记结果可随请求头转发给上游
+	if g.waf != nil {
+		g.router.Use(routeScoped(router.MiddlewareWAF, g.waf.Middleware()))
+	}
+
+	// 提示词注入检测在WAF之后，降级改写的模型对后续的限流和LLM代理生效
+	if g.promptGuard != nil {
+		g.router.Use(routeScoped(router.MiddlewarePromptGuard, g.promptGuard.Middleware()))
+	}
+
+	// 决策轨迹需在限流熔断之前创建
+	if g.decisions != nil {
+		g.router.Use(g.decisions.Middleware())
+	}
+
+	// 缓存命中在限流熔断之前返回，熔断期间可由过期缓存兜底
+	if g.responseCache != nil {
+		g.router.Use(routeScoped(router.MiddlewareCache, g.responseCache.Middleware(routeCacheRule)))
+	}
+
+	g.router.Use(routeRateLimit())
+	if g.keyLimiter != nil {
+		g.router.Use(routeScoped(router.MiddlewareRateLimit, g.apiKeyLimit()))
+	}
+	if g.tenantQuotas != nil {
+		g.router.Use(routeScoped(router.MiddlewareRateLimit, g.tenantQuota()))
+	}
+
+	g.router.Use(
+		routeScoped(router.MiddlewareRateLimit, g.middleware.RateLimit()),
+		routeScoped(router.MiddlewareCircuitBreaker, g.middleware.CircuitBreaker()),
+	)
+
+	// 并发限制在熔断之后，熔断拒绝的请求不占用空位，排队拒绝的请求不计入熔断失败
+	g.router.Use(routeConcurrency())
+	if g.concurrency != nil {
+		g.router.Use(routeScoped(router.MiddlewareConcurrency, g.clusterConcurrency()))
+	}
+
+	g.router.Use(
+		routeScoped(router.MiddlewareErrorSampling, g.middleware.ErrorSampling()),
+		routeScoped(router.MiddlewareMetrics, g.middleware.Metrics()),
+		g.bodyLimit(),
+		g.schemaValidation(),
+	)
+
+	// 测试钩子需同时满足编译标签和配置开关
+	if g.hooks != nil {
+		g.router.Use(g.hooks.Middleware())
+		log.Println("Warning: test hooks are enabled, do not use this build in production")
+	} else if g.config.TestHooks.Enabled {
+		log.Println("Test hooks enabled in config but not compiled in (build with -tags testhooks)")
+	}
 }
 
 // setupRoutes 设置路由
@@ -127,12 +442,44 @@ func (g *Gateway) setupRoutes() {
 
 	// 管理API路由
 	admin := g.router.Group("/admin")
+	adminAuth := g.middleware.AdminAuth(g.config.Admin.APIKeys)
 	{
 		admin.GET("/stats", g.getStatsHandler)
+		admin.GET("/components", g.getComponentsHandler)
+		admin.GET("/config", g.getConfigHandler)
 		admin.GET("/clusters", g.getClustersHandler)
 		admin.GET("/policies", g.getPoliciesHandler)
+		admin.GET("/upstreams", g.getUpstreamsHandler)
+		admin.GET("/routes", g.getRoutesHandler)
+		admin.PUT("/routes/:name/splits", adminAuth, g.updateSplitsHandler)
+		admin.POST("/routes/:name/drain", adminAuth, g.drainRouteHandler)
+		admin.DELETE("/routes/:name/drain", adminAuth, g.resumeRouteHandler)
+		admin.GET("/inflight", g.getInflightHandler)
+		admin.GET("/explain/:request_id", g.explainHandler)
+		admin.GET("/ipfilter", g.getIPFilterHandler)
+		admin.GET("/waf", g.getWAFHandler)
+		admin.GET("/prompt-guard", g.getPromptGuardHandler)
+		admin.GET("/usage", g.getUsageHandler)
+	}
+
+	// 故障注入管理接口随测试钩子注册
+	if g.hooks != nil {
+		g.hooks.RegisterAdmin(admin)
+	}
+
+	// LLM费用查询接口
+	if g.llmProxy != nil {
+		g.llmProxy.RegisterAdmin(admin)
 	}
 
+	// OpenAI兼容端点，经过与代理路由相同的限流熔断和错误采样
+	if g.llmProxy != nil {
+		g.llmProxy.Register(g.router)
+	}
+
+	// 非/api前缀的请求（如gRPC服务路径）按路由表转发
+	g.router.NoRoute(g.routeHandler)
+
 	// 指标路由
 	if g.config.Metrics.Enabled {
 		g.router.GET("/metrics", g.metricsHandler)
@@ -141,11 +488,67 @@ func (g *Gateway) setupRoutes() {
 
 // Start 启动网关服务
 func (g *Gateway) Start() error {
+	g.startTime = time.Now()
+
 	// 启动错误采样器
 	if err := g.errorSampler.Start(); err != nil {
 		return fmt.Errorf("failed to start error sampler: %v", err)
 	}
 
+	// 监听路由流量拆分的运行时调整
+	if err := g.configWatcher.WatchPrefix("/routes/", g.onRouteUpdate); err != nil {
+		log.Printf("Failed to watch route splits: %v", err)
+	}
+
+	// 监听控制面发布的簇快照
+	if err := g.configWatcher.WatchPrefix("/clusters/", g.onClusterSnapshot); err != nil {
+		log.Printf("Failed to watch cluster snapshots: %v", err)
+	}
+
+	// 监听API密钥限流覆盖
+	if err := g.configWatcher.WatchPrefix(limiter.KeyLimitPrefix, g.onKeyLimitUpdate); err != nil {
+		log.Printf("Failed to watch api key rate limits: %v", err)
+	}
+
+	// 监听IP名单的运行时调整
+	if g.ipFilter != nil {
+		if err := g.configWatcher.WatchPrefix(ipfilter.KeyPrefix, g.onIPFilterUpdate); err != nil {
+			log.Printf("Failed to watch ip filter lists: %v", err)
+		}
+	}
+
+	// 订阅其他副本的簇识别结果，Redis不可用时各副本独立识别
+	if g.gossip != nil {
+		if err := g.gossip.Start(); err != nil {
+			log.Printf("Failed to start cluster signature gossip: %v", err)
+		}
+	}
+
+	// 控制面不可用时计数在本地累积，恢复后随下一个快照推送
+	if g.metricsExport != nil {
+		if err := g.metricsExport.Start(); err != nil {
+			log.Printf("Failed to start metrics export: %v", err)
+		}
+	}
+
+	// 加载LLM费用合计并启动定期持久化
+	if g.llmProxy != nil {
+		g.llmProxy.Start()
+	}
+
+	// 用量增量定期累加到Redis
+	if g.usage != nil {
+		g.usage.Start()
+	}
+
+	// 恢复其他副本交接的令牌桶状态，需在策略加载前完成
+	g.restoreLimiterState()
+
+	// 恢复API密钥令牌桶和配额消耗，并启动定期持久化
+	if g.keyPersister != nil {
+		g.keyPersister.Start()
+	}
+
 	// 启动配置监听器
 	if err := g.configWatcher.Start(); err != nil {
 		return fmt.Errorf("failed to start config watcher: %v", err)
@@ -154,22 +557,81 @@ func (g *Gateway) Start() error {
 	// 注册策略更新回调
 	g.configWatcher.RegisterCallback(g)
 
-	// 创建HTTP服务器
+	// 监听动态上游的实例变更
+	if err := g.startDiscovery(); err != nil {
+		return fmt.Errorf("failed to start upstream discovery: %v", err)
+	}
+
+	// 创建HTTP服务器（开启h2c时支持明文HTTP/2和gRPC）
+	network, addr, err := listener.Address(g.config.Server.Network, g.config.Server.Host, g.config.Server.Port)
+	if err != nil {
+		return fmt.Errorf("invalid server address: %v", err)
+	}
+	g.router.UseH2C = g.config.Server.EnableH2C
 	g.server = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", g.config.Server.Host, g.config.Server.Port),
-		Handler: g.router,
+		Addr:    addr,
+		Handler: g.router.Handler(),
+	}
+
+	// 配置TLS终结
+	tlsEnabled := g.config.Server.TLS.Enabled
+	if tlsEnabled {
+		serverTLS, err := tlsconf.NewServerTLS(&g.config.Server.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to load tls certificates: %v", err)
+		}
+
+		tlsConfig, err := serverTLS.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("invalid tls config: %v", err)
+		}
+
+		g.server.TLSConfig = tlsConfig
+		serverTLS.Watch(g.stopCh)
+	}
+
+	// 启动上游mTLS证书热加载
+	g.upstreams.Watch(g.stopCh)
+
+	// 创建监听器，升级启动时继承父进程的监听FD
+	ln, err := listener.Listen(network, g.server.Addr, g.config.Server.ReusePort)
+	if err != nil {
+		return err
+	}
+	g.listener = ln
+	if listener.Inherited() {
+		log.Printf("Inherited listener on %s from parent process", ln.Addr())
+	}
+
+	// 启动长耗时请求检测
+	if g.config.Watchdog.Enabled {
+		g.wg.Add(1)
+		go g.watchdogLoop()
 	}
 
 	// 启动HTTP服务器
 	g.wg.Add(1)
 	go func() {
 		defer g.wg.Done()
-		log.Printf("Starting gateway server on %s", g.server.Addr)
-		if err := g.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsEnabled {
+			log.Printf("Starting gateway server on %s (TLS)", g.server.Addr)
+			err = g.server.ServeTLS(ln, "", "")
+		} else {
+			log.Printf("Starting gateway server on %s", g.server.Addr)
+			err = g.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Printf("Failed to start server: %v", err)
 		}
 	}()
 
+	if g.grpcAdmin != nil {
+		if err := g.grpcAdmin.Start(); err != nil {
+			return fmt.Errorf("failed to start grpc admin server: %v", err)
+		}
+	}
+
 	log.Println("Gateway started successfully")
 	return nil
 }
@@ -181,29 +643,69 @@ func (g *Gateway) Stop() error {
 	// 关闭停止信号
 	close(g.stopCh)
 
-	// 停止HTTP服务器
-	if g.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	// gRPC探针在排空开始前即返回NOT_SERVING
+	if g.grpcAdmin != nil {
+		g.grpcAdmin.Stop()
+	}
 
-		if err := g.server.Shutdown(ctx); err != nil {
-			log.Printf("Failed to shutdown server gracefully: %v", err)
-		}
+	// 排空在途请求后停止HTTP服务器
+	if g.server != nil {
+		g.drain()
 	}
 
 	// 停止各个组件
+	if g.accessLog != nil {
+		g.accessLog.Close()
+	}
+
 	if g.errorSampler != nil {
 		g.errorSampler.Stop()
 	}
 
+	for _, d := range g.discoveries {
+		d.Stop()
+	}
+
 	if g.configWatcher != nil {
 		g.configWatcher.Stop()
 	}
 
+	// 在限流器清理前汇总最终统计
+	g.writeShutdownReport()
+
 	if g.rateLimiter != nil {
+		g.publishLimiterState()
 		g.rateLimiter.Cleanup()
 	}
 
+	if g.keyPersister != nil {
+		g.keyPersister.Stop()
+	}
+
+	if g.gossip != nil {
+		g.gossip.Stop()
+	}
+
+	if g.llmProxy != nil {
+		g.llmProxy.Stop()
+	}
+
+	if g.metricsExport != nil {
+		g.metricsExport.Stop()
+	}
+
+	if g.usage != nil {
+		g.usage.Stop()
+	}
+
+	if g.recorder != nil {
+		g.recorder.Stop()
+	}
+
+	if g.responseCache != nil {
+		g.responseCache.Close()
+	}
+
 	// 等待所有goroutine结束
 	g.wg.Wait()
 
@@ -211,9 +713,112 @@ func (g *Gateway) Stop() error {
 	return nil
 }
 
+// Upgrade 启动新进程并交接监听器，新进程就绪后由调用方停止当前进程完成排空
+func (g *Gateway) Upgrade() error {
+	if g.listener == nil {
+		return fmt.Errorf("gateway is not listening")
+	}
+
+	process, err := listener.Upgrade(g.listener)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Started new gateway process %d with inherited listener", process.Pid)
+	return nil
+}
+
+// startDiscovery 创建上游配置中用到的服务发现提供者并开始监听
+func (g *Gateway) startDiscovery() error {
+	providers := make(map[string]interfaces.Discovery)
+	for _, upstreamConfig := range g.config.Upstreams {
+		if upstreamConfig.Discovery == nil {
+			continue
+		}
+
+		name := upstreamConfig.Discovery.Provider
+		if _, exists := providers[name]; exists {
+			continue
+		}
+
+		switch name {
+		case discovery.ProviderETCD:
+			providers[name] = discovery.NewETCDDiscovery(g.configWatcher, g.config.Discovery.ETCDPrefix)
+		case discovery.ProviderKubernetes:
+			d, err := discovery.NewKubernetesDiscovery(&g.config.Discovery.Kubernetes)
+			if err != nil {
+				return err
+			}
+			providers[name] = d
+		default:
+			return fmt.Errorf("unknown discovery provider: %s", name)
+		}
+		g.discoveries = append(g.discoveries, providers[name])
+	}
+
+	if len(providers) == 0 {
+		return nil
+	}
+
+	return g.upstreams.Discover(providers)
+}
+
+// restoreLimiterState 从Redis恢复令牌桶状态
+func (g *Gateway) restoreLimiterState() {
+	if g.handoff == nil {
+		return
+	}
+
+	stateful, ok := g.rateLimiter.(interfaces.StatefulRateLimiter)
+	if !ok {
+		return
+	}
+
+	snapshots, err := g.handoff.Load()
+	if err != nil {
+		log.Printf("Failed to load limiter handoff state: %v", err)
+		return
+	}
+
+	applied := stateful.Restore(snapshots)
+	log.Printf("Loaded %d bucket states from handoff (%d applied immediately)", len(snapshots), applied)
+}
+
+// publishLimiterState 停止前发布令牌桶状态，供替换副本使用
+func (g *Gateway) publishLimiterState() {
+	if g.handoff == nil {
+		return
+	}
+	defer g.handoff.Close()
+
+	stateful, ok := g.rateLimiter.(interfaces.StatefulRateLimiter)
+	if !ok {
+		return
+	}
+
+	if err := g.handoff.Publish(stateful.Snapshot()); err != nil {
+		log.Printf("Failed to publish limiter handoff state: %v", err)
+	}
+}
+
 // OnPolicyUpdate 策略更新回调
 func (g *Gateway) OnPolicyUpdate(clusterID string, policy *types.Policy) error {
 	log.Printf("Received policy update for cluster: %s", clusterID)
+	atomic.AddInt64(&g.policyUpdates, 1)
+
+	// WAF规则集不参与限流熔断
+	if policy.PolicyType == types.PolicyTypeWAF {
+		if g.waf == nil {
+			log.Printf("Ignoring WAF policy %s: waf is disabled", clusterID)
+			return nil
+		}
+		if err := g.waf.SetRuleSet(clusterID, policy); err != nil {
+			return err
+		}
+		g.overrides.setPolicy(clusterID, policy)
+		g.recordPolicyChange(clusterID, policy)
+		return nil
+	}
 
 	// 更新限流器策略
 	if err := g.rateLimiter.UpdatePolicy(clusterID, policy); err != nil {
@@ -225,34 +830,45 @@ func (g *Gateway) OnPolicyUpdate(clusterID string, policy *types.Policy) error {
 		log.Printf("Failed to update circuit breaker policy: %v", err)
 	}
 
+	if g.concurrency != nil {
+		if err := g.concurrency.UpdatePolicy(clusterID, policy); err != nil {
+			log.Printf("Failed to update concurrency policy: %v", err)
+		}
+	}
+
+	g.overrides.setPolicy(clusterID, policy)
+	g.recordPolicyChange(clusterID, policy)
 	return nil
 }
 
 // OnPolicyDelete 策略删除回调
 func (g *Gateway) OnPolicyDelete(clusterID string) error {
 	log.Printf("Received policy delete for cluster: %s", clusterID)
+	atomic.AddInt64(&g.policyDeletes, 1)
+	if g.waf != nil {
+		g.waf.RemoveRuleSet(clusterID)
+	}
+	if g.concurrency != nil {
+		g.concurrency.RemovePolicy(clusterID)
+	}
 	// 这里可以实现策略删除逻辑
+	if previous := g.overrides.setPolicy(clusterID, nil); previous != nil {
+		g.recordPolicyChange(clusterID, previous)
+	}
 	return nil
 }
 
 // proxyHandler 代理处理器
 func (g *Gateway) proxyHandler(c *gin.Context) {
-	// 这里应该实现到下游服务的代理逻辑
-	// 为了演示，我们返回一个简单的响应
-
-	// 模拟服务响应
-	service := utils.ExtractServiceName(c)
-
-	// 模拟一些错误情况用于测试
-	if c.Query("simulate_error") == "true" {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Simulated error for testing",
-			"service": service,
-			"path": c.Request.URL.Path,
-		})
+	// 命中路由规则时转发到上游
+	if route := matchedRoute(c); route != nil {
+		g.forwardRoute(c, route)
 		return
 	}
 
+	// 未配置路由时返回模拟响应
+	service := utils.ExtractServiceName(c)
+
 	// 正常响应
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Request processed successfully",
@@ -263,6 +879,238 @@ func (g *Gateway) proxyHandler(c *gin.Context) {
 	})
 }
 
+// routeMatch 路由匹配中间件，提前确定路由以便限流熔断使用路由的簇命名空间
+func (g *Gateway) routeMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route := g.routes.Match(c.Request); route != nil {
+			c.Set("route", route)
+			if route.Namespace != "" {
+				c.Set("cluster_namespace", route.Namespace)
+			}
+		}
+		c.Next()
+	}
+}
+
+// matchedRoute 获取路由匹配中间件写入的路由
+func matchedRoute(c *gin.Context) *router.Route {
+	if value, exists := c.Get("route"); exists {
+		if route, ok := value.(*router.Route); ok {
+			return route
+		}
+	}
+	return nil
+}
+
+// routeCacheRule 获取请求所属路由的缓存规则
+func routeCacheRule(c *gin.Context) *types.RouteCacheConfig {
+	if route := matchedRoute(c); route != nil {
+		return route.Cache
+	}
+	return nil
+}
+
+// forwardRoute 按路由转发，配置了流量拆分时按权重选择上游版本
+func (g *Gateway) forwardRoute(c *gin.Context, route *router.Route) {
+	upstreamName, version := route.SelectUpstream()
+	g.inflight.setUpstream(c, upstreamName)
+
+	c.Set("route_name", route.Name)
+	if version != "" {
+		c.Set("upstream_version", version)
+	}
+
+	g.upstreams.Mirror(c, route.Mirror, route)
+
+	cancel, ok := applyDeadline(c, route)
+	if !ok {
+		abortDeadlineExceeded(c)
+		return
+	}
+	defer cancel()
+
+	g.upstreams.Forward(c, upstreamName, route)
+
+	if c.GetString("signal_type") == types.SignalSchemaResponse && g.metrics != nil {
+		g.metrics.RecordSchemaViolation(route.Name, "response")
+	}
+}
+
+// onRouteUpdate 处理etcd中的路由拆分变更，键格式为"/routes/<name>/splits"
+func (g *Gateway) onRouteUpdate(key string, value []byte, deleted bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(key, "/routes/"), "/splits")
+	if name == "" || !strings.HasSuffix(key, "/splits") {
+		return
+	}
+
+	var splits []types.RouteSplitConfig
+	if !deleted {
+		if err := json.Unmarshal(value, &splits); err != nil {
+			log.Printf("Invalid splits for route %s: %v", name, err)
+			return
+		}
+	}
+
+	if err := g.routes.SetSplits(name, splits); err != nil {
+		log.Printf("Failed to update splits for route %s: %v", name, err)
+		return
+	}
+
+	log.Printf("Updated traffic splits for route %s: %d versions", name, len(splits))
+	g.recordConfigChange("etcd:" + key)
+}
+
+// newSemanticCache 创建LLM语义缓存，提示词向量存放在向量库中
+func newSemanticCache(config *types.SemanticCacheConfig) (*llm.SemanticCache, error) {
+	vectors, err := vectordb.NewVectorDB(&config.VectorDB)
+	if err != nil {
+		return nil, err
+	}
+	return llm.NewSemanticCache(config, newEmbeddingService(config.Embedding), vectors), nil
+}
+
+// newPromptGuard 创建提示词注入检测，开启相似度检测时攻击样本向量存放在向量库中
+func newPromptGuard(config *types.PromptGuardConfig, metrics interfaces.MetricsCollector) (*promptguard.Guard, error) {
+	if !config.Similarity.Enabled {
+		return promptguard.NewGuard(config, nil, nil, metrics)
+	}
+
+	vectors, err := vectordb.NewVectorDB(&config.Similarity.VectorDB)
+	if err != nil {
+		return nil, err
+	}
+	return promptguard.NewGuard(config, newEmbeddingService(config.Similarity.Embedding), vectors, metrics)
+}
+
+// newEmbeddingService 创建网关内使用的向量化服务，参数未配置时使用默认值
+func newEmbeddingService(config types.EmbeddingConfig) interfaces.EmbeddingService {
+	if config.Dimension <= 0 {
+		config.Dimension = 384
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 32
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = 10000
+	}
+	return embedding.NewEmbeddingService(&config)
+}
+
+// onClusterSnapshot 加载控制面发布的簇快照，嵌入模型不一致时拒绝并告警
+func (g *Gateway) onClusterSnapshot(key string, value []byte, deleted bool) {
+	if key != types.ClusterSnapshotKey || deleted {
+		return
+	}
+
+	var snapshot types.ClusterSnapshot
+	if err := json.Unmarshal(value, &snapshot); err != nil {
+		log.Printf("Invalid cluster snapshot: %v", err)
+		return
+	}
+
+	err := g.vectorAgent.ApplySnapshot(&snapshot)
+	if g.metrics != nil {
+		g.metrics.RecordClusterSnapshot(err == nil)
+	}
+	if err != nil {
+		if _, mismatch := err.(*vector.EmbeddingMismatchError); mismatch {
+			log.Printf("ALERT: refusing cluster snapshot, keeping existing clusters: %v", err)
+			return
+		}
+		log.Printf("Failed to apply cluster snapshot: %v", err)
+	}
+}
+
+// routeHandler 路由表转发处理器
+func (g *Gateway) routeHandler(c *gin.Context) {
+	if route := matchedRoute(c); route != nil {
+		g.forwardRoute(c, route)
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "Route not found",
+		"code":  "ROUTE_NOT_FOUND",
+	})
+}
+
+// getRoutesHandler 获取路由及流量拆分
+func (g *Gateway) getRoutesHandler(c *gin.Context) {
+	routes := make([]gin.H, 0)
+	for _, route := range g.routes.Routes() {
+		routes = append(routes, gin.H{
+			"name":        route.Name,
+			"host":        route.Host,
+			"path_prefix": route.PathPrefix,
+			"upstream":    route.Upstream,
+			"namespace":   route.Namespace,
+			"splits":      route.Splits(),
+			"skip":        route.SkippedMiddleware(),
+			"concurrency": route.ConcurrencyStats(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"routes": routes})
+}
+
+// updateSplitsHandler 运行时调整路由流量拆分，仅作用于当前实例，集群范围调整写入etcd
+func (g *Gateway) updateSplitsHandler(c *gin.Context) {
+	var splits []types.RouteSplitConfig
+	if err := c.ShouldBindJSON(&splits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid splits: %v", err)})
+		return
+	}
+
+	name := c.Param("name")
+	if err := g.routes.SetSplits(name, splits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Updated traffic splits for route %s via admin API", name)
+	g.recordConfigChange("admin")
+	c.JSON(http.StatusOK, gin.H{"route": name, "splits": splits})
+}
+
+// getIPFilterHandler 获取生效的IP名单和拦截统计
+func (g *Gateway) getIPFilterHandler(c *gin.Context) {
+	if g.ipFilter == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": true,
+		"filter":  g.ipFilter.Stats(),
+	})
+}
+
+// getWAFHandler 获取WAF规则集和命中统计
+func (g *Gateway) getWAFHandler(c *gin.Context) {
+	if g.waf == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": true,
+		"waf":     g.waf.Stats(),
+	})
+}
+
+// getPromptGuardHandler 获取提示词注入检测统计
+func (g *Gateway) getPromptGuardHandler(c *gin.Context) {
+	if g.promptGuard == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":      true,
+		"prompt_guard": g.promptGuard.Stats(),
+	})
+}
+
 // getStatsHandler 获取统计信息
 func (g *Gateway) getStatsHandler(c *gin.Context) {
 	clusterID := c.Query("cluster_id")
@@ -325,6 +1173,34 @@ func (g *Gateway) getPoliciesHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, policy)
 }
 
+// getUpstreamsHandler 获取上游实例状态
+func (g *Gateway) getUpstreamsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"upstreams": g.upstreams.Status(),
+	})
+}
+
+// explainHandler 查询请求的决策轨迹（为什么被限流/熔断）
+func (g *Gateway) explainHandler(c *gin.Context) {
+	if g.decisions == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Decision trail recording is disabled",
+		})
+		return
+	}
+
+	requestID := c.Param("request_id")
+	trail, found := g.decisions.Get(requestID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("No decision trail found for request: %s", requestID),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, trail)
+}
+
 // metricsHandler 指标处理器
 func (g *Gateway) metricsHandler(c *gin.Context) {
 	// 这里应该返回Prometheus格式的指标
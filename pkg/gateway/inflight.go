@@ -0,0 +1,353 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// maxDrainWait 排空接口等待在途请求完成的最长时间
+const maxDrainWait = 5 * time.Minute
+
+// inflightRequest 在途请求
+type inflightRequest struct {
+	route    string
+	upstream string
+	start    time.Time
+	expected time.Duration // 路由预期耗时，为0时看门狗不检测
+	method   string
+	path     string
+	tenant   string
+	traceID  string
+	service  string
+	cancel   context.CancelCauseFunc // 看门狗未开启取消时为nil
+	stuck    bool                    // 已被看门狗标记
+}
+
+// inflightGroup 按路由或上游聚合的在途请求
+type inflightGroup struct {
+	Name      string `json:"name"`
+	InFlight  int    `json:"in_flight"`
+	Stuck     int    `json:"stuck"`
+	OldestAge string `json:"oldest_age"`
+	oldest    time.Time
+}
+
+// inflightTracker 按路由、上游统计在途请求，支持单独排空某个路由
+type inflightTracker struct {
+	requests map[*inflightRequest]struct{}
+	draining map[string]time.Time // 路由名 -> 开始排空时间
+	rejected map[string]int64     // 路由排空期间拒绝的请求数
+	mutex    sync.RWMutex
+}
+
+// newInflightTracker 创建在途请求统计
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{
+		requests: make(map[*inflightRequest]struct{}),
+		draining: make(map[string]time.Time),
+		rejected: make(map[string]int64),
+	}
+}
+
+// inflightGuard 在途请求统计中间件，需放在路由匹配之后：排空中的路由拒绝新请求，已接收的请求正常完成；
+// 管理接口的请求不计入，排空等待期间的排空请求本身不算作在途
+func (g *Gateway) inflightGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/admin/") {
+			c.Next()
+			return
+		}
+
+		request := &inflightRequest{
+			start:   time.Now(),
+			method:  c.Request.Method,
+			path:    c.Request.URL.Path,
+			tenant:  utils.ExtractTenant(c),
+			traceID: utils.ExtractTraceID(c),
+			service: utils.ExtractServiceName(c),
+		}
+		if route := matchedRoute(c); route != nil {
+			request.route = route.Name
+			request.expected = route.Expected
+		}
+		routeName := request.route
+
+		if !g.inflight.admit(request) {
+			c.Header("Retry-After", "30")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Route is draining for maintenance",
+				"code":  "ROUTE_DRAINING",
+				"route": routeName,
+			})
+			return
+		}
+		defer g.inflight.release(request)
+
+		// 看门狗可取消卡住的请求，上游调用使用派生的context
+		if g.config.Watchdog.Enabled && g.config.Watchdog.Cancel {
+			ctx, cancel := context.WithCancelCause(c.Request.Context())
+			defer cancel(nil)
+			c.Request = c.Request.WithContext(ctx)
+			g.inflight.setCancel(request, cancel)
+		}
+
+		c.Set("inflight_request", request)
+		c.Next()
+	}
+}
+
+// admit 登记在途请求，路由排空中时拒绝
+func (t *inflightTracker) admit(request *inflightRequest) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, draining := t.draining[request.route]; draining && request.route != "" {
+		t.rejected[request.route]++
+		return false
+	}
+
+	t.requests[request] = struct{}{}
+	return true
+}
+
+// setCancel 记录取消请求的函数
+func (t *inflightTracker) setCancel(request *inflightRequest, cancel context.CancelCauseFunc) {
+	t.mutex.Lock()
+	request.cancel = cancel
+	t.mutex.Unlock()
+}
+
+// release 请求完成
+func (t *inflightTracker) release(request *inflightRequest) {
+	t.mutex.Lock()
+	delete(t.requests, request)
+	t.mutex.Unlock()
+}
+
+// setUpstream 记录请求转发的上游
+func (t *inflightTracker) setUpstream(c *gin.Context, upstream string) {
+	value, exists := c.Get("inflight_request")
+	if !exists {
+		return
+	}
+	if request, ok := value.(*inflightRequest); ok {
+		t.mutex.Lock()
+		request.upstream = upstream
+		t.mutex.Unlock()
+	}
+}
+
+// drain 开始排空路由，重复调用时保留最早的开始时间
+func (t *inflightTracker) drain(route string) time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if since, exists := t.draining[route]; exists {
+		return since
+	}
+	since := time.Now()
+	t.draining[route] = since
+	return since
+}
+
+// resume 恢复路由接收新请求，返回路由是否处于排空中
+func (t *inflightTracker) resume(route string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	_, exists := t.draining[route]
+	delete(t.draining, route)
+	delete(t.rejected, route)
+	return exists
+}
+
+// count 路由的在途请求数
+func (t *inflightTracker) count(route string) int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	n := 0
+	for request := range t.requests {
+		if request.route == route {
+			n++
+		}
+	}
+	return n
+}
+
+// snapshot 按路由、上游聚合在途请求，按在途数从多到少排列
+func (t *inflightTracker) snapshot() gin.H {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	now := time.Now()
+	routes := make(map[string]*inflightGroup)
+	upstreams := make(map[string]*inflightGroup)
+	var oldest time.Time
+	stuck := 0
+	for request := range t.requests {
+		addInflight(routes, request, request.route)
+		if request.upstream != "" {
+			addInflight(upstreams, request, request.upstream)
+		}
+		if request.stuck {
+			stuck++
+		}
+		if oldest.IsZero() || request.start.Before(oldest) {
+			oldest = request.start
+		}
+	}
+
+	draining := make([]gin.H, 0, len(t.draining))
+	for route, since := range t.draining {
+		inFlight := 0
+		if group, exists := routes[route]; exists {
+			inFlight = group.InFlight
+		}
+		draining = append(draining, gin.H{
+			"route":     route,
+			"since":     since,
+			"in_flight": inFlight,
+			"rejected":  t.rejected[route],
+			"drained":   inFlight == 0,
+		})
+	}
+	sort.Slice(draining, func(i, j int) bool {
+		return draining[i]["route"].(string) < draining[j]["route"].(string)
+	})
+
+	oldestAge := ""
+	if !oldest.IsZero() {
+		oldestAge = utils.FormatDuration(now.Sub(oldest))
+	}
+	return gin.H{
+		"in_flight":  len(t.requests),
+		"stuck":      stuck,
+		"oldest_age": oldestAge,
+		"routes":     sortedInflight(routes, now),
+		"upstreams":  sortedInflight(upstreams, now),
+		"draining":   draining,
+	}
+}
+
+// addInflight 累加一条在途请求
+func addInflight(groups map[string]*inflightGroup, request *inflightRequest, name string) {
+	group, exists := groups[name]
+	if !exists {
+		group = &inflightGroup{Name: name, oldest: request.start}
+		groups[name] = group
+	}
+	group.InFlight++
+	if request.stuck {
+		group.Stuck++
+	}
+	if request.start.Before(group.oldest) {
+		group.oldest = request.start
+	}
+}
+
+// sortedInflight 按在途数从多到少排列，未匹配路由的请求名称为空
+func sortedInflight(groups map[string]*inflightGroup, now time.Time) []*inflightGroup {
+	result := make([]*inflightGroup, 0, len(groups))
+	for _, group := range groups {
+		group.OldestAge = utils.FormatDuration(now.Sub(group.oldest))
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].InFlight != result[j].InFlight {
+			return result[i].InFlight > result[j].InFlight
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// getInflightHandler 获取按路由、上游聚合的在途请求和排空中的路由
+func (g *Gateway) getInflightHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, g.inflight.snapshot())
+}
+
+// drainRouteHandler 排空路由：停止接收新请求，在途请求正常完成；
+// 指定wait时等待在途请求完成或超时后返回
+func (g *Gateway) drainRouteHandler(c *gin.Context) {
+	name := c.Param("name")
+	if !g.routeExists(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("route %s not found", name)})
+		return
+	}
+
+	var wait time.Duration
+	if value := c.Query("wait"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid wait duration: %s", value)})
+			return
+		}
+		if parsed > maxDrainWait {
+			parsed = maxDrainWait
+		}
+		wait = parsed
+	}
+
+	since := g.inflight.drain(name)
+	inFlight := g.inflight.count(name)
+	log.Printf("Draining route %s via admin API: %d requests in flight", name, inFlight)
+
+	if wait > 0 && inFlight > 0 {
+		deadline := time.NewTimer(wait)
+		defer deadline.Stop()
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+
+	waitLoop:
+		for inFlight > 0 {
+			select {
+			case <-ticker.C:
+				inFlight = g.inflight.count(name)
+			case <-deadline.C:
+				break waitLoop
+			case <-c.Request.Context().Done():
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"route":     name,
+		"since":     since,
+		"in_flight": inFlight,
+		"drained":   inFlight == 0,
+	})
+}
+
+// resumeRouteHandler 恢复路由接收新请求
+func (g *Gateway) resumeRouteHandler(c *gin.Context) {
+	name := c.Param("name")
+	if !g.inflight.resume(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("route %s is not draining", name)})
+		return
+	}
+
+	log.Printf("Resumed route %s via admin API", name)
+	c.JSON(http.StatusOK, gin.H{"route": name, "draining": false})
+}
+
+// routeExists 路由表中是否存在该路由
+func (g *Gateway) routeExists(name string) bool {
+	for _, route := range g.routes.Routes() {
+		if route.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package ipfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// etcd中的运行时名单，值为CIDR或IP的JSON数组，存在时替换配置文件中的对应名单
+const (
+	KeyPrefix = "/ipfilter/"
+	KeyAllow  = KeyPrefix + "allow"
+	KeyDeny   = KeyPrefix + "deny"
+)
+
+// 拦截原因
+const (
+	ReasonDenied     = "denied"      // 命中拒绝名单
+	ReasonNotAllowed = "not_allowed" // 配置了允许名单但未命中
+	ReasonInvalidIP  = "invalid_ip"  // 无法解析客户端IP
+)
+
+// lists 生效中的名单
+type lists struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// IPFilter 基于CIDR的访问控制，先匹配拒绝名单，允许名单非空时只放行命中的地址
+type IPFilter struct {
+	metrics    interfaces.MetricsCollector
+	current    atomic.Value // *lists
+	allow      []netip.Prefix
+	deny       []netip.Prefix
+	runtime    map[string][]netip.Prefix // etcd中的名单，按键保存
+	denied     int64
+	notAllowed int64
+	mutex      sync.Mutex
+}
+
+// NewIPFilter 创建IP访问控制
+func NewIPFilter(config *types.IPFilterConfig, metrics interfaces.MetricsCollector) (*IPFilter, error) {
+	allow, err := ParsePrefixes(config.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow list: %v", err)
+	}
+	deny, err := ParsePrefixes(config.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny list: %v", err)
+	}
+
+	f := &IPFilter{
+		metrics: metrics,
+		allow:   allow,
+		deny:    deny,
+		runtime: make(map[string][]netip.Prefix),
+	}
+	f.rebuild()
+
+	return f, nil
+}
+
+// ParsePrefixes 解析CIDR或单个IP，IPv4映射的IPv6网段（如::ffff:10.0.0.0/104）按IPv4网段匹配
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, unmapPrefix(prefix).Masked())
+			continue
+		}
+
+		addr, err := utils.ParseIP(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Check 检查客户端IP，返回空字符串表示放行，否则返回拦截原因
+func (f *IPFilter) Check(ip string) string {
+	l := f.current.Load().(*lists)
+	if len(l.allow) == 0 && len(l.deny) == 0 {
+		return ""
+	}
+
+	addr, err := utils.ParseIP(ip)
+	if err != nil {
+		return ReasonInvalidIP
+	}
+
+	if contains(l.deny, addr) {
+		return ReasonDenied
+	}
+	if len(l.allow) > 0 && !contains(l.allow, addr) {
+		return ReasonNotAllowed
+	}
+	return ""
+}
+
+// Middleware IP访问控制中间件，拦截时返回403
+func (f *IPFilter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := utils.ExtractClientIP(c)
+		reason := f.Check(ip)
+		if reason == "" {
+			c.Next()
+			return
+		}
+
+		if reason == ReasonDenied {
+			atomic.AddInt64(&f.denied, 1)
+		} else {
+			atomic.AddInt64(&f.notAllowed, 1)
+		}
+		if f.metrics != nil {
+			f.metrics.RecordIPBlocked(reason)
+		}
+		utils.RecordStageError(c, "ip_filter", fmt.Errorf("client address %s %s", ip, reason))
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":     "Access denied for client address",
+			"code":      "IP_" + strings.ToUpper(reason),
+			"client_ip": ip,
+		})
+	}
+}
+
+// OnUpdate 处理etcd中的名单变更，删除键时恢复配置文件中的名单
+func (f *IPFilter) OnUpdate(key string, value []byte, deleted bool) {
+	if key != KeyAllow && key != KeyDeny {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if deleted {
+		delete(f.runtime, key)
+		log.Printf("Runtime IP list %s removed, using configured list", key)
+	} else {
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			log.Printf("Invalid IP list %s: %v", key, err)
+			return
+		}
+		prefixes, err := ParsePrefixes(values)
+		if err != nil {
+			log.Printf("Invalid IP list %s: %v", key, err)
+			return
+		}
+		f.runtime[key] = prefixes
+		log.Printf("Updated runtime IP list %s: %d entries", key, len(prefixes))
+	}
+
+	f.rebuildLocked()
+}
+
+// Stats 获取生效名单和拦截统计
+func (f *IPFilter) Stats() map[string]interface{} {
+	l := f.current.Load().(*lists)
+	return map[string]interface{}{
+		"allow":       formatPrefixes(l.allow),
+		"deny":        formatPrefixes(l.deny),
+		"denied":      atomic.LoadInt64(&f.denied),
+		"not_allowed": atomic.LoadInt64(&f.notAllowed),
+	}
+}
+
+// Lists 获取生效的允许和拒绝名单
+func (f *IPFilter) Lists() ([]string, []string) {
+	l := f.current.Load().(*lists)
+	return formatPrefixes(l.allow), formatPrefixes(l.deny)
+}
+
+// rebuild 重新计算生效名单
+func (f *IPFilter) rebuild() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rebuildLocked()
+}
+
+// rebuildLocked 运行时名单存在时替换配置名单，调用方需持有锁
+func (f *IPFilter) rebuildLocked() {
+	l := &lists{allow: f.allow, deny: f.deny}
+	if allow, exists := f.runtime[KeyAllow]; exists {
+		l.allow = allow
+	}
+	if deny, exists := f.runtime[KeyDeny]; exists {
+		l.deny = deny
+	}
+	f.current.Store(l)
+}
+
+// unmapPrefix IPv4映射的IPv6网段转换为IPv4网段，客户端地址解析时同样还原为IPv4
+func unmapPrefix(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr()
+	if !addr.Is4In6() || prefix.Bits() < 96 {
+		return prefix
+	}
+	return netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+}
+
+// contains 判断地址是否命中任一网段
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPrefixes 网段转换为字符串
+func formatPrefixes(prefixes []netip.Prefix) []string {
+	values := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		values = append(values, prefix.String())
+	}
+	return values
+}
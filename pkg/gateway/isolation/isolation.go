@@ -0,0 +1,148 @@
+package isolation
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 跳过阶段的原因
+const (
+	ReasonTimeout   = "timeout"   // 调用超时
+	ReasonPanic     = "panic"     // 调用panic
+	ReasonBypassed  = "bypassed"  // 处于自动旁路期间
+	ReasonSaturated = "saturated" // 同时执行的调用达到上限
+)
+
+// ErrSkipped 阶段被跳过时返回的错误
+var ErrSkipped = fmt.Errorf("stage skipped by failure isolation")
+
+// Stage 非关键阶段的故障隔离：调用在独立协程中执行，超时或panic时调用方立即返回，
+// 连续失败达到阈值后在bypass_duration内直接跳过该阶段
+type Stage struct {
+	name        string
+	config      types.IsolationConfig
+	metrics     interfaces.MetricsCollector
+	pending     int64 // 正在执行的调用数，含超时后仍未返回的
+	failures    int64 // 连续失败次数
+	bypassUntil int64 // 旁路截止时间（UnixNano）
+	calls       int64
+	timeouts    int64
+	panics      int64
+	skipped     int64
+	bypasses    int64 // 进入旁路的次数
+}
+
+// NewStage 创建阶段隔离，metrics可为nil
+func NewStage(name string, config *types.IsolationConfig, metrics interfaces.MetricsCollector) *Stage {
+	cfg := *config
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 50 * time.Millisecond
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.BypassDuration <= 0 {
+		cfg.BypassDuration = 30 * time.Second
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 1000
+	}
+
+	return &Stage{name: name, config: cfg, metrics: metrics}
+}
+
+// Run 执行一次调用，返回false表示调用被跳过、超时或panic，此时fn写入的结果不可读取
+func (s *Stage) Run(fn func()) bool {
+	if time.Now().UnixNano() < atomic.LoadInt64(&s.bypassUntil) {
+		s.skip(ReasonBypassed)
+		return false
+	}
+	if atomic.AddInt64(&s.pending, 1) > int64(s.config.MaxPending) {
+		atomic.AddInt64(&s.pending, -1)
+		s.skip(ReasonSaturated)
+		return false
+	}
+	atomic.AddInt64(&s.calls, 1)
+
+	done := make(chan interface{}, 1)
+	go func() {
+		defer atomic.AddInt64(&s.pending, -1)
+		defer func() { done <- recover() }()
+		fn()
+	}()
+
+	timer := time.NewTimer(s.config.Timeout)
+	defer timer.Stop()
+
+	select {
+	case recovered := <-done:
+		if recovered != nil {
+			atomic.AddInt64(&s.panics, 1)
+			log.Printf("Stage %s panicked, skipping: %v", s.name, recovered)
+			s.fail(ReasonPanic)
+			return false
+		}
+		atomic.StoreInt64(&s.failures, 0)
+		return true
+	case <-timer.C:
+		atomic.AddInt64(&s.timeouts, 1)
+		s.fail(ReasonTimeout)
+		return false
+	}
+}
+
+// fail 记录一次失败，连续失败达到阈值时进入旁路
+func (s *Stage) fail(reason string) {
+	s.skip(reason)
+	if atomic.AddInt64(&s.failures, 1) < int64(s.config.FailureThreshold) {
+		return
+	}
+
+	atomic.StoreInt64(&s.failures, 0)
+	atomic.StoreInt64(&s.bypassUntil, time.Now().Add(s.config.BypassDuration).UnixNano())
+	atomic.AddInt64(&s.bypasses, 1)
+	log.Printf("Stage %s failed %d times in a row (last: %s), bypassing for %v",
+		s.name, s.config.FailureThreshold, reason, s.config.BypassDuration)
+}
+
+// skip 记录一次跳过
+func (s *Stage) skip(reason string) {
+	atomic.AddInt64(&s.skipped, 1)
+	if s.metrics != nil {
+		s.metrics.RecordStageIsolation(s.name, reason)
+	}
+}
+
+// Bypassed 当前是否处于自动旁路期间
+func (s *Stage) Bypassed() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&s.bypassUntil)
+}
+
+// Health 处于旁路期间时为degraded
+func (s *Stage) Health() types.ComponentHealth {
+	if s.Bypassed() {
+		return types.ComponentHealth{
+			Status:  types.ComponentDegraded,
+			Message: fmt.Sprintf("%s bypassed until %s", s.name, time.Unix(0, atomic.LoadInt64(&s.bypassUntil)).Format(time.RFC3339)),
+		}
+	}
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+// Stats 获取隔离统计
+func (s *Stage) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"calls":    atomic.LoadInt64(&s.calls),
+		"timeouts": atomic.LoadInt64(&s.timeouts),
+		"panics":   atomic.LoadInt64(&s.panics),
+		"skipped":  atomic.LoadInt64(&s.skipped),
+		"bypasses": atomic.LoadInt64(&s.bypasses),
+		"bypassed": s.Bypassed(),
+		"pending":  atomic.LoadInt64(&s.pending),
+	}
+}
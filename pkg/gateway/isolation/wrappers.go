@@ -0,0 +1,115 @@
+package isolation
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 隔离的阶段名
+const (
+	StageClusterIdentification = "cluster_identification"
+	StageErrorSampling         = "error_sampling"
+)
+
+// isolatedVectorAgent 簇识别在隔离阶段中执行的向量代理，快照加载等控制路径的调用不受影响
+type isolatedVectorAgent struct {
+	interfaces.VectorAgent
+	stage *Stage
+}
+
+// VectorAgent 为向量代理的簇识别加上故障隔离，识别被跳过时返回ErrSkipped，请求按未识别簇处理
+func VectorAgent(agent interfaces.VectorAgent, config *types.IsolationConfig, metrics interfaces.MetricsCollector) interfaces.VectorAgent {
+	return &isolatedVectorAgent{
+		VectorAgent: agent,
+		stage:       NewStage(StageClusterIdentification, config, metrics),
+	}
+}
+
+// IdentifyCluster 识别错误所属的簇
+func (a *isolatedVectorAgent) IdentifyCluster(errorSignature string) (string, error) {
+	clusterID, _, err := a.IdentifyClusterWithScore(errorSignature)
+	return clusterID, err
+}
+
+// IdentifyClusterWithScore 识别错误所属的簇并返回相似度
+func (a *isolatedVectorAgent) IdentifyClusterWithScore(errorSignature string) (string, float64, error) {
+	if errorSignature == "" {
+		return "", 0, nil
+	}
+
+	var clusterID string
+	var similarity float64
+	var err error
+	if !a.stage.Run(func() {
+		clusterID, similarity, err = a.VectorAgent.IdentifyClusterWithScore(errorSignature)
+	}) {
+		return "", 0, ErrSkipped
+	}
+	return clusterID, similarity, err
+}
+
+// Health 簇识别处于旁路期间时为degraded
+func (a *isolatedVectorAgent) Health() types.ComponentHealth {
+	return a.stage.Health()
+}
+
+// Stats 向量代理统计，附加隔离统计
+func (a *isolatedVectorAgent) Stats() map[string]interface{} {
+	return withIsolation(a.VectorAgent, a.stage)
+}
+
+// isolatedSampler 错误采样在隔离阶段中执行的采样器
+type isolatedSampler struct {
+	interfaces.ErrorSampler
+	stage *Stage
+}
+
+// ErrorSampler 为请求路径上的错误采样加上故障隔离，采样被跳过时丢弃该事件
+func ErrorSampler(sampler interfaces.ErrorSampler, config *types.IsolationConfig, metrics interfaces.MetricsCollector) interfaces.ErrorSampler {
+	return &isolatedSampler{
+		ErrorSampler: sampler,
+		stage:        NewStage(StageErrorSampling, config, metrics),
+	}
+}
+
+// SampleError 采样错误；采样可能在超时后继续执行，因此使用请求上下文的副本，
+// 被跳过的事件只计入隔离统计，不返回错误以免每个请求都输出日志
+func (s *isolatedSampler) SampleError(ctx *gin.Context, err error) error {
+	copied := ctx.Copy()
+	copied.Errors = append(copied.Errors, ctx.Errors...)
+
+	var sampleErr error
+	if !s.stage.Run(func() {
+		sampleErr = s.ErrorSampler.SampleError(copied, err)
+	}) {
+		return nil
+	}
+	return sampleErr
+}
+
+// Health 采样器本身不可用时为unhealthy，采样处于旁路期间时为degraded
+func (s *isolatedSampler) Health() types.ComponentHealth {
+	if health := s.ErrorSampler.Health(); health.Status != types.ComponentHealthy {
+		return health
+	}
+	return s.stage.Health()
+}
+
+// Stats 采样器统计，附加隔离统计
+func (s *isolatedSampler) Stats() map[string]interface{} {
+	return withIsolation(s.ErrorSampler, s.stage)
+}
+
+// withIsolation 组件的统计，附加isolation字段
+func withIsolation(component interface{}, stage *Stage) map[string]interface{} {
+	stats := make(map[string]interface{})
+	if reporter, ok := component.(interfaces.StatsReporter); ok {
+		for key, value := range reporter.Stats() {
+			stats[key] = value
+		}
+	}
+	stats["isolation"] = stage.Stats()
+	return stats
+}
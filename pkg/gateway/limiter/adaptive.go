@@ -0,0 +1,214 @@
+package limiter
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// maxAdaptiveSamples 每个调整周期保留的延迟样本上限，超过后覆盖最早的样本
+const maxAdaptiveSamples = 2048
+
+// 自适应限流的调整动作
+const (
+	AdaptiveIncrease = "increase"
+	AdaptiveDecrease = "decrease"
+	AdaptiveHold     = "hold"
+)
+
+// aimdController 单个簇的AIMD速率控制器
+type aimdController struct {
+	config    types.AdaptiveRateConfig
+	latencies []time.Duration // 当前周期的延迟样本
+	next      int             // 样本满后下一个覆盖的位置
+	requests  int
+	failures  int
+	last      time.Time
+	stats     *types.AdaptiveRateStats // 最近一次调整，尚未调整时为nil
+	mutex     sync.Mutex
+}
+
+// normalizeAdaptive 校验自适应限流配置并补全默认值
+func normalizeAdaptive(config *types.AdaptiveRateConfig, initialRate, maxRate float64) (*types.AdaptiveRateConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	cfg := *config
+	if cfg.TargetP95 < 0 || cfg.MaxErrorRate < 0 || cfg.MaxErrorRate > 1 || cfg.Increase < 0 ||
+		cfg.Decrease < 0 || cfg.Decrease >= 1 || cfg.MinRate < 0 || cfg.MaxRate < 0 || cfg.Interval < 0 || cfg.MinSamples < 0 {
+		return nil, fmt.Errorf("invalid adaptive rate limit config")
+	}
+	if cfg.MaxErrorRate == 0 {
+		cfg.MaxErrorRate = 0.05
+	}
+	if cfg.Increase == 0 {
+		cfg.Increase = initialRate * 0.1
+		if cfg.Increase < 1 {
+			cfg.Increase = 1
+		}
+	}
+	if cfg.Decrease == 0 {
+		cfg.Decrease = 0.7
+	}
+	if cfg.MinRate == 0 {
+		cfg.MinRate = 1
+	}
+	if cfg.MaxRate == 0 {
+		cfg.MaxRate = maxRate
+	}
+	if cfg.MaxRate < cfg.MinRate {
+		return nil, fmt.Errorf("adaptive max rate %.2f is below min rate %.2f", cfg.MaxRate, cfg.MinRate)
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.MinSamples == 0 {
+		cfg.MinSamples = 20
+	}
+	return &cfg, nil
+}
+
+// newAIMDController 创建AIMD速率控制器
+func newAIMDController(config types.AdaptiveRateConfig) *aimdController {
+	return &aimdController{config: config, last: time.Now()}
+}
+
+// observe 记录一个请求的上游延迟和结果
+func (a *aimdController) observe(latency time.Duration, failed bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.requests++
+	if failed {
+		a.failures++
+	}
+	if len(a.latencies) < maxAdaptiveSamples {
+		a.latencies = append(a.latencies, latency)
+		return
+	}
+	a.latencies[a.next] = latency
+	a.next = (a.next + 1) % maxAdaptiveSamples
+}
+
+// adjust 调整周期结束时按本周期的p95延迟和错误率计算新速率，周期未结束时返回false
+func (a *aimdController) adjust(rate float64, now time.Time) (float64, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if now.Sub(a.last) < a.config.Interval {
+		return rate, false
+	}
+	a.last = now
+
+	stats := &types.AdaptiveRateStats{Samples: a.requests, Action: AdaptiveHold, AdjustedAt: now}
+	if a.requests > 0 {
+		stats.ErrorRate = float64(a.failures) / float64(a.requests)
+	}
+	if len(a.latencies) > 0 {
+		sort.Slice(a.latencies, func(i, j int) bool { return a.latencies[i] < a.latencies[j] })
+		stats.P95 = a.latencies[(len(a.latencies)*95-1)/100]
+	}
+	a.latencies, a.next, a.requests, a.failures = a.latencies[:0], 0, 0, 0
+
+	newRate := rate
+	if stats.Samples >= a.config.MinSamples {
+		overloaded := stats.ErrorRate > a.config.MaxErrorRate ||
+			(a.config.TargetP95 > 0 && stats.P95 > a.config.TargetP95)
+		if overloaded {
+			stats.Action = AdaptiveDecrease
+			newRate = rate * a.config.Decrease
+		} else {
+			stats.Action = AdaptiveIncrease
+			newRate = rate + a.config.Increase
+		}
+		if newRate < a.config.MinRate {
+			newRate = a.config.MinRate
+		}
+		if newRate > a.config.MaxRate {
+			newRate = a.config.MaxRate
+		}
+	}
+
+	a.stats = stats
+	return newRate, newRate != rate
+}
+
+// snapshot 获取最近一次调整的统计
+func (a *aimdController) snapshot() *types.AdaptiveRateStats {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.stats == nil {
+		return nil
+	}
+	stats := *a.stats
+	return &stats
+}
+
+// ObserveUpstream 记录请求的上游延迟和结果，供自适应限流的簇调整速率；网关自身拒绝的请求不应记录
+func (crl *clusterRateLimiter) ObserveUpstream(ctx *gin.Context, latency time.Duration, failed bool) {
+	clusterID := ctx.GetString("cluster_id")
+	if clusterID == "" {
+		return
+	}
+
+	crl.mutex.RLock()
+	limiter, exists := crl.clusters[clusterID]
+	var adaptive *aimdController
+	if exists {
+		adaptive = limiter.adaptive
+	}
+	crl.mutex.RUnlock()
+
+	if adaptive != nil {
+		adaptive.observe(latency, failed)
+	}
+}
+
+// adaptLoop 定期按各簇的调整周期执行AIMD调整
+func (crl *clusterRateLimiter) adaptLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			crl.adapt(time.Now())
+		case <-crl.stopCh:
+			return
+		}
+	}
+}
+
+// adapt 调整所有自适应簇的速率
+func (crl *clusterRateLimiter) adapt(now time.Time) {
+	crl.mutex.Lock()
+	defer crl.mutex.Unlock()
+
+	for clusterID, limiter := range crl.clusters {
+		if limiter.adaptive == nil {
+			continue
+		}
+
+		rate, changed := limiter.adaptive.adjust(limiter.CurrentRate, now)
+		if !changed {
+			continue
+		}
+
+		capacity := rateCapacity(rate)
+		setRate(limiter.Limiter, rate, capacity)
+		limiter.updateKeyLimiters(rate, capacity)
+		if rate < limiter.CurrentRate {
+			log.Printf("Adaptive rate limit decreased for cluster %s: %.2f -> %.2f", clusterID, limiter.CurrentRate, rate)
+		}
+		limiter.CurrentRate = rate
+		limiter.Capacity = capacity
+	}
+}
@@ -0,0 +1,226 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// API密钥请求被拒绝的原因
+const (
+	KeyRejectRate  = "rate"
+	KeyRejectQuota = "quota"
+)
+
+// keyState 单个API密钥的令牌桶和配额消耗
+type keyState struct {
+	bucket       *TokenBucket // 未配置速率时为nil
+	quotaUsed    int64
+	quotaResetAt time.Time
+	lastSeen     time.Time
+}
+
+// KeyLimiter 按API密钥的速率和配额限制，密钥以摘要标识
+type KeyLimiter struct {
+	config        types.APIKeyLimitConfig
+	keys          map[string]*keyState
+	rateRejected  int64
+	quotaRejected int64
+	mutex         sync.Mutex
+}
+
+// NewKeyLimiter 创建API密钥限流器
+func NewKeyLimiter(config *types.APIKeyLimitConfig) *KeyLimiter {
+	cfg := *config
+	if cfg.Burst <= 0 {
+		cfg.Burst = int64(cfg.Rate)
+		if cfg.Burst < 1 {
+			cfg.Burst = 1
+		}
+	}
+	if cfg.QuotaPeriod <= 0 {
+		cfg.QuotaPeriod = 24 * time.Hour
+	}
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = time.Hour
+	}
+
+	return &KeyLimiter{
+		config: cfg,
+		keys:   make(map[string]*keyState),
+	}
+}
+
+// Allow 检查密钥是否允许请求，配额用尽时不消耗令牌
+func (kl *KeyLimiter) Allow(keyID string) (bool, string) {
+	now := time.Now()
+
+	kl.mutex.Lock()
+	defer kl.mutex.Unlock()
+
+	state := kl.stateLocked(keyID, now)
+	state.lastSeen = now
+
+	if kl.config.Quota > 0 {
+		if !now.Before(state.quotaResetAt) {
+			state.quotaUsed = 0
+			state.quotaResetAt = kl.quotaWindowEnd(now)
+		}
+		if state.quotaUsed >= kl.config.Quota {
+			atomic.AddInt64(&kl.quotaRejected, 1)
+			return false, KeyRejectQuota
+		}
+	}
+
+	if state.bucket != nil && !state.bucket.Allow() {
+		atomic.AddInt64(&kl.rateRejected, 1)
+		return false, KeyRejectRate
+	}
+
+	state.quotaUsed++
+	return true, ""
+}
+
+// QuotaRemaining 获取密钥当前配额周期的剩余请求数和重置时间，未配置配额时返回-1
+func (kl *KeyLimiter) QuotaRemaining(keyID string) (int64, time.Time) {
+	if kl.config.Quota <= 0 {
+		return -1, time.Time{}
+	}
+
+	kl.mutex.Lock()
+	defer kl.mutex.Unlock()
+
+	state, exists := kl.keys[keyID]
+	if !exists || !time.Now().Before(state.quotaResetAt) {
+		return kl.config.Quota, kl.quotaWindowEnd(time.Now())
+	}
+
+	remaining := kl.config.Quota - state.quotaUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, state.quotaResetAt
+}
+
+// Snapshot 导出所有密钥的令牌桶和配额消耗
+func (kl *KeyLimiter) Snapshot() []types.KeyBucketSnapshot {
+	now := time.Now()
+
+	kl.mutex.Lock()
+	defer kl.mutex.Unlock()
+
+	snapshots := make([]types.KeyBucketSnapshot, 0, len(kl.keys))
+	for keyID, state := range kl.keys {
+		snapshot := types.KeyBucketSnapshot{
+			KeyID:        keyID,
+			QuotaUsed:    state.quotaUsed,
+			QuotaResetAt: state.quotaResetAt,
+			Timestamp:    now,
+		}
+		if state.bucket != nil {
+			snapshot.Tokens = state.bucket.GetTokens()
+			snapshot.Capacity = state.bucket.GetCapacity()
+			snapshot.Rate = state.bucket.GetRate()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots
+}
+
+// Restore 合并恢复密钥状态：令牌取较少的一份，同一配额周期的消耗取较大的一份，
+// 已结束的配额周期不再恢复，返回恢复的密钥数
+func (kl *KeyLimiter) Restore(snapshots []types.KeyBucketSnapshot) int {
+	now := time.Now()
+
+	kl.mutex.Lock()
+	defer kl.mutex.Unlock()
+
+	restored := 0
+	for _, snapshot := range snapshots {
+		if snapshot.KeyID == "" {
+			continue
+		}
+
+		_, existed := kl.keys[snapshot.KeyID]
+		state := kl.stateLocked(snapshot.KeyID, now)
+		if !existed {
+			state.lastSeen = snapshot.Timestamp
+		}
+
+		if state.bucket != nil {
+			restoredBucket := NewTokenBucket(state.bucket.GetCapacity(), state.bucket.GetRate())
+			restoredBucket.Restore(snapshot.Tokens, snapshot.Timestamp)
+			if !existed || restoredBucket.GetTokens() < state.bucket.GetTokens() {
+				state.bucket = restoredBucket
+			}
+		}
+
+		if kl.config.Quota > 0 && now.Before(snapshot.QuotaResetAt) {
+			if !now.Before(state.quotaResetAt) || !state.quotaResetAt.Equal(snapshot.QuotaResetAt) {
+				state.quotaUsed = 0
+				state.quotaResetAt = snapshot.QuotaResetAt
+			}
+			if snapshot.QuotaUsed > state.quotaUsed {
+				state.quotaUsed = snapshot.QuotaUsed
+			}
+		}
+
+		restored++
+	}
+
+	return restored
+}
+
+// Cleanup 清理空闲且配额周期已结束的密钥，返回清理数量
+func (kl *KeyLimiter) Cleanup() int {
+	now := time.Now()
+
+	kl.mutex.Lock()
+	defer kl.mutex.Unlock()
+
+	removed := 0
+	for keyID, state := range kl.keys {
+		if now.Sub(state.lastSeen) > kl.config.IdleTTL && !now.Before(state.quotaResetAt) {
+			delete(kl.keys, keyID)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Stats 获取统计信息
+func (kl *KeyLimiter) Stats() map[string]interface{} {
+	kl.mutex.Lock()
+	keys := len(kl.keys)
+	kl.mutex.Unlock()
+
+	return map[string]interface{}{
+		"keys":           keys,
+		"rate_rejected":  atomic.LoadInt64(&kl.rateRejected),
+		"quota_rejected": atomic.LoadInt64(&kl.quotaRejected),
+	}
+}
+
+// stateLocked 获取或创建密钥状态，调用方需持有锁
+func (kl *KeyLimiter) stateLocked(keyID string, now time.Time) *keyState {
+	state, exists := kl.keys[keyID]
+	if exists {
+		return state
+	}
+
+	state = &keyState{lastSeen: now}
+	if kl.config.Rate > 0 {
+		state.bucket = NewTokenBucket(kl.config.Burst, kl.config.Rate)
+	}
+	kl.keys[keyID] = state
+	return state
+}
+
+// quotaWindowEnd 配额周期按UTC对齐，所有副本的周期边界一致
+func (kl *KeyLimiter) quotaWindowEnd(now time.Time) time.Time {
+	return now.UTC().Truncate(kl.config.QuotaPeriod).Add(kl.config.QuotaPeriod)
+}
@@ -0,0 +1,147 @@
+package limiter
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// KeyLimitPrefix etcd中API密钥限流覆盖的键前缀，键为"/ratelimits/keys/<密钥摘要>"，值为types.APIKeyRateLimit
+const KeyLimitPrefix = "/ratelimits/keys/"
+
+// perKeyIdleTTL 按密钥的簇限流器空闲超过该时间后清理
+const perKeyIdleTTL = 10 * time.Minute
+
+// keyOverride 单个API密钥的限流覆盖
+type keyOverride struct {
+	limit   types.APIKeyRateLimit
+	limiter rateAlgorithm
+}
+
+// perKeyLimiter PerKey策略下单个密钥的簇限流器
+type perKeyLimiter struct {
+	limiter  rateAlgorithm
+	lastSeen int64 // UnixNano
+}
+
+// KeyIDFromLimitKey 从etcd键中解析密钥摘要，不是密钥限流覆盖的键时返回false
+func KeyIDFromLimitKey(key string) (string, bool) {
+	keyID := strings.TrimPrefix(key, KeyLimitPrefix)
+	if keyID == key || keyID == "" || strings.Contains(keyID, "/") {
+		return "", false
+	}
+	return keyID, true
+}
+
+// UpdateKeyLimit 设置API密钥的限流覆盖，limit为nil时删除；限流参数未变化时保留已消耗的状态
+func (crl *clusterRateLimiter) UpdateKeyLimit(keyID string, limit *types.APIKeyRateLimit) error {
+	if keyID == "" {
+		return fmt.Errorf("api key id cannot be empty")
+	}
+
+	if limit == nil {
+		crl.mutex.Lock()
+		delete(crl.keyLimits, keyID)
+		crl.mutex.Unlock()
+		log.Printf("Removed rate limit override for api key %s", keyID)
+		return nil
+	}
+
+	if limit.Rate <= 0 {
+		return fmt.Errorf("rate for api key %s must be positive", keyID)
+	}
+	cfg := *limit
+	algorithm, window, err := normalizeAlgorithm(cfg.Algorithm, cfg.Window)
+	if err != nil {
+		return err
+	}
+	cfg.Algorithm, cfg.Window = algorithm, window
+	if cfg.Burst <= 0 {
+		cfg.Burst = int64(cfg.Rate)
+		if cfg.Burst < 1 {
+			cfg.Burst = 1
+		}
+	}
+
+	crl.mutex.Lock()
+	defer crl.mutex.Unlock()
+
+	if existing, exists := crl.keyLimits[keyID]; exists && existing.limit == cfg {
+		return nil
+	}
+	crl.keyLimits[keyID] = &keyOverride{
+		limit:   cfg,
+		limiter: newRateAlgorithm(cfg.Algorithm, cfg.Rate, cfg.Burst, cfg.Window),
+	}
+
+	log.Printf("Updated rate limit override for api key %s: algorithm=%s, rate=%.2f, burst=%d",
+		keyID, cfg.Algorithm, cfg.Rate, cfg.Burst)
+	return nil
+}
+
+// allowKey 检查密钥的限流覆盖，没有覆盖的密钥直接放行
+func (crl *clusterRateLimiter) allowKey(ctx *gin.Context, keyID string) bool {
+	crl.mutex.RLock()
+	override, exists := crl.keyLimits[keyID]
+	crl.mutex.RUnlock()
+
+	if !exists {
+		return true
+	}
+	allowed := admit(ctx, override.limiter)
+	recordQuota(ctx, override.limiter, allowed)
+	ctx.Set("rate_limit_check", types.RateLimitCheck{
+		Algorithm: override.limit.Algorithm,
+		Limiter:   types.RateLimiterKeyOverride,
+	})
+	if allowed {
+		return true
+	}
+	atomic.AddInt64(&crl.keyRejected, 1)
+	return false
+}
+
+// keyLimiter 获取或创建密钥在簇内的限流器，调用方需持有限流器的读锁
+func (l *clusterLimiter) keyLimiter(keyID string) rateAlgorithm {
+	l.keyMutex.Lock()
+	defer l.keyMutex.Unlock()
+
+	now := time.Now().UnixNano()
+	entry, exists := l.keyLimiters[keyID]
+	if !exists {
+		if l.keyLimiters == nil {
+			l.keyLimiters = make(map[string]*perKeyLimiter)
+		}
+		entry = &perKeyLimiter{limiter: newRateAlgorithm(l.Algorithm, l.CurrentRate, l.Capacity, l.Window)}
+		l.keyLimiters[keyID] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter
+}
+
+// updateKeyLimiters 策略速率变化时调整已有的按密钥限流器，调用方需持有限流器的写锁
+func (l *clusterLimiter) updateKeyLimiters(rate float64, capacity int64) {
+	for _, entry := range l.keyLimiters {
+		setRate(entry.limiter, rate, capacity)
+	}
+}
+
+// resetKeyLimiters 丢弃按密钥的限流器，调用方需持有限流器的写锁
+func (l *clusterLimiter) resetKeyLimiters() {
+	l.keyLimiters = nil
+}
+
+// removeIdleKeys 清理空闲的按密钥限流器，调用方需持有限流器的写锁
+func (l *clusterLimiter) removeIdleKeys(now time.Time) {
+	for keyID, entry := range l.keyLimiters {
+		if now.Sub(time.Unix(0, entry.lastSeen)) > perKeyIdleTTL {
+			delete(l.keyLimiters, keyID)
+		}
+	}
+}
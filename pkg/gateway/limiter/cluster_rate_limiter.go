@@ -1 +1,469 @@
-git 
\ No newline at end of file
+package limiter
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// clusterRateLimiter 基于簇的限流器
+type clusterRateLimiter struct {
+	config      *types.LimiterConfig
+	vectorAgent interfaces.VectorAgent
+	clusters    map[string]*clusterLimiter
+	warmState   map[string]types.BucketSnapshot // 等待应用的交接状态
+	keyLimits   map[string]*keyOverride         // 密钥摘要 -> etcd中的限流覆盖
+	policies    int64                           // 已应用的策略数
+	keyRejected int64                           // 密钥覆盖拒绝数
+	mutex       sync.RWMutex
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+}
+
+// defaultWindow 滑动窗口策略未指定窗口长度时的默认值
+const defaultWindow = time.Minute
+
+// rateAlgorithm 簇限流算法，令牌桶、滑动窗口和漏桶均实现
+type rateAlgorithm interface {
+	Allow() bool
+	SetRate(rate float64)
+	GetRate() float64
+	GetTokens() int64
+	GetCapacity() int64
+	Restore(tokens int64, at time.Time)
+}
+
+// clusterLimiter 簇限流器
+type clusterLimiter struct {
+	ClusterID        string
+	Limiter          rateAlgorithm
+	Algorithm        string
+	Window           time.Duration
+	Capacity         int64
+	PerKey           bool
+	Policy           *types.Policy
+	Severity         float64
+	BaseRate         float64
+	CurrentRate      float64
+	TotalRequests    int64
+	AllowedRequests  int64
+	RejectedRequests int64
+	keyLimiters      map[string]*perKeyLimiter // PerKey策略下按密钥摘要的限流器
+	adaptive         *aimdController           // 自适应限流策略的速率控制器，其他策略为nil
+	keyMutex         sync.Mutex
+}
+
+// NewClusterRateLimiter 创建基于簇的限流器
+func NewClusterRateLimiter(config *types.LimiterConfig, vectorAgent interfaces.VectorAgent) interfaces.RateLimiter {
+	crl := &clusterRateLimiter{
+		config:      config,
+		vectorAgent: vectorAgent,
+		clusters:    make(map[string]*clusterLimiter),
+		warmState:   make(map[string]types.BucketSnapshot),
+		keyLimits:   make(map[string]*keyOverride),
+		stopCh:      make(chan struct{}),
+	}
+
+	go crl.cleanupLoop()
+	go crl.adaptLoop()
+
+	return crl
+}
+
+// Allow 检查是否允许请求，携带API密钥的请求先检查该密钥的限流覆盖，再检查簇限流；
+// 检查结果记录在请求上下文的"rate_limit_quota"中
+func (crl *clusterRateLimiter) Allow(ctx *gin.Context) bool {
+	keyID := ""
+	if key := utils.ExtractAPIKey(ctx); key != "" {
+		keyID = utils.APIKeyID(key)
+		if !crl.allowKey(ctx, keyID) {
+			return false
+		}
+	}
+
+	clusterID := crl.identifyCluster(ctx)
+	if clusterID == "" {
+		return true // 无法识别簇，放行
+	}
+
+	// 策略切换算法时会替换限流器，需在锁内读取
+	crl.mutex.RLock()
+	limiter, exists := crl.clusters[clusterID]
+	var algorithm rateAlgorithm
+	check := types.RateLimitCheck{ClusterID: clusterID}
+	if exists {
+		algorithm = limiter.Limiter
+		check.Algorithm, check.Limiter = limiter.Algorithm, types.RateLimiterCluster
+		if limiter.Policy != nil {
+			check.PolicyVersion = limiter.Policy.Version
+		}
+		if limiter.PerKey && keyID != "" {
+			algorithm = limiter.keyLimiter(keyID)
+			check.Limiter = types.RateLimiterPerKey
+		}
+	}
+	crl.mutex.RUnlock()
+	ctx.Set("rate_limit_check", check)
+
+	if !exists {
+		return true // 簇不存在限流策略，放行
+	}
+
+	atomic.AddInt64(&limiter.TotalRequests, 1)
+
+	allowed := admit(ctx, algorithm)
+	recordQuota(ctx, algorithm, allowed)
+	if allowed {
+		atomic.AddInt64(&limiter.AllowedRequests, 1)
+		return true
+	}
+
+	atomic.AddInt64(&limiter.RejectedRequests, 1)
+	return false
+}
+
+// UpdatePolicy 更新簇策略
+func (crl *clusterRateLimiter) UpdatePolicy(clusterID string, policy *types.Policy) error {
+	if policy == nil {
+		return fmt.Errorf("policy cannot be nil")
+	}
+
+	if policy.PolicyType != types.PolicyTypeRateLimit || policy.RateLimit == nil {
+		return nil
+	}
+
+	// 基于限制比例和严重度调整令牌速率
+	baseRate := crl.config.DefaultRate
+	rate := baseRate * (1.0 - policy.RateLimit.LimitRate)
+	rate = utils.ClampFloat64(rate, 1.0, crl.config.MaxRate)
+
+	algorithm, window, err := normalizeAlgorithm(policy.RateLimit.Algorithm, policy.RateLimit.Window)
+	if err != nil {
+		return err
+	}
+	adaptive, err := normalizeAdaptive(policy.RateLimit.Adaptive, rate, crl.config.MaxRate)
+	if err != nil {
+		return err
+	}
+
+	crl.mutex.Lock()
+	defer crl.mutex.Unlock()
+
+	limiter, exists := crl.clusters[clusterID]
+	if !exists {
+		limiter = &clusterLimiter{ClusterID: clusterID}
+		crl.clusters[clusterID] = limiter
+	}
+
+	// 自适应配置未变化时保留已调整的速率，策略重新下发不会回到初始速率
+	switch {
+	case adaptive == nil:
+		limiter.adaptive = nil
+	case exists && limiter.adaptive != nil && limiter.adaptive.config == *adaptive:
+		rate = limiter.CurrentRate
+	default:
+		limiter.adaptive = newAIMDController(*adaptive)
+		rate = utils.ClampFloat64(rate, adaptive.MinRate, adaptive.MaxRate)
+	}
+	capacity := rateCapacity(rate)
+
+	if !exists || !limiter.matches(algorithm, window) {
+		limiter.Limiter = newRateAlgorithm(algorithm, rate, capacity, window)
+		limiter.Algorithm = algorithm
+		limiter.resetKeyLimiters()
+
+		// 新建的限流器优先使用交接状态，避免满桶导致的瞬时超额放行
+		if snapshot, ok := crl.warmState[clusterID]; ok {
+			limiter.Limiter.Restore(snapshot.Tokens, snapshot.Timestamp)
+			delete(crl.warmState, clusterID)
+			log.Printf("Applied warm bucket state for cluster %s: tokens=%d", clusterID, snapshot.Tokens)
+		}
+	} else {
+		setRate(limiter.Limiter, rate, capacity)
+		limiter.updateKeyLimiters(rate, capacity)
+	}
+	if !policy.RateLimit.PerKey {
+		limiter.resetKeyLimiters()
+	}
+
+	limiter.Policy = policy
+	limiter.Severity = policy.Severity
+	atomic.AddInt64(&crl.policies, 1)
+	limiter.BaseRate = baseRate
+	limiter.CurrentRate = rate
+	limiter.Capacity = capacity
+	limiter.Window = window
+	limiter.PerKey = policy.RateLimit.PerKey
+
+	log.Printf("Updated rate limiter for cluster %s: algorithm=%s, rate=%.2f, capacity=%d, per_key=%v, adaptive=%v",
+		clusterID, algorithm, rate, limiter.Limiter.GetCapacity(), limiter.PerKey, limiter.adaptive != nil)
+	return nil
+}
+
+// rateCapacity 速率对应的令牌桶容量，至少为1
+func rateCapacity(rate float64) int64 {
+	capacity := int64(rate)
+	if capacity < 1 {
+		capacity = 1
+	}
+	return capacity
+}
+
+// normalizeAlgorithm 校验限流算法并补全默认值
+func normalizeAlgorithm(algorithm string, window time.Duration) (string, time.Duration, error) {
+	if algorithm == "" {
+		algorithm = types.RateLimitTokenBucket
+	}
+	switch algorithm {
+	case types.RateLimitTokenBucket, types.RateLimitSlidingWindow, types.RateLimitLeakyBucket:
+	default:
+		return "", 0, fmt.Errorf("unsupported rate limit algorithm %q", algorithm)
+	}
+	if window <= 0 {
+		window = defaultWindow
+	}
+	return algorithm, window, nil
+}
+
+// newRateAlgorithm 按策略指定的算法创建限流器
+func newRateAlgorithm(algorithm string, rate float64, capacity int64, window time.Duration) rateAlgorithm {
+	switch algorithm {
+	case types.RateLimitSlidingWindow:
+		return NewSlidingWindow(rate, window)
+	case types.RateLimitLeakyBucket:
+		return NewLeakyBucket(capacity, rate)
+	}
+	return NewTokenBucket(capacity, rate)
+}
+
+// setRate 调整限流器速率，令牌桶的容量和漏桶的排队上限随速率调整
+func setRate(algorithm rateAlgorithm, rate float64, capacity int64) {
+	switch limiter := algorithm.(type) {
+	case *TokenBucket:
+		limiter.SetCapacity(capacity)
+	case *LeakyBucket:
+		limiter.SetCapacity(capacity)
+	}
+	algorithm.SetRate(rate)
+}
+
+// admit 检查是否放行请求，漏桶在排队未满时等待到放行时刻，请求被取消时不放行
+func admit(ctx *gin.Context, algorithm rateAlgorithm) bool {
+	bucket, ok := algorithm.(*LeakyBucket)
+	if !ok {
+		return algorithm.Allow()
+	}
+	if ctx.Request == nil {
+		return bucket.Allow()
+	}
+	return bucket.Wait(ctx.Request.Context())
+}
+
+// matches 当前限流器是否为指定的算法和窗口，不一致时需重建
+func (l *clusterLimiter) matches(algorithm string, window time.Duration) bool {
+	if l.Algorithm != algorithm {
+		return false
+	}
+	if sw, ok := l.Limiter.(*SlidingWindow); ok {
+		return sw.GetWindow() == window
+	}
+	return true
+}
+
+// GetStats 获取簇限流统计
+func (crl *clusterRateLimiter) GetStats(clusterID string) (*types.ClusterStats, error) {
+	crl.mutex.RLock()
+	limiter, exists := crl.clusters[clusterID]
+	var algorithm rateAlgorithm
+	var algorithmName string
+	var adaptive *aimdController
+	var severity float64
+	var policyVersion int64
+	if exists {
+		algorithm, algorithmName, adaptive = limiter.Limiter, limiter.Algorithm, limiter.adaptive
+		severity = limiter.Severity
+		if limiter.Policy != nil {
+			policyVersion = limiter.Policy.Version
+		}
+	}
+	crl.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("no rate limiter for cluster: %s", clusterID)
+	}
+
+	stats := &types.ClusterStats{
+		ClusterID:        clusterID,
+		TotalRequests:    atomic.LoadInt64(&limiter.TotalRequests),
+		AllowedRequests:  atomic.LoadInt64(&limiter.AllowedRequests),
+		RejectedRequests: atomic.LoadInt64(&limiter.RejectedRequests),
+		CurrentRate:      algorithm.GetRate(),
+		Tokens:           algorithm.GetTokens(),
+		Capacity:         algorithm.GetCapacity(),
+		Severity:         severity,
+		Algorithm:        algorithmName,
+		PolicyVersion:    policyVersion,
+	}
+	if adaptive != nil {
+		stats.Adaptive = adaptive.snapshot()
+	}
+
+	return stats, nil
+}
+
+// Stats 汇总所有簇的限流统计
+func (crl *clusterRateLimiter) Stats() map[string]interface{} {
+	crl.mutex.RLock()
+	defer crl.mutex.RUnlock()
+
+	var total, allowed, rejected int64
+	for _, limiter := range crl.clusters {
+		total += atomic.LoadInt64(&limiter.TotalRequests)
+		allowed += atomic.LoadInt64(&limiter.AllowedRequests)
+		rejected += atomic.LoadInt64(&limiter.RejectedRequests)
+	}
+
+	return map[string]interface{}{
+		"clusters":          len(crl.clusters),
+		"total_requests":    total,
+		"allowed_requests":  allowed,
+		"rejected_requests": rejected,
+		"policies_applied":  atomic.LoadInt64(&crl.policies),
+		"key_overrides":     len(crl.keyLimits),
+		"key_rejected":      atomic.LoadInt64(&crl.keyRejected),
+	}
+}
+
+// Health 健康状态，Cleanup之后不再可用
+func (crl *clusterRateLimiter) Health() types.ComponentHealth {
+	select {
+	case <-crl.stopCh:
+		return types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "rate limiter has been cleaned up"}
+	default:
+	}
+	if crl.vectorAgent == nil {
+		return types.ComponentHealth{Status: types.ComponentDegraded, Message: "no vector agent, only requests already tagged with a cluster are limited"}
+	}
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+// Cleanup 停止后台清理并释放限流器
+func (crl *clusterRateLimiter) Cleanup() error {
+	crl.stopOnce.Do(func() {
+		close(crl.stopCh)
+	})
+
+	crl.mutex.Lock()
+	crl.clusters = make(map[string]*clusterLimiter)
+	crl.mutex.Unlock()
+
+	return nil
+}
+
+// Snapshot 导出所有令牌桶的填充状态
+func (crl *clusterRateLimiter) Snapshot() []types.BucketSnapshot {
+	crl.mutex.RLock()
+	defer crl.mutex.RUnlock()
+
+	now := time.Now()
+	snapshots := make([]types.BucketSnapshot, 0, len(crl.clusters))
+	for clusterID, limiter := range crl.clusters {
+		snapshot := types.BucketSnapshot{
+			ClusterID: clusterID,
+			Tokens:    limiter.Limiter.GetTokens(),
+			Capacity:  limiter.Limiter.GetCapacity(),
+			Rate:      limiter.Limiter.GetRate(),
+			Timestamp: now,
+		}
+		if limiter.Policy != nil {
+			snapshot.PolicyVersion = limiter.Policy.Version
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots
+}
+
+// Restore 恢复令牌桶填充状态，尚未建立的令牌桶在策略到达时应用
+func (crl *clusterRateLimiter) Restore(snapshots []types.BucketSnapshot) int {
+	crl.mutex.Lock()
+	defer crl.mutex.Unlock()
+
+	applied := 0
+	for _, snapshot := range snapshots {
+		if limiter, exists := crl.clusters[snapshot.ClusterID]; exists {
+			limiter.Limiter.Restore(snapshot.Tokens, snapshot.Timestamp)
+			applied++
+			continue
+		}
+		crl.warmState[snapshot.ClusterID] = snapshot
+	}
+
+	return applied
+}
+
+// identifyCluster 识别请求所属簇
+func (crl *clusterRateLimiter) identifyCluster(ctx *gin.Context) string {
+	if clusterID := ctx.GetString("cluster_id"); clusterID != "" {
+		return clusterID
+	}
+
+	// 从上下文提取错误特征（如果存在）
+	errorSignature := utils.ExtractErrorSignature(ctx)
+	if errorSignature == "" || crl.vectorAgent == nil {
+		return ""
+	}
+
+	// 调用向量化Agent计算簇归属
+	clusterID, err := crl.vectorAgent.IdentifyCluster(errorSignature)
+	if err != nil {
+		return ""
+	}
+
+	return utils.NamespacedClusterID(ctx, clusterID)
+}
+
+// cleanupLoop 定期清理过期策略的限流器
+func (crl *clusterRateLimiter) cleanupLoop() {
+	interval := crl.config.CleanupInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			crl.removeExpired()
+		case <-crl.stopCh:
+			return
+		}
+	}
+}
+
+// removeExpired 删除策略已过期的限流器
+func (crl *clusterRateLimiter) removeExpired() {
+	now := time.Now()
+
+	crl.mutex.Lock()
+	defer crl.mutex.Unlock()
+
+	for clusterID, limiter := range crl.clusters {
+		if limiter.Policy != nil && !limiter.Policy.ExpireTime.IsZero() && now.After(limiter.Policy.ExpireTime) {
+			delete(crl.clusters, clusterID)
+			log.Printf("Removed expired rate limiter for cluster %s", clusterID)
+			continue
+		}
+		limiter.removeIdleKeys(now)
+	}
+}
@@ -0,0 +1,291 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 并发限制的拒绝原因
+var (
+	ErrConcurrencyQueueFull    = errors.New("concurrency queue full")
+	ErrConcurrencyQueueTimeout = errors.New("concurrency queue timeout")
+)
+
+// defaultQueueTimeout 未配置排队超时时的默认值
+const defaultQueueTimeout = time.Second
+
+// ConcurrencyLimiter 在途请求数限制：达到上限后新请求按到达顺序排队等待空位，
+// 队列已满或排队超时时拒绝
+type ConcurrencyLimiter struct {
+	config   types.ConcurrencyLimitConfig
+	slots    chan struct{}
+	queued   int64
+	admitted int64 // 获得空位的请求数
+	waited   int64 // 排队后获得空位的请求数
+	rejected int64 // 队列已满拒绝数
+	timedOut int64 // 排队超时拒绝数
+}
+
+// NewConcurrencyLimiter 创建并发限制器
+func NewConcurrencyLimiter(config *types.ConcurrencyLimitConfig) (*ConcurrencyLimiter, error) {
+	if config.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("max concurrent must be positive")
+	}
+	if config.MaxQueue < 0 || config.QueueTimeout < 0 {
+		return nil, fmt.Errorf("max queue and queue timeout must not be negative")
+	}
+
+	cfg := *config
+	if cfg.QueueTimeout == 0 {
+		cfg.QueueTimeout = defaultQueueTimeout
+	}
+	return &ConcurrencyLimiter{config: cfg, slots: make(chan struct{}, cfg.MaxConcurrent)}, nil
+}
+
+// Acquire 获取空位，需要排队时最多等待排队超时或ctx结束；成功时返回释放函数，请求结束后调用，重复调用无副作用
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case cl.slots <- struct{}{}:
+		atomic.AddInt64(&cl.admitted, 1)
+		return cl.releaser(), nil
+	default:
+	}
+
+	if atomic.AddInt64(&cl.queued, 1) > cl.config.MaxQueue {
+		atomic.AddInt64(&cl.queued, -1)
+		atomic.AddInt64(&cl.rejected, 1)
+		return nil, ErrConcurrencyQueueFull
+	}
+	defer atomic.AddInt64(&cl.queued, -1)
+
+	timer := time.NewTimer(cl.config.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case cl.slots <- struct{}{}:
+		atomic.AddInt64(&cl.admitted, 1)
+		atomic.AddInt64(&cl.waited, 1)
+		return cl.releaser(), nil
+	case <-timer.C:
+		atomic.AddInt64(&cl.timedOut, 1)
+		return nil, ErrConcurrencyQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releaser 创建只释放一次空位的函数
+func (cl *ConcurrencyLimiter) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-cl.slots })
+	}
+}
+
+// InFlight 获取在途请求数
+func (cl *ConcurrencyLimiter) InFlight() int64 {
+	return int64(len(cl.slots))
+}
+
+// Queued 获取排队中的请求数
+func (cl *ConcurrencyLimiter) Queued() int64 {
+	return atomic.LoadInt64(&cl.queued)
+}
+
+// Config 获取并发限制配置
+func (cl *ConcurrencyLimiter) Config() types.ConcurrencyLimitConfig {
+	return cl.config
+}
+
+// Stats 获取统计信息
+func (cl *ConcurrencyLimiter) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"max_concurrent": cl.config.MaxConcurrent,
+		"max_queue":      cl.config.MaxQueue,
+		"in_flight":      cl.InFlight(),
+		"queued":         cl.Queued(),
+		"admitted":       atomic.LoadInt64(&cl.admitted),
+		"waited":         atomic.LoadInt64(&cl.waited),
+		"rejected":       atomic.LoadInt64(&cl.rejected),
+		"timed_out":      atomic.LoadInt64(&cl.timedOut),
+	}
+}
+
+// clusterConcurrencyEntry 单个簇的并发限制器
+type clusterConcurrencyEntry struct {
+	limiter  *ConcurrencyLimiter
+	lastSeen time.Time
+}
+
+// ClusterConcurrency 按簇的并发限制，每个簇独立计算在途请求，限流策略中的concurrency覆盖配置
+type ClusterConcurrency struct {
+	config      *types.ClusterConcurrencyConfig
+	idleTTL     time.Duration
+	limiters    map[string]*clusterConcurrencyEntry
+	overrides   map[string]types.ConcurrencyLimitConfig // 簇ID -> 策略下发的并发限制
+	lastCleanup time.Time
+	rejected    int64
+	timedOut    int64
+	mutex       sync.Mutex
+}
+
+// NewClusterConcurrency 创建按簇的并发限制
+func NewClusterConcurrency(config *types.ClusterConcurrencyConfig) (*ClusterConcurrency, error) {
+	limits := map[string]types.ConcurrencyLimitConfig{"default": config.Default}
+	for clusterID, limit := range config.Clusters {
+		limits[clusterID] = limit
+	}
+	for clusterID, limit := range limits {
+		if limit.MaxConcurrent == 0 {
+			continue
+		}
+		if _, err := NewConcurrencyLimiter(&limit); err != nil {
+			return nil, fmt.Errorf("invalid concurrency limit for %s: %v", clusterID, err)
+		}
+	}
+
+	cc := &ClusterConcurrency{
+		config:      config,
+		idleTTL:     config.IdleTTL,
+		limiters:    make(map[string]*clusterConcurrencyEntry),
+		overrides:   make(map[string]types.ConcurrencyLimitConfig),
+		lastCleanup: time.Now(),
+	}
+	if cc.idleTTL <= 0 {
+		cc.idleTTL = perKeyIdleTTL
+	}
+	return cc, nil
+}
+
+// Acquire 获取簇的空位，簇未配置并发限制时直接返回
+func (cc *ClusterConcurrency) Acquire(ctx context.Context, clusterID string) (func(), error) {
+	limiter := cc.limiter(clusterID)
+	if limiter == nil {
+		return func() {}, nil
+	}
+
+	release, err := limiter.Acquire(ctx)
+	switch err {
+	case ErrConcurrencyQueueFull:
+		atomic.AddInt64(&cc.rejected, 1)
+	case ErrConcurrencyQueueTimeout:
+		atomic.AddInt64(&cc.timedOut, 1)
+	}
+	return release, err
+}
+
+// UpdatePolicy 按限流策略设置簇的并发限制，策略未携带concurrency时恢复配置的限制
+func (cc *ClusterConcurrency) UpdatePolicy(clusterID string, policy *types.Policy) error {
+	if policy.PolicyType != types.PolicyTypeRateLimit || policy.RateLimit == nil {
+		return nil
+	}
+	if policy.RateLimit.Concurrency == nil {
+		cc.RemovePolicy(clusterID)
+		return nil
+	}
+
+	limit := *policy.RateLimit.Concurrency
+	if limit.MaxConcurrent != 0 {
+		if _, err := NewConcurrencyLimiter(&limit); err != nil {
+			return fmt.Errorf("invalid concurrency limit for cluster %s: %v", clusterID, err)
+		}
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if existing, exists := cc.overrides[clusterID]; exists && existing == limit {
+		return nil
+	}
+	cc.overrides[clusterID] = limit
+	// 已获得空位的请求释放到原限制器，新限制器从空开始计数
+	delete(cc.limiters, clusterID)
+
+	log.Printf("Updated concurrency limit for cluster %s: max_concurrent=%d, max_queue=%d",
+		clusterID, limit.MaxConcurrent, limit.MaxQueue)
+	return nil
+}
+
+// RemovePolicy 删除策略下发的并发限制，恢复配置的限制
+func (cc *ClusterConcurrency) RemovePolicy(clusterID string) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if _, exists := cc.overrides[clusterID]; !exists {
+		return
+	}
+	delete(cc.overrides, clusterID)
+	delete(cc.limiters, clusterID)
+	log.Printf("Removed concurrency limit override for cluster %s", clusterID)
+}
+
+// Stats 获取统计信息
+func (cc *ClusterConcurrency) Stats() map[string]interface{} {
+	cc.mutex.Lock()
+	clusters := make(map[string]interface{}, len(cc.limiters))
+	for clusterID, entry := range cc.limiters {
+		clusters[clusterID] = entry.limiter.Stats()
+	}
+	overrides := len(cc.overrides)
+	cc.mutex.Unlock()
+
+	return map[string]interface{}{
+		"clusters":  clusters,
+		"overrides": overrides,
+		"rejected":  atomic.LoadInt64(&cc.rejected),
+		"timed_out": atomic.LoadInt64(&cc.timedOut),
+	}
+}
+
+// limiter 获取或创建簇的并发限制器，未配置并发限制时返回nil
+func (cc *ClusterConcurrency) limiter(clusterID string) *ConcurrencyLimiter {
+	now := time.Now()
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.cleanupLocked(now)
+
+	if entry, exists := cc.limiters[clusterID]; exists {
+		entry.lastSeen = now
+		return entry.limiter
+	}
+
+	limit, exists := cc.overrides[clusterID]
+	if !exists {
+		if limit, exists = cc.config.Clusters[clusterID]; !exists {
+			limit = cc.config.Default
+		}
+	}
+	if limit.MaxConcurrent == 0 {
+		return nil
+	}
+
+	limiter, err := NewConcurrencyLimiter(&limit)
+	if err != nil {
+		return nil // 配置已在创建和更新时校验
+	}
+	cc.limiters[clusterID] = &clusterConcurrencyEntry{limiter: limiter, lastSeen: now}
+	return limiter
+}
+
+// cleanupLocked 清理空闲且没有在途请求的簇，每个空闲周期最多执行一次，调用方需持有锁
+func (cc *ClusterConcurrency) cleanupLocked(now time.Time) {
+	if now.Sub(cc.lastCleanup) < cc.idleTTL {
+		return
+	}
+	cc.lastCleanup = now
+
+	for clusterID, entry := range cc.limiters {
+		if now.Sub(entry.lastSeen) > cc.idleTTL && entry.limiter.InFlight() == 0 && entry.limiter.Queued() == 0 {
+			delete(cc.limiters, clusterID)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// StateHandoff 限流器状态交接，通过Redis在副本之间传递令牌桶填充状态
+type StateHandoff struct {
+	client redis.UniversalClient
+	key    string
+	ttl    time.Duration
+}
+
+// NewStateHandoff 创建限流器状态交接
+func NewStateHandoff(redisConfig *types.RedisConfig, config *types.LimiterHandoffConfig) *StateHandoff {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:       redisConfig.Addresses,
+		Password:    redisConfig.Password,
+		DB:          redisConfig.DB,
+		PoolSize:    redisConfig.PoolSize,
+		DialTimeout: redisConfig.Timeout,
+	})
+
+	key := config.Key
+	if key == "" {
+		key = "gateway:limiter:handoff"
+	}
+
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = 2 * time.Minute
+	}
+
+	return &StateHandoff{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+	}
+}
+
+// Publish 发布令牌桶状态，同一簇已有状态时保留令牌更少的一份（偏保守）
+func (sh *StateHandoff) Publish(snapshots []types.BucketSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	existing, err := sh.load(ctx)
+	if err != nil {
+		log.Printf("Failed to read existing handoff state: %v", err)
+		existing = make(map[string]types.BucketSnapshot)
+	}
+
+	values := make(map[string]interface{}, len(snapshots))
+	for _, snapshot := range snapshots {
+		if prev, ok := existing[snapshot.ClusterID]; ok && prev.Tokens < snapshot.Tokens {
+			continue
+		}
+
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			continue
+		}
+		values[snapshot.ClusterID] = data
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	pipe := sh.client.TxPipeline()
+	pipe.HSet(ctx, sh.key, values)
+	pipe.Expire(ctx, sh.key, sh.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish limiter state: %v", err)
+	}
+
+	log.Printf("Published %d bucket states for handoff", len(values))
+	return nil
+}
+
+// Load 读取未过期的令牌桶状态
+func (sh *StateHandoff) Load() ([]types.BucketSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	states, err := sh.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]types.BucketSnapshot, 0, len(states))
+	for _, snapshot := range states {
+		if time.Since(snapshot.Timestamp) > sh.ttl {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// Close 关闭Redis连接
+func (sh *StateHandoff) Close() error {
+	return sh.client.Close()
+}
+
+// load 读取Redis中的全部状态
+func (sh *StateHandoff) load(ctx context.Context) (map[string]types.BucketSnapshot, error) {
+	values, err := sh.client.HGetAll(ctx, sh.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load limiter state: %v", err)
+	}
+
+	states := make(map[string]types.BucketSnapshot, len(values))
+	for clusterID, value := range values {
+		var snapshot types.BucketSnapshot
+		if err := json.Unmarshal([]byte(value), &snapshot); err != nil {
+			log.Printf("Skipping invalid handoff state for cluster %s: %v", clusterID, err)
+			continue
+		}
+		states[clusterID] = snapshot
+	}
+
+	return states, nil
+}
@@ -0,0 +1,163 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LeakyBucket 漏桶限流器：请求按固定间隔（1/速率）依次放行，到达过快的请求排队等待，
+// 排队数达到容量时拒绝。与令牌桶不同，上游在任意短时间内都不会看到超过速率的突发，
+// 适合按短时间窗口计费或限流的LLM服务
+type LeakyBucket struct {
+	capacity int64     // 同时等待放行的请求上限
+	rate     float64   // 每秒放行的请求数
+	next     time.Time // 下一个请求的放行时刻
+	mutex    sync.Mutex
+}
+
+// NewLeakyBucket 创建漏桶
+func NewLeakyBucket(capacity int64, rate float64) *LeakyBucket {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LeakyBucket{capacity: capacity, rate: rate, next: time.Now()}
+}
+
+// Allow 不等待，只有当前可以立即放行时才允许
+func (lb *LeakyBucket) Allow() bool {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	now := time.Now()
+	if lb.next.After(now) {
+		return false
+	}
+	lb.next = now.Add(lb.interval())
+	return true
+}
+
+// Reserve 预约一个放行时刻，返回需要等待的时长，排队已满时返回false
+func (lb *LeakyBucket) Reserve() (time.Duration, bool) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	now := time.Now()
+	if lb.next.Before(now) {
+		lb.next = now
+	}
+	delay := lb.next.Sub(now)
+	if lb.waiting(delay) >= lb.capacity {
+		return 0, false
+	}
+	lb.next = lb.next.Add(lb.interval())
+	return delay, true
+}
+
+// Wait 预约放行时刻并等待，排队已满或等待期间ctx结束时返回false。
+// ctx结束时已预约的时刻不归还，排在后面的请求不会因此提前放行，保证上游看到的速率不超过设定值
+func (lb *LeakyBucket) Wait(ctx context.Context) bool {
+	delay, ok := lb.Reserve()
+	if !ok {
+		return false
+	}
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SetRate 动态设置放行速率，已预约的请求不受影响
+func (lb *LeakyBucket) SetRate(rate float64) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	lb.rate = rate
+}
+
+// GetRate 获取放行速率
+func (lb *LeakyBucket) GetRate() float64 {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	return lb.rate
+}
+
+// GetTokens 获取剩余的排队位置
+func (lb *LeakyBucket) GetTokens() int64 {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	remaining := lb.capacity - lb.waiting(time.Until(lb.next))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// GetCapacity 获取排队上限
+func (lb *LeakyBucket) GetCapacity() int64 {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	return lb.capacity
+}
+
+// SetCapacity 设置排队上限
+func (lb *LeakyBucket) SetCapacity(capacity int64) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if capacity < 1 {
+		capacity = 1
+	}
+	lb.capacity = capacity
+}
+
+// Restore 按快照恢复剩余排队位置，快照之后的时间按速率放行
+func (lb *LeakyBucket) Restore(tokens int64, at time.Time) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if tokens < 0 {
+		tokens = 0
+	}
+	if tokens > lb.capacity {
+		tokens = lb.capacity
+	}
+	if at.IsZero() || at.After(time.Now()) {
+		at = time.Now()
+	}
+
+	lb.next = at
+	if waiting := lb.capacity - tokens; waiting > 0 {
+		lb.next = at.Add(time.Duration(waiting+1) * lb.interval())
+	}
+}
+
+// interval 相邻两个请求的放行间隔（内部方法，需要加锁调用）
+func (lb *LeakyBucket) interval() time.Duration {
+	if lb.rate <= 0 {
+		return time.Second
+	}
+	return time.Duration(float64(time.Second) / lb.rate)
+}
+
+// waiting 新请求需要等待delay时，排在它前面仍在等待放行的请求数（内部方法，需要加锁调用）；
+// 最近放行的请求占用一个间隔，不计入
+func (lb *LeakyBucket) waiting(delay time.Duration) int64 {
+	if delay <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(delay)/float64(lb.interval()))) - 1
+}
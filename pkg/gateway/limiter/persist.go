@@ -0,0 +1,217 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// KeyStatePersister API密钥令牌桶和配额消耗的持久化，定期写入Redis，启动时合并恢复，
+// 滚动发布时客户的速率预算和配额不会被重置
+type KeyStatePersister struct {
+	client   redis.UniversalClient
+	key      string
+	interval time.Duration
+	ttl      time.Duration
+	limiter  *KeyLimiter
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewKeyStatePersister 创建API密钥状态持久化
+func NewKeyStatePersister(redisConfig *types.RedisConfig, config *types.APIKeyLimitConfig, limiter *KeyLimiter) *KeyStatePersister {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:       redisConfig.Addresses,
+		Password:    redisConfig.Password,
+		DB:          redisConfig.DB,
+		PoolSize:    redisConfig.PoolSize,
+		DialTimeout: redisConfig.Timeout,
+	})
+
+	key := config.Persistence.Key
+	if key == "" {
+		key = "gateway:limiter:apikeys"
+	}
+
+	interval := config.Persistence.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	// 状态至少保留一个配额周期
+	ttl := limiter.config.QuotaPeriod
+	if ttl < 10*interval {
+		ttl = 10 * interval
+	}
+
+	return &KeyStatePersister{
+		client:   client,
+		key:      key,
+		interval: interval,
+		ttl:      ttl,
+		limiter:  limiter,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 恢复已持久化的状态并启动定期持久化
+func (p *KeyStatePersister) Start() {
+	snapshots, err := p.Load()
+	if err != nil {
+		log.Printf("Failed to load api key limiter state: %v", err)
+	} else {
+		restored := p.limiter.Restore(snapshots)
+		log.Printf("Restored %d api key bucket states", restored)
+	}
+
+	p.wg.Add(1)
+	go p.persistLoop()
+}
+
+// Stop 停止定期持久化，写入最终状态后关闭Redis连接
+func (p *KeyStatePersister) Stop() {
+	p.once.Do(func() {
+		close(p.stopCh)
+		p.wg.Wait()
+
+		if err := p.Save(p.limiter.Snapshot()); err != nil {
+			log.Printf("Failed to persist api key limiter state: %v", err)
+		}
+		p.client.Close()
+	})
+}
+
+// Save 写入密钥状态，与Redis中其他副本写入的同一密钥状态合并：
+// 令牌取较少的一份，同一配额周期的消耗取较大的一份
+func (p *KeyStatePersister) Save(snapshots []types.KeyBucketSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fields := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		fields[i] = snapshot.KeyID
+	}
+
+	existing, err := p.client.HMGet(ctx, p.key, fields...).Result()
+	if err != nil {
+		log.Printf("Failed to read existing api key state: %v", err)
+		existing = nil
+	}
+
+	values := make(map[string]interface{}, len(snapshots))
+	for i, snapshot := range snapshots {
+		if i < len(existing) {
+			if raw, ok := existing[i].(string); ok {
+				var prev types.KeyBucketSnapshot
+				if err := json.Unmarshal([]byte(raw), &prev); err == nil {
+					snapshot = mergeKeySnapshot(snapshot, prev)
+				}
+			}
+		}
+
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			continue
+		}
+		values[snapshot.KeyID] = data
+	}
+
+	pipe := p.client.TxPipeline()
+	pipe.HSet(ctx, p.key, values)
+	pipe.Expire(ctx, p.key, p.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to persist api key state: %v", err)
+	}
+
+	return nil
+}
+
+// Load 读取配额周期未结束或令牌未补满的密钥状态
+func (p *KeyStatePersister) Load() ([]types.KeyBucketSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	values, err := p.client.HGetAll(ctx, p.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api key state: %v", err)
+	}
+
+	now := time.Now()
+	snapshots := make([]types.KeyBucketSnapshot, 0, len(values))
+	stale := make([]string, 0)
+	for keyID, value := range values {
+		var snapshot types.KeyBucketSnapshot
+		if err := json.Unmarshal([]byte(value), &snapshot); err != nil {
+			log.Printf("Skipping invalid api key state for %s: %v", keyID, err)
+			continue
+		}
+		if now.Sub(snapshot.Timestamp) > p.ttl {
+			stale = append(stale, keyID)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if len(stale) > 0 {
+		p.client.HDel(ctx, p.key, stale...)
+	}
+
+	return snapshots, nil
+}
+
+// persistLoop 定期持久化并清理空闲密钥
+func (p *KeyStatePersister) persistLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Save(p.limiter.Snapshot()); err != nil {
+				log.Printf("Failed to persist api key limiter state: %v", err)
+			}
+			p.limiter.Cleanup()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// mergeKeySnapshot 合并同一密钥的两份状态，偏保守
+func mergeKeySnapshot(current, prev types.KeyBucketSnapshot) types.KeyBucketSnapshot {
+	if prev.Capacity > 0 && refilledTokens(prev, current.Timestamp) < current.Tokens {
+		current.Tokens = refilledTokens(prev, current.Timestamp)
+	}
+	if prev.QuotaResetAt.Equal(current.QuotaResetAt) && prev.QuotaUsed > current.QuotaUsed {
+		current.QuotaUsed = prev.QuotaUsed
+	}
+	return current
+}
+
+// refilledTokens 按速率计算快照到指定时间的令牌数
+func refilledTokens(snapshot types.KeyBucketSnapshot, at time.Time) int64 {
+	elapsed := at.Sub(snapshot.Timestamp).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	tokens := snapshot.Tokens + int64(elapsed*snapshot.Rate)
+	if tokens > snapshot.Capacity {
+		tokens = snapshot.Capacity
+	}
+	return tokens
+}
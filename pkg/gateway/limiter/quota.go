@@ -0,0 +1,44 @@
+package limiter
+
+import (
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// recordQuota 在请求上下文的"rate_limit_quota"中记录限流器的检查结果：拒绝时记录拒绝的限流器，放行时记录剩余量最少的限流器
+func recordQuota(ctx *gin.Context, algorithm rateAlgorithm, allowed bool) {
+	quota := quotaOf(algorithm, allowed)
+	if existing, ok := ctx.Get("rate_limit_quota"); ok && allowed {
+		if previous, ok := existing.(types.RateLimitQuota); ok && previous.Remaining <= quota.Remaining {
+			return
+		}
+	}
+	ctx.Set("rate_limit_quota", quota)
+}
+
+// quotaOf 由限流器当前状态计算检查结果，各算法的剩余量都按速率恢复
+func quotaOf(algorithm rateAlgorithm, allowed bool) types.RateLimitQuota {
+	quota := types.RateLimitQuota{
+		Limit:     algorithm.GetCapacity(),
+		Remaining: algorithm.GetTokens(),
+	}
+	if quota.Remaining > quota.Limit {
+		quota.Remaining = quota.Limit
+	}
+
+	now := time.Now()
+	rate := algorithm.GetRate()
+	if rate <= 0 {
+		quota.Reset = now
+		return quota
+	}
+	quota.Reset = now.Add(time.Duration(float64(quota.Limit-quota.Remaining) / rate * float64(time.Second)))
+	if !allowed {
+		quota.RetryAfter = time.Duration(math.Max(1/rate, 0.001) * float64(time.Second))
+	}
+	return quota
+}
@@ -0,0 +1,268 @@
+package limiter
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// quotaSpec 配额树中一级的配额，已按上级收紧
+type quotaSpec struct {
+	quota    int64
+	rate     float64
+	burst    int64
+	children map[string]*quotaSpec // 服务名或路由名 -> 下级配额
+}
+
+// quotaNode 租户在某一级上的令牌桶和配额消耗
+type quotaNode struct {
+	scope    string
+	spec     *quotaSpec
+	bucket   *TokenBucket // 未配置速率时为nil
+	used     int64
+	resetAt  time.Time
+	lastSeen time.Time
+}
+
+// QuotaTree 租户分级配额：每个请求依次检查租户、服务、路由三级的配额和速率，
+// 所有层级都允许时才消耗，任一级拒绝时其他层级不受影响
+type QuotaTree struct {
+	period        time.Duration
+	idleTTL       time.Duration
+	tenants       map[string]*quotaSpec
+	nodes         map[string]*quotaNode // 层级路径 -> 状态
+	lastCleanup   time.Time
+	rateRejected  int64
+	quotaRejected int64
+	mutex         sync.Mutex
+}
+
+// NewQuotaTree 创建租户分级配额，下级的配额和速率超过上级时按上级收紧
+func NewQuotaTree(config *types.TenantQuotaConfig) (*QuotaTree, error) {
+	qt := &QuotaTree{
+		period:      config.Period,
+		idleTTL:     config.IdleTTL,
+		tenants:     make(map[string]*quotaSpec),
+		nodes:       make(map[string]*quotaNode),
+		lastCleanup: time.Now(),
+	}
+	if qt.period <= 0 {
+		qt.period = 24 * time.Hour
+	}
+	if qt.idleTTL <= 0 {
+		qt.idleTTL = time.Hour
+	}
+
+	for _, tenant := range config.Tenants {
+		if tenant.Tenant == "" {
+			return nil, fmt.Errorf("tenant quota requires a tenant or \"*\"")
+		}
+		if _, exists := qt.tenants[tenant.Tenant]; exists {
+			return nil, fmt.Errorf("duplicate quota for tenant %s", tenant.Tenant)
+		}
+		tenantScope := "tenant:" + tenant.Tenant
+		tenantSpec, err := newQuotaSpec(tenantScope, tenant.Quota, tenant.Rate, tenant.Burst, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, service := range tenant.Services {
+			if service.Service == "" {
+				return nil, fmt.Errorf("service quota of %s requires a service", tenantScope)
+			}
+			if _, exists := tenantSpec.children[service.Service]; exists {
+				return nil, fmt.Errorf("duplicate quota for service %s of %s", service.Service, tenantScope)
+			}
+			serviceScope := tenantScope + "/service:" + service.Service
+			serviceSpec, err := newQuotaSpec(serviceScope, service.Quota, service.Rate, service.Burst, tenantSpec)
+			if err != nil {
+				return nil, err
+			}
+			tenantSpec.children[service.Service] = serviceSpec
+
+			for _, route := range service.Routes {
+				if route.Route == "" {
+					return nil, fmt.Errorf("route quota of %s requires a route", serviceScope)
+				}
+				if _, exists := serviceSpec.children[route.Route]; exists {
+					return nil, fmt.Errorf("duplicate quota for route %s of %s", route.Route, serviceScope)
+				}
+				routeSpec, err := newQuotaSpec(serviceScope+"/route:"+route.Route, route.Quota, route.Rate, route.Burst, serviceSpec)
+				if err != nil {
+					return nil, err
+				}
+				serviceSpec.children[route.Route] = routeSpec
+			}
+		}
+
+		qt.tenants[tenant.Tenant] = tenantSpec
+	}
+
+	return qt, nil
+}
+
+// newQuotaSpec 校验一级配额并按上级收紧
+func newQuotaSpec(scope string, quota int64, rate float64, burst int64, parent *quotaSpec) (*quotaSpec, error) {
+	if quota < 0 || rate < 0 || burst < 0 {
+		return nil, fmt.Errorf("quota, rate and burst of %s must not be negative", scope)
+	}
+
+	spec := &quotaSpec{quota: quota, rate: rate, burst: burst, children: make(map[string]*quotaSpec)}
+	if spec.rate > 0 && spec.burst <= 0 {
+		spec.burst = int64(spec.rate)
+		if spec.burst < 1 {
+			spec.burst = 1
+		}
+	}
+
+	if parent != nil {
+		if parent.quota > 0 && spec.quota > parent.quota {
+			log.Printf("Quota of %s exceeds its parent, capped at %d", scope, parent.quota)
+			spec.quota = parent.quota
+		}
+		if parent.rate > 0 && spec.rate > parent.rate {
+			log.Printf("Rate of %s exceeds its parent, capped at %.2f", scope, parent.rate)
+			spec.rate = parent.rate
+		}
+		if parent.burst > 0 && spec.burst > parent.burst {
+			spec.burst = parent.burst
+		}
+	}
+	return spec, nil
+}
+
+// Allow 检查租户在服务和路由上的请求，服务或路由未配置配额时只检查已配置的上级；
+// 允许时返回剩余配额最少的层级，拒绝时返回拒绝请求的层级
+func (qt *QuotaTree) Allow(tenant, service, route string) types.QuotaDecision {
+	if tenant == "" {
+		return types.QuotaDecision{Allowed: true}
+	}
+	tenantSpec, exists := qt.tenants[tenant]
+	if !exists {
+		if tenantSpec, exists = qt.tenants["*"]; !exists {
+			return types.QuotaDecision{Allowed: true}
+		}
+	}
+
+	scopes := []string{"tenant:" + tenant}
+	specs := []*quotaSpec{tenantSpec}
+	if serviceSpec, ok := tenantSpec.children[service]; ok && service != "" {
+		scopes = append(scopes, scopes[0]+"/service:"+service)
+		specs = append(specs, serviceSpec)
+		if routeSpec, ok := serviceSpec.children[route]; ok && route != "" {
+			scopes = append(scopes, scopes[1]+"/route:"+route)
+			specs = append(specs, routeSpec)
+		}
+	}
+
+	now := time.Now()
+
+	qt.mutex.Lock()
+	defer qt.mutex.Unlock()
+
+	qt.cleanupLocked(now)
+
+	nodes := make([]*quotaNode, len(specs))
+	for i, spec := range specs {
+		nodes[i] = qt.nodeLocked(scopes[i], spec, now)
+	}
+
+	for _, node := range nodes {
+		if node.spec.quota > 0 && node.used >= node.spec.quota {
+			atomic.AddInt64(&qt.quotaRejected, 1)
+			return node.decision(false, KeyRejectQuota)
+		}
+	}
+	// 令牌桶只在本对象的锁内访问，先检查所有层级再消耗，避免上级被拒绝的请求消耗下级令牌
+	for _, node := range nodes {
+		if node.bucket != nil && node.bucket.GetTokens() < 1 {
+			atomic.AddInt64(&qt.rateRejected, 1)
+			return node.decision(false, KeyRejectRate)
+		}
+	}
+
+	var tightest *quotaNode
+	for _, node := range nodes {
+		if node.bucket != nil {
+			node.bucket.Allow()
+		}
+		node.used++
+		if node.spec.quota > 0 && (tightest == nil || node.spec.quota-node.used < tightest.spec.quota-tightest.used) {
+			tightest = node
+		}
+	}
+
+	if tightest == nil {
+		return types.QuotaDecision{Allowed: true, Scope: nodes[len(nodes)-1].scope}
+	}
+	return tightest.decision(true, "")
+}
+
+// Stats 获取统计信息
+func (qt *QuotaTree) Stats() map[string]interface{} {
+	qt.mutex.Lock()
+	nodes := len(qt.nodes)
+	qt.mutex.Unlock()
+
+	return map[string]interface{}{
+		"tenants":        len(qt.tenants),
+		"nodes":          nodes,
+		"rate_rejected":  atomic.LoadInt64(&qt.rateRejected),
+		"quota_rejected": atomic.LoadInt64(&qt.quotaRejected),
+	}
+}
+
+// nodeLocked 获取或创建层级状态并在配额周期结束时重置，调用方需持有锁
+func (qt *QuotaTree) nodeLocked(scope string, spec *quotaSpec, now time.Time) *quotaNode {
+	node, exists := qt.nodes[scope]
+	if !exists {
+		node = &quotaNode{scope: scope, spec: spec}
+		if spec.rate > 0 {
+			node.bucket = NewTokenBucket(spec.burst, spec.rate)
+		}
+		qt.nodes[scope] = node
+	}
+
+	if !now.Before(node.resetAt) {
+		node.used = 0
+		node.resetAt = now.UTC().Truncate(qt.period).Add(qt.period)
+	}
+	node.lastSeen = now
+	return node
+}
+
+// cleanupLocked 清理空闲且配额周期已结束的层级，每个空闲周期最多执行一次，调用方需持有锁
+func (qt *QuotaTree) cleanupLocked(now time.Time) {
+	if now.Sub(qt.lastCleanup) < qt.idleTTL {
+		return
+	}
+	qt.lastCleanup = now
+
+	for scope, node := range qt.nodes {
+		if now.Sub(node.lastSeen) > qt.idleTTL && !now.Before(node.resetAt) {
+			delete(qt.nodes, scope)
+		}
+	}
+}
+
+// decision 以该层级生成检查结果
+func (node *quotaNode) decision(allowed bool, reason string) types.QuotaDecision {
+	decision := types.QuotaDecision{
+		Allowed: allowed,
+		Reason:  reason,
+		Scope:   node.scope,
+		ResetAt: node.resetAt,
+	}
+	if node.spec.quota > 0 {
+		decision.Limit = node.spec.quota
+		decision.Remaining = node.spec.quota - node.used
+		if decision.Remaining < 0 {
+			decision.Remaining = 0
+		}
+	}
+	return decision
+}
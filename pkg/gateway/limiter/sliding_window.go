@@ -0,0 +1,140 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SlidingWindow 滑动窗口计数限流器：按上一个固定窗口的计数在当前窗口中的剩余占比加权估算滑动窗口内的请求数，
+// 任意一个窗口长度内放行的请求不超过速率×窗口长度，不像令牌桶那样允许满桶突发
+type SlidingWindow struct {
+	window   time.Duration
+	rate     float64   // 每秒请求数
+	limit    float64   // 窗口内的请求上限
+	start    time.Time // 当前固定窗口的起点
+	current  int64     // 当前固定窗口内的请求数
+	previous int64     // 上一个固定窗口内的请求数
+	mutex    sync.Mutex
+}
+
+// NewSlidingWindow 创建滑动窗口限流器
+func NewSlidingWindow(rate float64, window time.Duration) *SlidingWindow {
+	sw := &SlidingWindow{window: window, start: time.Now()}
+	sw.setRate(rate)
+	return sw
+}
+
+// Allow 检查是否允许请求
+func (sw *SlidingWindow) Allow() bool {
+	return sw.AllowN(1)
+}
+
+// AllowN 检查是否允许N个请求
+func (sw *SlidingWindow) AllowN(n int64) bool {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	now := time.Now()
+	sw.advance(now)
+	if sw.estimate(now)+float64(n) > sw.limit {
+		return false
+	}
+	sw.current += n
+	return true
+}
+
+// SetRate 动态设置速率，窗口内的请求上限随之调整，已计数的请求保留
+func (sw *SlidingWindow) SetRate(rate float64) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	sw.setRate(rate)
+}
+
+// setRate 设置速率（内部方法，需要加锁调用），上限至少为1
+func (sw *SlidingWindow) setRate(rate float64) {
+	sw.rate = rate
+	sw.limit = math.Max(1, rate*sw.window.Seconds())
+}
+
+// GetRate 获取当前速率
+func (sw *SlidingWindow) GetRate() float64 {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	return sw.rate
+}
+
+// GetTokens 获取窗口内剩余可放行的请求数
+func (sw *SlidingWindow) GetTokens() int64 {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	now := time.Now()
+	sw.advance(now)
+	remaining := int64(sw.limit - sw.estimate(now))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// GetCapacity 获取窗口内的请求上限
+func (sw *SlidingWindow) GetCapacity() int64 {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	return int64(sw.limit)
+}
+
+// GetWindow 获取窗口长度
+func (sw *SlidingWindow) GetWindow() time.Duration {
+	return sw.window
+}
+
+// Restore 按快照恢复剩余请求数，已用的请求视为发生在快照时刻所在的窗口内
+func (sw *SlidingWindow) Restore(tokens int64, at time.Time) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	if at.IsZero() || at.After(time.Now()) {
+		at = time.Now()
+	}
+
+	used := int64(sw.limit) - tokens
+	if used < 0 {
+		used = 0
+	}
+
+	sw.start = at
+	sw.current = used
+	sw.previous = 0
+	sw.advance(time.Now())
+}
+
+// advance 滚动固定窗口（内部方法，需要加锁调用），跨过一个以上窗口时上一窗口计数清零
+func (sw *SlidingWindow) advance(now time.Time) {
+	elapsed := now.Sub(sw.start)
+	if elapsed < sw.window {
+		return
+	}
+
+	windows := elapsed / sw.window
+	if windows == 1 {
+		sw.previous = sw.current
+	} else {
+		sw.previous = 0
+	}
+	sw.current = 0
+	sw.start = sw.start.Add(windows * sw.window)
+}
+
+// estimate 估算滑动窗口内的请求数（内部方法，需要加锁调用）
+func (sw *SlidingWindow) estimate(now time.Time) float64 {
+	weight := 1 - float64(now.Sub(sw.start))/float64(sw.window)
+	if weight < 0 {
+		weight = 0
+	}
+	return float64(sw.previous)*weight + float64(sw.current)
+}
@@ -125,6 +125,9 @@ func (tb *TokenBucket) IsFull() bool {
 
 // GetCapacity 获取桶容量
 func (tb *TokenBucket) GetCapacity() int64 {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
 	return tb.capacity
 }
 
@@ -138,3 +141,24 @@ func (tb *TokenBucket) SetCapacity(capacity int64) {
 		tb.tokens = capacity
 	}
 }
+
+// Restore 按快照恢复令牌数，快照之后的时间按速率补充
+func (tb *TokenBucket) Restore(tokens int64, at time.Time) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	if tokens < 0 {
+		tokens = 0
+	}
+	if tokens > tb.capacity {
+		tokens = tb.capacity
+	}
+
+	if at.IsZero() || at.After(time.Now()) {
+		at = time.Now()
+	}
+
+	tb.tokens = tokens
+	tb.lastRefill = at
+	tb.refill()
+}
@@ -0,0 +1,227 @@
+package limiter
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 令牌限流维度
+const (
+	TokenDimensionCluster = "cluster"
+	TokenDimensionAPIKey  = "api_key"
+	TokenDimensionModel   = "model"
+)
+
+// TokenSubject 一次LLM请求的令牌限流维度取值，为空的维度不参与限流
+type TokenSubject struct {
+	ClusterID string
+	KeyID     string
+	Model     string
+}
+
+// tokenBucket 按每分钟令牌数补充的令牌桶，允许欠账：请求完成后按实际用量补扣，余额为负时拒绝后续请求
+type tokenBucket struct {
+	tpm        int64
+	tokens     float64
+	lastRefill time.Time
+	lastSeen   time.Time
+}
+
+// refill 按经过的时间补充令牌，容量为一分钟的令牌数
+func (tb *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(tb.lastRefill).Seconds()
+	if elapsed > 0 {
+		tb.tokens = math.Min(float64(tb.tpm), tb.tokens+elapsed*float64(tb.tpm)/60)
+		tb.lastRefill = now
+	}
+}
+
+// retryAfter 余额补足n个令牌需要等待的时间
+func (tb *tokenBucket) retryAfter(n int64) time.Duration {
+	need := math.Min(float64(n), float64(tb.tpm)) - tb.tokens
+	if need <= 0 {
+		return 0
+	}
+	return time.Duration(need / (float64(tb.tpm) / 60) * float64(time.Second))
+}
+
+// TokenLimiter 按每分钟令牌数（TPM）限流，簇、API密钥、模型三个维度分别计量，
+// 请求前按提示词令牌数检查并预扣，完成后按实际用量补扣
+type TokenLimiter struct {
+	config      types.TokenLimitConfig
+	buckets     map[string]*tokenBucket // "<维度>:<取值>" -> 令牌桶
+	lastCleanup time.Time
+	allowed     int64
+	rejected    int64
+	consumed    int64
+	mutex       sync.Mutex
+}
+
+// NewTokenLimiter 创建令牌限流器
+func NewTokenLimiter(config *types.TokenLimitConfig) *TokenLimiter {
+	cfg := *config
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = 10 * time.Minute
+	}
+
+	return &TokenLimiter{
+		config:      cfg,
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow 检查所有维度的余额是否足够n个令牌，足够时在所有维度预扣；
+// 不足时返回被拒绝的维度和建议的重试等待时间
+func (tl *TokenLimiter) Allow(subject TokenSubject, n int64) (bool, string, time.Duration) {
+	now := time.Now()
+
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+
+	tl.cleanupLocked(now)
+
+	buckets := tl.bucketsLocked(subject, now)
+	for _, entry := range buckets {
+		// 单次请求超过桶容量时只要求桶满，避免大请求永远无法通过
+		if entry.bucket.tokens < math.Min(float64(n), float64(entry.bucket.tpm)) {
+			atomic.AddInt64(&tl.rejected, 1)
+			return false, entry.dimension, entry.bucket.retryAfter(n)
+		}
+	}
+
+	for _, entry := range buckets {
+		entry.bucket.tokens -= float64(n)
+	}
+	atomic.AddInt64(&tl.allowed, 1)
+	atomic.AddInt64(&tl.consumed, n)
+	return true, "", 0
+}
+
+// Consume 按实际用量补扣令牌，n为负时退还多预扣的令牌
+func (tl *TokenLimiter) Consume(subject TokenSubject, n int64) {
+	if n == 0 {
+		return
+	}
+	now := time.Now()
+
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+
+	for _, entry := range tl.bucketsLocked(subject, now) {
+		entry.bucket.tokens = math.Min(float64(entry.bucket.tpm), entry.bucket.tokens-float64(n))
+	}
+	atomic.AddInt64(&tl.consumed, n)
+}
+
+// Remaining 获取各维度中最少的剩余令牌数，没有限流维度时返回-1
+func (tl *TokenLimiter) Remaining(subject TokenSubject) int64 {
+	now := time.Now()
+
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+
+	remaining := int64(-1)
+	for _, entry := range tl.bucketsLocked(subject, now) {
+		tokens := int64(entry.bucket.tokens)
+		if tokens < 0 {
+			tokens = 0
+		}
+		if remaining < 0 || tokens < remaining {
+			remaining = tokens
+		}
+	}
+	return remaining
+}
+
+// Stats 获取统计信息
+func (tl *TokenLimiter) Stats() map[string]interface{} {
+	tl.mutex.Lock()
+	buckets := len(tl.buckets)
+	tl.mutex.Unlock()
+
+	return map[string]interface{}{
+		"buckets":         buckets,
+		"allowed":         atomic.LoadInt64(&tl.allowed),
+		"rejected":        atomic.LoadInt64(&tl.rejected),
+		"tokens_consumed": atomic.LoadInt64(&tl.consumed),
+	}
+}
+
+// dimensionBucket 维度及其令牌桶
+type dimensionBucket struct {
+	dimension string
+	bucket    *tokenBucket
+}
+
+// bucketsLocked 获取请求涉及的令牌桶并补充令牌，未配置TPM的维度跳过（需要加锁调用）
+func (tl *TokenLimiter) bucketsLocked(subject TokenSubject, now time.Time) []dimensionBucket {
+	buckets := make([]dimensionBucket, 0, 3)
+	add := func(dimension, value string, tpm int64) {
+		if value == "" || tpm <= 0 {
+			return
+		}
+
+		key := dimension + ":" + value
+		bucket, exists := tl.buckets[key]
+		if !exists {
+			bucket = &tokenBucket{tpm: tpm, tokens: float64(tpm), lastRefill: now}
+			tl.buckets[key] = bucket
+		}
+		bucket.tpm = tpm
+		bucket.refill(now)
+		bucket.lastSeen = now
+		buckets = append(buckets, dimensionBucket{dimension: dimension, bucket: bucket})
+	}
+
+	add(TokenDimensionCluster, subject.ClusterID, lookupTPM(tl.config.Clusters, subject.ClusterID, false))
+	add(TokenDimensionAPIKey, subject.KeyID, lookupTPM(tl.config.APIKeys, subject.KeyID, false))
+	add(TokenDimensionModel, subject.Model, lookupTPM(tl.config.Models, subject.Model, true))
+	return buckets
+}
+
+// cleanupLocked 定期清理空闲的令牌桶（需要加锁调用）
+func (tl *TokenLimiter) cleanupLocked(now time.Time) {
+	if now.Sub(tl.lastCleanup) < tl.config.IdleTTL {
+		return
+	}
+	tl.lastCleanup = now
+
+	for key, bucket := range tl.buckets {
+		if now.Sub(bucket.lastSeen) > tl.config.IdleTTL {
+			delete(tl.buckets, key)
+		}
+	}
+}
+
+// lookupTPM 查找取值对应的TPM，先精确匹配，允许时再按最长前缀匹配"xxx*"，最后使用"*"
+func lookupTPM(limits map[string]int64, value string, prefix bool) int64 {
+	if value == "" || len(limits) == 0 {
+		return 0
+	}
+	if tpm, ok := limits[value]; ok {
+		return tpm
+	}
+
+	if prefix {
+		best, tpm := -1, int64(0)
+		for pattern, limit := range limits {
+			if pattern == "*" || !strings.HasSuffix(pattern, "*") {
+				continue
+			}
+			if p := strings.TrimSuffix(pattern, "*"); strings.HasPrefix(value, p) && len(p) > best {
+				best, tpm = len(p), limit
+			}
+		}
+		if best >= 0 {
+			return tpm
+		}
+	}
+
+	return limits["*"]
+}
@@ -0,0 +1,129 @@
+// Package listener 网关监听器，支持SO_REUSEPORT和监听FD交接实现零停机重启
+package listener
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// EnvListenerFD 子进程继承的监听器FD环境变量
+const EnvListenerFD = "GATEWAY_LISTENER_FD"
+
+// inheritedFD 继承的监听器在子进程中的FD（ExtraFiles从3开始）
+const inheritedFD = 3
+
+// Address 校验监听网络和地址并拼接监听地址：host为IP时必须与网络的地址族一致，
+// IPv6地址可带或不带方括号；network为空时使用"tcp"
+func Address(network, host string, port int) (string, string, error) {
+	switch network {
+	case "":
+		network = "tcp"
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return "", "", fmt.Errorf("unsupported listen network %q", network)
+	}
+	if port < 0 || port > 65535 {
+		return "", "", fmt.Errorf("invalid listen port %d", port)
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		is4 := addr.Unmap().Is4()
+		if network == "tcp4" && !is4 {
+			return "", "", fmt.Errorf("listen address %s is not an IPv4 address", host)
+		}
+		if network == "tcp6" && is4 {
+			return "", "", fmt.Errorf("listen address %s is not an IPv6 address", host)
+		}
+	} else if strings.Contains(host, ":") {
+		return "", "", fmt.Errorf("invalid listen address %q: %v", host, err)
+	}
+
+	return network, net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// Listen 创建监听器：优先使用父进程交接的FD，其次按配置开启SO_REUSEPORT；
+// network为"tcp"且监听"::"时同时接受IPv4和IPv6连接，"tcp6"时仅接受IPv6
+func Listen(network, addr string, reusePort bool) (net.Listener, error) {
+	if ln, err := inherited(); ln != nil || err != nil {
+		return ln, err
+	}
+
+	lc := net.ListenConfig{}
+	if reusePort {
+		lc.Control = reusePortControl
+	}
+
+	ln, err := lc.Listen(context.Background(), network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %s: %v", network, addr, err)
+	}
+	return ln, nil
+}
+
+// Inherited 判断当前进程是否由升级流程启动
+func Inherited() bool {
+	return os.Getenv(EnvListenerFD) != ""
+}
+
+// inherited 从环境变量恢复父进程交接的监听器，未交接时返回nil
+func inherited() (net.Listener, error) {
+	value := os.Getenv(EnvListenerFD)
+	if value == "" {
+		return nil, nil
+	}
+	// 只使用一次，避免再次升级时孙进程误用
+	os.Unsetenv(EnvListenerFD)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", EnvListenerFD, err)
+	}
+
+	file := os.NewFile(uintptr(fd), "gateway-listener")
+	defer file.Close()
+
+	ln, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore inherited listener: %v", err)
+	}
+	return ln, nil
+}
+
+// Upgrade 以相同参数启动新进程并交接监听器，新旧进程共享同一监听队列，
+// 旧进程随后排空退出，期间不会拒绝新连接
+func Upgrade(ln net.Listener) (*os.Process, error) {
+	tcpListener, ok := ln.(*net.TCPListener)
+	if !ok {
+		return nil, fmt.Errorf("listener %T does not support fd handoff", ln)
+	}
+
+	file, err := tcpListener.File()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listener fd: %v", err)
+	}
+	defer file.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %v", err)
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", EnvListenerFD, inheritedFD))
+	cmd.ExtraFiles = []*os.File{file}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start new process: %v", err)
+	}
+	return cmd.Process, nil
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// OpenAI兼容端点
+const (
+	EndpointChatCompletions = "chat/completions"
+	EndpointCompletions     = "completions"
+	EndpointEmbeddings      = "embeddings"
+)
+
+// adapter 提供方协议适配，请求和响应均以OpenAI格式表示
+type adapter interface {
+	// newRequest 将OpenAI格式的请求转换为提供方请求
+	newRequest(ctx context.Context, endpoint, model string, body []byte) (*http.Request, error)
+	// convertResponse 将提供方的非流式响应体转换为OpenAI格式，错误响应同样转换
+	convertResponse(endpoint, model string, status int, body []byte) []byte
+	// convertStream 将提供方的SSE流转换为OpenAI格式的SSE流写出
+	convertStream(model string, src io.Reader, dst streamWriter) error
+}
+
+// streamWriter 流式响应输出，每个事件写出后立即刷新
+type streamWriter interface {
+	io.Writer
+	Flush()
+}
+
+// newAdapter 按提供方类型创建适配器
+func newAdapter(config *types.LLMProviderConfig, apiKey string) (adapter, error) {
+	switch config.Type {
+	case types.LLMProviderOpenAI, types.LLMProviderVLLM:
+		if config.BaseURL == "" {
+			return nil, fmt.Errorf("base_url is required")
+		}
+		return &openAIAdapter{baseURL: strings.TrimSuffix(config.BaseURL, "/"), apiKey: apiKey}, nil
+	case types.LLMProviderAzure:
+		if config.BaseURL == "" {
+			return nil, fmt.Errorf("base_url is required")
+		}
+		version := config.APIVersion
+		if version == "" {
+			version = "2024-02-01"
+		}
+		return &azureAdapter{
+			baseURL:     strings.TrimSuffix(config.BaseURL, "/"),
+			apiKey:      apiKey,
+			apiVersion:  version,
+			deployments: config.Deployments,
+		}, nil
+	case types.LLMProviderAnthropic:
+		return newAnthropicAdapter(config, apiKey), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", config.Type)
+	}
+}
+
+// openAIAdapter OpenAI及vLLM等OpenAI兼容服务，请求和响应原样透传
+type openAIAdapter struct {
+	baseURL string
+	apiKey  string
+}
+
+// newRequest 创建请求
+func (oa *openAIAdapter) newRequest(ctx context.Context, endpoint, model string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oa.baseURL+"/"+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if oa.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+oa.apiKey)
+	}
+	return req, nil
+}
+
+// convertResponse 原样返回
+func (oa *openAIAdapter) convertResponse(endpoint, model string, status int, body []byte) []byte {
+	return body
+}
+
+// convertStream 原样转发
+func (oa *openAIAdapter) convertStream(model string, src io.Reader, dst streamWriter) error {
+	return copyStream(src, dst)
+}
+
+// azureAdapter Azure OpenAI，按部署名路由，请求体中的model字段被忽略
+type azureAdapter struct {
+	baseURL     string
+	apiKey      string
+	apiVersion  string
+	deployments map[string]string
+}
+
+// newRequest 创建请求
+func (aa *azureAdapter) newRequest(ctx context.Context, endpoint, model string, body []byte) (*http.Request, error) {
+	deployment := model
+	if name, exists := aa.deployments[model]; exists {
+		deployment = name
+	}
+
+	target := fmt.Sprintf("%s/openai/deployments/%s/%s?api-version=%s",
+		aa.baseURL, url.PathEscape(deployment), endpoint, url.QueryEscape(aa.apiVersion))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if aa.apiKey != "" {
+		req.Header.Set("api-key", aa.apiKey)
+	}
+	return req, nil
+}
+
+// convertResponse Azure响应与OpenAI格式一致
+func (aa *azureAdapter) convertResponse(endpoint, model string, status int, body []byte) []byte {
+	return body
+}
+
+// convertStream 原样转发
+func (aa *azureAdapter) convertStream(model string, src io.Reader, dst streamWriter) error {
+	return copyStream(src, dst)
+}
+
+// copyStream 边读边写，每次读取后刷新
+func copyStream(src io.Reader, dst streamWriter) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			dst.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
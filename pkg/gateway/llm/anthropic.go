@@ -0,0 +1,318 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultAnthropicMaxTokens Anthropic要求max_tokens，OpenAI请求未指定时使用
+const defaultAnthropicMaxTokens = 1024
+
+// anthropicAdapter Anthropic Messages API，仅支持chat/completions
+type anthropicAdapter struct {
+	baseURL    string
+	apiKey     string
+	apiVersion string
+}
+
+// newAnthropicAdapter 创建Anthropic适配器
+func newAnthropicAdapter(config *types.LLMProviderConfig, apiKey string) *anthropicAdapter {
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	version := config.APIVersion
+	if version == "" {
+		version = "2023-06-01"
+	}
+	return &anthropicAdapter{baseURL: baseURL, apiKey: apiKey, apiVersion: version}
+}
+
+// chatRequest OpenAI chat/completions请求中需要转换的字段
+type chatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []chatMessage   `json:"messages"`
+	MaxTokens   int             `json:"max_tokens"`
+	Temperature *float64        `json:"temperature"`
+	TopP        *float64        `json:"top_p"`
+	Stop        json.RawMessage `json:"stop"`
+	Stream      bool            `json:"stream"`
+}
+
+// chatMessage OpenAI消息，content可为字符串或内容块数组
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text 提取消息文本，内容块数组中只保留文本块
+func (m *chatMessage) text() string {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// anthropicMessage Anthropic消息
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicRequest Anthropic Messages请求
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+// newRequest 将OpenAI chat请求转换为Anthropic Messages请求，system消息合并为system字段
+func (aa *anthropicAdapter) newRequest(ctx context.Context, endpoint, model string, body []byte) (*http.Request, error) {
+	if endpoint != EndpointChatCompletions {
+		return nil, &unsupportedError{endpoint: endpoint}
+	}
+
+	var in chatRequest
+	if err := json.Unmarshal(body, &in); err != nil {
+		return nil, fmt.Errorf("invalid chat request: %v", err)
+	}
+
+	out := anthropicRequest{
+		Model:       model,
+		MaxTokens:   in.MaxTokens,
+		Temperature: in.Temperature,
+		TopP:        in.TopP,
+		Stream:      in.Stream,
+	}
+	if out.MaxTokens <= 0 {
+		out.MaxTokens = defaultAnthropicMaxTokens
+	}
+
+	if len(in.Stop) > 0 {
+		var stop string
+		if err := json.Unmarshal(in.Stop, &stop); err == nil {
+			out.StopSequences = []string{stop}
+		} else {
+			json.Unmarshal(in.Stop, &out.StopSequences)
+		}
+	}
+
+	systems := make([]string, 0)
+	for i := range in.Messages {
+		message := &in.Messages[i]
+		switch message.Role {
+		case "system", "developer":
+			systems = append(systems, message.text())
+		case "assistant":
+			out.Messages = append(out.Messages, anthropicMessage{Role: "assistant", Content: message.text()})
+		default:
+			out.Messages = append(out.Messages, anthropicMessage{Role: "user", Content: message.text()})
+		}
+	}
+	out.System = strings.Join(systems, "\n")
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, aa.baseURL+"/v1/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", aa.apiVersion)
+	if aa.apiKey != "" {
+		req.Header.Set("x-api-key", aa.apiKey)
+	}
+	return req, nil
+}
+
+// anthropicResponse Anthropic Messages响应
+type anthropicResponse struct {
+	ID         string `json:"id"`
+	Model      string `json:"model"`
+	StopReason string `json:"stop_reason"`
+	Content    []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// convertResponse 将Anthropic响应转换为chat.completion，错误转换为OpenAI错误格式
+func (aa *anthropicAdapter) convertResponse(endpoint, model string, status int, body []byte) []byte {
+	var in anthropicResponse
+	if err := json.Unmarshal(body, &in); err != nil {
+		return errorBody(fmt.Sprintf("invalid upstream response: %v", err), "upstream_error", "")
+	}
+
+	if status/100 != 2 || in.Error != nil {
+		message, errType := http.StatusText(status), "upstream_error"
+		if in.Error != nil {
+			message, errType = in.Error.Message, in.Error.Type
+		}
+		return errorBody(message, errType, "")
+	}
+
+	texts := make([]string, 0, len(in.Content))
+	for _, block := range in.Content {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+
+	out, _ := json.Marshal(map[string]interface{}{
+		"id":      in.ID,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": strings.Join(texts, "")},
+			"finish_reason": finishReason(in.StopReason),
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     in.Usage.InputTokens,
+			"completion_tokens": in.Usage.OutputTokens,
+			"total_tokens":      in.Usage.InputTokens + in.Usage.OutputTokens,
+		},
+	})
+	return out
+}
+
+// anthropicEvent Anthropic流式事件中需要转换的字段
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		ID string `json:"id"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// convertStream 将Anthropic事件流转换为chat.completion.chunk事件流，以data: [DONE]结束
+func (aa *anthropicAdapter) convertStream(model string, src io.Reader, dst streamWriter) error {
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	id := ""
+	created := time.Now().Unix()
+	writeChunk := func(delta map[string]string, finish interface{}) error {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finish,
+			}},
+		})
+		if _, err := fmt.Fprintf(dst, "data: %s\n\n", chunk); err != nil {
+			return err
+		}
+		dst.Flush()
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line[5:])), &event); err != nil {
+			continue
+		}
+
+		var err error
+		switch event.Type {
+		case "message_start":
+			id = event.Message.ID
+			err = writeChunk(map[string]string{"role": "assistant"}, nil)
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				err = writeChunk(map[string]string{"content": event.Delta.Text}, nil)
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				err = writeChunk(map[string]string{}, finishReason(event.Delta.StopReason))
+			}
+		case "error":
+			message := "upstream stream error"
+			if event.Error != nil {
+				message = event.Error.Message
+			}
+			_, err = fmt.Fprintf(dst, "data: %s\n\n", errorBody(message, "upstream_error", ""))
+		case "message_stop":
+			if _, err := io.WriteString(dst, "data: [DONE]\n\n"); err != nil {
+				return err
+			}
+			dst.Flush()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// finishReason 将Anthropic的stop_reason映射为OpenAI的finish_reason
+func finishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return "stop"
+	}
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 超出上下文窗口时的动作
+const (
+	ContextActionReject   = "reject"
+	ContextActionTruncate = "truncate"
+)
+
+// contextGuard 上下文窗口检查
+type contextGuard struct {
+	action string
+	margin float64
+}
+
+// contextOverflow 请求超出模型上下文窗口，消息与OpenAI返回的context_length_exceeded一致
+type contextOverflow struct {
+	window     int
+	prompt     int
+	completion int
+}
+
+// Error 实现error接口
+func (e *contextOverflow) Error() string {
+	return fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens (%d in the messages, %d in the completion). Please reduce the length of the messages or completion.",
+		e.window, e.prompt+e.completion, e.prompt, e.completion)
+}
+
+// newContextGuard 创建上下文窗口检查
+func newContextGuard(config *types.ContextGuardConfig) (*contextGuard, error) {
+	action := config.Action
+	switch action {
+	case "":
+		action = ContextActionReject
+	case ContextActionReject, ContextActionTruncate:
+	default:
+		return nil, fmt.Errorf("unsupported context guard action %q", config.Action)
+	}
+
+	margin := config.SafetyMargin
+	if margin < 0 || margin >= 1 {
+		return nil, fmt.Errorf("context guard safety_margin must be in [0, 1)")
+	}
+	if margin == 0 {
+		margin = 0.05
+	}
+
+	return &contextGuard{action: action, margin: margin}, nil
+}
+
+// check 估算请求占用的令牌数并与上下文窗口比较，输出令牌取请求的max_tokens或max_completion_tokens，
+// 均未指定时取模型路由的默认max_tokens；截断模式下返回丢弃最早消息后的请求体和丢弃的消息数
+func (g *contextGuard) check(endpoint string, body []byte, window, defaultMaxTokens int) ([]byte, int, error) {
+	completion := 0
+	if endpoint != EndpointEmbeddings {
+		completion = requestedMaxTokens(body, defaultMaxTokens)
+	}
+
+	limit := int(float64(window) * (1 - g.margin))
+	prompt := CountPromptTokens(endpoint, body)
+	if prompt+completion <= limit {
+		return body, 0, nil
+	}
+
+	overflow := &contextOverflow{window: window, prompt: prompt, completion: completion}
+	if g.action != ContextActionTruncate || endpoint != EndpointChatCompletions {
+		return nil, 0, overflow
+	}
+
+	truncated, dropped, ok := truncateMessages(body, limit-completion)
+	if !ok {
+		return nil, 0, overflow
+	}
+	return truncated, dropped, nil
+}
+
+// requestedMaxTokens 请求的输出令牌上限
+func requestedMaxTokens(body []byte, defaultMaxTokens int) int {
+	var params struct {
+		MaxTokens           *int `json:"max_tokens"`
+		MaxCompletionTokens *int `json:"max_completion_tokens"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return defaultMaxTokens
+	}
+	switch {
+	case params.MaxCompletionTokens != nil:
+		return *params.MaxCompletionTokens
+	case params.MaxTokens != nil:
+		return *params.MaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// promptMessage 截断时解析的消息
+type promptMessage struct {
+	Role    string          `json:"role"`
+	Name    string          `json:"name"`
+	Content json.RawMessage `json:"content"`
+}
+
+// truncateMessages 从最早的非system消息开始丢弃，直到估算的输入令牌不超过budget；
+// system消息和最后一轮消息（最后一条及其前面连续的tool结果和发起调用的助手消息）始终保留，
+// 丢弃带tool_calls的消息时一并丢弃紧随其后的tool结果
+func truncateMessages(body []byte, budget int) ([]byte, int, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, 0, false
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(fields["messages"], &raw); err != nil || len(raw) == 0 {
+		return nil, 0, false
+	}
+
+	messages := make([]promptMessage, len(raw))
+	costs := make([]int, len(raw))
+	total := tokensReplyPrime
+	for i, item := range raw {
+		if err := json.Unmarshal(item, &messages[i]); err != nil {
+			return nil, 0, false
+		}
+		costs[i] = tokensPerMessage + EstimateTokens(messages[i].Role) + contentTokens(messages[i].Content)
+		if messages[i].Name != "" {
+			costs[i] += tokensPerName + EstimateTokens(messages[i].Name)
+		}
+		total += costs[i]
+	}
+
+	keep := make([]bool, len(raw))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	last := len(raw) - 1
+	for last > 0 && messages[last].Role == "tool" {
+		last--
+	}
+
+	dropped := 0
+	for i := 0; i < last && total > budget; i++ {
+		if !keep[i] || messages[i].Role == "system" {
+			continue
+		}
+		keep[i] = false
+		total -= costs[i]
+		dropped++
+
+		// 工具调用的结果不能脱离发起调用的助手消息单独存在
+		for j := i + 1; j < last && messages[j].Role == "tool"; j++ {
+			keep[j] = false
+			total -= costs[j]
+			dropped++
+		}
+	}
+	if total > budget {
+		return nil, 0, false
+	}
+
+	kept := make([]json.RawMessage, 0, len(raw)-dropped)
+	for i, item := range raw {
+		if keep[i] {
+			kept = append(kept, item)
+		}
+	}
+	fields["messages"], _ = json.Marshal(kept)
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, 0, false
+	}
+	return data, dropped, true
+}
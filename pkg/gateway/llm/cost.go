@@ -0,0 +1,424 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// costRetention Redis中花费记录的保留时间，覆盖完整的月周期
+const costRetention = 40 * 24 * time.Hour
+
+// CostSubject 一次请求的费用核算维度取值，为空的维度不累计
+type CostSubject struct {
+	KeyID  string
+	Tenant string
+	Model  string
+}
+
+// CostTracker LLM费用核算：按模型单价计算每次请求的花费，按API密钥、租户、模型累计日、月花费，
+// 开启持久化时定期把增量累加到Redis并读回所有副本的合计，预算检查使用合计值
+type CostTracker struct {
+	config   types.CostConfig
+	client   redis.UniversalClient // 未开启持久化时为nil
+	key      string
+	interval time.Duration
+	totals   map[string]map[string]float64 // 周期 -> "<维度>:<取值>" -> 花费（已持久化部分）
+	pending  map[string]map[string]float64 // 周期 -> "<维度>:<取值>" -> 尚未写入Redis的增量
+	mutex    sync.Mutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewCostTracker 创建费用核算
+func NewCostTracker(config *types.CostConfig, redisConfig *types.RedisConfig) *CostTracker {
+	ct := &CostTracker{
+		config:  *config,
+		totals:  make(map[string]map[string]float64),
+		pending: make(map[string]map[string]float64),
+		stopCh:  make(chan struct{}),
+	}
+
+	if config.Persistence.Enabled {
+		ct.client = redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:       redisConfig.Addresses,
+			Password:    redisConfig.Password,
+			DB:          redisConfig.DB,
+			PoolSize:    redisConfig.PoolSize,
+			DialTimeout: redisConfig.Timeout,
+		})
+		ct.key = config.Persistence.Key
+		if ct.key == "" {
+			ct.key = "gateway:llm:cost"
+		}
+		ct.interval = config.Persistence.Interval
+		if ct.interval <= 0 {
+			ct.interval = 30 * time.Second
+		}
+	}
+
+	return ct
+}
+
+// Start 读取已持久化的花费并启动定期持久化
+func (ct *CostTracker) Start() {
+	if ct.client == nil {
+		return
+	}
+
+	if err := ct.Flush(); err != nil {
+		log.Printf("Failed to load llm cost totals: %v", err)
+	}
+
+	ct.wg.Add(1)
+	go ct.flushLoop()
+}
+
+// Stop 写入剩余增量后关闭Redis连接
+func (ct *CostTracker) Stop() {
+	if ct.client == nil {
+		return
+	}
+
+	ct.once.Do(func() {
+		close(ct.stopCh)
+		ct.wg.Wait()
+		if err := ct.Flush(); err != nil {
+			log.Printf("Failed to persist llm cost totals: %v", err)
+		}
+		ct.client.Close()
+	})
+}
+
+// Price 计算请求花费，模型未配置单价时为0
+func (ct *CostTracker) Price(model string, promptTokens, completionTokens int64) float64 {
+	pricing, ok := lookupPricing(ct.config.Pricing, model)
+	if !ok {
+		return 0
+	}
+	return float64(promptTokens)/1000*pricing.PromptPer1K + float64(completionTokens)/1000*pricing.CompletionPer1K
+}
+
+// Record 计算并累计请求花费
+func (ct *CostTracker) Record(subject CostSubject, promptTokens, completionTokens int64) float64 {
+	cost := ct.Price(subject.Model, promptTokens, completionTokens)
+	if cost <= 0 {
+		return 0
+	}
+
+	now := time.Now().UTC()
+
+	day, month := periodKey(types.CostPeriodDay, now), periodKey(types.CostPeriodMonth, now)
+	deltas := make(map[string]float64)
+	for _, field := range subjectFields(subject) {
+		deltas[field] = cost
+	}
+
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	// 未开启持久化时直接计入本地合计
+	if ct.client == nil {
+		ct.pruneLocked(map[string]bool{day: true, month: true})
+		ct.mergeLocked(day, deltas)
+		ct.mergeLocked(month, deltas)
+		return cost
+	}
+
+	ct.mergePendingLocked(day, deltas)
+	ct.mergePendingLocked(month, deltas)
+	return cost
+}
+
+// CheckBudget 检查请求涉及的维度是否已超出预算，返回第一个超出的预算
+func (ct *CostTracker) CheckBudget(subject CostSubject) (*types.CostBudgetConfig, float64, bool) {
+	return ct.ExceedsBudget(subject, 0)
+}
+
+// ExceedsBudget 检查计入尚未核算的花费extra后是否超出预算，用于流式响应中途检查
+func (ct *CostTracker) ExceedsBudget(subject CostSubject, extra float64) (*types.CostBudgetConfig, float64, bool) {
+	now := time.Now().UTC()
+
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	for i := range ct.config.Budgets {
+		budget := &ct.config.Budgets[i]
+		value := subjectValue(subject, budget.Scope)
+		if value == "" || (budget.ID != "*" && budget.ID != value) {
+			continue
+		}
+
+		period := periodKey(budgetPeriod(budget), now)
+		field := budget.Scope + ":" + value
+		spent := ct.totals[period][field] + ct.pending[period][field] + extra
+		if spent >= budget.Limit {
+			return budget, spent, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// Spend 获取当前日、月周期的花费，维度为空时返回所有维度
+func (ct *CostTracker) Spend(scope string) map[string]interface{} {
+	now := time.Now().UTC()
+	day, month := periodKey(types.CostPeriodDay, now), periodKey(types.CostPeriodMonth, now)
+
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	collect := func(period string) map[string]float64 {
+		spend := make(map[string]float64)
+		for _, source := range []map[string]float64{ct.totals[period], ct.pending[period]} {
+			for field, cost := range source {
+				if scope == "" || strings.HasPrefix(field, scope+":") {
+					spend[field] += cost
+				}
+			}
+		}
+		return spend
+	}
+
+	return map[string]interface{}{
+		"day":         day,
+		"month":       month,
+		"day_spend":   collect(day),
+		"month_spend": collect(month),
+	}
+}
+
+// Stats 获取统计信息
+func (ct *CostTracker) Stats() map[string]interface{} {
+	now := time.Now().UTC()
+	day, month := periodKey(types.CostPeriodDay, now), periodKey(types.CostPeriodMonth, now)
+
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	total := func(period string) float64 {
+		sum := 0.0
+		for field, cost := range ct.totals[period] {
+			if strings.HasPrefix(field, types.CostScopeModel+":") {
+				sum += cost
+			}
+		}
+		for field, cost := range ct.pending[period] {
+			if strings.HasPrefix(field, types.CostScopeModel+":") {
+				sum += cost
+			}
+		}
+		return sum
+	}
+
+	return map[string]interface{}{
+		"day_spend_usd":   total(day),
+		"month_spend_usd": total(month),
+		"persisted":       ct.client != nil,
+	}
+}
+
+// Flush 将增量累加到Redis并读回所有副本的合计
+func (ct *CostTracker) Flush() error {
+	now := time.Now().UTC()
+	current := map[string]bool{
+		periodKey(types.CostPeriodDay, now):   true,
+		periodKey(types.CostPeriodMonth, now): true,
+	}
+
+	if ct.client == nil {
+		return nil
+	}
+
+	ct.mutex.Lock()
+	pending := ct.pending
+	ct.pending = make(map[string]map[string]float64)
+	ct.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipe := ct.client.Pipeline()
+	for period, deltas := range pending {
+		key := ct.key + ":" + period
+		for field, delta := range deltas {
+			pipe.HIncrByFloat(ctx, key, field, delta)
+		}
+		pipe.Expire(ctx, key, costRetention)
+	}
+	reads := make(map[string]*redis.MapStringStringCmd, len(current))
+	for period := range current {
+		reads[period] = pipe.HGetAll(ctx, ct.key+":"+period)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		// 写入失败时保留增量，下次重试
+		ct.mutex.Lock()
+		for period, deltas := range pending {
+			ct.mergePendingLocked(period, deltas)
+		}
+		ct.mutex.Unlock()
+		return err
+	}
+
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	for period, cmd := range reads {
+		totals := make(map[string]float64)
+		for field, value := range cmd.Val() {
+			if cost, err := strconv.ParseFloat(value, 64); err == nil {
+				totals[field] = cost
+			}
+		}
+		ct.totals[period] = totals
+	}
+	ct.pruneLocked(current)
+	return nil
+}
+
+// flushLoop 定期持久化
+func (ct *CostTracker) flushLoop() {
+	defer ct.wg.Done()
+
+	ticker := time.NewTicker(ct.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ct.Flush(); err != nil {
+				log.Printf("Failed to persist llm cost totals: %v", err)
+			}
+		case <-ct.stopCh:
+			return
+		}
+	}
+}
+
+// mergeLocked 将增量并入本地合计（需要加锁调用）
+func (ct *CostTracker) mergeLocked(period string, deltas map[string]float64) {
+	totals := ct.totals[period]
+	if totals == nil {
+		totals = make(map[string]float64)
+		ct.totals[period] = totals
+	}
+	for field, delta := range deltas {
+		totals[field] += delta
+	}
+}
+
+// mergePendingLocked 将增量放回待写入队列（需要加锁调用）
+func (ct *CostTracker) mergePendingLocked(period string, deltas map[string]float64) {
+	current := ct.pending[period]
+	if current == nil {
+		current = make(map[string]float64)
+		ct.pending[period] = current
+	}
+	for field, delta := range deltas {
+		current[field] += delta
+	}
+}
+
+// pruneLocked 清理已结束周期的本地合计（需要加锁调用）
+func (ct *CostTracker) pruneLocked(current map[string]bool) {
+	for period := range ct.totals {
+		if !current[period] {
+			delete(ct.totals, period)
+		}
+	}
+}
+
+// validateCostConfig 校验预算配置
+func validateCostConfig(config *types.CostConfig) error {
+	for i, budget := range config.Budgets {
+		switch budget.Scope {
+		case types.CostScopeAPIKey, types.CostScopeTenant, types.CostScopeModel:
+		default:
+			return fmt.Errorf("budget %d has unknown scope %q", i, budget.Scope)
+		}
+		if budget.ID == "" {
+			return fmt.Errorf("budget %d requires an id or \"*\"", i)
+		}
+		if budget.Limit <= 0 {
+			return fmt.Errorf("budget %d requires a positive limit", i)
+		}
+		if budget.Period != "" && budget.Period != types.CostPeriodDay && budget.Period != types.CostPeriodMonth {
+			return fmt.Errorf("budget %d has unknown period %q", i, budget.Period)
+		}
+	}
+	return nil
+}
+
+// periodKey 周期标识，如"day:20240105"、"month:202401"
+func periodKey(period string, now time.Time) string {
+	if period == types.CostPeriodDay {
+		return "day:" + now.Format("20060102")
+	}
+	return "month:" + now.Format("200601")
+}
+
+// budgetPeriod 预算周期，默认按月
+func budgetPeriod(budget *types.CostBudgetConfig) string {
+	if budget.Period == types.CostPeriodDay {
+		return types.CostPeriodDay
+	}
+	return types.CostPeriodMonth
+}
+
+// subjectFields 请求需要累计的字段
+func subjectFields(subject CostSubject) []string {
+	fields := make([]string, 0, 3)
+	for _, scope := range []string{types.CostScopeAPIKey, types.CostScopeTenant, types.CostScopeModel} {
+		if value := subjectValue(subject, scope); value != "" {
+			fields = append(fields, scope+":"+value)
+		}
+	}
+	return fields
+}
+
+// subjectValue 维度取值
+func subjectValue(subject CostSubject, scope string) string {
+	switch scope {
+	case types.CostScopeAPIKey:
+		return subject.KeyID
+	case types.CostScopeTenant:
+		return subject.Tenant
+	case types.CostScopeModel:
+		return subject.Model
+	}
+	return ""
+}
+
+// lookupPricing 查找模型单价，先精确匹配，再按最长前缀匹配"xxx*"，最后使用"*"
+func lookupPricing(pricing map[string]types.ModelPricing, model string) (types.ModelPricing, bool) {
+	if p, ok := pricing[model]; ok {
+		return p, true
+	}
+
+	best := -1
+	var matched types.ModelPricing
+	for pattern, p := range pricing {
+		if pattern == "*" || !strings.HasSuffix(pattern, "*") {
+			continue
+		}
+		if prefix := strings.TrimSuffix(pattern, "*"); strings.HasPrefix(model, prefix) && len(prefix) > best {
+			best, matched = len(prefix), p
+		}
+	}
+	if best >= 0 {
+		return matched, true
+	}
+
+	p, ok := pricing["*"]
+	return p, ok
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// inflightCall 正在进行的上游请求，完成后共享给等待的相同请求
+type inflightCall struct {
+	done   chan struct{}
+	shared bool // 为false时领头请求未得到可共享的响应，等待方需自行转发
+	status int
+	body   []byte
+}
+
+// inflightGroup 合并正在进行的相同LLM请求：相同请求同时到达时只有第一个转发给上游，
+// 其余等待其响应，类似singleflight
+type inflightGroup struct {
+	maxWait   time.Duration
+	calls     map[string]*inflightCall
+	mutex     sync.Mutex
+	leaders   int64
+	coalesced int64
+	fallbacks int64
+}
+
+// newInflightGroup 创建请求合并
+func newInflightGroup(config *types.LLMDedupConfig) *inflightGroup {
+	maxWait := config.MaxWait
+	if maxWait <= 0 {
+		maxWait = 60 * time.Second
+	}
+	return &inflightGroup{maxWait: maxWait, calls: make(map[string]*inflightCall)}
+}
+
+// dedupKey 按端点、租户和规范化的请求体计算请求键，字段顺序和空白不同的请求视为相同；
+// 请求体不是合法JSON时不合并
+func dedupKey(endpoint, tenant string, body []byte) (string, bool) {
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", false
+	}
+	normalized, err := json.Marshal(parsed)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	h.Write([]byte(endpoint))
+	h.Write([]byte{0})
+	h.Write([]byte(tenant))
+	h.Write([]byte{0})
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// join 没有相同请求在进行时成为领头请求并返回leader为true，调用方完成后必须调用finish；
+// 否则等待领头请求完成，返回共享的响应。领头请求未得到可共享的响应时重新竞争领头，
+// 等待超时或客户端断开时返回nil，调用方自行转发
+func (g *inflightGroup) join(ctx context.Context, key string) (call *inflightCall, leader bool) {
+	timer := time.NewTimer(g.maxWait)
+	defer timer.Stop()
+
+	for {
+		g.mutex.Lock()
+		call, exists := g.calls[key]
+		if !exists {
+			call = &inflightCall{done: make(chan struct{})}
+			g.calls[key] = call
+			g.mutex.Unlock()
+			atomic.AddInt64(&g.leaders, 1)
+			return call, true
+		}
+		g.mutex.Unlock()
+
+		select {
+		case <-call.done:
+			if call.shared {
+				atomic.AddInt64(&g.coalesced, 1)
+				return call, false
+			}
+		case <-timer.C:
+			atomic.AddInt64(&g.fallbacks, 1)
+			return nil, false
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// finish 领头请求完成，shared为true时等待方使用status和body作为响应
+func (g *inflightGroup) finish(key string, call *inflightCall, shared bool, status int, body []byte) {
+	g.mutex.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mutex.Unlock()
+
+	call.shared, call.status, call.body = shared, status, body
+	close(call.done)
+}
+
+// Stats 获取合并统计
+func (g *inflightGroup) Stats() map[string]interface{} {
+	g.mutex.Lock()
+	inflight := len(g.calls)
+	g.mutex.Unlock()
+
+	return map[string]interface{}{
+		"inflight":  inflight,
+		"leaders":   atomic.LoadInt64(&g.leaders),
+		"coalesced": atomic.LoadInt64(&g.coalesced),
+		"fallbacks": atomic.LoadInt64(&g.fallbacks),
+	}
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// modelRoute 模型路由
+type modelRoute struct {
+	pattern       string
+	provider      *provider // 为nil时按提供方声明的模型选择
+	upstreamModel string
+	timeout       time.Duration
+	retries       int
+	maxTokens     int
+	semanticCache bool
+	cacheTTL      time.Duration
+	contextWindow int
+	dedup         bool
+}
+
+// modelTable 模型路由表
+type modelTable struct {
+	exact    map[string]*modelRoute
+	prefixes []*modelRoute // 按前缀长度从长到短
+	fallback *modelRoute   // "*"
+}
+
+// newModelTable 创建模型路由表，指定的提供方必须存在
+func newModelTable(configs []types.LLMModelConfig, providers []*provider) (*modelTable, error) {
+	mt := &modelTable{exact: make(map[string]*modelRoute)}
+
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("model route requires a name")
+		}
+		if cfg.Retries < 0 || cfg.MaxTokens < 0 || cfg.Timeout < 0 || cfg.ContextWindow < 0 {
+			return nil, fmt.Errorf("model route %s has negative retries, max_tokens, timeout or context_window", cfg.Name)
+		}
+
+		route := &modelRoute{
+			pattern:       cfg.Name,
+			upstreamModel: cfg.UpstreamModel,
+			timeout:       cfg.Timeout,
+			retries:       cfg.Retries,
+			maxTokens:     cfg.MaxTokens,
+			semanticCache: cfg.SemanticCache,
+			cacheTTL:      cfg.CacheTTL,
+			contextWindow: cfg.ContextWindow,
+			dedup:         cfg.Dedup,
+		}
+		if cfg.Provider != "" {
+			for _, prov := range providers {
+				if prov.name == cfg.Provider {
+					route.provider = prov
+					break
+				}
+			}
+			if route.provider == nil {
+				return nil, fmt.Errorf("model route %s references unknown provider %s", cfg.Name, cfg.Provider)
+			}
+		}
+
+		switch {
+		case cfg.Name == "*":
+			mt.fallback = route
+		case strings.HasSuffix(cfg.Name, "*"):
+			mt.prefixes = append(mt.prefixes, route)
+		default:
+			mt.exact[cfg.Name] = route
+		}
+	}
+
+	// 前缀长度相同时保持配置顺序
+	sort.SliceStable(mt.prefixes, func(i, j int) bool {
+		return len(mt.prefixes[i].pattern) > len(mt.prefixes[j].pattern)
+	})
+
+	return mt, nil
+}
+
+// lookup 查找模型路由，先精确匹配，再按最长前缀匹配，最后使用"*"，未配置时返回nil
+func (mt *modelTable) lookup(model string) *modelRoute {
+	if route, ok := mt.exact[model]; ok {
+		return route
+	}
+	for _, route := range mt.prefixes {
+		if strings.HasPrefix(model, strings.TrimSuffix(route.pattern, "*")) {
+			return route
+		}
+	}
+	return mt.fallback
+}
+
+// rewrite 改写请求体中的模型名并补充默认max_tokens，返回改写后的请求体和转发给提供方的模型名
+func (mr *modelRoute) rewrite(endpoint, model string, body []byte) ([]byte, string) {
+	upstreamModel := model
+	if mr.upstreamModel != "" {
+		upstreamModel = mr.upstreamModel
+	}
+
+	setMaxTokens := mr.maxTokens > 0 && endpoint != EndpointEmbeddings
+	if upstreamModel == model && !setMaxTokens {
+		return body, model
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, model
+	}
+
+	if upstreamModel != model {
+		fields["model"], _ = json.Marshal(upstreamModel)
+	}
+	if setMaxTokens {
+		_, hasMaxTokens := fields["max_tokens"]
+		_, hasMaxCompletionTokens := fields["max_completion_tokens"]
+		if !hasMaxTokens && !hasMaxCompletionTokens {
+			fields["max_tokens"], _ = json.Marshal(mr.maxTokens)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return body, model
+	}
+	return data, upstreamModel
+}
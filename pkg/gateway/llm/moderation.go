@@ -0,0 +1,432 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ModeratedOutput 待审核的LLM输出
+type ModeratedOutput struct {
+	Model  string
+	Text   string // 截至目前的完整输出
+	Offset int    // 上次检查后新增内容在Text中的起始位置
+	Final  bool   // 输出已结束
+}
+
+// ResponseInspector LLM响应内容审核，流式响应按Interval分段多次调用
+type ResponseInspector interface {
+	Name() string
+	// Interval 流式响应两次检查之间至少新增的字符数，0表示每个事件都检查
+	Interval() int
+	// Inspect 检查输出，违规时返回Violation
+	Inspect(ctx context.Context, output *ModeratedOutput) (*Violation, error)
+}
+
+// Violation 内容违规
+type Violation struct {
+	Inspector string `json:"inspector"`
+	Category  string `json:"category"`
+	Reason    string `json:"reason"`
+}
+
+// Error 实现error接口
+func (v *Violation) Error() string {
+	return fmt.Sprintf("response blocked by %s: %s", v.Inspector, v.Reason)
+}
+
+// moderator 依次调用审核器
+type moderator struct {
+	inspectors []ResponseInspector
+	metrics    interfaces.MetricsCollector
+	checked    int64
+	violations int64
+}
+
+// newModerator 按配置创建内置审核器
+func newModerator(config *types.ModerationConfig, metrics interfaces.MetricsCollector) (*moderator, error) {
+	m := &moderator{metrics: metrics}
+
+	if config.Keywords.Enabled {
+		inspector, err := NewKeywordInspector(config.Keywords.Words, config.Keywords.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		m.inspectors = append(m.inspectors, inspector)
+	}
+	if config.API.Enabled {
+		inspector, err := NewModerationAPIInspector(&config.API)
+		if err != nil {
+			return nil, err
+		}
+		m.inspectors = append(m.inspectors, inspector)
+	}
+	return m, nil
+}
+
+// inspect 用到期的审核器检查输出，checked记录每个审核器上次检查时的输出长度
+func (m *moderator) inspect(ctx context.Context, output *ModeratedOutput, checked []int) *Violation {
+	for i, inspector := range m.inspectors {
+		if !output.Final && len(output.Text)-checked[i] < inspector.Interval() {
+			continue
+		}
+		if output.Final && checked[i] == len(output.Text) {
+			continue
+		}
+
+		output.Offset = checked[i]
+		checked[i] = len(output.Text)
+		atomic.AddInt64(&m.checked, 1)
+
+		violation, err := inspector.Inspect(ctx, output)
+		if err != nil {
+			log.Printf("Response inspector %s failed: %v", inspector.Name(), err)
+			continue
+		}
+		if violation != nil {
+			if violation.Inspector == "" {
+				violation.Inspector = inspector.Name()
+			}
+			atomic.AddInt64(&m.violations, 1)
+			if m.metrics != nil {
+				m.metrics.RecordModerationViolation(violation.Inspector, violation.Category)
+			}
+			return violation
+		}
+	}
+	return nil
+}
+
+// newChecked 为一次响应创建各审核器的检查进度
+func (m *moderator) newChecked() []int {
+	return make([]int, len(m.inspectors))
+}
+
+// stats 获取审核统计
+func (m *moderator) stats() map[string]interface{} {
+	names := make([]string, 0, len(m.inspectors))
+	for _, inspector := range m.inspectors {
+		names = append(names, inspector.Name())
+	}
+	return map[string]interface{}{
+		"inspectors": names,
+		"checked":    atomic.LoadInt64(&m.checked),
+		"violations": atomic.LoadInt64(&m.violations),
+	}
+}
+
+// moderatedWriter 流式响应审核：按行缓冲，数据事件的新增文本审核通过后才转发，违规时停止写出
+type moderatedWriter struct {
+	dst       streamWriter
+	ctx       context.Context
+	moderator *moderator
+	output    ModeratedOutput
+	text      strings.Builder
+	checked   []int
+	pending   []byte
+	violation *Violation
+}
+
+// newModeratedWriter 创建流式响应审核
+func newModeratedWriter(ctx context.Context, dst streamWriter, m *moderator, model string) *moderatedWriter {
+	return &moderatedWriter{
+		dst:       dst,
+		ctx:       ctx,
+		moderator: m,
+		output:    ModeratedOutput{Model: model},
+		checked:   m.newChecked(),
+	}
+}
+
+// Write 整行审核后转发，违规时返回Violation
+func (mw *moderatedWriter) Write(p []byte) (int, error) {
+	if mw.violation != nil {
+		return 0, mw.violation
+	}
+
+	consumed := 0
+	for len(p) > 0 {
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			if len(mw.pending)+len(p) > maxStreamLineBytes {
+				// 超长的行不审核，直接转发
+				if err := mw.forward(append(mw.pending, p...)); err != nil {
+					return consumed, err
+				}
+				mw.pending = mw.pending[:0]
+			} else {
+				mw.pending = append(mw.pending, p...)
+			}
+			return consumed + len(p), nil
+		}
+
+		line := append(mw.pending, p[:idx+1]...)
+		mw.pending = mw.pending[:0]
+		p = p[idx+1:]
+		consumed += idx + 1
+
+		trimmed := bytes.TrimSpace(line)
+		chunk, ok := parseStreamChunk(trimmed)
+		if ok {
+			for _, choice := range chunk.Choices {
+				mw.text.WriteString(choice.Text)
+				mw.text.WriteString(choice.Delta.Content)
+			}
+			mw.output.Text = mw.text.String()
+		}
+		// 结束事件转发前对剩余内容做最后一次检查
+		if bytes.Equal(trimmed, []byte("data: [DONE]")) {
+			mw.output.Final = true
+			ok = true
+		}
+		if ok {
+			if violation := mw.moderator.inspect(mw.ctx, &mw.output, mw.checked); violation != nil {
+				mw.violation = violation
+				return consumed, violation
+			}
+		}
+		if err := mw.forward(line); err != nil {
+			return consumed, err
+		}
+	}
+	return consumed, nil
+}
+
+// forward 转发给下游
+func (mw *moderatedWriter) forward(data []byte) error {
+	_, err := mw.dst.Write(data)
+	return err
+}
+
+// Flush 刷新输出
+func (mw *moderatedWriter) Flush() {
+	mw.dst.Flush()
+}
+
+// finish 提供方未发送结束事件时检查剩余内容并转发未完整的行
+func (mw *moderatedWriter) finish() error {
+	if mw.violation != nil || mw.output.Final {
+		return nil
+	}
+	mw.output.Final = true
+	if violation := mw.moderator.inspect(mw.ctx, &mw.output, mw.checked); violation != nil {
+		mw.violation = violation
+		return violation
+	}
+	if len(mw.pending) > 0 {
+		err := mw.forward(mw.pending)
+		mw.pending = mw.pending[:0]
+		return err
+	}
+	return nil
+}
+
+// responseText 提取非流式响应中各选项的输出文本
+func responseText(body []byte) string {
+	var response struct {
+		Choices []struct {
+			Text    string `json:"text"`
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, choice := range response.Choices {
+		sb.WriteString(choice.Text)
+		sb.WriteString(choice.Message.Content)
+	}
+	return sb.String()
+}
+
+// KeywordInspector 关键词黑名单审核，每个事件都检查
+type KeywordInspector struct {
+	patterns []*regexp.Regexp
+	overlap  int // 检查新增内容时向前包含的字符数，覆盖跨事件的关键词
+}
+
+// NewKeywordInspector 创建关键词审核，关键词不区分大小写
+func NewKeywordInspector(words, patterns []string) (*KeywordInspector, error) {
+	ki := &KeywordInspector{overlap: 256}
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		ki.patterns = append(ki.patterns, regexp.MustCompile("(?i)"+regexp.QuoteMeta(word)))
+		if len(word) > ki.overlap {
+			ki.overlap = len(word)
+		}
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation pattern %q: %v", pattern, err)
+		}
+		ki.patterns = append(ki.patterns, re)
+	}
+	return ki, nil
+}
+
+// Name 审核器名
+func (ki *KeywordInspector) Name() string {
+	return "keywords"
+}
+
+// Interval 每个事件都检查
+func (ki *KeywordInspector) Interval() int {
+	return 0
+}
+
+// Inspect 检查新增内容及其之前的一段重叠内容
+func (ki *KeywordInspector) Inspect(ctx context.Context, output *ModeratedOutput) (*Violation, error) {
+	start := output.Offset - ki.overlap
+	if start < 0 {
+		start = 0
+	}
+	text := output.Text[start:]
+	for _, re := range ki.patterns {
+		if re.MatchString(text) {
+			return &Violation{Category: "blocklist", Reason: fmt.Sprintf("output matches blocked pattern %s", re.String())}, nil
+		}
+	}
+	return nil, nil
+}
+
+// ModerationAPIInspector 调用OpenAI /v1/moderations格式的外部审核接口
+type ModerationAPIInspector struct {
+	url        string
+	apiKey     string
+	model      string
+	interval   int
+	failClosed bool
+	client     *http.Client
+}
+
+// NewModerationAPIInspector 创建外部接口审核
+func NewModerationAPIInspector(config *types.ModerationAPIConfig) (*ModerationAPIInspector, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("moderation api url is required")
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 500
+	}
+
+	return &ModerationAPIInspector{
+		url:        config.URL,
+		apiKey:     os.ExpandEnv(config.APIKey),
+		model:      config.Model,
+		interval:   interval,
+		failClosed: config.FailClosed,
+		client:     &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name 审核器名
+func (ai *ModerationAPIInspector) Name() string {
+	return "moderation_api"
+}
+
+// Interval 流式响应每累计interval个字符调用一次
+func (ai *ModerationAPIInspector) Interval() int {
+	return ai.interval
+}
+
+// Inspect 提交完整输出，接口返回flagged时按违规处理
+func (ai *ModerationAPIInspector) Inspect(ctx context.Context, output *ModeratedOutput) (*Violation, error) {
+	flagged, categories, err := ai.moderate(ctx, output.Text)
+	if err != nil {
+		if ai.failClosed {
+			return &Violation{Category: "unavailable", Reason: "moderation api unavailable"}, nil
+		}
+		return nil, err
+	}
+	if !flagged {
+		return nil, nil
+	}
+
+	category := "flagged"
+	if len(categories) > 0 {
+		category = categories[0]
+	}
+	return &Violation{Category: category, Reason: "flagged as " + strings.Join(categories, ",")}, nil
+}
+
+// moderate 调用审核接口，返回是否违规和违规的类别
+func (ai *ModerationAPIInspector) moderate(ctx context.Context, text string) (bool, []string, error) {
+	payload := map[string]interface{}{"input": text}
+	if ai.model != "" {
+		payload["model"] = ai.model
+	}
+	body, _ := json.Marshal(payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ai.url, bytes.NewReader(body))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to create moderation request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ai.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+ai.apiKey)
+	}
+
+	resp, err := ai.client.Do(req)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to call moderation api: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read moderation response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("moderation api returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return false, nil, fmt.Errorf("failed to parse moderation response: %v", err)
+	}
+
+	flagged := false
+	var categories []string
+	for _, r := range result.Results {
+		if !r.Flagged {
+			continue
+		}
+		flagged = true
+		for name, hit := range r.Categories {
+			if hit {
+				categories = append(categories, name)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return flagged, categories, nil
+}
@@ -0,0 +1,795 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// maxResponseBytes 非流式响应体上限
+const maxResponseBytes = 32 << 20
+
+// 语义缓存响应头
+const (
+	headerSemanticCache      = "X-Semantic-Cache"
+	headerSemanticSimilarity = "X-Semantic-Cache-Similarity"
+)
+
+// headerContextTruncated 为放入上下文窗口丢弃的消息数
+const headerContextTruncated = "X-Context-Truncated"
+
+// headerCoalesced 响应来自同时进行的相同请求
+const headerCoalesced = "X-LLM-Coalesced"
+
+// provider LLM提供方
+type provider struct {
+	name     string
+	models   []string
+	adapter  adapter
+	client   *http.Client
+	requests int64
+	failures int64
+	retries  int64
+}
+
+// Proxy OpenAI兼容的LLM代理，按请求中的model选择提供方，由适配器完成协议转换
+type Proxy struct {
+	providers       []*provider
+	defaultProvider *provider
+	models          *modelTable
+	tokenLimiter    *limiter.TokenLimiter // 未启用令牌限流时为nil
+	costTracker     *CostTracker          // 未启用费用核算时为nil
+	semanticCache   *SemanticCache        // 未启用语义缓存时为nil
+	redactor        *Redactor             // 未启用提示词脱敏时为nil
+	moderator       *moderator            // 未启用响应审核时为nil
+	tenants         *tenantTable          // 未配置租户策略时为nil
+	contextGuard    *contextGuard         // 未启用上下文窗口检查时为nil
+	inflight        *inflightGroup        // 未启用相同请求合并时为nil
+	enforceStreams  bool
+	metrics         interfaces.MetricsCollector
+}
+
+// unsupportedError 提供方不支持的端点
+type unsupportedError struct {
+	endpoint string
+}
+
+// Error 实现error接口
+func (e *unsupportedError) Error() string {
+	return fmt.Sprintf("endpoint %s is not supported by this provider", e.endpoint)
+}
+
+// NewProxy 创建LLM代理
+func NewProxy(config *types.LLMConfig, redisConfig *types.RedisConfig, metrics interfaces.MetricsCollector) (*Proxy, error) {
+	p := &Proxy{metrics: metrics, enforceStreams: config.StreamEnforcement}
+
+	for i, cfg := range config.Providers {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", cfg.Type, i)
+		}
+
+		adapter, err := newAdapter(&cfg, os.ExpandEnv(cfg.APIKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm provider %s: %v", name, err)
+		}
+
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = 60 * time.Second
+		}
+
+		// 只限制等待响应头的时间，流式响应的总时长不受限
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = timeout
+
+		prov := &provider{
+			name:    name,
+			models:  cfg.Models,
+			adapter: adapter,
+			client:  &http.Client{Transport: transport},
+		}
+		p.providers = append(p.providers, prov)
+
+		if name == config.DefaultProvider {
+			p.defaultProvider = prov
+		}
+	}
+
+	if config.DefaultProvider != "" && p.defaultProvider == nil {
+		return nil, fmt.Errorf("default llm provider %s not found", config.DefaultProvider)
+	}
+
+	models, err := newModelTable(config.Models, p.providers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid llm model routes: %v", err)
+	}
+	p.models = models
+
+	if len(config.Tenants) > 0 {
+		tenants, err := newTenantTable(config.Tenants)
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm tenant policies: %v", err)
+		}
+		p.tenants = tenants
+	}
+
+	if config.ContextGuard.Enabled {
+		guard, err := newContextGuard(&config.ContextGuard)
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm context guard config: %v", err)
+		}
+		p.contextGuard = guard
+	}
+
+	if config.Dedup.Enabled {
+		p.inflight = newInflightGroup(&config.Dedup)
+	}
+
+	if config.TokenLimit.Enabled {
+		p.tokenLimiter = limiter.NewTokenLimiter(&config.TokenLimit)
+	}
+
+	if config.Cost.Enabled {
+		if err := validateCostConfig(&config.Cost); err != nil {
+			return nil, fmt.Errorf("invalid llm cost config: %v", err)
+		}
+		p.costTracker = NewCostTracker(&config.Cost, redisConfig)
+	}
+
+	if config.Redaction.Enabled {
+		redactor, err := NewRedactor(&config.Redaction, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm redaction config: %v", err)
+		}
+		p.redactor = redactor
+	}
+
+	if config.Moderation.Enabled {
+		m, err := newModerator(&config.Moderation, metrics)
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm moderation config: %v", err)
+		}
+		p.moderator = m
+	}
+
+	return p, nil
+}
+
+// AttachSemanticCache 启用语义缓存，只对开启了semantic_cache的模型路由生效
+func (p *Proxy) AttachSemanticCache(cache *SemanticCache) {
+	p.semanticCache = cache
+}
+
+// AddInspector 添加响应审核器，在内置审核器之后调用，需在开始处理请求前添加
+func (p *Proxy) AddInspector(inspector ResponseInspector) {
+	if p.moderator == nil {
+		p.moderator = &moderator{metrics: p.metrics}
+	}
+	p.moderator.inspectors = append(p.moderator.inspectors, inspector)
+}
+
+// Start 启动费用持久化和语义缓存清理
+func (p *Proxy) Start() {
+	if p.costTracker != nil {
+		p.costTracker.Start()
+	}
+	if p.semanticCache != nil {
+		p.semanticCache.Start()
+	}
+}
+
+// Stop 停止费用持久化和语义缓存清理
+func (p *Proxy) Stop() {
+	if p.costTracker != nil {
+		p.costTracker.Stop()
+	}
+	if p.semanticCache != nil {
+		p.semanticCache.Stop()
+	}
+}
+
+// Register 注册OpenAI兼容端点
+func (p *Proxy) Register(engine *gin.Engine) {
+	v1 := engine.Group("/v1")
+	v1.POST("/chat/completions", p.handler(EndpointChatCompletions))
+	v1.POST("/completions", p.handler(EndpointCompletions))
+	v1.POST("/embeddings", p.handler(EndpointEmbeddings))
+	v1.GET("/models", p.listModels)
+}
+
+// RegisterAdmin 注册费用查询、脱敏和审核统计管理接口
+func (p *Proxy) RegisterAdmin(admin *gin.RouterGroup) {
+	if p.costTracker != nil {
+		admin.GET("/costs", p.getCostsHandler)
+	}
+	if p.redactor != nil {
+		admin.GET("/redactions", p.getRedactionsHandler)
+	}
+	if p.moderator != nil {
+		admin.GET("/moderation", p.getModerationHandler)
+	}
+}
+
+// getModerationHandler 查询响应审核统计
+func (p *Proxy) getModerationHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, p.moderator.stats())
+}
+
+// getRedactionsHandler 查询按规则统计的提示词脱敏次数
+func (p *Proxy) getRedactionsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, p.redactor.Stats())
+}
+
+// getCostsHandler 查询当前日、月周期的花费，可按scope过滤维度
+func (p *Proxy) getCostsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, p.costTracker.Spend(c.Query("scope")))
+}
+
+// selectProvider 按模型名选择提供方，模型路由指定了提供方时直接使用，
+// 否则按提供方声明的模型先精确匹配，再前缀匹配，最后使用默认提供方
+func (p *Proxy) selectProvider(model string, route *modelRoute) *provider {
+	if route != nil && route.provider != nil {
+		return route.provider
+	}
+	for _, prov := range p.providers {
+		for _, pattern := range prov.models {
+			if pattern == model {
+				return prov
+			}
+		}
+	}
+	for _, prov := range p.providers {
+		for _, pattern := range prov.models {
+			if strings.HasSuffix(pattern, "*") && strings.HasPrefix(model, strings.TrimSuffix(pattern, "*")) {
+				return prov
+			}
+		}
+	}
+	return p.defaultProvider
+}
+
+// handler 代理一个OpenAI兼容端点
+func (p *Proxy) handler(endpoint string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortError(c, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %v", err), "invalid_request_error", "")
+			return
+		}
+
+		var request struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			abortError(c, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err), "invalid_request_error", "")
+			return
+		}
+		if request.Model == "" {
+			abortError(c, http.StatusBadRequest, "model is required", "invalid_request_error", "")
+			return
+		}
+
+		// 租户策略先于模型路由检查，不向租户暴露白名单之外的模型是否存在
+		if p.tenants != nil {
+			if err := p.tenants.check(utils.ExtractTenant(c), request.Model, body); err != nil {
+				var violation *tenantViolation
+				errors.As(err, &violation)
+				utils.RecordStageError(c, "tenant_policy", err)
+				abortError(c, http.StatusForbidden, violation.message, "permission_error", violation.code)
+				return
+			}
+		}
+
+		route := p.models.lookup(request.Model)
+		prov := p.selectProvider(request.Model, route)
+		if prov == nil {
+			abortError(c, http.StatusNotFound, fmt.Sprintf("The model %s does not exist", request.Model), "invalid_request_error", "model_not_found")
+			return
+		}
+
+		c.Set("llm_model", request.Model)
+		c.Set("llm_provider", prov.name)
+		c.Set("upstream_target", prov.name)
+
+		// 脱敏后的请求体同时用于令牌估算和语义缓存，敏感信息不离开网关
+		if p.redactor != nil {
+			var redactions int
+			if body, redactions = p.redactor.Redact(endpoint, body); redactions > 0 {
+				c.Set("llm_redactions", redactions)
+				utils.AnnotateStage(c, "redaction", fmt.Sprintf("redacted %d values from prompt", redactions))
+			}
+		}
+
+		// 上下文窗口检查在脱敏之后，截断后的请求体用于令牌估算、语义缓存和转发
+		if p.contextGuard != nil && route != nil && route.contextWindow > 0 {
+			checked, dropped, err := p.contextGuard.check(endpoint, body, route.contextWindow, route.maxTokens)
+			if err != nil {
+				p.recordContextGuard(request.Model, ContextActionReject)
+				utils.RecordStageError(c, "context_guard", err)
+				abortError(c, http.StatusBadRequest, err.Error(), "invalid_request_error", "context_length_exceeded")
+				return
+			}
+			if dropped > 0 {
+				body = checked
+				p.recordContextGuard(request.Model, ContextActionTruncate)
+				utils.AnnotateStage(c, "context_guard", fmt.Sprintf("dropped %d messages to fit the context window", dropped))
+				c.Header(headerContextTruncated, strconv.Itoa(dropped))
+			}
+		}
+
+		promptTokens := int64(CountPromptTokens(endpoint, body))
+		c.Set("llm_prompt_tokens", promptTokens)
+
+		subject := limiter.TokenSubject{ClusterID: c.GetString("cluster_id"), Model: request.Model}
+		if key := utils.ExtractAPIKey(c); key != "" {
+			subject.KeyID = utils.APIKeyID(key)
+		}
+		costSubject := CostSubject{KeyID: subject.KeyID, Tenant: utils.ExtractTenant(c), Model: request.Model}
+
+		// 语义缓存命中时不转发，不消耗令牌和预算
+		var cacheQuery *SemanticQuery
+		if p.semanticCache != nil && route != nil && route.semanticCache && !request.Stream {
+			if query, ok := NewSemanticQuery(endpoint, request.Model, costSubject.Tenant, body); ok {
+				cached, similarity, hit := p.semanticCache.Lookup(query)
+				p.recordSemanticCache(request.Model, hit)
+				if hit {
+					c.Set("llm_cache", "hit")
+					c.Header(headerSemanticCache, "HIT")
+					c.Header(headerSemanticSimilarity, strconv.FormatFloat(similarity, 'f', 4, 64))
+					c.Data(http.StatusOK, "application/json", cached)
+					return
+				}
+				c.Header(headerSemanticCache, "MISS")
+				cacheQuery = query
+			}
+		}
+
+		// 超出预算按限流处理
+		if p.costTracker != nil {
+			if budget, spent, exceeded := p.costTracker.CheckBudget(costSubject); exceeded {
+				if p.metrics != nil {
+					p.metrics.RecordRateLimitHit("budget", budget.Scope)
+				}
+				abortError(c, http.StatusTooManyRequests,
+					fmt.Sprintf("Budget of $%.2f per %s exceeded for %s (spent $%.2f)", budget.Limit, budgetPeriod(budget), budget.Scope, spent),
+					"insufficient_quota", "budget_exceeded")
+				return
+			}
+		}
+		if p.tokenLimiter != nil {
+			allowed, dimension, retryAfter := p.tokenLimiter.Allow(subject, promptTokens)
+			if !allowed {
+				c.Header("Retry-After", strconv.Itoa(int(retryAfter/time.Second)+1))
+				abortError(c, http.StatusTooManyRequests,
+					fmt.Sprintf("Rate limit reached for tokens per minute on %s", dimension), "tokens", "rate_limit_exceeded")
+				return
+			}
+		}
+
+		// 相同请求正在进行时等待其响应，准入检查已通过，等待方不转发，退还预扣的令牌
+		var sharedStatus int
+		var sharedBody []byte
+		if p.inflight != nil && route != nil && route.dedup && !request.Stream {
+			if key, ok := dedupKey(endpoint, costSubject.Tenant, body); ok {
+				call, leader := p.inflight.join(c.Request.Context(), key)
+				if leader {
+					defer func() {
+						p.inflight.finish(key, call, sharedStatus != 0, sharedStatus, sharedBody)
+					}()
+				} else if call != nil {
+					p.refundTokens(subject, promptTokens)
+					c.Set("llm_cache", "coalesced")
+					c.Header(headerCoalesced, "true")
+					c.Data(call.status, "application/json", call.body)
+					return
+				}
+			}
+		}
+
+		atomic.AddInt64(&prov.requests, 1)
+
+		upstreamModel := request.Model
+		if route != nil {
+			body, upstreamModel = route.rewrite(endpoint, request.Model, body)
+		}
+
+		// 模型路由的超时覆盖重试在内的整个请求，流式请求收到响应头后停止计时
+		ctx := c.Request.Context()
+		var timer *time.Timer
+		if route != nil && route.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+			timer = time.AfterFunc(route.timeout, cancel)
+			defer timer.Stop()
+		}
+
+		resp, err := p.send(ctx, prov, route, endpoint, upstreamModel, body)
+		if err != nil {
+			p.refundTokens(subject, promptTokens)
+			var unsupported *unsupportedError
+			if errors.As(err, &unsupported) {
+				abortError(c, http.StatusBadRequest, err.Error(), "invalid_request_error", "unsupported_endpoint")
+				return
+			}
+			var invalid *requestError
+			if errors.As(err, &invalid) {
+				abortError(c, http.StatusBadRequest, err.Error(), "invalid_request_error", "")
+				return
+			}
+			if c.Request.Context().Err() != nil {
+				c.Abort()
+				return
+			}
+			p.upstreamFailed(c, prov, err)
+			if ctx.Err() != nil {
+				abortError(c, http.StatusGatewayTimeout, "Upstream LLM provider timed out", "upstream_error", "timeout")
+				return
+			}
+			abortError(c, http.StatusBadGateway, "Upstream LLM provider request failed", "upstream_error", "")
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			p.upstreamFailed(c, prov, fmt.Errorf("provider %s returned %d", prov.name, resp.StatusCode))
+		}
+
+		if request.Stream && resp.StatusCode == http.StatusOK {
+			if timer != nil {
+				timer.Stop()
+			}
+			p.stream(c, prov, endpoint, request.Model, resp, subject, costSubject, promptTokens)
+			return
+		}
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		if err != nil {
+			p.upstreamFailed(c, prov, err)
+			abortError(c, http.StatusBadGateway, "Failed to read upstream LLM response", "upstream_error", "")
+			return
+		}
+
+		converted := prov.adapter.convertResponse(endpoint, request.Model, resp.StatusCode, data)
+		p.settleTokens(c, subject, costSubject, promptTokens, resp.StatusCode, converted)
+		if p.moderated(endpoint) && resp.StatusCode == http.StatusOK {
+			output := &ModeratedOutput{Model: request.Model, Text: responseText(converted), Final: true}
+			if violation := p.moderator.inspect(c.Request.Context(), output, p.moderator.newChecked()); violation != nil {
+				utils.RecordStageError(c, "moderation", violation)
+				abortError(c, http.StatusBadRequest, violation.Error(), "invalid_request_error", "content_policy_violation")
+				return
+			}
+		}
+		if cacheQuery != nil && resp.StatusCode == http.StatusOK {
+			p.semanticCache.Store(cacheQuery, converted, route.cacheTTL)
+		}
+		// 只共享2xx响应，其余状态码等待的请求重新竞争领头后自行转发
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			sharedStatus, sharedBody = resp.StatusCode, converted
+		}
+		c.Data(resp.StatusCode, "application/json", converted)
+	}
+}
+
+// requestError 请求无法转换为提供方格式
+type requestError struct {
+	err error
+}
+
+// Error 实现error接口
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// send 发送请求，连接失败或提供方返回5xx时按模型路由的重试次数重试，
+// 重试之间按次数线性退避，最后一次的5xx响应原样返回
+func (p *Proxy) send(ctx context.Context, prov *provider, route *modelRoute, endpoint, model string, body []byte) (*http.Response, error) {
+	retries := 0
+	if route != nil {
+		retries = route.retries
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := prov.adapter.newRequest(ctx, endpoint, model, body)
+		if err != nil {
+			return nil, &requestError{err: err}
+		}
+
+		resp, err := prov.client.Do(req)
+		if attempt >= retries || ctx.Err() != nil || (err == nil && resp.StatusCode < 500) {
+			return resp, err
+		}
+
+		if err == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		atomic.AddInt64(&prov.retries, 1)
+
+		select {
+		case <-time.After(time.Duration(attempt+1) * 100 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// settleTokens 按响应中的实际用量修正预扣的令牌并核算花费，提供方返回错误时退还预扣
+func (p *Proxy) settleTokens(c *gin.Context, subject limiter.TokenSubject, costSubject CostSubject, estimated int64, status int, body []byte) {
+	if status >= 400 {
+		p.refundTokens(subject, estimated)
+		return
+	}
+
+	prompt, completion, ok := responseUsage(body)
+	if !ok {
+		return
+	}
+	c.Set("llm_prompt_tokens", prompt)
+	c.Set("llm_completion_tokens", completion)
+
+	if p.tokenLimiter != nil {
+		p.tokenLimiter.Consume(subject, prompt-estimated+completion)
+	}
+
+	cost := 0.0
+	if p.costTracker != nil {
+		cost = p.costTracker.Record(costSubject, prompt, completion)
+	}
+	c.Set("llm_cost", cost)
+	if p.metrics != nil {
+		p.metrics.RecordLLMUsage(costSubject.Model, costSubject.Tenant, prompt, completion, cost)
+	}
+}
+
+// recordContextGuard 记录超出上下文窗口的处理动作
+func (p *Proxy) recordContextGuard(model, action string) {
+	if p.metrics != nil {
+		p.metrics.RecordContextGuard(model, action)
+	}
+}
+
+// recordSemanticCache 记录语义缓存命中情况
+func (p *Proxy) recordSemanticCache(model string, hit bool) {
+	if p.metrics == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	p.metrics.RecordSemanticCache(model, result)
+}
+
+// refundTokens 退还预扣的令牌
+func (p *Proxy) refundTokens(subject limiter.TokenSubject, tokens int64) {
+	if p.tokenLimiter != nil {
+		p.tokenLimiter.Consume(subject, -tokens)
+	}
+}
+
+// stream 转发流式响应，转发的同时计量输出令牌：令牌限流按实时计数补扣，
+// 开启流式限额时余额或预算耗尽即截断，无论正常结束、截断还是客户端断开均按计量结果结算
+func (p *Proxy) stream(c *gin.Context, prov *provider, endpoint, model string, resp *http.Response, subject limiter.TokenSubject, costSubject CostSubject, promptTokens int64) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+
+	charged := int64(0)
+	meter := newStreamMeter(c.Writer, func(completion int64) error {
+		if p.tokenLimiter != nil {
+			p.tokenLimiter.Consume(subject, completion-charged)
+			charged = completion
+		}
+		if !p.enforceStreams {
+			return nil
+		}
+		return p.checkStream(subject, costSubject, promptTokens, completion)
+	})
+
+	// 审核在计量之前，违规事件不转发也不计量
+	var writer streamWriter = meter
+	var moderated *moderatedWriter
+	if p.moderated(endpoint) {
+		moderated = newModeratedWriter(c.Request.Context(), meter, p.moderator, model)
+		writer = moderated
+	}
+
+	err := prov.adapter.convertStream(model, resp.Body, writer)
+	if err == nil && moderated != nil {
+		err = moderated.finish()
+	}
+	p.settleStream(c, subject, costSubject, promptTokens, charged, meter)
+
+	if err == nil {
+		return
+	}
+	var violation *Violation
+	if errors.As(err, &violation) {
+		c.Set("stream_outcome", "policy_violation")
+		utils.RecordStageError(c, "moderation", violation)
+		c.Writer.Write(streamErrorEvent(&streamLimitError{
+			errType: "invalid_request_error",
+			code:    "content_policy_violation",
+			message: violation.Error(),
+		}))
+		c.Writer.Flush()
+		return
+	}
+	var limited *streamLimitError
+	if errors.As(err, &limited) {
+		c.Set("stream_outcome", "limit_exceeded")
+		c.Writer.Write(streamErrorEvent(limited))
+		c.Writer.Flush()
+		return
+	}
+	if c.Request.Context().Err() != nil {
+		c.Set("client_canceled", "streaming")
+		return
+	}
+	c.Set("stream_outcome", "upstream_error")
+	p.upstreamFailed(c, prov, err)
+}
+
+// moderated 端点的响应是否需要审核
+func (p *Proxy) moderated(endpoint string) bool {
+	return p.moderator != nil && len(p.moderator.inspectors) > 0 && endpoint != EndpointEmbeddings
+}
+
+// checkStream 检查流式响应按当前输出令牌数是否已耗尽TPM余额或超出预算
+func (p *Proxy) checkStream(subject limiter.TokenSubject, costSubject CostSubject, promptTokens, completion int64) error {
+	if p.tokenLimiter != nil && p.tokenLimiter.Remaining(subject) == 0 {
+		if p.metrics != nil {
+			p.metrics.RecordRateLimitHit("tokens", subject.Model)
+		}
+		return &streamLimitError{
+			errType: "tokens",
+			code:    "rate_limit_exceeded",
+			message: fmt.Sprintf("Rate limit reached for tokens per minute after %d completion tokens", completion),
+		}
+	}
+
+	if p.costTracker != nil {
+		cost := p.costTracker.Price(costSubject.Model, promptTokens, completion)
+		if budget, spent, exceeded := p.costTracker.ExceedsBudget(costSubject, cost); exceeded {
+			if p.metrics != nil {
+				p.metrics.RecordRateLimitHit("budget", budget.Scope)
+			}
+			return &streamLimitError{
+				errType: "insufficient_quota",
+				code:    "budget_exceeded",
+				message: fmt.Sprintf("Budget of $%.2f per %s exceeded for %s during streaming (spent $%.2f)", budget.Limit, budgetPeriod(budget), budget.Scope, spent),
+			}
+		}
+	}
+	return nil
+}
+
+// settleStream 按计量结果结算流式响应的令牌和花费，charged为计量过程中已补扣的输出令牌
+func (p *Proxy) settleStream(c *gin.Context, subject limiter.TokenSubject, costSubject CostSubject, estimated, charged int64, meter *streamMeter) {
+	prompt, completion, _ := meter.tokens(estimated)
+	c.Set("llm_prompt_tokens", prompt)
+	c.Set("llm_completion_tokens", completion)
+
+	if p.tokenLimiter != nil {
+		p.tokenLimiter.Consume(subject, prompt-estimated+completion-charged)
+	}
+
+	cost := 0.0
+	if p.costTracker != nil {
+		cost = p.costTracker.Record(costSubject, prompt, completion)
+	}
+	c.Set("llm_cost", cost)
+	if p.metrics != nil {
+		p.metrics.RecordLLMUsage(costSubject.Model, costSubject.Tenant, prompt, completion, cost)
+	}
+}
+
+// upstreamFailed 记录提供方失败，失败计入簇的错误采样
+func (p *Proxy) upstreamFailed(c *gin.Context, prov *provider, err error) {
+	atomic.AddInt64(&prov.failures, 1)
+	c.Set("upstream_failed", true)
+	utils.RecordStageError(c, "llm", err)
+	log.Printf("LLM provider %s request failed: %v", prov.name, err)
+}
+
+// listModels 列出提供方声明的模型，通配模式和租户白名单之外的模型不列出
+func (p *Proxy) listModels(c *gin.Context) {
+	var policy *tenantPolicy
+	if p.tenants != nil {
+		policy = p.tenants.lookup(utils.ExtractTenant(c))
+	}
+
+	models := make([]gin.H, 0)
+	seen := make(map[string]bool)
+	for _, prov := range p.providers {
+		for _, model := range prov.models {
+			if strings.HasSuffix(model, "*") || seen[model] {
+				continue
+			}
+			if policy != nil && !policy.allows(model) {
+				continue
+			}
+			seen[model] = true
+			models = append(models, gin.H{"id": model, "object": "model", "owned_by": prov.name})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": models})
+}
+
+// Stats 获取各提供方的请求统计
+func (p *Proxy) Stats() map[string]interface{} {
+	providers := make(map[string]interface{}, len(p.providers))
+	for _, prov := range p.providers {
+		providers[prov.name] = map[string]int64{
+			"requests": atomic.LoadInt64(&prov.requests),
+			"failures": atomic.LoadInt64(&prov.failures),
+			"retries":  atomic.LoadInt64(&prov.retries),
+		}
+	}
+	stats := map[string]interface{}{"providers": providers}
+	if p.tokenLimiter != nil {
+		stats["token_limiter"] = p.tokenLimiter.Stats()
+	}
+	if p.costTracker != nil {
+		stats["cost"] = p.costTracker.Stats()
+	}
+	if p.semanticCache != nil {
+		stats["semantic_cache"] = p.semanticCache.Stats()
+	}
+	if p.tenants != nil {
+		stats["tenant_rejections"] = p.tenants.Stats()
+	}
+	if p.inflight != nil {
+		stats["dedup"] = p.inflight.Stats()
+	}
+	return stats
+}
+
+// abortError 以OpenAI错误格式返回
+func abortError(c *gin.Context, status int, message, errType, code string) {
+	c.Data(status, "application/json", errorBody(message, errType, code))
+	c.Abort()
+}
+
+// errorBody OpenAI错误格式
+func errorBody(message, errType, code string) []byte {
+	var codeValue interface{}
+	if code != "" {
+		codeValue = code
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errType,
+			"code":    codeValue,
+		},
+	})
+	return data
+}
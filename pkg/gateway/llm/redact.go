@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultRedactionPatterns 未配置builtin时使用的内置脱敏规则
+var defaultRedactionPatterns = []string{"phone", "email", "creditcard"}
+
+// Redactor 转发前脱敏请求中的提示词，按规则统计脱敏次数
+type Redactor struct {
+	desensitizer interfaces.Desensitizer
+	metrics      interfaces.MetricsCollector
+	counts       map[string]int64
+	requests     int64 // 发生过脱敏的请求数
+	mutex        sync.Mutex
+}
+
+// NewRedactor 创建提示词脱敏
+func NewRedactor(config *types.RedactionConfig, metrics interfaces.MetricsCollector) (*Redactor, error) {
+	d := utils.NewDesensitizer()
+
+	builtin := config.Builtin
+	if len(builtin) == 0 {
+		builtin = defaultRedactionPatterns
+	}
+	enabled := make(map[string]bool, len(builtin))
+	for _, name := range builtin {
+		enabled[name] = true
+	}
+	known := d.PatternNames()
+	for _, name := range known {
+		if !enabled[name] {
+			d.RemovePattern(name)
+		}
+		delete(enabled, name)
+	}
+	for name := range enabled {
+		return nil, fmt.Errorf("unknown builtin redaction pattern %q", name)
+	}
+
+	for i, cfg := range config.Patterns {
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("pattern-%d", i)
+		}
+		// AddPattern忽略无效的正则，需预先校验
+		if _, err := regexp.Compile(cfg.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s: %v", name, err)
+		}
+		replacement := cfg.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED]"
+		}
+		d.AddPattern(name, cfg.Pattern, replacement)
+	}
+
+	return &Redactor{
+		desensitizer: d,
+		metrics:      metrics,
+		counts:       make(map[string]int64),
+	}, nil
+}
+
+// Redact 脱敏请求体中的对话消息、prompt和input，返回脱敏后的请求体和替换次数，
+// 没有替换时原样返回请求体
+func (r *Redactor) Redact(endpoint string, body []byte) ([]byte, int) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, 0
+	}
+
+	counts := make(map[string]int)
+	changed := false
+	switch endpoint {
+	case EndpointChatCompletions:
+		changed = r.redactMessages(fields, counts)
+	case EndpointCompletions:
+		changed = r.redactField(fields, "prompt", counts)
+	case EndpointEmbeddings:
+		changed = r.redactField(fields, "input", counts)
+	}
+	if !changed {
+		return body, 0
+	}
+
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return body, 0
+	}
+
+	total := r.record(counts)
+	return redacted, total
+}
+
+// redactMessages 脱敏对话消息的文本内容
+func (r *Redactor) redactMessages(fields map[string]json.RawMessage, counts map[string]int) bool {
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(fields["messages"], &messages); err != nil {
+		return false
+	}
+
+	changed := false
+	for _, message := range messages {
+		if r.redactField(message, "content", counts) {
+			changed = true
+		}
+	}
+	if changed {
+		fields["messages"], _ = json.Marshal(messages)
+	}
+	return changed
+}
+
+// redactField 脱敏字符串、字符串数组或文本片段数组字段
+func (r *Redactor) redactField(fields map[string]json.RawMessage, name string, counts map[string]int) bool {
+	raw, exists := fields[name]
+	if !exists {
+		return false
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		redacted, ok := r.redactText(text, counts)
+		if ok {
+			fields[name], _ = json.Marshal(redacted)
+		}
+		return ok
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return false
+	}
+	changed := false
+	for i, item := range items {
+		if json.Unmarshal(item, &text) == nil {
+			if redacted, ok := r.redactText(text, counts); ok {
+				items[i], _ = json.Marshal(redacted)
+				changed = true
+			}
+			continue
+		}
+		// 文本片段{"type":"text","text":"..."}，图片等其他片段不处理
+		var part map[string]json.RawMessage
+		if json.Unmarshal(item, &part) != nil || string(part["type"]) != `"text"` {
+			continue
+		}
+		if r.redactField(part, "text", counts) {
+			items[i], _ = json.Marshal(part)
+			changed = true
+		}
+	}
+	if changed {
+		fields[name], _ = json.Marshal(items)
+	}
+	return changed
+}
+
+// redactText 脱敏一段文本
+func (r *Redactor) redactText(text string, counts map[string]int) (string, bool) {
+	redacted, matched := r.desensitizer.DesensitizeCount(text)
+	for name, n := range matched {
+		counts[name] += n
+	}
+	return redacted, len(matched) > 0
+}
+
+// record 累计脱敏次数
+func (r *Redactor) record(counts map[string]int) int {
+	total := 0
+	r.mutex.Lock()
+	r.requests++
+	for name, n := range counts {
+		r.counts[name] += int64(n)
+		total += n
+	}
+	r.mutex.Unlock()
+
+	if r.metrics != nil {
+		for name, n := range counts {
+			r.metrics.RecordRedaction(name, n)
+		}
+	}
+	return total
+}
+
+// Stats 获取按规则统计的脱敏次数
+func (r *Redactor) Stats() map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	counts := make(map[string]int64, len(r.counts))
+	for name, n := range r.counts {
+		counts[name] = n
+	}
+	patterns := r.desensitizer.PatternNames()
+	sort.Strings(patterns)
+
+	return map[string]interface{}{
+		"patterns":   patterns,
+		"redactions": counts,
+		"requests":   r.requests,
+	}
+}
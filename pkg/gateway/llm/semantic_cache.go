@@ -0,0 +1,319 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// semanticSearchTopK 每次查找取回的候选数，候选中可能混有其他租户、模型的条目
+const semanticSearchTopK = 8
+
+// semanticEntry 缓存的响应
+type semanticEntry struct {
+	scope   string
+	body    []byte
+	expires time.Time
+}
+
+// SemanticQuery 一次可缓存请求的查找键，查找时计算的向量在写入缓存时复用
+type SemanticQuery struct {
+	scope  string // 端点、模型、租户和生成参数，只在同一范围内匹配
+	prompt string
+	vector []float32
+}
+
+// SemanticCache LLM语义缓存：按提示词向量的相似度命中已缓存的响应
+type SemanticCache struct {
+	config   types.SemanticCacheConfig
+	embedder interfaces.EmbeddingService
+	vectors  interfaces.VectorDB
+	entries  map[string]*semanticEntry
+	seq      int64
+	hits     int64
+	misses   int64
+	evicted  int64
+	mutex    sync.RWMutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewSemanticCache 创建语义缓存
+func NewSemanticCache(config *types.SemanticCacheConfig, embedder interfaces.EmbeddingService, vectors interfaces.VectorDB) *SemanticCache {
+	cfg := *config
+	if cfg.Threshold <= 0 || cfg.Threshold > 1 {
+		cfg.Threshold = 0.95
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+
+	return &SemanticCache{
+		config:   cfg,
+		embedder: embedder,
+		vectors:  vectors,
+		entries:  make(map[string]*semanticEntry),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 开始定期清理过期条目
+func (sc *SemanticCache) Start() {
+	sc.wg.Add(1)
+	go sc.purgeLoop()
+	log.Printf("LLM semantic cache started (threshold=%.2f, ttl=%v)", sc.config.Threshold, sc.config.TTL)
+}
+
+// Stop 停止清理
+func (sc *SemanticCache) Stop() {
+	close(sc.stopCh)
+	sc.wg.Wait()
+}
+
+// NewSemanticQuery 由请求构建查找键，流式请求、向量化端点和无法提取文本提示词的请求不缓存
+func NewSemanticQuery(endpoint, model, tenant string, body []byte) (*SemanticQuery, bool) {
+	if endpoint != EndpointChatCompletions && endpoint != EndpointCompletions {
+		return nil, false
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+
+	var prompt string
+	var ok bool
+	if endpoint == EndpointChatCompletions {
+		prompt, ok = chatPromptText(fields["messages"])
+	} else {
+		prompt, ok = textContent(fields["prompt"])
+	}
+	if !ok || strings.TrimSpace(prompt) == "" {
+		return nil, false
+	}
+
+	// 提示词以外的字段（温度、max_tokens、工具定义等）必须完全一致
+	for _, name := range []string{"messages", "prompt", "model", "stream", "user"} {
+		delete(fields, name)
+	}
+	params, _ := json.Marshal(fields)
+	digest := sha256.Sum256(params)
+
+	return &SemanticQuery{
+		scope:  fmt.Sprintf("%s|%s|%s|%s", endpoint, model, tenant, hex.EncodeToString(digest[:8])),
+		prompt: prompt,
+	}, true
+}
+
+// chatPromptText 按"角色: 内容"逐行拼接对话消息，包含非文本内容时不缓存
+func chatPromptText(raw json.RawMessage) (string, bool) {
+	var messages []struct {
+		Role    string          `json:"role"`
+		Name    string          `json:"name"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &messages); err != nil || len(messages) == 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for _, message := range messages {
+		text, ok := textContent(message.Content)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(message.Role)
+		if message.Name != "" {
+			sb.WriteString("(" + message.Name + ")")
+		}
+		sb.WriteString(": ")
+		sb.WriteString(text)
+		sb.WriteString("\n")
+	}
+	return sb.String(), true
+}
+
+// textContent 提取字符串、字符串数组或文本片段数组中的文本
+func textContent(raw json.RawMessage) (string, bool) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, true
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return "", false
+	}
+
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		var part struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		switch {
+		case json.Unmarshal(item, &text) == nil:
+			parts = append(parts, text)
+		case json.Unmarshal(item, &part) == nil && part.Type == "text":
+			parts = append(parts, part.Text)
+		default:
+			// 图片等非文本内容、令牌ID数组
+			return "", false
+		}
+	}
+	return strings.Join(parts, "\n"), true
+}
+
+// Lookup 查找同一范围内相似度达到阈值的未过期响应
+func (sc *SemanticCache) Lookup(query *SemanticQuery) ([]byte, float64, bool) {
+	vector, err := sc.embedder.EmbedText(query.prompt)
+	if err != nil {
+		log.Printf("Failed to embed prompt for semantic cache: %v", err)
+		atomic.AddInt64(&sc.misses, 1)
+		return nil, 0, false
+	}
+	query.vector = vector
+
+	results, err := sc.vectors.SearchSimilar(vector, semanticSearchTopK)
+	if err != nil {
+		log.Printf("Failed to search semantic cache: %v", err)
+		atomic.AddInt64(&sc.misses, 1)
+		return nil, 0, false
+	}
+
+	now := time.Now()
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	for _, result := range results {
+		if result.Similarity < sc.config.Threshold {
+			break
+		}
+		entry, exists := sc.entries[result.ID]
+		if exists && entry.scope == query.scope && now.Before(entry.expires) {
+			atomic.AddInt64(&sc.hits, 1)
+			return entry.body, result.Similarity, true
+		}
+	}
+
+	atomic.AddInt64(&sc.misses, 1)
+	return nil, 0, false
+}
+
+// Store 缓存响应，ttl为0时使用默认缓存时间，查找时未能向量化的请求不缓存
+func (sc *SemanticCache) Store(query *SemanticQuery, body []byte, ttl time.Duration) {
+	if query.vector == nil {
+		return
+	}
+	if ttl <= 0 {
+		ttl = sc.config.TTL
+	}
+
+	sc.mutex.Lock()
+	if len(sc.entries) >= sc.config.MaxEntries {
+		sc.purgeLocked(time.Now())
+	}
+	if len(sc.entries) >= sc.config.MaxEntries {
+		sc.evictOldestLocked()
+	}
+	sc.seq++
+	id := fmt.Sprintf("llm-cache-%d", sc.seq)
+	sc.entries[id] = &semanticEntry{scope: query.scope, body: body, expires: time.Now().Add(ttl)}
+	sc.mutex.Unlock()
+
+	if err := sc.vectors.AddVector(id, query.vector); err != nil {
+		log.Printf("Failed to add semantic cache vector: %v", err)
+		sc.mutex.Lock()
+		delete(sc.entries, id)
+		sc.mutex.Unlock()
+	}
+}
+
+// purgeLoop 定期清理过期条目
+func (sc *SemanticCache) purgeLoop() {
+	defer sc.wg.Done()
+
+	interval := sc.config.TTL / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sc.mutex.Lock()
+			sc.purgeLocked(time.Now())
+			sc.mutex.Unlock()
+		case <-sc.stopCh:
+			return
+		}
+	}
+}
+
+// purgeLocked 删除过期条目，调用方持有写锁
+func (sc *SemanticCache) purgeLocked(now time.Time) {
+	for id, entry := range sc.entries {
+		if !now.Before(entry.expires) {
+			sc.removeLocked(id)
+		}
+	}
+}
+
+// evictOldestLocked 淘汰最早过期的条目，调用方持有写锁
+func (sc *SemanticCache) evictOldestLocked() {
+	oldest := ""
+	var expires time.Time
+	for id, entry := range sc.entries {
+		if oldest == "" || entry.expires.Before(expires) {
+			oldest, expires = id, entry.expires
+		}
+	}
+	if oldest != "" {
+		sc.removeLocked(oldest)
+		sc.evicted++
+	}
+}
+
+// removeLocked 删除条目及其向量
+func (sc *SemanticCache) removeLocked(id string) {
+	delete(sc.entries, id)
+	if err := sc.vectors.DeleteVector(id); err != nil {
+		log.Printf("Failed to delete semantic cache vector %s: %v", id, err)
+	}
+}
+
+// Stats 获取缓存统计
+func (sc *SemanticCache) Stats() map[string]interface{} {
+	sc.mutex.RLock()
+	entries, evicted := len(sc.entries), sc.evicted
+	sc.mutex.RUnlock()
+
+	hits := atomic.LoadInt64(&sc.hits)
+	misses := atomic.LoadInt64(&sc.misses)
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+	return map[string]interface{}{
+		"entries":   entries,
+		"hits":      hits,
+		"misses":    misses,
+		"evicted":   evicted,
+		"hit_rate":  hitRate,
+		"threshold": sc.config.Threshold,
+	}
+}
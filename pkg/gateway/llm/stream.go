@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// maxStreamLineBytes 计量时缓存的未完整SSE行上限，超过时丢弃该行不计量
+const maxStreamLineBytes = 1 << 20
+
+// streamLimitError 流式响应中途超出令牌限额或预算
+type streamLimitError struct {
+	errType string
+	code    string
+	message string
+}
+
+// Error 实现error接口
+func (e *streamLimitError) Error() string {
+	return e.message
+}
+
+// streamMeter 包装流式输出，逐行解析转发给客户端的OpenAI格式SSE事件，实时估算输出令牌数；
+// 事件携带usage时以实际用量为准。check返回错误时在当前事件结束后停止转发
+type streamMeter struct {
+	dst        streamWriter
+	pending    []byte
+	skipping   bool // 当前行超过上限，跳过到行尾
+	boundary   bool // 已写出的内容在事件边界上
+	completion int64
+	usage      *streamUsage
+	limited    error
+	check      func(completion int64) error
+}
+
+// streamUsage 流式响应末尾事件中的实际用量
+type streamUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+// newStreamMeter 创建流式计量
+func newStreamMeter(dst streamWriter, check func(completion int64) error) *streamMeter {
+	return &streamMeter{dst: dst, boundary: true, check: check}
+}
+
+// Write 按行转发并计量，超限后写完当前事件即返回错误，保证客户端收到的事件完整
+func (sm *streamMeter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if sm.limited != nil && sm.boundary {
+			return written, sm.limited
+		}
+
+		segment := p
+		if idx := bytes.IndexByte(p, '\n'); idx >= 0 {
+			segment = p[:idx+1]
+		}
+		n, err := sm.dst.Write(segment)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(segment):]
+		sm.consume(segment)
+	}
+
+	if sm.limited != nil && sm.boundary {
+		return written, sm.limited
+	}
+	return written, nil
+}
+
+// Flush 刷新输出
+func (sm *streamMeter) Flush() {
+	sm.dst.Flush()
+}
+
+// consume 计量一行或一行的一部分，不完整的行留到下次写出
+func (sm *streamMeter) consume(segment []byte) {
+	if segment[len(segment)-1] != '\n' {
+		sm.boundary = false
+		if !sm.skipping {
+			sm.pending = append(sm.pending, segment...)
+			if len(sm.pending) > maxStreamLineBytes {
+				sm.pending, sm.skipping = sm.pending[:0], true
+			}
+		}
+		return
+	}
+
+	line := segment
+	if len(sm.pending) > 0 {
+		line = append(sm.pending, segment...)
+	}
+	skipped := sm.skipping
+	sm.pending, sm.skipping = sm.pending[:0], false
+
+	line = bytes.TrimSpace(line)
+	sm.boundary = len(line) == 0 && !skipped
+	if skipped || len(line) == 0 {
+		return
+	}
+
+	before := sm.completion
+	sm.parseLine(line)
+	if sm.check != nil && sm.limited == nil && sm.completion != before {
+		sm.limited = sm.check(sm.completion)
+	}
+}
+
+// parseLine 解析一行SSE数据事件
+func (sm *streamMeter) parseLine(line []byte) {
+	chunk, ok := parseStreamChunk(line)
+	if !ok {
+		return
+	}
+
+	for _, choice := range chunk.Choices {
+		sm.completion += int64(EstimateTokens(choice.Text) + EstimateTokens(choice.Delta.Content))
+		for _, call := range choice.Delta.ToolCalls {
+			sm.completion += int64(EstimateTokens(call.Function.Arguments))
+		}
+	}
+	if chunk.Usage != nil {
+		sm.usage = chunk.Usage
+	}
+}
+
+// streamChunk OpenAI格式的流式事件
+type streamChunk struct {
+	Choices []struct {
+		Text  string `json:"text"`
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *streamUsage `json:"usage"`
+}
+
+// parseStreamChunk 解析一行SSE数据事件，非数据行和[DONE]返回false
+func parseStreamChunk(line []byte) (*streamChunk, bool) {
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return nil, false
+	}
+	data := bytes.TrimSpace(line[5:])
+	if len(data) == 0 || data[0] != '{' {
+		return nil, false
+	}
+
+	var chunk streamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, false
+	}
+	return &chunk, true
+}
+
+// tokens 获取用量，提供方返回了usage时使用实际用量，否则提示词使用估算值、输出使用实时计数
+func (sm *streamMeter) tokens(estimatedPrompt int64) (prompt, completion int64, exact bool) {
+	if sm.usage != nil {
+		return sm.usage.PromptTokens, sm.usage.CompletionTokens, true
+	}
+	return estimatedPrompt, sm.completion, false
+}
+
+// streamErrorEvent 流式响应中途终止时写出的错误事件
+func streamErrorEvent(err *streamLimitError) []byte {
+	return []byte(fmt.Sprintf("data: %s\n\ndata: [DONE]\n\n", errorBody(err.message, err.errType, err.code)))
+}
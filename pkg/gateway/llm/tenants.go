@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// tenantPolicy 租户的模型白名单和生成参数上限
+type tenantPolicy struct {
+	tenant         string
+	models         []string // 为空时不限制
+	maxTokens      int
+	maxTemperature *float64
+	maxN           int
+}
+
+// tenantTable 租户策略表
+type tenantTable struct {
+	tenants    map[string]*tenantPolicy
+	fallback   *tenantPolicy // "*"，未单独配置的租户和未识别租户使用
+	rejections map[string]int64
+	mutex      sync.Mutex
+}
+
+// tenantViolation 请求违反租户策略
+type tenantViolation struct {
+	code    string // model_not_allowed / parameter_limit_exceeded
+	message string
+}
+
+func (v *tenantViolation) Error() string {
+	return v.message
+}
+
+// newTenantTable 创建租户策略表
+func newTenantTable(configs []types.LLMTenantConfig) (*tenantTable, error) {
+	tt := &tenantTable{
+		tenants:    make(map[string]*tenantPolicy),
+		rejections: make(map[string]int64),
+	}
+
+	for _, cfg := range configs {
+		if cfg.Tenant == "" {
+			return nil, fmt.Errorf("tenant policy requires a tenant")
+		}
+		if cfg.MaxTokens < 0 || cfg.MaxN < 0 || (cfg.MaxTemperature != nil && *cfg.MaxTemperature < 0) {
+			return nil, fmt.Errorf("tenant policy %s has negative max_tokens, max_temperature or max_n", cfg.Tenant)
+		}
+		if _, exists := tt.tenants[cfg.Tenant]; exists || (cfg.Tenant == "*" && tt.fallback != nil) {
+			return nil, fmt.Errorf("duplicate tenant policy %s", cfg.Tenant)
+		}
+
+		policy := &tenantPolicy{
+			tenant:         cfg.Tenant,
+			models:         cfg.Models,
+			maxTokens:      cfg.MaxTokens,
+			maxTemperature: cfg.MaxTemperature,
+			maxN:           cfg.MaxN,
+		}
+		if cfg.Tenant == "*" {
+			tt.fallback = policy
+		} else {
+			tt.tenants[cfg.Tenant] = policy
+		}
+	}
+
+	return tt, nil
+}
+
+// lookup 查找租户策略，没有策略时返回nil
+func (tt *tenantTable) lookup(tenant string) *tenantPolicy {
+	if policy, ok := tt.tenants[tenant]; ok {
+		return policy
+	}
+	return tt.fallback
+}
+
+// check 检查租户能否以请求中的参数调用模型
+func (tt *tenantTable) check(tenant, model string, body []byte) error {
+	policy := tt.lookup(tenant)
+	if policy == nil {
+		return nil
+	}
+
+	err := policy.check(tenant, model, body)
+	if err != nil {
+		tt.mutex.Lock()
+		tt.rejections[tenantLabel(tenant)]++
+		tt.mutex.Unlock()
+	}
+	return err
+}
+
+// Stats 获取按租户统计的拒绝次数
+func (tt *tenantTable) Stats() map[string]int64 {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	rejections := make(map[string]int64, len(tt.rejections))
+	for tenant, n := range tt.rejections {
+		rejections[tenant] = n
+	}
+	return rejections
+}
+
+// allows 模型是否在白名单中
+func (tp *tenantPolicy) allows(model string) bool {
+	if len(tp.models) == 0 {
+		return true
+	}
+	for _, pattern := range tp.models {
+		if matchModel(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// check 检查模型白名单和max_tokens、max_completion_tokens、temperature、n的上限
+func (tp *tenantPolicy) check(tenant, model string, body []byte) error {
+	if !tp.allows(model) {
+		return &tenantViolation{
+			code:    "model_not_allowed",
+			message: fmt.Sprintf("Tenant %s is not allowed to use model %s", tenantLabel(tenant), model),
+		}
+	}
+
+	var params struct {
+		MaxTokens           *int     `json:"max_tokens"`
+		MaxCompletionTokens *int     `json:"max_completion_tokens"`
+		Temperature         *float64 `json:"temperature"`
+		N                   *int     `json:"n"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return nil
+	}
+
+	exceeds := func(name string, value, limit interface{}) error {
+		return &tenantViolation{
+			code:    "parameter_limit_exceeded",
+			message: fmt.Sprintf("%s %v exceeds the limit of %v for tenant %s", name, value, limit, tenantLabel(tenant)),
+		}
+	}
+	if tp.maxTokens > 0 {
+		if params.MaxTokens != nil && *params.MaxTokens > tp.maxTokens {
+			return exceeds("max_tokens", *params.MaxTokens, tp.maxTokens)
+		}
+		if params.MaxCompletionTokens != nil && *params.MaxCompletionTokens > tp.maxTokens {
+			return exceeds("max_completion_tokens", *params.MaxCompletionTokens, tp.maxTokens)
+		}
+	}
+	if tp.maxTemperature != nil && params.Temperature != nil && *params.Temperature > *tp.maxTemperature {
+		return exceeds("temperature", *params.Temperature, *tp.maxTemperature)
+	}
+	if tp.maxN > 0 && params.N != nil && *params.N > tp.maxN {
+		return exceeds("n", *params.N, tp.maxN)
+	}
+	return nil
+}
+
+// matchModel 模型名匹配，支持"gpt-4*"前缀匹配和"*"
+func matchModel(pattern, model string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(model, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == model
+}
+
+// tenantLabel 未识别租户显示为default
+func tenantLabel(tenant string) string {
+	if tenant == "" {
+		return "default"
+	}
+	return tenant
+}
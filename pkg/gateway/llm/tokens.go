@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"encoding/json"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 对话格式的额外令牌（与OpenAI cookbook中cl100k_base模型的计算方式一致）
+const (
+	tokensPerMessage = 3 // 每条消息的角色和分隔符
+	tokensPerName    = 1 // 消息携带name时
+	tokensReplyPrime = 3 // 回复的起始标记
+)
+
+// EstimateTokens 估算文本的令牌数，近似tiktoken的cl100k_base编码：按其预分词规则切分为
+// 单词（可带一个前导空格）、数字（每3位一组）、标点串、换行串，单词约6个字符一个令牌，
+// 非ASCII字符每个字符一个令牌，4字节字符（如emoji）两个令牌
+func EstimateTokens(text string) int {
+	tokens := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case r >= utf8.RuneSelf:
+			if size == 4 {
+				tokens += 2
+			} else {
+				tokens++
+			}
+			i += size
+
+		case r == ' ' && i+1 < len(text) && isWordByte(text[i+1]):
+			// 前导空格并入后面的单词
+			i++
+
+		case isWordByte(text[i]):
+			n := runLength(text[i:], isWordByte)
+			tokens += (n + 5) / 6
+			i += n
+
+		case text[i] >= '0' && text[i] <= '9':
+			n := runLength(text[i:], func(b byte) bool { return b >= '0' && b <= '9' })
+			tokens += (n + 2) / 3
+			i += n
+
+		case text[i] == '\n' || text[i] == '\r':
+			i += runLength(text[i:], func(b byte) bool { return b == '\n' || b == '\r' })
+			tokens++
+
+		case text[i] == ' ' || text[i] == '\t':
+			// 连续空白合并为一个令牌
+			i += runLength(text[i:], func(b byte) bool { return b == ' ' || b == '\t' })
+			tokens++
+
+		default:
+			n := runLength(text[i:], isPunctByte)
+			if n == 0 {
+				n = 1
+			}
+			tokens += (n + 1) / 2
+			i += n
+		}
+	}
+	return tokens
+}
+
+// isWordByte ASCII字母
+func isWordByte(b byte) bool {
+	return b < utf8.RuneSelf && unicode.IsLetter(rune(b))
+}
+
+// isPunctByte ASCII标点和符号
+func isPunctByte(b byte) bool {
+	return b < utf8.RuneSelf && b > ' ' && !isWordByte(b) && !(b >= '0' && b <= '9')
+}
+
+// runLength 从开头起连续满足条件的字节数
+func runLength(s string, match func(byte) bool) int {
+	n := 0
+	for n < len(s) && match(s[n]) {
+		n++
+	}
+	return n
+}
+
+// CountPromptTokens 估算OpenAI格式请求的输入令牌数，请求体无法解析时返回0
+func CountPromptTokens(endpoint string, body []byte) int {
+	switch endpoint {
+	case EndpointChatCompletions:
+		var request struct {
+			Messages []struct {
+				Role    string          `json:"role"`
+				Name    string          `json:"name"`
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			return 0
+		}
+
+		tokens := tokensReplyPrime
+		for _, message := range request.Messages {
+			tokens += tokensPerMessage + EstimateTokens(message.Role) + contentTokens(message.Content)
+			if message.Name != "" {
+				tokens += tokensPerName + EstimateTokens(message.Name)
+			}
+		}
+		return tokens
+
+	case EndpointCompletions:
+		var request struct {
+			Prompt json.RawMessage `json:"prompt"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			return 0
+		}
+		return contentTokens(request.Prompt)
+
+	case EndpointEmbeddings:
+		var request struct {
+			Input json.RawMessage `json:"input"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			return 0
+		}
+		return contentTokens(request.Input)
+	}
+	return 0
+}
+
+// contentTokens 估算内容的令牌数，内容可以是字符串、字符串数组、令牌ID数组或多模态内容片段数组
+func contentTokens(raw json.RawMessage) int {
+	if len(raw) == 0 {
+		return 0
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return EstimateTokens(text)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return 0
+	}
+
+	tokens := 0
+	for _, item := range items {
+		var part struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		switch {
+		case json.Unmarshal(item, &text) == nil:
+			tokens += EstimateTokens(text)
+		case json.Unmarshal(item, &part) == nil:
+			tokens += EstimateTokens(part.Text)
+		default:
+			// 令牌ID数组或嵌套的令牌ID数组
+			var ids []int
+			if json.Unmarshal(item, &ids) == nil {
+				tokens += len(ids)
+			} else {
+				tokens++
+			}
+		}
+	}
+	return tokens
+}
+
+// responseUsage 解析OpenAI格式响应中的用量
+func responseUsage(body []byte) (prompt, completion int64, ok bool) {
+	var response struct {
+		Usage *struct {
+			PromptTokens     int64 `json:"prompt_tokens"`
+			CompletionTokens int64 `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Usage == nil {
+		return 0, 0, false
+	}
+	return response.Usage.PromptTokens, response.Usage.CompletionTokens, true
+}
@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"log"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/llm-aware-gateway/pkg/interfaces"
@@ -16,6 +18,25 @@ type metricsCollector struct {
 	clusterSize          *prometheus.GaugeVec
 	clusterSeverity      *prometheus.GaugeVec
 	policyApplied        *prometheus.CounterVec
+	streamOutcomes       *prometheus.CounterVec
+	streamEvents         *prometheus.CounterVec
+	clientCancels        *prometheus.CounterVec
+	upstreamVersions     *prometheus.CounterVec
+	upstreamVersionTime  *prometheus.HistogramVec
+	ipBlocked            *prometheus.CounterVec
+	wafMatches           *prometheus.CounterVec
+	llmTokens            *prometheus.CounterVec
+	llmCost              *prometheus.CounterVec
+	schemaViolations     *prometheus.CounterVec
+	clusterSnapshots     *prometheus.CounterVec
+	snapshotCompatible   prometheus.Gauge
+	semanticCache        *prometheus.CounterVec
+	stuckRequests        *prometheus.CounterVec
+	promptGuard          *prometheus.CounterVec
+	redactions           *prometheus.CounterVec
+	moderation           *prometheus.CounterVec
+	contextGuard         *prometheus.CounterVec
+	stageIsolation       *prometheus.CounterVec
 }
 
 // NewMetricsCollector 创建指标收集器
@@ -77,10 +98,162 @@ func NewMetricsCollector() interfaces.MetricsCollector {
 			},
 			[]string{"cluster_id", "policy_type"},
 		),
+
+		streamOutcomes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_stream_outcomes_total",
+				Help: "Total number of streamed responses by final outcome",
+			},
+			[]string{"path", "outcome"},
+		),
+
+		streamEvents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_stream_events_total",
+				Help: "Total number of SSE events relayed to clients",
+			},
+			[]string{"path"},
+		),
+
+		clientCancels: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_client_cancellations_total",
+				Help: "Total number of requests canceled by client disconnect, by stage",
+			},
+			[]string{"path", "stage"},
+		),
+
+		upstreamVersions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_upstream_version_requests_total",
+				Help: "Total number of requests per route and upstream version",
+			},
+			[]string{"route", "version", "status"},
+		),
+
+		upstreamVersionTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "gateway_upstream_version_duration_seconds",
+				Help:    "Request duration per route and upstream version",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"route", "version"},
+		),
+
+		ipBlocked: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_ip_blocked_total",
+				Help: "Total number of requests blocked by the IP allow/deny lists",
+			},
+			[]string{"reason"},
+		),
+
+		wafMatches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_waf_matches_total",
+				Help: "Total number of requests matched by WAF rules",
+			},
+			[]string{"rule", "action"},
+		),
+
+		llmTokens: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_llm_tokens_total",
+				Help: "Total number of LLM tokens by model and type (prompt/completion)",
+			},
+			[]string{"model", "type"},
+		),
+
+		llmCost: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_llm_cost_usd_total",
+				Help: "Total LLM spend in USD by model and tenant",
+			},
+			[]string{"model", "tenant"},
+		),
+
+		schemaViolations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_schema_violations_total",
+				Help: "Total number of request/response bodies failing route schema validation",
+			},
+			[]string{"route", "direction"},
+		),
+
+		clusterSnapshots: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_cluster_snapshots_total",
+				Help: "Total number of cluster snapshots received from the control plane",
+			},
+			[]string{"result"},
+		),
+
+		semanticCache: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_llm_semantic_cache_total",
+				Help: "Total number of LLM semantic cache lookups by result",
+			},
+			[]string{"model", "result"},
+		),
+
+		stuckRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_stuck_requests_total",
+				Help: "Total number of requests flagged by the watchdog for exceeding their expected duration",
+			},
+			[]string{"route", "action"},
+		),
+
+		promptGuard: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_prompt_guard_total",
+				Help: "Total number of requests acted on by prompt injection detection",
+			},
+			[]string{"action"},
+		),
+
+		redactions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_llm_redactions_total",
+				Help: "Total number of sensitive values redacted from LLM prompts by pattern",
+			},
+			[]string{"pattern"},
+		),
+
+		moderation: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_llm_moderation_violations_total",
+				Help: "Total number of LLM responses blocked by content moderation",
+			},
+			[]string{"inspector", "category"},
+		),
+
+		contextGuard: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_llm_context_guard_total",
+				Help: "Total number of LLM requests rejected or truncated for exceeding the model context window",
+			},
+			[]string{"model", "action"},
+		),
+
+		stageIsolation: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "gateway_stage_isolation_total",
+				Help: "Total number of non-critical stage calls skipped by failure isolation",
+			},
+			[]string{"stage", "reason"},
+		),
+
+		snapshotCompatible: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "gateway_cluster_snapshot_compatible",
+				Help: "Whether the latest cluster snapshot matches the local embedding model (1 = compatible, 0 = rejected)",
+			},
+		),
 	}
 
-	// 注册所有指标
-	prometheus.MustRegister(
+	// 注册所有指标；同名指标已注册时（如同一进程内创建多个网关）只记录日志，指标照常计数
+	for _, collector := range []prometheus.Collector{
 		mc.requestTotal,
 		mc.requestDuration,
 		mc.rateLimitHits,
@@ -88,7 +261,30 @@ func NewMetricsCollector() interfaces.MetricsCollector {
 		mc.clusterSize,
 		mc.clusterSeverity,
 		mc.policyApplied,
-	)
+		mc.streamOutcomes,
+		mc.streamEvents,
+		mc.clientCancels,
+		mc.upstreamVersions,
+		mc.upstreamVersionTime,
+		mc.ipBlocked,
+		mc.wafMatches,
+		mc.llmTokens,
+		mc.llmCost,
+		mc.schemaViolations,
+		mc.clusterSnapshots,
+		mc.snapshotCompatible,
+		mc.semanticCache,
+		mc.stuckRequests,
+		mc.promptGuard,
+		mc.redactions,
+		mc.moderation,
+		mc.contextGuard,
+		mc.stageIsolation,
+	} {
+		if err := prometheus.Register(collector); err != nil {
+			log.Printf("Failed to register gateway metric: %v", err)
+		}
+	}
 
 	return mc
 }
@@ -123,3 +319,94 @@ func (mc *metricsCollector) UpdateClusterSeverity(clusterID string, severity flo
 func (mc *metricsCollector) RecordPolicyApplied(clusterID string, policyType types.PolicyType) {
 	mc.policyApplied.WithLabelValues(clusterID, string(policyType)).Inc()
 }
+
+// RecordStreamOutcome 记录流式响应结果
+func (mc *metricsCollector) RecordStreamOutcome(path, outcome string, events int64) {
+	mc.streamOutcomes.WithLabelValues(path, outcome).Inc()
+	mc.streamEvents.WithLabelValues(path).Add(float64(events))
+}
+
+// RecordClientCancel 记录客户端断开导致的取消，与上游错误分开统计
+func (mc *metricsCollector) RecordClientCancel(path, stage string) {
+	mc.clientCancels.WithLabelValues(path, stage).Inc()
+}
+
+// RecordUpstreamVersion 记录按版本拆分的请求，用于对比金丝雀版本健康度
+func (mc *metricsCollector) RecordUpstreamVersion(route, version, status string, duration float64) {
+	mc.upstreamVersions.WithLabelValues(route, version, status).Inc()
+	mc.upstreamVersionTime.WithLabelValues(route, version).Observe(duration)
+}
+
+// RecordIPBlocked 记录被IP访问控制拦截的请求
+func (mc *metricsCollector) RecordIPBlocked(reason string) {
+	mc.ipBlocked.WithLabelValues(reason).Inc()
+}
+
+// RecordWAFMatch 记录WAF规则命中
+func (mc *metricsCollector) RecordWAFMatch(rule, action string) {
+	mc.wafMatches.WithLabelValues(rule, action).Inc()
+}
+
+// RecordLLMUsage 记录LLM令牌用量和花费
+func (mc *metricsCollector) RecordLLMUsage(model, tenant string, promptTokens, completionTokens int64, cost float64) {
+	mc.llmTokens.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	mc.llmTokens.WithLabelValues(model, "completion").Add(float64(completionTokens))
+	if cost > 0 {
+		mc.llmCost.WithLabelValues(model, tenant).Add(cost)
+	}
+}
+
+// RecordSchemaViolation 记录Schema校验失败
+func (mc *metricsCollector) RecordSchemaViolation(route, direction string) {
+	mc.schemaViolations.WithLabelValues(route, direction).Inc()
+}
+
+// RecordClusterSnapshot 记录簇快照加载结果
+func (mc *metricsCollector) RecordClusterSnapshot(applied bool) {
+	if applied {
+		mc.clusterSnapshots.WithLabelValues("applied").Inc()
+		mc.snapshotCompatible.Set(1)
+		return
+	}
+	mc.clusterSnapshots.WithLabelValues("rejected").Inc()
+	mc.snapshotCompatible.Set(0)
+}
+
+// RecordSemanticCache 记录LLM语义缓存查找结果
+func (mc *metricsCollector) RecordSemanticCache(model, result string) {
+	mc.semanticCache.WithLabelValues(model, result).Inc()
+}
+
+// RecordStuckRequest 记录看门狗发现的卡住请求
+func (mc *metricsCollector) RecordStuckRequest(route string, canceled bool) {
+	action := "flagged"
+	if canceled {
+		action = "canceled"
+	}
+	mc.stuckRequests.WithLabelValues(route, action).Inc()
+}
+
+// RecordPromptGuard 记录提示词注入检测动作
+func (mc *metricsCollector) RecordPromptGuard(action string) {
+	mc.promptGuard.WithLabelValues(action).Inc()
+}
+
+// RecordRedaction 记录LLM提示词脱敏次数
+func (mc *metricsCollector) RecordRedaction(pattern string, count int) {
+	mc.redactions.WithLabelValues(pattern).Add(float64(count))
+}
+
+// RecordModerationViolation 记录LLM响应审核违规
+func (mc *metricsCollector) RecordModerationViolation(inspector, category string) {
+	mc.moderation.WithLabelValues(inspector, category).Inc()
+}
+
+// RecordContextGuard 记录超出上下文窗口的LLM请求的处理动作
+func (mc *metricsCollector) RecordContextGuard(model, action string) {
+	mc.contextGuard.WithLabelValues(model, action).Inc()
+}
+
+// RecordStageIsolation 记录因超时、panic或自动旁路而跳过的非关键阶段调用
+func (mc *metricsCollector) RecordStageIsolation(stage, reason string) {
+	mc.stageIsolation.WithLabelValues(stage, reason).Inc()
+}
@@ -0,0 +1,363 @@
+package metricsexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultTopic kafka传输的默认topic
+const defaultTopic = "gateway-metrics"
+
+// publisher 快照发送方式
+type publisher interface {
+	publish(ctx context.Context, snapshot *types.MetricsSnapshot) error
+	close() error
+}
+
+// Exporter 按簇累计请求、错误、拒绝计数，定期以增量快照推送到控制面。
+// 推送失败的快照保留原序号在下次推送时重发，期间的新增计数进入下一个快照
+type Exporter struct {
+	config    types.MetricsExportConfig
+	kafka     *types.KafkaConfig
+	instance  string
+	started   time.Time
+	publisher publisher
+	pending   map[string]*types.ClusterCounters // 上一快照以来的增量
+	versions  map[string]map[string]*types.ClusterCounters
+	breakers  interfaces.BreakerStatsSource // 可选，簇熔断统计随快照推送
+	since     time.Time
+	unacked   *types.MetricsSnapshot // 推送失败待重发的快照
+	seq       uint64
+	mutex     sync.Mutex
+	flushing  sync.Mutex
+	pushed    int64
+	failed    int64
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewExporter 创建计数快照推送
+func NewExporter(config *types.MetricsExportConfig, kafkaConfig *types.KafkaConfig) (*Exporter, error) {
+	cfg := *config
+	if cfg.Transport == "" {
+		cfg.Transport = types.MetricsExportHTTP
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = defaultTopic
+	}
+	if cfg.Instance == "" {
+		cfg.Instance, _ = os.Hostname()
+	}
+
+	e := &Exporter{
+		config:   cfg,
+		kafka:    kafkaConfig,
+		instance: cfg.Instance,
+		started:  time.Now(),
+		pending:  make(map[string]*types.ClusterCounters),
+		versions: make(map[string]map[string]*types.ClusterCounters),
+		stopCh:   make(chan struct{}),
+	}
+	e.since = e.started
+
+	switch cfg.Transport {
+	case types.MetricsExportHTTP:
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("metrics export endpoint is required for http transport")
+		}
+		e.publisher = &httpPublisher{
+			url:    strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/metrics",
+			apiKey: os.ExpandEnv(cfg.APIKey),
+			client: &http.Client{},
+		}
+	case types.MetricsExportKafka:
+		if kafkaConfig == nil || len(kafkaConfig.Brokers) == 0 {
+			return nil, fmt.Errorf("kafka brokers are required for kafka transport")
+		}
+	default:
+		return nil, fmt.Errorf("unknown metrics export transport %q", cfg.Transport)
+	}
+
+	return e, nil
+}
+
+// SetBreakerSource 设置熔断统计来源，每个快照附带上一快照以来的簇熔断统计，需在Start之前调用
+func (e *Exporter) SetBreakerSource(source interfaces.BreakerStatsSource) {
+	e.breakers = source
+}
+
+// Middleware 请求结束后按簇累计计数，需放在限流、熔断等会拒绝请求的中间件之前
+func (e *Exporter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		clusterID := c.GetString("cluster_id")
+		if clusterID == "" {
+			clusterID = types.UnclusteredID
+		}
+		rejected := utils.IsRequestRejected(c)
+		failed := !rejected && utils.IsRequestFailed(c)
+
+		e.mutex.Lock()
+		count(e.pending, clusterID, failed, rejected)
+		if version := c.GetInt64("policy_version"); version > e.pending[clusterID].PolicyVersion {
+			e.pending[clusterID].PolicyVersion = version
+		}
+		// 流量拆分的路由同时按上游版本计数，供金丝雀分析比较错误率
+		if version := c.GetString("upstream_version"); version != "" {
+			route := c.GetString("route_name")
+			if e.versions[route] == nil {
+				e.versions[route] = make(map[string]*types.ClusterCounters)
+			}
+			count(e.versions[route], version, failed, rejected)
+		}
+		e.mutex.Unlock()
+	}
+}
+
+// count 累计一个请求
+func count(pending map[string]*types.ClusterCounters, key string, failed, rejected bool) {
+	counters, exists := pending[key]
+	if !exists {
+		counters = &types.ClusterCounters{}
+		pending[key] = counters
+	}
+	counters.Requests++
+	if failed {
+		counters.Errors++
+	}
+	if rejected {
+		counters.Rejections++
+	}
+}
+
+// Flush 重发上次失败的快照并推送新增计数，没有新增计数和熔断统计时不推送
+func (e *Exporter) Flush() error {
+	e.flushing.Lock()
+	defer e.flushing.Unlock()
+
+	if e.unacked != nil {
+		if err := e.send(e.unacked); err != nil {
+			return err
+		}
+		e.unacked = nil
+	}
+
+	var breakers map[string]*types.BreakerWindowStats
+	if e.breakers != nil {
+		breakers = e.breakers.DrainBreakerStats()
+	}
+
+	e.mutex.Lock()
+	if len(e.pending) == 0 && len(breakers) == 0 {
+		e.mutex.Unlock()
+		return nil
+	}
+	now := time.Now()
+	e.seq++
+	snapshot := &types.MetricsSnapshot{
+		Instance: e.instance,
+		Started:  e.started,
+		Sequence: e.seq,
+		Start:    e.since,
+		End:      now,
+		Clusters: e.pending,
+	}
+	if len(breakers) > 0 {
+		snapshot.Breakers = breakers
+	}
+	if len(e.versions) > 0 {
+		snapshot.Versions = e.versions
+		e.versions = make(map[string]map[string]*types.ClusterCounters)
+	}
+	e.pending = make(map[string]*types.ClusterCounters)
+	e.since = now
+	e.mutex.Unlock()
+
+	if err := e.send(snapshot); err != nil {
+		e.unacked = snapshot
+		return err
+	}
+	return nil
+}
+
+// send 推送单个快照
+func (e *Exporter) send(snapshot *types.MetricsSnapshot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout)
+	defer cancel()
+
+	if err := e.publisher.publish(ctx, snapshot); err != nil {
+		atomic.AddInt64(&e.failed, 1)
+		return fmt.Errorf("failed to push metrics snapshot %d: %v", snapshot.Sequence, err)
+	}
+	atomic.AddInt64(&e.pushed, 1)
+	return nil
+}
+
+// Start 启动定期推送
+func (e *Exporter) Start() error {
+	if e.config.Transport == types.MetricsExportKafka {
+		producer, err := newKafkaPublisher(e.kafka.Brokers, e.config.Topic, e.instance)
+		if err != nil {
+			return err
+		}
+		e.publisher = producer
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := e.Flush(); err != nil {
+					log.Printf("Metrics export failed: %v", err)
+				}
+			case <-e.stopCh:
+				return
+			}
+		}
+	}()
+
+	log.Printf("Metrics export started (instance=%s, transport=%s, interval=%v)", e.instance, e.config.Transport, e.config.Interval)
+	return nil
+}
+
+// Stop 停止定期推送并推送剩余计数
+func (e *Exporter) Stop() {
+	close(e.stopCh)
+	e.wg.Wait()
+
+	if e.publisher == nil {
+		return
+	}
+	if err := e.Flush(); err != nil {
+		log.Printf("Failed to push final metrics snapshot: %v", err)
+	}
+	if err := e.publisher.close(); err != nil {
+		log.Printf("Failed to close metrics export publisher: %v", err)
+	}
+}
+
+// Stats 获取推送统计
+func (e *Exporter) Stats() map[string]interface{} {
+	e.flushing.Lock()
+	seq, unacked := e.seq, e.unacked != nil
+	e.flushing.Unlock()
+
+	e.mutex.Lock()
+	pending := len(e.pending)
+	e.mutex.Unlock()
+
+	return map[string]interface{}{
+		"instance":         e.instance,
+		"transport":        e.config.Transport,
+		"seq":              seq,
+		"pushed":           atomic.LoadInt64(&e.pushed),
+		"failed":           atomic.LoadInt64(&e.failed),
+		"pending_clusters": pending,
+		"unacked":          unacked,
+	}
+}
+
+// httpPublisher 推送到控制面/v1/metrics
+type httpPublisher struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func (p *httpPublisher) publish(ctx context.Context, snapshot *types.MetricsSnapshot) error {
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *httpPublisher) close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// kafkaPublisher 以实例标识为消息键写入Kafka，同一实例的快照进入同一分区保持顺序
+type kafkaPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+	key      string
+}
+
+// newKafkaPublisher 创建Kafka同步生产者
+func newKafkaPublisher(brokers []string, topic, key string) (*kafkaPublisher, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForLocal
+	saramaConfig.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %v", err)
+	}
+	return &kafkaPublisher{producer: producer, topic: topic, key: key}, nil
+}
+
+func (p *kafkaPublisher) publish(_ context.Context, snapshot *types.MetricsSnapshot) error {
+	value, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+
+	_, _, err = p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(p.key),
+		Value: sarama.ByteEncoder(value),
+	})
+	return err
+}
+
+func (p *kafkaPublisher) close() error {
+	return p.producer.Close()
+}
@@ -1,16 +1,24 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/llm-aware-gateway/pkg/gateway/decision"
 	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
 	"github.com/llm-aware-gateway/pkg/utils"
 )
 
@@ -21,6 +29,12 @@ type Middleware struct {
 	errorSampler   interfaces.ErrorSampler
 	vectorAgent    interfaces.VectorAgent
 	metrics        interfaces.MetricsCollector
+	components     func() map[string]interface{} // 就绪检查的组件来源，未设置时只检查限流、熔断和采样
+
+	served          int64 // 通过限流熔断后处理的请求数
+	serverErrors    int64 // 5xx响应数
+	rateLimited     int64 // 限流拒绝数
+	breakerRejected int64 // 熔断拒绝数
 }
 
 // NewMiddleware 创建中间件管理器
@@ -40,6 +54,33 @@ func NewMiddleware(
 	}
 }
 
+// SetComponents 设置就绪检查的组件来源，需在开始处理请求之前调用
+func (m *Middleware) SetComponents(components func() map[string]interface{}) {
+	m.components = components
+}
+
+// readinessComponents 就绪检查的组件
+func (m *Middleware) readinessComponents() map[string]interface{} {
+	if m.components != nil {
+		return m.components()
+	}
+	return map[string]interface{}{
+		"rate_limiter":    m.rateLimiter,
+		"circuit_breaker": m.circuitBreaker,
+		"error_sampler":   m.errorSampler,
+	}
+}
+
+// Stats 获取请求处理统计
+func (m *Middleware) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"requests_served":  atomic.LoadInt64(&m.served),
+		"server_errors":    atomic.LoadInt64(&m.serverErrors),
+		"rate_limited":     atomic.LoadInt64(&m.rateLimited),
+		"breaker_rejected": atomic.LoadInt64(&m.breakerRejected),
+	}
+}
+
 // Recovery 恢复中间件
 func (m *Middleware) Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
@@ -50,23 +91,18 @@ func (m *Middleware) Recovery() gin.HandlerFunc {
 	})
 }
 
-// Logger 日志中间件
-func (m *Middleware) Logger() gin.HandlerFunc {
-	return gin.LoggerWithConfig(gin.LoggerConfig{
-		Formatter: func(param gin.LogFormatterParams) string {
-			return fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
-				param.ClientIP,
-				param.TimeStamp.Format(time.RFC1123),
-				param.Method,
-				param.Path,
-				param.Request.Proto,
-				param.StatusCode,
-				param.Latency,
-				param.Request.UserAgent(),
-				param.ErrorMessage,
-			)
-		},
-	})
+// RequestID 请求ID中间件
+func (m *Middleware) RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = utils.GenerateID()
+		}
+
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
 }
 
 // Tracing 链路追踪中间件
@@ -83,6 +119,47 @@ func (m *Middleware) Authentication() gin.HandlerFunc {
 	}
 }
 
+// AdminAuth 管理接口认证，支持Authorization: Bearer和X-API-Key，密钥支持${ENV}引用环境变量；
+// 管理接口与数据面共用端口，未配置密钥时拒绝所有请求
+func (m *Middleware) AdminAuth(apiKeys []string) gin.HandlerFunc {
+	keys := make([]string, 0, len(apiKeys))
+	for _, key := range apiKeys {
+		if key = os.ExpandEnv(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(keys) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Admin API keys are not configured",
+			})
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if auth := c.GetHeader("Authorization"); key == "" && strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		// 常量时间比较，逐个比较所有密钥
+		valid := false
+		for _, expected := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(expected)) == 1 {
+				valid = true
+			}
+		}
+		if key == "" || !valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or missing admin API key",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RateLimit 限流中间件
 func (m *Middleware) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -92,9 +169,19 @@ func (m *Middleware) RateLimit() gin.HandlerFunc {
 		}
 
 		// 检查是否允许请求
-		if !m.rateLimiter.Allow(c) {
+		allowed := m.rateLimiter.Allow(c)
+		clusterID := utils.ExtractServiceName(c)
+
+		if decision.Enabled(c) {
+			m.recordRateLimitDecision(c, allowed)
+		}
+		setRateLimitHeaders(c, allowed)
+
+		if !allowed {
+			atomic.AddInt64(&m.rateLimited, 1)
+			utils.RecordStageError(c, "rate_limit", fmt.Errorf("rate limit exceeded for %s", clusterID))
+
 			// 记录限流指标
-			clusterID := utils.ExtractServiceName(c)
 			if m.metrics != nil {
 				m.metrics.RecordRateLimitHit(clusterID, "RATE_LIMIT")
 			}
@@ -107,7 +194,39 @@ func (m *Middleware) RateLimit() gin.HandlerFunc {
 			return
 		}
 
+		start := time.Now()
 		c.Next()
+
+		// 只记录实际转发到上游的请求，上游返回429同样视为过载
+		if observer, ok := m.rateLimiter.(interfaces.UpstreamObserver); ok &&
+			c.GetString("upstream_target") != "" && !utils.IsClientCanceled(c) {
+			failed := utils.IsRequestFailed(c) || c.Writer.Status() == http.StatusTooManyRequests
+			observer.ObserveUpstream(c, time.Since(start), failed)
+		}
+	}
+}
+
+// setRateLimitHeaders 按限流器的检查结果返回X-RateLimit-Limit、X-RateLimit-Remaining、X-RateLimit-Reset（Unix秒），
+// 拒绝时附带Retry-After（秒），请求没有匹配到限流策略时不返回
+func setRateLimitHeaders(c *gin.Context, allowed bool) {
+	value, exists := c.Get("rate_limit_quota")
+	if !exists {
+		return
+	}
+	quota, ok := value.(types.RateLimitQuota)
+	if !ok {
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.FormatInt(quota.Limit, 10))
+	c.Header("X-RateLimit-Remaining", strconv.FormatInt(quota.Remaining, 10))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(float64(quota.Reset.UnixNano())/float64(time.Second))), 10))
+	if !allowed {
+		retryAfter := int64(math.Ceil(quota.RetryAfter.Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
 	}
 }
 
@@ -124,14 +243,26 @@ func (m *Middleware) CircuitBreaker() gin.HandlerFunc {
 		if m.vectorAgent != nil {
 			errorSignature := utils.ExtractErrorSignature(c)
 			if errorSignature != "" {
-				if id, err := m.vectorAgent.IdentifyCluster(errorSignature); err == nil {
-					clusterID = id
+				if id, similarity, err := m.vectorAgent.IdentifyClusterWithScore(errorSignature); err == nil {
+					clusterID = utils.NamespacedClusterID(c, id)
+					decision.RecordSimilarity(c, id, similarity)
 				}
 			}
 		}
 
+		// 保存簇ID到上下文，供后续中间件使用，熔断拒绝的请求同样归属到簇
+		c.Set("cluster_id", clusterID)
+
 		// 检查熔断器状态
-		if !m.circuitBreaker.Allow(c.Request.Context(), clusterID) {
+		allowed := m.circuitBreaker.Allow(c.Request.Context(), clusterID)
+		if decision.Enabled(c) {
+			m.recordBreakerDecision(c, clusterID, allowed)
+		}
+
+		if !allowed {
+			atomic.AddInt64(&m.breakerRejected, 1)
+			utils.RecordStageError(c, "circuit_breaker", fmt.Errorf("circuit breaker open for cluster %s", clusterID))
+
 			// 记录熔断指标
 			if m.metrics != nil {
 				m.metrics.RecordCircuitBreakerState(clusterID, 1) // 1 = OPEN
@@ -145,14 +276,14 @@ func (m *Middleware) CircuitBreaker() gin.HandlerFunc {
 			return
 		}
 
-		// 保存簇ID到上下文，供后续中间件使用
-		c.Set("cluster_id", clusterID)
-
 		// 执行请求
 		c.Next()
 
-		// 根据请求结果记录成功或失败
-		if c.Writer.Status() >= 500 {
+		// 根据请求结果记录成功或失败，客户端取消不影响熔断
+		if utils.IsClientCanceled(c) {
+			return
+		}
+		if utils.IsRequestFailed(c) {
 			m.circuitBreaker.RecordFailure(clusterID)
 		} else {
 			m.circuitBreaker.RecordSuccess(clusterID)
@@ -165,14 +296,18 @@ func (m *Middleware) ErrorSampling() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
-		// 检查是否有错误
-		if len(c.Errors) > 0 || c.Writer.Status() >= 400 {
+		// 客户端取消不是服务端错误，不参与采样聚类
+		if utils.IsClientCanceled(c) {
+			return
+		}
+
+		// 检查是否有错误，处理链中的注解不视为错误
+		lastErr := utils.LastRequestError(c)
+		if lastErr != nil || c.Writer.Status() >= 400 || utils.IsRequestFailed(c) {
 			if m.errorSampler != nil {
 				// 构造错误
-				var err error
-				if len(c.Errors) > 0 {
-					err = c.Errors.Last()
-				} else {
+				err := lastErr
+				if err == nil {
 					err = errors.New(http.StatusText(c.Writer.Status()))
 				}
 
@@ -195,6 +330,12 @@ func (m *Middleware) Metrics() gin.HandlerFunc {
 
 		c.Next()
 
+		atomic.AddInt64(&m.served, 1)
+		canceled := c.GetString("client_canceled")
+		if c.Writer.Status() >= 500 && canceled == "" {
+			atomic.AddInt64(&m.serverErrors, 1)
+		}
+
 		// 记录请求指标
 		if m.metrics != nil {
 			duration := time.Since(start).Seconds()
@@ -205,7 +346,21 @@ func (m *Middleware) Metrics() gin.HandlerFunc {
 			}
 
 			status := fmt.Sprintf("%d", c.Writer.Status())
+			if canceled != "" {
+				status = fmt.Sprintf("%d", utils.StatusClientClosedRequest)
+				m.metrics.RecordClientCancel(c.Request.URL.Path, canceled)
+			}
 			m.metrics.RecordRequest(c.Request.Method, c.Request.URL.Path, status, clusterIDStr, duration)
+
+			// 按版本拆分的路由额外记录版本指标
+			if version := c.GetString("upstream_version"); version != "" {
+				m.metrics.RecordUpstreamVersion(c.GetString("route_name"), version, status, duration)
+			}
+
+			// 流式响应按最终结果额外记录
+			if outcome := c.GetString("stream_outcome"); outcome != "" {
+				m.metrics.RecordStreamOutcome(c.Request.URL.Path, outcome, c.GetInt64("stream_events"))
+			}
 		}
 	}
 }
@@ -239,24 +394,11 @@ func (m *Middleware) HealthCheck() gin.HandlerFunc {
 		}
 
 		if c.Request.URL.Path == "/ready" {
-			// 检查各组件是否就绪
-			ready := true
-			components := make(map[string]bool)
-
-			// 检查限流器
-			if m.rateLimiter != nil {
-				components["rate_limiter"] = true
-			} else {
-				components["rate_limiter"] = false
-				ready = false
-			}
-
-			// 检查熔断器
-			if m.circuitBreaker != nil {
-				components["circuit_breaker"] = true
-			} else {
-				components["circuit_breaker"] = false
-				ready = false
+			// 任一组件不可用时未就绪，降级的组件不影响就绪
+			statuses, ready := utils.CollectComponents(m.readinessComponents())
+			components := make(map[string]types.ComponentHealth, len(statuses))
+			for name, status := range statuses {
+				components[name] = status.Health
 			}
 
 			if ready {
@@ -279,3 +421,99 @@ func (m *Middleware) HealthCheck() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// recordRateLimitDecision 按限流器在请求上下文中记录的簇、算法和检查结果记录限流决策
+func (m *Middleware) recordRateLimitDecision(c *gin.Context, allowed bool) {
+	step := decision.Step{
+		Stage:    "rate_limit",
+		Decision: decision.DecisionAllow,
+	}
+
+	var check types.RateLimitCheck
+	if value, exists := c.Get("rate_limit_check"); exists {
+		check, _ = value.(types.RateLimitCheck)
+	}
+	step.ClusterID = check.ClusterID
+	step.PolicyVersion = check.PolicyVersion
+
+	if check.Limiter != "" {
+		step.Details = map[string]interface{}{
+			"limiter":   check.Limiter,
+			"algorithm": check.Algorithm,
+		}
+		if value, exists := c.Get("rate_limit_quota"); exists {
+			if quota, ok := value.(types.RateLimitQuota); ok {
+				step.Details["limit"] = quota.Limit
+				step.Details["remaining"] = quota.Remaining
+			}
+		}
+		if check.ClusterID != "" {
+			if stats, err := m.rateLimiter.GetStats(check.ClusterID); err == nil && stats != nil {
+				step.Details["current_rate"] = stats.CurrentRate
+				step.Details["severity"] = stats.Severity
+			}
+		}
+	}
+
+	if !allowed {
+		step.Decision = decision.DecisionReject
+		step.Reason = rateLimitRejectReason(check)
+	}
+
+	decision.Record(c, step)
+}
+
+// rateLimitRejectReason 按拒绝请求的限流器和算法描述拒绝原因
+func rateLimitRejectReason(check types.RateLimitCheck) string {
+	var exhausted string
+	switch check.Algorithm {
+	case types.RateLimitSlidingWindow:
+		exhausted = "sliding window limit reached"
+	case types.RateLimitLeakyBucket:
+		exhausted = "leaky bucket queue full"
+	default:
+		exhausted = "token bucket exhausted"
+	}
+
+	switch check.Limiter {
+	case types.RateLimiterKeyOverride:
+		return "api key override " + exhausted
+	case types.RateLimiterPerKey:
+		return "per-key " + exhausted
+	default:
+		return exhausted
+	}
+}
+
+// recordBreakerDecision 记录熔断决策
+func (m *Middleware) recordBreakerDecision(c *gin.Context, clusterID string, allowed bool) {
+	state := m.circuitBreaker.GetState(clusterID)
+
+	step := decision.Step{
+		Stage:     "circuit_breaker",
+		Decision:  decision.DecisionAllow,
+		ClusterID: clusterID,
+		Details: map[string]interface{}{
+			"breaker_state": breakerStateName(state),
+		},
+	}
+
+	if !allowed {
+		step.Decision = decision.DecisionReject
+		step.Reason = "circuit breaker open for cluster"
+	}
+
+	decision.Record(c, step)
+}
+
+// breakerStateName 熔断状态名称
+func breakerStateName(state types.BreakerState) string {
+	switch state {
+	case types.BreakerStateOpen:
+		return "OPEN"
+	case types.BreakerStateHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "CLOSED"
+	}
+}
@@ -0,0 +1,20 @@
+package gateway
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// policyVersion 请求结束后记录簇当时生效的策略版本，访问日志和计数快照据此与控制面的策略历史对应
+func (g *Gateway) policyVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		clusterID := c.GetString("cluster_id")
+		if clusterID == "" {
+			return
+		}
+		if policy, err := g.configWatcher.GetPolicy(clusterID); err == nil && policy != nil && policy.Version != 0 {
+			c.Set("policy_version", policy.Version)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package promptguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultMaxBodyBytes 默认检查的请求体字节数
+const defaultMaxBodyBytes = 1 << 20
+
+// maxSimilarityTexts 每个请求参与相似度检测的文本数，取最后几条消息
+const maxSimilarityTexts = 4
+
+// attackSearchTopK 相似度检测取回的候选数，向量库可能与其他用途共用
+const attackSearchTopK = 4
+
+// attackIDPrefix 攻击样本在向量库中的ID前缀
+const attackIDPrefix = "prompt-attack-"
+
+// 标记结果随请求转发给上游的请求头
+const (
+	ScoreHeader = "X-Prompt-Guard-Score"
+	RulesHeader = "X-Prompt-Guard-Rules"
+)
+
+// defaultRules 未配置规则时使用的内置规则
+var defaultRules = []types.PromptGuardRule{
+	{ID: "ignore_instructions", Pattern: `\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|preceding)\s+(instructions|prompts?|rules|directions|guidelines)`, Score: 0.7},
+	{ID: "system_prompt_leak", Pattern: `\b(reveal|show|print|repeat|output|display)\s+(me\s+)?(your|the)\s+(system|hidden|initial|original)\s+(prompt|instructions|message)`, Score: 0.6},
+	{ID: "role_override", Pattern: `\byou\s+are\s+now\s+(in\s+)?(dan|developer\s+mode|jailbroken|unrestricted|unfiltered)\b`, Score: 0.7},
+	{ID: "do_anything_now", Pattern: `\bdo\s+anything\s+now\b`, Score: 0.6},
+	{ID: "no_restrictions", Pattern: `\b(pretend|act\s+as\s+if|imagine)\s+(that\s+)?(you\s+have|there\s+are)\s+no\s+(restrictions|rules|filters|guidelines|limitations)`, Score: 0.6},
+	{ID: "jailbreak", Pattern: `\bjailbr(eak|oken)\b`, Score: 0.4},
+	{ID: "fake_system_turn", Pattern: `(^|\n)\s*(#+\s*)?(system|assistant)\s*:`, Score: 0.4},
+}
+
+// rule 编译后的规则
+type rule struct {
+	id      string
+	pattern *regexp.Regexp
+	score   float64
+}
+
+// Result 提示词打分结果
+type Result struct {
+	Score      float64  `json:"score"`
+	Rules      []string `json:"rules,omitempty"`      // 命中的规则
+	Attack     string   `json:"attack,omitempty"`     // 最相似的已知攻击样本
+	Similarity float64  `json:"similarity,omitempty"` // 与该样本的相似度
+	Action     string   `json:"action,omitempty"`     // 执行的动作，未达到标记阈值时为空
+}
+
+// Guard 提示词注入检测：按正则规则和已知攻击样本相似度为请求中的提示词打分，
+// 达到阈值时拒绝、标记或改用更安全的模型
+type Guard struct {
+	paths          []string
+	maxBodyBytes   int64
+	flagThreshold  float64
+	blockThreshold float64
+	action         string
+	downgradeModel string
+	rules          []*rule
+	threshold      float64
+	embedder       interfaces.EmbeddingService // 未开启相似度检测时为nil
+	vectors        interfaces.VectorDB
+	attacks        map[string]string // 向量ID -> 攻击样本
+	metrics        interfaces.MetricsCollector
+	inspected      int64
+	flagged        int64
+	blocked        int64
+	downgraded     int64
+}
+
+// NewGuard 创建提示词注入检测，embedder或vectors为nil时只使用正则规则
+func NewGuard(config *types.PromptGuardConfig, embedder interfaces.EmbeddingService, vectors interfaces.VectorDB, metrics interfaces.MetricsCollector) (*Guard, error) {
+	g := &Guard{
+		paths:          config.Paths,
+		maxBodyBytes:   config.MaxBodyBytes,
+		flagThreshold:  config.FlagThreshold,
+		blockThreshold: config.BlockThreshold,
+		action:         config.Action,
+		downgradeModel: config.DowngradeModel,
+		threshold:      config.Similarity.Threshold,
+		attacks:        make(map[string]string),
+		metrics:        metrics,
+	}
+	if len(g.paths) == 0 {
+		g.paths = []string{"/v1/"}
+	}
+	if g.maxBodyBytes <= 0 {
+		g.maxBodyBytes = defaultMaxBodyBytes
+	}
+	if g.flagThreshold <= 0 {
+		g.flagThreshold = 0.5
+	}
+	if g.blockThreshold <= 0 {
+		g.blockThreshold = 0.8
+	}
+	if g.threshold <= 0 || g.threshold > 1 {
+		g.threshold = 0.85
+	}
+
+	switch g.action {
+	case "":
+		g.action = types.PromptGuardActionBlock
+	case types.PromptGuardActionBlock, types.PromptGuardActionFlag:
+	case types.PromptGuardActionDowngrade:
+		if g.downgradeModel == "" {
+			return nil, fmt.Errorf("prompt guard: downgrade_model is required for action downgrade")
+		}
+	default:
+		return nil, fmt.Errorf("prompt guard: unknown action %q", config.Action)
+	}
+
+	ruleConfigs := config.Rules
+	if len(ruleConfigs) == 0 {
+		ruleConfigs = defaultRules
+	}
+	for i, cfg := range ruleConfigs {
+		id := cfg.ID
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i)
+		}
+		pattern, err := regexp.Compile("(?i)" + cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("prompt guard rule %s: invalid pattern: %v", id, err)
+		}
+		score := cfg.Score
+		if score <= 0 || score > 1 {
+			score = 0.5
+		}
+		g.rules = append(g.rules, &rule{id: id, pattern: pattern, score: score})
+	}
+
+	if embedder != nil && vectors != nil && len(config.Similarity.Attacks) > 0 {
+		if err := g.loadAttacks(config.Similarity.Attacks, embedder, vectors); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// loadAttacks 向量化已知攻击样本并写入向量库
+func (g *Guard) loadAttacks(attacks []string, embedder interfaces.EmbeddingService, vectors interfaces.VectorDB) error {
+	embedded, err := embedder.EmbedBatch(attacks)
+	if err != nil {
+		return fmt.Errorf("failed to embed prompt attack samples: %v", err)
+	}
+	for i, vector := range embedded {
+		id := fmt.Sprintf("%s%d", attackIDPrefix, i)
+		if err := vectors.AddVector(id, vector); err != nil {
+			return fmt.Errorf("failed to add prompt attack vector: %v", err)
+		}
+		g.attacks[id] = attacks[i]
+	}
+
+	g.embedder = embedder
+	g.vectors = vectors
+	log.Printf("Loaded %d prompt attack samples for injection detection", len(g.attacks))
+	return nil
+}
+
+// Score 为提示词文本打分，多个信号的分数按1-Π(1-score)合并
+func (g *Guard) Score(texts []string) Result {
+	var result Result
+	remaining := 1.0
+
+	for _, r := range g.rules {
+		for _, text := range texts {
+			if r.pattern.MatchString(text) {
+				result.Rules = append(result.Rules, r.id)
+				remaining *= 1 - r.score
+				break
+			}
+		}
+	}
+
+	if g.embedder != nil {
+		if attack, similarity := g.nearestAttack(texts); similarity >= g.threshold {
+			result.Attack = attack
+			result.Similarity = similarity
+			remaining *= 1 - similarity
+		}
+	}
+
+	result.Score = 1 - remaining
+	return result
+}
+
+// nearestAttack 查找与最后几条文本最相似的攻击样本
+func (g *Guard) nearestAttack(texts []string) (string, float64) {
+	if len(texts) > maxSimilarityTexts {
+		texts = texts[len(texts)-maxSimilarityTexts:]
+	}
+
+	attack := ""
+	best := 0.0
+	for _, text := range texts {
+		vector, err := g.embedder.EmbedText(text)
+		if err != nil {
+			log.Printf("Failed to embed prompt for injection detection: %v", err)
+			continue
+		}
+		results, err := g.vectors.SearchSimilar(vector, attackSearchTopK)
+		if err != nil {
+			log.Printf("Failed to search prompt attack samples: %v", err)
+			continue
+		}
+		for _, candidate := range results {
+			sample, exists := g.attacks[candidate.ID]
+			if exists && candidate.Similarity > best {
+				attack, best = sample, candidate.Similarity
+			}
+		}
+	}
+	return attack, best
+}
+
+// Middleware 提示词注入检测中间件，只检查配置路径下的POST JSON请求
+func (g *Guard) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost || !g.inspects(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		body, complete := g.readBody(c)
+		if !complete {
+			c.Next()
+			return
+		}
+		texts := promptTexts(body)
+		if len(texts) == 0 {
+			c.Next()
+			return
+		}
+
+		atomic.AddInt64(&g.inspected, 1)
+		result := g.Score(texts)
+		if result.Score < g.flagThreshold {
+			c.Next()
+			return
+		}
+
+		result.Action = types.PromptGuardActionFlag
+		if result.Score >= g.blockThreshold {
+			result.Action = g.action
+		}
+		c.Set("prompt_guard", &result)
+		if g.metrics != nil {
+			g.metrics.RecordPromptGuard(result.Action)
+		}
+
+		switch result.Action {
+		case types.PromptGuardActionBlock:
+			atomic.AddInt64(&g.blocked, 1)
+			utils.RecordStageError(c, "prompt_guard", fmt.Errorf("prompt injection score %.2f (rules=%v)", result.Score, result.Rules))
+			log.Printf("Blocked request %s with prompt injection score %.2f (rules=%v)", c.Request.URL.Path, result.Score, result.Rules)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Request blocked by prompt injection detection",
+				"code":  "PROMPT_INJECTION",
+				"score": result.Score,
+				"rules": result.Rules,
+			})
+			return
+		case types.PromptGuardActionDowngrade:
+			if g.downgrade(c, body) {
+				atomic.AddInt64(&g.downgraded, 1)
+				utils.AnnotateStage(c, "prompt_guard", fmt.Sprintf("prompt injection score %.2f, downgraded to %s", result.Score, g.downgradeModel))
+			}
+		default:
+			atomic.AddInt64(&g.flagged, 1)
+			utils.AnnotateStage(c, "prompt_guard", fmt.Sprintf("prompt injection score %.2f", result.Score))
+		}
+
+		c.Request.Header.Set(ScoreHeader, fmt.Sprintf("%.2f", result.Score))
+		if len(result.Rules) > 0 {
+			c.Request.Header.Set(RulesHeader, strings.Join(result.Rules, ","))
+		}
+		c.Next()
+	}
+}
+
+// inspects 路径是否需要检查
+func (g *Guard) inspects(path string) bool {
+	for _, prefix := range g.paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody 读取请求体并放回，超过检查上限时拼回已读部分并跳过检查
+func (g *Guard) readBody(c *gin.Context) ([]byte, bool) {
+	if body, exists := c.Get("request_body"); exists {
+		if data, ok := body.([]byte); ok {
+			return data, int64(len(data)) <= g.maxBodyBytes
+		}
+	}
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || c.Request.ContentLength > g.maxBodyBytes {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, g.maxBodyBytes+1))
+	if err != nil {
+		log.Printf("Failed to read request body for prompt injection detection: %v", err)
+	}
+	if err != nil || int64(len(body)) > g.maxBodyBytes {
+		c.Request.Body = &splicedBody{
+			Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body),
+			closer: c.Request.Body,
+		}
+		return nil, false
+	}
+
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true
+}
+
+// splicedBody 已读前缀与剩余请求体拼接
+type splicedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+// Close 关闭原始请求体
+func (sb *splicedBody) Close() error {
+	return sb.closer.Close()
+}
+
+// downgrade 将请求的模型改写为降级模型
+func (g *Guard) downgrade(c *gin.Context, body []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	model, _ := json.Marshal(g.downgradeModel)
+	fields["model"] = model
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		log.Printf("Failed to rewrite model for prompt injection downgrade: %v", err)
+		return false
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+	c.Request.ContentLength = int64(len(rewritten))
+	c.Request.Header.Del("Content-Length")
+	if _, exists := c.Get("request_body"); exists {
+		c.Set("request_body", rewritten)
+	}
+	return true
+}
+
+// promptTexts 提取OpenAI格式请求中的提示词：对话中非system角色的消息和补全请求的prompt，
+// system消息由调用方控制，不参与检测
+func promptTexts(body []byte) []string {
+	var request struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Prompt json.RawMessage `json:"prompt"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil
+	}
+
+	texts := make([]string, 0, len(request.Messages)+1)
+	for _, message := range request.Messages {
+		if message.Role == "system" || message.Role == "developer" {
+			continue
+		}
+		texts = appendText(texts, message.Content)
+	}
+	if len(request.Prompt) > 0 {
+		texts = appendText(texts, request.Prompt)
+	}
+	return texts
+}
+
+// appendText 追加字符串、字符串数组或文本片段数组中的非空文本
+func appendText(texts []string, raw json.RawMessage) []string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if strings.TrimSpace(text) != "" {
+			texts = append(texts, text)
+		}
+		return texts
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return texts
+	}
+	for _, item := range items {
+		var part struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(item, &text) == nil {
+			part.Text = text
+		} else if json.Unmarshal(item, &part) != nil || part.Type != "text" {
+			continue
+		}
+		if strings.TrimSpace(part.Text) != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return texts
+}
+
+// Stats 获取检测统计
+func (g *Guard) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"rules":           len(g.rules),
+		"attack_samples":  len(g.attacks),
+		"flag_threshold":  g.flagThreshold,
+		"block_threshold": g.blockThreshold,
+		"action":          g.action,
+		"inspected":       atomic.LoadInt64(&g.inspected),
+		"flagged":         atomic.LoadInt64(&g.flagged),
+		"blocked":         atomic.LoadInt64(&g.blocked),
+		"downgraded":      atomic.LoadInt64(&g.downgraded),
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/decision"
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// tenantQuota 按租户→服务→路由的分级配额限流，响应头返回剩余配额最少的层级
+func (g *Gateway) tenantQuota() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var service, routeName string
+		if route := matchedRoute(c); route != nil {
+			service, routeName = route.Upstream, route.Name
+		}
+
+		result := g.tenantQuotas.Allow(utils.ExtractTenant(c), service, routeName)
+		if result.Limit > 0 {
+			c.Header("X-Tenant-Quota-Limit", strconv.FormatInt(result.Limit, 10))
+			c.Header("X-Tenant-Quota-Remaining", strconv.FormatInt(result.Remaining, 10))
+			c.Header("X-Tenant-Quota-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+		}
+		if result.Scope != "" {
+			c.Header("X-Tenant-Quota-Scope", result.Scope)
+		}
+
+		if result.Allowed {
+			c.Next()
+			return
+		}
+
+		code := "TENANT_RATE_LIMIT_EXCEEDED"
+		message := "Tenant rate limit exceeded"
+		if result.Reason == limiter.KeyRejectQuota {
+			code = "TENANT_QUOTA_EXCEEDED"
+			message = "Tenant quota exceeded"
+		}
+
+		if decision.Enabled(c) {
+			decision.Record(c, decision.Step{
+				Stage:    "rate_limit",
+				Decision: decision.DecisionReject,
+				Reason:   strings.ToLower(message) + " at " + result.Scope,
+				Details: map[string]interface{}{
+					"limiter":   types.RateLimiterTenantQuota,
+					"limit":     result.Limit,
+					"remaining": result.Remaining,
+				},
+			})
+		}
+
+		if g.metrics != nil {
+			g.metrics.RecordRateLimitHit("tenant_quota", code)
+		}
+		utils.RecordStageError(c, "tenant_quota", fmt.Errorf("%s at %s", result.Reason, result.Scope))
+
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": message,
+			"code":  code,
+			"scope": result.Scope,
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// buildShutdownReport 汇总各组件的最终统计
+func (g *Gateway) buildShutdownReport() *types.ShutdownReport {
+	now := time.Now()
+	report := &types.ShutdownReport{
+		StartTime:  g.startTime,
+		StopTime:   now,
+		Components: make(map[string]map[string]interface{}),
+	}
+	if !g.startTime.IsZero() {
+		report.Uptime = utils.FormatDuration(now.Sub(g.startTime))
+	}
+
+	report.Components["gateway"] = map[string]interface{}{
+		"policies_applied": atomic.LoadInt64(&g.policyUpdates),
+		"policies_deleted": atomic.LoadInt64(&g.policyDeletes),
+	}
+
+	report.Components["drain"] = map[string]interface{}{
+		"in_flight": g.drainStats.InFlight,
+		"cut_off":   g.drainStats.CutOff,
+		"duration":  g.drainStats.Duration,
+	}
+
+	for name, component := range g.components() {
+		if reporter, ok := component.(interfaces.StatsReporter); ok {
+			report.Components[name] = reporter.Stats()
+		}
+	}
+
+	return report
+}
+
+// writeShutdownReport 输出停止报告，配置了文件时同时写入文件
+func (g *Gateway) writeShutdownReport() {
+	data, err := json.Marshal(g.buildShutdownReport())
+	if err != nil {
+		log.Printf("Failed to marshal shutdown report: %v", err)
+		return
+	}
+
+	log.Printf("Shutdown report: %s", data)
+
+	reportFile := g.config.Shutdown.ReportFile
+	if reportFile == "" {
+		return
+	}
+
+	if err := os.WriteFile(reportFile, data, 0644); err != nil {
+		log.Printf("Failed to write shutdown report to %s: %v", reportFile, err)
+		return
+	}
+	log.Printf("Shutdown report written to %s", reportFile)
+}
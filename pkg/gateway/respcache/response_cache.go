@@ -0,0 +1,279 @@
+// Package respcache 网关响应缓存，缓存幂等请求的上游响应，上游故障时可返回过期缓存
+package respcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 缓存状态响应头
+const (
+	headerCacheStatus = "X-Cache"
+	cacheHit          = "HIT"
+	cacheMiss         = "MISS"
+	cacheStale        = "STALE"
+	cacheBypass       = "BYPASS"
+)
+
+// 缓存默认值
+const (
+	defaultTTL          = time.Minute
+	defaultMaxBodyBytes = 1 << 20
+	defaultKeyPrefix    = "gateway:respcache:"
+)
+
+// skippedHeaders 不随缓存保存的响应头
+var skippedHeaders = map[string]bool{
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Trailer":           true,
+	"Upgrade":           true,
+	"Set-Cookie":        true,
+	"Age":               true,
+	headerCacheStatus:   true,
+}
+
+// RuleFunc 获取请求对应的路由缓存规则，返回nil表示不缓存
+type RuleFunc func(c *gin.Context) *types.RouteCacheConfig
+
+// ResponseCache 响应缓存
+type ResponseCache struct {
+	config *types.ResponseCacheConfig
+	store  store
+	prefix string
+}
+
+// NewResponseCache 创建响应缓存，backend为redis时多副本共享
+func NewResponseCache(config *types.ResponseCacheConfig, redisConfig *types.RedisConfig) *ResponseCache {
+	var s store
+	switch config.Backend {
+	case "redis":
+		s = newRedisStore(redisConfig)
+	default:
+		s = newMemoryStore(config.MaxEntries)
+	}
+
+	prefix := config.KeyPrefix
+	if prefix == "" {
+		prefix = defaultKeyPrefix
+	}
+
+	return &ResponseCache{
+		config: config,
+		store:  s,
+		prefix: prefix,
+	}
+}
+
+// Close 关闭缓存存储
+func (rc *ResponseCache) Close() error {
+	return rc.store.Close()
+}
+
+// Middleware 响应缓存中间件，只处理开启了缓存的路由上的GET/HEAD请求
+// 持有过期缓存时缓冲上游响应，上游返回5xx（包括熔断拒绝）时改用过期缓存
+func (rc *ResponseCache) Middleware(rule RuleFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		routeRule := rule(c)
+		if routeRule == nil || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.Next()
+			return
+		}
+
+		requestCC := parseCacheControl(c.Request.Header.Get("Cache-Control"))
+		if _, noStore := requestCC["no-store"]; noStore {
+			c.Header(headerCacheStatus, cacheBypass)
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		key := rc.key(c.Request, routeRule.Vary)
+		cached, found := rc.store.Get(key)
+
+		_, noCache := requestCC["no-cache"]
+		if found && cached.fresh(now) && !noCache && requestCC["max-age"] != "0" {
+			rc.serve(c, cached, cacheHit, now)
+			return
+		}
+
+		var stale *entry
+		if found && rc.config.StaleIfError > 0 && now.Before(cached.Expires.Add(rc.config.StaleIfError)) {
+			stale = cached
+		}
+
+		before := c.Writer.Header().Clone()
+		c.Header(headerCacheStatus, cacheMiss)
+
+		writer := newCaptureWriter(c.Writer, rc.maxBodyBytes(), stale != nil)
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if stale != nil && writer.buffering && writer.Status() >= http.StatusInternalServerError {
+			resetHeader(c.Writer.Header(), before)
+			rc.serve(c, stale, cacheStale, now)
+			return
+		}
+		writer.release()
+
+		ttl, ok := rc.storeTTL(c.Request, writer, routeRule)
+		if !ok {
+			return
+		}
+
+		e := &entry{
+			Status:   writer.Status(),
+			Header:   storedHeader(c.Writer.Header(), before),
+			Body:     writer.body.Bytes(),
+			StoredAt: now,
+			Expires:  now.Add(ttl),
+		}
+		if err := rc.store.Set(key, e, ttl+rc.config.StaleIfError); err != nil {
+			log.Printf("Failed to store cached response for %s: %v", c.Request.URL.Path, err)
+		}
+	}
+}
+
+// serve 返回缓存的响应
+func (rc *ResponseCache) serve(c *gin.Context, e *entry, status string, now time.Time) {
+	header := c.Writer.Header()
+	for name, values := range e.Header {
+		header[name] = values
+	}
+	header.Set("Age", strconv.Itoa(int(now.Sub(e.StoredAt).Seconds())))
+	header.Set(headerCacheStatus, status)
+
+	c.Status(e.Status)
+	if c.Request.Method != http.MethodHead {
+		c.Writer.Write(e.Body)
+	}
+	c.Abort()
+}
+
+// storeTTL 根据上游响应确定缓存时长，返回false表示不可缓存
+func (rc *ResponseCache) storeTTL(req *http.Request, writer *captureWriter, routeRule *types.RouteCacheConfig) (time.Duration, bool) {
+	if writer.overflow || writer.Status() != http.StatusOK {
+		return 0, false
+	}
+
+	header := writer.Header()
+	if header.Get("Set-Cookie") != "" || header.Get("Vary") == "*" {
+		return 0, false
+	}
+
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, exists := cc[directive]; exists {
+			return 0, false
+		}
+	}
+
+	// 携带凭证的请求只有在上游显式允许共享缓存时才缓存
+	_, public := cc["public"]
+	sMaxAge, shared := cc["s-maxage"]
+	if req.Header.Get("Authorization") != "" && !public && !shared {
+		return 0, false
+	}
+
+	ttl := routeRule.TTL
+	if ttl <= 0 {
+		ttl = rc.config.DefaultTTL
+	}
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	maxAge, exists := cc["max-age"]
+	if shared {
+		maxAge, exists = sMaxAge, true
+	}
+	if exists {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0, false
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	if rc.config.MaxTTL > 0 && ttl > rc.config.MaxTTL {
+		ttl = rc.config.MaxTTL
+	}
+
+	return ttl, ttl > 0
+}
+
+// key 计算缓存键：方法、Host、路径、规范化的查询参数和Vary请求头
+func (rc *ResponseCache) key(req *http.Request, vary []string) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method)
+	io.WriteString(h, "\n"+strings.ToLower(req.Host))
+	io.WriteString(h, "\n"+req.URL.Path)
+	io.WriteString(h, "\n"+req.URL.Query().Encode())
+
+	for _, names := range [][]string{rc.config.Vary, vary} {
+		for _, name := range names {
+			io.WriteString(h, "\n"+http.CanonicalHeaderKey(name)+":"+req.Header.Get(name))
+		}
+	}
+
+	return rc.prefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// maxBodyBytes 可缓存的最大响应体
+func (rc *ResponseCache) maxBodyBytes() int64 {
+	if rc.config.MaxBodyBytes > 0 {
+		return rc.config.MaxBodyBytes
+	}
+	return defaultMaxBodyBytes
+}
+
+// parseCacheControl 解析Cache-Control指令
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// storedHeader 提取上游设置的响应头，排除前置中间件设置的请求相关头
+func storedHeader(header, before http.Header) http.Header {
+	stored := make(http.Header)
+	for name, values := range header {
+		if skippedHeaders[name] {
+			continue
+		}
+		if _, exists := before[name]; exists {
+			continue
+		}
+		stored[name] = append([]string(nil), values...)
+	}
+	return stored
+}
+
+// resetHeader 将响应头恢复到上游写入之前
+func resetHeader(header, before http.Header) {
+	for name := range header {
+		delete(header, name)
+	}
+	for name, values := range before {
+		header[name] = values
+	}
+}
@@ -0,0 +1,134 @@
+package respcache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// entry 缓存的响应
+type entry struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+	Expires  time.Time   `json:"expires"` // 新鲜期截止时间，之后仅在上游出错时使用
+}
+
+// fresh 判断缓存是否仍在新鲜期
+func (e *entry) fresh(now time.Time) bool {
+	return now.Before(e.Expires)
+}
+
+// store 缓存存储后端
+type store interface {
+	Get(key string) (*entry, bool)
+	Set(key string, e *entry, ttl time.Duration) error
+	Close() error
+}
+
+// memoryStore 基于utils.Cache的进程内存储
+type memoryStore struct {
+	cache interfaces.Cache
+}
+
+// newMemoryStore 创建进程内存储
+func newMemoryStore(maxEntries int) *memoryStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &memoryStore{cache: utils.NewCache(maxEntries)}
+}
+
+// Get 读取缓存
+func (ms *memoryStore) Get(key string) (*entry, bool) {
+	value, found := ms.cache.Get(key)
+	if !found {
+		return nil, false
+	}
+	e, ok := value.(*entry)
+	return e, ok
+}
+
+// Set 写入缓存
+func (ms *memoryStore) Set(key string, e *entry, ttl time.Duration) error {
+	seconds := int64(ttl.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return ms.cache.Set(key, e, seconds)
+}
+
+// Close 关闭存储
+func (ms *memoryStore) Close() error {
+	return nil
+}
+
+// redisStore 基于Redis的共享存储，多副本共享缓存
+type redisStore struct {
+	client  redis.UniversalClient
+	timeout time.Duration
+}
+
+// newRedisStore 创建Redis存储
+func newRedisStore(config *types.RedisConfig) *redisStore {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:       config.Addresses,
+		Password:    config.Password,
+		DB:          config.DB,
+		PoolSize:    config.PoolSize,
+		DialTimeout: config.Timeout,
+	})
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 100 * time.Millisecond
+	}
+
+	return &redisStore{
+		client:  client,
+		timeout: timeout,
+	}
+}
+
+// Get 读取缓存，Redis不可用时视为未命中
+func (rs *redisStore) Get(key string) (*entry, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), rs.timeout)
+	defer cancel()
+
+	data, err := rs.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false
+	}
+	return &e, true
+}
+
+// Set 写入缓存
+func (rs *redisStore) Set(key string, e *entry, ttl time.Duration) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rs.timeout)
+	defer cancel()
+
+	return rs.client.Set(ctx, key, data, ttl).Err()
+}
+
+// Close 关闭Redis连接
+func (rs *redisStore) Close() error {
+	return rs.client.Close()
+}
@@ -0,0 +1,119 @@
+package respcache
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureWriter 记录响应体用于写入缓存
+// buffering模式下暂不写出，等待判断是否改用过期缓存；响应体超过上限时转为直接写出
+type captureWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	maxBody   int64
+	buffering bool
+	status    int
+	overflow  bool // 响应体超过上限，不缓存
+}
+
+// newCaptureWriter 创建响应记录器
+func newCaptureWriter(w gin.ResponseWriter, maxBody int64, buffering bool) *captureWriter {
+	return &captureWriter{
+		ResponseWriter: w,
+		maxBody:        maxBody,
+		buffering:      buffering,
+	}
+}
+
+// WriteHeader 记录状态码
+func (w *captureWriter) WriteHeader(code int) {
+	if w.buffering {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeaderNow 缓冲模式下延迟到release时写出
+func (w *captureWriter) WriteHeaderNow() {
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Write 写入响应体
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if w.buffering {
+		if int64(w.body.Len()+len(data)) <= w.maxBody {
+			return w.body.Write(data)
+		}
+		w.release()
+	}
+
+	if !w.overflow {
+		if int64(w.body.Len()+len(data)) > w.maxBody {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data)
+		}
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写入字符串响应体
+func (w *captureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status 获取状态码
+func (w *captureWriter) Status() int {
+	if w.buffering {
+		if w.status == 0 {
+			return http.StatusOK
+		}
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+// Size 获取已写入的响应体长度
+func (w *captureWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Written 判断是否已写入响应
+func (w *captureWriter) Written() bool {
+	if w.buffering {
+		return w.status != 0 || w.body.Len() > 0
+	}
+	return w.ResponseWriter.Written()
+}
+
+// Flush 缓冲模式下不刷新
+func (w *captureWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// release 结束缓冲，写出已缓冲的状态码和响应体
+func (w *captureWriter) release() {
+	if !w.buffering {
+		return
+	}
+	w.buffering = false
+
+	w.ResponseWriter.WriteHeader(w.Status())
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	} else {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// routeScoped 包装全局中间件，匹配路由配置了跳过时直接进入下一个处理器
+func routeScoped(name string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route := matchedRoute(c); route != nil && route.Skips(name) {
+			utils.AnnotateStage(c, name, "skipped by route "+route.Name)
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+// routeRateLimit 路由级限流，在簇限流之前按路由总速率拒绝请求
+func routeRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := matchedRoute(c)
+		if route == nil || route.AllowRequest() {
+			c.Next()
+			return
+		}
+
+		utils.RecordStageError(c, "route_rate_limit", fmt.Errorf("rate limit exceeded for route %s", route.Name))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Route rate limit exceeded",
+			"code":  "ROUTE_RATE_LIMIT_EXCEEDED",
+			"route": route.Name,
+		})
+		c.Abort()
+	}
+}
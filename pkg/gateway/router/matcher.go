@@ -0,0 +1,308 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxMatchBodyBytes 按请求体字段匹配时读取的请求体上限，超过时请求体字段条件不满足
+const maxMatchBodyBytes = 1 << 20
+
+// condition 编译后的匹配条件
+type condition struct {
+	name     string   // 规范化的请求头名或查询参数名
+	path     []string // 请求体字段路径
+	expected string
+}
+
+// routeMatcher 路由编译后的请求头、查询参数和请求体字段条件
+type routeMatcher struct {
+	headers []condition
+	query   []condition
+	body    []condition
+}
+
+// newRouteMatcher 编译路由条件，按名称排序保证匹配顺序稳定
+func newRouteMatcher(headers, query, bodyFields map[string]string) *routeMatcher {
+	m := &routeMatcher{}
+	for name, expected := range headers {
+		m.headers = append(m.headers, condition{name: http.CanonicalHeaderKey(name), expected: expected})
+	}
+	for name, expected := range query {
+		m.query = append(m.query, condition{name: name, expected: expected})
+	}
+	for path, expected := range bodyFields {
+		m.body = append(m.body, condition{name: path, path: splitPath(path), expected: expected})
+	}
+	for _, conditions := range [][]condition{m.headers, m.query, m.body} {
+		sort.Slice(conditions, func(i, j int) bool { return conditions[i].name < conditions[j].name })
+	}
+	return m
+}
+
+// match 依次匹配请求头、查询参数和请求体字段，请求体仅在前两类条件满足后才读取
+func (m *routeMatcher) match(rc *requestContent) bool {
+	for _, cond := range m.headers {
+		values, exists := rc.req.Header[cond.name]
+		if !exists || !matchValue(cond.expected, values) {
+			return false
+		}
+	}
+
+	if len(m.query) > 0 {
+		query := rc.query()
+		for _, cond := range m.query {
+			values, exists := query[cond.name]
+			if !exists || !matchValue(cond.expected, values) {
+				return false
+			}
+		}
+	}
+
+	if len(m.body) > 0 {
+		doc, ok := rc.body()
+		if !ok {
+			return false
+		}
+		for _, cond := range m.body {
+			values, exists := fieldValues(doc, cond.path)
+			if !exists || !matchValue(cond.expected, values) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// requestContent 一次匹配过程中延迟解析的查询参数和JSON请求体，多个候选路由共享
+type requestContent struct {
+	req        *http.Request
+	queryVals  map[string][]string
+	doc        interface{}
+	bodyLoaded bool
+	bodyOK     bool
+}
+
+// query 解析查询参数
+func (rc *requestContent) query() map[string][]string {
+	if rc.queryVals == nil {
+		rc.queryVals = rc.req.URL.Query()
+	}
+	return rc.queryVals
+}
+
+// body 读取并解析JSON请求体，读取的内容放回请求中供后续转发
+func (rc *requestContent) body() (interface{}, bool) {
+	if rc.bodyLoaded {
+		return rc.doc, rc.bodyOK
+	}
+	rc.bodyLoaded = true
+
+	req := rc.req
+	if req.Body == nil || req.Body == http.NoBody || !isJSONRequest(req) || req.ContentLength > maxMatchBodyBytes {
+		return nil, false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxMatchBodyBytes+1))
+	req.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(data), req.Body), Closer: req.Body}
+	if err != nil || len(data) > maxMatchBodyBytes {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&rc.doc); err != nil {
+		return nil, false
+	}
+	rc.bodyOK = true
+	return rc.doc, true
+}
+
+// replayBody 重放已读取部分的请求体
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// isJSONRequest 请求体是否为JSON，未声明类型时按JSON尝试解析
+func isJSONRequest(req *http.Request) bool {
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// fieldValues 按点分路径取字段的字符串形式，字段为标量数组时返回所有元素
+func fieldValues(node interface{}, path []string) ([]string, bool) {
+	for _, key := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, exists := n[key]
+			if !exists {
+				return nil, false
+			}
+			node = child
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return nil, false
+			}
+			node = n[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	if items, ok := node.([]interface{}); ok {
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			if value, ok := scalarString(item); ok {
+				values = append(values, value)
+			}
+		}
+		return values, true
+	}
+
+	value, ok := scalarString(node)
+	if !ok {
+		// 对象字段只能用"*"匹配存在
+		return nil, true
+	}
+	return []string{value}, true
+}
+
+// scalarString JSON标量的字符串形式
+func scalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "null", true
+	}
+	return "", false
+}
+
+// pathIndex 按路径前缀索引的候选路由，只对实际存在的前缀长度做一次哈希查找
+type pathIndex struct {
+	lengths  []int               // 前缀长度，从长到短
+	prefixes map[string][]*Route // 前缀 -> 按优先级排列的候选路由
+}
+
+// newPathIndex 创建路径索引
+func newPathIndex() *pathIndex {
+	return &pathIndex{prefixes: make(map[string][]*Route)}
+}
+
+// add 按优先级顺序加入路由
+func (pi *pathIndex) add(route *Route) {
+	if _, exists := pi.prefixes[route.PathPrefix]; !exists {
+		pi.lengths = append(pi.lengths, len(route.PathPrefix))
+	}
+	pi.prefixes[route.PathPrefix] = append(pi.prefixes[route.PathPrefix], route)
+}
+
+// compile 整理前缀长度
+func (pi *pathIndex) compile() {
+	sort.Sort(sort.Reverse(sort.IntSlice(pi.lengths)))
+	unique := pi.lengths[:0]
+	for i, length := range pi.lengths {
+		if i == 0 || length != pi.lengths[i-1] {
+			unique = append(unique, length)
+		}
+	}
+	pi.lengths = unique
+}
+
+// lookup 按最长前缀优先查找第一个满足条件的路由
+func (pi *pathIndex) lookup(host string, rc *requestContent) *Route {
+	if pi == nil {
+		return nil
+	}
+
+	path := rc.req.URL.Path
+	for _, length := range pi.lengths {
+		if length > len(path) {
+			continue
+		}
+		for _, route := range pi.prefixes[path[:length]] {
+			if matchHost(route.Host, host) && route.matcher.match(rc) {
+				return route
+			}
+		}
+	}
+	return nil
+}
+
+// matcherTree 编译后的路由匹配树：第一层按Host分为精确、通配、任意三级，
+// 第二层按路径前缀索引，叶子上的候选路由按条件数从多到少排列
+type matcherTree struct {
+	exact    map[string]*pathIndex
+	wildcard *pathIndex
+	any      *pathIndex
+}
+
+// newMatcherTree 由已按优先级排序的路由构建匹配树
+func newMatcherTree(routes []*Route) *matcherTree {
+	tree := &matcherTree{exact: make(map[string]*pathIndex)}
+	for _, route := range routes {
+		var index *pathIndex
+		switch hostPriority(route.Host) {
+		case 2:
+			if index = tree.exact[route.Host]; index == nil {
+				index = newPathIndex()
+				tree.exact[route.Host] = index
+			}
+		case 1:
+			if tree.wildcard == nil {
+				tree.wildcard = newPathIndex()
+			}
+			index = tree.wildcard
+		default:
+			if tree.any == nil {
+				tree.any = newPathIndex()
+			}
+			index = tree.any
+		}
+		index.add(route)
+	}
+
+	for _, index := range tree.exact {
+		index.compile()
+	}
+	for _, index := range []*pathIndex{tree.wildcard, tree.any} {
+		if index != nil {
+			index.compile()
+		}
+	}
+	return tree
+}
+
+// match 按精确Host、通配Host、任意Host的顺序查找
+func (t *matcherTree) match(req *http.Request) *Route {
+	host := requestHost(req)
+	rc := &requestContent{req: req}
+
+	if route := t.exact[host].lookup(host, rc); route != nil {
+		return route
+	}
+	if route := t.wildcard.lookup(host, rc); route != nil {
+		return route
+	}
+	return t.any.lookup(host, rc)
+}
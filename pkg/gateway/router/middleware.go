@@ -0,0 +1,119 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 可按路由跳过的中间件
+const (
+	MiddlewareAuth           = "auth"
+	MiddlewareWAF            = "waf"
+	MiddlewarePromptGuard    = "prompt_guard"
+	MiddlewareCache          = "cache"
+	MiddlewareRateLimit      = "rate_limit"
+	MiddlewareConcurrency    = "concurrency"
+	MiddlewareCircuitBreaker = "circuit_breaker"
+	MiddlewareErrorSampling  = "error_sampling"
+	MiddlewareMetrics        = "metrics"
+)
+
+var knownMiddleware = map[string]bool{
+	MiddlewareAuth:           true,
+	MiddlewareWAF:            true,
+	MiddlewarePromptGuard:    true,
+	MiddlewareCache:          true,
+	MiddlewareRateLimit:      true,
+	MiddlewareConcurrency:    true,
+	MiddlewareCircuitBreaker: true,
+	MiddlewareErrorSampling:  true,
+	MiddlewareMetrics:        true,
+}
+
+// routeMiddleware 路由级中间件链
+type routeMiddleware struct {
+	skip        map[string]bool
+	limiter     *limiter.TokenBucket
+	concurrency *limiter.ConcurrencyLimiter
+}
+
+// newRouteMiddleware 解析路由级中间件配置
+func newRouteMiddleware(config *types.RouteMiddlewareConfig) (*routeMiddleware, error) {
+	rm := &routeMiddleware{skip: make(map[string]bool, len(config.Skip))}
+	for _, name := range config.Skip {
+		if !knownMiddleware[name] {
+			return nil, fmt.Errorf("unknown middleware %q", name)
+		}
+		rm.skip[name] = true
+	}
+
+	if rl := config.RateLimit; rl != nil {
+		if rl.Rate <= 0 {
+			return nil, fmt.Errorf("route rate limit must be positive")
+		}
+		burst := rl.Burst
+		if burst <= 0 {
+			burst = int64(rl.Rate)
+			if burst < 1 {
+				burst = 1
+			}
+		}
+		rm.limiter = limiter.NewTokenBucket(burst, rl.Rate)
+	}
+
+	if config.Concurrency != nil {
+		concurrency, err := limiter.NewConcurrencyLimiter(config.Concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route concurrency limit: %v", err)
+		}
+		rm.concurrency = concurrency
+	}
+
+	return rm, nil
+}
+
+// Skips 判断路由是否跳过指定中间件
+func (r *Route) Skips(name string) bool {
+	return r.middleware != nil && r.middleware.skip[name]
+}
+
+// AllowRequest 路由级限流，未配置时总是放行
+func (r *Route) AllowRequest() bool {
+	if r.middleware == nil || r.middleware.limiter == nil {
+		return true
+	}
+	return r.middleware.limiter.Allow()
+}
+
+// AcquireSlot 获取路由并发限制的空位，未配置时直接返回
+func (r *Route) AcquireSlot(ctx context.Context) (func(), error) {
+	if r.middleware == nil || r.middleware.concurrency == nil {
+		return func() {}, nil
+	}
+	return r.middleware.concurrency.Acquire(ctx)
+}
+
+// ConcurrencyStats 获取路由并发限制的统计，未配置时返回nil
+func (r *Route) ConcurrencyStats() map[string]interface{} {
+	if r.middleware == nil || r.middleware.concurrency == nil {
+		return nil
+	}
+	return r.middleware.concurrency.Stats()
+}
+
+// SkippedMiddleware 获取路由跳过的中间件
+func (r *Route) SkippedMiddleware() []string {
+	names := make([]string, 0)
+	if r.middleware == nil {
+		return names
+	}
+	for name := range r.middleware.skip {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,179 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/llm-aware-gateway/pkg/gateway/compression"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultErrorBodyBytes 识别响应体错误时默认读取的上限
+const defaultErrorBodyBytes = 64 << 10
+
+// maxErrorMessageBytes 错误消息长度上限
+const maxErrorMessageBytes = 512
+
+// errorRule 编译后的响应体错误规则
+type errorRule struct {
+	path    []string
+	pattern *regexp.Regexp
+}
+
+// errorExtractor 路由的响应体错误规则
+type errorExtractor struct {
+	rules        []errorRule
+	maxBodyBytes int64
+}
+
+// newErrorExtractor 编译响应体错误规则，未配置时返回nil
+func newErrorExtractor(config *types.ResponseErrorConfig) (*errorExtractor, error) {
+	if len(config.Rules) == 0 {
+		return nil, nil
+	}
+
+	ex := &errorExtractor{maxBodyBytes: config.MaxBodyBytes}
+	if ex.maxBodyBytes <= 0 {
+		ex.maxBodyBytes = defaultErrorBodyBytes
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Path == "" && rule.Pattern == "" {
+			return nil, fmt.Errorf("response error rule %d: path or pattern is required", i)
+		}
+		compiled := errorRule{path: splitPath(rule.Path)}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("response error rule %d: invalid pattern: %v", i, err)
+			}
+			compiled.pattern = pattern
+		}
+		ex.rules = append(ex.rules, compiled)
+	}
+	return ex, nil
+}
+
+// ClassifyResponse 按规则检查成功状态的非流式响应体，命中时返回包含响应体中错误消息的错误，响应体原样保留
+func (r *Route) ClassifyResponse(resp *http.Response) error {
+	ex := r.errors
+	if ex == nil || resp.StatusCode < 200 || resp.StatusCode >= 300 || compression.IsEncoded(resp.Header.Get("Content-Encoding")) {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, ex.maxBodyBytes))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	// 超过上限时只有前缀，JSON规则无法解析，正则规则仍可匹配
+	var doc interface{}
+	parsed := json.Unmarshal(data, &doc) == nil
+
+	for _, rule := range ex.rules {
+		if message, ok := rule.match(data, doc, parsed); ok {
+			if len(message) > maxErrorMessageBytes {
+				message = message[:maxErrorMessageBytes]
+			}
+			return fmt.Errorf("upstream returned error in response body: %s", message)
+		}
+	}
+	return nil
+}
+
+// match 配置了path时检查字段，字段非空且满足pattern（如配置）时命中；只配置pattern时匹配整个响应体
+func (rule *errorRule) match(data []byte, doc interface{}, parsed bool) (string, bool) {
+	if len(rule.path) == 0 {
+		groups := rule.pattern.FindSubmatch(data)
+		if groups == nil {
+			return "", false
+		}
+		if len(groups) > 1 {
+			return string(groups[1]), true
+		}
+		return string(groups[0]), true
+	}
+
+	if !parsed {
+		return "", false
+	}
+	value, ok := lookupField(doc, rule.path)
+	if !ok || isEmptyValue(value) {
+		return "", false
+	}
+
+	message := errorMessage(value)
+	if rule.pattern != nil {
+		groups := rule.pattern.FindStringSubmatch(message)
+		if groups == nil {
+			return "", false
+		}
+		if len(groups) > 1 {
+			return groups[1], true
+		}
+	}
+	return message, true
+}
+
+// lookupField 按点分路径取字段
+func lookupField(node interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, exists := n[key]
+			if !exists {
+				return nil, false
+			}
+			node = child
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return nil, false
+			}
+			node = n[idx]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// isEmptyValue null、false、空字符串、空对象和空数组不视为错误
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// errorMessage 字段值作为错误消息：对象优先取message字段，其余按JSON输出
+func errorMessage(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if message, ok := v["message"].(string); ok && message != "" {
+			return message
+		}
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// rewriter 路由的请求改写规则
+type rewriter struct {
+	stripPrefix string
+	addPrefix   string
+	regex       *regexp.Regexp
+	replacement string
+	host        string
+}
+
+// newRewriter 编译改写规则，未配置时返回nil
+func newRewriter(config *types.RewriteConfig) (*rewriter, error) {
+	if config.StripPrefix == "" && config.AddPrefix == "" && config.Regex == "" && config.Host == "" {
+		return nil, nil
+	}
+
+	rw := &rewriter{
+		stripPrefix: config.StripPrefix,
+		addPrefix:   config.AddPrefix,
+		replacement: config.Replacement,
+		host:        config.Host,
+	}
+
+	if config.Regex != "" {
+		regex, err := regexp.Compile(config.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rewrite regex %q: %v", config.Regex, err)
+		}
+		rw.regex = regex
+	}
+
+	return rw, nil
+}
+
+// RewriteRequest 在转发前改写出站请求，依次执行去前缀、正则替换、加前缀和Host改写，最后转换请求头
+func (r *Route) RewriteRequest(out *http.Request) {
+	r.transformRequest(out)
+
+	rw := r.rewrite
+	if rw == nil {
+		return
+	}
+
+	path := out.URL.Path
+
+	if rw.stripPrefix != "" && strings.HasPrefix(path, rw.stripPrefix) {
+		path = strings.TrimPrefix(path, rw.stripPrefix)
+	}
+	if rw.regex != nil {
+		path = rw.regex.ReplaceAllString(path, rw.replacement)
+	}
+	if rw.addPrefix != "" {
+		path = strings.TrimSuffix(rw.addPrefix, "/") + "/" + strings.TrimPrefix(path, "/")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	if path != out.URL.Path {
+		out.URL.Path = path
+		out.URL.RawPath = ""
+	}
+
+	if rw.host != "" {
+		out.Host = rw.host
+	}
+}
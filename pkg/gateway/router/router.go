@@ -0,0 +1,216 @@
+package router
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Route 路由规则
+type Route struct {
+	Name       string
+	Host       string // 为空时匹配任意Host，支持"*.example.com"通配
+	PathPrefix string
+	Upstream   string
+	Namespace  string // 簇命名空间，不同域名的簇和策略互相隔离
+	Headers    map[string]string
+	Query      map[string]string
+	BodyFields map[string]string // JSON请求体字段条件，键为点分路径
+	Mirror     *types.MirrorConfig
+	Body       types.BodyConfig
+	Cache      *types.RouteCacheConfig
+	Timeout    time.Duration
+	Expected   time.Duration // 预期耗时，用于检测卡住的请求
+
+	matcher    *routeMatcher
+	rewrite    *rewriter
+	splits     *splitTable
+	splitMutex sync.RWMutex
+	middleware *routeMiddleware
+	transform  *transformer
+	validator  *validator
+	errors     *errorExtractor
+}
+
+// Router 路由表
+type Router struct {
+	routes []*Route
+	tree   *matcherTree
+	mutex  sync.RWMutex
+}
+
+// NewRouter 创建路由表
+func NewRouter(configs []types.RouteConfig) *Router {
+	r := &Router{}
+	r.Reload(configs)
+	return r
+}
+
+// Reload 重新加载路由规则
+func (r *Router) Reload(configs []types.RouteConfig) {
+	routes := make([]*Route, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Upstream == "" && len(cfg.Splits) == 0 {
+			continue
+		}
+
+		name := cfg.Name
+		if name == "" {
+			name = cfg.Host + cfg.PathPrefix
+		}
+
+		rw, err := newRewriter(&cfg.Rewrite)
+		if err != nil {
+			log.Printf("Skipping route %s: %v", name, err)
+			continue
+		}
+
+		splits, err := newSplitTable(cfg.Splits)
+		if err != nil {
+			log.Printf("Skipping route %s: invalid splits: %v", name, err)
+			continue
+		}
+
+		mw, err := newRouteMiddleware(&cfg.Middleware)
+		if err != nil {
+			log.Printf("Skipping route %s: invalid middleware: %v", name, err)
+			continue
+		}
+
+		tf, err := newTransformer(&cfg.Transform)
+		if err != nil {
+			log.Printf("Skipping route %s: invalid transform: %v", name, err)
+			continue
+		}
+
+		v, err := newValidator(&cfg.Schema)
+		if err != nil {
+			log.Printf("Skipping route %s: invalid schema: %v", name, err)
+			continue
+		}
+
+		ex, err := newErrorExtractor(&cfg.ResponseErrors)
+		if err != nil {
+			log.Printf("Skipping route %s: invalid response error rules: %v", name, err)
+			continue
+		}
+
+		routes = append(routes, &Route{
+			Name:       name,
+			Host:       strings.ToLower(cfg.Host),
+			PathPrefix: cfg.PathPrefix,
+			Upstream:   cfg.Upstream,
+			Namespace:  cfg.Namespace,
+			Headers:    cfg.Headers,
+			Query:      cfg.Query,
+			BodyFields: cfg.BodyFields,
+			Mirror:     cfg.Mirror,
+			Body:       cfg.Body,
+			Cache:      cfg.Cache,
+			Timeout:    cfg.Timeout,
+			Expected:   cfg.Expected,
+			matcher:    newRouteMatcher(cfg.Headers, cfg.Query, cfg.BodyFields),
+			rewrite:    rw,
+			splits:     splits,
+			middleware: mw,
+			transform:  tf,
+			validator:  v,
+			errors:     ex,
+		})
+	}
+
+	// 精确Host优先于通配Host，通配Host优先于任意Host，同级按最长前缀优先，
+	// 前缀相同时条件更多的规则优先
+	sort.SliceStable(routes, func(i, j int) bool {
+		if pi, pj := hostPriority(routes[i].Host), hostPriority(routes[j].Host); pi != pj {
+			return pi > pj
+		}
+		if li, lj := len(routes[i].PathPrefix), len(routes[j].PathPrefix); li != lj {
+			return li > lj
+		}
+		return routes[i].conditions() > routes[j].conditions()
+	})
+
+	tree := newMatcherTree(routes)
+
+	r.mutex.Lock()
+	r.routes = routes
+	r.tree = tree
+	r.mutex.Unlock()
+}
+
+// Match 匹配请求对应的路由，配置了请求体字段条件的候选路由会读取JSON请求体，读取的内容放回请求中
+func (r *Router) Match(req *http.Request) *Route {
+	r.mutex.RLock()
+	tree := r.tree
+	r.mutex.RUnlock()
+
+	return tree.match(req)
+}
+
+// Routes 获取所有路由
+func (r *Router) Routes() []*Route {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	routes := make([]*Route, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+// conditions 条件数量
+func (r *Route) conditions() int {
+	return len(r.Headers) + len(r.Query) + len(r.BodyFields)
+}
+
+// matchValue 任一取值匹配即可，"*"只要求存在，"xxx*"按前缀匹配
+func matchValue(expected string, values []string) bool {
+	if expected == "*" {
+		return true
+	}
+	prefix, isPrefix := strings.CutSuffix(expected, "*")
+	for _, value := range values {
+		if value == expected || (isPrefix && strings.HasPrefix(value, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostPriority Host规则的匹配优先级
+func hostPriority(host string) int {
+	switch {
+	case host == "":
+		return 0
+	case strings.HasPrefix(host, "*."):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matchHost 匹配Host规则
+func matchHost(pattern, host string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+// requestHost 获取请求的Host（小写，去除端口）
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,123 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Split 按权重拆分的上游版本
+type Split struct {
+	Upstream string `json:"upstream"`
+	Version  string `json:"version"`
+	Weight   int    `json:"weight"`
+}
+
+// splitTable 路由的流量拆分表
+type splitTable struct {
+	splits []Split
+	total  int
+}
+
+// newSplitTable 校验并创建拆分表
+func newSplitTable(configs []types.RouteSplitConfig) (*splitTable, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	table := &splitTable{}
+	for _, cfg := range configs {
+		if cfg.Upstream == "" {
+			return nil, fmt.Errorf("split upstream cannot be empty")
+		}
+		if cfg.Weight < 0 {
+			return nil, fmt.Errorf("split weight for %s cannot be negative", cfg.Upstream)
+		}
+
+		version := cfg.Version
+		if version == "" {
+			version = cfg.Upstream
+		}
+
+		table.splits = append(table.splits, Split{
+			Upstream: cfg.Upstream,
+			Version:  version,
+			Weight:   cfg.Weight,
+		})
+		table.total += cfg.Weight
+	}
+
+	if table.total == 0 {
+		return nil, fmt.Errorf("total split weight must be positive")
+	}
+
+	return table, nil
+}
+
+// pick 按权重随机选择
+func (st *splitTable) pick() Split {
+	n := rand.Intn(st.total)
+	for _, split := range st.splits {
+		if n < split.Weight {
+			return split
+		}
+		n -= split.Weight
+	}
+	return st.splits[len(st.splits)-1]
+}
+
+// SelectUpstream 选择本次请求的上游及版本，未配置拆分时使用路由默认上游
+func (r *Route) SelectUpstream() (string, string) {
+	r.splitMutex.RLock()
+	table := r.splits
+	r.splitMutex.RUnlock()
+
+	if table == nil {
+		return r.Upstream, ""
+	}
+
+	split := table.pick()
+	return split.Upstream, split.Version
+}
+
+// Splits 获取当前拆分配置
+func (r *Route) Splits() []Split {
+	r.splitMutex.RLock()
+	defer r.splitMutex.RUnlock()
+
+	if r.splits == nil {
+		return nil
+	}
+
+	splits := make([]Split, len(r.splits.splits))
+	copy(splits, r.splits.splits)
+	return splits
+}
+
+// SetSplits 运行时更新拆分权重，传入空列表时恢复为默认上游
+func (r *Route) SetSplits(configs []types.RouteSplitConfig) error {
+	table, err := newSplitTable(configs)
+	if err != nil {
+		return err
+	}
+
+	r.splitMutex.Lock()
+	r.splits = table
+	r.splitMutex.Unlock()
+	return nil
+}
+
+// SetSplits 按路由名更新拆分权重
+func (rt *Router) SetSplits(routeName string, configs []types.RouteSplitConfig) error {
+	rt.mutex.RLock()
+	defer rt.mutex.RUnlock()
+
+	for _, route := range rt.routes {
+		if route.Name == routeName {
+			return route.SetSplits(configs)
+		}
+	}
+
+	return fmt.Errorf("route not found: %s", routeName)
+}
@@ -0,0 +1,305 @@
+package router
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// maxTransformBodyBytes 改写JSON响应体的大小上限，超过时原样转发
+const maxTransformBodyBytes = 8 << 20
+
+// headerTransform 编译后的请求头转换
+type headerTransform struct {
+	rename map[string]string
+	remove []string
+	set    map[string]string
+}
+
+// fieldRule 编译后的JSON字段改写规则
+type fieldRule struct {
+	op    string
+	path  []string
+	to    []string
+	value interface{}
+}
+
+// transformer 路由的请求/响应转换规则
+type transformer struct {
+	request  *headerTransform
+	response *headerTransform
+	body     []fieldRule
+}
+
+// newTransformer 编译转换规则，未配置时返回nil
+func newTransformer(config *types.TransformConfig) (*transformer, error) {
+	t := &transformer{
+		request:  newHeaderTransform(&config.RequestHeaders),
+		response: newHeaderTransform(&config.ResponseHeaders),
+	}
+
+	if auth := config.UpstreamAuth; auth != nil {
+		name, value, err := authHeader(auth)
+		if err != nil {
+			return nil, err
+		}
+		if t.request == nil {
+			t.request = &headerTransform{}
+		}
+		if t.request.set == nil {
+			t.request.set = make(map[string]string)
+		}
+		t.request.set[name] = value
+	}
+
+	for i, rule := range config.ResponseBody {
+		fr := fieldRule{op: rule.Op, path: splitPath(rule.Path)}
+		if len(fr.path) == 0 {
+			return nil, fmt.Errorf("response_body rule %d: empty path", i)
+		}
+
+		switch rule.Op {
+		case types.JSONFieldSet:
+			if err := json.Unmarshal([]byte(rule.Value), &fr.value); err != nil {
+				fr.value = rule.Value
+			}
+		case types.JSONFieldRemove:
+		case types.JSONFieldRename:
+			if fr.to = splitPath(rule.To); len(fr.to) == 0 {
+				return nil, fmt.Errorf("response_body rule %d: rename requires a target path", i)
+			}
+		default:
+			return nil, fmt.Errorf("response_body rule %d: unknown op %q", i, rule.Op)
+		}
+		t.body = append(t.body, fr)
+	}
+
+	if t.request == nil && t.response == nil && len(t.body) == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// newHeaderTransform 编译请求头转换，未配置时返回nil
+func newHeaderTransform(config *types.HeaderTransformConfig) *headerTransform {
+	if len(config.Rename) == 0 && len(config.Remove) == 0 && len(config.Set) == 0 {
+		return nil
+	}
+
+	ht := &headerTransform{}
+	if len(config.Rename) > 0 {
+		ht.rename = make(map[string]string, len(config.Rename))
+		for from, to := range config.Rename {
+			ht.rename[http.CanonicalHeaderKey(from)] = http.CanonicalHeaderKey(to)
+		}
+	}
+	for _, name := range config.Remove {
+		ht.remove = append(ht.remove, http.CanonicalHeaderKey(name))
+	}
+	if len(config.Set) > 0 {
+		ht.set = make(map[string]string, len(config.Set))
+		for name, value := range config.Set {
+			ht.set[http.CanonicalHeaderKey(name)] = os.ExpandEnv(value)
+		}
+	}
+	return ht
+}
+
+// authHeader 生成上游认证头
+func authHeader(config *types.UpstreamAuthConfig) (string, string, error) {
+	switch config.Type {
+	case types.UpstreamAuthBearer:
+		return "Authorization", "Bearer " + os.ExpandEnv(config.Token), nil
+	case types.UpstreamAuthBasic:
+		credentials := os.ExpandEnv(config.Username) + ":" + os.ExpandEnv(config.Password)
+		return "Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+	case types.UpstreamAuthHeader:
+		if config.Header == "" {
+			return "", "", fmt.Errorf("upstream_auth type header requires a header name")
+		}
+		return http.CanonicalHeaderKey(config.Header), os.ExpandEnv(config.Token), nil
+	default:
+		return "", "", fmt.Errorf("unknown upstream_auth type %q", config.Type)
+	}
+}
+
+// apply 依次执行重命名、删除、设置
+func (ht *headerTransform) apply(header http.Header) {
+	if ht == nil {
+		return
+	}
+	for from, to := range ht.rename {
+		if values, exists := header[from]; exists {
+			delete(header, from)
+			header[to] = values
+		}
+	}
+	for _, name := range ht.remove {
+		header.Del(name)
+	}
+	for name, value := range ht.set {
+		header.Set(name, value)
+	}
+}
+
+// transformRequest 转换出站请求头
+func (r *Route) transformRequest(out *http.Request) {
+	if r.transform != nil {
+		r.transform.request.apply(out.Header)
+	}
+}
+
+// TransformResponse 转换上游响应头，JSON响应体按字段规则改写
+func (r *Route) TransformResponse(resp *http.Response) error {
+	t := r.transform
+	if t == nil {
+		return nil
+	}
+
+	t.response.apply(resp.Header)
+
+	if len(t.body) == 0 || !rewritableJSON(resp) {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTransformBodyBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %v", err)
+	}
+	if len(data) > maxTransformBodyBytes {
+		// 超过上限时拼回已读部分原样转发
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return nil
+	}
+	resp.Body.Close()
+
+	if rewritten, ok := t.rewriteBody(data); ok {
+		data = rewritten
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return nil
+}
+
+// rewritableJSON 判断响应是否为未压缩的JSON
+func rewritableJSON(resp *http.Response) bool {
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// rewriteBody 按规则改写JSON，解析失败时返回false
+func (t *transformer) rewriteBody(data []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, false
+	}
+
+	for _, rule := range t.body {
+		switch rule.op {
+		case types.JSONFieldSet:
+			doc = setField(doc, rule.path, rule.value)
+		case types.JSONFieldRemove:
+			doc, _, _ = takeField(doc, rule.path)
+		case types.JSONFieldRename:
+			var value interface{}
+			var found bool
+			if doc, value, found = takeField(doc, rule.path); found {
+				doc = setField(doc, rule.to, value)
+			}
+		}
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// splitPath 解析点分路径
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// setField 设置字段，对象中缺失的中间层自动创建，数组下标越界时忽略
+func setField(node interface{}, path []string, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[path[0]] = setField(n[path[0]], path[1:], value)
+		return n
+	case []interface{}:
+		if idx, err := strconv.Atoi(path[0]); err == nil && idx >= 0 && idx < len(n) {
+			n[idx] = setField(n[idx], path[1:], value)
+		}
+		return n
+	case nil:
+		return map[string]interface{}{path[0]: setField(nil, path[1:], value)}
+	default:
+		return node
+	}
+}
+
+// takeField 取出并删除字段，数组元素删除后后续元素前移
+func takeField(node interface{}, path []string) (interface{}, interface{}, bool) {
+	if len(path) == 0 {
+		return node, nil, false
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, exists := n[path[0]]
+		if !exists {
+			return n, nil, false
+		}
+		if len(path) == 1 {
+			delete(n, path[0])
+			return n, child, true
+		}
+		updated, value, found := takeField(child, path[1:])
+		n[path[0]] = updated
+		return n, value, found
+	case []interface{}:
+		idx, err := strconv.Atoi(path[0])
+		if err != nil || idx < 0 || idx >= len(n) {
+			return n, nil, false
+		}
+		if len(path) == 1 {
+			value := n[idx]
+			return append(n[:idx], n[idx+1:]...), value, true
+		}
+		updated, value, found := takeField(n[idx], path[1:])
+		n[idx] = updated
+		return n, value, found
+	default:
+		return node, nil, false
+	}
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/llm-aware-gateway/pkg/gateway/schema"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultSchemaBodyBytes 请求体校验的默认上限
+const defaultSchemaBodyBytes = 1 << 20
+
+// validator 路由的请求/响应体Schema
+type validator struct {
+	request      *schema.Schema
+	response     *schema.Schema
+	maxBodyBytes int64
+}
+
+// newValidator 加载Schema，未配置时返回nil
+func newValidator(config *types.SchemaConfig) (*validator, error) {
+	if config.Request == "" && config.Response == "" {
+		return nil, nil
+	}
+
+	v := &validator{maxBodyBytes: config.MaxBodyBytes}
+	if v.maxBodyBytes <= 0 {
+		v.maxBodyBytes = defaultSchemaBodyBytes
+	}
+
+	var err error
+	if config.Request != "" {
+		if v.request, err = schema.Load(config.Request); err != nil {
+			return nil, fmt.Errorf("request schema: %v", err)
+		}
+	}
+	if config.Response != "" {
+		if v.response, err = schema.Load(config.Response); err != nil {
+			return nil, fmt.Errorf("response schema: %v", err)
+		}
+	}
+	return v, nil
+}
+
+// RequestSchema 获取请求体Schema和校验的请求体上限，未配置时返回nil
+func (r *Route) RequestSchema() (*schema.Schema, int64) {
+	if r.validator == nil || r.validator.request == nil {
+		return nil, 0
+	}
+	return r.validator.request, r.validator.maxBodyBytes
+}
+
+// InspectsResponse 路由是否需要读取响应体（JSON字段改写、响应Schema校验或响应体错误识别）
+func (r *Route) InspectsResponse() bool {
+	return (r.transform != nil && len(r.transform.body) > 0) || (r.validator != nil && r.validator.response != nil) || r.errors != nil
+}
+
+// ValidateResponse 校验成功状态的非流式JSON响应体，校验失败时返回错误，响应体原样保留
+func (r *Route) ValidateResponse(resp *http.Response) error {
+	if r.validator == nil || r.validator.response == nil || resp.StatusCode < 200 || resp.StatusCode >= 300 || !rewritableJSON(resp) {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTransformBodyBytes+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil || len(data) > maxTransformBodyBytes {
+		// 读取失败或超过上限时不校验
+		return nil
+	}
+
+	if violations := r.validator.response.Validate(data); len(violations) > 0 {
+		return fmt.Errorf("response body does not match schema: %s", FormatViolations(violations))
+	}
+	return nil
+}
+
+// FormatViolations 拼接校验错误，用于错误信息和错误采样
+func FormatViolations(violations []schema.Violation) string {
+	parts := make([]string, 0, len(violations))
+	for _, v := range violations {
+		path := v.Path
+		if path == "" {
+			path = "/"
+		}
+		parts = append(parts, path+": "+v.Message)
+	}
+	return strings.Join(parts, "; ")
+}
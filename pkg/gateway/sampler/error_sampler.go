@@ -1 +1,272 @@
-ls 
\ No newline at end of file
+package sampler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultMaxBodyCapture 采集的请求体长度
+const defaultMaxBodyCapture = 2048
+
+// errorSampler 错误采样器实现，采样后的事件异步发送到Kafka
+type errorSampler struct {
+	config       *types.SamplerConfig
+	kafkaConfig  *types.KafkaConfig
+	router       *TopicRouter
+	desensitizer interfaces.Desensitizer
+	truncator    *Truncator
+	producer     sarama.AsyncProducer
+	queue        chan *types.ErrorEvent
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+
+	sampled    int64 // 采样入队的事件数
+	dropped    int64 // 队列满丢弃的事件数
+	flushed    int64 // 提交给生产者的事件数
+	sendFailed int64 // 发送失败的事件数
+	truncated  int64 // 被截断的事件数
+}
+
+// NewErrorSampler 创建错误采样器
+func NewErrorSampler(config *types.SamplerConfig, kafkaConfig *types.KafkaConfig) interfaces.ErrorSampler {
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 1000
+	}
+
+	return &errorSampler{
+		config:       config,
+		kafkaConfig:  kafkaConfig,
+		router:       NewTopicRouter(kafkaConfig.Topic, kafkaConfig.TopicRoutes),
+		desensitizer: utils.NewDesensitizer(),
+		truncator:    NewTruncator(config),
+		queue:        make(chan *types.ErrorEvent, bufferSize),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// SampleError 按采样率采样错误，队列满时丢弃
+func (es *errorSampler) SampleError(ctx *gin.Context, err error) error {
+	if rand.Float64() >= es.config.SamplingRate {
+		return nil
+	}
+
+	event := &types.ErrorEvent{
+		EventID:      utils.GenerateID(),
+		TraceID:      utils.ExtractTraceID(ctx),
+		SpanID:       utils.ExtractSpanID(ctx),
+		RequestPath:  ctx.Request.URL.Path,
+		Method:       ctx.Request.Method,
+		ServiceName:  utils.ExtractServiceName(ctx),
+		Tenant:       utils.ExtractTenant(ctx),
+		StatusCode:   ctx.Writer.Status(),
+		ErrorMessage: es.message(err.Error()),
+		StackTrace:   utils.ExtractStackTrace(err, es.truncator.maxFrames),
+		Timestamp:    time.Now(),
+		RequestBody:  es.captureBody(ctx),
+		SignalType:   ctx.GetString("signal_type"),
+		Route:        ctx.GetString("route_name"),
+		Version:      ctx.GetString("upstream_version"),
+		Errors:       es.stageErrors(ctx),
+	}
+
+	return es.enqueue(event)
+}
+
+// SampleEvent 直接提交由网关构建的事件（如卡住的请求），不按采样率丢弃
+func (es *errorSampler) SampleEvent(event *types.ErrorEvent) error {
+	if event.EventID == "" {
+		event.EventID = utils.GenerateID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	event.ErrorMessage = es.message(event.ErrorMessage)
+	return es.enqueue(event)
+}
+
+// message 脱敏错误消息。超长消息先截到两倍上限再脱敏，限制脱敏的开销，
+// 同时避免在上限处切断敏感信息导致其不再匹配脱敏规则；入队时再截到上限
+func (es *errorSampler) message(msg string) string {
+	msg, _ = truncateString(msg, 2*es.truncator.maxMessage)
+	return es.desensitizer.Desensitize(msg)
+}
+
+// enqueue 截断超限的事件后入队，队列满时丢弃
+func (es *errorSampler) enqueue(event *types.ErrorEvent) error {
+	if es.truncator.Apply(event) {
+		atomic.AddInt64(&es.truncated, 1)
+	}
+
+	select {
+	case es.queue <- event:
+		atomic.AddInt64(&es.sampled, 1)
+		return nil
+	default:
+		atomic.AddInt64(&es.dropped, 1)
+		return fmt.Errorf("sample queue is full, dropping event %s", event.EventID)
+	}
+}
+
+// stageErrors 汇总处理链各阶段的错误和注解，消息同样脱敏
+func (es *errorSampler) stageErrors(ctx *gin.Context) []types.StageError {
+	stages := utils.StageErrors(ctx)
+	for i := range stages {
+		stages[i].Message = es.message(stages[i].Message)
+	}
+	return stages
+}
+
+// captureBody 采集缓冲模式下的请求体，流式请求体不可重复读取因此不采集
+func (es *errorSampler) captureBody(ctx *gin.Context) string {
+	value, exists := ctx.Get("request_body")
+	if !exists {
+		return ""
+	}
+
+	body, ok := value.([]byte)
+	if !ok || len(body) == 0 {
+		return ""
+	}
+
+	limit := es.config.MaxBodyCapture
+	if limit <= 0 {
+		limit = defaultMaxBodyCapture
+	}
+	if len(body) > limit {
+		body = body[:limit]
+	}
+
+	return es.desensitizer.Desensitize(string(body))
+}
+
+// Start 启动Kafka生产者和发送协程
+func (es *errorSampler) Start() error {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForLocal
+	saramaConfig.Producer.Return.Errors = true
+	saramaConfig.Producer.Flush.Frequency = 100 * time.Millisecond
+
+	producer, err := sarama.NewAsyncProducer(es.kafkaConfig.Brokers, saramaConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka producer: %v", err)
+	}
+	es.producer = producer
+
+	es.wg.Add(2)
+	go es.sendLoop()
+	go es.errorLoop()
+
+	log.Printf("Error sampler started (sampling_rate=%.2f, topic_routes=%d)", es.config.SamplingRate, len(es.kafkaConfig.TopicRoutes))
+	return nil
+}
+
+// Stop 停止采样器，发送队列中剩余的事件
+func (es *errorSampler) Stop() error {
+	close(es.stopCh)
+
+	if es.producer != nil {
+		es.wg.Wait()
+		if err := es.producer.Close(); err != nil {
+			return fmt.Errorf("failed to close kafka producer: %v", err)
+		}
+	}
+
+	log.Println("Error sampler stopped")
+	return nil
+}
+
+// sendLoop 将采样事件按路由规则发送到对应topic
+func (es *errorSampler) sendLoop() {
+	defer es.wg.Done()
+
+	for {
+		select {
+		case event := <-es.queue:
+			es.send(event)
+		case <-es.stopCh:
+			for {
+				select {
+				case event := <-es.queue:
+					es.send(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 发送单个事件
+func (es *errorSampler) send(event *types.ErrorEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal error event: %v", err)
+		return
+	}
+
+	es.producer.Input() <- &sarama.ProducerMessage{
+		Topic: es.router.Route(event),
+		Key:   sarama.StringEncoder(event.ServiceName),
+		Value: sarama.ByteEncoder(data),
+	}
+	atomic.AddInt64(&es.flushed, 1)
+}
+
+// Stats 获取采样统计
+func (es *errorSampler) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"events_sampled":   atomic.LoadInt64(&es.sampled),
+		"events_dropped":   atomic.LoadInt64(&es.dropped),
+		"events_flushed":   atomic.LoadInt64(&es.flushed),
+		"send_failures":    atomic.LoadInt64(&es.sendFailed),
+		"events_truncated": atomic.LoadInt64(&es.truncated),
+		"queue_pending":    len(es.queue),
+	}
+}
+
+// Health 健康状态：未启动时不可用，发送队列已满时降级
+func (es *errorSampler) Health() types.ComponentHealth {
+	select {
+	case <-es.stopCh:
+		return types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "error sampler stopped"}
+	default:
+	}
+	if es.producer == nil {
+		return types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "kafka producer not started"}
+	}
+	if len(es.queue) == cap(es.queue) {
+		return types.ComponentHealth{Status: types.ComponentDegraded, Message: "send queue is full, sampled events are dropped"}
+	}
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+// errorLoop 记录发送失败
+func (es *errorSampler) errorLoop() {
+	defer es.wg.Done()
+
+	for {
+		select {
+		case err, ok := <-es.producer.Errors():
+			if !ok {
+				return
+			}
+			atomic.AddInt64(&es.sendFailed, 1)
+			log.Printf("Failed to send error event to topic %s: %v", err.Msg.Topic, err.Err)
+		case <-es.stopCh:
+			return
+		}
+	}
+}
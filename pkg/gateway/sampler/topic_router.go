@@ -0,0 +1,96 @@
+package sampler
+
+import (
+	"strings"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 严重度分类
+const (
+	SeverityCritical = "critical" // 5xx或gRPC服务端失败
+	SeverityClient   = "client"   // 4xx
+	SeverityOther    = "other"
+)
+
+// TopicRouter 按事件属性选择Kafka topic
+type TopicRouter struct {
+	defaultTopic string
+	routes       []types.TopicRouteConfig
+}
+
+// NewTopicRouter 创建topic路由器，规则按顺序匹配，均不匹配时使用默认topic
+func NewTopicRouter(defaultTopic string, routes []types.TopicRouteConfig) *TopicRouter {
+	return &TopicRouter{
+		defaultTopic: defaultTopic,
+		routes:       routes,
+	}
+}
+
+// Route 选择事件的目标topic
+func (tr *TopicRouter) Route(event *types.ErrorEvent) string {
+	severity := SeverityClass(event.StatusCode)
+
+	for _, route := range tr.routes {
+		if !matchAttr(route.Service, event.ServiceName) ||
+			!matchAttr(route.Severity, severity) ||
+			!matchAttr(route.Tenant, event.Tenant) {
+			continue
+		}
+		return expandTopic(route.Topic, event, severity)
+	}
+
+	return expandTopic(tr.defaultTopic, event, severity)
+}
+
+// SeverityClass 根据状态码判断严重度分类
+func SeverityClass(statusCode int) string {
+	switch {
+	case statusCode >= 500 || statusCode < 100:
+		// 状态码为0或200但上游失败的流式/gRPC请求同样按严重处理
+		return SeverityCritical
+	case statusCode >= 400:
+		return SeverityClient
+	default:
+		return SeverityOther
+	}
+}
+
+// matchAttr 匹配属性，规则为空或"*"时匹配任意值
+func matchAttr(pattern, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
+
+// expandTopic 展开topic模板中的{service}、{severity}、{tenant}占位符
+func expandTopic(template string, event *types.ErrorEvent, severity string) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+
+	tenant := event.Tenant
+	if tenant == "" {
+		tenant = "default"
+	}
+
+	replacer := strings.NewReplacer(
+		"{service}", sanitizeTopicPart(event.ServiceName),
+		"{severity}", severity,
+		"{tenant}", sanitizeTopicPart(tenant),
+	)
+	return replacer.Replace(template)
+}
+
+// sanitizeTopicPart 将topic片段限制为Kafka允许的字符
+func sanitizeTopicPart(part string) string {
+	if len(part) > 64 {
+		part = part[:64]
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, part)
+}
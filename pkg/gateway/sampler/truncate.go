@@ -0,0 +1,115 @@
+package sampler
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 截断默认值
+const (
+	defaultMaxMessageLength = 4096      // 错误消息和阶段错误消息的最大字节数
+	defaultMaxStackFrames   = 10        // 保留的最大堆栈帧数
+	defaultMaxEventSize     = 64 * 1024 // 序列化后事件的最大字节数
+)
+
+// Truncator 采样事件截断：限制错误消息长度、堆栈帧数和事件序列化后的总大小，
+// 避免上游返回的超长错误撑大Kafka消息、向量化输入和存储
+type Truncator struct {
+	maxMessage int
+	maxFrames  int
+	maxEvent   int
+}
+
+// NewTruncator 按采样配置创建截断器，未配置的限制使用默认值
+func NewTruncator(config *types.SamplerConfig) *Truncator {
+	t := &Truncator{
+		maxMessage: config.MaxMessageLength,
+		maxFrames:  config.MaxStackFrames,
+		maxEvent:   config.MaxEventSize,
+	}
+	if t.maxMessage <= 0 {
+		t.maxMessage = defaultMaxMessageLength
+	}
+	if t.maxFrames <= 0 {
+		t.maxFrames = defaultMaxStackFrames
+	}
+	if t.maxEvent <= 0 {
+		t.maxEvent = defaultMaxEventSize
+	}
+	return t
+}
+
+// Apply 截断事件并设置Truncated标记，返回是否发生截断。先按字段限制截断，
+// 总大小仍超限时依次丢弃请求体、阶段错误、堆栈帧，最后缩短错误消息
+func (t *Truncator) Apply(event *types.ErrorEvent) bool {
+	truncated := false
+	var cut bool
+
+	if event.ErrorMessage, cut = truncateString(event.ErrorMessage, t.maxMessage); cut {
+		truncated = true
+	}
+	for i := range event.Errors {
+		if event.Errors[i].Message, cut = truncateString(event.Errors[i].Message, t.maxMessage); cut {
+			truncated = true
+		}
+	}
+	if len(event.StackTrace) > t.maxFrames {
+		event.StackTrace = event.StackTrace[:t.maxFrames]
+		truncated = true
+	}
+	for i := range event.StackTrace {
+		if event.StackTrace[i], cut = truncateString(event.StackTrace[i], t.maxMessage); cut {
+			truncated = true
+		}
+	}
+
+	// 预留Truncated字段本身的长度
+	for excess := t.excess(event, truncated); excess > 0; excess = t.excess(event, true) {
+		truncated = true
+		switch {
+		case event.RequestBody != "":
+			event.RequestBody = ""
+		case len(event.Errors) > 0:
+			event.Errors = nil
+		case len(event.StackTrace) > 0:
+			event.StackTrace = event.StackTrace[:len(event.StackTrace)-1]
+		case event.ErrorMessage != "":
+			limit := len(event.ErrorMessage) - excess
+			if limit < 0 {
+				limit = 0
+			}
+			event.ErrorMessage, _ = truncateString(event.ErrorMessage, limit)
+		default:
+			// 剩余字段都很短，只有路径等请求属性异常时才会到这里，不再继续截断
+			event.Truncated = true
+			return true
+		}
+	}
+
+	event.Truncated = event.Truncated || truncated
+	return truncated
+}
+
+// excess 事件序列化后超出总大小限制的字节数
+func (t *Truncator) excess(event *types.ErrorEvent, truncated bool) int {
+	marked := *event
+	marked.Truncated = marked.Truncated || truncated
+	data, err := json.Marshal(&marked)
+	if err != nil {
+		return 0
+	}
+	return len(data) - t.maxEvent
+}
+
+// truncateString 按字节数截断字符串，不切断多字节字符
+func truncateString(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit], true
+}
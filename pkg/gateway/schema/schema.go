@@ -0,0 +1,594 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxViolations 单次校验返回的最大错误数
+const maxViolations = 20
+
+// maxRefDepth 连续$ref的解析深度上限
+const maxRefDepth = 32
+
+// Violation 校验错误，Path为JSON Pointer形式的字段位置
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Schema 编译后的JSON Schema，支持draft-07的常用关键字：
+// type、enum、const、properties、required、additionalProperties、items、
+// minItems/maxItems、minLength/maxLength、pattern、minimum/maximum、
+// exclusiveMinimum/exclusiveMaximum、allOf/anyOf/oneOf/not，以及指向
+// "#/definitions/..."或"#/$defs/..."的$ref；format等其他关键字被忽略
+type Schema struct {
+	root *node
+}
+
+// node 编译后的Schema节点
+type node struct {
+	always *bool // 布尔Schema
+
+	types      []string
+	enum       []interface{}
+	constant   *interface{}
+	properties map[string]*node
+	required   []string
+	additional *node // additionalProperties，false编译为always=false的节点
+	items      *node
+	minItems   *int
+	maxItems   *int
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+	minimum    *float64
+	maximum    *float64
+	exclMin    *float64
+	exclMax    *float64
+	allOf      []*node
+	anyOf      []*node
+	oneOf      []*node
+	not        *node
+
+	ref      string
+	resolved *node
+}
+
+// Load 从文件加载Schema，值以"{"开头时按内联Schema解析
+func Load(source string) (*Schema, error) {
+	if strings.HasPrefix(strings.TrimSpace(source), "{") {
+		return Compile([]byte(source))
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %v", err)
+	}
+	return Compile(data)
+}
+
+// Compile 编译Schema
+func Compile(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %v", err)
+	}
+
+	c := &compiler{root: raw, refs: make(map[string]*node)}
+	root, err := c.compile(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.resolve(); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate 校验JSON请求体，请求体不是合法JSON时返回一条根位置的错误
+func (s *Schema) Validate(data []byte) []Violation {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return []Violation{{Path: "", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	return s.ValidateValue(doc)
+}
+
+// ValidateValue 校验已解析的JSON值，数字需为json.Number或float64
+func (s *Schema) ValidateValue(doc interface{}) []Violation {
+	v := &validator{}
+	v.validate(s.root, doc, "")
+	return v.violations
+}
+
+// compiler Schema编译器
+type compiler struct {
+	root interface{}
+	refs map[string]*node
+	all  []*node
+}
+
+// compile 编译一个Schema节点
+func (c *compiler) compile(raw interface{}) (*node, error) {
+	n := &node{}
+	c.all = append(c.all, n)
+
+	if b, ok := raw.(bool); ok {
+		n.always = &b
+		return n, nil
+	}
+
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema must be an object or boolean")
+	}
+
+	if ref, ok := obj["$ref"].(string); ok {
+		// draft-07中$ref会忽略同级的其他关键字
+		n.ref = ref
+		return n, nil
+	}
+
+	switch t := obj["type"].(type) {
+	case string:
+		n.types = []string{t}
+	case []interface{}:
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("type must be a string or an array of strings")
+			}
+			n.types = append(n.types, name)
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("type must be a string or an array of strings")
+	}
+
+	if enum, ok := obj["enum"].([]interface{}); ok {
+		n.enum = enum
+	}
+	if constant, ok := obj["const"]; ok {
+		n.constant = &constant
+	}
+
+	if props, ok := obj["properties"].(map[string]interface{}); ok {
+		n.properties = make(map[string]*node, len(props))
+		for name, sub := range props {
+			child, err := c.compile(sub)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %v", name, err)
+			}
+			n.properties[name] = child
+		}
+	}
+	if required, ok := obj["required"].([]interface{}); ok {
+		for _, item := range required {
+			if name, ok := item.(string); ok {
+				n.required = append(n.required, name)
+			}
+		}
+	}
+	if additional, ok := obj["additionalProperties"]; ok {
+		child, err := c.compile(additional)
+		if err != nil {
+			return nil, fmt.Errorf("additionalProperties: %v", err)
+		}
+		n.additional = child
+	}
+	if items, ok := obj["items"]; ok {
+		child, err := c.compile(items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+		n.items = child
+	}
+
+	var err error
+	if n.minItems, err = intKeyword(obj, "minItems"); err != nil {
+		return nil, err
+	}
+	if n.maxItems, err = intKeyword(obj, "maxItems"); err != nil {
+		return nil, err
+	}
+	if n.minLength, err = intKeyword(obj, "minLength"); err != nil {
+		return nil, err
+	}
+	if n.maxLength, err = intKeyword(obj, "maxLength"); err != nil {
+		return nil, err
+	}
+	if n.minimum, err = numberKeyword(obj, "minimum"); err != nil {
+		return nil, err
+	}
+	if n.maximum, err = numberKeyword(obj, "maximum"); err != nil {
+		return nil, err
+	}
+	if n.exclMin, err = numberKeyword(obj, "exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if n.exclMax, err = numberKeyword(obj, "exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+
+	if pattern, ok := obj["pattern"].(string); ok {
+		if n.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	for keyword, target := range map[string]*[]*node{"allOf": &n.allOf, "anyOf": &n.anyOf, "oneOf": &n.oneOf} {
+		list, ok := obj[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, sub := range list {
+			child, err := c.compile(sub)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %v", keyword, i, err)
+			}
+			*target = append(*target, child)
+		}
+	}
+	if not, ok := obj["not"]; ok {
+		if n.not, err = c.compile(not); err != nil {
+			return nil, fmt.Errorf("not: %v", err)
+		}
+	}
+
+	return n, nil
+}
+
+// resolve 解析所有$ref，只支持文档内引用
+func (c *compiler) resolve() error {
+	for i := 0; i < len(c.all); i++ {
+		n := c.all[i]
+		if n.ref == "" {
+			continue
+		}
+
+		target, exists := c.refs[n.ref]
+		if !exists {
+			raw, err := c.lookup(n.ref)
+			if err != nil {
+				return err
+			}
+			// 先登记再编译，支持递归引用
+			target = &node{}
+			c.refs[n.ref] = target
+			compiled, err := c.compile(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %v", n.ref, err)
+			}
+			*target = *compiled
+			c.all = append(c.all, target)
+		}
+		n.resolved = target
+	}
+	return nil
+}
+
+// lookup 按JSON Pointer查找被引用的Schema
+func (c *compiler) lookup(ref string) (interface{}, error) {
+	if ref == "#" {
+		return c.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are supported", ref)
+	}
+
+	current := c.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if current, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return current, nil
+}
+
+// validator 一次校验的状态
+type validator struct {
+	violations []Violation
+}
+
+// fail 记录错误
+func (v *validator) fail(path, format string, args ...interface{}) {
+	if len(v.violations) < maxViolations {
+		v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// validate 校验一个值
+func (v *validator) validate(n *node, value interface{}, path string) {
+	// 只由$ref组成的循环引用不会终止，限制解析深度
+	for depth := 0; n.resolved != nil; depth++ {
+		if depth >= maxRefDepth {
+			v.fail(path, "schema reference is circular")
+			return
+		}
+		n = n.resolved
+	}
+
+	if n.always != nil {
+		if !*n.always {
+			v.fail(path, "value is not allowed")
+		}
+		return
+	}
+
+	if len(n.types) > 0 && !matchesType(n.types, value) {
+		v.fail(path, "expected %s, got %s", strings.Join(n.types, " or "), typeOf(value))
+		return
+	}
+
+	if n.constant != nil && !equal(*n.constant, value) {
+		v.fail(path, "value must be %s", display(*n.constant))
+	}
+	if n.enum != nil {
+		found := false
+		for _, item := range n.enum {
+			if equal(item, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			items := make([]string, 0, len(n.enum))
+			for _, item := range n.enum {
+				items = append(items, display(item))
+			}
+			v.fail(path, "value must be one of %s", strings.Join(items, ", "))
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(n, val, path)
+	case []interface{}:
+		if n.minItems != nil && len(val) < *n.minItems {
+			v.fail(path, "array must have at least %d items", *n.minItems)
+		}
+		if n.maxItems != nil && len(val) > *n.maxItems {
+			v.fail(path, "array must have at most %d items", *n.maxItems)
+		}
+		if n.items != nil {
+			for i, item := range val {
+				v.validate(n.items, item, path+"/"+strconv.Itoa(i))
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(val)
+		if n.minLength != nil && length < *n.minLength {
+			v.fail(path, "string must be at least %d characters", *n.minLength)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			v.fail(path, "string must be at most %d characters", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(val) {
+			v.fail(path, "string does not match pattern %q", n.pattern.String())
+		}
+	default:
+		if number, ok := toFloat(value); ok {
+			v.validateNumber(n, number, path)
+		}
+	}
+
+	for _, sub := range n.allOf {
+		v.validate(sub, value, path)
+	}
+	if len(n.anyOf) > 0 && countMatches(n.anyOf, value) == 0 {
+		v.fail(path, "value does not match any of the allowed schemas")
+	}
+	if len(n.oneOf) > 0 {
+		if matched := countMatches(n.oneOf, value); matched != 1 {
+			v.fail(path, "value must match exactly one schema, matched %d", matched)
+		}
+	}
+	if n.not != nil && countMatches([]*node{n.not}, value) == 1 {
+		v.fail(path, "value must not match the schema")
+	}
+}
+
+// validateObject 校验对象的必填字段和字段Schema
+func (v *validator) validateObject(n *node, obj map[string]interface{}, path string) {
+	for _, name := range n.required {
+		if _, exists := obj[name]; !exists {
+			v.fail(path+"/"+escapePointer(name), "required property is missing")
+		}
+	}
+
+	// 按字段名排序，保证错误顺序稳定
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := path + "/" + escapePointer(name)
+		if child, exists := n.properties[name]; exists {
+			v.validate(child, obj[name], fieldPath)
+		} else if n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				v.fail(fieldPath, "additional property is not allowed")
+			} else {
+				v.validate(n.additional, obj[name], fieldPath)
+			}
+		}
+	}
+}
+
+// validateNumber 校验数值范围
+func (v *validator) validateNumber(n *node, number float64, path string) {
+	if n.minimum != nil && number < *n.minimum {
+		v.fail(path, "value must be >= %v", *n.minimum)
+	}
+	if n.maximum != nil && number > *n.maximum {
+		v.fail(path, "value must be <= %v", *n.maximum)
+	}
+	if n.exclMin != nil && number <= *n.exclMin {
+		v.fail(path, "value must be > %v", *n.exclMin)
+	}
+	if n.exclMax != nil && number >= *n.exclMax {
+		v.fail(path, "value must be < %v", *n.exclMax)
+	}
+}
+
+// countMatches 满足的子Schema数量
+func countMatches(nodes []*node, value interface{}) int {
+	matched := 0
+	for _, sub := range nodes {
+		probe := &validator{}
+		probe.validate(sub, value, "")
+		if len(probe.violations) == 0 {
+			matched++
+		}
+	}
+	return matched
+}
+
+// matchesType 判断值是否属于任一类型
+func matchesType(types []string, value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf JSON类型名，整数值的数字为integer
+func typeOf(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		if number, ok := toFloat(val); ok {
+			if number == math.Trunc(number) && !math.IsInf(number, 0) {
+				return "integer"
+			}
+			return "number"
+		}
+	}
+	return "unknown"
+}
+
+// toFloat 数字转float64
+func toFloat(value interface{}) (float64, bool) {
+	switch val := value.(type) {
+	case json.Number:
+		f, err := val.Float64()
+		return f, err == nil
+	case float64:
+		return val, true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	}
+	return 0, false
+}
+
+// equal 比较两个JSON值，数字按数值比较
+func equal(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, item := range av {
+			if other, exists := bv[key]; !exists || !equal(item, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// display 值的JSON表示
+func display(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// escapePointer 转义JSON Pointer中的字段名
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// intKeyword 读取非负整数关键字
+func intKeyword(obj map[string]interface{}, keyword string) (*int, error) {
+	raw, exists := obj[keyword]
+	if !exists {
+		return nil, nil
+	}
+	number, ok := toFloat(raw)
+	if !ok || number < 0 || number != math.Trunc(number) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", keyword)
+	}
+	value := int(number)
+	return &value, nil
+}
+
+// numberKeyword 读取数值关键字
+func numberKeyword(obj map[string]interface{}, keyword string) (*float64, error) {
+	raw, exists := obj[keyword]
+	if !exists {
+		return nil, nil
+	}
+	number, ok := toFloat(raw)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", keyword)
+	}
+	return &number, nil
+}
@@ -0,0 +1,175 @@
+package soak
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ShapeRecord 一秒内某类请求的流量形态
+type ShapeRecord struct {
+	Offset       int64   `json:"offset"` // 相对录制开始的秒数
+	Method       string  `json:"method"`
+	Path         string  `json:"path"`
+	Status       int     `json:"status"`
+	Count        int64   `json:"count"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// shapeKey 聚合键
+type shapeKey struct {
+	offset int64
+	method string
+	path   string
+	status int
+}
+
+// shapeAgg 聚合值
+type shapeAgg struct {
+	count   int64
+	latency time.Duration
+}
+
+// Recorder 流量形态录制器，只记录请求元数据，不记录请求体
+type Recorder struct {
+	file    *os.File
+	writer  *bufio.Writer
+	start   time.Time
+	buckets map[shapeKey]*shapeAgg
+	mutex   sync.Mutex
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRecorder 创建流量形态录制器
+func NewRecorder(config *types.SoakConfig) (*Recorder, error) {
+	file, err := os.OpenFile(config.CaptureFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %v", err)
+	}
+
+	flushInterval := config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+
+	r := &Recorder{
+		file:    file,
+		writer:  bufio.NewWriter(file),
+		start:   time.Now(),
+		buckets: make(map[shapeKey]*shapeAgg),
+		stopCh:  make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go r.flushLoop(flushInterval)
+
+	log.Printf("Soak capture started, writing to %s", config.CaptureFile)
+	return r, nil
+}
+
+// Middleware 录制中间件
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		r.record(c.Request.Method, c.Request.URL.Path, c.Writer.Status(), start, time.Since(start))
+	}
+}
+
+// Stop 停止录制并写出剩余数据
+func (r *Recorder) Stop() error {
+	close(r.stopCh)
+	r.wg.Wait()
+
+	r.flush(true)
+
+	if err := r.writer.Flush(); err != nil {
+		log.Printf("Failed to flush capture file: %v", err)
+	}
+
+	log.Println("Soak capture stopped")
+	return r.file.Close()
+}
+
+// record 记录一次请求
+func (r *Recorder) record(method, path string, status int, at time.Time, latency time.Duration) {
+	key := shapeKey{
+		offset: int64(at.Sub(r.start) / time.Second),
+		method: method,
+		path:   path,
+		status: status,
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	agg, exists := r.buckets[key]
+	if !exists {
+		agg = &shapeAgg{}
+		r.buckets[key] = agg
+	}
+	agg.count++
+	agg.latency += latency
+}
+
+// flushLoop 定期写出已结束的秒级桶
+func (r *Recorder) flushLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.flush(false)
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// flush 写出秒级桶，all为false时保留当前秒
+func (r *Recorder) flush(all bool) {
+	current := int64(time.Since(r.start) / time.Second)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, agg := range r.buckets {
+		if !all && key.offset >= current {
+			continue
+		}
+
+		record := ShapeRecord{
+			Offset:       key.offset,
+			Method:       key.method,
+			Path:         key.path,
+			Status:       key.status,
+			Count:        agg.count,
+			AvgLatencyMs: float64(agg.latency.Milliseconds()) / float64(agg.count),
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			continue
+		}
+
+		r.writer.Write(data)
+		r.writer.WriteByte('\n')
+		delete(r.buckets, key)
+	}
+
+	r.writer.Flush()
+}
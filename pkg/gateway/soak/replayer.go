@@ -0,0 +1,159 @@
+package soak
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplayStats 回放统计
+type ReplayStats struct {
+	Sent       int64 `json:"sent"`
+	Failed     int64 `json:"failed"`
+	Mismatched int64 `json:"mismatched"` // 返回状态与录制状态类别不一致
+}
+
+// Replayer 流量形态回放器
+type Replayer struct {
+	target  string
+	speed   float64
+	client  *http.Client
+	records map[int64][]ShapeRecord
+	maxSec  int64
+	stats   ReplayStats
+}
+
+// NewReplayer 创建回放器，speed为时间轴倍速
+func NewReplayer(captureFile, target string, speed float64) (*Replayer, error) {
+	file, err := os.Open(captureFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %v", err)
+	}
+	defer file.Close()
+
+	if speed <= 0 {
+		speed = 1.0
+	}
+
+	r := &Replayer{
+		target:  target,
+		speed:   speed,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		records: make(map[int64][]ShapeRecord),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record ShapeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("Skipping invalid shape record: %v", err)
+			continue
+		}
+
+		r.records[record.Offset] = append(r.records[record.Offset], record)
+		if record.Offset > r.maxSec {
+			r.maxSec = record.Offset
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read capture file: %v", err)
+	}
+
+	return r, nil
+}
+
+// Run 按录制的时间轴回放流量
+func (r *Replayer) Run(ctx context.Context) (*ReplayStats, error) {
+	offsets := make([]int64, 0, len(r.records))
+	for offset := range r.records {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	start := time.Now()
+	var wg sync.WaitGroup
+
+	for _, offset := range offsets {
+		due := start.Add(time.Duration(float64(offset) * float64(time.Second) / r.speed))
+		select {
+		case <-time.After(time.Until(due)):
+		case <-ctx.Done():
+			wg.Wait()
+			return &r.stats, ctx.Err()
+		}
+
+		window := time.Duration(float64(time.Second) / r.speed)
+		for _, record := range r.records[offset] {
+			wg.Add(1)
+			go func(record ShapeRecord) {
+				defer wg.Done()
+				r.replayRecord(ctx, record, window)
+			}(record)
+		}
+	}
+
+	wg.Wait()
+	log.Printf("Replay finished: %d seconds, sent=%d failed=%d mismatched=%d",
+		r.maxSec+1, r.stats.Sent, r.stats.Failed, r.stats.Mismatched)
+
+	return &r.stats, nil
+}
+
+// replayRecord 在时间窗口内均匀发出一条形态记录对应的请求
+func (r *Replayer) replayRecord(ctx context.Context, record ShapeRecord, window time.Duration) {
+	interval := window / time.Duration(record.Count)
+
+	for i := int64(0); i < record.Count; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		status, err := r.send(ctx, record)
+		atomic.AddInt64(&r.stats.Sent, 1)
+		if err != nil {
+			atomic.AddInt64(&r.stats.Failed, 1)
+			continue
+		}
+		if status/100 != record.Status/100 {
+			atomic.AddInt64(&r.stats.Mismatched, 1)
+		}
+	}
+}
+
+// send 发送一次合成请求，录制时为5xx的请求通过测试钩子复现错误
+// 目标网关需使用testhooks构建标签编译并开启test_hooks.enabled
+func (r *Replayer) send(ctx context.Context, record ShapeRecord) (int, error) {
+	url := r.target + record.Path
+	if record.Status >= 500 {
+		url += "?simulate_error=true"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, record.Method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Soak-Replay", "true")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,29 @@
+//go:build !testhooks
+
+// Package testhooks 测试与混沌钩子，仅在使用testhooks构建标签编译时生效
+package testhooks
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Compiled 测试钩子是否编译进当前二进制
+const Compiled = false
+
+// Hooks 生产构建不包含测试钩子
+type Hooks struct{}
+
+// New 生产构建不包含测试钩子，始终返回nil
+func New(config *types.TestHooksConfig) (*Hooks, error) {
+	return nil, nil
+}
+
+// Middleware 生产构建不包含测试钩子
+func (h *Hooks) Middleware() gin.HandlerFunc {
+	return nil
+}
+
+// RegisterAdmin 生产构建不注册故障注入管理接口
+func (h *Hooks) RegisterAdmin(admin *gin.RouterGroup) {}
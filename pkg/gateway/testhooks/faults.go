@@ -0,0 +1,242 @@
+//go:build testhooks
+
+package testhooks
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/router"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// fault 生效中的故障注入规则
+type fault struct {
+	rule       types.FaultRule
+	routes     map[string]bool
+	expireTime time.Time
+	injected   int64
+}
+
+// faultTable 故障注入规则表
+type faultTable struct {
+	faults map[string]*fault
+	mutex  sync.Mutex
+}
+
+// newFaultTable 创建故障注入规则表
+func newFaultTable() *faultTable {
+	return &faultTable{faults: make(map[string]*fault)}
+}
+
+// set 添加或替换规则
+func (ft *faultTable) set(rule types.FaultRule) error {
+	if rule.ID == "" {
+		return fmt.Errorf("fault rule id is empty")
+	}
+	if rule.Percentage <= 0 || rule.Percentage > 100 {
+		return fmt.Errorf("fault %s: percentage must be in (0, 100]", rule.ID)
+	}
+	if rule.Delay <= 0 && !rule.Abort && rule.Status == 0 {
+		return fmt.Errorf("fault %s: one of delay, abort or status is required", rule.ID)
+	}
+	if rule.Status != 0 && (rule.Status < 400 || rule.Status > 599) {
+		return fmt.Errorf("fault %s: status must be an error status code", rule.ID)
+	}
+
+	f := &fault{rule: rule}
+	if len(rule.Routes) > 0 {
+		f.routes = make(map[string]bool, len(rule.Routes))
+		for _, name := range rule.Routes {
+			f.routes[name] = true
+		}
+	}
+	if rule.Duration > 0 {
+		f.expireTime = time.Now().Add(rule.Duration)
+	}
+
+	ft.mutex.Lock()
+	ft.faults[rule.ID] = f
+	ft.mutex.Unlock()
+
+	log.Printf("Fault injection %s enabled: %.1f%% of requests", rule.ID, rule.Percentage)
+	return nil
+}
+
+// remove 删除规则
+func (ft *faultTable) remove(id string) bool {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+
+	if _, exists := ft.faults[id]; !exists {
+		return false
+	}
+	delete(ft.faults, id)
+	log.Printf("Fault injection %s disabled", id)
+	return true
+}
+
+// pick 选出对请求生效的第一条规则，同时清理已到期的规则
+func (ft *faultTable) pick(c *gin.Context) *fault {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+
+	if len(ft.faults) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	routeName := ""
+	if value, exists := c.Get("route"); exists {
+		if route, ok := value.(*router.Route); ok {
+			routeName = route.Name
+		}
+	}
+
+	ids := make([]string, 0, len(ft.faults))
+	for id, f := range ft.faults {
+		if !f.expireTime.IsZero() && now.After(f.expireTime) {
+			delete(ft.faults, id)
+			log.Printf("Fault injection %s expired", id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		f := ft.faults[id]
+		if !f.matches(routeName, c.Request.URL.Path) || rand.Float64()*100 >= f.rule.Percentage {
+			continue
+		}
+		f.injected++
+		return f
+	}
+	return nil
+}
+
+// matches 判断规则是否作用于请求
+func (f *fault) matches(routeName, path string) bool {
+	if f.routes != nil && !f.routes[routeName] {
+		return false
+	}
+	if f.rule.PathPrefix != "" && !strings.HasPrefix(path, f.rule.PathPrefix) {
+		return false
+	}
+	return true
+}
+
+// inject 按规则注入故障，返回true表示请求已被中止
+func (ft *faultTable) inject(c *gin.Context) bool {
+	f := ft.pick(c)
+	if f == nil {
+		return false
+	}
+
+	c.Set("fault_injected", f.rule.ID)
+
+	if f.rule.Delay > 0 {
+		timer := time.NewTimer(f.rule.Delay)
+		select {
+		case <-timer.C:
+		case <-c.Request.Context().Done():
+			timer.Stop()
+			c.Abort()
+			return true
+		}
+	}
+
+	if f.rule.Abort {
+		utils.RecordStageError(c, "fault", fmt.Errorf("fault %s: connection aborted", f.rule.ID))
+		if hijacker, ok := c.Writer.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				c.Abort()
+				return true
+			}
+		}
+		// HTTP/2等无法接管连接时以502代替
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error": "Fault injected: connection aborted",
+			"code":  "FAULT_INJECTED",
+			"fault": f.rule.ID,
+		})
+		return true
+	}
+
+	if f.rule.Status != 0 {
+		c.AbortWithStatusJSON(f.rule.Status, gin.H{
+			"error": "Fault injected",
+			"code":  "FAULT_INJECTED",
+			"fault": f.rule.ID,
+		})
+		return true
+	}
+
+	return false
+}
+
+// list 获取生效中的规则
+func (ft *faultTable) list() []gin.H {
+	ft.mutex.Lock()
+	defer ft.mutex.Unlock()
+
+	ids := make([]string, 0, len(ft.faults))
+	for id := range ft.faults {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	faults := make([]gin.H, 0, len(ids))
+	for _, id := range ids {
+		f := ft.faults[id]
+		faults = append(faults, gin.H{
+			"rule":        f.rule,
+			"expire_time": f.expireTime,
+			"injected":    f.injected,
+		})
+	}
+	return faults
+}
+
+// listFaultsHandler 获取故障注入规则
+func (h *Hooks) listFaultsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"faults": h.faults.list()})
+}
+
+// setFaultHandler 添加或替换故障注入规则
+func (h *Hooks) setFaultHandler(c *gin.Context) {
+	var rule types.FaultRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid fault rule: %v", err)})
+		return
+	}
+	rule.ID = c.Param("id")
+
+	if err := h.faults.set(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fault": rule})
+}
+
+// deleteFaultHandler 删除故障注入规则
+func (h *Hooks) deleteFaultHandler(c *gin.Context) {
+	id := c.Param("id")
+	if !h.faults.remove(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Fault not found: %s", id)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": id})
+}
@@ -0,0 +1,61 @@
+//go:build testhooks
+
+// Package testhooks 测试与混沌钩子，仅在使用testhooks构建标签编译时生效
+package testhooks
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// Compiled 测试钩子是否编译进当前二进制
+const Compiled = true
+
+// Hooks 测试钩子
+type Hooks struct {
+	faults *faultTable
+}
+
+// New 创建测试钩子，加载配置中的故障注入规则
+func New(config *types.TestHooksConfig) (*Hooks, error) {
+	faults := newFaultTable()
+	for _, rule := range config.Faults {
+		if err := faults.set(rule); err != nil {
+			return nil, err
+		}
+	}
+	return &Hooks{faults: faults}, nil
+}
+
+// Middleware 测试钩子中间件，需放在中间件链末尾，使模拟错误和注入的故障经过采样和指标统计
+// 支持的钩子：?simulate_error=true 返回500；按故障注入规则注入延迟、中止和错误状态码
+func (h *Hooks) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("simulate_error") == "true" {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Simulated error for testing",
+				"service": utils.ExtractServiceName(c),
+				"path":    c.Request.URL.Path,
+			})
+			c.Abort()
+			return
+		}
+
+		if h.faults.inject(c) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RegisterAdmin 注册故障注入管理接口
+func (h *Hooks) RegisterAdmin(admin *gin.RouterGroup) {
+	admin.GET("/faults", h.listFaultsHandler)
+	admin.PUT("/faults/:id", h.setFaultHandler)
+	admin.DELETE("/faults/:id", h.deleteFaultHandler)
+}
@@ -0,0 +1,210 @@
+package tlsconf
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ClientTLS 访问上游的客户端TLS配置，支持客户端证书、CA固定和证书轮换
+type ClientTLS struct {
+	clientCert *CertReloader
+	caFile     string
+	caPool     *x509.CertPool
+	caModTime  time.Time
+	pins       map[string]bool
+	config     *types.UpstreamTLSConfig
+	mutex      sync.RWMutex
+}
+
+// NewClientTLS 创建上游客户端TLS配置
+func NewClientTLS(config *types.UpstreamTLSConfig) (*ClientTLS, error) {
+	ct := &ClientTLS{
+		caFile: config.CAFile,
+		pins:   make(map[string]bool),
+		config: config,
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		reloader, err := NewCertReloader(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		ct.clientCert = reloader
+	}
+
+	if ct.caFile != "" {
+		if err := ct.reloadCA(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pin := range config.PinnedSHA256 {
+		ct.pins[pin] = true
+	}
+
+	return ct, nil
+}
+
+// TLSConfig 构建标准库TLS配置
+// 证书校验在VerifyConnection中使用当前CA池完成，CA文件轮换后无需重建连接池
+func (ct *ClientTLS) TLSConfig() (*tls.Config, error) {
+	minVersion, err := ParseVersion(ct.config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: ct.config.ServerName,
+	}
+
+	if ct.clientCert != nil {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return ct.clientCert.Certificate(), nil
+		}
+	}
+
+	if ct.caFile != "" || len(ct.pins) > 0 {
+		// 关闭内置校验，由verifyConnection按当前CA池和固定指纹校验
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = ct.verifyConnection
+	}
+
+	return tlsConfig, nil
+}
+
+// Watch 定期检查客户端证书和CA文件是否变更，直到stopCh关闭
+func (ct *ClientTLS) Watch(stopCh <-chan struct{}) {
+	interval := ct.config.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	if ct.clientCert != nil {
+		ct.clientCert.Watch(interval, stopCh)
+	}
+
+	if ct.caFile == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if ct.caChanged() {
+					if err := ct.reloadCA(); err != nil {
+						log.Printf("Failed to reload CA bundle %s: %v", ct.caFile, err)
+					} else {
+						log.Printf("Reloaded CA bundle %s", ct.caFile)
+					}
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// verifyConnection 校验上游证书链和固定指纹
+func (ct *ClientTLS) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("upstream presented no certificate")
+	}
+
+	leaf := state.PeerCertificates[0]
+
+	ct.mutex.RLock()
+	roots := ct.caPool
+	ct.mutex.RUnlock()
+
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		serverName := ct.config.ServerName
+		if serverName == "" {
+			serverName = state.ServerName
+		}
+
+		if _, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			DNSName:       serverName,
+		}); err != nil {
+			return fmt.Errorf("failed to verify upstream certificate: %v", err)
+		}
+	}
+
+	if len(ct.pins) > 0 && !ct.pinned(state.PeerCertificates) {
+		return fmt.Errorf("upstream certificate does not match any pinned key")
+	}
+
+	return nil
+}
+
+// pinned 检查证书链中是否有公钥与固定指纹匹配
+func (ct *ClientTLS) pinned(certs []*x509.Certificate) bool {
+	for _, cert := range certs {
+		if ct.pins[SPKIFingerprint(cert)] {
+			return true
+		}
+	}
+	return false
+}
+
+// caChanged 检查CA文件是否变更
+func (ct *ClientTLS) caChanged() bool {
+	modTime, err := latestModTime(ct.caFile)
+	if err != nil {
+		return false
+	}
+
+	ct.mutex.RLock()
+	defer ct.mutex.RUnlock()
+	return modTime.After(ct.caModTime)
+}
+
+// reloadCA 加载CA证书
+func (ct *ClientTLS) reloadCA() error {
+	modTime, err := latestModTime(ct.caFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(ct.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no valid certificates in CA bundle %s", ct.caFile)
+	}
+
+	ct.mutex.Lock()
+	ct.caPool = pool
+	ct.caModTime = modTime
+	ct.mutex.Unlock()
+
+	return nil
+}
+
+// SPKIFingerprint 计算证书公钥的SHA256指纹（base64编码）
+func SPKIFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package tlsconf
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader 证书热加载器，证书文件变更后自动重新加载
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time
+	mutex    sync.RWMutex
+}
+
+// NewCertReloader 创建证书热加载器
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Certificate 获取当前证书
+func (r *CertReloader) Certificate() *tls.Certificate {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert
+}
+
+// Watch 定期检查证书文件是否变更，直到stopCh关闭
+func (r *CertReloader) Watch(interval time.Duration, stopCh <-chan struct{}) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if r.changed() {
+					if err := r.reload(); err != nil {
+						log.Printf("Failed to reload certificate %s: %v", r.certFile, err)
+					} else {
+						log.Printf("Reloaded certificate %s", r.certFile)
+					}
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// changed 检查证书文件是否变更
+func (r *CertReloader) changed() bool {
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return modTime.After(r.modTime)
+}
+
+// reload 加载证书
+func (r *CertReloader) reload() error {
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %v", err)
+	}
+
+	r.mutex.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mutex.Unlock()
+
+	return nil
+}
+
+// latestModTime 获取证书和私钥文件的最新修改时间
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat %s: %v", file, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,129 @@
+package tlsconf
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ServerTLS 网关监听器TLS配置
+type ServerTLS struct {
+	defaultCert *CertReloader
+	sniCerts    map[string]*CertReloader
+	config      *types.TLSConfig
+}
+
+// NewServerTLS 创建监听器TLS配置
+func NewServerTLS(config *types.TLSConfig) (*ServerTLS, error) {
+	defaultCert, err := NewCertReloader(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default certificate: %v", err)
+	}
+
+	st := &ServerTLS{
+		defaultCert: defaultCert,
+		sniCerts:    make(map[string]*CertReloader),
+		config:      config,
+	}
+
+	for _, sni := range config.SNI {
+		reloader, err := NewCertReloader(sni.CertFile, sni.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate for host %s: %v", sni.Host, err)
+		}
+		st.sniCerts[strings.ToLower(sni.Host)] = reloader
+	}
+
+	return st, nil
+}
+
+// TLSConfig 构建标准库TLS配置
+func (st *ServerTLS) TLSConfig() (*tls.Config, error) {
+	minVersion, err := ParseVersion(st.config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := ParseCipherSuites(st.config.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: st.getCertificate,
+	}, nil
+}
+
+// Watch 启动所有证书的热加载
+func (st *ServerTLS) Watch(stopCh <-chan struct{}) {
+	interval := st.config.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	st.defaultCert.Watch(interval, stopCh)
+	for _, reloader := range st.sniCerts {
+		reloader.Watch(interval, stopCh)
+	}
+}
+
+// getCertificate 按SNI选择证书，支持通配符域名
+func (st *ServerTLS) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	serverName := strings.ToLower(hello.ServerName)
+
+	if reloader, exists := st.sniCerts[serverName]; exists {
+		return reloader.Certificate(), nil
+	}
+
+	if idx := strings.IndexByte(serverName, '.'); idx > 0 {
+		if reloader, exists := st.sniCerts["*"+serverName[idx:]]; exists {
+			return reloader.Certificate(), nil
+		}
+	}
+
+	return st.defaultCert.Certificate(), nil
+}
+
+// ParseVersion 解析TLS版本
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version: %s", version)
+	}
+}
+
+// ParseCipherSuites 按名称解析加密套件，为空时使用Go默认套件
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	available := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		available[suite.Name] = suite.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, exists := available[name]
+		if !exists {
+			return nil, fmt.Errorf("unsupported or insecure cipher suite: %s", name)
+		}
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}
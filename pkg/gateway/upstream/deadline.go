@@ -0,0 +1,27 @@
+package upstream
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// propagateDeadline 将请求剩余时间写入出站请求头，上游可据此提前放弃注定超时的处理
+func propagateDeadline(out *http.Request, grpc bool) {
+	deadline, ok := out.Context().Deadline()
+	if !ok {
+		return
+	}
+
+	remaining := time.Until(deadline)
+	if remaining < time.Millisecond {
+		remaining = time.Millisecond
+	}
+
+	out.Header.Set("X-Request-Timeout", strconv.FormatInt(remaining.Milliseconds(), 10))
+	if grpc {
+		out.Header.Set("Grpc-Timeout", utils.FormatGRPCTimeout(remaining))
+	}
+}
@@ -0,0 +1,91 @@
+package upstream
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+// idleCloser 支持关闭空闲连接的传输层
+type idleCloser interface {
+	CloseIdleConnections()
+}
+
+// watchDNS 定期重新解析上游域名，地址变化时关闭空闲连接，
+// 使长连接池在上游扩缩容或切换后连接到新地址
+func (p *Pool) watchDNS(interval time.Duration, stopCh <-chan struct{}) {
+	hosts := make(map[string]string)
+	p.mutex.Lock()
+	for _, target := range p.targets {
+		host := target.URL.Hostname()
+		if _, err := netip.ParseAddr(host); err != nil {
+			hosts[host] = ""
+		}
+	}
+	p.mutex.Unlock()
+	if len(hosts) == 0 {
+		return
+	}
+
+	// 记录初始解析结果作为基线
+	for host := range hosts {
+		hosts[host] = resolveHost(host)
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if p.refreshDNS(hosts) {
+					p.closeIdleConnections()
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// refreshDNS 重新解析域名，返回是否有地址变化
+func (p *Pool) refreshDNS(hosts map[string]string) bool {
+	changed := false
+	for host, previous := range hosts {
+		current := resolveHost(host)
+		if current == "" || current == previous {
+			continue
+		}
+
+		log.Printf("Upstream %s host %s resolved to new addresses: %s", p.name, host, current)
+		hosts[host] = current
+		changed = true
+	}
+	return changed
+}
+
+// closeIdleConnections 关闭上游的空闲连接
+func (p *Pool) closeIdleConnections() {
+	if closer, ok := p.transport.(idleCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// resolveHost 解析域名，返回排序后的地址列表，失败时返回空
+func resolveHost(host string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		log.Printf("Failed to resolve upstream host %s: %v", host, err)
+		return ""
+	}
+
+	sort.Strings(addrs)
+	return strings.Join(addrs, ",")
+}
@@ -0,0 +1,153 @@
+package upstream
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+const (
+	// weightScale 权重放大倍数，保证权重系数细粒度生效
+	weightScale = 100
+
+	// defaultBreakerRecoveryStep 实例熔断器默认恢复步长
+	defaultBreakerRecoveryStep = 0.2
+)
+
+// targetHealth 实例健康信号
+type targetHealth struct {
+	latencyEWMA float64 // 延迟EWMA（秒）
+	errorEWMA   float64 // 错误率EWMA
+	samples     int64
+	degraded    bool
+	factor      float64 // 当前生效的权重系数
+}
+
+// newTargetHealth 创建实例健康信号
+func newTargetHealth() *targetHealth {
+	return &targetHealth{
+		factor: 1.0,
+	}
+}
+
+// observe 记录一次请求结果（需要加锁调用）
+func (th *targetHealth) observe(latency time.Duration, failed bool, alpha float64) {
+	errorValue := 0.0
+	if failed {
+		errorValue = 1.0
+	}
+
+	if th.samples == 0 {
+		th.latencyEWMA = latency.Seconds()
+		th.errorEWMA = errorValue
+	} else {
+		th.latencyEWMA = alpha*latency.Seconds() + (1-alpha)*th.latencyEWMA
+		th.errorEWMA = alpha*errorValue + (1-alpha)*th.errorEWMA
+	}
+
+	th.samples++
+}
+
+// withHealthDefaults 填充健康加权默认配置
+func withHealthDefaults(config types.HealthWeightConfig) types.HealthWeightConfig {
+	if config.LatencyAlpha <= 0 || config.LatencyAlpha > 1 {
+		config.LatencyAlpha = 0.2
+	}
+	if config.DegradeRatio <= 1 {
+		config.DegradeRatio = 2.0
+	}
+	if config.RecoverRatio <= 0 || config.RecoverRatio >= config.DegradeRatio {
+		config.RecoverRatio = (1 + config.DegradeRatio) / 2
+	}
+	if config.MinWeightFactor <= 0 {
+		config.MinWeightFactor = 0.05
+	}
+	if config.HalfOpenFactor <= 0 {
+		config.HalfOpenFactor = 0.25
+	}
+	if config.RecoverStep <= 0 {
+		config.RecoverStep = 0.2
+	}
+	if config.UpdateInterval <= 0 {
+		config.UpdateInterval = time.Second
+	}
+	if config.BreakDuration <= 0 {
+		config.BreakDuration = 30 * time.Second
+	}
+	return config
+}
+
+// recalculate 根据熔断状态和延迟信号重算权重系数（需要加锁调用）
+func (p *Pool) recalculate() {
+	if !p.config.Enabled {
+		return
+	}
+
+	median := p.medianLatency()
+
+	for _, target := range p.targets {
+		health := target.health
+
+		// 开启状态的熔断器到期后读取状态即为半开，不经过Allow以免记录虚假请求
+		state := p.breakerState(target)
+
+		// 延迟信号：进入与退出降权使用不同阈值，避免在阈值附近来回抖动
+		ratio := 1.0
+		if median > 0 && health.samples > 0 {
+			ratio = health.latencyEWMA / median
+		}
+
+		wasDegraded := health.degraded
+		if !health.degraded && ratio >= p.config.DegradeRatio {
+			health.degraded = true
+		} else if health.degraded && ratio <= p.config.RecoverRatio {
+			health.degraded = false
+		}
+
+		if health.degraded != wasDegraded {
+			log.Printf("Upstream target %s degraded=%v (latency ratio: %.2f)", target.ID, health.degraded, ratio)
+		}
+
+		desired := 1.0
+		if health.degraded {
+			desired = 1.0 / ratio
+		}
+
+		// 错误信号：熔断器开启前按近期错误率逐步降权
+		desired *= 1.0 - health.errorEWMA
+
+		// 熔断信号
+		if state == types.BreakerStateHalfOpen {
+			desired *= p.config.HalfOpenFactor
+		}
+
+		desired = utils.ClampFloat64(desired, p.config.MinWeightFactor, 1.0)
+
+		// 降权立即生效，回升按步长缓慢进行
+		if desired < health.factor {
+			health.factor = desired
+		} else {
+			health.factor = utils.MinFloat64(desired, health.factor+p.config.RecoverStep)
+		}
+	}
+}
+
+// medianLatency 计算池内实例延迟中位数（需要加锁调用）
+func (p *Pool) medianLatency() float64 {
+	latencies := make([]float64, 0, len(p.targets))
+	for _, target := range p.targets {
+		if target.health.samples > 0 {
+			latencies = append(latencies, target.health.latencyEWMA)
+		}
+	}
+
+	if len(latencies) == 0 {
+		return 0
+	}
+
+	sort.Float64s(latencies)
+	return latencies[len(latencies)/2]
+}
@@ -0,0 +1,107 @@
+package upstream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/llm-aware-gateway/pkg/gateway/compression"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Manager 上游管理器
+type Manager struct {
+	pools       map[string]*Pool
+	breaker     interfaces.CircuitBreaker
+	mirrorSem   chan struct{}        // 限制并发镜像请求数
+	compression *compression.Handler // 可选，改写校验响应体前解压
+	mutex       sync.RWMutex
+}
+
+// NewManager 创建上游管理器
+func NewManager(configs []types.UpstreamConfig, breaker interfaces.CircuitBreaker) (*Manager, error) {
+	m := &Manager{
+		pools:     make(map[string]*Pool),
+		breaker:   breaker,
+		mirrorSem: make(chan struct{}, defaultMirrorConcurrency),
+	}
+
+	for i := range configs {
+		pool, err := NewPool(&configs[i], breaker)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create upstream pool: %v", err)
+		}
+		m.pools[pool.Name()] = pool
+	}
+
+	return m, nil
+}
+
+// SetCompression 设置内容编码处理，需在开始转发前调用
+func (m *Manager) SetCompression(handler *compression.Handler) {
+	m.compression = handler
+}
+
+// Pool 获取上游实例池
+func (m *Manager) Pool(name string) (*Pool, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	pool, exists := m.pools[name]
+	return pool, exists
+}
+
+// Watch 启动上游客户端证书和CA的热加载及域名刷新
+func (m *Manager) Watch(stopCh <-chan struct{}) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, pool := range m.pools {
+		if pool.clientTLS != nil {
+			pool.clientTLS.Watch(stopCh)
+		}
+		// 内置解析器按记录TTL刷新，否则按固定间隔检查系统解析结果
+		if pool.resolver != nil {
+			pool.resolver.watch(stopCh, func(string) { pool.closeIdleConnections() })
+		} else if pool.dnsRefresh > 0 {
+			pool.watchDNS(pool.dnsRefresh, stopCh)
+		}
+	}
+}
+
+// Discover 为配置了服务发现的上游监听实例变更
+func (m *Manager) Discover(providers map[string]interfaces.Discovery) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, pool := range m.pools {
+		if pool.discovery == nil {
+			continue
+		}
+
+		provider, exists := providers[pool.discovery.Provider]
+		if !exists {
+			return fmt.Errorf("unknown discovery provider %q for upstream %s", pool.discovery.Provider, pool.name)
+		}
+
+		pool := pool
+		if err := provider.Watch(pool.discovery, pool.SetTargets); err != nil {
+			return fmt.Errorf("failed to watch targets for upstream %s: %v", pool.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Status 获取所有上游实例状态
+func (m *Manager) Status() map[string][]TargetStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status := make(map[string][]TargetStatus, len(m.pools))
+	for name, pool := range m.pools {
+		status[name] = pool.Status()
+	}
+
+	return status
+}
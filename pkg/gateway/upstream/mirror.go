@@ -0,0 +1,123 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 镜像默认值
+const (
+	defaultMirrorTimeout     = 10 * time.Second
+	defaultMirrorMaxBody     = 1 << 20
+	defaultMirrorConcurrency = 100
+)
+
+// Mirror 按比例将请求异步复制到影子上游，响应丢弃，不影响主请求
+// 请求体需缓冲后才能复制，超过MaxBodyBytes的请求不镜像
+func (m *Manager) Mirror(c *gin.Context, config *types.MirrorConfig, rewriter RequestRewriter) {
+	if config == nil || config.Upstream == "" || rand.Float64()*100 >= config.Percentage {
+		return
+	}
+
+	pool, exists := m.Pool(config.Upstream)
+	if !exists {
+		return
+	}
+
+	body, ok := bufferBody(c.Request, config.MaxBodyBytes)
+	if !ok {
+		return
+	}
+
+	// 并发镜像数达到上限时丢弃，避免影子流量拖垮网关
+	select {
+	case m.mirrorSem <- struct{}{}:
+	default:
+		return
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultMirrorTimeout
+	}
+
+	shadow := c.Request.Clone(context.Background())
+	go func() {
+		defer func() { <-m.mirrorSem }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		m.sendMirror(ctx, pool, shadow.WithContext(ctx), body, rewriter)
+	}()
+}
+
+// sendMirror 发送镜像请求
+func (m *Manager) sendMirror(ctx context.Context, pool *Pool, req *http.Request, body []byte, rewriter RequestRewriter) {
+	target, err := pool.Next()
+	if err != nil {
+		return
+	}
+
+	if rewriter != nil {
+		rewriter.RewriteRequest(req)
+	}
+
+	req.URL.Scheme = target.URL.Scheme
+	req.URL.Host = target.URL.Host
+	req.Host = target.URL.Host
+	req.RequestURI = ""
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("X-Shadow-Request", "true")
+
+	start := time.Now()
+	resp, err := pool.transport.RoundTrip(req)
+	if err != nil {
+		pool.Report(target, time.Since(start), true)
+		if ctx.Err() == nil {
+			log.Printf("Mirror request to %s failed: %v", target.ID, err)
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+	pool.Report(target, time.Since(start), resp.StatusCode >= 500)
+}
+
+// bufferBody 读取请求体并恢复，超过上限时不缓冲并返回false
+func bufferBody(req *http.Request, maxBytes int64) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = defaultMirrorMaxBody
+	}
+
+	buffered, err := io.ReadAll(io.LimitReader(req.Body, maxBytes+1))
+	if err != nil || int64(len(buffered)) > maxBytes {
+		// 已读出的部分与剩余请求体拼接，主请求不受影响
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(buffered), req.Body), req.Body}
+		return nil, false
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(buffered))
+	return buffered, true
+}
+
+// readCloser 组合读取器与原始请求体的关闭
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
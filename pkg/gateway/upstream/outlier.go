@@ -0,0 +1,177 @@
+package upstream
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// outlierState 实例异常检测状态
+type outlierState struct {
+	consecutiveErrors int
+	latencies         []time.Duration // 延迟样本环形缓冲
+	next              int
+	ejectedUntil      time.Time
+	lastEjection      time.Time
+	ejections         int // 累计驱逐次数，驱逐时长按次数倍增
+}
+
+// newOutlierState 创建实例异常检测状态
+func newOutlierState(windowSize int) *outlierState {
+	return &outlierState{
+		latencies: make([]time.Duration, 0, windowSize),
+	}
+}
+
+// ejected 判断实例当前是否处于驱逐期
+func (st *outlierState) ejected(now time.Time) bool {
+	return now.Before(st.ejectedUntil)
+}
+
+// record 记录一次延迟样本
+func (st *outlierState) record(latency time.Duration) {
+	if len(st.latencies) < cap(st.latencies) {
+		st.latencies = append(st.latencies, latency)
+		return
+	}
+	if len(st.latencies) == 0 {
+		return
+	}
+	st.latencies[st.next] = latency
+	st.next = (st.next + 1) % len(st.latencies)
+}
+
+// percentile 计算窗口内的延迟分位数
+func (st *outlierState) percentile(p float64) time.Duration {
+	if len(st.latencies) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(st.latencies))
+	copy(sorted, st.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// reset 驱逐结束后清空样本，避免旧样本导致再次驱逐
+func (st *outlierState) reset() {
+	st.consecutiveErrors = 0
+	st.latencies = st.latencies[:0]
+	st.next = 0
+}
+
+// withOutlierDefaults 填充异常驱逐默认配置
+func withOutlierDefaults(config types.OutlierDetectionConfig) types.OutlierDetectionConfig {
+	if config.ConsecutiveErrors <= 0 {
+		config.ConsecutiveErrors = 5
+	}
+	if config.LatencyPercentile <= 0 || config.LatencyPercentile > 1 {
+		config.LatencyPercentile = 0.99
+	}
+	if config.WindowSize <= 0 {
+		config.WindowSize = 100
+	}
+	if config.MinSamples <= 0 || config.MinSamples > config.WindowSize {
+		config.MinSamples = config.WindowSize / 5
+	}
+	if config.BaseEjectionTime <= 0 {
+		config.BaseEjectionTime = 30 * time.Second
+	}
+	if config.MaxEjectionTime < config.BaseEjectionTime {
+		config.MaxEjectionTime = 10 * config.BaseEjectionTime
+	}
+	if config.MaxEjectionPercent <= 0 || config.MaxEjectionPercent > 100 {
+		config.MaxEjectionPercent = 10
+	}
+	return config
+}
+
+// observeOutlier 记录请求结果，连续失败达到阈值时驱逐实例（需要加锁调用）
+func (p *Pool) observeOutlier(target *Target, latency time.Duration, failed bool) {
+	if !p.outlier.Enabled {
+		return
+	}
+
+	state := target.outlier
+	if failed {
+		state.consecutiveErrors++
+	} else {
+		state.consecutiveErrors = 0
+		state.record(latency)
+	}
+
+	if state.consecutiveErrors >= p.outlier.ConsecutiveErrors {
+		p.eject(target, "consecutive errors")
+	}
+}
+
+// detectLatencyOutliers 按延迟分位数驱逐慢实例，并恢复驱逐到期的实例（需要加锁调用）
+func (p *Pool) detectLatencyOutliers(now time.Time) {
+	if !p.outlier.Enabled {
+		return
+	}
+
+	for _, target := range p.targets {
+		state := target.outlier
+		if !state.ejectedUntil.IsZero() && !state.ejected(now) {
+			state.ejectedUntil = time.Time{}
+			state.reset()
+			log.Printf("Upstream target %s returned from ejection", target.ID)
+		}
+
+		// 长时间未再被驱逐时重置倍增次数
+		if state.ejections > 0 && now.Sub(state.lastEjection) > p.outlier.MaxEjectionTime*2 {
+			state.ejections = 0
+		}
+
+		if p.outlier.LatencyThreshold <= 0 || state.ejected(now) || len(state.latencies) < p.outlier.MinSamples {
+			continue
+		}
+
+		if latency := state.percentile(p.outlier.LatencyPercentile); latency > p.outlier.LatencyThreshold {
+			p.eject(target, "latency percentile "+latency.String())
+		}
+	}
+}
+
+// eject 驱逐实例，超过最大驱逐比例时放弃（需要加锁调用）
+func (p *Pool) eject(target *Target, reason string) {
+	now := time.Now()
+	if target.outlier.ejected(now) {
+		return
+	}
+
+	ejected := 0
+	for _, t := range p.targets {
+		if t.outlier.ejected(now) {
+			ejected++
+		}
+	}
+
+	maxEjected := len(p.targets) * p.outlier.MaxEjectionPercent / 100
+	if maxEjected < 1 {
+		maxEjected = 1
+	}
+	// 始终保留至少一个可用实例
+	if ejected >= maxEjected || ejected+1 >= len(p.targets) {
+		log.Printf("Skipping ejection of upstream target %s (%s): max ejection reached", target.ID, reason)
+		target.outlier.consecutiveErrors = 0
+		return
+	}
+
+	state := target.outlier
+	state.ejections++
+	duration := p.outlier.BaseEjectionTime * time.Duration(state.ejections)
+	if duration > p.outlier.MaxEjectionTime {
+		duration = p.outlier.MaxEjectionTime
+	}
+	state.ejectedUntil = now.Add(duration)
+	state.lastEjection = now
+	state.consecutiveErrors = 0
+
+	log.Printf("Ejected upstream target %s for %v: %s", target.ID, duration, reason)
+}
@@ -0,0 +1,299 @@
+package upstream
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/gateway/tlsconf"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// Target 上游实例
+type Target struct {
+	ID     string
+	URL    *url.URL
+	Weight int
+
+	health  *targetHealth
+	outlier *outlierState
+	current int // 平滑加权轮询的当前权重
+}
+
+// TargetStatus 上游实例状态
+type TargetStatus struct {
+	ID              string             `json:"id"`
+	URL             string             `json:"url"`
+	Weight          int                `json:"weight"`
+	EffectiveWeight int                `json:"effective_weight"`
+	LatencyEWMA     float64            `json:"latency_ewma_ms"`
+	ErrorEWMA       float64            `json:"error_ewma"`
+	Degraded        bool               `json:"degraded"`
+	Ejected         bool               `json:"ejected"`
+	BreakerState    types.BreakerState `json:"breaker_state"`
+}
+
+// Pool 上游实例池
+type Pool struct {
+	name       string
+	targets    []*Target
+	config     types.HealthWeightConfig
+	outlier    types.OutlierDetectionConfig
+	breaker    interfaces.CircuitBreaker
+	transport  http.RoundTripper
+	clientTLS  *tlsconf.ClientTLS
+	dnsRefresh time.Duration
+	resolver   *dnsResolver
+	discovery  *types.UpstreamDiscoveryConfig
+	lastUpdate time.Time
+	mutex      sync.Mutex
+}
+
+// NewPool 创建上游实例池
+func NewPool(config *types.UpstreamConfig, breaker interfaces.CircuitBreaker) (*Pool, error) {
+	if len(config.Targets) == 0 && config.Discovery == nil {
+		return nil, fmt.Errorf("upstream %s has no targets", config.Name)
+	}
+
+	var resolver *dnsResolver
+	if config.Transport.DNS.Enabled {
+		resolver = newDNSResolver(&config.Transport.DNS)
+	}
+
+	transport, clientTLS, err := newTransport(config, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &Pool{
+		name:       config.Name,
+		config:     withHealthDefaults(config.Health),
+		outlier:    withOutlierDefaults(config.Outlier),
+		breaker:    breaker,
+		transport:  transport,
+		clientTLS:  clientTLS,
+		dnsRefresh: config.Transport.DNSRefreshInterval,
+		resolver:   resolver,
+		discovery:  config.Discovery,
+	}
+
+	for _, targetConfig := range config.Targets {
+		target, err := pool.newTarget(targetConfig)
+		if err != nil {
+			return nil, err
+		}
+		pool.targets = append(pool.targets, target)
+	}
+
+	return pool, nil
+}
+
+// newTarget 创建上游实例并注册实例熔断器
+func (p *Pool) newTarget(config types.UpstreamTargetConfig) (*Target, error) {
+	targetURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target url %s for upstream %s: %v", config.URL, p.name, err)
+	}
+
+	weight := config.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	target := &Target{
+		ID:      fmt.Sprintf("upstream:%s/%s", p.name, targetURL.Host),
+		URL:     targetURL,
+		Weight:  weight,
+		health:  newTargetHealth(),
+		outlier: newOutlierState(p.outlier.WindowSize),
+	}
+
+	// 为每个实例注册熔断器，熔断状态作为健康信号参与加权
+	if p.breaker != nil {
+		if err := p.breaker.UpdatePolicy(target.ID, &types.Policy{
+			ClusterID:  target.ID,
+			PolicyType: types.PolicyTypeCircuitBreak,
+			CircuitBreak: &types.CircuitBreakPolicy{
+				BreakDuration: p.config.BreakDuration,
+				RecoveryStep:  defaultBreakerRecoveryStep,
+			},
+			CreateTime: time.Now(),
+			IsActive:   true,
+		}); err != nil {
+			log.Printf("Failed to register breaker for target %s: %v", target.ID, err)
+		}
+	}
+
+	return target, nil
+}
+
+// SetTargets 替换实例列表，已存在的实例保留健康信号和轮询状态
+func (p *Pool) SetTargets(configs []types.UpstreamTargetConfig) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	existing := make(map[string]*Target, len(p.targets))
+	for _, target := range p.targets {
+		existing[target.ID] = target
+	}
+
+	targets := make([]*Target, 0, len(configs))
+	seen := make(map[string]bool, len(configs))
+	added := 0
+	for _, config := range configs {
+		targetURL, err := url.Parse(config.URL)
+		if err != nil {
+			log.Printf("Skipping discovered target %s for upstream %s: %v", config.URL, p.name, err)
+			continue
+		}
+
+		id := fmt.Sprintf("upstream:%s/%s", p.name, targetURL.Host)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if target, ok := existing[id]; ok {
+			if config.Weight > 0 {
+				target.Weight = config.Weight
+			}
+			targets = append(targets, target)
+			continue
+		}
+
+		target, err := p.newTarget(config)
+		if err != nil {
+			log.Printf("Skipping discovered target for upstream %s: %v", p.name, err)
+			continue
+		}
+		targets = append(targets, target)
+		added++
+	}
+
+	p.targets = targets
+
+	log.Printf("Updated targets for upstream %s: %d total, %d added, %d removed",
+		p.name, len(targets), added, len(existing)+added-len(targets))
+}
+
+// Name 获取上游名称
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Transport 获取上游共享的传输层，转发到该上游的功能应复用此连接池
+func (p *Pool) Transport() http.RoundTripper {
+	return p.transport
+}
+
+// Next 按有效权重选择一个实例（平滑加权轮询）
+func (p *Pool) Next() (*Target, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if time.Since(p.lastUpdate) >= p.config.UpdateInterval {
+		p.recalculate()
+		p.detectLatencyOutliers(time.Now())
+		p.lastUpdate = time.Now()
+	}
+
+	var best *Target
+	total := 0
+
+	for _, target := range p.targets {
+		weight := p.effectiveWeight(target)
+		if weight <= 0 {
+			continue
+		}
+
+		target.current += weight
+		total += weight
+
+		if best == nil || target.current > best.current {
+			best = target
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no available target in upstream %s", p.name)
+	}
+
+	best.current -= total
+	return best, nil
+}
+
+// Report 上报一次请求结果
+func (p *Pool) Report(target *Target, latency time.Duration, failed bool) {
+	p.mutex.Lock()
+	target.health.observe(latency, failed, p.config.LatencyAlpha)
+	p.observeOutlier(target, latency, failed)
+	p.mutex.Unlock()
+
+	if p.breaker == nil {
+		return
+	}
+
+	if failed {
+		p.breaker.RecordFailure(target.ID)
+	} else {
+		p.breaker.RecordSuccess(target.ID)
+	}
+}
+
+// Status 获取所有实例状态
+func (p *Pool) Status() []TargetStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	statuses := make([]TargetStatus, 0, len(p.targets))
+	for _, target := range p.targets {
+		statuses = append(statuses, TargetStatus{
+			ID:              target.ID,
+			URL:             target.URL.String(),
+			Weight:          target.Weight,
+			EffectiveWeight: p.effectiveWeight(target),
+			LatencyEWMA:     target.health.latencyEWMA * 1000,
+			ErrorEWMA:       target.health.errorEWMA,
+			Degraded:        target.health.degraded,
+			Ejected:         target.outlier.ejected(now),
+			BreakerState:    p.breakerState(target),
+		})
+	}
+
+	return statuses
+}
+
+// effectiveWeight 计算实例的有效权重（需要加锁调用）
+func (p *Pool) effectiveWeight(target *Target) int {
+	if target.outlier.ejected(time.Now()) {
+		return 0
+	}
+
+	if !p.config.Enabled {
+		return target.Weight * weightScale
+	}
+
+	if p.breakerState(target) == types.BreakerStateOpen {
+		return 0
+	}
+
+	weight := int(float64(target.Weight*weightScale) * target.health.factor)
+	if weight < 1 {
+		weight = 1
+	}
+
+	return weight
+}
+
+// breakerState 获取实例熔断状态
+func (p *Pool) breakerState(target *Target) types.BreakerState {
+	if p.breaker == nil {
+		return types.BreakerStateClosed
+	}
+	return p.breaker.GetState(target.ID)
+}
@@ -0,0 +1,293 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/compression"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// RequestRewriter 转发前改写出站请求
+type RequestRewriter interface {
+	RewriteRequest(out *http.Request)
+}
+
+// ResponseTransformer 返回客户端前转换上游响应，rewriter实现该接口时生效
+type ResponseTransformer interface {
+	TransformResponse(resp *http.Response) error
+}
+
+// ResponseValidator 校验上游响应，rewriter实现该接口时生效；校验失败的响应照常返回，
+// 错误计入请求错误并标记信号类型，由错误采样单独聚类
+type ResponseValidator interface {
+	ValidateResponse(resp *http.Response) error
+}
+
+// ResponseClassifier 识别成功状态响应体中的上游错误，rewriter实现该接口时生效；
+// 命中的响应照常返回，计为上游失败并以响应体中的错误消息进入错误采样
+type ResponseClassifier interface {
+	ClassifyResponse(resp *http.Response) error
+}
+
+// ResponseInspector 声明是否读取响应体，rewriter实现该接口且返回true时压缩的响应体先解压再改写校验
+type ResponseInspector interface {
+	InspectsResponse() bool
+}
+
+// Forward 将请求转发到指定上游，rewriter可为nil
+func (m *Manager) Forward(c *gin.Context, upstreamName string, rewriter RequestRewriter) {
+	pool, exists := m.Pool(upstreamName)
+	if !exists {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("Upstream not found: %s", upstreamName),
+			"code":  "UPSTREAM_NOT_FOUND",
+		})
+		return
+	}
+
+	target, err := pool.Next()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": err.Error(),
+			"code":  "NO_HEALTHY_UPSTREAM",
+		})
+		return
+	}
+
+	grpc := isGRPCRequest(c.Request)
+	inspector, ok := rewriter.(ResponseInspector)
+	decompress := m.compression != nil && !grpc && ok && inspector.InspectsResponse()
+
+	// 延迟取首包时间，失败与否在流结束后（gRPC需读取trailers）判定
+	start := time.Now()
+	var firstByte time.Duration
+	var stream *streamObserver
+	failed := false
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if rewriter != nil {
+				rewriter.RewriteRequest(pr.Out)
+			}
+			host := pr.Out.Host
+			pr.SetURL(target.URL)
+			if host != pr.In.Host {
+				// 改写规则指定了Host时保留，SetURL默认使用上游地址
+				pr.Out.Host = host
+			}
+			forwardClientIP(c, pr)
+			propagateDeadline(pr.Out, grpc)
+			if accept := pr.Out.Header.Get("Accept-Encoding"); decompress && accept != "" {
+				// 未声明时由Transport透明解压gzip，声明时只保留可解压的编码
+				pr.Out.Header.Set("Accept-Encoding", compression.FilterAcceptEncoding(accept))
+			}
+		},
+		Transport: pool.transport,
+		ModifyResponse: func(resp *http.Response) error {
+			firstByte = time.Since(start)
+			failed = resp.StatusCode >= 500
+			decoded := decompress && !isEventStream(resp) && m.compression.DecodeResponse(resp)
+			// 在改写之前识别，改写规则可能移除错误字段
+			if classifier, ok := rewriter.(ResponseClassifier); ok && !isEventStream(resp) {
+				if err := classifier.ClassifyResponse(resp); err != nil {
+					failed = true
+					c.Set("upstream_failed", true)
+					c.Set("signal_type", types.SignalResponseError)
+					utils.RecordStageError(c, "upstream", err)
+				}
+			}
+			if transformer, ok := rewriter.(ResponseTransformer); ok {
+				if err := transformer.TransformResponse(resp); err != nil {
+					return err
+				}
+			}
+			if validator, ok := rewriter.(ResponseValidator); ok {
+				if err := validator.ValidateResponse(resp); err != nil {
+					c.Set("signal_type", types.SignalSchemaResponse)
+					utils.RecordStageError(c, "schema", err)
+				}
+			}
+			if decoded {
+				if err := m.compression.EncodeResponse(resp, c.GetHeader("Accept-Encoding")); err != nil {
+					return err
+				}
+			}
+			if isEventStream(resp) {
+				stream = newStreamObserver(resp.Body)
+				resp.Body = stream
+				prepareStreamHeaders(resp.Header)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			firstByte = time.Since(start)
+
+			// 流式请求体读取时超限属于客户端错误，不计入上游失败
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit),
+					"code":  "BODY_TOO_LARGE",
+				})
+				return
+			}
+
+			// 客户端已断开，上游调用随请求context取消，转发结束后按取消处理
+			if errors.Is(err, context.Canceled) && clientGone(c) {
+				return
+			}
+
+			failed = true
+			// 看门狗取消的卡住请求同样按上游超时响应
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(context.Cause(r.Context()), ErrStuckRequest) {
+				log.Printf("Upstream %s exceeded request deadline", target.ID)
+				utils.RecordStageError(c, "upstream", err)
+				if grpc {
+					w.Header().Set("Content-Type", "application/grpc")
+					w.Header().Set("Grpc-Status", strconv.Itoa(utils.GRPCStatusDeadlineExceeded))
+					w.Header().Set("Grpc-Message", "request deadline exceeded")
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				c.JSON(http.StatusGatewayTimeout, gin.H{
+					"error": "Upstream request deadline exceeded",
+					"code":  "UPSTREAM_TIMEOUT",
+				})
+				return
+			}
+
+			log.Printf("Failed to proxy request to %s: %v", target.ID, err)
+			utils.RecordStageError(c, "upstream", err)
+			if grpc {
+				// gRPC客户端依赖grpc-status而非HTTP状态码
+				w.Header().Set("Content-Type", "application/grpc")
+				w.Header().Set("Grpc-Status", strconv.Itoa(utils.GRPCStatusUnavailable))
+				w.Header().Set("Grpc-Message", "upstream request failed")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": "Upstream request failed",
+				"code":  "UPSTREAM_ERROR",
+			})
+		},
+	}
+
+	if grpc || c.GetHeader("Accept") == "text/event-stream" {
+		// gRPC与SSE流式响应需要立即刷新，不做缓冲
+		proxy.FlushInterval = -1
+	}
+
+	c.Set("upstream_target", target.ID)
+	aborted := serveProxy(proxy, c)
+
+	// 客户端断开时请求context被取消，上游请求随之中止；
+	// 取消单独记录，不计入实例和簇的失败
+	if clientGone(c) {
+		stage := CancelStageStreaming
+		if !c.Writer.Written() {
+			stage = CancelStageAwaiting
+		}
+		c.Set("client_canceled", stage)
+		if stream != nil {
+			c.Set("stream_outcome", StreamOutcomeClientCanceled)
+			c.Set("stream_events", stream.events)
+		}
+		log.Printf("Client canceled request to %s while %s, upstream call aborted", target.ID, stage)
+		c.Abort()
+		return
+	}
+
+	// SSE流读取上游失败时已转发的事件无法撤回，不中止处理，
+	// 由下方按upstream_error结果记录指标和采样
+	if aborted && (stream == nil || stream.readErr == nil) {
+		panic(http.ErrAbortHandler)
+	}
+
+	if grpc {
+		code, message := grpcStatus(c.Writer.Header())
+		c.Set("grpc_status", code)
+		if utils.IsGRPCFailure(code) && !failed {
+			failed = true
+			c.Set("upstream_failed", true)
+			utils.RecordStageError(c, "upstream", fmt.Errorf("grpc status %d: %s", code, message))
+		}
+	}
+
+	if stream != nil {
+		// 流式响应在状态码发出后才能确定结果，以最终结果记录指标和采样
+		outcome, detail := stream.outcome()
+		c.Set("stream_outcome", outcome)
+		c.Set("stream_events", stream.events)
+		if (outcome == StreamOutcomeUpstreamError || outcome == StreamOutcomeErrorEvent) && !failed {
+			failed = true
+			c.Set("upstream_failed", true)
+			utils.RecordStageError(c, "upstream", fmt.Errorf("stream %s: %s", outcome, utils.Truncate(detail, 512)))
+		}
+	}
+
+	pool.Report(target, firstByte, failed)
+}
+
+// serveProxy 执行反向代理，响应复制中断时反向代理以http.ErrAbortHandler中止处理，
+// 此处接管以便区分客户端取消和上游错误
+func serveProxy(proxy *httputil.ReverseProxy, c *gin.Context) (aborted bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != http.ErrAbortHandler {
+				panic(r)
+			}
+			aborted = true
+		}
+	}()
+
+	proxy.ServeHTTP(c.Writer, c.Request)
+	return false
+}
+
+// ErrStuckRequest 看门狗取消卡住请求时的取消原因，用于与客户端断开区分
+var ErrStuckRequest = errors.New("request canceled by watchdog")
+
+// clientGone 判断客户端是否已断开（请求context被取消而非超时，也不是被看门狗取消）
+func clientGone(c *gin.Context) bool {
+	ctx := c.Request.Context()
+	return errors.Is(ctx.Err(), context.Canceled) && !errors.Is(context.Cause(ctx), ErrStuckRequest)
+}
+
+// grpcStatus 从响应头或trailers中读取gRPC状态
+func grpcStatus(header http.Header) (int, string) {
+	for _, prefix := range []string{"", http.TrailerPrefix} {
+		if value := header.Get(prefix + "Grpc-Status"); value != "" {
+			code, err := strconv.Atoi(value)
+			if err != nil {
+				return utils.GRPCStatusUnknown, value
+			}
+			return code, header.Get(prefix + "Grpc-Message")
+		}
+	}
+
+	return utils.GRPCStatusOK, ""
+}
+
+// forwardClientIP 设置转发头：对端为可信代理时保留其X-Forwarded-For链，
+// 否则丢弃客户端自带的值防止伪造；X-Real-IP为解析出的真实客户端IP
+func forwardClientIP(c *gin.Context, pr *httputil.ProxyRequest) {
+	clientIP := c.ClientIP()
+	if clientIP != c.RemoteIP() {
+		if prior := pr.In.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			pr.Out.Header["X-Forwarded-For"] = append([]string(nil), prior...)
+		}
+	}
+	pr.SetXForwarded()
+	pr.Out.Header.Set("X-Real-IP", utils.NormalizeIP(clientIP))
+}
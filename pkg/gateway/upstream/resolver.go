@@ -0,0 +1,415 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 解析器默认值
+const (
+	defaultDNSMinTTL   = 5 * time.Second
+	defaultDNSMaxTTL   = 5 * time.Minute
+	dnsQueryTimeout    = 3 * time.Second
+	dnsRefreshLead     = 0.2 // 记录剩余TTL低于该比例时提前刷新
+	dnsRefreshTick     = time.Second
+	resolvConfPath     = "/etc/resolv.conf"
+	defaultNameserver  = "127.0.0.1:53"
+	maxDNSMessageBytes = 4096
+)
+
+// dnsRecord 域名解析结果
+type dnsRecord struct {
+	addrs    []string
+	ttl      time.Duration
+	expires  time.Time
+	next     uint32 // 轮询起点，分散到不同地址
+	resolved bool
+}
+
+// dnsResolver 上游域名解析器，按记录TTL异步刷新，地址变化时通知连接池重新建连
+type dnsResolver struct {
+	nameservers []string
+	minTTL      time.Duration
+	maxTTL      time.Duration
+	records     map[string]*dnsRecord
+	mutex       sync.Mutex
+}
+
+// newDNSResolver 创建域名解析器
+func newDNSResolver(config *types.UpstreamDNSConfig) *dnsResolver {
+	nameservers := append([]string(nil), config.Nameservers...)
+	if len(nameservers) == 0 {
+		nameservers = systemNameservers()
+	}
+	for i, server := range nameservers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			// IPv6名称服务器可带方括号，如"[2001:db8::53]"
+			nameservers[i] = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+	}
+
+	minTTL := config.MinTTL
+	if minTTL <= 0 {
+		minTTL = defaultDNSMinTTL
+	}
+	maxTTL := config.MaxTTL
+	if maxTTL < minTTL {
+		maxTTL = defaultDNSMaxTTL
+	}
+
+	return &dnsResolver{
+		nameservers: nameservers,
+		minTTL:      minTTL,
+		maxTTL:      maxTTL,
+		records:     make(map[string]*dnsRecord),
+	}
+}
+
+// dialContext 包装拨号器，域名使用缓存的解析结果，按拨号网络筛选地址族后依次尝试各地址；
+// 双栈时IPv4和IPv6地址交替尝试，避免某一地址族整体不可达时逐个超时
+func (r *dnsResolver) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		if _, err := netip.ParseAddr(host); err == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := r.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		addrs = interleaveFamilies(addrs, network)
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no %s addresses for host %s", network, host)
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// interleaveFamilies 按拨号网络筛选地址，"tcp"时从首个地址的地址族开始交替排列两种地址族
+func interleaveFamilies(addrs []string, network string) []string {
+	var v4, v6 []string
+	for _, value := range addrs {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			continue
+		}
+		if addr.Unmap().Is4() {
+			v4 = append(v4, value)
+		} else {
+			v6 = append(v6, value)
+		}
+	}
+
+	switch network {
+	case "tcp4":
+		return v4
+	case "tcp6":
+		return v6
+	}
+
+	first, second := v4, v6
+	if len(v6) > 0 && len(addrs) > 0 && addrs[0] == v6[0] {
+		first, second = v6, v4
+	}
+	ordered := make([]string, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+	return ordered
+}
+
+// lookup 获取域名地址，返回的列表按轮询起点旋转
+func (r *dnsResolver) lookup(ctx context.Context, host string) ([]string, error) {
+	r.mutex.Lock()
+	record, exists := r.records[host]
+	r.mutex.Unlock()
+
+	if !exists || !record.resolved {
+		if _, err := r.refresh(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record = r.records[host]
+	if len(record.addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for host %s", host)
+	}
+
+	start := int(record.next % uint32(len(record.addrs)))
+	record.next++
+
+	rotated := make([]string, 0, len(record.addrs))
+	rotated = append(rotated, record.addrs[start:]...)
+	rotated = append(rotated, record.addrs[:start]...)
+	return rotated, nil
+}
+
+// refresh 重新解析域名，返回地址是否变化；解析失败时保留旧记录
+func (r *dnsResolver) refresh(ctx context.Context, host string) (bool, error) {
+	addrs, ttl, err := r.resolve(ctx, host)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, exists := r.records[host]
+	if !exists {
+		record = &dnsRecord{}
+		r.records[host] = record
+	}
+
+	if err != nil {
+		// 解析失败时按最小TTL重试，继续使用旧地址
+		record.expires = time.Now().Add(r.minTTL)
+		if record.resolved {
+			log.Printf("Failed to refresh upstream host %s, keeping %d cached addresses: %v", host, len(record.addrs), err)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to resolve upstream host %s: %v", host, err)
+	}
+
+	if ttl < r.minTTL {
+		ttl = r.minTTL
+	}
+	if ttl > r.maxTTL {
+		ttl = r.maxTTL
+	}
+
+	changed := record.resolved && strings.Join(record.addrs, ",") != strings.Join(addrs, ",")
+	record.addrs = addrs
+	record.ttl = ttl
+	record.expires = time.Now().Add(ttl)
+	record.resolved = true
+
+	return changed, nil
+}
+
+// watch 在记录过期前异步刷新，地址变化时调用onChange
+func (r *dnsResolver) watch(stopCh <-chan struct{}, onChange func(host string)) {
+	ticker := time.NewTicker(dnsRefreshTick)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, host := range r.dueHosts() {
+					ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+					changed, err := r.refresh(ctx, host)
+					cancel()
+					if err != nil {
+						log.Printf("%v", err)
+						continue
+					}
+					if changed {
+						log.Printf("Upstream host %s resolved to new addresses", host)
+						onChange(host)
+					}
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// dueHosts 获取即将过期需要刷新的域名
+func (r *dnsResolver) dueHosts() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	var hosts []string
+	for host, record := range r.records {
+		lead := time.Duration(float64(record.ttl) * dnsRefreshLead)
+		if now.Add(lead).After(record.expires) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// resolve 查询A和AAAA记录，返回排序后的地址和最小TTL
+func (r *dnsResolver) resolve(ctx context.Context, host string) ([]string, time.Duration, error) {
+	var addrs []string
+	var minTTL uint32
+	var lastErr error
+
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, ttl, err := r.query(ctx, host, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(answers) > 0 && (minTTL == 0 || ttl < minTTL) {
+			minTTL = ttl
+		}
+		addrs = append(addrs, answers...)
+	}
+
+	if len(addrs) == 0 {
+		// 依赖search域的短域名等情况回退到系统解析器，TTL未知时按最小TTL刷新
+		fallback, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			if lastErr == nil {
+				lastErr = err
+			}
+			return nil, 0, lastErr
+		}
+		sort.Strings(fallback)
+		return fallback, r.minTTL, nil
+	}
+
+	sort.Strings(addrs)
+	return addrs, time.Duration(minTTL) * time.Second, nil
+}
+
+// query 向名称服务器发送一次查询，依次尝试各服务器
+func (r *dnsResolver) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]string, uint32, error) {
+	name, err := dnsmessage.NewName(fqdn(host))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid host name %s: %v", host, err)
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	request, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to pack dns query: %v", err)
+	}
+
+	var lastErr error
+	for _, server := range r.nameservers {
+		answers, ttl, err := exchange(ctx, server, request, id)
+		if err == nil {
+			return answers, ttl, nil
+		}
+		lastErr = err
+	}
+	return nil, 0, lastErr
+}
+
+// exchange 通过UDP完成一次DNS查询，返回应答中的地址和最小TTL
+func exchange(ctx context.Context, server string, request []byte, id uint16) ([]string, uint32, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to dial nameserver %s: %v", server, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(request); err != nil {
+		return nil, 0, fmt.Errorf("failed to send dns query to %s: %v", server, err)
+	}
+
+	buf := make([]byte, maxDNSMessageBytes)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read dns response from %s: %v", server, err)
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf[:n]); err != nil {
+		return nil, 0, fmt.Errorf("invalid dns response from %s: %v", server, err)
+	}
+	if response.Header.ID != id {
+		return nil, 0, fmt.Errorf("mismatched dns response id from %s", server)
+	}
+	if response.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("dns query to %s failed: %s", server, response.Header.RCode)
+	}
+
+	var addrs []string
+	var minTTL uint32
+	for _, answer := range response.Answers {
+		var ip net.IP
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			ip = net.IP(body.AAAA[:])
+		default:
+			continue // CNAME等记录的地址已包含在应答中
+		}
+
+		addrs = append(addrs, ip.String())
+		if minTTL == 0 || answer.Header.TTL < minTTL {
+			minTTL = answer.Header.TTL
+		}
+	}
+
+	return addrs, minTTL, nil
+}
+
+// systemNameservers 读取系统名称服务器配置
+func systemNameservers() []string {
+	file, err := os.Open(resolvConfPath)
+	if err != nil {
+		return []string{defaultNameserver}
+	}
+	defer file.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+
+	if len(servers) == 0 {
+		return []string{defaultNameserver}
+	}
+	return servers
+}
+
+// fqdn 补全域名末尾的点
+func fqdn(host string) string {
+	if strings.HasSuffix(host, ".") {
+		return host
+	}
+	return host + "."
+}
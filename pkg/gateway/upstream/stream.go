@@ -0,0 +1,138 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// 流式响应结果
+const (
+	StreamOutcomeCompleted      = "completed"
+	StreamOutcomeErrorEvent     = "error_event"
+	StreamOutcomeUpstreamError  = "upstream_error"
+	StreamOutcomeClientCanceled = "client_canceled"
+)
+
+// 客户端取消发生的阶段
+const (
+	CancelStageAwaiting  = "awaiting_response" // 上游尚未返回响应头
+	CancelStageStreaming = "streaming"         // 响应传输过程中
+)
+
+// maxPendingLine 未结束行的最大缓冲长度，超出部分丢弃
+const maxPendingLine = 64 * 1024
+
+// streamObserver SSE流观察器，透传数据的同时解析事件以判定最终结果
+type streamObserver struct {
+	body       io.ReadCloser
+	pending    []byte
+	hasData    bool
+	events     int64
+	bytes      int64
+	errorEvent string
+	readErr    error
+}
+
+// newStreamObserver 创建SSE流观察器
+func newStreamObserver(body io.ReadCloser) *streamObserver {
+	return &streamObserver{
+		body: body,
+	}
+}
+
+// Read 读取上游数据
+func (so *streamObserver) Read(p []byte) (int, error) {
+	n, err := so.body.Read(p)
+	if n > 0 {
+		so.bytes += int64(n)
+		so.scan(p[:n])
+	}
+	if err != nil && err != io.EOF {
+		so.readErr = err
+	}
+	return n, err
+}
+
+// Close 关闭上游响应体
+func (so *streamObserver) Close() error {
+	return so.body.Close()
+}
+
+// scan 按行解析SSE数据
+func (so *streamObserver) scan(chunk []byte) {
+	for len(chunk) > 0 {
+		idx := bytes.IndexByte(chunk, '\n')
+		if idx < 0 {
+			if len(so.pending)+len(chunk) <= maxPendingLine {
+				so.pending = append(so.pending, chunk...)
+			}
+			return
+		}
+
+		line := chunk[:idx]
+		if len(so.pending) > 0 {
+			line = append(so.pending, line...)
+			so.pending = so.pending[:0]
+		}
+		so.handleLine(bytes.TrimRight(line, "\r"))
+		chunk = chunk[idx+1:]
+	}
+}
+
+// handleLine 处理单行SSE数据
+func (so *streamObserver) handleLine(line []byte) {
+	switch {
+	case len(line) == 0:
+		// 空行表示一个事件结束
+		if so.hasData {
+			so.events++
+			so.hasData = false
+		}
+
+	case bytes.HasPrefix(line, []byte("event:")):
+		if string(bytes.TrimSpace(line[len("event:"):])) == "error" {
+			so.errorEvent = "error event"
+		}
+
+	case bytes.HasPrefix(line, []byte("data:")):
+		so.hasData = true
+		data := bytes.TrimSpace(line[len("data:"):])
+		// OpenAI兼容接口在流中以 {"error": ...} 形式返回错误
+		if bytes.HasPrefix(data, []byte(`{"error"`)) {
+			so.errorEvent = string(data)
+		}
+	}
+}
+
+// outcome 判定流的最终结果
+func (so *streamObserver) outcome() (string, string) {
+	if so.readErr != nil {
+		if errors.Is(so.readErr, context.Canceled) {
+			return StreamOutcomeClientCanceled, so.readErr.Error()
+		}
+		return StreamOutcomeUpstreamError, so.readErr.Error()
+	}
+
+	if so.errorEvent != "" {
+		return StreamOutcomeErrorEvent, so.errorEvent
+	}
+
+	return StreamOutcomeCompleted, ""
+}
+
+// isEventStream 判断响应是否为SSE流
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// prepareStreamHeaders 设置流式响应头，禁止中间层缓冲
+func prepareStreamHeaders(header http.Header) {
+	header.Del("Content-Length")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+}
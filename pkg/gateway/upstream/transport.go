@@ -0,0 +1,222 @@
+package upstream
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/llm-aware-gateway/pkg/gateway/tlsconf"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 上游协议
+const (
+	ProtocolHTTP = "http"
+	ProtocolH2   = "h2"
+	ProtocolH2C  = "h2c"
+	ProtocolGRPC = "grpc"
+)
+
+// newTransport 根据上游协议创建传输层，配置了mTLS时同时返回客户端TLS配置
+// resolver不为nil时域名由内置解析器解析
+func newTransport(config *types.UpstreamConfig, resolver *dnsResolver) (http.RoundTripper, *tlsconf.ClientTLS, error) {
+	var clientTLS *tlsconf.ClientTLS
+	var tlsConfig *tls.Config
+
+	if hasClientTLS(&config.TLS) {
+		ct, err := tlsconf.NewClientTLS(&config.TLS)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load tls config for upstream %s: %v", config.Name, err)
+		}
+
+		tlsConfig, err = ct.TLSConfig()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid tls config for upstream %s: %v", config.Name, err)
+		}
+		clientTLS = ct
+	}
+
+	pooling := withTransportDefaults(config.Transport)
+	family, err := dialNetwork(pooling.IPFamily)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid transport config for upstream %s: %v", config.Name, err)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   pooling.DialTimeout,
+		KeepAlive: pooling.KeepAlive,
+	}
+	dial := dialer.DialContext
+	if resolver != nil {
+		dial = resolver.dialContext(dialer)
+	}
+	if family != "tcp" {
+		inner := dial
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return inner(ctx, family, addr)
+		}
+	}
+
+	if isTLSUpstream(config) {
+		tlsConfig = withSessionCache(tlsConfig, pooling.TLSSessionCacheSize)
+	}
+
+	switch strings.ToLower(config.Protocol) {
+	case ProtocolH2:
+		return newH2Transport(pooling, dial, tlsConfig), clientTLS, nil
+
+	case ProtocolH2C, ProtocolGRPC:
+		// gRPC目标使用https时走TLS的HTTP/2，否则使用明文h2c
+		if isTLSUpstream(config) {
+			return newH2Transport(pooling, dial, tlsConfig), clientTLS, nil
+		}
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+		applyH2Settings(h2c, pooling)
+		return h2c, clientTLS, nil
+
+	default:
+		transport := &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dial,
+			MaxIdleConns:          pooling.MaxIdleConns,
+			MaxIdleConnsPerHost:   pooling.MaxIdleConnsPerHost,
+			MaxConnsPerHost:       pooling.MaxConnsPerHost,
+			IdleConnTimeout:       pooling.IdleConnTimeout,
+			TLSHandshakeTimeout:   pooling.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: pooling.ResponseHeaderTimeout,
+			ExpectContinueTimeout: time.Second,
+			TLSClientConfig:       tlsConfig,
+		}
+
+		// https上游通过ALPN协商HTTP/2，同一上游的请求复用连接
+		h2, err := http2.ConfigureTransports(transport)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to configure http2 for upstream %s: %v", config.Name, err)
+		}
+		applyH2Settings(h2, pooling)
+		return transport, clientTLS, nil
+	}
+}
+
+// dialFunc 建立TCP连接
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newH2Transport 创建基于TLS的HTTP/2传输层
+func newH2Transport(pooling types.UpstreamTransportConfig, dial dialFunc, tlsConfig *tls.Config) *http2.Transport {
+	h2 := &http2.Transport{
+		TLSClientConfig: tlsConfig,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, pooling.TLSHandshakeTimeout)
+			defer cancel()
+
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConn := tls.Client(conn, cfg)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		},
+	}
+	applyH2Settings(h2, pooling)
+	return h2
+}
+
+// applyH2Settings 应用HTTP/2连接管理参数，空闲超时由HTTP/1传输层配置或HTTP/2默认值决定
+func applyH2Settings(h2 *http2.Transport, pooling types.UpstreamTransportConfig) {
+	h2.ReadIdleTimeout = pooling.KeepAlive
+	h2.PingTimeout = pooling.PingTimeout
+	h2.StrictMaxConcurrentStreams = pooling.StrictMaxConcurrentStreams
+}
+
+// withSessionCache 为上游TLS开启会话缓存，size为负数时关闭
+func withSessionCache(tlsConfig *tls.Config, size int) *tls.Config {
+	if size < 0 {
+		return tlsConfig
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+	tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(size)
+	return tlsConfig
+}
+
+// withTransportDefaults 填充连接池默认值，与标准库默认传输层保持一致
+func withTransportDefaults(config types.UpstreamTransportConfig) types.UpstreamTransportConfig {
+	if config.MaxIdleConns <= 0 {
+		config.MaxIdleConns = 100
+	}
+	if config.MaxIdleConnsPerHost <= 0 {
+		config.MaxIdleConnsPerHost = 32
+	}
+	if config.IdleConnTimeout <= 0 {
+		config.IdleConnTimeout = 90 * time.Second
+	}
+	if config.TLSHandshakeTimeout <= 0 {
+		config.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 30 * time.Second
+	}
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = 30 * time.Second
+	}
+	if config.TLSSessionCacheSize == 0 {
+		config.TLSSessionCacheSize = 64
+	}
+	if config.PingTimeout <= 0 {
+		config.PingTimeout = 15 * time.Second
+	}
+	return config
+}
+
+// dialNetwork 地址族对应的拨号网络，为空时双栈
+func dialNetwork(family string) (string, error) {
+	switch strings.ToLower(family) {
+	case "":
+		return "tcp", nil
+	case "ipv4":
+		return "tcp4", nil
+	case "ipv6":
+		return "tcp6", nil
+	}
+	return "", fmt.Errorf("unsupported ip family %q", family)
+}
+
+// hasClientTLS 判断是否配置了上游TLS
+func hasClientTLS(config *types.UpstreamTLSConfig) bool {
+	return config.CertFile != "" || config.CAFile != "" || config.ServerName != "" || len(config.PinnedSHA256) > 0
+}
+
+// isTLSUpstream 判断上游实例是否使用TLS
+func isTLSUpstream(config *types.UpstreamConfig) bool {
+	for _, target := range config.Targets {
+		if strings.HasPrefix(target.URL, "https://") {
+			return true
+		}
+	}
+	return false
+}
+
+// isGRPCRequest 判断是否为gRPC请求
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
@@ -0,0 +1,534 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// fieldSeparator Redis哈希字段中维度取值之间的分隔符，请求头中不会出现
+const fieldSeparator = "\x1f"
+
+// 计数在Redis哈希字段中的名称
+const (
+	metricRequests         = "requests"
+	metricErrors           = "errors"
+	metricRejections       = "rejections"
+	metricPromptTokens     = "prompt_tokens"
+	metricCompletionTokens = "completion_tokens"
+	metricCost             = "cost"
+)
+
+// Subject 一次请求的用量维度取值
+type Subject struct {
+	Tenant string
+	KeyID  string // API密钥摘要
+	Model  string
+	Route  string
+}
+
+// row 报表行的分组键，未参与分组的维度为空，未按时间分组时start为0
+type row struct {
+	start int64
+	Subject
+}
+
+// Ledger 用量核算：按租户、API密钥、模型、路由分时间桶累计用量，
+// 开启持久化时定期把增量累加到Redis，报表汇总Redis中所有副本的用量和本地尚未写入的增量
+type Ledger struct {
+	resolution time.Duration
+	retention  time.Duration
+	client     redis.UniversalClient // 未开启持久化时为nil
+	key        string
+	interval   time.Duration
+	buckets    map[int64]map[Subject]*types.UsageCounters // 时间桶起点(Unix秒) -> 用量；持久化时只保存尚未写入Redis的增量
+	mutex      sync.Mutex
+	flushed    int64
+	failed     int64
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+	once       sync.Once
+}
+
+// NewLedger 创建用量核算
+func NewLedger(config *types.UsageConfig, redisConfig *types.RedisConfig) *Ledger {
+	l := &Ledger{
+		resolution: config.Resolution,
+		retention:  config.Retention,
+		buckets:    make(map[int64]map[Subject]*types.UsageCounters),
+		stopCh:     make(chan struct{}),
+	}
+	if l.resolution <= 0 {
+		l.resolution = time.Hour
+	}
+	if l.retention <= 0 {
+		l.retention = 90 * 24 * time.Hour
+	}
+
+	if config.Persistence.Enabled {
+		l.client = redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:       redisConfig.Addresses,
+			Password:    redisConfig.Password,
+			DB:          redisConfig.DB,
+			PoolSize:    redisConfig.PoolSize,
+			DialTimeout: redisConfig.Timeout,
+		})
+		l.key = config.Persistence.Key
+		if l.key == "" {
+			l.key = "gateway:usage"
+		}
+		l.interval = config.Persistence.Interval
+		if l.interval <= 0 {
+			l.interval = 30 * time.Second
+		}
+	}
+
+	return l
+}
+
+// Start 启动定期持久化
+func (l *Ledger) Start() {
+	if l.client == nil {
+		return
+	}
+
+	l.wg.Add(1)
+	go l.flushLoop()
+}
+
+// Stop 写入剩余增量后关闭Redis连接
+func (l *Ledger) Stop() {
+	if l.client == nil {
+		return
+	}
+
+	l.once.Do(func() {
+		close(l.stopCh)
+		l.wg.Wait()
+		if err := l.Flush(); err != nil {
+			log.Printf("Failed to persist usage: %v", err)
+		}
+		l.client.Close()
+	})
+}
+
+// Middleware 请求结束后累计用量，需放在限流、熔断等会拒绝请求的中间件之前；管理接口的请求不计入
+func (l *Ledger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if strings.HasPrefix(c.Request.URL.Path, "/admin/") {
+			return
+		}
+
+		subject := Subject{
+			Tenant: utils.ExtractTenant(c),
+			Model:  c.GetString("llm_model"),
+			Route:  c.GetString("route_name"),
+		}
+		if key := utils.ExtractAPIKey(c); key != "" {
+			subject.KeyID = utils.APIKeyID(key)
+		}
+
+		counters := types.UsageCounters{Requests: 1}
+		if utils.IsRequestRejected(c) {
+			counters.Rejections = 1
+		} else if utils.IsRequestFailed(c) {
+			counters.Errors = 1
+		}
+		// 只计入已按响应结算的令牌，失败或被拒绝的请求预估的令牌不计入
+		if completion, ok := c.Get("llm_completion_tokens"); ok {
+			counters.PromptTokens = c.GetInt64("llm_prompt_tokens")
+			counters.CompletionTokens, _ = completion.(int64)
+			counters.Cost = c.GetFloat64("llm_cost")
+		}
+
+		l.Record(subject, time.Now(), counters)
+	}
+}
+
+// Record 累计用量
+func (l *Ledger) Record(subject Subject, at time.Time, counters types.UsageCounters) {
+	bucket := l.bucketOf(at)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.client == nil {
+		l.pruneLocked(time.Now())
+	}
+	add(l.bucketLocked(bucket), subject, &counters)
+}
+
+// Flush 将增量累加到Redis
+func (l *Ledger) Flush() error {
+	if l.client == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	pending := l.buckets
+	l.buckets = make(map[int64]map[Subject]*types.UsageCounters)
+	l.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipe := l.client.Pipeline()
+	for bucket, usage := range pending {
+		key := l.bucketKey(bucket)
+		for subject, counters := range usage {
+			prefix := encodeSubject(subject) + fieldSeparator
+			for metric, value := range intMetrics(counters) {
+				if value != 0 {
+					pipe.HIncrBy(ctx, key, prefix+metric, value)
+				}
+			}
+			if counters.Cost != 0 {
+				pipe.HIncrByFloat(ctx, key, prefix+metricCost, counters.Cost)
+			}
+		}
+		pipe.Expire(ctx, key, l.retention)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		// 写入失败时保留增量，下次重试
+		l.mutex.Lock()
+		for bucket, usage := range pending {
+			target := l.bucketLocked(bucket)
+			for subject, counters := range usage {
+				add(target, subject, counters)
+			}
+		}
+		l.mutex.Unlock()
+		atomic.AddInt64(&l.failed, 1)
+		return fmt.Errorf("failed to persist usage: %v", err)
+	}
+
+	atomic.AddInt64(&l.flushed, 1)
+	return nil
+}
+
+// flushLoop 定期持久化
+func (l *Ledger) flushLoop() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.Flush(); err != nil {
+				log.Printf("Usage persistence failed: %v", err)
+			}
+		case <-l.stopCh:
+			return
+		}
+	}
+}
+
+// Query 汇总时间范围[From, To)内的用量，范围按时间桶对齐，结果按时间和维度取值排序
+func (l *Ledger) Query(query *types.UsageQuery) ([]types.UsageRecord, error) {
+	if !query.To.After(query.From) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+	for _, group := range query.GroupBy {
+		switch group {
+		case types.UsageGroupTenant, types.UsageGroupAPIKey, types.UsageGroupModel, types.UsageGroupRoute,
+			types.UsageGroupTime, types.UsageGroupDay:
+		default:
+			return nil, fmt.Errorf("unknown group_by %q", group)
+		}
+	}
+
+	from := l.bucketOf(query.From)
+	if oldest := l.bucketOf(time.Now().Add(-l.retention)); from < oldest {
+		from = oldest
+	}
+	to := l.bucketOf(query.To.Add(-time.Nanosecond))
+	step := int64(l.resolution / time.Second)
+	if step < 1 {
+		step = 1
+	}
+
+	buckets := make([]int64, 0)
+	for bucket := from; bucket <= to; bucket += step {
+		buckets = append(buckets, bucket)
+	}
+
+	usage, err := l.load(buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[row]*types.UsageCounters)
+	for _, bucket := range buckets {
+		for subject, counters := range usage[bucket] {
+			if !matches(query, subject) {
+				continue
+			}
+			key := groupKey(query.GroupBy, bucket, subject)
+			total, exists := records[key]
+			if !exists {
+				total = &types.UsageCounters{}
+				records[key] = total
+			}
+			merge(total, counters)
+		}
+	}
+
+	return sortRecords(records), nil
+}
+
+// load 获取时间桶的用量，持久化时为Redis中的合计加上本地尚未写入的增量
+func (l *Ledger) load(buckets []int64) (map[int64]map[Subject]*types.UsageCounters, error) {
+	usage := make(map[int64]map[Subject]*types.UsageCounters, len(buckets))
+
+	if l.client != nil && len(buckets) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		pipe := l.client.Pipeline()
+		reads := make(map[int64]*redis.MapStringStringCmd, len(buckets))
+		for _, bucket := range buckets {
+			reads[bucket] = pipe.HGetAll(ctx, l.bucketKey(bucket))
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to read usage: %v", err)
+		}
+
+		for bucket, cmd := range reads {
+			for field, value := range cmd.Val() {
+				subject, metric, ok := decodeField(field)
+				if !ok {
+					continue
+				}
+				if usage[bucket] == nil {
+					usage[bucket] = make(map[Subject]*types.UsageCounters)
+				}
+				counters := usage[bucket][subject]
+				if counters == nil {
+					counters = &types.UsageCounters{}
+					usage[bucket][subject] = counters
+				}
+				setMetric(counters, metric, value)
+			}
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, bucket := range buckets {
+		for subject, counters := range l.buckets[bucket] {
+			if usage[bucket] == nil {
+				usage[bucket] = make(map[Subject]*types.UsageCounters)
+			}
+			add(usage[bucket], subject, counters)
+		}
+	}
+	return usage, nil
+}
+
+// Stats 获取统计信息
+func (l *Ledger) Stats() map[string]interface{} {
+	l.mutex.Lock()
+	buckets := len(l.buckets)
+	l.mutex.Unlock()
+
+	return map[string]interface{}{
+		"buckets":    buckets,
+		"resolution": l.resolution.String(),
+		"persisted":  l.client != nil,
+		"flushed":    atomic.LoadInt64(&l.flushed),
+		"failed":     atomic.LoadInt64(&l.failed),
+	}
+}
+
+// bucketOf 时间所属时间桶的起点(Unix秒)
+func (l *Ledger) bucketOf(at time.Time) int64 {
+	return at.Truncate(l.resolution).Unix()
+}
+
+// bucketKey 时间桶的Redis键
+func (l *Ledger) bucketKey(bucket int64) string {
+	return l.key + ":" + strconv.FormatInt(bucket, 10)
+}
+
+// bucketLocked 获取或创建时间桶（需要加锁调用）
+func (l *Ledger) bucketLocked(bucket int64) map[Subject]*types.UsageCounters {
+	usage := l.buckets[bucket]
+	if usage == nil {
+		usage = make(map[Subject]*types.UsageCounters)
+		l.buckets[bucket] = usage
+	}
+	return usage
+}
+
+// pruneLocked 清理超出保留时长的时间桶（需要加锁调用）
+func (l *Ledger) pruneLocked(now time.Time) {
+	oldest := l.bucketOf(now.Add(-l.retention))
+	for bucket := range l.buckets {
+		if bucket < oldest {
+			delete(l.buckets, bucket)
+		}
+	}
+}
+
+// add 将用量累加到维度取值对应的计数
+func add(usage map[Subject]*types.UsageCounters, subject Subject, counters *types.UsageCounters) {
+	total := usage[subject]
+	if total == nil {
+		total = &types.UsageCounters{}
+		usage[subject] = total
+	}
+	merge(total, counters)
+}
+
+// merge 累加计数
+func merge(total, counters *types.UsageCounters) {
+	total.Requests += counters.Requests
+	total.Errors += counters.Errors
+	total.Rejections += counters.Rejections
+	total.PromptTokens += counters.PromptTokens
+	total.CompletionTokens += counters.CompletionTokens
+	total.Cost += counters.Cost
+}
+
+// intMetrics 整数计数及其字段名
+func intMetrics(counters *types.UsageCounters) map[string]int64 {
+	return map[string]int64{
+		metricRequests:         counters.Requests,
+		metricErrors:           counters.Errors,
+		metricRejections:       counters.Rejections,
+		metricPromptTokens:     counters.PromptTokens,
+		metricCompletionTokens: counters.CompletionTokens,
+	}
+}
+
+// setMetric 按字段名设置从Redis读取的计数
+func setMetric(counters *types.UsageCounters, metric, value string) {
+	if metric == metricCost {
+		counters.Cost, _ = strconv.ParseFloat(value, 64)
+		return
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return
+	}
+	switch metric {
+	case metricRequests:
+		counters.Requests = n
+	case metricErrors:
+		counters.Errors = n
+	case metricRejections:
+		counters.Rejections = n
+	case metricPromptTokens:
+		counters.PromptTokens = n
+	case metricCompletionTokens:
+		counters.CompletionTokens = n
+	}
+}
+
+// encodeSubject 维度取值在Redis哈希字段中的编码
+func encodeSubject(subject Subject) string {
+	return strings.Join([]string{subject.Tenant, subject.KeyID, subject.Model, subject.Route}, fieldSeparator)
+}
+
+// decodeField 解析Redis哈希字段
+func decodeField(field string) (Subject, string, bool) {
+	parts := strings.Split(field, fieldSeparator)
+	if len(parts) != 5 {
+		return Subject{}, "", false
+	}
+	return Subject{Tenant: parts[0], KeyID: parts[1], Model: parts[2], Route: parts[3]}, parts[4], true
+}
+
+// matches 维度取值是否满足过滤条件
+func matches(query *types.UsageQuery, subject Subject) bool {
+	return (query.Tenant == "" || query.Tenant == subject.Tenant) &&
+		(query.APIKey == "" || query.APIKey == subject.KeyID) &&
+		(query.Model == "" || query.Model == subject.Model) &&
+		(query.Route == "" || query.Route == subject.Route)
+}
+
+// groupKey 按分组维度生成报表行的键
+func groupKey(groupBy []string, bucket int64, subject Subject) row {
+	var key row
+	for _, group := range groupBy {
+		switch group {
+		case types.UsageGroupTenant:
+			key.Tenant = subject.Tenant
+		case types.UsageGroupAPIKey:
+			key.KeyID = subject.KeyID
+		case types.UsageGroupModel:
+			key.Model = subject.Model
+		case types.UsageGroupRoute:
+			key.Route = subject.Route
+		case types.UsageGroupTime:
+			key.start = bucket
+		case types.UsageGroupDay:
+			key.start = time.Unix(bucket, 0).UTC().Truncate(24 * time.Hour).Unix()
+		}
+	}
+	return key
+}
+
+// sortRecords 生成报表行，按时间和维度取值排序
+func sortRecords(records map[row]*types.UsageCounters) []types.UsageRecord {
+	keys := make([]row, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		if a.KeyID != b.KeyID {
+			return a.KeyID < b.KeyID
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		return a.Route < b.Route
+	})
+
+	result := make([]types.UsageRecord, 0, len(keys))
+	for _, key := range keys {
+		record := types.UsageRecord{
+			Tenant:        key.Tenant,
+			APIKey:        key.KeyID,
+			Model:         key.Model,
+			Route:         key.Route,
+			UsageCounters: *records[key],
+		}
+		if key.start != 0 {
+			start := time.Unix(key.start, 0).UTC()
+			record.Start = &start
+		}
+		result = append(result, record)
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// csvCounterColumns 用量计数的CSV列
+var csvCounterColumns = []string{"requests", "errors", "rejections", "prompt_tokens", "completion_tokens", "cost"}
+
+// WriteCSV 以CSV输出报表，维度列按分组维度的顺序排在计数列之前
+func WriteCSV(w io.Writer, groupBy []string, records []types.UsageRecord) error {
+	writer := csv.NewWriter(w)
+
+	header := make([]string, 0, len(groupBy)+len(csvCounterColumns))
+	header = append(header, groupBy...)
+	header = append(header, csvCounterColumns...)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		line := make([]string, 0, len(header))
+		for _, group := range groupBy {
+			line = append(line, dimensionValue(&record, group))
+		}
+		line = append(line,
+			strconv.FormatInt(record.Requests, 10),
+			strconv.FormatInt(record.Errors, 10),
+			strconv.FormatInt(record.Rejections, 10),
+			strconv.FormatInt(record.PromptTokens, 10),
+			strconv.FormatInt(record.CompletionTokens, 10),
+			strconv.FormatFloat(record.Cost, 'f', 6, 64),
+		)
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Total 汇总报表所有行的计数
+func Total(records []types.UsageRecord) types.UsageCounters {
+	var total types.UsageCounters
+	for i := range records {
+		merge(&total, &records[i].UsageCounters)
+	}
+	return total
+}
+
+// dimensionValue 报表行在分组维度上的取值
+func dimensionValue(record *types.UsageRecord, group string) string {
+	switch group {
+	case types.UsageGroupTenant:
+		return record.Tenant
+	case types.UsageGroupAPIKey:
+		return record.APIKey
+	case types.UsageGroupModel:
+		return record.Model
+	case types.UsageGroupRoute:
+		return record.Route
+	case types.UsageGroupTime, types.UsageGroupDay:
+		if record.Start != nil {
+			return record.Start.Format(time.RFC3339)
+		}
+	}
+	return ""
+}
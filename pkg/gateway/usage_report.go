@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/usage"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// defaultUsageRange 未指定from时报表的时间范围
+const defaultUsageRange = 24 * time.Hour
+
+// getUsageHandler 按租户、API密钥、模型、路由汇总时间范围内的用量。
+// from/to为RFC3339时间，默认最近24小时；group_by为逗号分隔的分组维度（tenant、api_key、model、route、time、day）；
+// tenant、api_key（密钥摘要）、model、route过滤；format=csv时以CSV附件返回
+func (g *Gateway) getUsageHandler(c *gin.Context) {
+	if g.usage == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Usage accounting is disabled"})
+		return
+	}
+
+	query := &types.UsageQuery{
+		To:     time.Now(),
+		Tenant: c.Query("tenant"),
+		APIKey: c.Query("api_key"),
+		Model:  c.Query("model"),
+		Route:  c.Query("route"),
+	}
+	for name, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s: %v", name, err)})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultUsageRange)
+	}
+	if value := c.Query("group_by"); value != "" {
+		for _, group := range strings.Split(value, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				query.GroupBy = append(query.GroupBy, group)
+			}
+		}
+	}
+
+	records, err := g.usage.Query(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "csv":
+		filename := fmt.Sprintf("usage-%s-%s.csv", query.From.UTC().Format("20060102T150405Z"), query.To.UTC().Format("20060102T150405Z"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := usage.WriteCSV(c.Writer, query.GroupBy, records); err != nil {
+			c.Error(err)
+		}
+	case "json":
+		c.JSON(http.StatusOK, gin.H{
+			"from":     query.From,
+			"to":       query.To,
+			"group_by": query.GroupBy,
+			"records":  records,
+			"total":    usage.Total(records),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported format: %s", c.Query("format"))})
+	}
+}
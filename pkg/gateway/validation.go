@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/gateway/router"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// schemaValidation 请求体Schema校验中间件，不符合路由Schema的请求在边缘以400拒绝，
+// 校验失败作为单独的信号类型进入错误采样
+func (g *Gateway) schemaValidation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := matchedRoute(c)
+		if route == nil || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		requestSchema, maxBytes := route.RequestSchema()
+		if requestSchema == nil {
+			c.Next()
+			return
+		}
+
+		body, ok := bufferedBody(c, maxBytes)
+		if !ok {
+			return
+		}
+
+		violations := requestSchema.Validate(body)
+		if len(violations) == 0 {
+			c.Next()
+			return
+		}
+
+		if g.metrics != nil {
+			g.metrics.RecordSchemaViolation(route.Name, "request")
+		}
+		c.Set("signal_type", types.SignalSchemaRequest)
+		utils.RecordStageError(c, "schema", fmt.Errorf("request body does not match schema: %s", router.FormatViolations(violations)))
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":      "Request body does not match schema",
+			"code":       "SCHEMA_VALIDATION_FAILED",
+			"violations": violations,
+		})
+	}
+}
+
+// bufferedBody 获取请求体，缓冲模式下直接复用，否则读入内存后放回请求；超过上限时返回413
+func bufferedBody(c *gin.Context, maxBytes int64) ([]byte, bool) {
+	if value, exists := c.Get("request_body"); exists {
+		if body, ok := value.([]byte); ok {
+			return body, true
+		}
+	}
+
+	if c.Request.ContentLength > maxBytes {
+		abortBodyTooLarge(c, maxBytes)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBytes+1))
+	c.Request.Body.Close()
+	if err != nil {
+		// 路由的请求体上限更小时由其MaxBytesReader先触发
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortBodyTooLarge(c, tooLarge.Limit)
+			return nil, false
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read request body",
+			"code":  "INVALID_BODY",
+		})
+		return nil, false
+	}
+	if int64(len(body)) > maxBytes {
+		abortBodyTooLarge(c, maxBytes)
+		return nil, false
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	return body, true
+}
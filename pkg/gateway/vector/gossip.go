@@ -0,0 +1,211 @@
+package vector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultGossipChannel 默认Redis频道
+const defaultGossipChannel = "gateway:cluster-signatures"
+
+// gossipMessage 副本间传播的簇识别结果
+type gossipMessage struct {
+	Node       string  `json:"node"`
+	Signature  string  `json:"signature"`
+	ClusterID  string  `json:"cluster_id"`
+	Similarity float64 `json:"similarity"`
+}
+
+// learner 接收其他副本识别结果的向量代理
+type learner interface {
+	learnCluster(signature, clusterID string, similarity float64, ttl time.Duration) bool
+}
+
+// SignatureGossip 副本间共享错误签名到簇的映射：本副本通过向量化识别出新签名所属的簇后发布到Redis频道，
+// 其他副本收到后直接写入本地缓存，同一突发错误不必在每个副本上重复向量化
+type SignatureGossip struct {
+	client    redis.UniversalClient
+	config    types.GossipConfig
+	node      string
+	learner   learner
+	outbox    chan gossipMessage
+	published int64
+	received  int64
+	learned   int64
+	dropped   int64
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewSignatureGossip 创建签名共享
+func NewSignatureGossip(redisConfig *types.RedisConfig, config *types.GossipConfig) *SignatureGossip {
+	cfg := *config
+	if cfg.Channel == "" {
+		cfg.Channel = defaultGossipChannel
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 5 * time.Minute
+	}
+	if cfg.MaxSignatureBytes <= 0 {
+		cfg.MaxSignatureBytes = 4096
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:       redisConfig.Addresses,
+		Password:    redisConfig.Password,
+		DB:          redisConfig.DB,
+		PoolSize:    redisConfig.PoolSize,
+		DialTimeout: redisConfig.Timeout,
+	})
+
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SignatureGossip{
+		client: client,
+		config: cfg,
+		node:   fmt.Sprintf("%s-%s", hostname, utils.RandomID()[:8]),
+		outbox: make(chan gossipMessage, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// AttachGossip 将签名共享接入向量代理：代理识别出的新映射被发布，其他副本的映射写入代理缓存
+func AttachGossip(agent interfaces.VectorAgent, gossip *SignatureGossip) error {
+	va, ok := agent.(*vectorAgent)
+	if !ok {
+		return fmt.Errorf("vector agent does not support gossip")
+	}
+
+	va.mutex.Lock()
+	va.gossip = gossip
+	va.mutex.Unlock()
+	gossip.learner = va
+	return nil
+}
+
+// Start 订阅频道并启动发布循环
+func (sg *SignatureGossip) Start() error {
+	pubsub := sg.client.Subscribe(sg.ctx, sg.config.Channel)
+	if _, err := pubsub.Receive(sg.ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe gossip channel: %v", err)
+	}
+
+	sg.wg.Add(2)
+	go sg.receiveLoop(pubsub)
+	go sg.publishLoop()
+
+	log.Printf("Cluster signature gossip started on channel %s as %s", sg.config.Channel, sg.node)
+	return nil
+}
+
+// Stop 停止订阅和发布，关闭Redis连接
+func (sg *SignatureGossip) Stop() {
+	sg.cancel()
+	sg.wg.Wait()
+	sg.client.Close()
+}
+
+// Publish 排队发布本副本识别出的映射，队列满时丢弃
+func (sg *SignatureGossip) Publish(signature, clusterID string, similarity float64) {
+	if len(signature) > sg.config.MaxSignatureBytes {
+		return
+	}
+
+	select {
+	case sg.outbox <- gossipMessage{Node: sg.node, Signature: signature, ClusterID: clusterID, Similarity: similarity}:
+	default:
+		atomic.AddInt64(&sg.dropped, 1)
+	}
+}
+
+// Stats 获取统计信息
+func (sg *SignatureGossip) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"node":      sg.node,
+		"published": atomic.LoadInt64(&sg.published),
+		"received":  atomic.LoadInt64(&sg.received),
+		"learned":   atomic.LoadInt64(&sg.learned),
+		"dropped":   atomic.LoadInt64(&sg.dropped),
+	}
+}
+
+// publishLoop 发布排队的映射
+func (sg *SignatureGossip) publishLoop() {
+	defer sg.wg.Done()
+
+	for {
+		select {
+		case message := <-sg.outbox:
+			data, err := json.Marshal(message)
+			if err != nil {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(sg.ctx, 2*time.Second)
+			err = sg.client.Publish(ctx, sg.config.Channel, data).Err()
+			cancel()
+			if err != nil {
+				if atomic.AddInt64(&sg.dropped, 1)%100 == 1 {
+					log.Printf("Failed to publish cluster signature: %v", err)
+				}
+				continue
+			}
+			atomic.AddInt64(&sg.published, 1)
+		case <-sg.ctx.Done():
+			return
+		}
+	}
+}
+
+// receiveLoop 接收其他副本的映射，忽略本副本发布的消息
+func (sg *SignatureGossip) receiveLoop(pubsub *redis.PubSub) {
+	defer sg.wg.Done()
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			sg.handle([]byte(msg.Payload))
+		case <-sg.ctx.Done():
+			return
+		}
+	}
+}
+
+// handle 处理一条映射，簇未知的映射（如簇信息尚未同步）被忽略
+func (sg *SignatureGossip) handle(payload []byte) {
+	var message gossipMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return
+	}
+	if message.Node == sg.node || message.Signature == "" || message.ClusterID == "" {
+		return
+	}
+
+	atomic.AddInt64(&sg.received, 1)
+	if sg.learner != nil && sg.learner.learnCluster(message.Signature, message.ClusterID, message.Similarity, sg.config.TTL) {
+		atomic.AddInt64(&sg.learned, 1)
+	}
+}
@@ -0,0 +1,78 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// projection 簇快照的质心降维投影
+type projection struct {
+	method   string
+	inputDim int
+	matrix   [][]float32
+}
+
+// newProjection 解码快照中的投影矩阵
+func newProjection(p *types.VectorProjection) (*projection, error) {
+	if p.InputDim <= 0 || p.OutputDim <= 0 {
+		return nil, fmt.Errorf("projection dimensions must be positive")
+	}
+	values, err := utils.DecodeFloat32s(p.Matrix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode projection matrix: %v", err)
+	}
+	if len(values) != p.InputDim*p.OutputDim {
+		return nil, fmt.Errorf("projection matrix has %d values, expected %dx%d", len(values), p.OutputDim, p.InputDim)
+	}
+
+	matrix := make([][]float32, p.OutputDim)
+	for i := range matrix {
+		matrix[i] = values[i*p.InputDim : (i+1)*p.InputDim]
+	}
+	return &projection{method: p.Method, inputDim: p.InputDim, matrix: matrix}, nil
+}
+
+// apply 投影向量，维度与投影矩阵不符时返回nil
+func (p *projection) apply(vector []float32) []float32 {
+	if len(vector) != p.inputDim {
+		return nil
+	}
+	projected := make([]float32, len(p.matrix))
+	for i, row := range p.matrix {
+		var sum float64
+		for j, value := range row {
+			sum += float64(value) * float64(vector[j])
+		}
+		projected[i] = float32(sum)
+	}
+	return projected
+}
+
+// similarity 投影后的向量与降维质心的余弦相似度。查询向量使用投影前的范数：
+// 投影丢弃的分量与质心无关，用投影后的范数会使相似度偏高
+func (p *projection) similarity(projected []float32, queryNorm float64, centroid []float32) float64 {
+	if len(projected) != len(centroid) || queryNorm == 0 {
+		return 0
+	}
+	var dot, norm float64
+	for i := range projected {
+		dot += float64(projected[i]) * float64(centroid[i])
+		norm += float64(centroid[i]) * float64(centroid[i])
+	}
+	if norm == 0 {
+		return 0
+	}
+	return dot / (queryNorm * math.Sqrt(norm))
+}
+
+// vectorNorm 向量范数
+func vectorNorm(vector []float32) float64 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
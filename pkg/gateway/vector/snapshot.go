@@ -0,0 +1,102 @@
+package vector
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// EmbeddingMismatchError 簇快照的嵌入模型与本地嵌入模型不一致，或快照内质心维度不一致
+type EmbeddingMismatchError struct {
+	Local    types.EmbeddingModelInfo
+	Snapshot types.EmbeddingModelInfo
+	Reason   string
+}
+
+// Error 实现error接口
+func (e *EmbeddingMismatchError) Error() string {
+	return fmt.Sprintf("cluster snapshot rejected: %s (snapshot %s/%s dim %d, local %s/%s dim %d)",
+		e.Reason,
+		e.Snapshot.Model, e.Snapshot.Version, e.Snapshot.Dimension,
+		e.Local.Model, e.Local.Version, e.Local.Dimension)
+}
+
+// ApplySnapshot 加载控制面发布的簇快照；快照未声明嵌入模型、与本地嵌入模型不一致或质心维度不符时
+// 拒绝加载并保留现有的簇，避免用不同模型的向量计算出无意义的相似度
+func (va *vectorAgent) ApplySnapshot(snapshot *types.ClusterSnapshot) error {
+	proj, err := va.checkEmbedding(snapshot)
+	if err != nil {
+		va.mutex.Lock()
+		va.snapshotsRejected++
+		va.lastRejection = err.Error()
+		va.mutex.Unlock()
+		return err
+	}
+
+	clusters := make(map[string]*types.Cluster, len(snapshot.Clusters))
+	for _, cluster := range snapshot.Clusters {
+		if cluster != nil && cluster.ID != "" {
+			clusters[cluster.ID] = cluster
+		}
+	}
+	if err := va.setClusters(clusters, proj); err != nil {
+		return err
+	}
+
+	va.mutex.Lock()
+	va.snapshotEmbedding = snapshot.Embedding
+	va.lastRejection = ""
+	va.mutex.Unlock()
+
+	log.Printf("Applied cluster snapshot generated at %s by %s/%s (dim %d)",
+		snapshot.GeneratedAt.Format("2006-01-02T15:04:05Z07:00"),
+		snapshot.Embedding.Model, snapshot.Embedding.Version, snapshot.Embedding.Dimension)
+	return nil
+}
+
+// checkEmbedding 校验快照的嵌入模型信息并解码质心投影；未接入本地嵌入服务时只校验快照自身的一致性
+func (va *vectorAgent) checkEmbedding(snapshot *types.ClusterSnapshot) (*projection, error) {
+	var local types.EmbeddingModelInfo
+	if va.embeddingService != nil {
+		local = va.embeddingService.ModelInfo()
+	}
+	mismatch := func(reason string) error {
+		return &EmbeddingMismatchError{Local: local, Snapshot: snapshot.Embedding, Reason: reason}
+	}
+
+	info := snapshot.Embedding
+	if info.Model == "" || info.Dimension <= 0 {
+		return nil, mismatch("snapshot does not declare its embedding model and dimension")
+	}
+
+	// 降维快照的质心为投影后的维度，投影矩阵的输入维度需与嵌入模型一致
+	dimension := info.Dimension
+	var proj *projection
+	if p := snapshot.Projection; p != nil {
+		if p.InputDim != info.Dimension {
+			return nil, mismatch(fmt.Sprintf("projection input dimension %d differs", p.InputDim))
+		}
+		var err error
+		if proj, err = newProjection(p); err != nil {
+			return nil, mismatch(err.Error())
+		}
+		dimension = p.OutputDim
+	}
+	for _, cluster := range snapshot.Clusters {
+		if cluster != nil && len(cluster.Centroid) > 0 && len(cluster.Centroid) != dimension {
+			return nil, mismatch(fmt.Sprintf("centroid of cluster %s has dimension %d", cluster.ID, len(cluster.Centroid)))
+		}
+	}
+
+	if va.embeddingService == nil {
+		return proj, nil
+	}
+	if local.Dimension != info.Dimension {
+		return nil, mismatch("embedding dimension differs")
+	}
+	if local.Model != info.Model || local.Version != info.Version {
+		return nil, mismatch("embedding model differs")
+	}
+	return proj, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/llm-aware-gateway/pkg/interfaces"
 	"github.com/llm-aware-gateway/pkg/types"
@@ -16,6 +17,11 @@ type vectorAgent struct {
 	clusters         map[string]*types.Cluster
 	cache            interfaces.Cache
 	similarityThreshold float64
+	gossip           *SignatureGossip // 未开启副本间共享时为nil
+	snapshotEmbedding types.EmbeddingModelInfo // 当前簇快照的嵌入模型
+	projection       *projection // 簇快照开启质心降维时的投影，否则为nil
+	snapshotsRejected int64
+	lastRejection     string
 	mutex            sync.RWMutex
 }
 
@@ -29,34 +35,70 @@ func NewVectorAgent(embeddingService interfaces.EmbeddingService, cache interfac
 	}
 }
 
+// clusterMatch 簇识别结果缓存项
+type clusterMatch struct {
+	clusterID  string
+	similarity float64
+}
+
 // IdentifyCluster 识别错误所属的簇
 func (va *vectorAgent) IdentifyCluster(errorSignature string) (string, error) {
+	clusterID, _, err := va.IdentifyClusterWithScore(errorSignature)
+	return clusterID, err
+}
+
+// IdentifyClusterWithScore 识别错误所属的簇并返回相似度
+func (va *vectorAgent) IdentifyClusterWithScore(errorSignature string) (string, float64, error) {
 	if errorSignature == "" {
-		return "", nil
+		return "", 0, nil
 	}
 
 	// 首先检查缓存
 	if cached, found := va.cache.Get(errorSignature); found {
-		if clusterID, ok := cached.(string); ok {
-			return clusterID, nil
+		if match, ok := cached.(*clusterMatch); ok {
+			return match.clusterID, match.similarity, nil
 		}
 	}
 
 	// 生成错误签名的向量
 	vector, err := va.GenerateVector(errorSignature)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate vector: %v", err)
+		return "", 0, fmt.Errorf("failed to generate vector: %v", err)
 	}
 
 	// 查找最相似的簇
-	clusterID := va.findMostSimilarCluster(vector)
+	clusterID, similarity := va.findMostSimilarCluster(vector)
 
-	// 缓存结果（TTL 5分钟）
+	// 缓存结果（TTL 5分钟），并通知其他副本
 	if clusterID != "" {
-		va.cache.Set(errorSignature, clusterID, 300)
+		va.cache.Set(errorSignature, &clusterMatch{clusterID: clusterID, similarity: similarity}, 300)
+
+		va.mutex.RLock()
+		gossip := va.gossip
+		va.mutex.RUnlock()
+		if gossip != nil {
+			gossip.Publish(errorSignature, clusterID, similarity)
+		}
+	}
+
+	return clusterID, similarity, nil
+}
+
+// learnCluster 写入其他副本识别出的映射，本地未知的簇或已缓存的签名不写入
+func (va *vectorAgent) learnCluster(signature, clusterID string, similarity float64, ttl time.Duration) bool {
+	va.mutex.RLock()
+	_, known := va.clusters[clusterID]
+	va.mutex.RUnlock()
+	if !known {
+		return false
+	}
+
+	if _, found := va.cache.Get(signature); found {
+		return false
 	}
 
-	return clusterID, nil
+	va.cache.Set(signature, &clusterMatch{clusterID: clusterID, similarity: similarity}, int64(ttl/time.Second))
+	return true
 }
 
 // GenerateVector 生成文本向量
@@ -77,11 +119,18 @@ func (va *vectorAgent) GenerateVector(text string) ([]float32, error) {
 	return vector, nil
 }
 
-// UpdateClusters 更新簇信息
+// UpdateClusters 更新簇信息，质心为全精度向量
 func (va *vectorAgent) UpdateClusters(clusters map[string]*types.Cluster) error {
+	return va.setClusters(clusters, nil)
+}
+
+// setClusters 同时替换簇和质心投影，避免匹配时使用维度不一致的质心和投影
+func (va *vectorAgent) setClusters(clusters map[string]*types.Cluster, proj *projection) error {
 	va.mutex.Lock()
 	defer va.mutex.Unlock()
 
+	va.projection = proj
+
 	// 更新簇信息
 	va.clusters = make(map[string]*types.Cluster)
 	for clusterID, cluster := range clusters {
@@ -111,19 +160,32 @@ func (va *vectorAgent) UpdateClusters(clusters map[string]*types.Cluster) error
 }
 
 // findMostSimilarCluster 查找最相似的簇
-func (va *vectorAgent) findMostSimilarCluster(vector []float32) string {
+func (va *vectorAgent) findMostSimilarCluster(vector []float32) (string, float64) {
 	va.mutex.RLock()
 	defer va.mutex.RUnlock()
 
 	var bestClusterID string
 	var bestSimilarity float64
 
+	// 降维快照的质心在低维空间中，查询向量先投影
+	var projected []float32
+	var queryNorm float64
+	if va.projection != nil {
+		projected = va.projection.apply(vector)
+		queryNorm = vectorNorm(vector)
+	}
+
 	for clusterID, cluster := range va.clusters {
 		if len(cluster.Centroid) == 0 {
 			continue
 		}
 
-		similarity := utils.CosineSimilarity(vector, cluster.Centroid)
+		var similarity float64
+		if va.projection != nil {
+			similarity = va.projection.similarity(projected, queryNorm, cluster.Centroid)
+		} else {
+			similarity = utils.CosineSimilarity(vector, cluster.Centroid)
+		}
 		if similarity > bestSimilarity && similarity >= va.similarityThreshold {
 			bestSimilarity = similarity
 			bestClusterID = clusterID
@@ -134,7 +196,27 @@ func (va *vectorAgent) findMostSimilarCluster(vector []float32) string {
 		log.Printf("Found similar cluster: %s (similarity: %.4f)", bestClusterID, bestSimilarity)
 	}
 
-	return bestClusterID
+	return bestClusterID, bestSimilarity
+}
+
+// Stats 获取向量代理统计
+func (va *vectorAgent) Stats() map[string]interface{} {
+	va.mutex.RLock()
+	embedding, rejected, lastRejection := va.snapshotEmbedding, va.snapshotsRejected, va.lastRejection
+	proj := va.projection
+	va.mutex.RUnlock()
+
+	stats := map[string]interface{}{
+		"clusters_known":       va.getClusterCount(),
+		"similarity_threshold": va.getSimilarityThreshold(),
+		"snapshot_embedding":   embedding,
+		"snapshots_rejected":   rejected,
+		"last_rejection":       lastRejection,
+	}
+	if proj != nil {
+		stats["projection"] = fmt.Sprintf("%s %d->%d", proj.method, proj.inputDim, len(proj.matrix))
+	}
+	return stats
 }
 
 // getClusterCount 获取簇数量
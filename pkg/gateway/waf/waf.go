@@ -0,0 +1,355 @@
+package waf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// defaultMaxBodyBytes 默认检查的请求体字节数
+const defaultMaxBodyBytes = 64 << 10
+
+// configRuleSet 配置文件中的规则集名
+const configRuleSet = "config"
+
+// TagsHeader 标记动作命中时转发给上游的请求头
+const TagsHeader = "X-WAF-Tags"
+
+// rule 编译后的WAF规则
+type rule struct {
+	id      string
+	action  string
+	methods map[string]bool
+	path    *regexp.Regexp
+	headers map[string]*regexp.Regexp
+	body    *regexp.Regexp
+}
+
+// ruleSet 规则集，通过策略下发的规则集带过期时间
+type ruleSet struct {
+	rules      []*rule
+	expireTime time.Time
+}
+
+// WAF 基于规则的请求过滤：先检查方法和请求头数量限制，再按规则集名顺序匹配规则
+type WAF struct {
+	allowedMethods map[string]bool
+	maxHeaders     int
+	maxBodyBytes   int64
+	metrics        interfaces.MetricsCollector
+	ruleSets       map[string]*ruleSet
+	snapshot       atomic.Value // []*ruleSet，按规则集名排序
+	blocked        int64
+	tagged         int64
+	mutex          sync.Mutex
+}
+
+// NewWAF 创建WAF
+func NewWAF(config *types.WAFConfig, metrics interfaces.MetricsCollector) (*WAF, error) {
+	w := &WAF{
+		maxHeaders:   config.MaxHeaders,
+		maxBodyBytes: config.MaxBodyBytes,
+		metrics:      metrics,
+		ruleSets:     make(map[string]*ruleSet),
+	}
+	if w.maxBodyBytes <= 0 {
+		w.maxBodyBytes = defaultMaxBodyBytes
+	}
+	if len(config.AllowedMethods) > 0 {
+		w.allowedMethods = make(map[string]bool, len(config.AllowedMethods))
+		for _, method := range config.AllowedMethods {
+			w.allowedMethods[strings.ToUpper(method)] = true
+		}
+	}
+
+	rules, err := compileRules(config.Rules)
+	if err != nil {
+		return nil, err
+	}
+	w.ruleSets[configRuleSet] = &ruleSet{rules: rules}
+	w.rebuildLocked()
+
+	return w, nil
+}
+
+// compileRules 编译规则
+func compileRules(configs []types.WAFRule) ([]*rule, error) {
+	rules := make([]*rule, 0, len(configs))
+	for i, cfg := range configs {
+		id := cfg.ID
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i)
+		}
+
+		r := &rule{id: id, action: cfg.Action}
+		switch r.action {
+		case "":
+			r.action = types.WAFActionBlock
+		case types.WAFActionBlock, types.WAFActionTag:
+		default:
+			return nil, fmt.Errorf("waf rule %s: unknown action %q", id, cfg.Action)
+		}
+
+		if len(cfg.Methods) > 0 {
+			r.methods = make(map[string]bool, len(cfg.Methods))
+			for _, method := range cfg.Methods {
+				r.methods[strings.ToUpper(method)] = true
+			}
+		}
+
+		var err error
+		if cfg.Path != "" {
+			if r.path, err = regexp.Compile(cfg.Path); err != nil {
+				return nil, fmt.Errorf("waf rule %s: invalid path pattern: %v", id, err)
+			}
+		}
+		if cfg.Body != "" {
+			if r.body, err = regexp.Compile(cfg.Body); err != nil {
+				return nil, fmt.Errorf("waf rule %s: invalid body pattern: %v", id, err)
+			}
+		}
+		if len(cfg.Headers) > 0 {
+			r.headers = make(map[string]*regexp.Regexp, len(cfg.Headers))
+			for name, pattern := range cfg.Headers {
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("waf rule %s: invalid header pattern for %s: %v", id, name, err)
+				}
+				r.headers[http.CanonicalHeaderKey(name)] = re
+			}
+		}
+
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// SetRuleSet 替换通过策略下发的规则集
+func (w *WAF) SetRuleSet(name string, policy *types.Policy) error {
+	if policy.WAF == nil {
+		return fmt.Errorf("waf policy %s has no rules", name)
+	}
+
+	rules, err := compileRules(policy.WAF.Rules)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	w.ruleSets[name] = &ruleSet{rules: rules, expireTime: policy.ExpireTime}
+	w.rebuildLocked()
+	w.mutex.Unlock()
+
+	log.Printf("Loaded WAF rule set %s: %d rules", name, len(rules))
+	return nil
+}
+
+// RemoveRuleSet 删除通过策略下发的规则集，配置文件中的规则不受影响
+func (w *WAF) RemoveRuleSet(name string) bool {
+	if name == configRuleSet {
+		return false
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, exists := w.ruleSets[name]; !exists {
+		return false
+	}
+	delete(w.ruleSets, name)
+	w.rebuildLocked()
+
+	log.Printf("Removed WAF rule set %s", name)
+	return true
+}
+
+// rebuildLocked 生成按名称排序的规则集快照，调用方需持有锁
+func (w *WAF) rebuildLocked() {
+	names := make([]string, 0, len(w.ruleSets))
+	for name := range w.ruleSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sets := make([]*ruleSet, 0, len(names))
+	for _, name := range names {
+		sets = append(sets, w.ruleSets[name])
+	}
+	w.snapshot.Store(sets)
+}
+
+// Middleware WAF中间件，阻断时返回403，标记时放行并附加X-WAF-Tags请求头
+func (w *WAF) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if w.allowedMethods != nil && !w.allowedMethods[c.Request.Method] {
+			w.block(c, "method_not_allowed")
+			return
+		}
+		if w.maxHeaders > 0 && len(c.Request.Header) > w.maxHeaders {
+			w.block(c, "too_many_headers")
+			return
+		}
+
+		sets := w.snapshot.Load().([]*ruleSet)
+		now := time.Now()
+
+		target := requestTarget(c.Request)
+		var body []byte
+		bodyRead := false
+		tags := make([]string, 0)
+
+		for _, set := range sets {
+			if !set.expireTime.IsZero() && now.After(set.expireTime) {
+				continue
+			}
+			for _, r := range set.rules {
+				if r.body != nil && !bodyRead {
+					body = w.peekBody(c)
+					bodyRead = true
+				}
+				if !r.matches(c.Request, target, body) {
+					continue
+				}
+
+				if r.action == types.WAFActionBlock {
+					w.block(c, r.id)
+					return
+				}
+				tags = append(tags, r.id)
+				if w.metrics != nil {
+					w.metrics.RecordWAFMatch(r.id, r.action)
+				}
+			}
+		}
+
+		if len(tags) > 0 {
+			atomic.AddInt64(&w.tagged, 1)
+			c.Set("waf_tags", tags)
+			utils.AnnotateStage(c, "waf", "tagged by waf rules "+strings.Join(tags, ","))
+			c.Request.Header.Set(TagsHeader, strings.Join(tags, ","))
+		}
+
+		c.Next()
+	}
+}
+
+// matches 判断请求是否满足规则的全部条件
+func (r *rule) matches(req *http.Request, target string, body []byte) bool {
+	if r.methods != nil && !r.methods[req.Method] {
+		return false
+	}
+	if r.path != nil && !r.path.MatchString(target) {
+		return false
+	}
+	for name, re := range r.headers {
+		values, exists := req.Header[name]
+		if !exists || !matchAny(re, values) {
+			return false
+		}
+	}
+	if r.body != nil && !r.body.Match(body) {
+		return false
+	}
+	return true
+}
+
+// requestTarget 解码后的路径和查询串，避免编码绕过规则
+func requestTarget(req *http.Request) string {
+	if req.URL.RawQuery == "" {
+		return req.URL.Path
+	}
+	query, err := url.QueryUnescape(req.URL.RawQuery)
+	if err != nil {
+		query = req.URL.RawQuery
+	}
+	return req.URL.Path + "?" + query
+}
+
+// matchAny 任一请求头值匹配即可
+func matchAny(re *regexp.Regexp, values []string) bool {
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// peekBody 读取请求体前缀用于检查，读取的内容拼回请求体，不影响流式转发
+func (w *WAF) peekBody(c *gin.Context) []byte {
+	if body, exists := c.Get("request_body"); exists {
+		if data, ok := body.([]byte); ok {
+			return data
+		}
+	}
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(c.Request.Body, w.maxBodyBytes))
+	if err != nil {
+		log.Printf("Failed to read request body for WAF inspection: %v", err)
+	}
+	c.Request.Body = &splicedBody{
+		Reader: io.MultiReader(bytes.NewReader(prefix), c.Request.Body),
+		closer: c.Request.Body,
+	}
+	return prefix
+}
+
+// splicedBody 已读前缀与剩余请求体拼接
+type splicedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+// Close 关闭原始请求体
+func (sb *splicedBody) Close() error {
+	return sb.closer.Close()
+}
+
+// block 返回403
+func (w *WAF) block(c *gin.Context, ruleID string) {
+	atomic.AddInt64(&w.blocked, 1)
+	utils.RecordStageError(c, "waf", fmt.Errorf("blocked by waf rule %s", ruleID))
+	if w.metrics != nil {
+		w.metrics.RecordWAFMatch(ruleID, types.WAFActionBlock)
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error": "Request blocked by WAF",
+		"code":  "WAF_BLOCKED",
+		"rule":  ruleID,
+	})
+}
+
+// Stats 获取规则集和命中统计
+func (w *WAF) Stats() map[string]interface{} {
+	w.mutex.Lock()
+	ruleSets := make(map[string]int, len(w.ruleSets))
+	for name, set := range w.ruleSets {
+		ruleSets[name] = len(set.rules)
+	}
+	w.mutex.Unlock()
+
+	return map[string]interface{}{
+		"rule_sets": ruleSets,
+		"blocked":   atomic.LoadInt64(&w.blocked),
+		"tagged":    atomic.LoadInt64(&w.tagged),
+	}
+}
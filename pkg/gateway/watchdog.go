@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/gateway/upstream"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// 看门狗默认值
+const (
+	defaultWatchdogMultiplier = 3.0
+	defaultWatchdogInterval   = time.Second
+)
+
+// stuckRequest 被看门狗标记的请求
+type stuckRequest struct {
+	request  inflightRequest
+	elapsed  time.Duration
+	expected time.Duration
+	canceled bool
+}
+
+// flagStuck 标记耗时超过预期时长倍数的请求，每个请求只标记一次；cancel为true时取消这些请求
+func (t *inflightTracker) flagStuck(now time.Time, multiplier float64, defaultExpected time.Duration, cancel bool) []stuckRequest {
+	var stuck []stuckRequest
+
+	t.mutex.Lock()
+	for request := range t.requests {
+		expected := request.expected
+		if expected <= 0 {
+			expected = defaultExpected
+		}
+		if request.stuck || expected <= 0 {
+			continue
+		}
+
+		elapsed := now.Sub(request.start)
+		if elapsed <= time.Duration(float64(expected)*multiplier) {
+			continue
+		}
+
+		request.stuck = true
+		entry := stuckRequest{request: *request, elapsed: elapsed, expected: expected}
+		if cancel && request.cancel != nil {
+			entry.canceled = true
+		}
+		stuck = append(stuck, entry)
+	}
+	t.mutex.Unlock()
+
+	// 取消在锁外执行，上游调用随之中断并按超时响应
+	for _, entry := range stuck {
+		if entry.canceled {
+			entry.request.cancel(upstream.ErrStuckRequest)
+		}
+	}
+	return stuck
+}
+
+// watchdogLoop 定期检查在途请求，卡住的请求作为独立的错误信号送入错误采样
+func (g *Gateway) watchdogLoop() {
+	defer g.wg.Done()
+
+	config := g.config.Watchdog
+	if config.Multiplier <= 1 {
+		config.Multiplier = defaultWatchdogMultiplier
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaultWatchdogInterval
+	}
+
+	ticker := time.NewTicker(config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, entry := range g.inflight.flagStuck(now, config.Multiplier, config.DefaultExpected, config.Cancel) {
+				g.reportStuck(entry)
+			}
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// reportStuck 记录卡住的请求
+func (g *Gateway) reportStuck(entry stuckRequest) {
+	request := entry.request
+	route := request.route
+	if route == "" {
+		route = "-"
+	}
+	upstream := request.upstream
+	if upstream == "" {
+		upstream = "-"
+	}
+
+	log.Printf("Stuck request on route %s upstream %s: %s %s running for %v (expected %v, canceled=%v)",
+		route, upstream, request.method, request.path, entry.elapsed.Round(time.Millisecond), entry.expected, entry.canceled)
+
+	if g.metrics != nil {
+		g.metrics.RecordStuckRequest(route, entry.canceled)
+	}
+
+	// 消息不含耗时，同一路由、上游的卡住请求聚到同一簇
+	event := &types.ErrorEvent{
+		TraceID:      request.traceID,
+		RequestPath:  request.path,
+		Method:       request.method,
+		ServiceName:  request.service,
+		Tenant:       request.tenant,
+		StatusCode:   http.StatusGatewayTimeout,
+		ErrorMessage: fmt.Sprintf("request stuck on route %s upstream %s: no response within expected duration", route, upstream),
+		Timestamp:    time.Now(),
+		SignalType:   types.SignalStuckRequest,
+	}
+	if err := g.errorSampler.SampleEvent(event); err != nil {
+		log.Printf("Failed to sample stuck request: %v", err)
+	}
+}
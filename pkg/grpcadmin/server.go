@@ -0,0 +1,155 @@
+package grpcadmin
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// Server gRPC管理端口，提供标准健康检查协议（grpc.health.v1）和服务反射。
+// 健康状态由组件汇总得出：服务名""和name表示整体就绪，"<name>.<组件名>"表示单个组件
+type Server struct {
+	name       string
+	config     *types.GRPCAdminConfig
+	components func() map[string]interface{}
+	server     *grpc.Server
+	health     *health.Server
+	listener   net.Listener
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewServer 创建gRPC管理端口，components为健康检查的组件来源
+func NewServer(name string, config *types.GRPCAdminConfig, components func() map[string]interface{}) *Server {
+	s := &Server{
+		name:       name,
+		config:     config,
+		components: components,
+		server:     grpc.NewServer(),
+		health:     health.NewServer(),
+		stopCh:     make(chan struct{}),
+	}
+
+	healthpb.RegisterHealthServer(s.server, s.health)
+	if config.Reflection {
+		reflection.Register(s.server)
+	}
+
+	// 首次检查前不对外宣告就绪
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	s.health.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	return s
+}
+
+// GRPCServer 获取底层gRPC服务，管理RPC需在Start之前注册
+func (s *Server) GRPCServer() *grpc.Server {
+	return s.server
+}
+
+// Start 监听管理端口并开始刷新健康状态
+func (s *Server) Start() error {
+	addr := net.JoinHostPort(strings.Trim(s.config.Host, "[]"), strconv.Itoa(s.config.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on grpc admin address %s: %v", addr, err)
+	}
+	s.listener = listener
+
+	s.refresh()
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+			log.Printf("gRPC admin server error: %v", err)
+		}
+	}()
+	go s.refreshLoop()
+
+	log.Printf("gRPC admin server started on %s (reflection: %v)", listener.Addr(), s.config.Reflection)
+	return nil
+}
+
+// Stop 先将所有服务置为NOT_SERVING，再等待进行中的调用完成
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+	close(s.stopCh)
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		// Watch流不会自行结束，超时后强制关闭
+		s.server.Stop()
+	}
+	s.wg.Wait()
+
+	log.Println("gRPC admin server stopped")
+}
+
+// Addr 获取实际监听地址，端口配置为0时由系统分配
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// refreshLoop 定期刷新健康状态
+func (s *Server) refreshLoop() {
+	defer s.wg.Done()
+
+	interval := s.config.CheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refresh()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// refresh 按组件状态更新健康检查服务，降级的组件仍视为可服务
+func (s *Server) refresh() {
+	statuses, ready := utils.CollectComponents(s.components())
+
+	for name, status := range statuses {
+		s.health.SetServingStatus(s.name+"."+name, servingStatus(status.Health.Status != types.ComponentUnhealthy))
+	}
+	s.health.SetServingStatus("", servingStatus(ready))
+	s.health.SetServingStatus(s.name, servingStatus(ready))
+}
+
+// servingStatus 转换为健康检查协议的状态
+func servingStatus(serving bool) healthpb.HealthCheckResponse_ServingStatus {
+	if serving {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
@@ -2,20 +2,53 @@ package interfaces
 
 import (
 	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/llm-aware-gateway/pkg/types"
 )
 
+// Component 统一的内部组件接口，供管理接口汇总展示和就绪检查
+type Component interface {
+	Health() types.ComponentHealth
+	Stats() map[string]interface{}
+}
+
 // RateLimiter 限流器接口
 type RateLimiter interface {
+	Component
 	Allow(ctx *gin.Context) bool
 	UpdatePolicy(clusterID string, policy *types.Policy) error
+	UpdateKeyLimit(keyID string, limit *types.APIKeyRateLimit) error // limit为nil时删除该密钥的覆盖
 	GetStats(clusterID string) (*types.ClusterStats, error)
 	Cleanup() error
 }
 
+// StatefulRateLimiter 支持状态交接的限流器
+type StatefulRateLimiter interface {
+	Snapshot() []types.BucketSnapshot
+	Restore(snapshots []types.BucketSnapshot) int
+}
+
+// UpstreamObserver 按上游延迟和结果自适应调整速率的限流器
+type UpstreamObserver interface {
+	ObserveUpstream(ctx *gin.Context, latency time.Duration, failed bool)
+}
+
+// StatsReporter 提供运行统计的组件
+type StatsReporter interface {
+	Stats() map[string]interface{}
+}
+
+// BreakerStatsSource 提供窗口熔断统计的熔断器
+type BreakerStatsSource interface {
+	// DrainBreakerStats 取出上次调用以来有变化的簇熔断统计并开始新的窗口
+	DrainBreakerStats() map[string]*types.BreakerWindowStats
+}
+
 // CircuitBreaker 熔断器接口
 type CircuitBreaker interface {
+	Component
 	Allow(ctx context.Context, clusterID string) bool
 	RecordSuccess(clusterID string) error
 	RecordFailure(clusterID string) error
@@ -25,7 +58,9 @@ type CircuitBreaker interface {
 
 // ErrorSampler 错误采样器接口
 type ErrorSampler interface {
+	Component
 	SampleError(ctx *gin.Context, err error) error
+	SampleEvent(event *types.ErrorEvent) error
 	Start() error
 	Stop() error
 }
@@ -33,19 +68,35 @@ type ErrorSampler interface {
 // VectorAgent 向量代理接口
 type VectorAgent interface {
 	IdentifyCluster(errorSignature string) (string, error)
+	IdentifyClusterWithScore(errorSignature string) (string, float64, error)
 	GenerateVector(text string) ([]float32, error)
 	UpdateClusters(clusters map[string]*types.Cluster) error
+	ApplySnapshot(snapshot *types.ClusterSnapshot) error
 }
 
 // ConfigWatcher 配置监听器接口
 type ConfigWatcher interface {
+	Component
 	WatchPolicyUpdates() error
 	GetPolicy(clusterID string) (*types.Policy, error)
 	RegisterCallback(callback PolicyUpdateCallback) error
+	WatchPrefix(prefix string, callback KeyUpdateCallback) error
 	Start() error
 	Stop() error
 }
 
+// KeyUpdateCallback 通用配置键变更回调，deleted为true时value为空
+type KeyUpdateCallback func(key string, value []byte, deleted bool)
+
+// Discovery 上游服务发现接口
+type Discovery interface {
+	Watch(config *types.UpstreamDiscoveryConfig, callback DiscoveryCallback) error
+	Stop() error
+}
+
+// DiscoveryCallback 服务实例变更回调，targets为当前全量实例
+type DiscoveryCallback func(targets []types.UpstreamTargetConfig)
+
 // PolicyUpdateCallback 策略更新回调接口
 type PolicyUpdateCallback interface {
 	OnPolicyUpdate(clusterID string, policy *types.Policy) error
@@ -57,16 +108,28 @@ type EmbeddingService interface {
 	EmbedText(text string) ([]float32, error)
 	EmbedBatch(texts []string) ([][]float32, error)
 	PreprocessText(text string) string
+	RulesetVersion() string
+	ModelInfo() types.EmbeddingModelInfo
+	SetPreprocessRules(rules []types.PreprocessRule) error
+	Close() error
 }
 
 // ClusteringEngine 聚类引擎接口
 type ClusteringEngine interface {
+	Component
 	ProcessErrorEvent(event *types.ErrorEvent) error
 	FindMostSimilarCluster(vector []float32) (string, float64, error)
 	CreateNewCluster(event *types.ErrorEvent, vector []float32) (string, error)
 	GetCluster(clusterID string) (*types.Cluster, error)
 	GetAllClusters() (map[string]*types.Cluster, error)
+	ListClusters(cursor string, limit int) ([]*types.Cluster, string, error)
 	ReCluster() error
+	ExplainCluster(clusterID string, topN int) (*types.ClusterExplanation, error)
+	ReEmbed(dryRun bool) (*types.ReEmbedReport, error)
+	ReclusterReport() *types.ReclusterReport
+	SummarizeClusters() (int, error)
+	CalibrateThresholds(apply bool) ([]*types.ThresholdCalibration, error)
+	ThresholdCalibrations() []*types.ThresholdCalibration
 	Start() error
 	Stop() error
 }
@@ -83,8 +146,60 @@ type PolicyEngine interface {
 	Stop() error
 }
 
+// JobLock 控制面周期任务的互斥锁，多实例部署时同一任务同时只有一个实例执行
+type JobLock interface {
+	// Run 获取锁后执行任务并返回true，锁被其他实例持有时跳过并返回false
+	Run(job string, fn func() error) (bool, error)
+}
+
+// JobScheduler 控制面周期任务调度
+type JobScheduler interface {
+	// Schedule 按cron表达式或"@every 间隔"注册任务，返回的stop停止触发并等待进行中的执行结束
+	Schedule(name, spec string, jitter time.Duration, run func() error) (stop func(), err error)
+	// Jobs 获取所有任务的最近一次执行和下一次执行时间
+	Jobs() []types.JobStatus
+}
+
+// GatewayMetricsStore 网关推送的簇计数快照存储
+type GatewayMetricsStore interface {
+	// Ingest 接收快照，已接收过的快照（重发）返回false
+	Ingest(snapshot *types.MetricsSnapshot) bool
+	// ClusterRates 计算簇在窗口内的速率，没有网关上报过该簇时返回false
+	ClusterRates(clusterID string, window time.Duration) (types.ClusterRates, bool)
+	// VersionRates 计算路由的上游版本在窗口内的速率，没有网关上报过该版本时返回false
+	VersionRates(route, version string, window time.Duration) (types.ClusterRates, bool)
+	// BreakerHistory 汇总网关上报的簇熔断统计，没有网关上报过该簇的熔断统计时返回false
+	BreakerHistory(clusterID string, window time.Duration) (types.BreakerHistory, bool)
+	Instances() []types.GatewayInstanceStatus
+}
+
+// IncidentNarrator 簇事件说明生成
+type IncidentNarrator interface {
+	Explain(clusterID string) (*types.IncidentNarrative, error)
+}
+
+// CanaryReporter 金丝雀分析结论来源
+type CanaryReporter interface {
+	// Verdicts 获取各路由最近一次分析的结论
+	Verdicts() []types.CanaryVerdict
+	// Analyze 立即分析路由并返回结论
+	Analyze(route string) (*types.CanaryVerdict, error)
+}
+
+// TimeSeriesStore 簇速率时序存储接口
+type TimeSeriesStore interface {
+	Record(clusterID string, at time.Time, count int64)
+	Range(clusterID string, from, to time.Time) []types.TimeSeriesPoint
+	Rate(clusterID string, window time.Duration) float64
+	GrowthRate(clusterID string, window time.Duration) float64
+	Clusters() []string
+	Start() error
+	Stop() error
+}
+
 // VectorDB 向量数据库接口
 type VectorDB interface {
+	Component
 	AddVector(id string, vector []float32) error
 	SearchSimilar(query []float32, topK int) ([]types.SearchResult, error)
 	GetVector(id string) ([]float32, error)
@@ -98,14 +213,47 @@ type ConfigStore interface {
 	Get(key string) (string, error)
 	Delete(key string) error
 	Watch(prefix string) (<-chan *ConfigChangeEvent, error)
+	GetWithPrefix(prefix string) (map[string]string, error)
 	Close() error
 }
 
+// Notifier 告警通知接口
+type Notifier interface {
+	Notify(event *types.AlertEvent)
+}
+
+// PolicyEffectivenessReporter 策略效果报告查询接口
+type PolicyEffectivenessReporter interface {
+	Reports(clusterID string) []*types.PolicyEffectivenessReport
+}
+
+// PolicyTemplateProvider 策略模板查询接口
+type PolicyTemplateProvider interface {
+	Templates() []types.PolicyTemplate
+}
+
+// PolicyEscalationReporter 策略升级事件查询接口
+type PolicyEscalationReporter interface {
+	Events(clusterID string) []types.EscalationEvent
+}
+
+// PolicyHistoryReporter 策略历史查询接口，clusterKey为空时返回所有簇
+type PolicyHistoryReporter interface {
+	ActiveAt(clusterKey, region string, at time.Time) []types.PolicyHistoryEntry
+	Timeline(clusterKey string, from, to time.Time) []types.PolicyHistoryEntry
+}
+
+// PolicyAuditReporter 策略巡检结果来源
+type PolicyAuditReporter interface {
+	LatestAudit() *types.PolicyAuditReport
+}
+
 // ConfigChangeEvent 配置变更事件
 type ConfigChangeEvent struct {
-	Type  ConfigChangeType
-	Key   string
-	Value string
+	Type     ConfigChangeType
+	Key      string
+	Value    string
+	Revision int64 // 变更的ETCD修订号，未知时为0
 }
 
 // ConfigChangeType 配置变更类型
@@ -124,12 +272,30 @@ type MetricsCollector interface {
 	UpdateClusterSize(clusterID string, size int64)
 	UpdateClusterSeverity(clusterID string, severity float64)
 	RecordPolicyApplied(clusterID string, policyType types.PolicyType)
+	RecordStreamOutcome(path, outcome string, events int64)
+	RecordClientCancel(path, stage string)
+	RecordUpstreamVersion(route, version, status string, duration float64)
+	RecordIPBlocked(reason string)
+	RecordWAFMatch(rule, action string)
+	RecordLLMUsage(model, tenant string, promptTokens, completionTokens int64, cost float64)
+	RecordSchemaViolation(route, direction string)
+	RecordClusterSnapshot(applied bool)
+	RecordSemanticCache(model, result string)
+	RecordStuckRequest(route string, canceled bool)
+	RecordPromptGuard(action string)
+	RecordRedaction(pattern string, count int)
+	RecordModerationViolation(inspector, category string)
+	RecordContextGuard(model, action string)
+	RecordStageIsolation(stage, reason string)
 }
 
 // Desensitizer 脱敏器接口
 type Desensitizer interface {
 	Desensitize(text string) string
+	DesensitizeCount(text string) (string, map[string]int)
 	AddPattern(name string, pattern string, replacement string)
+	RemovePattern(name string)
+	PatternNames() []string
 }
 
 // KafkaProducer Kafka生产者接口
@@ -147,6 +147,14 @@ var (
 		[]string{"policy_type"},
 	)
 
+	PolicyControlGaps = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_control_gaps",
+			Help: "Number of policy control-loop gaps found by the last audit",
+		},
+		[]string{"kind"},
+	)
+
 	// Kafka指标
 	KafkaMessagesProduced = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -313,6 +321,11 @@ func (m *MetricsCollector) RecordActivePolicies(policyType string, count int) {
 	ActivePolicies.WithLabelValues(policyType).Set(float64(count))
 }
 
+// RecordPolicyGaps 记录策略巡检发现的问题数
+func (m *MetricsCollector) RecordPolicyGaps(kind string, count int) {
+	PolicyControlGaps.WithLabelValues(kind).Set(float64(count))
+}
+
 // RecordKafka 记录Kafka指标
 func (m *MetricsCollector) RecordKafka(topic, group, operation, status string) {
 	switch operation {
@@ -6,28 +6,125 @@ import (
 
 // ErrorEvent 错误事件结构
 type ErrorEvent struct {
-	TraceID      string    `json:"trace_id"`
-	SpanID       string    `json:"span_id"`
-	RequestPath  string    `json:"request_path"`
-	Method       string    `json:"method"`
-	ServiceName  string    `json:"service_name"`
-	StatusCode   int       `json:"status_code"`
-	ErrorMessage string    `json:"error_message"`
-	StackTrace   []string  `json:"stack_trace"`
-	Timestamp    time.Time `json:"timestamp"`
-	EventID      string    `json:"event_id"`
+	TraceID        string       `json:"trace_id"`
+	SpanID         string       `json:"span_id"`
+	RequestPath    string       `json:"request_path"`
+	Method         string       `json:"method"`
+	ServiceName    string       `json:"service_name"`
+	Tenant         string       `json:"tenant,omitempty"`
+	StatusCode     int          `json:"status_code"`
+	ErrorMessage   string       `json:"error_message"`
+	StackTrace     []string     `json:"stack_trace"`
+	Timestamp      time.Time    `json:"timestamp"`
+	EventID        string       `json:"event_id"`
+	ClusterID      string       `json:"cluster_id,omitempty"`
+	RequestBody    string       `json:"request_body,omitempty"`
+	RulesetVersion string       `json:"ruleset_version,omitempty"` // 生成向量时使用的预处理规则集版本
+	SignalType     string       `json:"signal_type,omitempty"`     // 错误信号类型，为空时为普通请求错误
+	Route          string       `json:"route,omitempty"`           // 匹配的路由名
+	Version        string       `json:"version,omitempty"`         // 流量拆分选中的上游版本，用于金丝雀分析
+	Errors         []StageError `json:"errors,omitempty"`          // 处理链各阶段按发生顺序记录的错误和注解
+	Truncated      bool         `json:"truncated,omitempty"`       // 采样时因超出长度限制被截断
+}
+
+// StageError 请求处理链中某个阶段记录的错误或注解
+type StageError struct {
+	Stage      string `json:"stage"`
+	Message    string `json:"message"`
+	Annotation bool   `json:"annotation,omitempty"` // 注解不视为请求失败，如WAF标记、提示词降级
 }
 
+// 错误信号类型
+const (
+	SignalSchemaRequest  = "schema_request"  // 请求体不符合路由的Schema
+	SignalSchemaResponse = "schema_response" // 上游响应体不符合路由的Schema
+	SignalStuckRequest   = "stuck_request"   // 请求耗时超过路由预期时长的倍数，上游可能已挂起
+	SignalResponseError  = "response_error"  // 上游以成功状态码返回了包含错误的响应体
+)
+
 // Cluster 错误簇结构
 type Cluster struct {
-	ID          string      `json:"id"`
-	Centroid    []float32   `json:"centroid"`
-	Members     []string    `json:"members"`
-	ErrorCount  int64       `json:"error_count"`
-	CreateTime  time.Time   `json:"create_time"`
-	UpdateTime  time.Time   `json:"update_time"`
-	Severity    float64     `json:"severity"`
-	Description string      `json:"description"`
+	ID          string    `json:"id"`
+	Centroid    []float32 `json:"centroid"`
+	Members     []string  `json:"members"`
+	ErrorCount  int64     `json:"error_count"`
+	CreateTime  time.Time `json:"create_time"`
+	UpdateTime  time.Time `json:"update_time"`
+	Severity    float64   `json:"severity"`
+	Description string    `json:"description"`
+	Summary     string    `json:"summary,omitempty"`      // LLM生成的根因摘要
+	SummarySize int       `json:"summary_size,omitempty"` // 生成摘要时的成员数
+}
+
+// ClusterSnapshotKey 控制面发布簇快照的配置键
+const ClusterSnapshotKey = "/clusters/snapshot"
+
+// EmbeddingModelInfo 嵌入模型标识，质心只能与同一模型、同一维度生成的向量比较
+type EmbeddingModelInfo struct {
+	Model     string `json:"model"`
+	Version   string `json:"version"`
+	Dimension int    `json:"dimension"`
+}
+
+// ClusterSnapshot 控制面发布给网关的簇快照，携带生成质心的嵌入模型信息
+type ClusterSnapshot struct {
+	Embedding      EmbeddingModelInfo `json:"embedding"`
+	RulesetVersion string             `json:"ruleset_version"`
+	Clusters       []*Cluster         `json:"clusters"`             // 不含成员列表
+	Projection     *VectorProjection  `json:"projection,omitempty"` // 设置时质心为降维后的向量，网关先投影再匹配
+	GeneratedAt    time.Time          `json:"generated_at"`
+}
+
+// 质心降维方法
+const (
+	VectorReductionPCA    = "pca"    // 质心的主成分，质心落在投影空间内时点积不失真
+	VectorReductionRandom = "random" // 高斯随机投影，与簇无关，簇变化时投影矩阵不变
+)
+
+// VectorProjection 质心降维的投影矩阵，网关用它把本地生成的向量投影到质心所在的低维空间
+type VectorProjection struct {
+	Method    string `json:"method"`
+	InputDim  int    `json:"input_dim"`  // 嵌入模型的维度
+	OutputDim int    `json:"output_dim"` // 降维后的维度
+	Matrix    []byte `json:"matrix"`     // OutputDim×InputDim矩阵，按行以小端float32编码，控制快照大小
+}
+
+// ReEmbedReport 重新向量化任务结果
+type ReEmbedReport struct {
+	RulesetVersion string            `json:"ruleset_version"`
+	DryRun         bool              `json:"dry_run"`
+	Members        int               `json:"members"`  // 重新处理的成员数
+	Stale          int               `json:"stale"`    // 任务开始时规则集版本落后的成员数
+	Moved          int               `json:"moved"`    // 所属簇发生变化的成员数
+	Remapped       map[string]string `json:"remapped"` // 质心趋同被合并的簇：旧簇ID -> 保留的簇ID
+	Removed        []string          `json:"removed"`  // 成员全部迁出后删除的簇
+	StartTime      time.Time         `json:"start_time"`
+	EndTime        time.Time         `json:"end_time"`
+}
+
+// ClusterExplanation 簇相似度解释
+type ClusterExplanation struct {
+	Cluster        *Cluster           `json:"cluster"`
+	Threshold      float64            `json:"similarity_threshold"`
+	Neighbors      []ClusterNeighbor  `json:"neighbors"`          // 质心最相近的簇
+	Central        []MemberSimilarity `json:"central_members"`    // 最接近质心的成员
+	Peripheral     []MemberSimilarity `json:"peripheral_members"` // 最远离质心的成员
+	SampledMembers int                `json:"sampled_members"`
+}
+
+// ClusterNeighbor 相邻簇
+type ClusterNeighbor struct {
+	ClusterID   string  `json:"cluster_id"`
+	Similarity  float64 `json:"similarity"`
+	Description string  `json:"description"`
+	ErrorCount  int64   `json:"error_count"`
+}
+
+// MemberSimilarity 成员与质心的相似度
+type MemberSimilarity struct {
+	EventID    string  `json:"event_id"`
+	Signature  string  `json:"signature"`
+	Similarity float64 `json:"similarity"`
 }
 
 // PolicyType 策略类型
@@ -37,6 +134,15 @@ const (
 	RATE_LIMIT     PolicyType = "rate_limit"
 	CIRCUIT_BREAK  PolicyType = "circuit_break"
 	DEGRADE        PolicyType = "degrade"
+	WAF            PolicyType = "waf"
+)
+
+// 策略类型别名（数据面组件使用）
+const (
+	PolicyTypeRateLimit    = RATE_LIMIT
+	PolicyTypeCircuitBreak = CIRCUIT_BREAK
+	PolicyTypeDegrade      = DEGRADE
+	PolicyTypeWAF          = WAF
 )
 
 // Policy 策略结构
@@ -46,15 +152,68 @@ type Policy struct {
 	Severity      float64             `json:"severity"`
 	RateLimit     *RateLimitPolicy    `json:"rate_limit,omitempty"`
 	CircuitBreak  *CircuitBreakPolicy `json:"circuit_break,omitempty"`
+	WAF           *WAFPolicy          `json:"waf,omitempty"`
 	CreateTime    time.Time           `json:"create_time"`
 	ExpireTime    time.Time           `json:"expire_time"`
 	IsActive      bool                `json:"is_active"`
+	Version       int64               `json:"version,omitempty"` // 策略版本（ETCD修订号）
+	Regions       []string            `json:"regions,omitempty"` // 生效区域，为空时全局生效
+	Template      string              `json:"template,omitempty"` // 生成策略使用的模板名
+	Rationale     string              `json:"rationale,omitempty"` // LLM推荐策略的理由
 }
 
 // RateLimitPolicy 限流策略
 type RateLimitPolicy struct {
-	LimitRate float64       `json:"limit_rate"` // 限制比例 0.0-1.0
-	Duration  time.Duration `json:"duration"`
+	LimitRate   float64                 `json:"limit_rate"` // 限制比例 0.0-1.0
+	Duration    time.Duration           `json:"duration"`
+	Algorithm   string                  `json:"algorithm,omitempty"`   // token_bucket（默认）/ sliding_window / leaky_bucket
+	Window      time.Duration           `json:"window,omitempty"`      // 滑动窗口长度，默认1分钟，窗口内最多放行速率×窗口长度个请求
+	PerKey      bool                    `json:"per_key,omitempty"`     // 按API密钥分别限流，每个密钥独立使用策略速率，未携带密钥的请求共用簇限流器
+	Concurrency *ConcurrencyLimitConfig `json:"concurrency,omitempty"` // 簇在途请求上限，需开启limiter.concurrency
+	Adaptive    *AdaptiveRateConfig     `json:"adaptive,omitempty"`    // 按上游延迟和错误率自适应调整速率，策略速率作为初始速率
+}
+
+// AdaptiveRateConfig 自适应限流（AIMD）：每个调整周期统计簇请求的上游p95延迟和错误率，
+// 均未超过目标时速率加性增加，任一超过时乘性减少，使限流速率跟随上游实际容量
+type AdaptiveRateConfig struct {
+	TargetP95    time.Duration `yaml:"target_p95" json:"target_p95"`                   // 目标p95延迟，0表示只按错误率调整
+	MaxErrorRate float64       `yaml:"max_error_rate" json:"max_error_rate,omitempty"` // 错误率上限，默认0.05
+	Increase     float64       `yaml:"increase" json:"increase,omitempty"`             // 每个周期增加的速率（请求/秒），默认为初始速率的10%
+	Decrease     float64       `yaml:"decrease" json:"decrease,omitempty"`             // 超过目标时速率乘以该系数，默认0.7
+	MinRate      float64       `yaml:"min_rate" json:"min_rate,omitempty"`             // 速率下限，默认1
+	MaxRate      float64       `yaml:"max_rate" json:"max_rate,omitempty"`             // 速率上限，默认limiter.max_rate
+	Interval     time.Duration `yaml:"interval" json:"interval,omitempty"`             // 调整周期，默认5s
+	MinSamples   int           `yaml:"min_samples" json:"min_samples,omitempty"`       // 周期内样本数少于该值时保持速率，默认20
+}
+
+// AdaptiveRateStats 自适应限流最近一个调整周期的统计
+type AdaptiveRateStats struct {
+	P95        time.Duration `json:"p95"`
+	ErrorRate  float64       `json:"error_rate"`
+	Samples    int           `json:"samples"`
+	Action     string        `json:"action"` // increase / decrease / hold
+	AdjustedAt time.Time     `json:"adjusted_at"`
+}
+
+// APIKeyRateLimit 单个API密钥的限流覆盖，存放在etcd的"/ratelimits/keys/<密钥摘要>"，
+// 在簇限流之前检查，对该密钥的所有请求生效
+type APIKeyRateLimit struct {
+	Rate      float64       `json:"rate"`                // 每秒请求数
+	Burst     int64         `json:"burst,omitempty"`     // 令牌桶容量或漏桶排队上限，默认等于rate
+	Algorithm string        `json:"algorithm,omitempty"` // token_bucket（默认）/ sliding_window / leaky_bucket
+	Window    time.Duration `json:"window,omitempty"`    // sliding_window的窗口长度，默认1分钟
+}
+
+// 限流算法
+const (
+	RateLimitTokenBucket   = "token_bucket"   // 令牌桶，允许满桶突发
+	RateLimitSlidingWindow = "sliding_window" // 滑动窗口计数，窗口内的请求总数不超过上限，适合突发的批量任务
+	RateLimitLeakyBucket   = "leaky_bucket"   // 漏桶，按固定间隔放行，超出速率的请求排队等待，上游看不到突发
+)
+
+// WAFPolicy WAF规则策略，通过策略通道下发，键为"/policies/waf/<规则集名>"
+type WAFPolicy struct {
+	Rules []WAFRule `json:"rules"`
 }
 
 // CircuitBreakPolicy 熔断策略
@@ -72,11 +231,73 @@ const (
 	HALF_OPEN BreakerState = 2
 )
 
+// 熔断器状态别名（数据面组件使用）
+const (
+	BreakerStateClosed   = CLOSED
+	BreakerStateOpen     = OPEN
+	BreakerStateHalfOpen = HALF_OPEN
+)
+
+// ClusterStats 簇限流统计
+type ClusterStats struct {
+	ClusterID        string             `json:"cluster_id"`
+	TotalRequests    int64              `json:"total_requests"`
+	AllowedRequests  int64              `json:"allowed_requests"`
+	RejectedRequests int64              `json:"rejected_requests"`
+	CurrentRate      float64            `json:"current_rate"`
+	Tokens           int64              `json:"tokens"`
+	Capacity         int64              `json:"capacity"` // 滑动窗口为窗口内的请求上限，tokens为剩余可放行数
+	Severity         float64            `json:"severity"`
+	PolicyVersion    int64              `json:"policy_version,omitempty"`
+	Algorithm        string             `json:"algorithm,omitempty"`
+	Adaptive         *AdaptiveRateStats `json:"adaptive,omitempty"` // 自适应限流最近一次调整
+}
+
+// 检查请求的限流器
+const (
+	RateLimiterCluster     = "cluster"      // 簇限流器
+	RateLimiterPerKey      = "per_key"      // PerKey策略下按密钥的簇限流器
+	RateLimiterKeyOverride = "key_override" // API密钥的限流覆盖
+	RateLimiterAPIKey      = "api_key"      // API密钥的速率和配额限制
+	RateLimiterTenantQuota = "tenant_quota" // 租户分级配额
+)
+
+// RateLimitCheck 簇限流器检查请求时解析出的簇和策略，记录在请求上下文的"rate_limit_check"中，用于决策轨迹
+type RateLimitCheck struct {
+	ClusterID     string // 识别出的带命名空间的簇ID，密钥覆盖拒绝时为空
+	Algorithm     string // 检查请求的限流算法
+	PolicyVersion int64  // 簇限流策略的版本
+	Limiter       string // 检查请求的限流器，拒绝时即拒绝请求的限流器
+}
+
+// BucketSnapshot 令牌桶状态快照
+type BucketSnapshot struct {
+	ClusterID     string    `json:"cluster_id"`
+	Tokens        int64     `json:"tokens"`
+	Capacity      int64     `json:"capacity"`
+	Rate          float64   `json:"rate"`
+	PolicyVersion int64     `json:"policy_version,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// KeyBucketSnapshot API密钥令牌桶和配额消耗快照，密钥以摘要标识
+type KeyBucketSnapshot struct {
+	KeyID        string    `json:"key_id"`
+	Tokens       int64     `json:"tokens"`
+	Capacity     int64     `json:"capacity"`
+	Rate         float64   `json:"rate"`
+	QuotaUsed    int64     `json:"quota_used"`
+	QuotaResetAt time.Time `json:"quota_reset_at"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
 // BreakerConfig 熔断器配置
 type BreakerConfig struct {
-	FailureThreshold  int64         `json:"failure_threshold"`  // 失败次数阈值
-	RecoveryTimeout   time.Duration `json:"recovery_timeout"`   // 恢复超时时间
-	RecoveryIncrement float64       `json:"recovery_increment"` // 恢复增量 (20%)
+	FailureThreshold     int64         `json:"failure_threshold" yaml:"failure_threshold"`           // 失败次数阈值
+	RecoveryTimeout      time.Duration `json:"recovery_timeout" yaml:"recovery_timeout"`             // 恢复超时时间
+	RecoveryIncrement    float64       `json:"recovery_increment" yaml:"recovery_increment"`         // 恢复增量 (20%)
+	RecoveryInterval     time.Duration `json:"recovery_interval" yaml:"recovery_interval"`           // 每个恢复步的观察周期，健康时提升一个增量
+	RecoveryFailureRatio float64       `json:"recovery_failure_ratio" yaml:"recovery_failure_ratio"` // 恢复步内可容忍的失败率，超过则重新熔断
 }
 
 // SearchResult 搜索结果
@@ -88,22 +309,979 @@ type SearchResult struct {
 
 // GatewayConfig 网关配置
 type GatewayConfig struct {
-	Server       ServerConfig       `yaml:"server"`
-	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
-	CircuitBreak CircuitBreakConfig `yaml:"circuit_break"`
-	ErrorSampler ErrorSamplerConfig `yaml:"error_sampler"`
-	Kafka        KafkaConfig        `yaml:"kafka"`
-	ETCD         ETCDConfig         `yaml:"etcd"`
-	Redis        RedisConfig        `yaml:"redis"`
-	Monitoring   MonitoringConfig   `yaml:"monitoring"`
+	Server          ServerConfig        `yaml:"server"`
+	RateLimit       RateLimitConfig     `yaml:"rate_limit"`
+	CircuitBreak    CircuitBreakConfig  `yaml:"circuit_break"`
+	ErrorSampler    ErrorSamplerConfig  `yaml:"error_sampler"`
+	Kafka           KafkaConfig         `yaml:"kafka"`
+	ETCD            ETCDConfig          `yaml:"etcd"`
+	Redis           RedisConfig         `yaml:"redis"`
+	Monitoring      MonitoringConfig    `yaml:"monitoring"`
+	Routes          []RouteConfig       `yaml:"routes"`
+	Upstreams       []UpstreamConfig    `yaml:"upstreams"`
+	Breaker         BreakerConfig       `yaml:"breaker"`          // 簇熔断器
+	UpstreamBreaker *BreakerConfig      `yaml:"upstream_breaker"` // 上游实例熔断器，未配置时与簇熔断器参数相同
+	Soak            SoakConfig          `yaml:"soak"`
+	Decision        DecisionConfig      `yaml:"decision"`
+	Limiter         LimiterConfig       `yaml:"limiter"`
+	Sampler         SamplerConfig       `yaml:"sampler"`
+	TestHooks       TestHooksConfig     `yaml:"test_hooks"`
+	Shutdown        ShutdownConfig      `yaml:"shutdown"`
+	Cache           ResponseCacheConfig `yaml:"cache"`
+	Discovery       DiscoveryConfig     `yaml:"discovery"`
+	AccessLog       AccessLogConfig     `yaml:"access_log"`
+	IPFilter        IPFilterConfig      `yaml:"ip_filter"`
+	Region          string              `yaml:"region"` // 网关所在区域，只接收全局策略和本区域策略
+	WAF             WAFConfig           `yaml:"waf"`
+	LLM             LLMConfig           `yaml:"llm"`
+	Gossip          GossipConfig        `yaml:"gossip"`
+	Watchdog        WatchdogConfig      `yaml:"watchdog"`
+	PromptGuard     PromptGuardConfig   `yaml:"prompt_guard"`
+	MetricsExport   MetricsExportConfig `yaml:"metrics_export"`
+	Compression     CompressionConfig   `yaml:"compression"`
+	IDs             IDConfig            `yaml:"ids"`
+	Isolation       IsolationConfig     `yaml:"isolation"`
+	Usage           UsageConfig         `yaml:"usage"`
+	AdminGRPC       GRPCAdminConfig     `yaml:"admin_grpc"`
+	Admin           AdminConfig         `yaml:"admin"`
+}
+
+// AdminConfig 管理接口配置，管理接口与数据面共用端口
+type AdminConfig struct {
+	APIKeys []string `yaml:"api_keys"` // 修改运行时配置的管理接口所需的API密钥，支持${ENV}引用环境变量；为空时拒绝这些请求
+}
+
+// GRPCAdminConfig gRPC管理端口：标准gRPC健康检查协议（grpc.health.v1）和服务反射，
+// 供grpcurl、Kubernetes gRPC探针等通用工具直接使用
+type GRPCAdminConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Host          string        `yaml:"host"`
+	Port          int           `yaml:"port"`
+	Reflection    bool          `yaml:"reflection"`     // 开启服务反射，grpcurl无需proto文件即可调用
+	CheckInterval time.Duration `yaml:"check_interval"` // 组件健康状态的刷新周期，默认5s
+}
+
+// IsolationConfig 非关键阶段（簇识别、错误采样）的故障隔离：调用超时或panic时跳过该阶段继续转发，
+// 连续失败达到阈值后自动旁路一段时间
+type IsolationConfig struct {
+	Timeout          time.Duration `yaml:"timeout"`           // 单次调用超时，默认50ms
+	FailureThreshold int           `yaml:"failure_threshold"` // 连续超时或panic多少次后旁路，默认5
+	BypassDuration   time.Duration `yaml:"bypass_duration"`   // 旁路持续时间，到期后恢复调用，默认30s
+	MaxPending       int           `yaml:"max_pending"`       // 同时执行的调用上限（含超时后仍未返回的），达到后直接旁路，默认1000
+}
+
+// UsageConfig 用量核算：按租户、API密钥、模型、路由分时间桶累计请求、错误、拒绝、令牌和花费，
+// 通过/admin/usage查询任意时间范围的用量报表，用于结算和客户支持
+type UsageConfig struct {
+	Enabled     bool                     `yaml:"enabled"`
+	Resolution  time.Duration            `yaml:"resolution"`  // 时间桶大小，报表时间范围按时间桶对齐，默认1小时
+	Retention   time.Duration            `yaml:"retention"`   // 保留时长，默认90天
+	Persistence LimiterPersistenceConfig `yaml:"persistence"` // 用量增量定期累加到Redis，报表汇总所有副本
+}
+
+// 用量报表的分组维度
+const (
+	UsageGroupTenant = "tenant"
+	UsageGroupAPIKey = "api_key"
+	UsageGroupModel  = "model"
+	UsageGroupRoute  = "route"
+	UsageGroupTime   = "time" // 按时间桶
+	UsageGroupDay    = "day"  // 按UTC日
+)
+
+// UsageCounters 用量计数
+type UsageCounters struct {
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`     // 上游或处理失败的请求，不含网关拒绝
+	Rejections       int64   `json:"rejections"` // 被限流、熔断、预算等网关阶段拒绝的请求
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"` // 美元，按LLM费用核算的模型单价计算
+}
+
+// UsageRecord 用量报表的一行，未参与分组的维度为空
+type UsageRecord struct {
+	Start  *time.Time `json:"start,omitempty"` // 按时间分组时为时间桶或日的起点
+	Tenant string     `json:"tenant,omitempty"`
+	APIKey string     `json:"api_key,omitempty"` // 密钥摘要
+	Model  string     `json:"model,omitempty"`
+	Route  string     `json:"route,omitempty"`
+	UsageCounters
+}
+
+// UsageQuery 用量报表查询条件，过滤条件为空时不过滤
+type UsageQuery struct {
+	From    time.Time
+	To      time.Time
+	GroupBy []string
+	Tenant  string
+	APIKey  string
+	Model   string
+	Route   string
+}
+
+// IDConfig 事件ID、请求ID、簇ID和策略ID的生成方式
+type IDConfig struct {
+	Generator string `yaml:"generator"` // random（默认）/ ulid / snowflake，后两者按生成时间排序
+	NodeID    int64  `yaml:"node_id"`   // snowflake节点ID，1-1023，多实例需各不相同；0表示由主机名和进程号推导
+}
+
+// CompressionConfig 请求/响应体压缩处理：需要检查请求体或改写、校验响应体时解压gzip/deflate，
+// 响应按客户端Accept-Encoding重新压缩，不检查时原样透传
+type CompressionConfig struct {
+	Enabled              bool  `yaml:"enabled"`
+	MaxDecompressedBytes int64 `yaml:"max_decompressed_bytes"` // 解压后大小上限，默认10MB
+	MaxRatio             int64 `yaml:"max_ratio"`              // 解压后与压缩前大小之比上限，默认100，小体积（1MB以内）不受限
+	MinCompressBytes     int   `yaml:"min_compress_bytes"`     // 小于该值的响应不重新压缩，默认1024
+}
+
+// MetricsExportConfig 网关定期向控制面推送按簇汇总的请求、错误、拒绝计数增量，策略评估不依赖Prometheus
+type MetricsExportConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Transport string        `yaml:"transport"` // http / kafka，默认http
+	Endpoint  string        `yaml:"endpoint"`  // http传输的控制面地址，快照发送到{endpoint}/v1/metrics
+	APIKey    string        `yaml:"api_key"`   // 控制面API密钥
+	Topic     string        `yaml:"topic"`     // kafka传输的topic，使用kafka.brokers，默认gateway-metrics
+	Instance  string        `yaml:"instance"`  // 实例标识，默认主机名
+	Interval  time.Duration `yaml:"interval"`  // 推送间隔，默认10秒
+	Timeout   time.Duration `yaml:"timeout"`   // 单次推送超时，默认5秒
+}
+
+// 计数快照传输方式
+const (
+	MetricsExportHTTP  = "http"
+	MetricsExportKafka = "kafka"
+)
+
+// UnclusteredID 未识别簇的请求在计数快照中的键
+const UnclusteredID = "_unclustered"
+
+// MetricsSnapshot 网关推送的计数快照，计数为上一快照以来的增量，只包含有变化的簇
+type MetricsSnapshot struct {
+	Instance string                      `json:"instance"`
+	Started  time.Time                   `json:"started"` // 网关进程启动时间，重启后序号重新计数
+	Sequence uint64                      `json:"seq"`     // 进程内递增，推送失败重发时序号不变，控制面据此去重
+	Start    time.Time                   `json:"start"`
+	End      time.Time                   `json:"end"`
+	Clusters map[string]*ClusterCounters `json:"clusters"`
+	// Versions 配置了流量拆分的路由按上游版本的计数，路由名 -> 版本 -> 计数
+	Versions map[string]map[string]*ClusterCounters `json:"versions,omitempty"`
+	// Breakers 上一快照以来有变化的簇熔断器统计，簇ID -> 窗口统计
+	Breakers map[string]*BreakerWindowStats `json:"breakers,omitempty"`
+}
+
+// ClusterCounters 簇的请求计数
+type ClusterCounters struct {
+	Requests      int64 `json:"requests,omitempty"`
+	Errors        int64 `json:"errors,omitempty"`         // 上游或处理失败的请求，不含网关拒绝
+	Rejections    int64 `json:"rejections,omitempty"`     // 被限流、熔断、WAF等网关阶段拒绝的请求
+	PolicyVersion int64 `json:"policy_version,omitempty"` // 窗口内请求观察到的最新策略版本，未命中策略时为0
+}
+
+// ClusterRates 由网关计数快照计算的簇速率（每秒）
+type ClusterRates struct {
+	Requests    float64 `json:"requests"`
+	Errors      float64 `json:"errors"`
+	Rejections  float64 `json:"rejections"`
+	ErrorGrowth float64 `json:"error_growth"` // 最近窗口相对上一个窗口的错误速率增长比例
+}
+
+// BreakerWindowStats 簇熔断器在一个统计窗口内的增量，随计数快照推送到控制面
+type BreakerWindowStats struct {
+	Requests       int64        `json:"requests,omitempty"`
+	Failures       int64        `json:"failures,omitempty"`
+	Rejections     int64        `json:"rejections,omitempty"`  // 熔断器拒绝的请求
+	Trips          int64        `json:"trips,omitempty"`       // 熔断开启次数，含半开回退后的重新开启
+	Recoveries     int64        `json:"recoveries,omitempty"`  // 恢复到关闭状态的次数
+	OpenMillis     int64        `json:"open_ms,omitempty"`     // 窗口内处于开启或半开状态的时长
+	RecoveryMillis int64        `json:"recovery_ms,omitempty"` // 窗口内完成的恢复从开启到关闭的总时长
+	State          BreakerState `json:"state"`                 // 窗口结束时的状态
+}
+
+// BreakerHistory 由网关上报的熔断统计汇总的簇熔断历史，多个网关实例的统计累加
+type BreakerHistory struct {
+	Window              string  `json:"window"`
+	Trips               int64   `json:"trips"`
+	Recoveries          int64   `json:"recoveries"`
+	Rejections          int64   `json:"rejections"`
+	OpenSeconds         float64 `json:"open_seconds"`
+	MeanRecoverySeconds float64 `json:"mean_recovery_seconds"` // 完成的恢复从开启到关闭的平均时长
+	TripsPerHour        float64 `json:"trips_per_hour"`
+}
+
+// GatewayInstanceStatus 推送计数快照的网关实例
+type GatewayInstanceStatus struct {
+	Instance   string    `json:"instance"`
+	Started    time.Time `json:"started"`
+	Sequence   uint64    `json:"seq"`
+	LastSeen   time.Time `json:"last_seen"`
+	Snapshots  int64     `json:"snapshots"`
+	Duplicates int64     `json:"duplicates"` // 重发的已接收快照
+	Gaps       int64     `json:"gaps"`       // 序号跳跃次数，通常是快照丢失
+}
+
+// WatchdogConfig 长耗时请求检测：在途请求耗时超过路由预期时长的倍数时记录卡住事件并送入错误采样，
+// 用于发现不返回错误但一直挂起的上游连接
+type WatchdogConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Multiplier      float64       `yaml:"multiplier"`       // 预期时长的倍数，默认3
+	DefaultExpected time.Duration `yaml:"default_expected"` // 路由未配置expected_duration时的预期时长，为0时不检测这些路由
+	CheckInterval   time.Duration `yaml:"check_interval"`   // 检查间隔，默认1秒
+	Cancel          bool          `yaml:"cancel"`           // 取消卡住的请求，上游调用随之中断
+}
+
+// GossipConfig 副本间簇识别结果共享配置，通过Redis发布订阅传播错误签名到簇的映射
+type GossipConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Channel           string        `yaml:"channel"`             // Redis频道，默认gateway:cluster-signatures
+	TTL               time.Duration `yaml:"ttl"`                 // 学到的映射在本地缓存的时间，默认5分钟
+	MaxSignatureBytes int           `yaml:"max_signature_bytes"` // 超过该长度的签名不传播，默认4096
+	QueueSize         int           `yaml:"queue_size"`          // 待发布队列长度，满时丢弃，默认1024
+}
+
+// LLMConfig OpenAI兼容LLM代理配置，开启后提供/v1/chat/completions、/v1/completions、/v1/embeddings
+type LLMConfig struct {
+	Enabled         bool                `yaml:"enabled"`
+	Providers       []LLMProviderConfig `yaml:"providers"`
+	DefaultProvider string              `yaml:"default_provider"` // 没有提供方声明请求的模型时使用
+	Models          []LLMModelConfig    `yaml:"models"`           // 按请求体model字段的路由和默认参数，先精确匹配再按最长前缀匹配
+	TokenLimit      TokenLimitConfig    `yaml:"token_limit"`
+	Cost            CostConfig          `yaml:"cost"`
+	SemanticCache   SemanticCacheConfig `yaml:"semantic_cache"`
+	Redaction       RedactionConfig     `yaml:"redaction"`
+	Moderation      ModerationConfig    `yaml:"moderation"`
+	Tenants         []LLMTenantConfig   `yaml:"tenants"` // 租户的模型白名单和生成参数上限，违反时返回403
+	ContextGuard    ContextGuardConfig  `yaml:"context_guard"`
+	Dedup           LLMDedupConfig      `yaml:"dedup"`
+
+	// StreamEnforcement 流式响应按实时计量的输出令牌检查TPM余额和预算，超出时中途截断，
+	// 未开启时只计量，在响应结束（含客户端提前断开）后结算
+	StreamEnforcement bool `yaml:"stream_enforcement"`
+}
+
+// LLMModelConfig 模型路由配置
+type LLMModelConfig struct {
+	Name          string        `yaml:"name"`           // 模型名，支持"gpt-4*"前缀匹配和"*"
+	Provider      string        `yaml:"provider"`       // 指定提供方，未配置时按提供方声明的模型选择
+	UpstreamModel string        `yaml:"upstream_model"` // 转发给提供方的模型名（如特定部署或微调版本），未配置时不改写
+	Timeout       time.Duration `yaml:"timeout"`        // 请求总超时，流式请求只限制等待响应头的时间
+	Retries       int           `yaml:"retries"`        // 连接失败或提供方返回5xx时的重试次数，开始向客户端转发后不再重试
+	MaxTokens     int           `yaml:"max_tokens"`     // 请求未指定max_tokens时使用的默认值
+	SemanticCache bool          `yaml:"semantic_cache"` // 开启语义缓存，需同时启用llm.semantic_cache
+	CacheTTL      time.Duration `yaml:"cache_ttl"`      // 覆盖语义缓存的默认缓存时间
+	ContextWindow int           `yaml:"context_window"` // 模型上下文窗口（令牌数），启用llm.context_guard时检查，0表示不检查
+	Dedup         bool          `yaml:"dedup"`          // 合并同时进行的相同非流式请求，需同时启用llm.dedup
+}
+
+// LLMDedupConfig 相同请求合并：同一租户规范化后相同的非流式请求同时到达时只转发一次，
+// 其余请求等待并共享响应，避免客户端重试风暴重复调用上游。等待方同样经过预算和令牌限流检查，
+// 只共享2xx响应
+type LLMDedupConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	MaxWait time.Duration `yaml:"max_wait"` // 等待相同请求的最长时间，超时后自行转发，默认60秒
+}
+
+// ContextGuardConfig 上下文窗口检查：估算的输入令牌数加上请求的输出令牌数超出模型路由的context_window时，
+// 按action拒绝或截断请求，避免请求在上游失败后仍计入花费和错误聚类
+type ContextGuardConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	Action       string  `yaml:"action"`        // "reject"（默认）返回400 context_length_exceeded；"truncate"从最早的非system消息开始丢弃，仍放不下时拒绝
+	SafetyMargin float64 `yaml:"safety_margin"` // 令牌估算误差余量，占上下文窗口的比例，默认0.05
+}
+
+// LLMTenantConfig 租户的模型白名单和生成参数上限，租户取认证阶段识别的租户或X-Tenant-ID请求头
+type LLMTenantConfig struct {
+	Tenant         string   `yaml:"tenant"`          // 租户标识，"*"为未单独配置的租户（含未识别租户）的默认策略
+	Models         []string `yaml:"models"`          // 允许调用的模型，支持"gpt-4*"前缀匹配，为空时不限制
+	MaxTokens      int      `yaml:"max_tokens"`      // max_tokens和max_completion_tokens上限，0表示不限制
+	MaxTemperature *float64 `yaml:"max_temperature"` // temperature上限，未配置时不限制
+	MaxN           int      `yaml:"max_n"`           // 单次请求生成的候选数n上限，0表示不限制
+}
+
+// SemanticCacheConfig LLM语义缓存配置：提示词向量与已缓存提示词的相似度达到阈值时直接返回缓存的响应，
+// 只缓存开启了semantic_cache的模型路由上的非流式chat/completions请求，不同租户、模型和生成参数之间不共享
+type SemanticCacheConfig struct {
+	Enabled    bool            `yaml:"enabled"`
+	Threshold  float64         `yaml:"threshold"`   // 余弦相似度阈值，默认0.95
+	TTL        time.Duration   `yaml:"ttl"`         // 默认缓存时间，默认10分钟
+	MaxEntries int             `yaml:"max_entries"` // 缓存条目上限，满时淘汰最早过期的条目，默认10000
+	Embedding  EmbeddingConfig `yaml:"embedding"`   // 提示词向量化配置，预处理规则同样作用于提示词
+	VectorDB   VectorDBConfig  `yaml:"vector_db"`
+}
+
+// RedactionConfig LLM提示词脱敏配置：转发前替换消息内容、prompt和input中的敏感信息
+type RedactionConfig struct {
+	Enabled  bool               `yaml:"enabled"`
+	Builtin  []string           `yaml:"builtin"`  // 使用的内置规则：phone、email、creditcard、ip、token、uuid，默认phone、email、creditcard
+	Patterns []RedactionPattern `yaml:"patterns"` // 自定义规则，与内置规则同名时覆盖
+}
+
+// RedactionPattern 自定义脱敏规则
+type RedactionPattern struct {
+	Name        string `yaml:"name"`
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"` // 默认[REDACTED]
+}
+
+// ModerationConfig LLM响应内容审核配置，流式响应在违规事件转发前截断
+type ModerationConfig struct {
+	Enabled  bool                    `yaml:"enabled"`
+	Keywords KeywordModerationConfig `yaml:"keywords"`
+	API      ModerationAPIConfig     `yaml:"api"`
+}
+
+// KeywordModerationConfig 关键词黑名单审核
+type KeywordModerationConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Words    []string `yaml:"words"`    // 不区分大小写的关键词
+	Patterns []string `yaml:"patterns"` // 正则表达式
+}
+
+// ModerationAPIConfig 外部审核接口，请求和响应为OpenAI /v1/moderations格式
+type ModerationAPIConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	URL        string        `yaml:"url"`
+	APIKey     string        `yaml:"api_key"` // 支持${ENV}引用环境变量
+	Model      string        `yaml:"model"`
+	Timeout    time.Duration `yaml:"timeout"`     // 默认5秒
+	Interval   int           `yaml:"interval"`    // 流式响应每累计多少字符调用一次，默认500，结束时总会调用
+	FailClosed bool          `yaml:"fail_closed"` // 接口调用失败时按违规处理，默认放行
+}
+
+// CostConfig LLM费用核算配置，按API密钥、租户、模型累计日、月花费
+type CostConfig struct {
+	Enabled     bool                     `yaml:"enabled"`
+	Pricing     map[string]ModelPricing  `yaml:"pricing"`     // 模型 -> 单价，支持"gpt-4*"前缀匹配和"*"
+	Budgets     []CostBudgetConfig       `yaml:"budgets"`     // 花费上限，超出后请求被拒绝直到周期结束
+	Persistence LimiterPersistenceConfig `yaml:"persistence"` // 花费定期累加到Redis，副本之间共享
+}
+
+// ModelPricing 模型单价（美元/千令牌）
+type ModelPricing struct {
+	PromptPer1K     float64 `yaml:"prompt_per_1k" json:"prompt_per_1k"`
+	CompletionPer1K float64 `yaml:"completion_per_1k" json:"completion_per_1k"`
+}
+
+// 费用核算维度
+const (
+	CostScopeAPIKey = "api_key"
+	CostScopeTenant = "tenant"
+	CostScopeModel  = "model"
+)
+
+// 预算周期，按UTC对齐
+const (
+	CostPeriodDay   = "day"
+	CostPeriodMonth = "month"
+)
+
+// CostBudgetConfig 花费上限
+type CostBudgetConfig struct {
+	Scope  string  `yaml:"scope" json:"scope"`   // api_key / tenant / model
+	ID     string  `yaml:"id" json:"id"`         // 维度取值（API密钥为摘要），"*"表示每个取值各自一份预算
+	Limit  float64 `yaml:"limit" json:"limit"`   // 美元
+	Period string  `yaml:"period" json:"period"` // day / month，默认month
+}
+
+// TokenLimitConfig 按令牌数的限流配置，值为每分钟令牌数（TPM），"*"为未单独配置时的默认值，0或未配置表示不限制
+type TokenLimitConfig struct {
+	Enabled  bool             `yaml:"enabled"`
+	Clusters map[string]int64 `yaml:"clusters"` // 簇ID -> TPM
+	APIKeys  map[string]int64 `yaml:"api_keys"` // API密钥摘要（utils.APIKeyID）-> TPM
+	Models   map[string]int64 `yaml:"models"`   // 模型 -> TPM，支持"gpt-4*"前缀匹配
+	IdleTTL  time.Duration    `yaml:"idle_ttl"` // 令牌桶空闲超过该时间后清理，默认10分钟
+}
+
+// LLM提供方类型
+const (
+	LLMProviderOpenAI    = "openai"
+	LLMProviderAzure     = "azure"
+	LLMProviderAnthropic = "anthropic"
+	LLMProviderVLLM      = "vllm"
+)
+
+// LLMProviderConfig LLM提供方配置
+type LLMProviderConfig struct {
+	Name        string            `yaml:"name"`
+	Type        string            `yaml:"type"`        // openai / azure / anthropic / vllm
+	BaseURL     string            `yaml:"base_url"`    // openai/vllm为包含/v1的地址，azure为资源地址，anthropic默认https://api.anthropic.com
+	APIKey      string            `yaml:"api_key"`     // 支持${ENV}引用环境变量
+	APIVersion  string            `yaml:"api_version"` // azure的api-version或anthropic-version
+	Models      []string          `yaml:"models"`      // 提供的模型，支持"gpt-4*"前缀匹配和"*"
+	Deployments map[string]string `yaml:"deployments"` // azure模型名到部署名的映射，未配置时部署名等于模型名
+	Timeout     time.Duration     `yaml:"timeout"`     // 等待响应头的超时，默认60秒
+}
+
+// WAFConfig WAF配置
+type WAFConfig struct {
+	Enabled        bool      `yaml:"enabled"`
+	AllowedMethods []string  `yaml:"allowed_methods"` // 允许的请求方法，为空时不限制
+	MaxHeaders     int       `yaml:"max_headers"`     // 请求头数量上限，0表示不限制
+	MaxBodyBytes   int64     `yaml:"max_body_bytes"`  // 请求体检查的字节数上限，默认64KB
+	Rules          []WAFRule `yaml:"rules"`
+}
+
+// WAF规则动作
+const (
+	WAFActionBlock = "block"
+	WAFActionTag   = "tag"
+)
+
+// WAFRule WAF规则，所有配置的条件同时满足时命中
+type WAFRule struct {
+	ID      string            `yaml:"id" json:"id"`
+	Action  string            `yaml:"action" json:"action"`   // block（默认）：返回403；tag：放行并通过X-WAF-Tags标记
+	Methods []string          `yaml:"methods" json:"methods"` // 只检查这些方法，为空时检查全部
+	Path    string            `yaml:"path" json:"path"`       // 路径及查询串正则
+	Headers map[string]string `yaml:"headers" json:"headers"` // 请求头值正则
+	Body    string            `yaml:"body" json:"body"`       // 请求体正则
+}
+
+// PromptGuardConfig 提示词注入检测配置：正则规则和已知攻击样本的向量相似度共同打分，
+// 分数达到flag_threshold时标记，达到block_threshold时执行action
+type PromptGuardConfig struct {
+	Enabled        bool              `yaml:"enabled"`
+	Paths          []string          `yaml:"paths"`           // 检查的路径前缀，默认/v1/
+	MaxBodyBytes   int64             `yaml:"max_body_bytes"`  // 检查的请求体字节数上限，超过时不检查，默认1MB
+	FlagThreshold  float64           `yaml:"flag_threshold"`  // 标记阈值，默认0.5
+	BlockThreshold float64           `yaml:"block_threshold"` // 执行动作的阈值，默认0.8
+	Action         string            `yaml:"action"`          // block（默认）、flag或downgrade
+	DowngradeModel string            `yaml:"downgrade_model"` // downgrade时改写请求的模型
+	Rules          []PromptGuardRule `yaml:"rules"`           // 为空时使用内置规则
+	Similarity     PromptSimilarity  `yaml:"similarity"`
+}
+
+// 提示词注入检测动作
+const (
+	PromptGuardActionBlock     = "block"
+	PromptGuardActionFlag      = "flag"
+	PromptGuardActionDowngrade = "downgrade"
+)
+
+// PromptGuardRule 提示词注入正则规则，多条规则命中时分数按1-Π(1-score)合并
+type PromptGuardRule struct {
+	ID      string  `yaml:"id" json:"id"`
+	Pattern string  `yaml:"pattern" json:"pattern"` // 不区分大小写
+	Score   float64 `yaml:"score" json:"score"`     // 0到1，默认0.5
+}
+
+// PromptSimilarity 已知攻击样本相似度检测，样本在启动时向量化写入向量库
+type PromptSimilarity struct {
+	Enabled   bool            `yaml:"enabled"`
+	Threshold float64         `yaml:"threshold"` // 余弦相似度达到阈值时以相似度计分，默认0.85
+	Attacks   []string        `yaml:"attacks"`   // 已知攻击样本
+	Embedding EmbeddingConfig `yaml:"embedding"`
+	VectorDB  VectorDBConfig  `yaml:"vector_db"`
+}
+
+// IPFilterConfig IP访问控制配置，etcd键/ipfilter/allow、/ipfilter/deny存在时替换对应名单
+type IPFilterConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Allow   []string `yaml:"allow"` // 允许的CIDR或IP，为空时不限制
+	Deny    []string `yaml:"deny"`  // 拒绝的CIDR或IP，优先于允许名单
+}
+
+// AccessLogConfig 访问日志配置
+type AccessLogConfig struct {
+	Format     string                `yaml:"format"`      // combined（默认）/ json
+	Fields     []string              `yaml:"fields"`      // JSON格式输出的字段，为空时输出全部
+	BufferSize int                   `yaml:"buffer_size"` // 异步写入缓冲条数，满时丢弃
+	Sinks      []AccessLogSinkConfig `yaml:"sinks"`       // 为空时输出到标准输出
+}
+
+// AccessLogSinkConfig 访问日志输出端配置
+type AccessLogSinkConfig struct {
+	Type       string `yaml:"type"`        // stdout / file / kafka
+	Path       string `yaml:"path"`        // file：日志文件路径
+	MaxSizeMB  int    `yaml:"max_size_mb"` // file：单个文件大小上限，默认100
+	MaxBackups int    `yaml:"max_backups"` // file：保留的轮转文件数，0表示不清理
+	Topic      string `yaml:"topic"`       // kafka：使用全局kafka.brokers
+}
+
+// ResponseCacheConfig 响应缓存配置
+type ResponseCacheConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Backend      string        `yaml:"backend"` // memory / redis
+	KeyPrefix    string        `yaml:"key_prefix"`
+	DefaultTTL   time.Duration `yaml:"default_ttl"`    // 上游未返回max-age时的缓存时长
+	MaxTTL       time.Duration `yaml:"max_ttl"`        // 缓存时长上限
+	StaleIfError time.Duration `yaml:"stale_if_error"` // 过期后上游出错时仍可返回缓存的时长
+	MaxEntries   int           `yaml:"max_entries"`
+	MaxBodyBytes int64         `yaml:"max_body_bytes"`
+	Vary         []string      `yaml:"vary"` // 参与缓存键的请求头
+}
+
+// RouteCacheConfig 路由级响应缓存配置
+type RouteCacheConfig struct {
+	TTL  time.Duration `yaml:"ttl"`
+	Vary []string      `yaml:"vary"`
+}
+
+// ShutdownConfig 停止配置
+type ShutdownConfig struct {
+	ReportFile   string        `yaml:"report_file"`   // 运行报告输出文件，为空时只写日志
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // 等待在途请求完成的最长时间
+	DrainDelay   time.Duration `yaml:"drain_delay"`   // 开始排空后继续监听的时间，等待负载均衡摘除实例
+}
+
+// 组件健康状态
+const (
+	ComponentHealthy   = "healthy"
+	ComponentDegraded  = "degraded"  // 仍可服务，但部分功能受限，如降级为内存模式
+	ComponentUnhealthy = "unhealthy" // 无法正常工作，网关未就绪
+)
+
+// ComponentHealth 组件健康状态
+type ComponentHealth struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ComponentStatus 组件的健康状态和运行统计
+type ComponentStatus struct {
+	Health ComponentHealth        `json:"health"`
+	Stats  map[string]interface{} `json:"stats,omitempty"`
+}
+
+// ConfigChange 配置项的变更，值为JSON形式，新增时Old为空，删除时New为空
+type ConfigChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// ConfigRevision 一次运行时配置变更，source为变更来源，如"admin"、etcd键或"policy:<簇ID>"
+type ConfigRevision struct {
+	Time    time.Time      `json:"time"`
+	Source  string         `json:"source"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// ShutdownReport 网关停止时的运行报告，汇总各组件的最终统计
+type ShutdownReport struct {
+	StartTime  time.Time                         `json:"start_time"`
+	StopTime   time.Time                         `json:"stop_time"`
+	Uptime     string                            `json:"uptime"`
+	Components map[string]map[string]interface{} `json:"components"`
+}
+
+// TestHooksConfig 测试钩子配置，仅在使用testhooks构建标签时生效
+type TestHooksConfig struct {
+	Enabled bool        `yaml:"enabled"`
+	Faults  []FaultRule `yaml:"faults"` // 启动时加载的故障注入规则，运行时可通过/admin/faults调整
+}
+
+// FaultRule 故障注入规则，延迟可与中止或错误状态码叠加
+type FaultRule struct {
+	ID         string        `yaml:"id" json:"id"`
+	Routes     []string      `yaml:"routes" json:"routes"`           // 生效的路由名，与PathPrefix都为空时对所有请求生效
+	PathPrefix string        `yaml:"path_prefix" json:"path_prefix"` // 生效的路径前缀
+	Percentage float64       `yaml:"percentage" json:"percentage"`   // 注入比例（0-100）
+	Delay      time.Duration `yaml:"delay" json:"delay"`             // 注入延迟
+	Abort      bool          `yaml:"abort" json:"abort"`             // 直接断开连接，不返回响应
+	Status     int           `yaml:"status" json:"status"`           // 返回的错误状态码
+	Duration   time.Duration `yaml:"duration" json:"duration"`       // 生效时长，到期自动移除，0表示一直生效
+}
+
+// LimiterConfig 簇限流器配置
+type LimiterConfig struct {
+	DefaultRate     float64                  `yaml:"default_rate"`
+	MaxRate         float64                  `yaml:"max_rate"`
+	CleanupInterval time.Duration            `yaml:"cleanup_interval"`
+	Handoff         LimiterHandoffConfig     `yaml:"handoff"`
+	APIKeys         APIKeyLimitConfig        `yaml:"api_keys"`    // 按API密钥的速率和配额限制
+	Tenants         TenantQuotaConfig        `yaml:"tenants"`     // 租户→服务→路由的分级配额
+	Concurrency     ClusterConcurrencyConfig `yaml:"concurrency"` // 按簇限制在途请求数
+}
+
+// ConcurrencyLimitConfig 并发限制：在途请求达到上限后新请求进入有界等待队列，队列已满或排队超时返回429
+type ConcurrencyLimitConfig struct {
+	MaxConcurrent int64         `yaml:"max_concurrent" json:"max_concurrent"` // 在途请求上限，0表示不限制
+	MaxQueue      int64         `yaml:"max_queue" json:"max_queue"`           // 等待队列长度，0表示不排队直接拒绝
+	QueueTimeout  time.Duration `yaml:"queue_timeout" json:"queue_timeout"`   // 排队超时，默认1s
+}
+
+// ClusterConcurrencyConfig 按簇的并发限制，适合延迟较高、QPS不能反映负载的LLM上游。
+// 簇由熔断阶段识别，未识别簇或跳过熔断的请求不受约束；限流策略中的concurrency覆盖配置
+type ClusterConcurrencyConfig struct {
+	Enabled  bool                              `yaml:"enabled"`
+	Default  ConcurrencyLimitConfig            `yaml:"default"`  // 未单独配置的簇各自按该限制计算
+	Clusters map[string]ConcurrencyLimitConfig `yaml:"clusters"` // 簇ID -> 并发限制
+	IdleTTL  time.Duration                     `yaml:"idle_ttl"` // 无在途请求的簇空闲超过该时间后清理，默认10m
+}
+
+// TenantQuotaConfig 租户分级配额：租户的配额约束其下所有服务和路由，服务的配额约束其下所有路由，
+// 每个请求依次检查租户、服务（路由的上游）、路由三级，任一级用尽即拒绝。未识别租户的请求不受约束
+type TenantQuotaConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Period  time.Duration `yaml:"period"`   // 配额周期，默认24h，按UTC对齐
+	IdleTTL time.Duration `yaml:"idle_ttl"` // 节点空闲超过该时间且配额周期结束后清理，默认1h
+	Tenants []TenantQuota `yaml:"tenants"`
+}
+
+// TenantQuota 租户配额，Quota和Rate为0表示该级不限制
+type TenantQuota struct {
+	Tenant   string         `yaml:"tenant"` // "*"为未单独配置的租户的默认配额，每个租户分别计算
+	Quota    int64          `yaml:"quota"`  // 每个配额周期的请求数
+	Rate     float64        `yaml:"rate"`   // 每秒请求数
+	Burst    int64          `yaml:"burst"`  // 突发容量，默认等于rate
+	Services []ServiceQuota `yaml:"services"`
+}
+
+// ServiceQuota 租户在某个服务（上游）上的配额，超过租户配额的部分按租户配额生效
+type ServiceQuota struct {
+	Service string       `yaml:"service"` // 上游名
+	Quota   int64        `yaml:"quota"`
+	Rate    float64      `yaml:"rate"`
+	Burst   int64        `yaml:"burst"`
+	Routes  []RouteQuota `yaml:"routes"`
+}
+
+// RouteQuota 租户在某个路由上的配额，超过服务配额的部分按服务配额生效
+type RouteQuota struct {
+	Route string  `yaml:"route"` // 路由名
+	Quota int64   `yaml:"quota"`
+	Rate  float64 `yaml:"rate"`
+	Burst int64   `yaml:"burst"`
+}
+
+// QuotaDecision 分级配额的检查结果，Scope为拒绝请求的层级或剩余配额最少的层级
+type QuotaDecision struct {
+	Allowed   bool
+	Reason    string    // 拒绝原因：rate / quota
+	Scope     string    // 层级路径，如"tenant:acme/service:llm/route:chat"
+	Limit     int64     // Scope所在层级的周期配额，未配置配额时为0
+	Remaining int64     // Scope所在层级的剩余配额
+	ResetAt   time.Time // 配额周期结束时间
+}
+
+// RateLimitQuota 簇限流器对请求的检查结果，网关据此返回X-RateLimit-*和Retry-After响应头
+type RateLimitQuota struct {
+	Limit      int64         // 令牌桶容量、滑动窗口的请求上限或漏桶的排队上限
+	Remaining  int64         // 检查后的剩余量
+	Reset      time.Time     // 剩余量按当前速率恢复到上限的时间
+	RetryAfter time.Duration // 被拒绝时建议的重试等待时长
+}
+
+// APIKeyLimitConfig API密钥限流和配额配置
+type APIKeyLimitConfig struct {
+	Enabled     bool                     `yaml:"enabled"`
+	Rate        float64                  `yaml:"rate"`         // 每个密钥每秒请求数，0表示不限速
+	Burst       int64                    `yaml:"burst"`        // 突发容量，默认等于rate
+	Quota       int64                    `yaml:"quota"`        // 每个配额周期的请求数，0表示不限配额
+	QuotaPeriod time.Duration            `yaml:"quota_period"` // 配额周期，默认24h
+	IdleTTL     time.Duration            `yaml:"idle_ttl"`     // 密钥空闲超过该时间且配额周期结束后清理，默认1h
+	Persistence LimiterPersistenceConfig `yaml:"persistence"`
+}
+
+// LimiterPersistenceConfig 令牌桶和配额的持久化配置，定期写入Redis，启动时合并恢复
+type LimiterPersistenceConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Key      string        `yaml:"key"`      // Redis哈希键
+	Interval time.Duration `yaml:"interval"` // 持久化间隔，默认30s
+}
+
+// LimiterHandoffConfig 限流状态交接配置
+type LimiterHandoffConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Key     string        `yaml:"key"` // Redis哈希键
+	TTL     time.Duration `yaml:"ttl"` // 状态有效期，超过后新副本不再采用
+}
+
+// DecisionConfig 决策轨迹配置
+type DecisionConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`         // 轨迹保留时间
+	MaxEntries int           `yaml:"max_entries"` // 最大保留条数
+}
+
+// SoakConfig 流量形态录制配置
+type SoakConfig struct {
+	CaptureEnabled bool          `yaml:"capture_enabled"`
+	CaptureFile    string        `yaml:"capture_file"`
+	FlushInterval  time.Duration `yaml:"flush_interval"`
+}
+
+// RouteConfig 路由配置
+type RouteConfig struct {
+	Name           string                `yaml:"name"`
+	Host           string                `yaml:"host"` // 按Host头路由，支持"*.example.com"
+	PathPrefix     string                `yaml:"path_prefix"`
+	Upstream       string                `yaml:"upstream"`
+	Namespace      string                `yaml:"namespace"`   // 簇命名空间，策略键为"/policies/<namespace>/<cluster_id>"
+	Headers        map[string]string     `yaml:"headers"`     // 请求头条件，值为"*"时只要求存在，"xxx*"按前缀匹配
+	Query          map[string]string     `yaml:"query"`       // 查询参数条件
+	BodyFields     map[string]string     `yaml:"body_fields"` // JSON请求体字段条件，键为点分路径（如"model"、"metadata.tier"）
+	Rewrite        RewriteConfig         `yaml:"rewrite"`
+	Mirror         *MirrorConfig         `yaml:"mirror"`
+	Body           BodyConfig            `yaml:"body"`
+	Cache          *RouteCacheConfig     `yaml:"cache"`             // 开启响应缓存，需同时开启全局cache.enabled
+	Timeout        time.Duration         `yaml:"timeout"`           // 请求总超时，扣除网关内耗时后作为截止时间传递给上游
+	Expected       time.Duration         `yaml:"expected_duration"` // 预期耗时，超过watchdog.multiplier倍时视为卡住的请求
+	Splits         []RouteSplitConfig    `yaml:"splits"`            // 按权重拆分到多个上游版本，运行时可通过管理API或etcd调整
+	Middleware     RouteMiddlewareConfig `yaml:"middleware"`        // 路由级中间件链，未配置时使用全局中间件
+	Transform      TransformConfig       `yaml:"transform"`         // 请求/响应转换规则，适配客户端与上游的接口差异
+	Schema         SchemaConfig          `yaml:"schema"`            // 请求/响应体JSON Schema校验
+	ResponseErrors ResponseErrorConfig   `yaml:"response_errors"`   // 识别以成功状态码返回的错误响应体
+}
+
+// ResponseErrorConfig 响应体错误识别：部分上游以200返回{"error": ...}，命中规则的响应照常返回客户端，
+// 计为上游失败（熔断、实例异常检测）并以响应体中的错误消息进入错误采样
+type ResponseErrorConfig struct {
+	Rules        []ResponseErrorRule `yaml:"rules"`
+	MaxBodyBytes int64               `yaml:"max_body_bytes"` // 读取的响应体上限，默认64KB，超过时JSON规则不生效
+}
+
+// ResponseErrorRule 响应体错误规则，path和pattern至少配置一个
+type ResponseErrorRule struct {
+	Path    string `yaml:"path"`    // JSON点分路径，字段存在且非空时视为错误，字段值作为错误消息（对象取message字段）
+	Pattern string `yaml:"pattern"` // 正则，配置path时匹配字段值，否则匹配响应体；有捕获组时以第一个捕获组作为错误消息
+}
+
+// SchemaConfig 路由的JSON Schema校验配置，值为Schema文件路径，以"{"开头时为内联Schema
+type SchemaConfig struct {
+	Request      string `yaml:"request"`        // 请求体Schema，校验失败返回400
+	Response     string `yaml:"response"`       // 非流式JSON响应体Schema，校验失败照常返回，只计入错误采样
+	MaxBodyBytes int64  `yaml:"max_body_bytes"` // 校验的请求体上限，超过时返回413，默认1MB
+}
+
+// TransformConfig 路由的请求/响应转换规则
+type TransformConfig struct {
+	RequestHeaders  HeaderTransformConfig `yaml:"request_headers"`  // 转发给上游前的请求头转换
+	UpstreamAuth    *UpstreamAuthConfig   `yaml:"upstream_auth"`    // 上游认证头，覆盖客户端的同名请求头
+	ResponseHeaders HeaderTransformConfig `yaml:"response_headers"` // 返回给客户端前的响应头转换
+	ResponseBody    []JSONFieldRule       `yaml:"response_body"`    // JSON响应体字段改写，流式响应不改写
+}
+
+// HeaderTransformConfig 请求头转换，依次执行重命名、删除、设置
+type HeaderTransformConfig struct {
+	Rename map[string]string `yaml:"rename"` // 原名 -> 新名
+	Remove []string          `yaml:"remove"`
+	Set    map[string]string `yaml:"set"` // 值支持${ENV}引用环境变量
+}
+
+// 上游认证方式
+const (
+	UpstreamAuthBearer = "bearer"
+	UpstreamAuthBasic  = "basic"
+	UpstreamAuthHeader = "header"
+)
+
+// UpstreamAuthConfig 上游认证配置，凭据支持${ENV}引用环境变量
+type UpstreamAuthConfig struct {
+	Type     string `yaml:"type"`   // bearer / basic / header
+	Header   string `yaml:"header"` // type为header时的请求头名，如"x-api-key"
+	Token    string `yaml:"token"`  // bearer令牌或header的值
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// JSON字段改写操作
+const (
+	JSONFieldSet    = "set"
+	JSONFieldRemove = "remove"
+	JSONFieldRename = "rename"
+)
+
+// JSONFieldRule JSON响应体字段改写规则，路径以"."分隔，数字段表示数组下标，如"choices.0.text"
+type JSONFieldRule struct {
+	Op    string `yaml:"op"`    // set / remove / rename
+	Path  string `yaml:"path"`  // 字段路径
+	To    string `yaml:"to"`    // rename的目标路径
+	Value string `yaml:"value"` // set的值，按JSON解析，非法JSON时作为字符串
+}
+
+// RouteMiddlewareConfig 路由级中间件配置
+type RouteMiddlewareConfig struct {
+	Skip        []string                `yaml:"skip"`        // 跳过的全局中间件：auth / waf / cache / rate_limit / concurrency / circuit_breaker / error_sampling / metrics
+	RateLimit   *RouteRateLimitConfig   `yaml:"rate_limit"`  // 路由级限流，在簇限流之前执行
+	Concurrency *ConcurrencyLimitConfig `yaml:"concurrency"` // 路由级并发限制，在熔断之后执行
+}
+
+// RouteRateLimitConfig 路由级限流配置
+type RouteRateLimitConfig struct {
+	Rate  float64 `yaml:"rate"`  // 每秒请求数
+	Burst int64   `yaml:"burst"` // 突发容量，默认等于rate
+}
+
+// RouteSplitConfig 路由流量拆分配置
+type RouteSplitConfig struct {
+	Upstream string `yaml:"upstream" json:"upstream"`
+	Version  string `yaml:"version" json:"version"` // 指标中的版本标签，默认为上游名
+	Weight   int    `yaml:"weight" json:"weight"`
+}
+
+// 请求体模式
+const (
+	BodyModeStream = "stream" // 边读边转发，适合大体积LLM请求
+	BodyModeBuffer = "buffer" // 预先读入内存，错误采样可附带请求体
+)
+
+// BodyConfig 请求体限制配置
+type BodyConfig struct {
+	MaxBytes int64  `yaml:"max_bytes"` // 请求体上限，超过返回413，0表示不限制
+	Mode     string `yaml:"mode"`      // stream / buffer，默认stream
+}
+
+// MirrorConfig 影子流量配置
+type MirrorConfig struct {
+	Upstream     string        `yaml:"upstream"`       // 影子上游
+	Percentage   float64       `yaml:"percentage"`     // 镜像比例（0-100）
+	Timeout      time.Duration `yaml:"timeout"`        // 影子请求超时
+	MaxBodyBytes int64         `yaml:"max_body_bytes"` // 请求体超过该大小时不镜像
+}
+
+// RewriteConfig 转发前的请求改写规则
+type RewriteConfig struct {
+	StripPrefix string `yaml:"strip_prefix"` // 去除的路径前缀
+	AddPrefix   string `yaml:"add_prefix"`   // 追加的路径前缀
+	Regex       string `yaml:"regex"`        // 路径正则，按Replacement替换（支持$1引用）
+	Replacement string `yaml:"replacement"`
+	Host        string `yaml:"host"` // 改写上游请求的Host头
+}
+
+// UpstreamConfig 上游服务配置
+type UpstreamConfig struct {
+	Name      string                   `yaml:"name"`
+	Protocol  string                   `yaml:"protocol"` // "http"(默认), "h2", "h2c" 或 "grpc"
+	Targets   []UpstreamTargetConfig   `yaml:"targets"`
+	Health    HealthWeightConfig       `yaml:"health"`
+	Outlier   OutlierDetectionConfig   `yaml:"outlier"`
+	TLS       UpstreamTLSConfig        `yaml:"tls"`
+	Transport UpstreamTransportConfig  `yaml:"transport"`
+	Discovery *UpstreamDiscoveryConfig `yaml:"discovery"` // 开启后实例列表由注册中心动态维护，targets可为空
+}
+
+// UpstreamDiscoveryConfig 上游服务发现配置
+type UpstreamDiscoveryConfig struct {
+	Provider  string `yaml:"provider"`  // etcd / kubernetes
+	Service   string `yaml:"service"`   // etcd中对应"<etcd_prefix><service>/"下的实例，Kubernetes中为Service名称
+	Namespace string `yaml:"namespace"` // Kubernetes命名空间，为空时使用discovery.kubernetes.namespace
+	Port      string `yaml:"port"`      // Kubernetes端口名，为空时使用第一个端口
+	Scheme    string `yaml:"scheme"`    // 实例URL协议，默认http
+}
+
+// DiscoveryConfig 服务发现配置
+type DiscoveryConfig struct {
+	ETCDPrefix string                    `yaml:"etcd_prefix"` // 实例注册键前缀，默认"/services/"
+	Kubernetes KubernetesDiscoveryConfig `yaml:"kubernetes"`
+}
+
+// KubernetesDiscoveryConfig Kubernetes服务发现配置，未设置时使用Pod内ServiceAccount访问API Server
+type KubernetesDiscoveryConfig struct {
+	APIServer     string        `yaml:"api_server"`
+	TokenFile     string        `yaml:"token_file"`
+	CAFile        string        `yaml:"ca_file"`
+	Namespace     string        `yaml:"namespace"`
+	RetryInterval time.Duration `yaml:"retry_interval"` // watch断开后的重连间隔
+}
+
+// UpstreamTransportConfig 上游连接池配置，未设置的项使用默认值
+type UpstreamTransportConfig struct {
+	MaxIdleConns          int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost       int           `yaml:"max_conns_per_host"` // 0表示不限制
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"` // 0表示不限制
+	DialTimeout           time.Duration `yaml:"dial_timeout"`
+	KeepAlive             time.Duration `yaml:"keep_alive"` // TCP keep-alive间隔，HTTP/2下同时作为PING探测间隔
+
+	TLSSessionCacheSize        int               `yaml:"tls_session_cache_size"`        // TLS会话缓存容量，复用会话减少握手，0使用默认值，负数关闭
+	StrictMaxConcurrentStreams bool              `yaml:"strict_max_concurrent_streams"` // HTTP/2并发流达到服务端上限时排队等待而非新建连接
+	PingTimeout                time.Duration     `yaml:"ping_timeout"`                  // HTTP/2 PING无响应时关闭连接
+	DNSRefreshInterval         time.Duration     `yaml:"dns_refresh_interval"`          // 定期重新解析上游域名，地址变化时关闭空闲连接，0表示不刷新
+	DNS                        UpstreamDNSConfig `yaml:"dns"`
+	IPFamily                   string            `yaml:"ip_family"` // 连接上游使用的地址族："ipv4"、"ipv6"，为空时双栈交替尝试
+}
+
+// UpstreamDNSConfig 上游内置域名解析配置
+type UpstreamDNSConfig struct {
+	Enabled     bool          `yaml:"enabled"`     // 开启后按记录TTL异步刷新，取代dns_refresh_interval
+	Nameservers []string      `yaml:"nameservers"` // 为空时读取/etc/resolv.conf
+	MinTTL      time.Duration `yaml:"min_ttl"`     // TTL下限，避免过于频繁的查询
+	MaxTTL      time.Duration `yaml:"max_ttl"`     // TTL上限，保证地址变化能及时生效
+}
+
+// UpstreamTLSConfig 上游mTLS配置
+type UpstreamTLSConfig struct {
+	CertFile       string        `yaml:"cert_file"` // 客户端证书
+	KeyFile        string        `yaml:"key_file"`
+	CAFile         string        `yaml:"ca_file"`       // 校验上游证书的CA，为空时使用系统CA
+	ServerName     string        `yaml:"server_name"`   // 覆盖SNI和证书校验的主机名
+	PinnedSHA256   []string      `yaml:"pinned_sha256"` // 上游公钥SHA256指纹（base64）
+	MinVersion     string        `yaml:"min_version"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// UpstreamTargetConfig 上游实例配置
+type UpstreamTargetConfig struct {
+	URL    string `yaml:"url" json:"url"`
+	Weight int    `yaml:"weight" json:"weight"`
+}
+
+// HealthWeightConfig 健康加权配置
+type HealthWeightConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	LatencyAlpha    float64       `yaml:"latency_alpha"`     // 延迟EWMA平滑系数
+	DegradeRatio    float64       `yaml:"degrade_ratio"`     // 延迟超过池中位数该倍数时进入降权
+	RecoverRatio    float64       `yaml:"recover_ratio"`     // 延迟回落到该倍数以下时退出降权
+	MinWeightFactor float64       `yaml:"min_weight_factor"` // 最小权重系数
+	HalfOpenFactor  float64       `yaml:"half_open_factor"`  // 熔断半开时的权重系数
+	RecoverStep     float64       `yaml:"recover_step"`      // 每次重算时权重系数的最大回升幅度
+	UpdateInterval  time.Duration `yaml:"update_interval"`   // 权重重算间隔
+	BreakDuration   time.Duration `yaml:"break_duration"`    // 实例熔断时长
+}
+
+// OutlierDetectionConfig 异常实例驱逐配置，驱逐期间实例不参与负载均衡
+type OutlierDetectionConfig struct {
+	Enabled            bool          `yaml:"enabled"`
+	ConsecutiveErrors  int           `yaml:"consecutive_errors"`   // 连续失败达到该次数时驱逐，默认5
+	LatencyPercentile  float64       `yaml:"latency_percentile"`   // 延迟分位数，默认0.99
+	LatencyThreshold   time.Duration `yaml:"latency_threshold"`    // 分位延迟超过该值时驱逐，0表示不按延迟驱逐
+	WindowSize         int           `yaml:"window_size"`          // 延迟样本窗口，默认100
+	MinSamples         int           `yaml:"min_samples"`          // 按延迟判定所需的最少样本数，默认20
+	BaseEjectionTime   time.Duration `yaml:"base_ejection_time"`   // 驱逐时长，按驱逐次数倍增，默认30s
+	MaxEjectionTime    time.Duration `yaml:"max_ejection_time"`    // 驱逐时长上限，默认5m
+	MaxEjectionPercent int           `yaml:"max_ejection_percent"` // 同时驱逐的实例比例上限，默认10，至少允许驱逐1个
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
+	Host         string        `yaml:"host"`    // 监听地址，IPv6地址可不带方括号，如"::"
+	Network      string        `yaml:"network"` // "tcp"（默认，监听"::"或空地址时为双栈）、"tcp4"、"tcp6"（仅IPv6）
 	Port         int           `yaml:"port"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	EnableH2C    bool           `yaml:"enable_h2c"` // 明文HTTP/2（gRPC客户端直连时需要）
+	TLS          TLSConfig      `yaml:"tls"`
+	ClientIP     ClientIPConfig `yaml:"client_ip"`
+	ReusePort    bool           `yaml:"reuse_port"` // 开启SO_REUSEPORT，允许新进程在旧进程排空时绑定同一端口
+}
+
+// ClientIPConfig 真实客户端IP提取配置，用于限流、GeoIP和日志
+type ClientIPConfig struct {
+	TrustedProxies []string `yaml:"trusted_proxies"` // 可信代理IP或CIDR，只有来自可信代理的请求才读取IP请求头
+	Headers        []string `yaml:"headers"`         // 按顺序读取的请求头，如X-Forwarded-For、X-Real-IP、CF-Connecting-IP
+}
+
+// TLSConfig 监听器TLS配置
+type TLSConfig struct {
+	Enabled        bool             `yaml:"enabled"`
+	CertFile       string           `yaml:"cert_file"`
+	KeyFile        string           `yaml:"key_file"`
+	MinVersion     string           `yaml:"min_version"`   // "1.2" 或 "1.3"
+	CipherSuites   []string         `yaml:"cipher_suites"` // 为空时使用Go默认套件
+	SNI            []SNICertConfig  `yaml:"sni"`           // 按虚拟主机选择证书
+	ReloadInterval time.Duration    `yaml:"reload_interval"`
+}
+
+// SNICertConfig 虚拟主机证书配置
+type SNICertConfig struct {
+	Host     string `yaml:"host"` // 支持 *.example.com 通配
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
 }
 
 // RateLimitConfig 限流配置
@@ -127,11 +1305,38 @@ type ErrorSamplerConfig struct {
 	MaxQueueSize int     `yaml:"max_queue_size"`
 }
 
+// SamplerConfig 网关错误采样器配置
+type SamplerConfig struct {
+	SamplingRate     float64 `yaml:"sampling_rate"`
+	BufferSize       int     `yaml:"buffer_size"`
+	MaxBodyCapture   int     `yaml:"max_body_capture"`   // 缓冲模式路由随错误事件采集的请求体长度
+	MaxMessageLength int     `yaml:"max_message_length"` // 错误消息和各阶段错误消息的最大字节数，默认4096
+	MaxStackFrames   int     `yaml:"max_stack_frames"`   // 保留的最大堆栈帧数，默认10
+	MaxEventSize     int     `yaml:"max_event_size"`     // 序列化后事件的最大字节数，默认65536，超出时依次丢弃请求体、阶段错误、堆栈帧
+}
+
 // KafkaConfig Kafka配置
 type KafkaConfig struct {
-	Brokers []string `yaml:"brokers"`
-	Topic   string   `yaml:"topic"`
-	GroupID string   `yaml:"group_id"`
+	Brokers     []string              `yaml:"brokers"`
+	Topic       string                `yaml:"topic"` // 默认topic，支持模板
+	GroupID     string                `yaml:"group_id"`
+	TopicRoutes []TopicRouteConfig    `yaml:"topic_routes"` // 采样器按顺序匹配的topic路由规则
+	Consumers   []TopicConsumerConfig `yaml:"consumers"`    // 控制面消费的topic，为空时只消费默认topic
+}
+
+// TopicRouteConfig topic路由规则，匹配条件为空表示任意值
+// Topic支持{service}、{severity}、{tenant}占位符
+type TopicRouteConfig struct {
+	Service  string `yaml:"service"`
+	Severity string `yaml:"severity"` // critical / client / other
+	Tenant   string `yaml:"tenant"`
+	Topic    string `yaml:"topic"`
+}
+
+// TopicConsumerConfig 控制面topic消费配置
+type TopicConsumerConfig struct {
+	Topic       string `yaml:"topic"`
+	Concurrency int    `yaml:"concurrency"` // 该topic的并发处理数
 }
 
 // ETCDConfig ETCD配置
@@ -159,29 +1364,408 @@ type MonitoringConfig struct {
 
 // ControlPlaneConfig 控制面配置
 type ControlPlaneConfig struct {
-	Embedding EmbeddingConfig `yaml:"embedding"`
-	Clustering ClusteringConfig `yaml:"clustering"`
-	VectorDB  VectorDBConfig  `yaml:"vector_db"`
-	Policy    PolicyConfig    `yaml:"policy"`
-	Kafka     KafkaConfig     `yaml:"kafka"`
-	ETCD      ETCDConfig      `yaml:"etcd"`
-	Storage   StorageConfig   `yaml:"storage"`
+	Embedding      EmbeddingConfig         `yaml:"embedding"`
+	Clustering     ClusteringConfig        `yaml:"clustering"`
+	VectorDB       VectorDBConfig          `yaml:"vector_db"`
+	Policy         PolicyConfig            `yaml:"policy"`
+	Kafka          KafkaConfig             `yaml:"kafka"`
+	ETCD           ETCDConfig              `yaml:"etcd"`
+	Storage        StorageConfig           `yaml:"storage"`
+	TimeSeries     TimeSeriesConfig        `yaml:"time_series"`
+	API            ControlPlaneAPIConfig   `yaml:"api"`
+	Effectiveness  EffectivenessConfig     `yaml:"effectiveness"`
+	Alerting       AlertingConfig          `yaml:"alerting"`
+	JobLock        JobLockConfig           `yaml:"job_lock"`
+	GatewayMetrics GatewayMetricsConfig    `yaml:"gateway_metrics"`
+	Canary         CanaryConfig            `yaml:"canary"`
+	Narrative      IncidentNarrativeConfig `yaml:"narrative"`
+	IDs            IDConfig                `yaml:"ids"` // 簇ID和事件ID的生成方式，多个控制面实例使用snowflake时需配置不同节点ID
+}
+
+// IncidentNarrativeConfig 簇事件说明配置：汇总簇成员、近期指标和已下发的策略，由LLM生成事件说明供值班人员查看
+type IncidentNarrativeConfig struct {
+	Enabled  bool            `yaml:"enabled"`
+	LLM      LLMClientConfig `yaml:"llm"`
+	Window   time.Duration   `yaml:"window"`    // 近期指标窗口，默认5分钟
+	Samples  int             `yaml:"samples"`   // 发送的代表性错误数，默认5
+	CacheTTL time.Duration   `yaml:"cache_ttl"` // 同一簇的说明缓存时长，默认1分钟
+}
+
+// IncidentNarrative 簇事件说明及生成说明所用的上下文
+type IncidentNarrative struct {
+	ClusterID   string            `json:"cluster_id"`
+	Narrative   string            `json:"narrative"`
+	Description string            `json:"description"`
+	Summary     string            `json:"summary,omitempty"`
+	ErrorCount  int64             `json:"error_count"`
+	Members     int               `json:"members"`
+	Window      string            `json:"window"`
+	ErrorRate   float64           `json:"error_rate"` // 每秒错误数
+	GrowthRate  float64           `json:"growth_rate"`
+	RequestRate *float64          `json:"request_rate,omitempty"` // 网关上报的每秒请求数
+	Policy      *Policy           `json:"policy,omitempty"`       // 当前下发的策略
+	Escalations []EscalationEvent `json:"escalations,omitempty"`
+	Samples     []string          `json:"samples"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// CanaryConfig 金丝雀分析配置：按路由比较基线版本和金丝雀版本错误事件的簇构成，
+// 结合网关计数快照中的版本错误率发布通过/失败结论，供持续部署系统轮询
+type CanaryConfig struct {
+	Enabled        bool                `yaml:"enabled"`
+	Interval       time.Duration       `yaml:"interval"`        // 分析间隔，默认1分钟
+	Window         time.Duration       `yaml:"window"`          // 分析窗口，默认15分钟
+	MinEvents      int                 `yaml:"min_events"`      // 金丝雀错误事件少于该数量时不比较簇构成，默认20
+	MaxNovelShare  float64             `yaml:"max_novel_share"` // 金丝雀错误中基线没有的簇占比上限，默认0.2
+	MaxDistance    float64             `yaml:"max_distance"`    // 两个版本簇分布的总变差距离上限，默认0.5
+	MatchThreshold float64             `yaml:"match_threshold"` // 簇质心余弦相似度达到该值时视为同一类错误，默认0.9
+	ErrorBudget    float64             `yaml:"error_budget"`    // 金丝雀错误率可高出基线的绝对值，需网关推送计数快照，默认0.01
+	MaxEvents      int                 `yaml:"max_events"`      // 每个版本保留的事件数上限，默认10000
+	Routes         []CanaryRouteConfig `yaml:"routes"`
+}
+
+// CanaryRouteConfig 参与金丝雀分析的路由
+type CanaryRouteConfig struct {
+	Route    string `yaml:"route"`
+	Baseline string `yaml:"baseline"` // 基线版本，默认stable
+	Canary   string `yaml:"canary"`   // 金丝雀版本，默认canary
+}
+
+// 金丝雀分析结论
+const (
+	CanaryPass         = "pass"
+	CanaryFail         = "fail"
+	CanaryInconclusive = "inconclusive" // 金丝雀错误事件不足且没有版本错误率可供判断
+)
+
+// CanaryVerdict 金丝雀分析结论
+type CanaryVerdict struct {
+	Route             string               `json:"route"`
+	Baseline          string               `json:"baseline"`
+	Canary            string               `json:"canary"`
+	Verdict           string               `json:"verdict"`
+	Reasons           []string             `json:"reasons,omitempty"`
+	BaselineEvents    int64                `json:"baseline_events"`
+	CanaryEvents      int64                `json:"canary_events"`
+	NovelShare        float64              `json:"novel_share"` // 金丝雀错误中基线没有的簇占比
+	Distance          float64              `json:"distance"`    // 两个版本簇分布的总变差距离，0为相同，1为完全不同
+	BaselineErrorRate *float64             `json:"baseline_error_rate,omitempty"`
+	CanaryErrorRate   *float64             `json:"canary_error_rate,omitempty"`
+	NovelClusters     []CanaryClusterShare `json:"novel_clusters,omitempty"`
+	Window            string               `json:"window"`
+	AnalyzedAt        time.Time            `json:"analyzed_at"`
+}
+
+// CanaryClusterShare 金丝雀版本中的簇及其占比
+type CanaryClusterShare struct {
+	ClusterID   string  `json:"cluster_id"`
+	Description string  `json:"description,omitempty"`
+	Count       int64   `json:"count"`
+	Share       float64 `json:"share"`
+}
+
+// GatewayMetricsConfig 控制面接收网关计数快照的配置，快照通过/v1/metrics或Kafka接入
+type GatewayMetricsConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Resolution time.Duration `yaml:"resolution"` // 时间桶大小，默认1分钟
+	Retention  time.Duration `yaml:"retention"`  // 保留时长，默认6小时
+	Topic      string        `yaml:"topic"`      // 从Kafka消费快照的topic，为空时只通过HTTP接收
+	// BreakerRetention 熔断统计历史在PostgreSQL中的保留时长，默认30天，未配置storage.postgresql时只保存在内存
+	BreakerRetention time.Duration `yaml:"breaker_retention"`
+}
+
+// JobLockConfig 控制面周期任务（重聚类、策略评估、时序压缩）的分布式锁配置，
+// 未启用时只在进程内防止任务重叠
+type JobLockConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Redis     RedisConfig   `yaml:"redis"`
+	KeyPrefix string        `yaml:"key_prefix"` // 默认controlplane:lock:
+	TTL       time.Duration `yaml:"ttl"`        // 锁过期时间，任务执行期间自动续期，默认30秒
+}
+
+// JobSchedule 控制面周期任务的调度配置，未配置cron时按任务原有的固定间隔执行
+type JobSchedule struct {
+	Cron   string        `yaml:"cron"`   // 5段cron表达式（分 时 日 月 周），或@hourly、@daily、"@every 30s"
+	Jitter time.Duration `yaml:"jitter"` // 每次触发前随机延迟[0, jitter)，避免多个实例或任务同时执行
+}
+
+// JobStatus 周期任务状态
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"`
+	Running      bool          `json:"running"`
+	Runs         int64         `json:"runs"`
+	Failures     int64         `json:"failures"`
+	Skipped      int64         `json:"skipped"` // 上一次执行未结束而跳过的触发次数
+	LastRun      time.Time     `json:"last_run,omitempty"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run,omitempty"`
+}
+
+// AlertingConfig 告警通知配置
+type AlertingConfig struct {
+	Enabled   bool                 `yaml:"enabled"`
+	Channels  []AlertChannelConfig `yaml:"channels"`
+	Templates map[string]string    `yaml:"templates"`  // 按事件类型的消息模板（text/template，数据为AlertEvent），"default"为缺省模板
+	Timeout   time.Duration        `yaml:"timeout"`    // 单次发送超时，默认5秒
+	QueueSize int                  `yaml:"queue_size"` // 待发送队列长度，满时丢弃，默认256
+}
+
+// 告警通知渠道类型
+const (
+	AlertChannelSlack    = "slack"
+	AlertChannelDingTalk = "dingtalk"
+	AlertChannelFeishu   = "feishu"
+	AlertChannelWeCom    = "wecom"
+)
+
+// AlertChannelConfig 告警通知渠道配置
+type AlertChannelConfig struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`         // slack / dingtalk / feishu / wecom
+	WebhookURL  string   `yaml:"webhook_url"`  // 机器人webhook地址
+	Secret      string   `yaml:"secret"`       // 钉钉/飞书机器人的加签密钥，未开启加签时为空
+	Events      []string `yaml:"events"`       // 订阅的事件类型，为空时订阅全部
+	MinSeverity string   `yaml:"min_severity"` // 最低告警级别：info / warning / critical，默认info
+}
+
+// 告警级别
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// AlertEvent 告警事件
+type AlertEvent struct {
+	Type     string            `json:"type"`     // 事件类型，如policy_ineffective
+	Severity string            `json:"severity"` // info / warning / critical
+	Title    string            `json:"title"`
+	Message  string            `json:"message"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// EffectivenessConfig 策略效果评估配置
+type EffectivenessConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Window           time.Duration `yaml:"window"`             // 策略生效前、失效后的对比窗口，默认15分钟
+	CheckInterval    time.Duration `yaml:"check_interval"`     // 检查到期策略的间隔，默认30秒
+	GatewayAdminURLs []string      `yaml:"gateway_admin_urls"` // 网关管理接口地址，策略失效时采集拦截量
+	MaxReports       int           `yaml:"max_reports"`        // 内存中保留的报告数，默认500
+}
+
+// PolicyEffectivenessReport 策略效果报告，速率单位为每秒错误事件数
+type PolicyEffectivenessReport struct {
+	ClusterID       string     `json:"cluster_id"`
+	PolicyKey       string     `json:"policy_key"`
+	Region          string     `json:"region,omitempty"` // 区域策略所属区域，全局策略为空
+	PolicyType      PolicyType `json:"policy_type"`
+	PolicyVersion   int64      `json:"policy_version,omitempty"`
+	Severity        float64    `json:"severity"`
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         time.Time  `json:"end_time"`
+	ErrorRateBefore float64    `json:"error_rate_before"`
+	ErrorRateDuring float64    `json:"error_rate_during"`
+	ErrorRateAfter  float64    `json:"error_rate_after"`
+	Reduction       float64    `json:"reduction"`  // 生效期间错误速率下降比例
+	Recurrence      float64    `json:"recurrence"` // 失效后错误速率相对生效前的比例
+	ErrorsAvoided   int64      `json:"errors_avoided"`
+	// 网关上报的策略期间请求量，未配置网关地址时为0
+	TrafficTotal   int64 `json:"traffic_total"`
+	TrafficBlocked int64 `json:"traffic_blocked"`
+	// 被拦截请求占比，作为对用户影响的估计
+	EstimatedUserImpact float64   `json:"estimated_user_impact"`
+	Verdict             string    `json:"verdict"` // effective / ineffective / inconclusive
+	GeneratedAt         time.Time `json:"generated_at"`
+}
+
+// ControlPlaneAPIConfig 控制面HTTP服务配置
+type ControlPlaneAPIConfig struct {
+	Host            string          `yaml:"host"`
+	Port            int             `yaml:"port"`
+	APIKeys         []string        `yaml:"api_keys"`          // 接入和管理接口的API密钥
+	MaxBatchSize    int             `yaml:"max_batch_size"`    // 单次推送的最大事件数
+	IngestQueueSize int             `yaml:"ingest_queue_size"` // 接入队列长度，满时拒绝
+	IngestWorkers   int             `yaml:"ingest_workers"`
+	Export          ExportConfig    `yaml:"export"` // 管理端批量导出
+	GRPC            GRPCAdminConfig `yaml:"grpc"`   // gRPC管理端口
+}
+
+// ExportConfig 管理端批量导出配置：簇、策略和最近事件按页读取并以NDJSON流式返回，
+// 每页读取时只短暂持有锁，写出速度受客户端接收速度和导出速率共同限制
+type ExportConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	PageSize      int     `yaml:"page_size"`       // 每页记录数，默认200
+	RatePerSecond float64 `yaml:"rate_per_second"` // 单个导出每秒写出的记录数上限，默认1000
+	MaxConcurrent int     `yaml:"max_concurrent"`  // 同时进行的导出数，超出时返回429，默认2
+	EventBuffer   int     `yaml:"event_buffer"`    // 保留供导出的最近事件数，默认10000
+}
+
+// TimeSeriesConfig 簇速率时序配置
+type TimeSeriesConfig struct {
+	Resolution      time.Duration `yaml:"resolution"`       // 时间桶粒度，默认1分钟
+	Retention       time.Duration `yaml:"retention"`        // 保留时长，默认6小时
+	PersistInterval time.Duration `yaml:"persist_interval"` // 快照持久化间隔
+}
+
+// TimeSeriesPoint 时序数据点
+type TimeSeriesPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Count     int64     `json:"count"`
 }
 
 // EmbeddingConfig 向量化配置
 type EmbeddingConfig struct {
-	ModelPath  string `yaml:"model_path"`
-	BatchSize  int    `yaml:"batch_size"`
-	CacheSize  int    `yaml:"cache_size"`
-	Dimension  int    `yaml:"dimension"`
+	Provider        string                  `yaml:"provider"`      // "mock"（默认）、"openai"（OpenAI兼容的/v1/embeddings接口）或"onnx"（本地ONNX模型）
+	ModelPath       string                  `yaml:"model_path"`    // onnx时为模型目录（含model.onnx和vocab.txt）或.onnx文件路径
+	ModelName       string                  `yaml:"model_name"`    // 模型名，随簇快照发布，默认mock-bge，openai时默认为openai.model，onnx时默认为模型目录名
+	ModelVersion    string                  `yaml:"model_version"` // 模型版本，更换权重时需同步修改
+	BatchSize       int                     `yaml:"batch_size"`    // 单次交给后端向量化的文本数，异步批量时为合并的批次上限
+	CacheSize       int                     `yaml:"cache_size"`
+	Dimension       int                     `yaml:"dimension"`        // openai和onnx时校验向量维度
+	PreprocessRules []PreprocessRule        `yaml:"preprocess_rules"` // 模板化规则，按顺序应用，为空时使用内置规则
+	OpenAI          OpenAIEmbeddingConfig   `yaml:"openai"`
+	ONNX            ONNXEmbeddingConfig     `yaml:"onnx"`
+	Pipeline        EmbeddingPipelineConfig `yaml:"pipeline"` // 异步批量向量化，并发调用较多时合并请求以提高吞吐
+}
+
+// EmbeddingPipelineConfig 异步批量向量化配置：缓存未命中的文本进入有界队列，
+// 工作协程在最长等待时间内把多个调用方的请求合并为batch_size大小的批次
+type EmbeddingPipelineConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	QueueSize int           `yaml:"queue_size"` // 等待合并的请求数上限，满时提交阻塞，默认1024
+	MaxWait   time.Duration `yaml:"max_wait"`   // 凑满一批的最长等待时间，默认5毫秒
+	Workers   int           `yaml:"workers"`    // 同时执行的批次数，默认1
+}
+
+// ONNXEmbeddingConfig 本地ONNX Runtime向量化配置，需使用-tags onnx构建
+type ONNXEmbeddingConfig struct {
+	RuntimeLibrary string `yaml:"runtime_library"` // onnxruntime动态库路径，为空时使用系统默认库名
+	VocabPath      string `yaml:"vocab_path"`      // WordPiece词表，为空时使用模型目录下的vocab.txt
+	MaxSeqLength   int    `yaml:"max_seq_length"`  // 分词后的最大长度（含[CLS]和[SEP]），默认512
+	Pooling        string `yaml:"pooling"`         // "cls"（BGE，默认）或"mean"（MiniLM等）
+	Cased          bool   `yaml:"cased"`           // 区分大小写的模型需开启，默认分词前转小写并去除重音
+}
+
+// OpenAIEmbeddingConfig OpenAI兼容向量化接口配置
+type OpenAIEmbeddingConfig struct {
+	Endpoint       string        `yaml:"endpoint"` // 完整地址，如 https://api.openai.com/v1/embeddings
+	APIKey         string        `yaml:"api_key"`  // 支持${ENV}引用环境变量
+	Model          string        `yaml:"model"`
+	Timeout        time.Duration `yaml:"timeout"`         // 单次请求超时，默认30s
+	MaxRetries     int           `yaml:"max_retries"`     // 网络错误、429和5xx时的重试次数，默认2
+	RetryBackoff   time.Duration `yaml:"retry_backoff"`   // 首次重试间隔，按次数倍增，默认500ms
+	SendDimensions bool          `yaml:"send_dimensions"` // 请求携带dimensions参数，用于支持缩减维度的模型
+}
+
+// PreprocessRule 预处理模板化规则，将匹配的变量替换为占位符
+type PreprocessRule struct {
+	Pattern     string `yaml:"pattern" json:"pattern"`
+	Replacement string `yaml:"replacement" json:"replacement"`
 }
 
 // ClusteringConfig 聚类配置
 type ClusteringConfig struct {
-	SimilarityThreshold   float64       `yaml:"similarity_threshold"`
-	ReclusteringInterval  time.Duration `yaml:"reclustering_interval"`
-	MinClusterSize       int           `yaml:"min_cluster_size"`
-	MaxClusters          int           `yaml:"max_clusters"`
+	SimilarityThreshold  float64                    `yaml:"similarity_threshold"`
+	ReclusteringInterval time.Duration              `yaml:"reclustering_interval"`
+	ReclusteringSchedule JobSchedule                `yaml:"reclustering_schedule"` // 配置cron时代替reclustering_interval
+	MinClusterSize       int                        `yaml:"min_cluster_size"`
+	MaxClusters          int                        `yaml:"max_clusters"`
+	KSelection           string                     `yaml:"k_selection"` // 重聚类K的选择方式：fixed / elbow / silhouette，默认fixed沿用当前簇数
+	MinK                 int                        `yaml:"min_k"`       // 自动选择K的下界，默认2
+	MaxK                 int                        `yaml:"max_k"`       // 自动选择K的上界，默认max_clusters
+	Summary              ClusterSummaryConfig       `yaml:"summary"`
+	Reduction            VectorReductionConfig      `yaml:"reduction"`
+	Calibration          ThresholdCalibrationConfig `yaml:"calibration"`
+}
+
+// ThresholdCalibrationConfig 相似度阈值校准：定期从簇成员中抽取同簇和异簇的向量对，按服务统计相似度分布，
+// 推荐F1最高的阈值，可选自动应用。未启用定期校准时仍可通过管理接口手动触发
+type ThresholdCalibrationConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	Interval     time.Duration `yaml:"interval"`      // 校准间隔，默认1小时
+	Schedule     JobSchedule   `yaml:"schedule"`      // 配置cron时代替interval
+	SamplePairs  int           `yaml:"sample_pairs"`  // 每个范围同簇、异簇各抽取的向量对数，默认500
+	MinPairs     int           `yaml:"min_pairs"`     // 同簇和异簇向量对都达到该数量才给出推荐，默认20
+	Step         float64       `yaml:"step"`          // 候选阈值步长，默认0.01
+	MinThreshold float64       `yaml:"min_threshold"` // 候选阈值下界，默认0.5
+	MaxThreshold float64       `yaml:"max_threshold"` // 候选阈值上界，默认0.99
+	AutoApply    bool          `yaml:"auto_apply"`    // 定期校准后自动应用推荐阈值，否则只推荐
+	MaxChange    float64       `yaml:"max_change"`    // 每次应用时阈值的最大调整幅度，默认0.05，小于0不限制
+}
+
+// VectorReductionConfig 簇快照的质心降维：控制面保留全精度向量，发布给网关的质心和投影矩阵降到较低维度，
+// 网关匹配更快、占用内存更少
+type VectorReductionConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Method    string `yaml:"method"`    // pca（默认）/ random
+	Dimension int    `yaml:"dimension"` // 降维后的维度，默认128；pca的维度不超过簇数
+	Seed      int64  `yaml:"seed"`      // random投影矩阵的随机种子，默认1，各控制面实例需一致
+}
+
+// ClusterSummaryConfig 簇摘要配置：簇达到一定规模后将代表性错误发送给LLM，生成可读的根因摘要保存在簇上
+type ClusterSummaryConfig struct {
+	Enabled       bool            `yaml:"enabled"`
+	LLM           LLMClientConfig `yaml:"llm"`
+	MinSize       int             `yaml:"min_size"`       // 成员数达到该值时生成摘要，默认20
+	Samples       int             `yaml:"samples"`        // 发送离质心最近的错误特征数，默认5
+	RefreshGrowth float64         `yaml:"refresh_growth"` // 成员数增长到上次生成时的倍数后重新生成，默认2，小于等于1时不重新生成
+	Interval      time.Duration   `yaml:"interval"`       // 检查间隔，默认1分钟
+}
+
+// LLMClientConfig 控制面调用OpenAI兼容chat/completions接口的配置
+type LLMClientConfig struct {
+	Endpoint  string        `yaml:"endpoint"` // 完整地址，如https://api.openai.com/v1/chat/completions
+	APIKey    string        `yaml:"api_key"`  // 支持${ENV}
+	Model     string        `yaml:"model"`
+	Timeout   time.Duration `yaml:"timeout"`    // 默认30秒
+	MaxTokens int           `yaml:"max_tokens"` // 默认300
+}
+
+// 重聚类K的选择方式
+const (
+	KSelectionFixed      = "fixed"
+	KSelectionElbow      = "elbow"
+	KSelectionSilhouette = "silhouette"
+)
+
+// ReclusterReport 重聚类结果
+type ReclusterReport struct {
+	Method   string          `json:"method"`
+	K        int             `json:"k"`
+	Clusters int             `json:"clusters"`         // 非空簇数量
+	Vectors  int             `json:"vectors"`
+	Scores   map[int]float64 `json:"scores,omitempty"` // 各候选K的评分：elbow为簇内平方和，silhouette为轮廓系数
+	Time     time.Time       `json:"time"`
+}
+
+// CalibrationScopeGlobal 全局校准范围，包含所有服务的簇成员；服务没有单独的阈值时使用全局阈值
+const CalibrationScopeGlobal = "*"
+
+// CalibrationPoint 校准曲线上的一个候选阈值，相似度不低于阈值的向量对视为会被归入同一簇
+type CalibrationPoint struct {
+	Threshold      float64 `json:"threshold"`
+	Precision      float64 `json:"precision"`        // 被归入同一簇的向量对中同簇对的比例
+	Recall         float64 `json:"recall"`           // 同簇对中被归入同一簇的比例
+	F1             float64 `json:"f1"`
+	FalseMergeRate float64 `json:"false_merge_rate"` // 异簇对中被错误归入同一簇的比例
+}
+
+// ThresholdCalibration 某个范围的相似度阈值校准结果
+type ThresholdCalibration struct {
+	Scope          string             `json:"scope"`                 // 服务名，*为全局
+	Current        float64            `json:"current"`               // 校准时生效的阈值
+	Recommended    float64            `json:"recommended,omitempty"` // 推荐阈值，样本不足时为空
+	Applied        bool               `json:"applied"`
+	SamePairs      int                `json:"same_pairs"`
+	DiffPairs      int                `json:"diff_pairs"`
+	SameSimilarity float64            `json:"same_similarity"` // 同簇对的平均相似度
+	DiffSimilarity float64            `json:"diff_similarity"` // 异簇对的平均相似度
+	Curve          []CalibrationPoint `json:"curve,omitempty"`
+	Reason         string             `json:"reason,omitempty"` // 没有推荐阈值的原因
+	Time           time.Time          `json:"time"`
 }
 
 // VectorDBConfig 向量数据库配置
@@ -193,10 +1777,136 @@ type VectorDBConfig struct {
 
 // PolicyConfig 策略配置
 type PolicyConfig struct {
-	ErrorRateThreshold  float64       `yaml:"error_rate_threshold"`
-	GrowthRateThreshold float64       `yaml:"growth_rate_threshold"`
-	WindowSize          time.Duration `yaml:"window_size"`
-	PolicyTTL           time.Duration `yaml:"policy_ttl"`
+	ErrorRateThreshold  float64                    `yaml:"error_rate_threshold"`
+	GrowthRateThreshold float64                    `yaml:"growth_rate_threshold"`
+	WindowSize          time.Duration              `yaml:"window_size"`
+	PolicyTTL           time.Duration              `yaml:"policy_ttl"`
+	EvaluateInterval    time.Duration              `yaml:"evaluate_interval"`
+	EvaluateSchedule    JobSchedule                `yaml:"evaluate_schedule"` // 配置cron时代替evaluate_interval
+	Templates           []PolicyTemplate           `yaml:"templates"`         // 内联模板，优先于模板文件
+	TemplatesFile       string                     `yaml:"templates_file"`    // 模板库YAML文件，均未配置时使用内置模板
+	Escalation          EscalationConfig           `yaml:"escalation"`
+	Recommendation      PolicyRecommendationConfig `yaml:"recommendation"`
+	Audit               PolicyAuditConfig          `yaml:"audit"`
+	BreakerHistory      BreakerSeverityConfig      `yaml:"breaker_history"`
+	History             PolicyHistoryConfig        `yaml:"history"`
+}
+
+// PolicyHistoryConfig 策略历史：记录每次策略下发和删除，可查询任一时刻各簇生效的策略，用于事后还原网关行为
+type PolicyHistoryConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Retention time.Duration `yaml:"retention"` // 保留时长，默认30天；每个策略键保留早于该时长的最后一条记录，保证保留期内的查询结果完整
+}
+
+// 策略历史的变更类型
+const (
+	PolicyHistoryPut    = "put"
+	PolicyHistoryDelete = "delete"
+)
+
+// PolicyHistoryEntry 策略变更记录
+type PolicyHistoryEntry struct {
+	PolicyKey string    `json:"policy_key"`        // 策略键中的簇ID，带命名空间
+	Region    string    `json:"region,omitempty"`  // 区域策略所属区域，全局策略为空
+	Action    string    `json:"action"`            // put / delete
+	Version   int64     `json:"version,omitempty"` // 策略版本（ETCD修订号），与网关访问日志中的policy_version一致
+	Time      time.Time `json:"time"`
+	Policy    *Policy   `json:"policy,omitempty"` // 删除记录为nil
+}
+
+// BreakerSeverityConfig 按网关上报的熔断历史提高严重度：近期反复熔断的簇在错误速率刚达到阈值时也按更高的严重度匹配模板
+type BreakerSeverityConfig struct {
+	Window    time.Duration `yaml:"window"`     // 统计熔断次数的窗口，默认1小时
+	TripBoost float64       `yaml:"trip_boost"` // 每次熔断增加的严重度，为0时不使用熔断历史
+	MaxBoost  float64       `yaml:"max_boost"`  // 严重度增量上限，默认0.2
+}
+
+// PolicyAuditConfig 策略控制环路巡检配置：定期检查长期生效的策略、簇已不存在的策略和缺少策略的高严重度簇
+type PolicyAuditConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	Interval          time.Duration `yaml:"interval"`           // 巡检间隔，默认1分钟
+	MaxAge            time.Duration `yaml:"max_age"`            // 生效超过该时长的策略视为陈旧，默认1小时
+	SeverityThreshold float64       `yaml:"severity_threshold"` // 按当前错误速率和增长率计算的严重度达到该值时簇应有策略，默认0.5
+	Grace             time.Duration `yaml:"grace"`              // 问题持续超过该时长才告警，避免策略生成前的短暂空窗，默认2分钟
+}
+
+// 策略控制环路问题类型
+const (
+	PolicyGapStale     = "stale_policy"      // 生效时间超过max_age或已过期仍标记为生效
+	PolicyGapOrphaned  = "orphaned_policy"   // 策略引用的簇已不存在
+	PolicyGapUncovered = "uncovered_cluster" // 严重度达到阈值的簇没有策略
+)
+
+// PolicyGap 策略控制环路问题
+type PolicyGap struct {
+	Kind      string    `json:"kind"`
+	ClusterID string    `json:"cluster_id"`
+	PolicyKey string    `json:"policy_key,omitempty"`
+	Region    string    `json:"region,omitempty"`
+	Severity  float64   `json:"severity,omitempty"`
+	Detail    string    `json:"detail"`
+	Since     time.Time `json:"since"` // 首次发现时间
+}
+
+// PolicyAuditReport 策略巡检结果
+type PolicyAuditReport struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	Policies  int         `json:"policies"`
+	Clusters  int         `json:"clusters"`
+	Gaps      []PolicyGap `json:"gaps"`
+}
+
+// PolicyRecommendationConfig LLM辅助策略推荐：触发策略时将簇的错误速率、增长率和代表性错误发送给LLM，
+// 由其推荐策略类型和参数；推荐结果需通过护栏校验，否则使用模板生成的策略
+type PolicyRecommendationConfig struct {
+	Enabled          bool            `yaml:"enabled"`
+	LLM              LLMClientConfig `yaml:"llm"`
+	Samples          int             `yaml:"samples"`            // 发送的代表性错误数，默认5
+	AllowedTypes     []PolicyType    `yaml:"allowed_types"`      // 可推荐的策略类型，默认rate_limit、degrade、circuit_break
+	MinLimitRate     float64         `yaml:"min_limit_rate"`     // 限制比例下界，默认0.05
+	MaxLimitRate     float64         `yaml:"max_limit_rate"`     // 限制比例上界，默认0.9
+	MaxDuration      time.Duration   `yaml:"max_duration"`       // 限流时长和熔断时长上限，默认policy_ttl
+	MinBreakSeverity float64         `yaml:"min_break_severity"` // 严重度低于该值时不接受熔断推荐，默认0.8
+}
+
+// EscalationConfig 策略逐级升级配置：按阶段顺序应用模板，错误速率持续增长时升级，恢复后逐级降级
+type EscalationConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Stages           []string      `yaml:"stages"`            // 各阶段使用的模板名，默认soft_throttle -> hard_throttle -> short_break
+	StageDuration    time.Duration `yaml:"stage_duration"`    // 阶段持续该时长后错误速率仍高于进入时则升级，默认5分钟
+	RecoveryDuration time.Duration `yaml:"recovery_duration"` // 指标低于阈值持续该时长后降级一个阶段，默认5分钟
+	Page             bool          `yaml:"page"`              // 最后阶段仍无法遏制时发送critical告警通知值班人员
+	MaxEvents        int           `yaml:"max_events"`        // 内存中保留的升级事件数，默认1000
+}
+
+// EscalationEvent 策略升级事件
+type EscalationEvent struct {
+	ClusterID string    `json:"cluster_id"`
+	Action    string    `json:"action"` // start / escalate / deescalate / page / resolve
+	Stage     int       `json:"stage"`  // 动作后所处阶段，resolve时为-1
+	Template  string    `json:"template,omitempty"`
+	ErrorRate float64   `json:"error_rate"`
+	Growth    float64   `json:"growth_rate"`
+	Time      time.Time `json:"time"`
+}
+
+// PolicyTemplate 策略模板：按严重度区间匹配，实例化时参数按严重度在区间内线性插值
+type PolicyTemplate struct {
+	Name          string              `yaml:"name" json:"name"`
+	Description   string              `yaml:"description" json:"description,omitempty"`
+	MinSeverity   float64             `yaml:"min_severity" json:"min_severity"` // 区间下界（含）
+	MaxSeverity   float64             `yaml:"max_severity" json:"max_severity"` // 区间上界（不含，1.0时含）
+	PolicyType    PolicyType          `yaml:"policy_type" json:"policy_type"`
+	LimitRateMin  float64             `yaml:"limit_rate_min" json:"limit_rate_min,omitempty"` // 区间下界对应的限制比例
+	LimitRateMax  float64             `yaml:"limit_rate_max" json:"limit_rate_max,omitempty"` // 区间上界对应的限制比例
+	Algorithm     string              `yaml:"algorithm" json:"algorithm,omitempty"`           // 限流算法：token_bucket（默认）/ sliding_window / leaky_bucket
+	Window        time.Duration       `yaml:"window" json:"window,omitempty"`                 // sliding_window的窗口长度
+	PerKey        bool                `yaml:"per_key" json:"per_key,omitempty"`               // 按API密钥分别限流
+	Adaptive      *AdaptiveRateConfig `yaml:"adaptive" json:"adaptive,omitempty"`             // 按上游延迟和错误率自适应调整速率
+	Duration      time.Duration       `yaml:"duration" json:"duration,omitempty"`
+	BreakDuration time.Duration       `yaml:"break_duration" json:"break_duration,omitempty"`
+	RecoveryStep  float64             `yaml:"recovery_step" json:"recovery_step,omitempty"`
+	TTL           time.Duration       `yaml:"ttl" json:"ttl"` // 策略有效期，未配置时使用policy_ttl
 }
 
 // StorageConfig 存储配置
@@ -212,6 +1922,7 @@ type PostgreSQLConfig struct {
 	Database     string        `yaml:"database"`
 	Username     string        `yaml:"username"`
 	Password     string        `yaml:"password"`
+	SSLMode      string        `yaml:"ssl_mode"`
 	MaxOpenConns int           `yaml:"max_open_conns"`
 	MaxIdleConns int           `yaml:"max_idle_conns"`
 	ConnTimeout  time.Duration `yaml:"conn_timeout"`
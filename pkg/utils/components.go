@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// CollectComponents 汇总组件的健康状态和统计：实现interfaces.Component的组件按其Health判断，
+// 只提供统计的组件视为健康，值为nil的组件视为未初始化。任一组件unhealthy时ready为false
+func CollectComponents(components map[string]interface{}) (map[string]types.ComponentStatus, bool) {
+	statuses := make(map[string]types.ComponentStatus, len(components))
+	ready := true
+	for name, component := range components {
+		var status types.ComponentStatus
+		switch c := component.(type) {
+		case nil:
+			status.Health = types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "not initialized"}
+		case interfaces.Component:
+			status.Health = c.Health()
+			status.Stats = c.Stats()
+		case interfaces.StatsReporter:
+			status.Health = types.ComponentHealth{Status: types.ComponentHealthy}
+			status.Stats = c.Stats()
+		default:
+			status.Health = types.ComponentHealth{Status: types.ComponentHealthy}
+		}
+		if status.Health.Status == types.ComponentUnhealthy {
+			ready = false
+		}
+		statuses[name] = status
+	}
+	return statuses, ready
+}
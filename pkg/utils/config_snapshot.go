@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// MaskedValue 脱敏后的凭据
+const MaskedValue = "******"
+
+// secretFields 需要脱敏的配置字段（yaml名），只脱敏字符串值；api_keys等以密钥摘要为键的字段不含明文，不脱敏
+var secretFields = map[string]bool{
+	"password":    true,
+	"secret":      true,
+	"token":       true,
+	"api_key":     true,
+	"webhook_url": true, // Slack等机器人的webhook地址本身即凭据
+}
+
+// secretPaths 值全部需要脱敏的字段（"父字段.字段"），转发给上游的请求头常用于携带上游凭据
+var secretPaths = map[string]bool{
+	"request_headers.set": true,
+	"admin.api_keys":      true,
+}
+
+// ConfigTree 将配置结构体转换为以yaml字段名为键的树，时长输出为"30s"形式，凭据字段脱敏，
+// 结果可直接序列化为JSON
+func ConfigTree(config interface{}) interface{} {
+	return configValue(reflect.ValueOf(config), "", false)
+}
+
+// configValue 递归转换配置值，name为所在字段的yaml名，secret表示当前值位于凭据字段下
+func configValue(v reflect.Value, name string, secret bool) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return configValue(v.Elem(), name, secret)
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldName, inline := yamlName(field)
+			if fieldName == "-" {
+				continue
+			}
+			value := configValue(v.Field(i), fieldName, secretFields[fieldName] || secretPaths[name+"."+fieldName])
+			if nested, ok := value.(map[string]interface{}); inline && ok {
+				for key, item := range nested {
+					fields[key] = item
+				}
+				continue
+			}
+			fields[fieldName] = value
+		}
+		return fields
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		items := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items[fmt.Sprint(iter.Key().Interface())] = configValue(iter.Value(), name, secret)
+		}
+		return items
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = configValue(v.Index(i), name, secret)
+		}
+		return items
+	case reflect.String:
+		if secret && v.String() != "" {
+			return MaskedValue
+		}
+		return v.String()
+	}
+	return v.Interface()
+}
+
+// yamlName 字段的yaml名和是否内联，未设置yaml标签时依次使用json名、小写字段名
+func yamlName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("yaml")
+	if !ok {
+		tag = strings.Split(field.Tag.Get("json"), ",")[0]
+	}
+	parts := strings.Split(tag, ",")
+	inline := false
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			inline = true
+		}
+	}
+	if parts[0] == "" {
+		return strings.ToLower(field.Name), inline
+	}
+	return parts[0], inline
+}
+
+// FlattenConfig 将配置树展开为"路径 -> JSON值"，路径形如"routes[0].splits[1].weight"，用于比较配置差异
+func FlattenConfig(tree interface{}) map[string]string {
+	flat := make(map[string]string)
+	flattenInto(flat, "", tree)
+	return flat
+}
+
+// flattenInto 递归展开配置树，空值和空的映射、列表不产生叶子
+func flattenInto(flat map[string]string, path string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			flattenInto(flat, child, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenInto(flat, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	case nil:
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			data = []byte(fmt.Sprintf("%q", fmt.Sprint(v)))
+		}
+		flat[path] = string(data)
+	}
+}
+
+// DiffConfig 比较两份展开后的配置，返回按路径排序的变更，新增的路径Old为空，删除的路径New为空
+func DiffConfig(old, new map[string]string) []types.ConfigChange {
+	var changes []types.ConfigChange
+	for path, value := range new {
+		if previous, exists := old[path]; !exists || previous != value {
+			changes = append(changes, types.ConfigChange{Path: path, Old: previous, New: value})
+		}
+	}
+	for path, value := range old {
+		if _, exists := new[path]; !exists {
+			changes = append(changes, types.ConfigChange{Path: path, Old: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
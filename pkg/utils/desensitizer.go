@@ -53,6 +53,49 @@ func (d *desensitizer) Desensitize(text string) string {
 	return result
 }
 
+// DesensitizeCount 脱敏文本并按规则名统计替换次数
+func (d *desensitizer) DesensitizeCount(text string) (string, map[string]int) {
+	counts := make(map[string]int)
+	if text == "" {
+		return text, counts
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	result := text
+	for name, pattern := range d.patterns {
+		matches := pattern.regex.FindAllStringIndex(result, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		counts[name] += len(matches)
+		result = pattern.regex.ReplaceAllString(result, pattern.replacement)
+	}
+
+	return result, counts
+}
+
+// RemovePattern 删除脱敏规则
+func (d *desensitizer) RemovePattern(name string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.patterns, name)
+}
+
+// PatternNames 获取脱敏规则名
+func (d *desensitizer) PatternNames() []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	names := make([]string, 0, len(d.patterns))
+	for name := range d.patterns {
+		names = append(names, name)
+	}
+	return names
+}
+
 // AddPattern 添加脱敏规则
 func (d *desensitizer) AddPattern(name string, pattern string, replacement string) {
 	regex, err := regexp.Compile(pattern)
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// ID生成方式
+const (
+	IDGeneratorRandom    = "random"    // 32位十六进制随机串，默认
+	IDGeneratorULID      = "ulid"      // 26位ULID，按毫秒时间排序
+	IDGeneratorSnowflake = "snowflake" // 19位十进制雪花ID，按毫秒时间排序，节点ID区分实例
+)
+
+// IDGenerator ID生成器
+type IDGenerator interface {
+	NewID() string
+}
+
+// idGenerator 当前使用的ID生成器，atomic.Value要求存储同一具体类型，因此包装为generatorBox
+var idGenerator atomic.Value
+
+// generatorBox ID生成器的包装
+type generatorBox struct {
+	generator IDGenerator
+}
+
+func init() {
+	SetIDGenerator(randomIDGenerator{})
+}
+
+// currentIDGenerator 当前使用的ID生成器
+func currentIDGenerator() IDGenerator {
+	return idGenerator.Load().(generatorBox).generator
+}
+
+// NewIDGenerator 按配置创建ID生成器
+func NewIDGenerator(config *types.IDConfig) (IDGenerator, error) {
+	switch config.Generator {
+	case "", IDGeneratorRandom:
+		return randomIDGenerator{}, nil
+	case IDGeneratorULID:
+		return &ulidGenerator{}, nil
+	case IDGeneratorSnowflake:
+		nodeID := config.NodeID
+		if nodeID == 0 {
+			nodeID = defaultNodeID()
+			log.Printf("Snowflake node id not configured, derived %d from hostname and pid", nodeID)
+		}
+		if nodeID < 0 || nodeID > snowflakeMaxNode {
+			return nil, fmt.Errorf("snowflake node id %d is outside [1, %d]", nodeID, snowflakeMaxNode)
+		}
+		return &snowflakeGenerator{node: nodeID}, nil
+	}
+	return nil, fmt.Errorf("unsupported id generator %q", config.Generator)
+}
+
+// ConfigureIDs 按配置替换全局ID生成器
+func ConfigureIDs(config *types.IDConfig) error {
+	generator, err := NewIDGenerator(config)
+	if err != nil {
+		return err
+	}
+	SetIDGenerator(generator)
+	return nil
+}
+
+// SetIDGenerator 替换全局ID生成器
+func SetIDGenerator(generator IDGenerator) {
+	idGenerator.Store(generatorBox{generator: generator})
+}
+
+// RandomID 32位十六进制随机串，不受ID生成器配置影响，用于锁令牌等需要截取前缀的场景
+func RandomID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// randomIDGenerator 随机ID
+type randomIDGenerator struct{}
+
+// NewID 生成ID
+func (randomIDGenerator) NewID() string {
+	return RandomID()
+}
+
+// crockford ULID使用的Crockford Base32字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator ULID：48位毫秒时间戳加80位随机数，同一毫秒内随机部分递增，保证单实例内严格有序
+type ulidGenerator struct {
+	lastMs  uint64
+	entropy [10]byte
+	mutex   sync.Mutex
+}
+
+// NewID 生成ULID
+func (g *ulidGenerator) NewID() string {
+	g.mutex.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms > g.lastMs {
+		g.lastMs = ms
+		rand.Read(g.entropy[:])
+	} else {
+		// 同一毫秒或时钟回拨时沿用上次时间戳并递增随机部分
+		for i := len(g.entropy) - 1; i >= 0; i-- {
+			g.entropy[i]++
+			if g.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+	ms = g.lastMs
+	entropy := g.entropy
+	g.mutex.Unlock()
+
+	var id [26]byte
+	for i := 9; i >= 0; i-- {
+		id[i] = crockford[ms&0x1f]
+		ms >>= 5
+	}
+
+	// 80位随机数按5位一组编码为16个字符
+	hi := uint64(binary.BigEndian.Uint16(entropy[0:2]))
+	lo := binary.BigEndian.Uint64(entropy[2:10])
+	for i := 25; i >= 10; i-- {
+		id[i] = crockford[lo&0x1f]
+		lo = lo>>5 | (hi&0x1f)<<59
+		hi >>= 5
+	}
+	return string(id[:])
+}
+
+// 雪花ID布局：41位毫秒时间戳（自snowflakeEpoch起）、10位节点ID、12位序号
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// snowflakeEpoch 雪花ID时间起点（2024-01-01 UTC）
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+// snowflakeGenerator 雪花ID，不同实例需配置不同节点ID，同一毫秒内序号用尽时借用下一毫秒
+type snowflakeGenerator struct {
+	node   int64
+	lastMs int64
+	seq    int64
+	mutex  sync.Mutex
+}
+
+// NewID 生成雪花ID，补零到19位使字符串顺序与数值顺序一致
+func (g *snowflakeGenerator) NewID() string {
+	g.mutex.Lock()
+	ms := time.Now().UnixMilli() - snowflakeEpoch
+	if ms > g.lastMs {
+		g.lastMs = ms
+		g.seq = 0
+	} else {
+		g.seq++
+		if g.seq > snowflakeMaxSeq {
+			g.lastMs++
+			g.seq = 0
+		}
+	}
+	id := g.lastMs<<(snowflakeNodeBits+snowflakeSeqBits) | g.node<<snowflakeSeqBits | g.seq
+	g.mutex.Unlock()
+
+	return fmt.Sprintf("%019d", id)
+}
+
+// defaultNodeID 未配置节点ID时由主机名和进程号推导到[1, 1023]，多实例部署应显式配置以避免冲突
+func defaultNodeID() int64 {
+	hostname, _ := os.Hostname()
+	h := fnv.New32a()
+	h.Write([]byte(hostname + "/" + strconv.Itoa(os.Getpid())))
+	return int64(h.Sum32()%snowflakeMaxNode) + 1
+}
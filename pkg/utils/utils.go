@@ -1,10 +1,14 @@
 package utils
 
 import (
-	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
+	"net/http"
+	"net/netip"
 	"runtime"
 	"strconv"
 	"strings"
@@ -12,23 +16,31 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/llm-aware-gateway/pkg/types"
 )
 
-// GenerateID 生成唯一ID
+// GenerateID 使用配置的ID生成器生成唯一ID，默认为32位十六进制随机串
 func GenerateID() string {
-	bytes := make([]byte, 16)
-	rand.Read(bytes)
-	return hex.EncodeToString(bytes)
+	return currentIDGenerator().NewID()
 }
 
-// GenerateClusterID 生成簇ID
+// GenerateClusterID 生成簇ID，使用ULID或雪花ID时按创建时间排序
 func GenerateClusterID() string {
-	return fmt.Sprintf("cluster_%d_%s", time.Now().Unix(), GenerateID()[:8])
+	return generatePrefixedID("cluster")
 }
 
 // GeneratePolicyID 生成策略ID
 func GeneratePolicyID() string {
-	return fmt.Sprintf("policy_%d_%s", time.Now().Unix(), GenerateID()[:8])
+	return generatePrefixedID("policy")
+}
+
+// generatePrefixedID 带前缀的ID，默认生成器沿用"<前缀>_<秒级时间戳>_<8位随机串>"格式
+func generatePrefixedID(prefix string) string {
+	if _, ok := currentIDGenerator().(randomIDGenerator); ok {
+		return fmt.Sprintf("%s_%d_%s", prefix, time.Now().Unix(), RandomID()[:8])
+	}
+	return prefix + "_" + GenerateID()
 }
 
 // ExtractTraceID 从Gin上下文提取TraceID
@@ -61,6 +73,110 @@ func ExtractServiceName(ctx *gin.Context) string {
 	return "unknown"
 }
 
+// ExtractTenant 提取租户标识，优先使用认证阶段写入上下文的租户
+func ExtractTenant(ctx *gin.Context) string {
+	if tenant := ctx.GetString("tenant"); tenant != "" {
+		return tenant
+	}
+	return ctx.GetHeader("X-Tenant-ID")
+}
+
+// ExtractAPIKey 提取API密钥，优先使用认证阶段写入上下文的密钥，其次为X-API-Key和Bearer令牌
+func ExtractAPIKey(ctx *gin.Context) string {
+	if key := ctx.GetString("api_key"); key != "" {
+		return key
+	}
+	if key := ctx.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := ctx.GetHeader("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// APIKeyID 计算API密钥的摘要标识，用于统计和持久化，避免明文密钥落盘
+func APIKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseIP 解析客户端地址，接受带方括号或端口的形式（如"[2001:db8::1]:443"），
+// 去掉IPv6区域标识并将IPv4映射地址还原为IPv4，保证同一客户端只有一种表示
+func ParseIP(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		value = addrPort.Addr().String()
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.WithZone("").Unmap(), nil
+}
+
+// NormalizeIP 客户端地址的规范形式，用作按IP统计和限制的键；无法解析时原样返回
+func NormalizeIP(value string) string {
+	addr, err := ParseIP(value)
+	if err != nil {
+		return value
+	}
+	return addr.String()
+}
+
+// ExtractClientIP 提取规范形式的客户端IP
+func ExtractClientIP(ctx *gin.Context) string {
+	return NormalizeIP(ctx.ClientIP())
+}
+
+// NamespacedClusterID 按路由的簇命名空间限定簇ID，未配置命名空间时原样返回
+func NamespacedClusterID(ctx *gin.Context, clusterID string) string {
+	namespace := ctx.GetString("cluster_namespace")
+	if namespace == "" || clusterID == "" {
+		return clusterID
+	}
+	return namespace + "/" + clusterID
+}
+
+// 策略键布局：全局策略为"/policies/<cluster_key>"，区域策略为"/regions/<region>/policies/<cluster_key>"，
+// 网关只监听全局前缀和本区域前缀
+const (
+	GlobalPolicyPrefix = "/policies/"
+	RegionPrefix       = "/regions/"
+)
+
+// PolicyPrefix 策略键前缀，区域为空时为全局前缀
+func PolicyPrefix(region string) string {
+	if region == "" {
+		return GlobalPolicyPrefix
+	}
+	return RegionPrefix + region + GlobalPolicyPrefix
+}
+
+// PolicyKey 策略键
+func PolicyKey(region, clusterKey string) string {
+	return PolicyPrefix(region) + clusterKey
+}
+
+// ParsePolicyKey 解析策略键中的区域和簇键，全局策略的区域为空
+func ParsePolicyKey(key string) (string, string, bool) {
+	if strings.HasPrefix(key, GlobalPolicyPrefix) {
+		clusterKey := strings.TrimPrefix(key, GlobalPolicyPrefix)
+		return "", clusterKey, clusterKey != ""
+	}
+
+	rest := strings.TrimPrefix(key, RegionPrefix)
+	if rest == key {
+		return "", "", false
+	}
+	idx := strings.Index(rest, GlobalPolicyPrefix)
+	if idx <= 0 {
+		return "", "", false
+	}
+	clusterKey := rest[idx+len(GlobalPolicyPrefix):]
+	return rest[:idx], clusterKey, clusterKey != ""
+}
+
 // ExtractStackTrace 提取堆栈信息
 func ExtractStackTrace(err error, maxFrames int) []string {
 	if err == nil {
@@ -98,6 +214,163 @@ func ExtractErrorSignature(ctx *gin.Context) string {
 	return ""
 }
 
+// gRPC状态码
+const (
+	GRPCStatusOK                = 0
+	GRPCStatusUnknown           = 2
+	GRPCStatusDeadlineExceeded  = 4
+	GRPCStatusResourceExhausted = 8
+	GRPCStatusInternal          = 13
+	GRPCStatusUnavailable       = 14
+	GRPCStatusDataLoss          = 15
+)
+
+// IsGRPCFailure 判断gRPC状态码是否属于服务端失败
+func IsGRPCFailure(code int) bool {
+	switch code {
+	case GRPCStatusUnknown, GRPCStatusDeadlineExceeded, GRPCStatusInternal,
+		GRPCStatusUnavailable, GRPCStatusDataLoss:
+		return true
+	}
+	return false
+}
+
+// ParseGRPCTimeout 解析grpc-timeout头，格式为最多8位数字加单位(H/M/S/m/u/n)
+func ParseGRPCTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+
+	return time.Duration(n) * unit, true
+}
+
+// FormatGRPCTimeout 格式化grpc-timeout头，优先使用毫秒精度
+func FormatGRPCTimeout(d time.Duration) string {
+	const maxValue = 99999999
+	if ms := d.Milliseconds(); ms <= maxValue {
+		if ms < 1 {
+			ms = 1
+		}
+		return strconv.FormatInt(ms, 10) + "m"
+	}
+	if s := int64(d.Seconds()); s <= maxValue {
+		return strconv.FormatInt(s, 10) + "S"
+	}
+	return strconv.FormatInt(int64(d.Hours()), 10) + "H"
+}
+
+// StatusClientClosedRequest 客户端在响应完成前断开时记录的状态码
+const StatusClientClosedRequest = 499
+
+// IsClientCanceled 判断请求是否因客户端断开而取消
+func IsClientCanceled(ctx *gin.Context) bool {
+	return ctx.GetString("client_canceled") != ""
+}
+
+// IsRequestFailed 判断请求是否失败（HTTP 5xx，或响应头发出后才确定的gRPC/流式失败），客户端取消不计为失败
+func IsRequestFailed(ctx *gin.Context) bool {
+	if IsClientCanceled(ctx) {
+		return false
+	}
+
+	if ctx.Writer.Status() >= 500 {
+		return true
+	}
+
+	return ctx.GetBool("upstream_failed")
+}
+
+// rejectionStages 网关自身拒绝请求的处理阶段，这些阶段的错误计为拒绝而不是错误
+var rejectionStages = map[string]bool{
+	"ip_filter":         true,
+	"waf":               true,
+	"prompt_guard":      true,
+	"tenant_policy":     true,
+	"route_rate_limit":  true,
+	"api_key_limit":     true,
+	"tenant_quota":      true,
+	"route_concurrency": true,
+	"concurrency_limit": true,
+	"rate_limit":        true,
+	"circuit_breaker":   true,
+}
+
+// IsRequestRejected 判断请求是否被网关自身拒绝（429，或限流、熔断、WAF等阶段记录了错误）
+func IsRequestRejected(ctx *gin.Context) bool {
+	if ctx.Writer.Status() == http.StatusTooManyRequests {
+		return true
+	}
+	for _, stageErr := range StageErrors(ctx) {
+		if !stageErr.Annotation && rejectionStages[stageErr.Stage] {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorTypeAnnotation 处理链注解的gin错误类型，注解记录在c.Errors中但不视为请求错误
+const ErrorTypeAnnotation gin.ErrorType = 1 << 8
+
+// errorTypeFailure 请求错误的gin错误类型
+const errorTypeFailure = gin.ErrorTypeAny &^ ErrorTypeAnnotation
+
+// RecordStageError 记录处理链某个阶段产生的错误
+func RecordStageError(ctx *gin.Context, stage string, err error) {
+	ctx.Error(err).SetMeta(stage)
+}
+
+// AnnotateStage 记录处理链某个阶段的注解
+func AnnotateStage(ctx *gin.Context, stage, message string) {
+	ctx.Error(errors.New(message)).SetType(ErrorTypeAnnotation).SetMeta(stage)
+}
+
+// LastRequestError 最后记录的请求错误，不含注解，没有错误时返回nil
+func LastRequestError(ctx *gin.Context) error {
+	if last := ctx.Errors.ByType(errorTypeFailure).Last(); last != nil {
+		return last
+	}
+	return nil
+}
+
+// StageErrors 按发生顺序汇总处理链各阶段记录的错误和注解，未标明阶段的错误记为handler
+func StageErrors(ctx *gin.Context) []types.StageError {
+	if len(ctx.Errors) == 0 {
+		return nil
+	}
+
+	result := make([]types.StageError, 0, len(ctx.Errors))
+	for _, e := range ctx.Errors {
+		stage, ok := e.Meta.(string)
+		if !ok || stage == "" {
+			stage = "handler"
+		}
+		result = append(result, types.StageError{
+			Stage:      stage,
+			Message:    e.Err.Error(),
+			Annotation: e.IsType(ErrorTypeAnnotation),
+		})
+	}
+	return result
+}
+
 // CosineSimilarity 计算余弦相似度
 func CosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) {
@@ -119,6 +392,27 @@ func CosineSimilarity(a, b []float32) float64 {
 	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
+// EncodeFloat32s 将向量按小端float32编码
+func EncodeFloat32s(values []float32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+// DecodeFloat32s 解码按小端float32编码的向量
+func DecodeFloat32s(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid float32 data length %d", len(data))
+	}
+	values := make([]float32, len(data)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return values, nil
+}
+
 // EuclideanDistance 计算欧几里得距离
 func EuclideanDistance(a, b []float32) float64 {
 	if len(a) != len(b) {
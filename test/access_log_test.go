@@ -0,0 +1,235 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/llm-aware-gateway/pkg/gateway/accesslog"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// accessLogTraceID 请求携带的trace_id
+const accessLogTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// accessLogRouter 创建记录访问日志的路由，处理前写入trace上下文、请求ID和簇ID
+func accessLogRouter(t *testing.T, logger *accesslog.AccessLogger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	traceID, err := trace.TraceIDFromHex(accessLogTraceID)
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), spanContext))
+		c.Set("request_id", "req-1")
+		c.Next()
+	}, logger.Middleware())
+	router.GET("/v1/chat", func(c *gin.Context) {
+		c.Set("cluster_id", "cluster-7")
+		c.Set("route_name", "chat")
+		c.String(http.StatusOK, "hello")
+	})
+	router.GET("/v1/fail", func(c *gin.Context) {
+		c.Set("cluster_id", "cluster-9")
+		c.Error(errors.New("upstream timeout"))
+		c.Status(http.StatusBadGateway)
+	})
+	return router
+}
+
+// fileAccessLogger 创建写入临时文件的访问日志记录器
+func fileAccessLogger(t *testing.T, config types.AccessLogConfig) (*accesslog.AccessLogger, string) {
+	path := filepath.Join(t.TempDir(), "logs", "access.log")
+	config.Sinks = []types.AccessLogSinkConfig{{Type: accesslog.SinkFile, Path: path}}
+	logger, err := accesslog.NewAccessLogger(&config, nil)
+	require.NoError(t, err)
+	return logger, path
+}
+
+// accessLogLines 关闭记录器后读取日志文件的全部行
+func accessLogLines(t *testing.T, logger *accesslog.AccessLogger, path string) []string {
+	require.NoError(t, logger.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	logger, path := fileAccessLogger(t, types.AccessLogConfig{Format: accesslog.FormatJSON})
+	router := accessLogRouter(t, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chat?stream=false", nil)
+	req.Header.Set("User-Agent", "sdk/1.0")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/fail", nil))
+
+	lines := accessLogLines(t, logger, path)
+	require.Len(t, lines, 2)
+
+	var ok map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &ok))
+	assert.Equal(t, "GET", ok["method"])
+	assert.Equal(t, "/v1/chat", ok["path"])
+	assert.Equal(t, "stream=false", ok["query"])
+	assert.EqualValues(t, 200, ok["status"])
+	assert.EqualValues(t, 5, ok["bytes"])
+	assert.Equal(t, "sdk/1.0", ok["user_agent"])
+	assert.Equal(t, "req-1", ok["request_id"])
+	assert.Equal(t, accessLogTraceID, ok["trace_id"])
+	assert.Equal(t, "cluster-7", ok["cluster_id"])
+	assert.Equal(t, "chat", ok["route"])
+	assert.Contains(t, ok, "duration_ms")
+	assert.NotContains(t, ok, "error")
+
+	// 失败请求附带错误消息
+	var failed map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &failed))
+	assert.EqualValues(t, 502, failed["status"])
+	assert.Equal(t, "cluster-9", failed["cluster_id"])
+	assert.Equal(t, "upstream timeout", failed["error"])
+}
+
+func TestAccessLogFields(t *testing.T) {
+	// 只输出配置的字段，未知字段忽略
+	logger, path := fileAccessLogger(t, types.AccessLogConfig{
+		Format: accesslog.FormatJSON,
+		Fields: []string{"status", "trace_id", "cluster_id", "unknown"},
+	})
+	router := accessLogRouter(t, logger)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chat", nil))
+
+	lines := accessLogLines(t, logger, path)
+	require.Len(t, lines, 1)
+	assert.JSONEq(t, `{"status":200,"trace_id":"`+accessLogTraceID+`","cluster_id":"cluster-7"}`, lines[0])
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	logger, path := fileAccessLogger(t, types.AccessLogConfig{})
+	router := accessLogRouter(t, logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chat?stream=false", nil)
+	req.RemoteAddr = "198.51.100.7:4000"
+	req.Header.Set("User-Agent", "sdk/1.0")
+	req.Header.Set("Referer", "https://example.com/")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "/v1/fail", nil)
+	req.RemoteAddr = "198.51.100.7:4000"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// 未配置格式时使用combined，末尾附加请求ID、trace_id、簇ID和耗时，空值输出为"-"
+	lines := accessLogLines(t, logger, path)
+	require.Len(t, lines, 2)
+	assert.Regexp(t, regexp.MustCompile(`^198\.51\.100\.7 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] `+
+		`"GET /v1/chat\?stream=false HTTP/1\.1" 200 5 "https://example\.com/" "sdk/1\.0" `+
+		`request_id=req-1 trace_id=`+accessLogTraceID+` cluster_id=cluster-7 duration_ms=\d+\.\d{3}$`), lines[0])
+	assert.Regexp(t, regexp.MustCompile(`"GET /v1/fail HTTP/1\.1" 502 0 "-" "-" request_id=req-1 trace_id=\w+ cluster_id=cluster-9 `), lines[1])
+}
+
+func TestAccessLogFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	// 已有文件接近大小上限，较早的轮转文件超出保留数量
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 1<<20-10)+"\n"), 0644))
+	for _, suffix := range []string{"20200101-000000.000", "20200102-000000.000"} {
+		require.NoError(t, os.WriteFile(path+"."+suffix, []byte("old\n"), 0644))
+	}
+
+	logger, err := accesslog.NewAccessLogger(&types.AccessLogConfig{
+		Format: accesslog.FormatJSON,
+		Fields: []string{"path"},
+		Sinks:  []types.AccessLogSinkConfig{{Type: accesslog.SinkFile, Path: path, MaxSizeMB: 1, MaxBackups: 2}},
+	}, nil)
+	require.NoError(t, err)
+	router := accessLogRouter(t, logger)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chat", nil))
+
+	// 写入超过上限时轮转，新文件只包含新日志，只保留最近的两个轮转文件
+	lines := accessLogLines(t, logger, path)
+	assert.Equal(t, []string{`{"path":"/v1/chat"}`}, lines)
+
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	assert.Equal(t, path+".20200102-000000.000", backups[0])
+	rotated, err := os.Stat(backups[1])
+	require.NoError(t, err)
+	assert.EqualValues(t, 1<<20-9, rotated.Size())
+
+	// 重新打开时追加写入
+	logger, err = accesslog.NewAccessLogger(&types.AccessLogConfig{
+		Format: accesslog.FormatJSON,
+		Fields: []string{"path"},
+		Sinks:  []types.AccessLogSinkConfig{{Type: accesslog.SinkFile, Path: path, MaxSizeMB: 1}},
+	}, nil)
+	require.NoError(t, err)
+	accessLogRouter(t, logger).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/fail", nil))
+	assert.Equal(t, []string{`{"path":"/v1/chat"}`, `{"path":"/v1/fail"}`}, accessLogLines(t, logger, path))
+	assert.EqualValues(t, 1, logger.Stats()["written"])
+}
+
+func TestAccessLogKafkaSink(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("access-logs", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+	})
+
+	logger, err := accesslog.NewAccessLogger(&types.AccessLogConfig{
+		Sinks: []types.AccessLogSinkConfig{{Type: accesslog.SinkKafka, Topic: "access-logs"}},
+	}, &types.KafkaConfig{Brokers: []string{broker.Addr()}})
+	require.NoError(t, err)
+	router := accessLogRouter(t, logger)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chat", nil))
+
+	// 关闭时发送剩余日志后关闭生产者
+	require.NoError(t, logger.Close())
+	produced := 0
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			produced++
+		}
+	}
+	assert.Equal(t, 1, produced)
+	assert.EqualValues(t, 1, logger.Stats()["written"])
+}
+
+func TestAccessLogConfigInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config types.AccessLogConfig
+		error  string
+	}{
+		{name: "unknown format", config: types.AccessLogConfig{Format: "common"}, error: "unsupported access log format: common"},
+		{name: "unknown sink", config: types.AccessLogConfig{Sinks: []types.AccessLogSinkConfig{{Type: "syslog"}}}, error: "unknown sink type: syslog"},
+		{name: "file without path", config: types.AccessLogConfig{Sinks: []types.AccessLogSinkConfig{{Type: accesslog.SinkFile}}}, error: "file sink path is empty"},
+		{name: "kafka without topic", config: types.AccessLogConfig{Sinks: []types.AccessLogSinkConfig{{Type: accesslog.SinkKafka}}}, error: "kafka sink topic is empty"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := accesslog.NewAccessLogger(&tc.config, &types.KafkaConfig{})
+			assert.ErrorContains(t, err, tc.error)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestAdaptiveRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 100, MaxRate: 200}, nil)
+	defer rl.Cleanup()
+
+	require.NoError(t, rl.UpdatePolicy("llm-slow", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit: &types.RateLimitPolicy{Adaptive: &types.AdaptiveRateConfig{
+			TargetP95:  200 * time.Millisecond,
+			Increase:   10,
+			Decrease:   0.5,
+			Interval:   100 * time.Millisecond,
+			MinSamples: 5,
+		}},
+	}))
+
+	observer := rl.(interfaces.UpstreamObserver)
+	observe := func(latency time.Duration, failed bool) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/chat", nil)
+		c.Set("cluster_id", "llm-slow")
+		for i := 0; i < 10; i++ {
+			observer.ObserveUpstream(c, latency, failed)
+		}
+	}
+	rate := func() float64 {
+		stats, err := rl.GetStats("llm-slow")
+		require.NoError(t, err)
+		return stats.CurrentRate
+	}
+
+	// p95超过目标时乘性减少
+	observe(time.Second, false)
+	require.Eventually(t, func() bool { return rate() == 50 }, 3*time.Second, 50*time.Millisecond)
+	stats, err := rl.GetStats("llm-slow")
+	require.NoError(t, err)
+	require.NotNil(t, stats.Adaptive)
+	assert.Equal(t, limiter.AdaptiveDecrease, stats.Adaptive.Action)
+	assert.Equal(t, time.Second, stats.Adaptive.P95)
+
+	// 延迟和错误率都在目标内时加性增加
+	observe(10*time.Millisecond, false)
+	require.Eventually(t, func() bool { return rate() == 60 }, 3*time.Second, 50*time.Millisecond)
+
+	// 错误率超过上限时同样减少，策略重新下发保留已调整的速率
+	observe(10*time.Millisecond, true)
+	require.Eventually(t, func() bool { return rate() == 30 }, 3*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, rl.UpdatePolicy("llm-slow", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit: &types.RateLimitPolicy{Adaptive: &types.AdaptiveRateConfig{
+			TargetP95:  200 * time.Millisecond,
+			Increase:   10,
+			Decrease:   0.5,
+			Interval:   100 * time.Millisecond,
+			MinSamples: 5,
+		}},
+	}))
+	assert.EqualValues(t, 30, rate())
+
+	assert.Error(t, rl.UpdatePolicy("llm-slow", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{Adaptive: &types.AdaptiveRateConfig{Decrease: 1.5}},
+	}))
+}
+
+func TestClusterStatsConcurrentPolicyUpdate(t *testing.T) {
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 100, MaxRate: 200}, nil)
+	defer rl.Cleanup()
+	require.NoError(t, rl.UpdatePolicy("chat", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{LimitRate: 0.5},
+	}))
+
+	// 策略重新下发的同时读取统计，在-race下检查严重度和策略版本在锁内读取
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := int64(1); i <= 200; i++ {
+			assert.NoError(t, rl.UpdatePolicy("chat", &types.Policy{
+				PolicyType: types.PolicyTypeRateLimit,
+				Severity:   float64(i) / 200,
+				Version:    i,
+				RateLimit:  &types.RateLimitPolicy{LimitRate: 0.5},
+			}))
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		stats, err := rl.GetStats("chat")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, stats.Severity, 1.0)
+	}
+	<-done
+
+	stats, err := rl.GetStats("chat")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, stats.Severity)
+	assert.Equal(t, int64(200), stats.PolicyVersion)
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("GATEWAY_ADMIN_KEY", "admin-secret")
+
+	m := middleware.NewMiddleware(nil, nil, nil, nil, nil)
+	router := gin.New()
+	router.PUT("/admin/routes/:name/splits", m.AdminAuth([]string{"${GATEWAY_ADMIN_KEY}"}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/routes/chat/splits", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or missing admin API key")
+	assert.Equal(t, http.StatusUnauthorized, send("X-API-Key", "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("Authorization", "Basic admin-secret").Code)
+
+	// 密钥引用环境变量，X-API-Key和Bearer令牌均可
+	assert.Equal(t, http.StatusOK, send("X-API-Key", "admin-secret").Code)
+	assert.Equal(t, http.StatusOK, send("Authorization", "Bearer admin-secret").Code)
+
+	// 未配置密钥（或环境变量为空）时拒绝所有请求，缺省配置不暴露写接口
+	closed := gin.New()
+	closed.PUT("/admin/routes/:name/splits", m.AdminAuth([]string{"${GATEWAY_ADMIN_KEY_UNSET}"}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodPut, "/admin/routes/chat/splits", nil)
+	req.Header.Set("X-API-Key", "")
+	w = httptest.NewRecorder()
+	closed.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 管理密钥在配置快照中脱敏
+	flat := utils.FlattenConfig(utils.ConfigTree(&types.GatewayConfig{
+		Admin: types.AdminConfig{APIKeys: []string{"admin-secret"}},
+	}))
+	assert.Equal(t, `"******"`, flat["admin.api_keys[0]"])
+}
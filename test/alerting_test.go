@@ -0,0 +1,80 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/alerting"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestAlertingChannels(t *testing.T) {
+	var mutex sync.Mutex
+	received := make(map[string]map[string]interface{})
+	queries := make(map[string]string)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		mutex.Lock()
+		received[r.URL.Path] = payload
+		queries[r.URL.Path] = r.URL.RawQuery
+		mutex.Unlock()
+
+		if r.URL.Path == "/feishu" {
+			w.Write([]byte(`{"code":0}`))
+			return
+		}
+		w.Write([]byte(`{"errcode":0}`))
+	}))
+	defer server.Close()
+
+	notifier, err := alerting.NewNotifier(&types.AlertingConfig{
+		Enabled: true,
+		Channels: []types.AlertChannelConfig{
+			{Type: types.AlertChannelSlack, WebhookURL: server.URL + "/slack", MinSeverity: types.AlertSeverityCritical},
+			{Type: types.AlertChannelDingTalk, WebhookURL: server.URL + "/dingtalk?access_token=t", Secret: "s"},
+			{Type: types.AlertChannelFeishu, WebhookURL: server.URL + "/feishu", Secret: "s"},
+			{Type: types.AlertChannelWeCom, WebhookURL: server.URL + "/wecom", Events: []string{"policy_ineffective"}},
+		},
+		Templates: map[string]string{"policy_ineffective": "策略无效: {{.Labels.cluster_id}}"},
+	})
+	require.NoError(t, err)
+
+	event := &types.AlertEvent{
+		Type:     "policy_ineffective",
+		Severity: types.AlertSeverityWarning,
+		Title:    "Policy ineffective",
+		Labels:   map[string]string{"cluster_id": "c1"},
+		Time:     time.Unix(0, 0),
+	}
+	text, err := notifier.Render(event)
+	require.NoError(t, err)
+	assert.Equal(t, "策略无效: c1", text)
+
+	notifier.Start()
+	notifier.Notify(event)
+	notifier.Stop()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	assert.NotContains(t, received, "/slack", "slack channel requires critical severity")
+	assert.Equal(t, "markdown", received["/dingtalk"]["msgtype"])
+	assert.Contains(t, queries["/dingtalk"], "access_token=t&timestamp=")
+	assert.Contains(t, queries["/dingtalk"], "&sign=")
+	assert.Equal(t, "text", received["/feishu"]["msg_type"])
+	assert.NotEmpty(t, received["/feishu"]["sign"])
+	assert.Equal(t, map[string]interface{}{"content": "策略无效: c1"}, received["/wecom"]["markdown"])
+
+	_, err = alerting.NewNotifier(&types.AlertingConfig{Channels: []types.AlertChannelConfig{{Type: "pager", WebhookURL: "http://x"}}})
+	assert.Error(t, err)
+}
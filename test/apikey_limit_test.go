@@ -0,0 +1,97 @@
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestKeyLimiterSnapshotRestore(t *testing.T) {
+	config := &types.APIKeyLimitConfig{Enabled: true, Rate: 1, Burst: 3, Quota: 4, QuotaPeriod: time.Hour}
+	before := limiter.NewKeyLimiter(config)
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := before.Allow("key-a")
+		assert.True(t, allowed)
+	}
+	allowed, reason := before.Allow("key-a")
+	assert.False(t, allowed)
+	assert.Equal(t, limiter.KeyRejectRate, reason)
+
+	remaining, resetAt := before.QuotaRemaining("key-a")
+	assert.Equal(t, int64(1), remaining)
+	assert.True(t, resetAt.After(time.Now()))
+
+	// 模拟重启：新实例恢复后速率预算和配额消耗延续
+	after := limiter.NewKeyLimiter(config)
+	assert.Equal(t, 1, after.Restore(before.Snapshot()))
+
+	allowed, reason = after.Allow("key-a")
+	assert.False(t, allowed)
+	assert.Equal(t, limiter.KeyRejectRate, reason)
+	remaining, _ = after.QuotaRemaining("key-a")
+	assert.Equal(t, int64(1), remaining)
+
+	// 已结束的配额周期不恢复
+	stale := limiter.NewKeyLimiter(config)
+	stale.Restore([]types.KeyBucketSnapshot{{
+		KeyID: "key-b", Tokens: 3, Capacity: 3, Rate: 1, QuotaUsed: 4,
+		QuotaResetAt: time.Now().Add(-time.Minute), Timestamp: time.Now().Add(-2 * time.Minute),
+	}})
+	allowed, _ = stale.Allow("key-b")
+	assert.True(t, allowed)
+
+	// 合并恢复取偏保守的一份
+	stale.Restore([]types.KeyBucketSnapshot{{
+		KeyID: "key-b", Tokens: 0, Capacity: 3, Rate: 1, QuotaUsed: 4,
+		QuotaResetAt: time.Now().UTC().Truncate(time.Hour).Add(time.Hour), Timestamp: time.Now(),
+	}})
+	allowed, reason = stale.Allow("key-b")
+	assert.False(t, allowed)
+	assert.Equal(t, limiter.KeyRejectQuota, reason)
+}
+
+func TestClusterLimiterPerKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 2, MaxRate: 100}, nil)
+	defer rl.Cleanup()
+
+	allow := func(key string) bool {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		c.Request.Header.Set("X-API-Key", key)
+		c.Set("cluster_id", "timeouts")
+		return rl.Allow(c)
+	}
+
+	// 密钥覆盖在簇限流之前检查，没有簇策略时同样生效
+	keyID, ok := limiter.KeyIDFromLimitKey(limiter.KeyLimitPrefix + utils.APIKeyID("sk-noisy"))
+	require.True(t, ok)
+	require.NoError(t, rl.UpdateKeyLimit(keyID, &types.APIKeyRateLimit{Rate: 1}))
+	assert.True(t, allow("sk-noisy"))
+	assert.False(t, allow("sk-noisy"))
+	assert.True(t, allow("sk-quiet"))
+
+	require.NoError(t, rl.UpdateKeyLimit(keyID, nil))
+	assert.True(t, allow("sk-noisy"))
+	assert.Error(t, rl.UpdateKeyLimit(keyID, &types.APIKeyRateLimit{}))
+
+	// per_key策略下每个密钥独立使用策略速率
+	require.NoError(t, rl.UpdatePolicy("timeouts", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{PerKey: true},
+	}))
+	for _, key := range []string{"sk-a", "sk-b"} {
+		assert.True(t, allow(key))
+		assert.True(t, allow(key))
+		assert.False(t, allow(key))
+	}
+}
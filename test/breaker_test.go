@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/breaker"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestBreakerSteppedRecovery(t *testing.T) {
+	cb := breaker.NewClusterCircuitBreaker(&types.BreakerConfig{
+		FailureThreshold: 2,
+		RecoveryInterval: 20 * time.Millisecond,
+	})
+
+	require.NoError(t, cb.UpdatePolicy("cluster-1", &types.Policy{
+		ClusterID:  "cluster-1",
+		PolicyType: types.PolicyTypeCircuitBreak,
+		CircuitBreak: &types.CircuitBreakPolicy{
+			BreakDuration: 10 * time.Millisecond,
+			RecoveryStep:  0.5,
+		},
+	}))
+
+	ctx := context.Background()
+	cb.RecordFailure("cluster-1")
+	cb.RecordFailure("cluster-1")
+	assert.Equal(t, types.BreakerStateOpen, cb.GetState("cluster-1"))
+	assert.False(t, cb.Allow(ctx, "cluster-1"))
+
+	// 半开阶段按50%放行
+	time.Sleep(15 * time.Millisecond)
+	admitted := 0
+	for i := 0; i < 10; i++ {
+		if cb.Allow(ctx, "cluster-1") {
+			admitted++
+		}
+	}
+	assert.Equal(t, types.BreakerStateHalfOpen, cb.GetState("cluster-1"))
+	assert.Equal(t, 5, admitted)
+
+	// 健康的恢复步结束后提升到100%并关闭
+	cb.RecordSuccess("cluster-1")
+	time.Sleep(25 * time.Millisecond)
+	assert.True(t, cb.Allow(ctx, "cluster-1"))
+	assert.Equal(t, types.BreakerStateClosed, cb.GetState("cluster-1"))
+
+	// 半开阶段出现失败时重新熔断
+	cb.RecordFailure("cluster-1")
+	cb.RecordFailure("cluster-1")
+	time.Sleep(15 * time.Millisecond)
+	cb.Allow(ctx, "cluster-1")
+	assert.Equal(t, types.BreakerStateHalfOpen, cb.GetState("cluster-1"))
+	cb.RecordFailure("cluster-1")
+	assert.Equal(t, types.BreakerStateOpen, cb.GetState("cluster-1"))
+}
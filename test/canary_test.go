@@ -0,0 +1,102 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/canary"
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/controlplane/timeseries"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestCanaryAnalysis(t *testing.T) {
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.95,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          100,
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 100, Dimension: 64}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	analyzer, err := canary.NewAnalyzer(&types.CanaryConfig{
+		MinEvents: 4,
+		Routes:    []types.CanaryRouteConfig{{Route: "chat-canary"}},
+	}, engine)
+	require.NoError(t, err)
+	require.NoError(t, clustering.AttachEventObserver(engine, analyzer.Observe))
+
+	send := func(version, message string, n int) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, engine.ProcessErrorEvent(&types.ErrorEvent{
+				EventID:      fmt.Sprintf("%s-%s-%d", version, message[:4], i),
+				ServiceName:  "chat",
+				ErrorMessage: message,
+				StatusCode:   502,
+				Route:        "chat-canary",
+				Version:      version,
+			}))
+		}
+	}
+	timeout := "upstream connect timeout while reading response headers"
+	refused := "database connection refused by primary replica"
+
+	// 事件不足且没有错误率时无法判断
+	send("stable", timeout, 4)
+	verdict, err := analyzer.Analyze("chat-canary")
+	require.NoError(t, err)
+	assert.Equal(t, types.CanaryInconclusive, verdict.Verdict)
+
+	// 与基线相同的错误构成
+	send("stable", refused, 4)
+	send("canary", timeout, 3)
+	send("canary", refused, 3)
+	verdict, err = analyzer.Analyze("chat-canary")
+	require.NoError(t, err)
+	assert.Equal(t, types.CanaryPass, verdict.Verdict, verdict.Reasons)
+	assert.Equal(t, int64(8), verdict.BaselineEvents)
+	assert.Equal(t, int64(6), verdict.CanaryEvents)
+	assert.Zero(t, verdict.NovelShare)
+	assert.InDelta(t, 0, verdict.Distance, 1e-9)
+
+	// 金丝雀出现基线没有的错误
+	send("canary", "nil pointer dereference in tokenizer plugin", 4)
+	verdict, err = analyzer.Analyze("chat-canary")
+	require.NoError(t, err)
+	assert.Equal(t, types.CanaryFail, verdict.Verdict)
+	assert.InDelta(t, 0.4, verdict.NovelShare, 1e-9)
+	require.Len(t, verdict.NovelClusters, 1)
+	assert.Equal(t, int64(4), verdict.NovelClusters[0].Count)
+
+	// 错误预算：金丝雀错误率高出基线
+	_, err = canary.NewAnalyzer(&types.CanaryConfig{Routes: []types.CanaryRouteConfig{{Route: "r", Baseline: "v1", Canary: "v1"}}}, engine)
+	assert.Error(t, err)
+
+	budgeted, err := canary.NewAnalyzer(&types.CanaryConfig{Routes: []types.CanaryRouteConfig{{Route: "chat-canary"}}}, engine)
+	require.NoError(t, err)
+	store := timeseries.NewGatewayMetricsStore(&types.GatewayMetricsConfig{Resolution: time.Second, Retention: time.Hour})
+	budgeted.SetGatewayMetrics(store)
+	store.Ingest(&types.MetricsSnapshot{
+		Instance: "gw-1",
+		Sequence: 1,
+		End:      time.Now(),
+		Versions: map[string]map[string]*types.ClusterCounters{
+			"chat-canary": {
+				"stable": {Requests: 1000, Errors: 5},
+				"canary": {Requests: 100, Errors: 3},
+			},
+		},
+	})
+	verdict, err = budgeted.Analyze("chat-canary")
+	require.NoError(t, err)
+	assert.Equal(t, types.CanaryFail, verdict.Verdict)
+	assert.InDelta(t, 0.03, *verdict.CanaryErrorRate, 1e-9)
+	assert.Len(t, budgeted.Verdicts(), 1)
+
+	_, err = analyzer.Analyze("unknown")
+	assert.Error(t, err)
+}
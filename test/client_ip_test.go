@@ -0,0 +1,244 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway"
+	"github.com/llm-aware-gateway/pkg/gateway/accesslog"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// forwardedHeaders 上游收到的转发头
+type forwardedHeaders struct {
+	ForwardedFor string `json:"xff"`
+	RealIP       string `json:"real_ip"`
+}
+
+// clientIPGateway 创建转发到回显上游的网关，请求的对端地址取自X-Test-Peer，
+// 用于模拟来自可信代理或任意客户端的连接
+func clientIPGateway(t *testing.T, clientIP types.ClientIPConfig) func(peer string, headers map[string]string) forwardedHeaders {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(forwardedHeaders{
+			ForwardedFor: strings.Join(r.Header.Values("X-Forwarded-For"), ", "),
+			RealIP:       r.Header.Get("X-Real-IP"),
+		})
+	}))
+	t.Cleanup(upstream.Close)
+
+	gw, err := gateway.NewGateway(&types.GatewayConfig{
+		Server:    types.ServerConfig{ClientIP: clientIP},
+		ETCD:      types.ETCDConfig{Endpoints: []string{"127.0.0.1:1"}, Timeout: time.Second},
+		Limiter:   types.LimiterConfig{DefaultRate: 1000, MaxRate: 10000},
+		Upstreams: []types.UpstreamConfig{{Name: "echo", Targets: []types.UpstreamTargetConfig{{URL: upstream.URL}}}},
+		Routes:    []types.RouteConfig{{Name: "echo", PathPrefix: "/echo", Upstream: "echo"}},
+	})
+	require.NoError(t, err)
+
+	router := gw.GetRouter()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = r.Header.Get("X-Test-Peer")
+		r.Header.Del("X-Test-Peer")
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	send := func(peer string, headers map[string]string) forwardedHeaders {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/echo", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Test-Peer", peer)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var forwarded forwardedHeaders
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&forwarded))
+		return forwarded
+	}
+	return send
+}
+
+func TestClientIPForwarding(t *testing.T) {
+	send := clientIPGateway(t, types.ClientIPConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Headers:        []string{"cf-connecting-ip", "X-Forwarded-For"},
+	})
+
+	cases := []struct {
+		name      string
+		peer      string
+		headers   map[string]string
+		forwarded forwardedHeaders
+	}{
+		{
+			name:      "direct client",
+			peer:      "203.0.113.5:4000",
+			forwarded: forwardedHeaders{ForwardedFor: "203.0.113.5", RealIP: "203.0.113.5"},
+		},
+		{
+			// 不可信对端自带的转发头全部丢弃
+			name: "untrusted peer with forged headers",
+			peer: "203.0.113.5:4000",
+			headers: map[string]string{
+				"X-Forwarded-For":  "198.51.100.7",
+				"CF-Connecting-IP": "198.51.100.8",
+			},
+			forwarded: forwardedHeaders{ForwardedFor: "203.0.113.5", RealIP: "203.0.113.5"},
+		},
+		{
+			// 从右侧跳过可信代理，取第一个不可信地址，左侧伪造的地址不生效
+			name:      "trusted proxy chain",
+			peer:      "10.0.0.2:4000",
+			headers:   map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7, 10.0.0.1"},
+			forwarded: forwardedHeaders{ForwardedFor: "1.2.3.4, 198.51.100.7, 10.0.0.1, 10.0.0.2", RealIP: "198.51.100.7"},
+		},
+		{
+			name:      "trusted proxy without headers",
+			peer:      "10.0.0.2:4000",
+			forwarded: forwardedHeaders{ForwardedFor: "10.0.0.2", RealIP: "10.0.0.2"},
+		},
+		{
+			// 按配置顺序优先读取CF-Connecting-IP
+			name: "cf-connecting-ip from trusted proxy",
+			peer: "10.0.0.2:4000",
+			headers: map[string]string{
+				"CF-Connecting-IP": "198.51.100.8",
+				"X-Forwarded-For":  "198.51.100.7",
+			},
+			forwarded: forwardedHeaders{ForwardedFor: "198.51.100.7, 10.0.0.2", RealIP: "198.51.100.8"},
+		},
+		{
+			name:      "invalid cf-connecting-ip falls back",
+			peer:      "10.0.0.2:4000",
+			headers:   map[string]string{"CF-Connecting-IP": "unknown", "X-Forwarded-For": "198.51.100.7"},
+			forwarded: forwardedHeaders{ForwardedFor: "198.51.100.7, 10.0.0.2", RealIP: "198.51.100.7"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.forwarded, send(tc.peer, tc.headers))
+		})
+	}
+}
+
+func TestClientIPv6Forwarding(t *testing.T) {
+	send := clientIPGateway(t, types.ClientIPConfig{TrustedProxies: []string{"2001:db8:ffff::/48"}})
+
+	cases := []struct {
+		name      string
+		peer      string
+		headers   map[string]string
+		forwarded forwardedHeaders
+	}{
+		{
+			name:      "direct client",
+			peer:      "[2001:db8::1]:4000",
+			forwarded: forwardedHeaders{ForwardedFor: "2001:db8::1", RealIP: "2001:db8::1"},
+		},
+		{
+			name:      "untrusted peer with forged header",
+			peer:      "[2001:db8::1]:4000",
+			headers:   map[string]string{"X-Forwarded-For": "2001:db8::7"},
+			forwarded: forwardedHeaders{ForwardedFor: "2001:db8::1", RealIP: "2001:db8::1"},
+		},
+		{
+			// X-Real-IP使用规范形式，转发链保留原值
+			name:      "trusted proxy chain",
+			peer:      "[2001:db8:ffff::1]:4000",
+			headers:   map[string]string{"X-Forwarded-For": "2001:DB8:0:0::7, 2001:db8:ffff::2"},
+			forwarded: forwardedHeaders{ForwardedFor: "2001:DB8:0:0::7, 2001:db8:ffff::2, 2001:db8:ffff::1", RealIP: "2001:db8::7"},
+		},
+		{
+			name:      "ipv4 client behind ipv6 proxy",
+			peer:      "[2001:db8:ffff::1]:4000",
+			headers:   map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			forwarded: forwardedHeaders{ForwardedFor: "198.51.100.7, 2001:db8:ffff::1", RealIP: "198.51.100.7"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.forwarded, send(tc.peer, tc.headers))
+		})
+	}
+}
+
+func TestAccessLogIPv6RemoteIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name      string
+		remote    string
+		forwarded string
+		remoteIP  string
+	}{
+		{name: "ipv6 peer", remote: "[2001:db8::1]:5000", remoteIP: "2001:db8::1"},
+		{name: "non-canonical peer", remote: "[2001:DB8:0:0::2]:5000", remoteIP: "2001:db8::2"},
+		{name: "ipv4-mapped peer", remote: "[::ffff:192.0.2.1]:5000", remoteIP: "192.0.2.1"},
+		{name: "trusted ipv6 proxy", remote: "[2001:db8:ffff::1]:5000", forwarded: "2001:DB8::7", remoteIP: "2001:db8::7"},
+		{name: "untrusted ipv6 peer", remote: "[2001:db8::3]:5000", forwarded: "2001:db8::7", remoteIP: "2001:db8::3"},
+	}
+
+	for _, format := range []string{accesslog.FormatJSON, accesslog.FormatCombined} {
+		path := filepath.Join(t.TempDir(), "access.log")
+		logger, err := accesslog.NewAccessLogger(&types.AccessLogConfig{
+			Format: format,
+			Fields: []string{"remote_ip"},
+			Sinks:  []types.AccessLogSinkConfig{{Type: accesslog.SinkFile, Path: path}},
+		}, nil)
+		require.NoError(t, err)
+
+		router := gin.New()
+		require.NoError(t, router.SetTrustedProxies([]string{"2001:db8:ffff::/48"}))
+		router.Use(logger.Middleware())
+		router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		for _, tc := range cases {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remote
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		require.NoError(t, logger.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, len(cases))
+
+		// combined格式的首个字段为客户端IP
+		for i, tc := range cases {
+			if format == accesslog.FormatJSON {
+				assert.JSONEq(t, `{"remote_ip":"`+tc.remoteIP+`"}`, lines[i], tc.name)
+			} else {
+				assert.True(t, strings.HasPrefix(lines[i], tc.remoteIP+" - - ["), "%s: %s", tc.name, lines[i])
+			}
+		}
+	}
+}
+
+func TestClientIPConfigInvalid(t *testing.T) {
+	_, err := gateway.NewGateway(&types.GatewayConfig{
+		Server: types.ServerConfig{ClientIP: types.ClientIPConfig{TrustedProxies: []string{"10.0.0.0/33"}}},
+	})
+	assert.Error(t, err)
+}
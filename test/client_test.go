@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/client"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestClientReportsBatches(t *testing.T) {
+	var mutex sync.Mutex
+	var received []types.ErrorEvent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/events", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var events []types.ErrorEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&events))
+
+		mutex.Lock()
+		received = append(received, events...)
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	c, err := client.New(client.Config{
+		Endpoint:      server.URL,
+		APIKey:        "secret",
+		ServiceName:   "billing",
+		FlushInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, c.Report(errors.New("invoice total mismatch"), &client.Event{RequestPath: "/invoices"}))
+	assert.True(t, c.Report(errors.New("ledger lock timeout"), nil))
+	assert.False(t, c.Report(nil, nil))
+
+	require.NoError(t, c.Flush(context.Background()))
+	require.NoError(t, c.Close())
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, "billing", received[0].ServiceName)
+	assert.Equal(t, "/invoices", received[0].RequestPath)
+	assert.NotEmpty(t, received[0].StackTrace)
+	assert.NotEmpty(t, received[0].EventID)
+}
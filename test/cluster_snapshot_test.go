@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/gateway/vector"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestClusterSnapshotEmbeddingCheck(t *testing.T) {
+	embed := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		ModelName: "bge-small", ModelVersion: "v1.5", BatchSize: 8, CacheSize: 10, Dimension: 3,
+	})
+	info := embed.ModelInfo()
+	assert.Equal(t, types.EmbeddingModelInfo{Model: "bge-small", Version: "v1.5", Dimension: 3}, info)
+
+	agent := vector.NewVectorAgent(embed, utils.NewCache(100))
+	stats := func() map[string]interface{} {
+		return agent.(interface{ Stats() map[string]interface{} }).Stats()
+	}
+	snapshot := &types.ClusterSnapshot{
+		Embedding:   info,
+		Clusters:    []*types.Cluster{{ID: "c1", Centroid: []float32{1, 0, 0}}},
+		GeneratedAt: time.Now(),
+	}
+	require.NoError(t, agent.ApplySnapshot(snapshot))
+	assert.EqualValues(t, 1, stats()["clusters_known"])
+
+	mismatched := []*types.ClusterSnapshot{
+		// 未声明嵌入模型
+		{Clusters: []*types.Cluster{{ID: "c2", Centroid: []float32{0, 1, 0}}}},
+		// 模型版本不同
+		{Embedding: types.EmbeddingModelInfo{Model: "bge-small", Version: "v2", Dimension: 3},
+			Clusters: []*types.Cluster{{ID: "c2", Centroid: []float32{0, 1, 0}}}},
+		// 维度不同
+		{Embedding: types.EmbeddingModelInfo{Model: "bge-small", Version: "v1.5", Dimension: 4},
+			Clusters: []*types.Cluster{{ID: "c2", Centroid: []float32{0, 1, 0, 0}}}},
+		// 质心维度与声明不符
+		{Embedding: info, Clusters: []*types.Cluster{{ID: "c2", Centroid: []float32{0, 1}}}},
+	}
+	for _, s := range mismatched {
+		err := agent.ApplySnapshot(s)
+		require.Error(t, err)
+		_, ok := err.(*vector.EmbeddingMismatchError)
+		assert.True(t, ok)
+	}
+
+	// 拒绝的快照不影响已加载的簇
+	current := stats()
+	assert.EqualValues(t, 1, current["clusters_known"])
+	assert.EqualValues(t, 4, current["snapshots_rejected"])
+	assert.NotEmpty(t, current["last_rejection"])
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestListClustersPaging(t *testing.T) {
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.9,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          100,
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 10, Dimension: 2}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	for i := 0; i < 25; i++ {
+		_, err := engine.CreateNewCluster(&types.ErrorEvent{EventID: fmt.Sprintf("event-%d", i)}, []float32{float32(i), 1})
+		require.NoError(t, err)
+	}
+
+	seen := make(map[string]bool)
+	cursor, pages := "", 0
+	for {
+		clusters, next, err := engine.ListClusters(cursor, 10)
+		require.NoError(t, err)
+		pages++
+		for _, cluster := range clusters {
+			assert.False(t, seen[cluster.ID], "cluster returned twice")
+			assert.Greater(t, cluster.ID, cursor)
+			seen[cluster.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Len(t, seen, 25)
+	assert.Equal(t, 3, pages)
+
+	_, _, err := engine.ListClusters("", 0)
+	assert.Error(t, err)
+}
+
+func TestClusterSummaries(t *testing.T) {
+	var calls int32
+	var lastPrompt string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		lastPrompt = req.Messages[len(req.Messages)-1].Content
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":" Upstream database is timing out. "}}]}`)
+	}))
+	defer llm.Close()
+
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.5,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          10,
+		Summary: types.ClusterSummaryConfig{
+			Enabled: true,
+			LLM:     types.LLMClientConfig{Endpoint: llm.URL, Model: "test-model"},
+			MinSize: 3,
+			Samples: 2,
+		},
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 10, Dimension: 16}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	seq := 0
+	process := func(n int) {
+		for i := 0; i < n; i++ {
+			seq++
+			require.NoError(t, engine.ProcessErrorEvent(&types.ErrorEvent{
+				EventID:      fmt.Sprintf("event-%d", seq),
+				ServiceName:  "orders",
+				Method:       "POST",
+				ErrorMessage: "database timeout",
+			}))
+		}
+	}
+
+	// 未达到规模时不调用LLM
+	process(2)
+	generated, err := engine.SummarizeClusters()
+	require.NoError(t, err)
+	assert.Equal(t, 0, generated)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	process(2)
+	generated, err = engine.SummarizeClusters()
+	require.NoError(t, err)
+	assert.Equal(t, 1, generated)
+	assert.Contains(t, lastPrompt, "database timeout")
+	assert.NotContains(t, lastPrompt, "\n2. ") // 相同特征只发送一次
+
+	clusters, _ := engine.GetAllClusters()
+	require.Len(t, clusters, 1)
+	for _, cluster := range clusters {
+		assert.Equal(t, "Upstream database is timing out.", cluster.Summary)
+		assert.Equal(t, 4, cluster.SummarySize)
+	}
+
+	// 成员数未翻倍时不重新生成
+	process(3)
+	generated, _ = engine.SummarizeClusters()
+	assert.Equal(t, 0, generated)
+
+	process(1)
+	generated, _ = engine.SummarizeClusters()
+	assert.Equal(t, 1, generated)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/breaker"
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// staticStats 只提供统计的组件
+type staticStats struct{}
+
+func (staticStats) Stats() map[string]interface{} {
+	return map[string]interface{}{"count": 1}
+}
+
+func TestComponentReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rateLimiter := limiter.NewClusterRateLimiter(&types.LimiterConfig{}, nil)
+	defer rateLimiter.Cleanup()
+	circuitBreaker := breaker.NewClusterCircuitBreaker(&types.BreakerConfig{})
+
+	statuses, ready := utils.CollectComponents(map[string]interface{}{
+		"rate_limiter":    rateLimiter,
+		"circuit_breaker": circuitBreaker,
+		"stats_only":      staticStats{},
+	})
+	assert.True(t, ready)
+	assert.Equal(t, types.ComponentDegraded, statuses["rate_limiter"].Health.Status)
+	assert.Equal(t, types.ComponentHealthy, statuses["circuit_breaker"].Health.Status)
+	assert.Equal(t, 0, statuses["circuit_breaker"].Stats["clusters"])
+	assert.Equal(t, types.ComponentHealthy, statuses["stats_only"].Health.Status)
+
+	// 未初始化的采样器使网关未就绪
+	mw := middleware.NewMiddleware(rateLimiter, circuitBreaker, nil, nil, nil)
+	engine := gin.New()
+	engine.Use(mw.HealthCheck())
+
+	ready503 := httptest.NewRecorder()
+	engine.ServeHTTP(ready503, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, ready503.Code)
+
+	var body struct {
+		Status     string                           `json:"status"`
+		Components map[string]types.ComponentHealth `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(ready503.Body.Bytes(), &body))
+	assert.Equal(t, "not_ready", body.Status)
+	assert.Equal(t, types.ComponentUnhealthy, body.Components["error_sampler"].Status)
+	assert.Equal(t, types.ComponentDegraded, body.Components["rate_limiter"].Status)
+
+	// 降级的组件不影响就绪，已清理的限流器不可用
+	mw.SetComponents(func() map[string]interface{} {
+		return map[string]interface{}{"rate_limiter": rateLimiter, "circuit_breaker": circuitBreaker}
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	rateLimiter.Cleanup()
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
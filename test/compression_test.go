@@ -0,0 +1,95 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/compression"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestCompressionDecode(t *testing.T) {
+	handler := compression.NewHandler(&types.CompressionConfig{Enabled: true, MaxDecompressedBytes: 4 << 20})
+
+	body := []byte(`{"model":"gpt-4","messages":[]}`)
+	decoded, err := handler.Decode(gzipBytes(t, body), "gzip")
+	require.NoError(t, err)
+	assert.Equal(t, body, decoded)
+
+	// 多层编码按逆序解压
+	decoded, err = handler.Decode(gzipBytes(t, gzipBytes(t, body)), "gzip, x-gzip")
+	require.NoError(t, err)
+	assert.Equal(t, body, decoded)
+
+	_, err = handler.Decode([]byte("not gzip"), "br")
+	assert.True(t, errors.Is(err, compression.ErrUnsupported))
+
+	// 解压炸弹：3MB的零压缩后只有几KB，超过压缩比上限
+	bomb := gzipBytes(t, make([]byte, 3<<20))
+	_, err = handler.Decode(bomb, "gzip")
+	assert.True(t, errors.Is(err, compression.ErrTooLarge))
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	assert.Equal(t, "gzip", compression.Negotiate("br, gzip;q=0.8, deflate;q=0.5"))
+	assert.Equal(t, "deflate", compression.Negotiate("gzip;q=0.1, deflate"))
+	assert.Equal(t, "", compression.Negotiate("br"))
+	assert.Equal(t, "", compression.Negotiate("gzip;q=0"))
+
+	assert.Equal(t, "gzip;q=0.8", compression.FilterAcceptEncoding("br, gzip;q=0.8"))
+	assert.Equal(t, "identity", compression.FilterAcceptEncoding("br"))
+}
+
+func TestCompressionResponseRoundTrip(t *testing.T) {
+	handler := compression.NewHandler(&types.CompressionConfig{Enabled: true, MinCompressBytes: 16})
+
+	body := []byte(strings.Repeat(`{"ok":true}`, 10))
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:   io.NopCloser(bytes.NewReader(gzipBytes(t, body))),
+	}
+
+	require.True(t, handler.DecodeResponse(resp))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, int64(len(body)), resp.ContentLength)
+
+	// 客户端只接受br时以identity返回
+	require.NoError(t, handler.EncodeResponse(resp, "br"))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	require.NoError(t, handler.EncodeResponse(resp, "gzip"))
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	reader, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, decoded)
+
+	// 无法解压的响应原样透传
+	resp = &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"br"}},
+		Body:   io.NopCloser(strings.NewReader("brotli bytes")),
+	}
+	assert.False(t, handler.DecodeResponse(resp))
+	assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	data, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "brotli bytes", string(data))
+	assert.Equal(t, int64(1), handler.Stats()["passthrough_responses"])
+}
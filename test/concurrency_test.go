@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	cl, err := limiter.NewConcurrencyLimiter(&types.ConcurrencyLimitConfig{
+		MaxConcurrent: 2,
+		MaxQueue:      1,
+		QueueTimeout:  100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	release1, err := cl.Acquire(ctx)
+	require.NoError(t, err)
+	release2, err := cl.Acquire(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, cl.InFlight())
+
+	// 上限已满时排队，排队超时拒绝
+	_, err = cl.Acquire(ctx)
+	assert.Equal(t, limiter.ErrConcurrencyQueueTimeout, err)
+
+	// 排队的请求在空位释放后获得空位，队列已满时直接拒绝
+	acquired := make(chan func(), 1)
+	go func() {
+		release, err := cl.Acquire(ctx)
+		if err == nil {
+			acquired <- release
+		}
+	}()
+	require.Eventually(t, func() bool { return cl.Queued() == 1 }, time.Second, time.Millisecond)
+	_, err = cl.Acquire(ctx)
+	assert.Equal(t, limiter.ErrConcurrencyQueueFull, err)
+
+	release1()
+	release1() // 重复释放无副作用
+	release3 := <-acquired
+	assert.EqualValues(t, 2, cl.InFlight())
+
+	release2()
+	release3()
+	assert.EqualValues(t, 0, cl.InFlight())
+
+	_, err = limiter.NewConcurrencyLimiter(&types.ConcurrencyLimitConfig{})
+	assert.Error(t, err)
+}
+
+func TestClusterConcurrencyPolicy(t *testing.T) {
+	cc, err := limiter.NewClusterConcurrency(&types.ClusterConcurrencyConfig{
+		Enabled:  true,
+		Clusters: map[string]types.ConcurrencyLimitConfig{"slow": {MaxConcurrent: 1}},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	release, err := cc.Acquire(ctx, "slow")
+	require.NoError(t, err)
+	_, err = cc.Acquire(ctx, "slow")
+	assert.Equal(t, limiter.ErrConcurrencyQueueFull, err)
+
+	// 未配置的簇不受限制
+	for i := 0; i < 10; i++ {
+		_, err = cc.Acquire(ctx, "fast")
+		assert.NoError(t, err)
+	}
+
+	// 策略下发的并发限制覆盖配置，删除后恢复配置的限制
+	require.NoError(t, cc.UpdatePolicy("slow", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{Concurrency: &types.ConcurrencyLimitConfig{MaxConcurrent: 2}},
+	}))
+	_, err = cc.Acquire(ctx, "slow")
+	assert.NoError(t, err)
+	_, err = cc.Acquire(ctx, "slow")
+	assert.NoError(t, err)
+	_, err = cc.Acquire(ctx, "slow")
+	assert.Error(t, err)
+
+	cc.RemovePolicy("slow")
+	release()
+	_, err = cc.Acquire(ctx, "slow")
+	assert.NoError(t, err)
+
+	assert.Error(t, cc.UpdatePolicy("slow", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{Concurrency: &types.ConcurrencyLimitConfig{MaxConcurrent: 1, MaxQueue: -1}},
+	}))
+}
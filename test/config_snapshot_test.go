@@ -0,0 +1,86 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestConfigSnapshot(t *testing.T) {
+	config := &types.GatewayConfig{
+		Routes: []types.RouteConfig{{
+			Name:     "chat",
+			Upstream: "llm",
+			Timeout:  30 * time.Second,
+			Transform: types.TransformConfig{
+				UpstreamAuth:    &types.UpstreamAuthConfig{Type: types.UpstreamAuthBearer, Token: "sk-live-secret"},
+				RequestHeaders:  types.HeaderTransformConfig{Set: map[string]string{"X-Upstream-Key": "uk-secret"}},
+				ResponseHeaders: types.HeaderTransformConfig{Set: map[string]string{"X-Served-By": "gateway"}},
+			},
+			Splits: []types.RouteSplitConfig{{Upstream: "llm", Weight: 100}},
+		}},
+		MetricsExport: types.MetricsExportConfig{APIKey: "cp-key"},
+		LLM: types.LLMConfig{
+			TokenLimit: types.TokenLimitConfig{APIKeys: map[string]int64{"ab12cd": 1000}},
+		},
+	}
+
+	tree := utils.ConfigTree(config)
+	data, err := json.Marshal(tree)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-live-secret")
+	assert.NotContains(t, string(data), "cp-key")
+	assert.NotContains(t, string(data), "uk-secret")
+
+	flat := utils.FlattenConfig(tree)
+	assert.Equal(t, `"******"`, flat["routes[0].transform.upstream_auth.token"])
+	assert.Equal(t, `"bearer"`, flat["routes[0].transform.upstream_auth.type"])
+	assert.Equal(t, `"30s"`, flat["routes[0].timeout"])
+	assert.Equal(t, `"******"`, flat["metrics_export.api_key"])
+	// 转发给上游的请求头值全部脱敏，返回给客户端的响应头不脱敏
+	assert.Equal(t, `"******"`, flat["routes[0].transform.request_headers.set.X-Upstream-Key"])
+	assert.Equal(t, `"gateway"`, flat["routes[0].transform.response_headers.set.X-Served-By"])
+	// 以密钥摘要为键的限额不含明文，不脱敏
+	assert.Equal(t, "1000", flat["llm.token_limit.api_keys.ab12cd"])
+
+	t.Run("diff", func(t *testing.T) {
+		updated := *config
+		updated.Routes = []types.RouteConfig{config.Routes[0]}
+		updated.Routes[0].Splits = []types.RouteSplitConfig{
+			{Upstream: "llm", Weight: 90},
+			{Upstream: "llm-canary", Weight: 10},
+		}
+
+		changes := utils.DiffConfig(flat, utils.FlattenConfig(utils.ConfigTree(&updated)))
+		assert.Equal(t, []types.ConfigChange{
+			{Path: "routes[0].splits[0].weight", Old: "100", New: "90"},
+			{Path: "routes[0].splits[1].upstream", New: `"llm-canary"`},
+			{Path: "routes[0].splits[1].version", New: `""`},
+			{Path: "routes[0].splits[1].weight", New: "10"},
+		}, changes)
+
+		assert.Empty(t, utils.DiffConfig(flat, utils.FlattenConfig(utils.ConfigTree(config))))
+	})
+}
+
+func TestConfigSnapshotRuntimeOverrides(t *testing.T) {
+	// etcd下发的策略没有yaml标签，按json名展开
+	tree := utils.ConfigTree(map[string]*types.Policy{
+		"cluster-1": {
+			PolicyType: types.PolicyTypeRateLimit,
+			Version:    3,
+			RateLimit:  &types.RateLimitPolicy{LimitRate: 0.5, Duration: time.Minute},
+		},
+	})
+	flat := utils.FlattenConfig(tree)
+	assert.Equal(t, "3", flat["cluster-1.version"])
+	assert.Equal(t, `"1m0s"`, flat["cluster-1.rate_limit.duration"])
+	assert.Equal(t, "0.5", flat["cluster-1.rate_limit.limit_rate"])
+	assert.NotContains(t, flat, "cluster-1.waf")
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/decision"
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// checkingRateLimiter 在请求上下文中记录预设检查结果的限流器
+type checkingRateLimiter struct {
+	interfaces.RateLimiter
+	check   types.RateLimitCheck
+	allowed bool
+}
+
+func (l *checkingRateLimiter) Allow(c *gin.Context) bool {
+	c.Set("rate_limit_check", l.check)
+	return l.allowed
+}
+
+func (l *checkingRateLimiter) GetStats(clusterID string) (*types.ClusterStats, error) {
+	return &types.ClusterStats{ClusterID: clusterID, CurrentRate: 4, Severity: 0.8}, nil
+}
+
+func TestRateLimitDecisionStep(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := &checkingRateLimiter{check: types.RateLimitCheck{
+		ClusterID:     "cluster-7",
+		Algorithm:     types.RateLimitTokenBucket,
+		PolicyVersion: 3,
+		Limiter:       types.RateLimiterCluster,
+	}}
+
+	store := decision.NewStore(&types.DecisionConfig{Enabled: true})
+	m := middleware.NewMiddleware(rl, nil, nil, nil, nil)
+	router := gin.New()
+	router.Use(m.RequestID(), store.Middleware(), m.RateLimit())
+	router.GET("/api/chat", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(id string) int {
+		req := httptest.NewRequest("GET", "/api/chat", nil)
+		req.Header.Set("X-Request-ID", id)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 决策步骤使用限流器解析出的簇ID、算法和策略版本，而不是从请求中重新推断
+	require.Equal(t, http.StatusTooManyRequests, send("rejected"))
+	trail, ok := store.Get("rejected")
+	require.True(t, ok)
+	require.Len(t, trail.Steps, 1)
+	step := trail.Steps[0]
+	assert.Equal(t, "rate_limit", step.Stage)
+	assert.Equal(t, decision.DecisionReject, step.Decision)
+	assert.Equal(t, "cluster-7", step.ClusterID)
+	assert.Equal(t, int64(3), step.PolicyVersion)
+	assert.Equal(t, "token bucket exhausted", step.Reason)
+	assert.Equal(t, types.RateLimitTokenBucket, step.Details["algorithm"])
+	assert.Equal(t, types.RateLimiterCluster, step.Details["limiter"])
+	assert.Equal(t, 0.8, step.Details["severity"])
+
+	rl.allowed = true
+	require.Equal(t, http.StatusOK, send("allowed"))
+	trail, ok = store.Get("allowed")
+	require.True(t, ok)
+	require.Len(t, trail.Steps, 1)
+	assert.Equal(t, decision.DecisionAllow, trail.Steps[0].Decision)
+	assert.Equal(t, "cluster-7", trail.Steps[0].ClusterID)
+	assert.Empty(t, trail.Steps[0].Reason)
+
+	// 簇没有限流策略时检查结果只有簇ID，决策步骤不带算法和限流器
+	rl.check = types.RateLimitCheck{ClusterID: "cluster-9"}
+	require.Equal(t, http.StatusOK, send("no-policy"))
+	trail, ok = store.Get("no-policy")
+	require.True(t, ok)
+	require.Len(t, trail.Steps, 1)
+	assert.Equal(t, "cluster-9", trail.Steps[0].ClusterID)
+	assert.Nil(t, trail.Steps[0].Details)
+}
+
+func TestClusterRateLimiterDecisionTrail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 2, MaxRate: 100}, nil)
+	defer rl.Cleanup()
+	require.NoError(t, rl.UpdatePolicy("chat", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		Version:    7,
+		RateLimit:  &types.RateLimitPolicy{Algorithm: types.RateLimitSlidingWindow, Window: time.Second},
+	}))
+
+	store := decision.NewStore(&types.DecisionConfig{Enabled: true})
+	m := middleware.NewMiddleware(rl, nil, nil, nil, nil)
+	router := gin.New()
+	router.Use(m.RequestID(), func(c *gin.Context) {
+		c.Set("cluster_id", "chat")
+		c.Next()
+	}, store.Middleware(), m.RateLimit())
+	router.GET("/api/chat", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var rejected string
+	for i := 0; i < 10 && rejected == ""; i++ {
+		id := "req-" + strconv.Itoa(i)
+		req := httptest.NewRequest("GET", "/api/chat", nil)
+		req.Header.Set("X-Request-ID", id)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code == http.StatusTooManyRequests {
+			rejected = id
+		}
+	}
+	require.NotEmpty(t, rejected)
+
+	trail, ok := store.Get(rejected)
+	require.True(t, ok)
+	require.Len(t, trail.Steps, 1)
+	step := trail.Steps[0]
+	assert.Equal(t, decision.DecisionReject, step.Decision)
+	assert.Equal(t, "chat", step.ClusterID)
+	assert.Equal(t, int64(7), step.PolicyVersion)
+	assert.Equal(t, "sliding window limit reached", step.Reason)
+	assert.Equal(t, types.RateLimitSlidingWindow, step.Details["algorithm"])
+	assert.Equal(t, types.RateLimiterCluster, step.Details["limiter"])
+	assert.EqualValues(t, 0, step.Details["remaining"])
+
+	// 密钥覆盖在识别簇之前拒绝，决策步骤记录覆盖的算法
+	require.NoError(t, rl.UpdateKeyLimit(utils.APIKeyID("sk-noisy"), &types.APIKeyRateLimit{Rate: 1}))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/api/chat", nil)
+		req.Header.Set("X-Request-ID", "key-"+strconv.Itoa(i))
+		req.Header.Set("X-API-Key", "sk-noisy")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	trail, ok = store.Get("key-1")
+	require.True(t, ok)
+	require.Len(t, trail.Steps, 1)
+	step = trail.Steps[0]
+	assert.Equal(t, decision.DecisionReject, step.Decision)
+	assert.Empty(t, step.ClusterID)
+	assert.Equal(t, "api key override token bucket exhausted", step.Reason)
+	assert.Equal(t, types.RateLimitTokenBucket, step.Details["algorithm"])
+	assert.Equal(t, types.RateLimiterKeyOverride, step.Details["limiter"])
+}
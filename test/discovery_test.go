@@ -0,0 +1,286 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/discovery"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// fakePrefixWatcher 模拟etcd配置监听器，WatchPrefix先同步回调已有的键，之后由测试触发变更
+type fakePrefixWatcher struct {
+	interfaces.ConfigWatcher
+	keys     map[string]string
+	prefix   string
+	callback interfaces.KeyUpdateCallback
+	watchErr error
+	mutex    sync.Mutex
+}
+
+func (w *fakePrefixWatcher) WatchPrefix(prefix string, callback interfaces.KeyUpdateCallback) error {
+	if w.watchErr != nil {
+		return w.watchErr
+	}
+	w.mutex.Lock()
+	w.prefix, w.callback = prefix, callback
+	w.mutex.Unlock()
+
+	for key, value := range w.keys {
+		if strings.HasPrefix(key, prefix) {
+			callback(key, []byte(value), false)
+		}
+	}
+	return nil
+}
+
+func (w *fakePrefixWatcher) put(key, value string) {
+	w.callback(key, []byte(value), false)
+}
+
+func (w *fakePrefixWatcher) delete(key string) {
+	w.callback(key, nil, true)
+}
+
+// discoveryUpdates 收集服务发现回调
+type discoveryUpdates struct {
+	updates [][]types.UpstreamTargetConfig
+	mutex   sync.Mutex
+}
+
+func (d *discoveryUpdates) callback(targets []types.UpstreamTargetConfig) {
+	d.mutex.Lock()
+	d.updates = append(d.updates, targets)
+	d.mutex.Unlock()
+}
+
+func (d *discoveryUpdates) count() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.updates)
+}
+
+func (d *discoveryUpdates) last() []types.UpstreamTargetConfig {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.updates[len(d.updates)-1]
+}
+
+func TestETCDDiscovery(t *testing.T) {
+	watcher := &fakePrefixWatcher{keys: map[string]string{
+		"/services/chat/b":  "10.0.0.2:8080",
+		"/services/chat/a":  `{"url":"http://10.0.0.1:8080","weight":2}`,
+		"/services/chat/x":  "",
+		"/services/other/c": "10.0.0.9:8080",
+	}}
+	sd := discovery.NewETCDDiscovery(watcher, "")
+	updates := &discoveryUpdates{}
+
+	// 首次加载完成后只回调一次，无效实例被忽略，结果按URL排序
+	require.NoError(t, sd.Watch(&types.UpstreamDiscoveryConfig{Service: "chat"}, updates.callback))
+	assert.Equal(t, "/services/chat/", watcher.prefix)
+	require.Equal(t, 1, updates.count())
+	assert.Equal(t, []types.UpstreamTargetConfig{
+		{URL: "http://10.0.0.1:8080", Weight: 2},
+		{URL: "http://10.0.0.2:8080"},
+	}, updates.last())
+
+	// 新增实例
+	watcher.put("/services/chat/c", `{"url":"10.0.0.3:8080","weight":1}`)
+	require.Equal(t, 2, updates.count())
+	assert.Equal(t, []types.UpstreamTargetConfig{
+		{URL: "http://10.0.0.1:8080", Weight: 2},
+		{URL: "http://10.0.0.2:8080"},
+		{URL: "http://10.0.0.3:8080", Weight: 1},
+	}, updates.last())
+
+	// 更新实例权重和地址
+	watcher.put("/services/chat/a", `{"url":"http://10.0.0.4:8080","weight":5}`)
+	require.Equal(t, 3, updates.count())
+	assert.Equal(t, []types.UpstreamTargetConfig{
+		{URL: "http://10.0.0.2:8080"},
+		{URL: "http://10.0.0.3:8080", Weight: 1},
+		{URL: "http://10.0.0.4:8080", Weight: 5},
+	}, updates.last())
+
+	// 无效的更新不回调，保留原实例
+	watcher.put("/services/chat/a", `{"url":""}`)
+	assert.Equal(t, 3, updates.count())
+
+	// 删除实例
+	watcher.delete("/services/chat/b")
+	require.Equal(t, 4, updates.count())
+	assert.Equal(t, []types.UpstreamTargetConfig{
+		{URL: "http://10.0.0.3:8080", Weight: 1},
+		{URL: "http://10.0.0.4:8080", Weight: 5},
+	}, updates.last())
+
+	// 停止后不再回调
+	require.NoError(t, sd.Stop())
+	watcher.put("/services/chat/d", "10.0.0.5:8080")
+	assert.Equal(t, 4, updates.count())
+}
+
+func TestETCDDiscoveryConfig(t *testing.T) {
+	// 自定义前缀补全斜杠，实例协议使用配置的scheme
+	watcher := &fakePrefixWatcher{keys: map[string]string{"/registry/chat/a": "10.0.0.1:8443"}}
+	updates := &discoveryUpdates{}
+	sd := discovery.NewETCDDiscovery(watcher, "/registry")
+	require.NoError(t, sd.Watch(&types.UpstreamDiscoveryConfig{Service: "chat", Scheme: "https"}, updates.callback))
+	assert.Equal(t, []types.UpstreamTargetConfig{{URL: "https://10.0.0.1:8443"}}, updates.last())
+
+	assert.Error(t, sd.Watch(&types.UpstreamDiscoveryConfig{}, updates.callback))
+
+	watcher = &fakePrefixWatcher{watchErr: errors.New("etcd unavailable")}
+	err := discovery.NewETCDDiscovery(watcher, "").Watch(&types.UpstreamDiscoveryConfig{Service: "chat"}, updates.callback)
+	assert.ErrorContains(t, err, "etcd unavailable")
+}
+
+// kubeEndpoints 构造Endpoints对象，端口包含metrics和http两个
+func kubeEndpoints(version string, ips ...string) map[string]interface{} {
+	addresses := make([]map[string]string, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, map[string]string{"ip": ip})
+	}
+	return map[string]interface{}{
+		"metadata": map[string]string{"resourceVersion": version},
+		"subsets": []map[string]interface{}{{
+			"addresses": addresses,
+			"ports": []map[string]interface{}{
+				{"name": "metrics", "port": 9090},
+				{"name": "http", "port": 8080},
+			},
+		}},
+	}
+}
+
+func TestKubernetesDiscovery(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("kube-token\n"), 0600))
+
+	var lists int32
+	events := make(chan map[string]interface{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer kube-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/prod/endpoints/chat":
+			n := atomic.AddInt32(&lists, 1)
+			if n == 1 {
+				json.NewEncoder(w).Encode(kubeEndpoints("10", "10.0.0.1", "fd00::1"))
+			} else {
+				json.NewEncoder(w).Encode(kubeEndpoints("20", "10.0.0.9"))
+			}
+		case r.URL.Path == "/api/v1/namespaces/prod/endpoints" && r.URL.Query().Get("watch") == "true":
+			assert.Equal(t, "metadata.name=chat", r.URL.Query().Get("fieldSelector"))
+			if r.URL.Query().Get("resourceVersion") != "10" {
+				<-r.Context().Done()
+				return
+			}
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					json.NewEncoder(w).Encode(event)
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	sd, err := discovery.NewKubernetesDiscovery(&types.KubernetesDiscoveryConfig{
+		APIServer:     api.URL + "/",
+		TokenFile:     tokenFile,
+		CAFile:        filepath.Join(t.TempDir(), "ca.crt"),
+		Namespace:     "default",
+		RetryInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer sd.Stop()
+
+	// 首次list同步回调，按端口名选择端口，IPv6地址带方括号
+	updates := &discoveryUpdates{}
+	require.NoError(t, sd.Watch(&types.UpstreamDiscoveryConfig{Service: "chat", Namespace: "prod", Port: "http"}, updates.callback))
+	require.Equal(t, 1, updates.count())
+	assert.Equal(t, []types.UpstreamTargetConfig{
+		{URL: "http://10.0.0.1:8080", Weight: 1},
+		{URL: "http://[fd00::1]:8080", Weight: 1},
+	}, updates.last())
+
+	expect := func(count int, targets []types.UpstreamTargetConfig) {
+		require.Eventually(t, func() bool { return updates.count() == count }, 2*time.Second, 5*time.Millisecond)
+		assert.Equal(t, targets, updates.last())
+	}
+
+	// 新增实例
+	events <- map[string]interface{}{"type": "MODIFIED", "object": kubeEndpoints("11", "10.0.0.1", "10.0.0.2", "fd00::1")}
+	expect(2, []types.UpstreamTargetConfig{
+		{URL: "http://10.0.0.1:8080", Weight: 1},
+		{URL: "http://10.0.0.2:8080", Weight: 1},
+		{URL: "http://[fd00::1]:8080", Weight: 1},
+	})
+
+	// 移除实例
+	events <- map[string]interface{}{"type": "MODIFIED", "object": kubeEndpoints("12", "10.0.0.2")}
+	expect(3, []types.UpstreamTargetConfig{{URL: "http://10.0.0.2:8080", Weight: 1}})
+
+	// Endpoints删除时实例清空
+	events <- map[string]interface{}{"type": "DELETED", "object": kubeEndpoints("13")}
+	require.Eventually(t, func() bool { return updates.count() == 4 }, 2*time.Second, 5*time.Millisecond)
+	assert.Empty(t, updates.last())
+
+	// ERROR事件后重新list
+	events <- map[string]interface{}{"type": "ERROR"}
+	expect(5, []types.UpstreamTargetConfig{{URL: "http://10.0.0.9:8080", Weight: 1}})
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lists))
+}
+
+func TestKubernetesDiscoveryConfig(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err := discovery.NewKubernetesDiscovery(&types.KubernetesDiscoveryConfig{})
+	assert.Error(t, err)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("forbidden"))
+	}))
+	defer api.Close()
+
+	sd, err := discovery.NewKubernetesDiscovery(&types.KubernetesDiscoveryConfig{
+		APIServer: api.URL,
+		TokenFile: filepath.Join(t.TempDir(), "token"),
+		CAFile:    filepath.Join(t.TempDir(), "ca.crt"),
+	})
+	require.NoError(t, err)
+	defer sd.Stop()
+
+	// 首次list失败时Watch返回错误，不启动监听
+	updates := &discoveryUpdates{}
+	err = sd.Watch(&types.UpstreamDiscoveryConfig{Service: "chat"}, updates.callback)
+	assert.ErrorContains(t, err, "403")
+	assert.Equal(t, 0, updates.count())
+	assert.Error(t, sd.Watch(&types.UpstreamDiscoveryConfig{}, updates.callback))
+}
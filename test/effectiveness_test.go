@@ -0,0 +1,51 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/effectiveness"
+	"github.com/llm-aware-gateway/pkg/controlplane/timeseries"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestPolicyEffectivenessReport(t *testing.T) {
+	store := timeseries.NewTimeSeriesStore(&types.TimeSeriesConfig{
+		Resolution: time.Minute,
+		Retention:  time.Hour,
+	}, nil)
+
+	start := time.Now().Truncate(time.Minute).Add(-30 * time.Minute)
+	end := start.Add(10 * time.Minute)
+	window := 10 * time.Minute
+
+	// 生效前每分钟60个错误，生效期间每分钟6个，失效后每分钟30个
+	for i := 0; i < 10; i++ {
+		offset := time.Duration(i) * time.Minute
+		store.Record("cluster-1", start.Add(-window).Add(offset), 60)
+		store.Record("cluster-1", start.Add(offset), 6)
+		store.Record("cluster-1", end.Add(offset), 30)
+	}
+
+	policy := &types.Policy{
+		ClusterID:  "cluster-1",
+		PolicyType: types.PolicyTypeRateLimit,
+		CreateTime: start,
+	}
+	report := effectiveness.BuildReport(store, policy, end, window, &effectiveness.TrafficStats{Total: 1000, Blocked: 250})
+
+	assert.InDelta(t, 1.0, report.ErrorRateBefore, 1e-9)
+	assert.InDelta(t, 0.1, report.ErrorRateDuring, 1e-9)
+	assert.InDelta(t, 0.5, report.ErrorRateAfter, 1e-9)
+	assert.InDelta(t, 0.9, report.Reduction, 1e-9)
+	assert.InDelta(t, 0.5, report.Recurrence, 1e-9)
+	assert.Equal(t, int64(540), report.ErrorsAvoided)
+	assert.InDelta(t, 0.25, report.EstimatedUserImpact, 1e-9)
+	assert.Equal(t, effectiveness.VerdictEffective, report.Verdict)
+
+	// 生效前没有错误时无法判断
+	report = effectiveness.BuildReport(store, &types.Policy{ClusterID: "cluster-2", CreateTime: start}, end, window, nil)
+	assert.Equal(t, effectiveness.VerdictInconclusive, report.Verdict)
+}
@@ -0,0 +1,265 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestOpenAIEmbeddingProvider(t *testing.T) {
+	var requests, failures int32
+	var batchSizes, dimensions []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		// 首次请求返回503以验证重试
+		if atomic.AddInt32(&failures, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var req struct {
+			Model      string   `json:"model"`
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-test", req.Model)
+		batchSizes = append(batchSizes, len(req.Input))
+		dimensions = append(dimensions, req.Dimensions)
+
+		// 倒序返回，客户端需按index还原顺序
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		data := make([]item, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, item{Index: i, Embedding: []float32{float32(len(req.Input[i])), 1, 0, 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_EMBEDDING_KEY", "test-key")
+	service := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		Provider:  embedding.ProviderOpenAI,
+		BatchSize: 2,
+		CacheSize: 100,
+		Dimension: 4,
+		OpenAI: types.OpenAIEmbeddingConfig{
+			Endpoint:       server.URL,
+			APIKey:         "${TEST_EMBEDDING_KEY}",
+			Model:          "text-embedding-test",
+			RetryBackoff:   time.Millisecond,
+			SendDimensions: true,
+		},
+	})
+	assert.Equal(t, "text-embedding-test", service.ModelInfo().Model)
+	assert.Equal(t, 4, service.ModelInfo().Dimension)
+
+	vectors, err := service.EmbedBatch([]string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	require.Len(t, vectors, 3)
+	for _, vector := range vectors {
+		assert.InDelta(t, 1.0, vector[0]*vector[0]+vector[1]*vector[1], 1e-5, "vectors must be normalized")
+	}
+	assert.Less(t, vectors[0][0], vectors[1][0], "vectors must follow input order")
+	assert.Less(t, vectors[1][0], vectors[2][0], "vectors must follow input order")
+	assert.Equal(t, []int{2, 1}, batchSizes)
+	assert.Equal(t, []int{4, 4}, dimensions)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// 缓存命中时不再请求
+	_, err = service.EmbedText("bb")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// 返回维度与配置不一致
+	mismatched := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		Provider:  embedding.ProviderOpenAI,
+		BatchSize: 8,
+		CacheSize: 10,
+		Dimension: 8,
+		OpenAI:    types.OpenAIEmbeddingConfig{Endpoint: server.URL, APIKey: "test-key", Model: "text-embedding-test"},
+	})
+	_, err = mismatched.EmbedText("dimension")
+	assert.Error(t, err)
+
+	// 配置无效时回退到模拟模型
+	fallback := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		Provider:  embedding.ProviderOpenAI,
+		BatchSize: 8,
+		CacheSize: 10,
+		Dimension: 4,
+	})
+	assert.Equal(t, "mock-bge", fallback.ModelInfo().Model)
+}
+
+func TestWordPieceTokenizer(t *testing.T) {
+	vocab := []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "connection", "time", "##out", "to", "db", "-", "01", "!", "超", "时", "cafe"}
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(vocab, "\n")+"\n"), 0o644))
+
+	tokenizer, err := embedding.NewWordPieceTokenizer(path, 16, true)
+	require.NoError(t, err)
+
+	// 小写、去重音、标点拆分、WordPiece后缀和中文逐字切分
+	assert.Equal(t, []string{"connection", "time", "##out", "to", "db", "-", "01", "!", "超", "时", "[UNK]", "cafe"},
+		tokenizer.Tokens("Connection TIMEOUT to db-01!超时 xyz Café"))
+
+	ids := tokenizer.Encode("timeout")
+	assert.Equal(t, []int64{2, 5, 6, 3}, ids)
+	assert.Equal(t, int64(0), tokenizer.PadID())
+
+	// 超出最大长度时截断并保留[SEP]
+	truncated, err := embedding.NewWordPieceTokenizer(path, 4, true)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 4, 5, 3}, truncated.Encode("connection time to db"))
+
+	// 区分大小写时不转小写
+	cased, err := embedding.NewWordPieceTokenizer(path, 16, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[UNK]"}, cased.Tokens("Connection"))
+
+	require.NoError(t, os.WriteFile(path, []byte("hello\nworld\n"), 0o644))
+	_, err = embedding.NewWordPieceTokenizer(path, 16, true)
+	assert.Error(t, err, "special tokens are required")
+
+	// 未使用onnx构建标签时回退到模拟模型
+	service := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		Provider:  embedding.ProviderONNX,
+		ModelPath: filepath.Dir(path),
+		BatchSize: 8,
+		CacheSize: 10,
+		Dimension: 4,
+	})
+	assert.Equal(t, "mock-bge", service.ModelInfo().Model)
+}
+
+func TestONNXPoolOutput(t *testing.T) {
+	// 2个样本、3个位置、隐藏维度2，第一个样本最后一个位置是填充
+	output := []float32{
+		1, 2, 3, 4, 100, 100,
+		5, 6, 7, 8, 9, 10,
+	}
+	shape := []int64{2, 3, 2}
+	mask := []int64{1, 1, 0, 1, 1, 1}
+
+	// cls取每个样本第一个位置
+	vectors, err := embedding.PoolOutput(output, shape, mask, embedding.PoolingCLS)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 2}, {5, 6}}, vectors)
+
+	// mean只对掩码为1的位置求平均，填充位置不参与
+	vectors, err = embedding.PoolOutput(output, shape, mask, embedding.PoolingMean)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{2, 3}, {7, 8}}, vectors)
+
+	// [batch, hidden]输出已经是句向量，与池化方式无关
+	vectors, err = embedding.PoolOutput([]float32{1, 2, 3, 4}, []int64{2, 2}, nil, embedding.PoolingMean)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 2}, {3, 4}}, vectors)
+
+	_, err = embedding.PoolOutput(output, []int64{2, 2, 2}, mask, embedding.PoolingCLS)
+	assert.Error(t, err)
+	_, err = embedding.PoolOutput(output, []int64{12}, nil, embedding.PoolingCLS)
+	assert.Error(t, err)
+}
+
+func TestEmbeddingPipelineCoalescesCallers(t *testing.T) {
+	var mutex sync.Mutex
+	var batchSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mutex.Lock()
+		batchSizes = append(batchSizes, len(req.Input))
+		mutex.Unlock()
+
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		data := make([]item, 0, len(req.Input))
+		for i, input := range req.Input {
+			data = append(data, item{Index: i, Embedding: []float32{float32(len(input)), float32(strings.Count(input, "o"))}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	config := types.EmbeddingConfig{
+		Provider:  embedding.ProviderOpenAI,
+		BatchSize: 8,
+		CacheSize: 100,
+		Dimension: 2,
+		OpenAI:    types.OpenAIEmbeddingConfig{Endpoint: server.URL, Model: "text-embedding-test"},
+	}
+	direct := embedding.NewEmbeddingService(&config)
+	config.Pipeline = types.EmbeddingPipelineConfig{Enabled: true, MaxWait: 50 * time.Millisecond}
+	service := embedding.NewEmbeddingService(&config)
+	defer service.Close()
+
+	texts := make([]string, 20)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("request %d failed: %s", i, strings.Repeat("o", i))
+	}
+	expected, err := direct.EmbedBatch(texts)
+	require.NoError(t, err)
+	mutex.Lock()
+	batchSizes = nil
+	mutex.Unlock()
+
+	// 并发的单条调用被合并为不超过batch_size的批次，每个调用方拿到自己的向量
+	vectors := make([][]float32, len(texts))
+	var wg sync.WaitGroup
+	for i := range texts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vector, err := service.EmbedText(texts[i])
+			assert.NoError(t, err)
+			vectors[i] = vector
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, expected, vectors, "each caller must receive its own vector")
+	mutex.Lock()
+	assert.Less(t, len(batchSizes), len(texts))
+	for _, size := range batchSizes {
+		assert.LessOrEqual(t, size, 8)
+	}
+	mutex.Unlock()
+
+	// 大批量按batch_size拆分后并行提交
+	more := make([]string, 20)
+	for i := range more {
+		more[i] = fmt.Sprintf("batch text %02d", i)
+	}
+	batch, err := service.EmbedBatch(more)
+	require.NoError(t, err)
+	assert.Len(t, batch, len(more))
+
+	require.NoError(t, service.Close())
+	_, err = service.EmbedText("after close")
+	assert.Error(t, err)
+}
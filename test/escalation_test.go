@@ -0,0 +1,145 @@
+package test
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/policy"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// memoryConfigStore 内存配置中心
+type memoryConfigStore struct {
+	values map[string]string
+	mutex  sync.Mutex
+}
+
+func newMemoryConfigStore() *memoryConfigStore {
+	return &memoryConfigStore{values: make(map[string]string)}
+}
+
+func (s *memoryConfigStore) Put(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memoryConfigStore) Get(key string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.values[key], nil
+}
+
+func (s *memoryConfigStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *memoryConfigStore) Watch(prefix string) (<-chan *interfaces.ConfigChangeEvent, error) {
+	return make(chan *interfaces.ConfigChangeEvent), nil
+}
+
+func (s *memoryConfigStore) GetWithPrefix(prefix string) (map[string]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	values := make(map[string]string)
+	for key, value := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (s *memoryConfigStore) Close() error {
+	return nil
+}
+
+// recordingNotifier 记录告警事件
+type recordingNotifier struct {
+	events []*types.AlertEvent
+}
+
+func (n *recordingNotifier) Notify(event *types.AlertEvent) {
+	n.events = append(n.events, event)
+}
+
+func TestPolicyEscalation(t *testing.T) {
+	registry, err := policy.NewTemplateRegistry(policy.DefaultTemplates())
+	require.NoError(t, err)
+
+	store := newMemoryConfigStore()
+	escalator, err := policy.NewEscalator(&types.EscalationConfig{
+		Enabled:          true,
+		StageDuration:    time.Minute,
+		RecoveryDuration: time.Minute,
+		Page:             true,
+	}, registry, store, 5*time.Minute)
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	escalator.SetNotifier(notifier)
+
+	currentTemplate := func() string {
+		value, _ := store.Get("/policies/c1")
+		if value == "" {
+			return ""
+		}
+		var p types.Policy
+		require.NoError(t, json.Unmarshal([]byte(value), &p))
+		return p.Template
+	}
+
+	start := time.Now()
+	require.NoError(t, escalator.Observe("c1", 10, 1, 0.3, true, start))
+	assert.Equal(t, policy.TemplateSoftThrottle, currentTemplate())
+
+	// 阶段未满或错误速率未继续增长时不升级
+	require.NoError(t, escalator.Observe("c1", 20, 1, 0.3, true, start.Add(30*time.Second)))
+	require.NoError(t, escalator.Observe("c1", 8, 0, 0.3, true, start.Add(2*time.Minute)))
+	assert.Equal(t, 0, escalator.Stage("c1"))
+
+	require.NoError(t, escalator.Observe("c1", 20, 1, 0.3, true, start.Add(3*time.Minute)))
+	assert.Equal(t, policy.TemplateHardThrottle, currentTemplate())
+
+	require.NoError(t, escalator.Observe("c1", 40, 1, 0.3, true, start.Add(5*time.Minute)))
+	assert.Equal(t, policy.TemplateShortBreak, currentTemplate())
+	value, _ := store.Get("/policies/c1")
+	var p types.Policy
+	require.NoError(t, json.Unmarshal([]byte(value), &p))
+	assert.GreaterOrEqual(t, p.Severity, 0.8)
+
+	// 最后阶段仍在增长时只通知一次值班
+	require.NoError(t, escalator.Observe("c1", 80, 1, 0.3, true, start.Add(7*time.Minute)))
+	require.NoError(t, escalator.Observe("c1", 160, 1, 0.3, true, start.Add(9*time.Minute)))
+
+	// 恢复后逐级降级，最后删除策略
+	recovered := start.Add(10 * time.Minute)
+	for i := 0; i <= 4; i++ {
+		require.NoError(t, escalator.Observe("c1", 1, 0, 0, false, recovered.Add(time.Duration(i)*time.Minute)))
+	}
+	assert.Equal(t, -1, escalator.Stage("c1"))
+	assert.Equal(t, "", currentTemplate())
+
+	var actions []string
+	for _, event := range escalator.Events("c1") {
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []string{
+		policy.ActionStart, policy.ActionEscalate, policy.ActionEscalate, policy.ActionPage,
+		policy.ActionDeescalate, policy.ActionDeescalate, policy.ActionResolve,
+	}, actions)
+
+	require.Len(t, notifier.events, len(actions))
+	assert.Equal(t, types.AlertSeverityCritical, notifier.events[3].Severity)
+}
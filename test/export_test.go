@@ -0,0 +1,137 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/api"
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// exportLine 导出的一行，记录行带cursor和data，结果行带kind和complete
+type exportLine struct {
+	Cursor     string          `json:"cursor"`
+	Data       json.RawMessage `json:"data"`
+	Kind       string          `json:"kind"`
+	Count      int             `json:"count"`
+	Complete   bool            `json:"complete"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+func TestAdminExport(t *testing.T) {
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.95,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          100,
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 100, Dimension: 64}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	server := api.NewServer(&types.ControlPlaneAPIConfig{
+		APIKeys: []string{"admin-key"},
+		Export:  types.ExportConfig{Enabled: true, PageSize: 2, RatePerSecond: 10000, EventBuffer: 4},
+	}, engine)
+	require.NoError(t, clustering.AttachEventObserver(engine, server.ObserveEvent))
+
+	messages := []string{"database connection refused", "upstream timeout after 30s", "invalid api token", "quota exceeded for tenant"}
+	for i := 0; i < 6; i++ {
+		require.NoError(t, engine.ProcessErrorEvent(&types.ErrorEvent{
+			EventID:      fmt.Sprintf("event-%d", i),
+			ServiceName:  "chat",
+			ErrorMessage: messages[i%len(messages)],
+			StatusCode:   500,
+		}))
+	}
+
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+
+	get := func(path string) (int, []exportLine) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("X-API-Key", "admin-key")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var lines []exportLine
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var line exportLine
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		return resp.StatusCode, lines
+	}
+
+	// 簇按页导出，最后一行为结果
+	clusters, err := engine.GetAllClusters()
+	require.NoError(t, err)
+	status, lines := get("/admin/export/clusters")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, lines, len(clusters)+1)
+	trailer := lines[len(lines)-1]
+	assert.Equal(t, "clusters", trailer.Kind)
+	assert.True(t, trailer.Complete)
+	assert.Equal(t, len(clusters), trailer.Count)
+
+	var cluster types.Cluster
+	require.NoError(t, json.Unmarshal(lines[0].Data, &cluster))
+	assert.Equal(t, lines[0].Cursor, cluster.ID)
+	assert.NotEmpty(t, cluster.Members)
+
+	// 事件缓冲区只保留最近4条，可从任意记录的cursor续传
+	status, lines = get("/admin/export/events")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, lines, 5)
+	assert.Equal(t, "3", lines[0].Cursor)
+	var event struct {
+		Seq   uint64            `json:"seq"`
+		Event *types.ErrorEvent `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(lines[3].Data, &event))
+	assert.Equal(t, "event-5", event.Event.EventID)
+	assert.NotEmpty(t, event.Event.ClusterID)
+
+	_, lines = get("/admin/export/events?cursor=4")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "5", lines[0].Cursor)
+
+	status, _ = get("/admin/export/events?cursor=abc")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 策略需要配置中心，全局和区域策略按键排序导出
+	status, _ = get("/admin/export/policies")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	store := newMemoryConfigStore()
+	for _, key := range []string{utils.PolicyKey("", "cluster-a"), utils.PolicyKey("eu", "cluster-b"), utils.PolicyKey("", "cluster-c")} {
+		value, _ := json.Marshal(&types.Policy{ClusterID: key, PolicyType: types.RATE_LIMIT})
+		require.NoError(t, store.Put(key, string(value)))
+	}
+	require.NoError(t, store.Put("/other/key", "ignored"))
+	server.SetConfigStore(store)
+
+	status, lines = get("/admin/export/policies")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, lines, 4)
+	assert.Equal(t, utils.PolicyKey("", "cluster-a"), lines[0].Cursor)
+	assert.Equal(t, utils.PolicyKey("eu", "cluster-b"), lines[2].Cursor)
+	assert.True(t, lines[3].Complete)
+
+	status, _ = get("/admin/export/secrets")
+	assert.Equal(t, http.StatusNotFound, status)
+}
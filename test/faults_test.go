@@ -0,0 +1,54 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/testhooks"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestFaultInjection(t *testing.T) {
+	if !testhooks.Compiled {
+		t.Skip("test hooks not compiled in, run with -tags testhooks")
+	}
+	gin.SetMode(gin.TestMode)
+
+	_, err := testhooks.New(&types.TestHooksConfig{Faults: []types.FaultRule{{ID: "empty", Percentage: 50}}})
+	assert.Error(t, err)
+
+	hooks, err := testhooks.New(&types.TestHooksConfig{Enabled: true, Faults: []types.FaultRule{
+		{ID: "chat-errors", PathPrefix: "/api/chat", Percentage: 100, Status: http.StatusServiceUnavailable},
+	}})
+	require.NoError(t, err)
+
+	engine := gin.New()
+	hooks.RegisterAdmin(engine.Group("/admin"))
+	engine.Use(hooks.Middleware())
+	engine.Any("/api/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader(body)))
+		return w
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, do("POST", "/api/chat/completions", nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/api/models", nil).Code)
+
+	// 通过管理接口调整规则
+	w := do("PUT", "/admin/faults/models-down", []byte(`{"path_prefix":"/api/models","percentage":100,"status":502}`))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusBadGateway, do("GET", "/api/models", nil).Code)
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/admin/faults/bad", []byte(`{"percentage":100,"status":200}`)).Code)
+	assert.Equal(t, http.StatusOK, do("DELETE", "/admin/faults/chat-errors", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/admin/faults/chat-errors", nil).Code)
+	assert.Equal(t, http.StatusOK, do("POST", "/api/chat/completions", nil).Code)
+}
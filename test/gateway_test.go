@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/llm-aware-gateway/pkg/gateway"
+	"github.com/llm-aware-gateway/pkg/gateway/testhooks"
 	"github.com/llm-aware-gateway/pkg/types"
 )
 
@@ -53,6 +54,9 @@ func TestGatewayBasicFunctionality(t *testing.T) {
 			Port:    9090,
 			Path:    "/metrics",
 		},
+		TestHooks: types.TestHooksConfig{
+			Enabled: true,
+		},
 	}
 
 	// 创建网关实例
@@ -104,6 +108,10 @@ func TestGatewayBasicFunctionality(t *testing.T) {
 	})
 
 	t.Run("模拟错误请求", func(t *testing.T) {
+		if !testhooks.Compiled {
+			t.Skip("test hooks not compiled in, run with -tags testhooks")
+		}
+
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest("GET", "/api/test?simulate_error=true", nil)
 		router.ServeHTTP(w, req)
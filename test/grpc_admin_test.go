@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"github.com/llm-aware-gateway/pkg/grpcadmin"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// toggleComponent 健康状态可切换的组件
+type toggleComponent struct {
+	healthy int32
+}
+
+func (c *toggleComponent) Health() types.ComponentHealth {
+	if atomic.LoadInt32(&c.healthy) == 1 {
+		return types.ComponentHealth{Status: types.ComponentHealthy}
+	}
+	return types.ComponentHealth{Status: types.ComponentUnhealthy, Message: "down"}
+}
+
+func (c *toggleComponent) Stats() map[string]interface{} {
+	return nil
+}
+
+func TestGRPCAdminHealth(t *testing.T) {
+	component := &toggleComponent{healthy: 1}
+	server := grpcadmin.NewServer("gateway", &types.GRPCAdminConfig{
+		Host:          "127.0.0.1",
+		Reflection:    true,
+		CheckInterval: 20 * time.Millisecond,
+	}, func() map[string]interface{} {
+		return map[string]interface{}{"rate_limiter": component}
+	})
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	conn, err := grpc.Dial(server.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := healthpb.NewHealthClient(conn)
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status("gateway.rate_limiter"))
+
+	// 组件不可用时整体和该组件都变为NOT_SERVING
+	atomic.StoreInt32(&component.healthy, 0)
+	assert.Eventually(t, func() bool {
+		return status("gateway") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status("gateway.rate_limiter"))
+
+	// 服务反射列出健康检查服务
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+
+	services := make([]string, 0)
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	assert.Contains(t, services, "grpc.health.v1.Health")
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestIDGenerators(t *testing.T) {
+	defer utils.SetIDGenerator(mustIDGenerator(t, &types.IDConfig{}))
+
+	// 默认格式保持不变
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), utils.GenerateID())
+	assert.Regexp(t, regexp.MustCompile(`^cluster_\d+_[0-9a-f]{8}$`), utils.GenerateClusterID())
+
+	ulid := mustIDGenerator(t, &types.IDConfig{Generator: utils.IDGeneratorULID})
+	first := ulid.NewID()
+	assert.Regexp(t, regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`), first)
+	assertSortedUnique(t, ulid, 5000)
+
+	// 时间前缀随时间递增
+	time.Sleep(2 * time.Millisecond)
+	assert.Greater(t, ulid.NewID()[:10], first[:10])
+
+	nodeA := mustIDGenerator(t, &types.IDConfig{Generator: utils.IDGeneratorSnowflake, NodeID: 1})
+	nodeB := mustIDGenerator(t, &types.IDConfig{Generator: utils.IDGeneratorSnowflake, NodeID: 2})
+	assert.Len(t, nodeA.NewID(), 19)
+	assertSortedUnique(t, nodeA, 10000)
+
+	// 不同节点同时生成也不会冲突
+	seen := make(map[string]bool)
+	for i := 0; i < 2000; i++ {
+		for _, id := range []string{nodeA.NewID(), nodeB.NewID()} {
+			assert.False(t, seen[id], "duplicate id %s", id)
+			seen[id] = true
+		}
+	}
+
+	// 簇ID带前缀且按创建顺序排列
+	utils.SetIDGenerator(ulid)
+	clusters := []string{utils.GenerateClusterID(), utils.GenerateClusterID(), utils.GenerateClusterID()}
+	assert.True(t, strings.HasPrefix(clusters[0], "cluster_"))
+	assert.True(t, sort.StringsAreSorted(clusters))
+
+	_, err := utils.NewIDGenerator(&types.IDConfig{Generator: utils.IDGeneratorSnowflake, NodeID: 1024})
+	assert.Error(t, err)
+	_, err = utils.NewIDGenerator(&types.IDConfig{Generator: "uuid"})
+	assert.Error(t, err)
+}
+
+func mustIDGenerator(t *testing.T, config *types.IDConfig) utils.IDGenerator {
+	generator, err := utils.NewIDGenerator(config)
+	require.NoError(t, err)
+	return generator
+}
+
+func assertSortedUnique(t *testing.T, generator utils.IDGenerator, n int) {
+	previous := ""
+	for i := 0; i < n; i++ {
+		id := generator.NewID()
+		require.Greater(t, id, previous)
+		previous = id
+	}
+}
@@ -0,0 +1,164 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/api"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// recordingEngine 记录送入聚类管道的事件
+type recordingEngine struct {
+	interfaces.ClusteringEngine
+	events []*types.ErrorEvent
+	mutex  sync.Mutex
+}
+
+func (e *recordingEngine) ProcessErrorEvent(event *types.ErrorEvent) error {
+	e.mutex.Lock()
+	e.events = append(e.events, event)
+	e.mutex.Unlock()
+	return nil
+}
+
+// ingestResult 接入接口的响应
+type ingestResult struct {
+	code     int
+	Accepted int    `json:"accepted"`
+	Invalid  int    `json:"invalid"`
+	Dropped  int    `json:"dropped"`
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+}
+
+// postEvents 向控制面推送事件，headers为认证请求头
+func postEvents(t *testing.T, server *api.Server, body string, headers map[string]string) ingestResult {
+	req := httptest.NewRequest(http.MethodPost, "/v1/events", strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	server.Router().ServeHTTP(w, req)
+
+	result := ingestResult{code: w.Code}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result), w.Body.String())
+	return result
+}
+
+func TestEventIngestionAuth(t *testing.T) {
+	server := api.NewServer(&types.ControlPlaneAPIConfig{APIKeys: []string{"ingest-key"}}, &recordingEngine{})
+	body := `[{"error_message":"upstream timeout"}]`
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		code    int
+	}{
+		{name: "missing key", code: http.StatusUnauthorized},
+		{name: "wrong key", headers: map[string]string{"X-API-Key": "other-key"}, code: http.StatusUnauthorized},
+		{name: "wrong bearer", headers: map[string]string{"Authorization": "Bearer other-key"}, code: http.StatusUnauthorized},
+		{name: "non-bearer authorization", headers: map[string]string{"Authorization": "Basic ingest-key"}, code: http.StatusUnauthorized},
+		{name: "api key header", headers: map[string]string{"X-API-Key": "ingest-key"}, code: http.StatusAccepted},
+		{name: "bearer token", headers: map[string]string{"Authorization": "Bearer ingest-key"}, code: http.StatusAccepted},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := postEvents(t, server, body, tc.headers)
+			assert.Equal(t, tc.code, result.code)
+			if tc.code == http.StatusUnauthorized {
+				assert.Equal(t, "UNAUTHORIZED", result.Code)
+				assert.Zero(t, result.Accepted)
+			}
+		})
+	}
+
+	// 未配置密钥时拒绝所有请求
+	server = api.NewServer(&types.ControlPlaneAPIConfig{}, &recordingEngine{})
+	assert.Equal(t, http.StatusUnauthorized, postEvents(t, server, body, map[string]string{"X-API-Key": "ingest-key"}).code)
+}
+
+func TestEventIngestionBatchValidation(t *testing.T) {
+	server := api.NewServer(&types.ControlPlaneAPIConfig{APIKeys: []string{"ingest-key"}, MaxBatchSize: 3}, &recordingEngine{})
+	auth := map[string]string{"X-API-Key": "ingest-key"}
+
+	events := func(n int) string {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"error_message":"error %d"}`, i)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+
+	cases := []struct {
+		name     string
+		body     string
+		code     int
+		accepted int
+		invalid  int
+		error    string
+	}{
+		{name: "malformed json", body: `[{"error_message":`, code: http.StatusBadRequest, error: "invalid event batch"},
+		{name: "wrong type", body: `{"events":"oops"}`, code: http.StatusBadRequest, error: "invalid event batch"},
+		{name: "empty array", body: `[]`, code: http.StatusBadRequest, error: "no events in request"},
+		{name: "empty wrapper", body: `{"events":[]}`, code: http.StatusBadRequest, error: "no events in request"},
+		{name: "oversized batch", body: events(4), code: http.StatusRequestEntityTooLarge, error: "batch size 4 exceeds limit 3"},
+		{name: "oversized body", body: `[{"error_message":"` + strings.Repeat("x", 4<<20) + `"}]`, code: http.StatusRequestEntityTooLarge, error: "request body too large"},
+		{name: "batch at limit", body: events(3), code: http.StatusAccepted, accepted: 3},
+		{name: "wrapped batch", body: `{"events":[{"error_message":"a"}]}`, code: http.StatusAccepted, accepted: 1},
+		// 缺少消息的事件和null计为无效，不影响其余事件
+		{name: "invalid events", body: `[{"error_message":"a"},{"service_name":"chat"},null]`, code: http.StatusAccepted, accepted: 1, invalid: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := postEvents(t, server, tc.body, auth)
+			assert.Equal(t, tc.code, result.code)
+			assert.Equal(t, tc.accepted, result.Accepted)
+			assert.Equal(t, tc.invalid, result.Invalid)
+			if tc.error != "" {
+				assert.Contains(t, result.Error, tc.error)
+			}
+		})
+	}
+}
+
+func TestEventIngestionQueue(t *testing.T) {
+	auth := map[string]string{"X-API-Key": "ingest-key"}
+
+	// 队列满时丢弃，全部丢弃时返回429提示退避
+	server := api.NewServer(&types.ControlPlaneAPIConfig{APIKeys: []string{"ingest-key"}, IngestQueueSize: 2}, &recordingEngine{})
+	result := postEvents(t, server, `[{"error_message":"a"},{"error_message":"b"},{"error_message":"c"}]`, auth)
+	assert.Equal(t, http.StatusAccepted, result.code)
+	assert.Equal(t, 2, result.Accepted)
+	assert.Equal(t, 1, result.Dropped)
+	result = postEvents(t, server, `[{"error_message":"d"}]`, auth)
+	assert.Equal(t, http.StatusTooManyRequests, result.code)
+	assert.Equal(t, 1, result.Dropped)
+
+	// 接入的事件补全字段并脱敏后送入聚类管道，推送方设置的簇ID被忽略
+	engine := &recordingEngine{}
+	server = api.NewServer(&types.ControlPlaneAPIConfig{Host: "127.0.0.1", APIKeys: []string{"ingest-key"}}, engine)
+	require.NoError(t, server.Start())
+	result = postEvents(t, server, `{"events":[{"error_message":"login failed for alice@example.com","cluster_id":"forged"}]}`, auth)
+	assert.Equal(t, http.StatusAccepted, result.code)
+	require.NoError(t, server.Stop())
+
+	require.Len(t, engine.events, 1)
+	event := engine.events[0]
+	assert.Equal(t, "login failed for [EMAIL]", event.ErrorMessage)
+	assert.Equal(t, "unknown", event.ServiceName)
+	assert.NotEmpty(t, event.EventID)
+	assert.False(t, event.Timestamp.IsZero())
+	assert.Empty(t, event.ClusterID)
+}
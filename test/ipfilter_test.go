@@ -0,0 +1,151 @@
+package test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/ipfilter"
+	"github.com/llm-aware-gateway/pkg/gateway/listener"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestIPFilterAllowDeny(t *testing.T) {
+	filter, err := ipfilter.NewIPFilter(&types.IPFilterConfig{
+		Enabled: true,
+		Allow:   []string{"10.0.0.0/8", "2001:db8::/32"},
+		Deny:    []string{"10.1.0.0/16", "10.2.3.4"},
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "", filter.Check("10.0.0.1"))
+	assert.Equal(t, "", filter.Check("::ffff:10.0.0.1"))
+	assert.Equal(t, "", filter.Check("2001:db8::1"))
+	assert.Equal(t, ipfilter.ReasonDenied, filter.Check("10.1.2.3"))
+	assert.Equal(t, ipfilter.ReasonDenied, filter.Check("10.2.3.4"))
+	assert.Equal(t, ipfilter.ReasonNotAllowed, filter.Check("192.168.1.1"))
+	assert.Equal(t, ipfilter.ReasonInvalidIP, filter.Check("not-an-ip"))
+
+	// etcd中的名单替换配置名单，删除后恢复
+	filter.OnUpdate(ipfilter.KeyAllow, []byte(`["192.168.0.0/16"]`), false)
+	assert.Equal(t, "", filter.Check("192.168.1.1"))
+	assert.Equal(t, ipfilter.ReasonNotAllowed, filter.Check("10.0.0.1"))
+
+	filter.OnUpdate(ipfilter.KeyAllow, []byte(`["bad"]`), false)
+	assert.Equal(t, "", filter.Check("192.168.1.1"), "invalid update must be ignored")
+
+	filter.OnUpdate(ipfilter.KeyAllow, nil, true)
+	assert.Equal(t, "", filter.Check("10.0.0.1"))
+
+	_, err = ipfilter.NewIPFilter(&types.IPFilterConfig{Deny: []string{"10.0.0.0/33"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestIPv6ClientAddresses(t *testing.T) {
+	// 同一客户端的不同写法归一为同一个键
+	assert.Equal(t, "2001:db8::1", utils.NormalizeIP("2001:DB8:0:0::1"))
+	assert.Equal(t, "2001:db8::1", utils.NormalizeIP("[2001:db8::1]:443"))
+	assert.Equal(t, "fe80::1", utils.NormalizeIP("fe80::1%eth0"))
+	assert.Equal(t, "10.0.0.1", utils.NormalizeIP("::ffff:10.0.0.1"))
+	assert.Equal(t, "10.0.0.1", utils.NormalizeIP("10.0.0.1:8080"))
+	assert.Equal(t, "not-an-ip", utils.NormalizeIP("not-an-ip"))
+
+	filter, err := ipfilter.NewIPFilter(&types.IPFilterConfig{
+		Enabled: true,
+		Allow:   []string{"2001:db8::/32", "::ffff:192.168.0.0/112", "fe80::/10"},
+		Deny:    []string{"[2001:db8::bad]"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", filter.Check("[2001:db8::1]:443"))
+	assert.Equal(t, "", filter.Check("192.168.3.4"), "mapped prefix must match plain IPv4 clients")
+	assert.Equal(t, "", filter.Check("fe80::1%eth0"), "zone must not prevent matching")
+	assert.Equal(t, ipfilter.ReasonDenied, filter.Check("2001:DB8::BAD"))
+	assert.Equal(t, ipfilter.ReasonNotAllowed, filter.Check("2001:db9::1"))
+
+	_, err = ipfilter.NewIPFilter(&types.IPFilterConfig{Allow: []string{"fe80::/10%eth0"}}, nil)
+	assert.Error(t, err)
+
+	// IPv6对端和经可信IPv6代理转发的客户端
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies([]string{"2001:db8:ffff::/48"}))
+	router.Use(filter.Middleware())
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, utils.ExtractClientIP(c)) })
+
+	request := func(remote, forwarded string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("[2001:db8::1]:5000", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2001:db8::1", w.Body.String())
+
+	w = request("[2001:db8:ffff::1]:5000", "2001:DB8::bad")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = request("[2001:db8::2]:5000", "2001:db8::bad")
+	assert.Equal(t, http.StatusOK, w.Code, "untrusted peer must not spoof the forwarded address")
+}
+
+func TestListenAddress(t *testing.T) {
+	network, addr, err := listener.Address("", "::", 8080)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "[::]:8080", addr)
+
+	_, addr, err = listener.Address("tcp6", "[2001:db8::1]", 443)
+	require.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]:443", addr)
+
+	_, addr, err = listener.Address("tcp4", "0.0.0.0", 80)
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:80", addr)
+
+	_, _, err = listener.Address("tcp4", "::1", 80)
+	assert.Error(t, err)
+	_, _, err = listener.Address("tcp6", "127.0.0.1", 80)
+	assert.Error(t, err)
+	_, _, err = listener.Address("udp", "", 80)
+	assert.Error(t, err)
+	_, _, err = listener.Address("tcp", "2001:db8::zz", 80)
+	assert.Error(t, err)
+
+	// 双栈监听同时接受IPv4和IPv6连接（环境不支持IPv6时跳过）
+	network, addr, err = listener.Address("tcp", "::", 0)
+	require.NoError(t, err)
+	ln, err := listener.Listen(network, addr, false)
+	if err != nil {
+		t.Skipf("ipv6 not available: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		require.NoError(t, err, host)
+		conn.Close()
+	}
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/breaker"
+	"github.com/llm-aware-gateway/pkg/gateway/isolation"
+	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// faultyVectorAgent 簇识别阻塞或panic的向量代理
+type faultyVectorAgent struct {
+	block chan struct{}
+	panic bool
+}
+
+func (a *faultyVectorAgent) IdentifyCluster(string) (string, error) { return "", nil }
+
+func (a *faultyVectorAgent) IdentifyClusterWithScore(string) (string, float64, error) {
+	if a.panic {
+		panic("embedding index corrupted")
+	}
+	if a.block != nil {
+		<-a.block
+	}
+	return "cluster-1", 0.9, nil
+}
+
+func (a *faultyVectorAgent) GenerateVector(string) ([]float32, error)       { return nil, nil }
+func (a *faultyVectorAgent) UpdateClusters(map[string]*types.Cluster) error { return nil }
+func (a *faultyVectorAgent) ApplySnapshot(*types.ClusterSnapshot) error     { return nil }
+
+func TestStageIsolation(t *testing.T) {
+	config := &types.IsolationConfig{
+		Timeout:          20 * time.Millisecond,
+		FailureThreshold: 2,
+		BypassDuration:   time.Hour,
+	}
+
+	t.Run("panic and timeout are skipped", func(t *testing.T) {
+		agent := &faultyVectorAgent{panic: true}
+		isolated := isolation.VectorAgent(agent, config, nil)
+
+		_, _, err := isolated.IdentifyClusterWithScore("NullPointerException")
+		assert.ErrorIs(t, err, isolation.ErrSkipped)
+
+		agent.panic = false
+		clusterID, _, err := isolated.IdentifyClusterWithScore("NullPointerException")
+		require.NoError(t, err)
+		assert.Equal(t, "cluster-1", clusterID)
+
+		agent.block = make(chan struct{})
+		defer close(agent.block)
+		start := time.Now()
+		_, _, err = isolated.IdentifyClusterWithScore("NullPointerException")
+		assert.ErrorIs(t, err, isolation.ErrSkipped)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("bypass after consecutive failures", func(t *testing.T) {
+		stage := isolation.NewStage("test", config, nil)
+		for i := 0; i < 2; i++ {
+			assert.False(t, stage.Run(func() { panic("boom") }))
+		}
+		assert.True(t, stage.Bypassed())
+		assert.Equal(t, types.ComponentDegraded, stage.Health().Status)
+
+		called := false
+		assert.False(t, stage.Run(func() { called = true }))
+		assert.False(t, called)
+
+		stats := stage.Stats()
+		assert.EqualValues(t, 2, stats["panics"])
+		assert.EqualValues(t, 3, stats["skipped"])
+		assert.EqualValues(t, 1, stats["bypasses"])
+	})
+
+	t.Run("request path survives a panicking agent", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		isolated := isolation.VectorAgent(&faultyVectorAgent{panic: true}, config, nil)
+		m := middleware.NewMiddleware(nil, breaker.NewClusterCircuitBreaker(&types.BreakerConfig{}), nil, isolated, nil)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("error", errors.New("upstream timeout"))
+			c.Next()
+		}, m.CircuitBreaker())
+		router.GET("/api", func(c *gin.Context) {
+			c.String(http.StatusOK, c.GetString("cluster_id"))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+}
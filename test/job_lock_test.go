@@ -0,0 +1,32 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/lock"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestLocalJobLock(t *testing.T) {
+	jobLock := lock.NewJobLock(&types.JobLockConfig{})
+
+	var nested bool
+	ran, err := jobLock.Run("recluster", func() error {
+		// 同名任务执行期间再次执行被跳过，不同任务不受影响
+		skipped, _ := jobLock.Run("recluster", func() error { return nil })
+		assert.False(t, skipped)
+		nested, _ = jobLock.Run("policy-evaluation", func() error { return nil })
+		return errors.New("boom")
+	})
+	assert.True(t, ran)
+	assert.EqualError(t, err, "boom")
+	assert.True(t, nested)
+
+	// 任务结束后释放
+	ran, err = jobLock.Run("recluster", func() error { return nil })
+	assert.True(t, ran)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestReClusterSelectsK(t *testing.T) {
+	for _, method := range []string{types.KSelectionSilhouette, types.KSelectionElbow} {
+		engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+			SimilarityThreshold:  0.9,
+			ReclusteringInterval: time.Hour,
+			MinClusterSize:       1,
+			MaxClusters:          20,
+			KSelection:           method,
+			MinK:                 2,
+			MaxK:                 6,
+		}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 10, Dimension: 3}),
+			&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+		// 三组相互分离的向量，每个向量各自成簇
+		groups := [][]float32{{10, 0, 0}, {0, 10, 0}, {0, 0, 10}}
+		for i := 0; i < 12; i++ {
+			center := groups[i%3]
+			vector := []float32{center[0] + float32(i)*0.01, center[1], center[2] + float32(i)*0.01}
+			_, err := engine.CreateNewCluster(&types.ErrorEvent{EventID: fmt.Sprintf("event-%d", i)}, vector)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, engine.ReCluster())
+
+		report := engine.ReclusterReport()
+		require.NotNil(t, report, method)
+		assert.Equal(t, method, report.Method)
+		assert.Equal(t, 3, report.K, method)
+		assert.Equal(t, 3, report.Clusters, method)
+		assert.Len(t, report.Scores, 5, method)
+
+		clusters, _ := engine.GetAllClusters()
+		assert.Len(t, clusters, 3, method)
+	}
+}
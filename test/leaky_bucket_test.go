@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestLeakyBucket(t *testing.T) {
+	lb := limiter.NewLeakyBucket(3, 20) // 每50ms放行一个，最多排队3个
+
+	assert.True(t, lb.Allow())
+	assert.False(t, lb.Allow(), "no burst without waiting")
+
+	// 排满3个位置后拒绝，放行时刻间隔固定
+	var delays []time.Duration
+	for {
+		delay, ok := lb.Reserve()
+		if !ok {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	require.Len(t, delays, 3)
+	for i := 1; i < len(delays); i++ {
+		assert.InDelta(t, float64(50*time.Millisecond), float64(delays[i]-delays[i-1]), float64(5*time.Millisecond))
+	}
+	assert.EqualValues(t, 0, lb.GetTokens())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, lb.Wait(ctx), "canceled while queued")
+}
+
+func TestClusterLeakyBucketPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 20, MaxRate: 100}, nil)
+	defer rl.Cleanup()
+
+	require.NoError(t, rl.UpdatePolicy("llm-chat", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{Algorithm: types.RateLimitLeakyBucket},
+	}))
+	stats, err := rl.GetStats("llm-chat")
+	require.NoError(t, err)
+	assert.Equal(t, types.RateLimitLeakyBucket, stats.Algorithm)
+
+	// 同时到达的请求按固定间隔依次放行，而不是一次性突发
+	var mutex sync.Mutex
+	var admitted []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("POST", "/v1/chat", nil)
+			c.Set("cluster_id", "llm-chat")
+			if rl.Allow(c) {
+				mutex.Lock()
+				admitted = append(admitted, time.Now())
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, admitted, 5)
+	first, last := admitted[0], admitted[0]
+	for _, at := range admitted {
+		if at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	assert.GreaterOrEqual(t, last.Sub(first), 180*time.Millisecond)
+}
@@ -0,0 +1,371 @@
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// stubRedis 本地RESP2服务，只实现状态交接用到的哈希、过期和事务命令
+type stubRedis struct {
+	listener net.Listener
+	hashes   map[string]map[string]string
+	expires  map[string]time.Duration
+	commands []string
+	mutex    sync.Mutex
+}
+
+// newStubRedis 启动Redis桩服务
+func newStubRedis(t *testing.T) *stubRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	r := &stubRedis{
+		listener: listener,
+		hashes:   make(map[string]map[string]string),
+		expires:  make(map[string]time.Duration),
+	}
+	t.Cleanup(func() { listener.Close() })
+	go r.serve()
+	return r
+}
+
+func (r *stubRedis) addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *stubRedis) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+// handle 处理单个连接，MULTI之后的命令排队到EXEC时执行
+func (r *stubRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	var queued [][]string
+	inTx := false
+	for {
+		args, err := readRESPCommand(reader)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			inTx, queued = true, nil
+			reply = "+OK\r\n"
+		case name == "EXEC":
+			reply = fmt.Sprintf("*%d\r\n", len(queued))
+			for _, cmd := range queued {
+				reply += r.execute(cmd)
+			}
+			inTx, queued = false, nil
+		case inTx:
+			queued = append(queued, args)
+			reply = "+QUEUED\r\n"
+		default:
+			reply = r.execute(args)
+		}
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// execute 执行命令并返回编码后的应答，HELLO等未实现的命令返回错误，客户端回退到RESP2
+func (r *stubRedis) execute(args []string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	name := strings.ToUpper(args[0])
+	r.commands = append(r.commands, name)
+
+	switch {
+	case name == "PING":
+		return "+PONG\r\n"
+	case name == "HSET" && len(args) >= 4 && len(args)%2 == 0:
+		hash, ok := r.hashes[args[1]]
+		if !ok {
+			hash = make(map[string]string)
+			r.hashes[args[1]] = hash
+		}
+		added := 0
+		for i := 2; i < len(args); i += 2 {
+			if _, exists := hash[args[i]]; !exists {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", added)
+	case name == "HGETALL" && len(args) == 2:
+		hash := r.hashes[args[1]]
+		reply := fmt.Sprintf("*%d\r\n", len(hash)*2)
+		for field, value := range hash {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n$%d\r\n%s\r\n", len(field), field, len(value), value)
+		}
+		return reply
+	case name == "EXPIRE" && len(args) == 3:
+		seconds, err := strconv.Atoi(args[2])
+		if err != nil {
+			return "-ERR value is not an integer\r\n"
+		}
+		r.expires[args[1]] = time.Duration(seconds) * time.Second
+		return ":1\r\n"
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// set 直接写入哈希字段，模拟其他副本已发布的状态
+func (r *stubRedis) set(key, field, value string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.hashes[key]; !ok {
+		r.hashes[key] = make(map[string]string)
+	}
+	r.hashes[key][field] = value
+}
+
+func (r *stubRedis) expire(key string) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.expires[key]
+}
+
+// count 返回已执行的指定命令次数
+func (r *stubRedis) count(name string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	n := 0
+	for _, cmd := range r.commands {
+		if cmd == name {
+			n++
+		}
+	}
+	return n
+}
+
+// readRESPCommand 读取一条由批量字符串数组编码的命令
+func readRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command line: %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid array length: %q", line)
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		header, err := readRESPLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+		if err != nil || !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("invalid bulk string header: %q", header)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func readRESPLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// snapshotTokens 按簇ID汇总快照中的令牌数
+func snapshotTokens(snapshots []types.BucketSnapshot) map[string]int64 {
+	tokens := make(map[string]int64, len(snapshots))
+	for _, snapshot := range snapshots {
+		tokens[snapshot.ClusterID] = snapshot.Tokens
+	}
+	return tokens
+}
+
+func TestLimiterStateHandoff(t *testing.T) {
+	redis := newStubRedis(t)
+	handoff := limiter.NewStateHandoff(&types.RedisConfig{Addresses: []string{redis.addr()}}, &types.LimiterHandoffConfig{})
+	defer handoff.Close()
+
+	// 未配置键和TTL时使用默认值，发布后可完整读回
+	now := time.Now()
+	require.NoError(t, handoff.Publish([]types.BucketSnapshot{
+		{ClusterID: "chat-timeouts", Tokens: 3, Capacity: 10, Rate: 10, PolicyVersion: 2, Timestamp: now},
+		{ClusterID: "embedding-5xx", Tokens: 7, Capacity: 20, Rate: 20, Timestamp: now},
+	}))
+	assert.Equal(t, 2*time.Minute, redis.expire("gateway:limiter:handoff"))
+
+	snapshots, err := handoff.Load()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, map[string]int64{"chat-timeouts": 3, "embedding-5xx": 7}, snapshotTokens(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.ClusterID == "chat-timeouts" {
+			assert.EqualValues(t, 10, snapshot.Capacity)
+			assert.EqualValues(t, 2, snapshot.PolicyVersion)
+			assert.WithinDuration(t, now, snapshot.Timestamp, time.Millisecond)
+		}
+	}
+
+	// 空快照不访问Redis
+	commands := redis.count("HGETALL")
+	require.NoError(t, handoff.Publish(nil))
+	assert.Equal(t, commands, redis.count("HGETALL"))
+}
+
+func TestLimiterStateHandoffConservativeMerge(t *testing.T) {
+	cases := []struct {
+		name      string
+		existing  int64
+		published int64
+		expected  int64
+	}{
+		{name: "fuller bucket is ignored", existing: 3, published: 8, expected: 3},
+		{name: "emptier bucket wins", existing: 3, published: 1, expected: 1},
+		{name: "equal tokens overwrite", existing: 3, published: 3, expected: 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			redis := newStubRedis(t)
+			handoff := limiter.NewStateHandoff(
+				&types.RedisConfig{Addresses: []string{redis.addr()}},
+				&types.LimiterHandoffConfig{Key: "handoff:test", TTL: 30 * time.Second},
+			)
+			defer handoff.Close()
+
+			// 两个副本先后停止，同一簇保留令牌更少的一份，其他簇照常写入
+			require.NoError(t, handoff.Publish([]types.BucketSnapshot{{ClusterID: "chat", Tokens: tc.existing, Timestamp: time.Now()}}))
+			require.NoError(t, handoff.Publish([]types.BucketSnapshot{
+				{ClusterID: "chat", Tokens: tc.published, Timestamp: time.Now()},
+				{ClusterID: "search", Tokens: 5, Timestamp: time.Now()},
+			}))
+			assert.Equal(t, 30*time.Second, redis.expire("handoff:test"))
+
+			snapshots, err := handoff.Load()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]int64{"chat": tc.expected, "search": 5}, snapshotTokens(snapshots))
+		})
+	}
+}
+
+func TestLimiterStateHandoffLoadSkipsStale(t *testing.T) {
+	redis := newStubRedis(t)
+	handoff := limiter.NewStateHandoff(
+		&types.RedisConfig{Addresses: []string{redis.addr()}},
+		&types.LimiterHandoffConfig{Key: "handoff:test", TTL: time.Minute},
+	)
+	defer handoff.Close()
+
+	// 快照时间超过TTL的状态和无法解析的状态被忽略
+	require.NoError(t, handoff.Publish([]types.BucketSnapshot{
+		{ClusterID: "fresh", Tokens: 4, Timestamp: time.Now()},
+		{ClusterID: "stale", Tokens: 2, Timestamp: time.Now().Add(-2 * time.Minute)},
+	}))
+	redis.set("handoff:test", "corrupt", "{not json")
+
+	snapshots, err := handoff.Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"fresh": 4}, snapshotTokens(snapshots))
+}
+
+func TestLimiterStateHandoffUnavailable(t *testing.T) {
+	handoff := limiter.NewStateHandoff(
+		&types.RedisConfig{Addresses: []string{"127.0.0.1:1"}, Timeout: 100 * time.Millisecond},
+		&types.LimiterHandoffConfig{},
+	)
+	defer handoff.Close()
+
+	// 读取已有状态失败时仍尝试写入，写入失败返回错误
+	err := handoff.Publish([]types.BucketSnapshot{{ClusterID: "chat", Tokens: 1, Timestamp: time.Now()}})
+	assert.ErrorContains(t, err, "failed to publish limiter state")
+
+	_, err = handoff.Load()
+	assert.ErrorContains(t, err, "failed to load limiter state")
+}
+
+func TestClusterLimiterHandoffRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := &types.LimiterConfig{DefaultRate: 10, MaxRate: 100}
+	policy := &types.Policy{PolicyType: types.PolicyTypeRateLimit, Version: 3, RateLimit: &types.RateLimitPolicy{}}
+
+	allow := func(rl interfaces.RateLimiter) bool {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/chat", nil)
+		c.Set("cluster_id", "chat")
+		return rl.Allow(c)
+	}
+	tokens := func(rl interfaces.RateLimiter) int64 {
+		stats, err := rl.GetStats("chat")
+		require.NoError(t, err)
+		return stats.Tokens
+	}
+
+	// 旧副本耗尽令牌后停止，导出当前填充状态
+	old := limiter.NewClusterRateLimiter(config, nil)
+	defer old.Cleanup()
+	require.NoError(t, old.UpdatePolicy("chat", policy))
+	for allow(old) {
+	}
+	snapshots := old.(interfaces.StatefulRateLimiter).Snapshot()
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "chat", snapshots[0].ClusterID)
+	assert.EqualValues(t, 10, snapshots[0].Capacity)
+	assert.EqualValues(t, 3, snapshots[0].PolicyVersion)
+	assert.LessOrEqual(t, snapshots[0].Tokens, int64(1))
+
+	// 新副本在策略到达前恢复，状态暂存到建立令牌桶时应用，不会以满桶放行突发
+	replacement := limiter.NewClusterRateLimiter(config, nil)
+	defer replacement.Cleanup()
+	assert.Equal(t, 0, replacement.(interfaces.StatefulRateLimiter).Restore(snapshots))
+	require.NoError(t, replacement.UpdatePolicy("chat", policy))
+	assert.LessOrEqual(t, tokens(replacement), int64(1))
+
+	// 已建立的令牌桶立即应用，快照之后经过的时间按速率补充
+	fresh := limiter.NewClusterRateLimiter(config, nil)
+	defer fresh.Cleanup()
+	require.NoError(t, fresh.UpdatePolicy("chat", policy))
+	assert.EqualValues(t, 10, tokens(fresh))
+	applied := fresh.(interfaces.StatefulRateLimiter).Restore([]types.BucketSnapshot{
+		{ClusterID: "chat", Tokens: 0, Timestamp: time.Now().Add(-500 * time.Millisecond)},
+		{ClusterID: "search", Tokens: 0, Timestamp: time.Now()},
+	})
+	assert.Equal(t, 1, applied)
+	assert.InDelta(t, 5, tokens(fresh), 1)
+}
@@ -0,0 +1,836 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/gateway/llm"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestLLMProxyAdapters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	openai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[]}`))
+	}))
+	defer openai.Close()
+
+	var anthropicRequest map[string]interface{}
+	anthropic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "ak-test", r.Header.Get("x-api-key"))
+		json.NewDecoder(r.Body).Decode(&anthropicRequest)
+
+		if anthropicRequest["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n")
+			io.WriteString(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n")
+			io.WriteString(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}\n\n")
+			io.WriteString(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+			return
+		}
+		w.Write([]byte(`{"id":"msg_1","content":[{"type":"text","text":"Hello"}],"stop_reason":"max_tokens","usage":{"input_tokens":5,"output_tokens":7}}`))
+	}))
+	defer anthropic.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled: true,
+		Providers: []types.LLMProviderConfig{
+			{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: openai.URL + "/v1", APIKey: "sk-test", Models: []string{"gpt-4o"}},
+			{Name: "anthropic", Type: types.LLMProviderAnthropic, BaseURL: anthropic.URL, APIKey: "ak-test", Models: []string{"claude-*"}},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w
+	}
+
+	w := post("/v1/chat/completions", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "chatcmpl-1")
+
+	w = post("/v1/chat/completions", `{"model":"claude-3-5-sonnet","messages":[{"role":"system","content":"be brief"},{"role":"user","content":[{"type":"text","text":"hi"}]}],"stop":"END"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "be brief", anthropicRequest["system"])
+	assert.Equal(t, float64(1024), anthropicRequest["max_tokens"])
+	assert.Equal(t, []interface{}{"END"}, anthropicRequest["stop_sequences"])
+
+	var completion struct {
+		Choices []struct {
+			Message      map[string]string `json:"message"`
+			FinishReason string            `json:"finish_reason"`
+		} `json:"choices"`
+		Usage map[string]int `json:"usage"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &completion))
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "Hello", completion.Choices[0].Message["content"])
+	assert.Equal(t, "length", completion.Choices[0].FinishReason)
+	assert.Equal(t, 12, completion.Usage["total_tokens"])
+
+	w = post("/v1/chat/completions", `{"model":"claude-3-5-sonnet","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"content":"Hi"`)
+	assert.Contains(t, w.Body.String(), `"finish_reason":"stop"`)
+	assert.True(t, strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n"))
+
+	assert.Equal(t, http.StatusNotFound, post("/v1/chat/completions", `{"model":"unknown"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/v1/embeddings", `{"model":"claude-3-5-sonnet","input":"x"}`).Code)
+}
+
+func TestLLMTokenLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	assert.Equal(t, 0, llm.EstimateTokens(""))
+	assert.Equal(t, 2, llm.EstimateTokens("hello world"))
+	assert.Equal(t, 4, llm.EstimateTokens("你好世界"))
+	assert.Equal(t, 2, llm.EstimateTokens("123456"))
+	assert.Equal(t, 3+3+1+1, llm.CountPromptTokens(llm.EndpointChatCompletions, []byte(`{"messages":[{"role":"user","content":"hi"}]}`)))
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":40}}`))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled: true,
+		Providers: []types.LLMProviderConfig{
+			{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"gpt-4o", "gpt-4o-mini"}},
+		},
+		TokenLimit: types.TokenLimitConfig{
+			Enabled: true,
+			Models:  map[string]int64{"gpt-4o": 100},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	post := func(model string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`)))
+		return w
+	}
+
+	// 每次按usage扣除50个令牌，第三次请求时余额不足
+	assert.Equal(t, http.StatusOK, post("gpt-4o").Code)
+	assert.Equal(t, http.StatusOK, post("gpt-4o").Code)
+	w := post("gpt-4o")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "rate_limit_exceeded")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// 未配置TPM的模型不受限
+	assert.Equal(t, http.StatusOK, post("gpt-4o-mini").Code)
+}
+
+func TestLLMCostBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":1000,"completion_tokens":1000}}`))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled: true,
+		Providers: []types.LLMProviderConfig{
+			{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"gpt-4o*"}},
+		},
+		Cost: types.CostConfig{
+			Enabled: true,
+			Pricing: map[string]types.ModelPricing{
+				"gpt-4o*":     {PromptPer1K: 0.005, CompletionPer1K: 0.015},
+				"gpt-4o-mini": {PromptPer1K: 0.0001, CompletionPer1K: 0.0004},
+			},
+			Budgets: []types.CostBudgetConfig{
+				{Scope: types.CostScopeTenant, ID: "*", Limit: 0.03, Period: types.CostPeriodDay},
+			},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+	proxy.RegisterAdmin(engine.Group("/admin"))
+
+	post := func(tenant, model string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("X-Tenant-ID", tenant)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// 每次花费$0.02，第二次请求后超出$0.03的日预算
+	assert.Equal(t, http.StatusOK, post("acme", "gpt-4o").Code)
+	assert.Equal(t, http.StatusOK, post("acme", "gpt-4o").Code)
+	w := post("acme", "gpt-4o")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "budget_exceeded")
+
+	// 预算按租户分别计算，精确匹配的单价优先于前缀
+	assert.Equal(t, http.StatusOK, post("other", "gpt-4o-mini").Code)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/admin/costs?scope=tenant", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var spend struct {
+		DaySpend map[string]float64 `json:"day_spend"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spend))
+	assert.InDelta(t, 0.04, spend.DaySpend["tenant:acme"], 1e-9)
+	assert.InDelta(t, 0.0005, spend.DaySpend["tenant:other"], 1e-9)
+	assert.NotContains(t, spend.DaySpend, "model:gpt-4o")
+}
+
+func TestLLMModelRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var attempts int32
+	var lastBody map[string]interface{}
+	inHouse := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求失败，重试后成功
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&lastBody)
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer inHouse.Close()
+
+	hosted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer hosted.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled: true,
+		Providers: []types.LLMProviderConfig{
+			{Name: "hosted", Type: types.LLMProviderOpenAI, BaseURL: hosted.URL, Models: []string{"*"}},
+			{Name: "in-house", Type: types.LLMProviderVLLM, BaseURL: inHouse.URL},
+		},
+		Models: []types.LLMModelConfig{
+			{Name: "llama*", Provider: "in-house", UpstreamModel: "meta-llama/Llama-3.1-8B", Retries: 1, MaxTokens: 256},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions",
+		strings.NewReader(`{"model":"llama-3","messages":[{"role":"user","content":"hi"}]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.Equal(t, "meta-llama/Llama-3.1-8B", lastBody["model"])
+	assert.Equal(t, float64(256), lastBody["max_tokens"])
+
+	// 未配置模型路由的模型按提供方声明选择，不重试
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","messages":[]}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	_, err = llm.NewProxy(&types.LLMConfig{
+		Models: []types.LLMModelConfig{{Name: "gpt-4o", Provider: "missing"}},
+	}, &types.RedisConfig{}, nil)
+	assert.Error(t, err)
+}
+
+// searchableVectorDB 按余弦相似度检索的内存向量库
+type searchableVectorDB struct {
+	vectors map[string][]float32
+}
+
+func (db *searchableVectorDB) AddVector(id string, vector []float32) error {
+	db.vectors[id] = vector
+	return nil
+}
+
+func (db *searchableVectorDB) SearchSimilar(query []float32, topK int) ([]types.SearchResult, error) {
+	results := make([]types.SearchResult, 0, len(db.vectors))
+	for id, vector := range db.vectors {
+		results = append(results, types.SearchResult{ID: id, Similarity: utils.CosineSimilarity(query, vector)})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+func (db *searchableVectorDB) GetVector(id string) ([]float32, error) {
+	return db.vectors[id], nil
+}
+
+func (db *searchableVectorDB) DeleteVector(id string) error {
+	delete(db.vectors, id)
+	return nil
+}
+
+func (db *searchableVectorDB) GetVectorCount() (int64, error) {
+	return int64(len(db.vectors)), nil
+}
+
+func (db *searchableVectorDB) Health() types.ComponentHealth {
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+func (db *searchableVectorDB) Stats() map[string]interface{} {
+	return map[string]interface{}{"vectors": len(db.vectors)}
+}
+
+func TestLLMSemanticCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"4"}}]}`))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled:   true,
+		Providers: []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		Models: []types.LLMModelConfig{
+			{Name: "gpt-4o", SemanticCache: true},
+			{Name: "gpt-4o-mini"},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	embed := embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 100, Dimension: 16})
+	vectors := &searchableVectorDB{vectors: make(map[string][]float32)}
+	cache := llm.NewSemanticCache(&types.SemanticCacheConfig{Enabled: true, Threshold: 0.99}, embed, vectors)
+	proxy.AttachSemanticCache(cache)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	send := func(body, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("X-Tenant-ID", tenant)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	prompt := `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"what is two plus two"}]}`
+	w := send(prompt, "acme")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get("X-Semantic-Cache"))
+
+	// 相同提示词命中缓存，不再转发
+	w = send(prompt, "acme")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Semantic-Cache"))
+	assert.Contains(t, w.Body.String(), `"content":"4"`)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 其他租户、不同生成参数、流式请求和未开启缓存的模型不共享
+	send(prompt, "globex")
+	send(strings.Replace(prompt, `"temperature":0`, `"temperature":1`, 1), "acme")
+	send(strings.Replace(prompt, `"temperature":0`, `"stream":true`, 1), "acme")
+	w = send(strings.Replace(prompt, `"gpt-4o"`, `"gpt-4o-mini"`, 1), "acme")
+	assert.Empty(t, w.Header().Get("X-Semantic-Cache"))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 1, stats["hits"])
+	assert.EqualValues(t, 3, stats["entries"])
+}
+
+func TestLLMStreamMetering(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 每个事件输出两个单词，不返回usage
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 50; i++ {
+			w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":" hello world"}}]}` + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled:           true,
+		Providers:         []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		StreamEnforcement: true,
+		Cost: types.CostConfig{
+			Enabled: true,
+			Pricing: map[string]types.ModelPricing{"*": {CompletionPer1K: 1}},
+			Budgets: []types.CostBudgetConfig{
+				{Scope: types.CostScopeTenant, ID: "small", Limit: 0.02, Period: types.CostPeriodDay},
+			},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+	proxy.RegisterAdmin(engine.Group("/admin"))
+
+	stream := func(tenant string) string {
+		req := httptest.NewRequest("POST", "/v1/chat/completions",
+			strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("X-Tenant-ID", tenant)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	spend := func(tenant string) float64 {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/admin/costs?scope=tenant", nil))
+		var result struct {
+			DaySpend map[string]float64 `json:"day_spend"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result.DaySpend["tenant:"+tenant]
+	}
+
+	// 未设预算的租户完整转发，按实时计数的100个输出令牌结算
+	body := stream("large")
+	assert.Equal(t, 50, strings.Count(body, "hello world"))
+	assert.InDelta(t, 0.1, spend("large"), 1e-9)
+
+	// 20个令牌后超出预算，中途截断并以错误事件结束
+	body = stream("small")
+	assert.Less(t, strings.Count(body, "hello world"), 50)
+	assert.Contains(t, body, "budget_exceeded")
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+	assert.InDelta(t, 0.02, spend("small"), 1e-9)
+}
+
+func TestLLMRedaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var forwarded []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	config := &types.LLMConfig{
+		Enabled:   true,
+		Providers: []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		Redaction: types.RedactionConfig{
+			Enabled:  true,
+			Patterns: []types.RedactionPattern{{Name: "order", Pattern: `ORD-\d+`, Replacement: "[ORDER]"}},
+		},
+	}
+	proxy, err := llm.NewProxy(config, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+	proxy.RegisterAdmin(engine.Group("/admin"))
+
+	body := `{"model":"gpt-4o","messages":[` +
+		`{"role":"user","content":"call 13800138000 or mail bob@example.com about ORD-42"},` +
+		`{"role":"user","content":[{"type":"text","text":"card 4111 1111 1111 1111"},{"type":"image_url","image_url":{"url":"https://x/1.png"}}]}]}`
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	text := string(forwarded)
+	assert.NotContains(t, text, "13800138000")
+	assert.NotContains(t, text, "bob@example.com")
+	assert.NotContains(t, text, "4111")
+	assert.Contains(t, text, "[PHONE]")
+	assert.Contains(t, text, "[ORDER]")
+	assert.Contains(t, text, "https://x/1.png")
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/admin/redactions", nil))
+	var stats struct {
+		Redactions map[string]int64 `json:"redactions"`
+		Requests   int64            `json:"requests"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, map[string]int64{"phone": 1, "email": 1, "creditcard": 1, "order": 1}, stats.Redactions)
+	assert.EqualValues(t, 1, stats.Requests)
+
+	config.Redaction.Builtin = []string{"ssn"}
+	_, err = llm.NewProxy(config, &types.RedisConfig{}, nil)
+	assert.Error(t, err)
+}
+
+func TestLLMModeration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"here is the launch code"}}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range []string{"the", " secret", " launch", " co", "de", " is"} {
+			w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"` + word + `"}}]}` + "\n\n"))
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	var moderated int32
+	moderation := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&moderated, 1)
+		w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":false}}]}`))
+	}))
+	defer moderation.Close()
+
+	config := &types.LLMConfig{
+		Enabled:    true,
+		Providers:  []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		Moderation: types.ModerationConfig{Enabled: true, Keywords: types.KeywordModerationConfig{Enabled: true, Words: []string{"Launch Code"}}},
+	}
+	proxy, err := llm.NewProxy(config, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+	engine := gin.New()
+	proxy.Register(engine)
+
+	send := func(engine *gin.Engine, stream bool) *httptest.ResponseRecorder {
+		body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+		if stream {
+			body = `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+		return w
+	}
+
+	// 跨事件的关键词在违规事件转发前截断
+	w := send(engine, true)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `" launch"`)
+	assert.NotContains(t, w.Body.String(), `"de"`)
+	assert.Contains(t, w.Body.String(), "content_policy_violation")
+	assert.True(t, strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n"))
+
+	w = send(engine, false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "content_policy_violation")
+
+	// 外部审核接口
+	config.Moderation.Keywords.Enabled = false
+	config.Moderation.API = types.ModerationAPIConfig{Enabled: true, URL: moderation.URL, Interval: 1000}
+	proxy, err = llm.NewProxy(config, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+	engine = gin.New()
+	proxy.Register(engine)
+
+	w = send(engine, true)
+	assert.Contains(t, w.Body.String(), "flagged as violence")
+	assert.Contains(t, w.Body.String(), `" is"`)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&moderated))
+}
+
+func TestLLMTenantPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	maxTemperature := 1.0
+	config := &types.LLMConfig{
+		Enabled:   true,
+		Providers: []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"gpt-4o", "gpt-4o-mini", "llama-3"}}},
+		Tenants: []types.LLMTenantConfig{
+			{Tenant: "free", Models: []string{"gpt-4o-mini", "llama-*"}, MaxTokens: 1024, MaxTemperature: &maxTemperature},
+			{Tenant: "*", Models: []string{"gpt-4o*"}},
+		},
+	}
+	proxy, err := llm.NewProxy(config, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	call := func(tenant, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("X-Tenant-ID", tenant)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, call("free", `{"model":"llama-3","max_tokens":512,"temperature":0.7}`).Code)
+
+	w := call("free", `{"model":"gpt-4o"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "model_not_allowed")
+
+	w = call("free", `{"model":"gpt-4o-mini","max_completion_tokens":4096}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "max_completion_tokens 4096 exceeds the limit of 1024 for tenant free")
+
+	w = call("free", `{"model":"gpt-4o-mini","temperature":1.5}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "parameter_limit_exceeded")
+
+	// 未单独配置的租户使用"*"策略
+	assert.Equal(t, http.StatusOK, call("acme", `{"model":"gpt-4o","max_tokens":8192}`).Code)
+	assert.Equal(t, http.StatusForbidden, call("", `{"model":"llama-3"}`).Code)
+
+	// 模型列表只包含白名单内的模型
+	req := httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("X-Tenant-ID", "free")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"llama-3"`)
+	assert.NotContains(t, w.Body.String(), `"gpt-4o"`)
+
+	rejections := proxy.Stats()["tenant_rejections"].(map[string]int64)
+	assert.Equal(t, int64(3), rejections["free"])
+	assert.Equal(t, int64(1), rejections["default"])
+
+	_, err = llm.NewProxy(&types.LLMConfig{Tenants: []types.LLMTenantConfig{{Tenant: "a"}, {Tenant: "a"}}}, &types.RedisConfig{}, nil)
+	assert.Error(t, err)
+}
+
+func TestLLMContextGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var lastBody struct {
+		Messages []map[string]string `json:"messages"`
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&lastBody)
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled:      true,
+		Providers:    []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		Models:       []types.LLMModelConfig{{Name: "small", ContextWindow: 200, MaxTokens: 10}},
+		ContextGuard: types.ContextGuardConfig{Enabled: true, Action: llm.ContextActionTruncate},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	post := func(model string, messages []map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"model": model, "messages": messages})
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(string(body))))
+		return w
+	}
+	long := strings.Repeat("word ", 50)
+
+	// 丢弃最早的对话，保留system消息和最后一条消息
+	conversation := []map[string]string{
+		{"role": "system", "content": "be brief"},
+		{"role": "user", "content": "first " + long},
+		{"role": "assistant", "content": long},
+		{"role": "user", "content": long},
+		{"role": "assistant", "content": long},
+		{"role": "user", "content": "last question"},
+	}
+	w := post("small", conversation)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("X-Context-Truncated"))
+	require.NotEmpty(t, lastBody.Messages)
+	assert.Equal(t, "system", lastBody.Messages[0]["role"])
+	assert.Equal(t, "last question", lastBody.Messages[len(lastBody.Messages)-1]["content"])
+	assert.Less(t, len(lastBody.Messages), len(conversation))
+	for _, message := range lastBody.Messages {
+		assert.NotContains(t, message["content"], "first")
+	}
+
+	// 最后一条消息本身超出上下文窗口时无法截断
+	w = post("small", []map[string]string{{"role": "user", "content": strings.Repeat(long, 5)}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "context_length_exceeded")
+	assert.Contains(t, w.Body.String(), "maximum context length is 200 tokens")
+
+	// 未配置上下文窗口的模型不检查
+	assert.Equal(t, http.StatusOK, post("gpt-4o", []map[string]string{{"role": "user", "content": strings.Repeat(long, 5)}}).Code)
+
+	_, err = llm.NewProxy(&types.LLMConfig{ContextGuard: types.ContextGuardConfig{Enabled: true, Action: "summarize"}}, &types.RedisConfig{}, nil)
+	assert.Error(t, err)
+}
+
+func TestLLMDedup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled:   true,
+		Providers: []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		Models:    []types.LLMModelConfig{{Name: "gpt-4o", Dedup: true}},
+		Dedup:     types.LLMDedupConfig{Enabled: true},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+		return w
+	}
+
+	// 字段顺序和空白不同的相同请求只转发一次
+	bodies := []string{
+		`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`,
+		`{"messages": [{"content": "hi", "role": "user"}], "model": "gpt-4o"}`,
+		`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`,
+	}
+	results := make(chan *httptest.ResponseRecorder, len(bodies))
+	for _, body := range bodies {
+		go func(body string) { results <- post(body) }(body)
+	}
+	require.Eventually(t, func() bool {
+		return proxy.Stats()["dedup"].(map[string]interface{})["inflight"].(int) == 1 && atomic.LoadInt32(&calls) == 1
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	coalesced := 0
+	for range bodies {
+		w := <-results
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "hello")
+		if w.Header().Get("X-LLM-Coalesced") == "true" {
+			coalesced++
+		}
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 2, coalesced)
+
+	// 请求完成后不再共享，未开启合并的模型不合并
+	assert.Equal(t, http.StatusOK, post(bodies[0]).Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Empty(t, post(`{"model":"gpt-4o-mini","messages":[]}`).Header().Get("X-LLM-Coalesced"))
+}
+
+func TestLLMDedupAdmission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls int32
+	first, second := make(chan struct{}), make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "gpt-4o-mini") {
+			w.Write([]byte(`{"id":"chatcmpl-0","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":40}}`))
+			return
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-first
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"slow down","type":"rate_limit_error"}}`))
+			return
+		}
+		<-second
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer upstream.Close()
+
+	proxy, err := llm.NewProxy(&types.LLMConfig{
+		Enabled:   true,
+		Providers: []types.LLMProviderConfig{{Name: "openai", Type: types.LLMProviderOpenAI, BaseURL: upstream.URL, Models: []string{"*"}}},
+		Models:    []types.LLMModelConfig{{Name: "gpt-4o", Dedup: true}},
+		Dedup:     types.LLMDedupConfig{Enabled: true},
+		TokenLimit: types.TokenLimitConfig{
+			Enabled: true,
+			APIKeys: map[string]int64{utils.APIKeyID("sk-limited"): 10},
+		},
+	}, &types.RedisConfig{}, nil)
+	require.NoError(t, err)
+
+	engine := gin.New()
+	proxy.Register(engine)
+
+	post := func(key, model string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("X-API-Key", key)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// 耗尽受限密钥的令牌
+	assert.Equal(t, http.StatusOK, post("sk-limited", "gpt-4o-mini").Code)
+
+	results := make(chan *httptest.ResponseRecorder, 3)
+	go func() { results <- post("sk-a", "gpt-4o") }()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 5*time.Millisecond)
+
+	// 相同请求正在进行时仍先经过令牌限流，超限的请求不等待共享响应
+	w := post("sk-limited", "gpt-4o")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "rate_limit_exceeded")
+	assert.Empty(t, w.Header().Get("X-LLM-Coalesced"))
+
+	// 领头请求的429不共享，等待的请求重新竞争领头，只共享2xx响应
+	go func() { results <- post("sk-b", "gpt-4o") }()
+	go func() { results <- post("sk-c", "gpt-4o") }()
+	time.Sleep(50 * time.Millisecond)
+	close(first)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(second)
+
+	codes := make(map[int]int)
+	coalesced := 0
+	for i := 0; i < 3; i++ {
+		w := <-results
+		codes[w.Code]++
+		if w.Header().Get("X-LLM-Coalesced") == "true" {
+			coalesced++
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+	}
+	assert.Equal(t, map[int]int{http.StatusTooManyRequests: 1, http.StatusOK: 2}, codes)
+	assert.Equal(t, 1, coalesced)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/timeseries"
+	"github.com/llm-aware-gateway/pkg/gateway/breaker"
+	"github.com/llm-aware-gateway/pkg/gateway/metricsexport"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestMetricsExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := timeseries.NewGatewayMetricsStore(&types.GatewayMetricsConfig{Resolution: time.Second, Retention: time.Minute})
+	var received []*types.MetricsSnapshot
+	failNext := false
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var snapshot types.MetricsSnapshot
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&snapshot))
+		received = append(received, &snapshot)
+		store.Ingest(&snapshot)
+		// 模拟控制面已接收但响应丢失
+		if failNext {
+			failNext = false
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer control.Close()
+
+	exporter, err := metricsexport.NewExporter(&types.MetricsExportConfig{Endpoint: control.URL, Instance: "gw-1"}, nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(exporter.Middleware())
+	router.GET("/:status", func(c *gin.Context) {
+		c.Set("cluster_id", "c1")
+		switch c.Param("status") {
+		case "429":
+			utils.RecordStageError(c, "rate_limit", assert.AnError)
+			c.Status(http.StatusTooManyRequests)
+		case "502":
+			c.Status(http.StatusBadGateway)
+		default:
+			c.Status(http.StatusOK)
+		}
+	})
+	router.GET("/other/path", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, path := range []string{"/200", "/200", "/502", "/429", "/other/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	require.NoError(t, exporter.Flush())
+	require.Len(t, received, 1)
+	assert.Equal(t, "gw-1", received[0].Instance)
+	assert.Equal(t, uint64(1), received[0].Sequence)
+	assert.Equal(t, types.ClusterCounters{Requests: 4, Errors: 1, Rejections: 1}, *received[0].Clusters["c1"])
+	assert.Equal(t, int64(1), received[0].Clusters[types.UnclusteredID].Requests)
+
+	// 没有新增计数时不推送
+	require.NoError(t, exporter.Flush())
+	assert.Len(t, received, 1)
+
+	// 推送失败的快照以原序号重发，控制面去重
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/502", nil))
+	failNext = true
+	assert.Error(t, exporter.Flush())
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/200", nil))
+	require.NoError(t, exporter.Flush())
+	require.Len(t, received, 4)
+	assert.Equal(t, uint64(2), received[2].Sequence)
+	assert.Equal(t, uint64(3), received[3].Sequence)
+	assert.Equal(t, int64(1), received[3].Clusters["c1"].Requests)
+
+	instances := store.Instances()
+	require.Len(t, instances, 1)
+	assert.Equal(t, uint64(3), instances[0].Sequence)
+	assert.Equal(t, int64(1), instances[0].Duplicates)
+
+	rates, ok := store.ClusterRates("c1", time.Minute)
+	require.True(t, ok)
+	assert.InDelta(t, 6.0/60, rates.Requests, 1e-9)
+	assert.InDelta(t, 2.0/60, rates.Errors, 1e-9)
+	_, ok = store.ClusterRates("unknown", time.Minute)
+	assert.False(t, ok)
+
+	_, err = metricsexport.NewExporter(&types.MetricsExportConfig{}, nil)
+	assert.Error(t, err)
+}
+
+func TestBreakerStatsExport(t *testing.T) {
+	store := timeseries.NewGatewayMetricsStore(&types.GatewayMetricsConfig{Resolution: time.Second, Retention: time.Hour})
+	var received []*types.MetricsSnapshot
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var snapshot types.MetricsSnapshot
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&snapshot))
+		received = append(received, &snapshot)
+		store.Ingest(&snapshot)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer control.Close()
+
+	cb := breaker.NewClusterCircuitBreaker(&types.BreakerConfig{
+		FailureThreshold:  2,
+		RecoveryTimeout:   50 * time.Millisecond,
+		RecoveryIncrement: 1,
+		RecoveryInterval:  10 * time.Millisecond,
+	})
+	require.NoError(t, cb.UpdatePolicy("c1", &types.Policy{PolicyType: types.PolicyTypeRateLimit}))
+	exporter, err := metricsexport.NewExporter(&types.MetricsExportConfig{Endpoint: control.URL, Instance: "gw-1"}, nil)
+	require.NoError(t, err)
+	exporter.SetBreakerSource(cb.(interfaces.BreakerStatsSource))
+
+	// 连续失败开启熔断，恢复超时后半开放行，一个恢复步后关闭
+	require.NoError(t, cb.RecordFailure("c1"))
+	require.NoError(t, cb.RecordFailure("c1"))
+	assert.False(t, cb.Allow(context.Background(), "c1"))
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, cb.Allow(context.Background(), "c1"))
+	require.NoError(t, cb.RecordSuccess("c1"))
+	time.Sleep(15 * time.Millisecond)
+	assert.True(t, cb.Allow(context.Background(), "c1"))
+	require.Equal(t, types.BreakerStateClosed, cb.GetState("c1"))
+
+	// 没有请求计数时也推送熔断统计
+	require.NoError(t, exporter.Flush())
+	require.Len(t, received, 1)
+	stats := received[0].Breakers["c1"]
+	require.NotNil(t, stats)
+	assert.EqualValues(t, 1, stats.Trips)
+	assert.EqualValues(t, 1, stats.Recoveries)
+	assert.EqualValues(t, 1, stats.Rejections)
+	assert.EqualValues(t, 2, stats.Failures)
+	assert.GreaterOrEqual(t, stats.RecoveryMillis, int64(60))
+	assert.Equal(t, stats.RecoveryMillis, stats.OpenMillis)
+
+	// 关闭状态且没有变化时不再推送
+	require.NoError(t, exporter.Flush())
+	assert.Len(t, received, 1)
+
+	history, ok := store.BreakerHistory("c1", time.Hour)
+	require.True(t, ok)
+	assert.EqualValues(t, 1, history.Trips)
+	assert.EqualValues(t, 1, history.Recoveries)
+	assert.InDelta(t, float64(stats.RecoveryMillis)/1000, history.MeanRecoverySeconds, 1e-9)
+	assert.InDelta(t, 1.0, history.TripsPerHour, 1e-9)
+	_, ok = store.BreakerHistory("unknown", time.Hour)
+	assert.False(t, ok)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/controlplane/narrative"
+	"github.com/llm-aware-gateway/pkg/controlplane/timeseries"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestIncidentNarrative(t *testing.T) {
+	var calls int32
+	var lastPrompt string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		lastPrompt = req.Messages[len(req.Messages)-1].Content
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"Payments are timing out; throttling is in place."}}]}`)
+	}))
+	defer llm.Close()
+
+	series := timeseries.NewTimeSeriesStore(&types.TimeSeriesConfig{Resolution: time.Second, Retention: time.Hour}, nil)
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.9,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          10,
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 10, Dimension: 16}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, series)
+
+	var clusterID string
+	for i := 0; i < 3; i++ {
+		event := &types.ErrorEvent{EventID: fmt.Sprintf("event-%d", i), ServiceName: "payments", ErrorMessage: "gateway timeout"}
+		require.NoError(t, engine.ProcessErrorEvent(event))
+		clusterID = event.ClusterID
+	}
+
+	store := newMemoryConfigStore()
+	value, _ := json.Marshal(&types.Policy{
+		ClusterID:  clusterID,
+		PolicyType: types.RATE_LIMIT,
+		Template:   "soft_throttle",
+		RateLimit:  &types.RateLimitPolicy{LimitRate: 0.3},
+	})
+	require.NoError(t, store.Put(utils.PolicyKey("", clusterID), string(value)))
+
+	narrator, err := narrative.NewNarrator(&types.IncidentNarrativeConfig{
+		Enabled: true,
+		LLM:     types.LLMClientConfig{Endpoint: llm.URL, Model: "test-model"},
+	}, engine, series)
+	require.NoError(t, err)
+	narrator.SetConfigStore(store)
+
+	result, err := narrator.Explain(clusterID)
+	require.NoError(t, err)
+	assert.Equal(t, "Payments are timing out; throttling is in place.", result.Narrative)
+	assert.Equal(t, 3, result.Members)
+	require.NotNil(t, result.Policy)
+	assert.Equal(t, "soft_throttle", result.Policy.Template)
+	assert.NotEmpty(t, result.Samples)
+	assert.Greater(t, result.ErrorRate, 0.0)
+	assert.Contains(t, lastPrompt, "gateway timeout")
+	assert.Contains(t, lastPrompt, "limit rate 0.30")
+
+	// 缓存有效期内不重复调用LLM
+	_, err = narrator.Explain(clusterID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err = narrator.Explain("missing")
+	assert.Error(t, err)
+}
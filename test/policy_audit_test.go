@@ -0,0 +1,86 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/controlplane/policy"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestPolicyAudit(t *testing.T) {
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.95,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          100,
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 100, Dimension: 64}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	var ids []string
+	for i, message := range []string{"database connection refused", "upstream timeout after 30s", "invalid api token"} {
+		event := &types.ErrorEvent{EventID: fmt.Sprintf("event-%d", i), ServiceName: "chat", ErrorMessage: message, StatusCode: 500}
+		require.NoError(t, engine.ProcessErrorEvent(event))
+		ids = append(ids, event.ClusterID)
+	}
+	stale, uncovered, quiet := ids[0], ids[1], ids[2]
+	require.Len(t, map[string]bool{stale: true, uncovered: true, quiet: true}, 3)
+
+	now := time.Now()
+	store := newMemoryConfigStore()
+	put := func(key string, p *types.Policy) {
+		value, _ := json.Marshal(p)
+		require.NoError(t, store.Put(key, string(value)))
+	}
+	put(utils.PolicyKey("", stale), &types.Policy{ClusterID: stale, PolicyType: types.RATE_LIMIT, IsActive: true,
+		CreateTime: now.Add(-2 * time.Hour), ExpireTime: now.Add(time.Hour)})
+	put(utils.PolicyKey("eu", "cluster_gone"), &types.Policy{ClusterID: "cluster_gone", PolicyType: types.CIRCUIT_BREAK,
+		IsActive: true, CreateTime: now, ExpireTime: now.Add(time.Hour)})
+	put(utils.PolicyKey("", "waf/default"), &types.Policy{PolicyType: types.WAF, WAF: &types.WAFPolicy{}})
+
+	severities := map[string]float64{stale: 0.9, uncovered: 0.8, quiet: 0.1}
+	auditor := policy.NewAuditor(&types.PolicyAuditConfig{MaxAge: time.Hour, Grace: time.Minute}, engine, store,
+		func(clusterID string) float64 { return severities[clusterID] })
+	notifier := &recordingNotifier{}
+	auditor.SetNotifier(notifier)
+
+	report, err := auditor.Audit(now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Policies)
+	assert.Equal(t, 3, report.Clusters)
+	require.Len(t, report.Gaps, 3)
+	gaps := make(map[string]types.PolicyGap)
+	for _, gap := range report.Gaps {
+		gaps[gap.Kind] = gap
+	}
+	assert.Equal(t, stale, gaps[types.PolicyGapStale].ClusterID)
+	assert.Equal(t, "eu", gaps[types.PolicyGapOrphaned].Region)
+	assert.Equal(t, uncovered, gaps[types.PolicyGapUncovered].ClusterID)
+	assert.Empty(t, notifier.events, "gaps within the grace period must not alert")
+	assert.Same(t, report, auditor.LatestAudit())
+
+	// 持续超过grace后每个问题只告警一次
+	_, err = auditor.Audit(now.Add(2 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, notifier.events, 3)
+	assert.Equal(t, policy.EventPolicyGap, notifier.events[0].Type)
+	_, err = auditor.Audit(now.Add(3 * time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, notifier.events, 3)
+
+	// 问题消失后从结果中移除
+	require.NoError(t, store.Delete(utils.PolicyKey("eu", "cluster_gone")))
+	severities[uncovered] = 0.2
+	report, err = auditor.Audit(now.Add(4 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, report.Gaps, 1)
+	assert.Equal(t, types.PolicyGapStale, report.Gaps[0].Kind)
+	assert.Equal(t, now, report.Gaps[0].Since)
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/policy"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func historyPolicy(t *testing.T, clusterID string, limitRate float64, expire time.Time, regions ...string) string {
+	data, err := json.Marshal(&types.Policy{
+		ClusterID:  clusterID,
+		PolicyType: types.RATE_LIMIT,
+		RateLimit:  &types.RateLimitPolicy{LimitRate: limitRate},
+		ExpireTime: expire,
+		IsActive:   true,
+		Regions:    regions,
+	})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestPolicyHistoryActiveAt(t *testing.T) {
+	store := newMemoryConfigStore()
+	history := policy.NewHistory(&types.PolicyHistoryConfig{Enabled: true}, store)
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expire := base.Add(time.Hour)
+	globalKey := utils.PolicyKey("", "chat/c1")
+	regionalKey := utils.PolicyKey("eu", "chat/c1")
+
+	history.RecordPut(globalKey, historyPolicy(t, "c1", 0.5, expire), 10, base)
+	history.RecordPut(globalKey, historyPolicy(t, "c1", 0.5, expire), 0, base.Add(time.Minute)) // 没有修订号且内容未变化
+	history.RecordPut(regionalKey, historyPolicy(t, "c1", 0.2, expire), 12, base.Add(10*time.Minute))
+	history.RecordDelete(regionalKey, 13, base.Add(20*time.Minute))
+
+	assert.Empty(t, history.ActiveAt("c1", "", base.Add(-time.Second)))
+
+	// 区域策略生效期间，该区域解析为区域策略，其他区域仍为全局策略
+	at := base.Add(15 * time.Minute)
+	active := history.ActiveAt("c1", "eu", at)
+	require.Len(t, active, 1)
+	assert.Equal(t, "eu", active[0].Region)
+	assert.Equal(t, int64(12), active[0].Version)
+	assert.Equal(t, 0.2, active[0].Policy.RateLimit.LimitRate)
+
+	active = history.ActiveAt("c1", "us", at)
+	require.Len(t, active, 1)
+	assert.Equal(t, "", active[0].Region)
+	assert.Equal(t, int64(10), active[0].Version)
+
+	assert.Len(t, history.ActiveAt("", "", at), 2)
+	assert.Len(t, history.ActiveAt("chat", "", at), 2, "namespace query")
+
+	// 区域策略删除后回落到全局策略，全局策略到期后没有生效策略
+	active = history.ActiveAt("chat/c1", "eu", base.Add(30*time.Minute))
+	require.Len(t, active, 1)
+	assert.Equal(t, "", active[0].Region)
+	assert.Empty(t, history.ActiveAt("c1", "eu", expire))
+
+	timeline := history.Timeline("c1", base.Add(5*time.Minute), time.Time{})
+	require.Len(t, timeline, 2)
+	assert.Equal(t, types.PolicyHistoryPut, timeline[0].Action)
+	assert.Equal(t, types.PolicyHistoryDelete, timeline[1].Action)
+	assert.Len(t, history.Timeline("", time.Time{}, time.Time{}), 3)
+}
+
+func TestPolicyHistoryRestart(t *testing.T) {
+	store := newMemoryConfigStore()
+	expire := time.Now().Add(time.Hour)
+	keepKey := utils.PolicyKey("", "c1")
+	goneKey := utils.PolicyKey("", "c2")
+	require.NoError(t, store.Put(keepKey, historyPolicy(t, "c1", 0.5, expire)))
+	require.NoError(t, store.Put(goneKey, historyPolicy(t, "c2", 0.5, expire)))
+
+	history := policy.NewHistory(&types.PolicyHistoryConfig{Enabled: true}, store)
+	require.NoError(t, history.Start())
+	history.Stop()
+	assert.Len(t, history.ActiveAt("", "", time.Now()), 2)
+
+	// 停机期间c2被删除：重启后从持久化的历史恢复，c1不重复记录，c2补录删除
+	require.NoError(t, store.Delete(goneKey))
+	restarted := policy.NewHistory(&types.PolicyHistoryConfig{Enabled: true}, store)
+	require.NoError(t, restarted.Start())
+	defer restarted.Stop()
+
+	assert.Len(t, restarted.Timeline("c1", time.Time{}, time.Time{}), 1)
+	timeline := restarted.Timeline("c2", time.Time{}, time.Time{})
+	require.Len(t, timeline, 2)
+	assert.Equal(t, types.PolicyHistoryDelete, timeline[1].Action)
+
+	active := restarted.ActiveAt("", "", time.Now())
+	require.Len(t, active, 1)
+	assert.Equal(t, "c1", active[0].PolicyKey)
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestPolicyKeyRegions(t *testing.T) {
+	assert.Equal(t, "/policies/tenant-a/cluster-1", utils.PolicyKey("", "tenant-a/cluster-1"))
+	assert.Equal(t, "/regions/eu-west/policies/cluster-1", utils.PolicyKey("eu-west", "cluster-1"))
+
+	tests := []struct {
+		key        string
+		region     string
+		clusterKey string
+		ok         bool
+	}{
+		{"/policies/cluster-1", "", "cluster-1", true},
+		{"/policies/tenant-a/cluster-1", "", "tenant-a/cluster-1", true},
+		{"/regions/eu-west/policies/tenant-a/cluster-1", "eu-west", "tenant-a/cluster-1", true},
+		{"/regions/eu-west/policies/", "eu-west", "", false},
+		{"/regions//policies/cluster-1", "", "", false},
+		{"/routes/llm/splits", "", "", false},
+	}
+
+	for _, tt := range tests {
+		region, clusterKey, ok := utils.ParsePolicyKey(tt.key)
+		assert.Equal(t, tt.ok, ok, tt.key)
+		if tt.ok {
+			assert.Equal(t, tt.region, region, tt.key)
+			assert.Equal(t, tt.clusterKey, clusterKey, tt.key)
+		}
+	}
+}
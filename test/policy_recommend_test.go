@@ -0,0 +1,83 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/controlplane/policy"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestPolicyRecommendationGuardrails(t *testing.T) {
+	var reply string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%q}}]}`, reply)
+	}))
+	defer llm.Close()
+
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.9,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          10,
+	}, embedding.NewEmbeddingService(&types.EmbeddingConfig{BatchSize: 8, CacheSize: 10, Dimension: 16}),
+		&memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+	event := &types.ErrorEvent{EventID: "event-1", ServiceName: "chat", ErrorMessage: "upstream overloaded"}
+	require.NoError(t, engine.ProcessErrorEvent(event))
+	cluster, err := engine.GetCluster(event.ClusterID)
+	require.NoError(t, err)
+
+	pe, err := policy.NewPolicyEngine(&types.PolicyConfig{
+		ErrorRateThreshold:  5,
+		GrowthRateThreshold: 0.5,
+		PolicyTTL:           5 * time.Minute,
+		Recommendation: types.PolicyRecommendationConfig{
+			Enabled: true,
+			LLM:     types.LLMClientConfig{Endpoint: llm.URL, Model: "test-model"},
+		},
+	}, engine, nil, newMemoryConfigStore())
+	require.NoError(t, err)
+
+	// 通过护栏的推荐直接使用
+	reply = "```json\n{\"policy_type\":\"rate_limit\",\"limit_rate\":0.3,\"duration\":\"90s\",\"rationale\":\"shed load\"}\n```"
+	generated, err := pe.GeneratePolicy(cluster, 8, 0.6)
+	require.NoError(t, err)
+	assert.Equal(t, policy.RecommendationTemplate, generated.Template)
+	assert.Equal(t, 0.3, generated.RateLimit.LimitRate)
+	assert.Equal(t, 90*time.Second, generated.RateLimit.Duration)
+	assert.Equal(t, "shed load", generated.Rationale)
+
+	// 限制比例超出上界时使用模板策略
+	reply = `{"policy_type":"rate_limit","limit_rate":1.0,"duration":"1m"}`
+	generated, err = pe.GeneratePolicy(cluster, 8, 0.6)
+	require.NoError(t, err)
+	assert.Equal(t, policy.TemplateLongDegrade, generated.Template)
+
+	// 严重度不足时不接受熔断
+	reply = `{"policy_type":"circuit_break","break_duration":"30s","recovery_step":0.2}`
+	generated, err = pe.GeneratePolicy(cluster, 8, 0.6)
+	require.NoError(t, err)
+	assert.NotEqual(t, types.CIRCUIT_BREAK, generated.PolicyType)
+
+	// 不允许的策略类型和无法解析的回复
+	reply = `{"policy_type":"waf"}`
+	generated, err = pe.GeneratePolicy(cluster, 8, 0.6)
+	require.NoError(t, err)
+	assert.NotEqual(t, policy.RecommendationTemplate, generated.Template)
+	reply = "I recommend throttling"
+	_, err = pe.GeneratePolicy(cluster, 8, 0.6)
+	require.NoError(t, err)
+
+	stats := pe.Recommender().Stats()
+	assert.Equal(t, int64(1), stats["accepted"])
+	assert.Equal(t, int64(3), stats["rejected"])
+	assert.Equal(t, int64(1), stats["failed"])
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/policy"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestPolicyTemplates(t *testing.T) {
+	registry, err := policy.NewTemplateRegistryFromConfig(&types.PolicyConfig{})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	p, err := registry.Instantiate("c1", 0.1, time.Minute, now)
+	require.NoError(t, err)
+	assert.Equal(t, policy.TemplateLongDegrade, p.Template)
+	assert.Equal(t, types.DEGRADE, p.PolicyType)
+	assert.Equal(t, now.Add(15*time.Minute), p.ExpireTime)
+
+	// 限制比例按严重度在区间内插值
+	p, err = registry.Instantiate("c1", 0.65, time.Minute, now)
+	require.NoError(t, err)
+	assert.Equal(t, policy.TemplateHardThrottle, p.Template)
+	require.NotNil(t, p.RateLimit)
+	assert.InDelta(t, 0.65, p.RateLimit.LimitRate, 1e-9)
+	assert.Equal(t, "c1", p.ClusterID)
+
+	p, err = registry.Instantiate("c1", 1.0, time.Minute, now)
+	require.NoError(t, err)
+	assert.Equal(t, policy.TemplateShortBreak, p.Template)
+	require.NotNil(t, p.CircuitBreak)
+	assert.Equal(t, 30*time.Second, p.CircuitBreak.BreakDuration)
+
+	// 模板库文件与内置模板一致
+	loaded, err := policy.LoadTemplateFile("../configs/policy_templates.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, policy.DefaultTemplates(), loaded)
+
+	// 区间重叠的模板库被拒绝
+	_, err = policy.NewTemplateRegistry([]types.PolicyTemplate{
+		{Name: "a", MinSeverity: 0, MaxSeverity: 0.6, PolicyType: types.DEGRADE},
+		{Name: "b", MinSeverity: 0.5, MaxSeverity: 1, PolicyType: types.DEGRADE},
+	})
+	assert.Error(t, err)
+
+	// 未覆盖的严重度无法实例化
+	registry, err = policy.NewTemplateRegistry([]types.PolicyTemplate{
+		{Name: "a", MinSeverity: 0.5, MaxSeverity: 1, PolicyType: types.DEGRADE},
+	})
+	require.NoError(t, err)
+	_, err = registry.Instantiate("c1", 0.2, time.Minute, now)
+	assert.Error(t, err)
+}
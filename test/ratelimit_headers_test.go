@@ -0,0 +1,81 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 2, MaxRate: 100}, nil)
+	defer rl.Cleanup()
+	require.NoError(t, rl.UpdatePolicy("chat", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{},
+	}))
+
+	m := middleware.NewMiddleware(rl, nil, nil, nil, nil)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("cluster_id", c.Query("cluster"))
+		c.Next()
+	}, m.RateLimit())
+	router.GET("/v1/chat", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(cluster string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/chat?cluster="+cluster, nil))
+		return w
+	}
+
+	w := do("chat")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Unix(), reset, 2)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	do("chat")
+	w = do("chat")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// 没有限流策略的簇不返回限流响应头
+	w = do("other")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestTokenBucketConcurrentCapacity(t *testing.T) {
+	bucket := limiter.NewTokenBucket(10, 10)
+
+	// 策略更新调整容量的同时请求路径读取容量填充X-RateLimit-Limit，在-race下检查容量读写加锁
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(1); i <= 1000; i++ {
+			bucket.SetCapacity(i)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		assert.Positive(t, bucket.GetCapacity())
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1000), bucket.GetCapacity())
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// memoryVectorDB 测试用内存向量库
+type memoryVectorDB struct {
+	vectors map[string][]float32
+}
+
+func (db *memoryVectorDB) AddVector(id string, vector []float32) error {
+	db.vectors[id] = vector
+	return nil
+}
+
+func (db *memoryVectorDB) SearchSimilar(query []float32, topK int) ([]types.SearchResult, error) {
+	return nil, nil
+}
+
+func (db *memoryVectorDB) GetVector(id string) ([]float32, error) {
+	vector, exists := db.vectors[id]
+	if !exists {
+		return nil, fmt.Errorf("vector not found: %s", id)
+	}
+	return vector, nil
+}
+
+func (db *memoryVectorDB) DeleteVector(id string) error {
+	delete(db.vectors, id)
+	return nil
+}
+
+func (db *memoryVectorDB) GetVectorCount() (int64, error) {
+	return int64(len(db.vectors)), nil
+}
+
+func (db *memoryVectorDB) Health() types.ComponentHealth {
+	return types.ComponentHealth{Status: types.ComponentHealthy}
+}
+
+func (db *memoryVectorDB) Stats() map[string]interface{} {
+	return map[string]interface{}{"vectors": len(db.vectors)}
+}
+
+func TestReEmbedMergesClustersAfterRuleChange(t *testing.T) {
+	// 初始规则不替换数字，订单号不同的错误落入不同的簇
+	embed := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		BatchSize: 8,
+		CacheSize: 100,
+		Dimension: 16,
+		PreprocessRules: []types.PreprocessRule{
+			{Pattern: `\s+`, Replacement: " "},
+		},
+	})
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.99,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          10,
+	}, embed, &memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	oldVersion := embed.RulesetVersion()
+	for i, order := range []string{"1001", "2002"} {
+		event := &types.ErrorEvent{
+			EventID:      fmt.Sprintf("event-%d", i),
+			ServiceName:  "orders",
+			Method:       "POST",
+			ErrorMessage: "order " + order + " failed",
+		}
+		require.NoError(t, engine.ProcessErrorEvent(event))
+		assert.Equal(t, oldVersion, event.RulesetVersion)
+	}
+	clusters, _ := engine.GetAllClusters()
+	require.Len(t, clusters, 2)
+
+	// 切换到内置规则后数字被模板化，两个簇的特征相同
+	require.NoError(t, embed.SetPreprocessRules(nil))
+	assert.NotEqual(t, oldVersion, embed.RulesetVersion())
+
+	report, err := engine.ReEmbed(true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Stale)
+	assert.Len(t, report.Remapped, 1)
+	clusters, _ = engine.GetAllClusters()
+	assert.Len(t, clusters, 2, "dry run must not modify clusters")
+
+	report, err = engine.ReEmbed(false)
+	require.NoError(t, err)
+	assert.Len(t, report.Remapped, 1)
+	clusters, _ = engine.GetAllClusters()
+	require.Len(t, clusters, 1)
+	for _, cluster := range clusters {
+		assert.ElementsMatch(t, []string{"event-0", "event-1"}, cluster.Members)
+		assert.Equal(t, int64(2), cluster.ErrorCount)
+	}
+
+	// 迁移完成后不再有落后的成员
+	report, err = engine.ReEmbed(true)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Stale)
+}
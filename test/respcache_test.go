@@ -0,0 +1,72 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/gateway/respcache"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestResponseCacheHitAndStaleIfError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cache := respcache.NewResponseCache(&types.ResponseCacheConfig{
+		Enabled:      true,
+		StaleIfError: time.Minute,
+	}, nil)
+	defer cache.Close()
+
+	rule := &types.RouteCacheConfig{TTL: 50 * time.Millisecond}
+	calls := 0
+	failing := false
+
+	engine := gin.New()
+	engine.Use(cache.Middleware(func(c *gin.Context) *types.RouteCacheConfig { return rule }))
+	engine.GET("/models", func(c *gin.Context) {
+		calls++
+		if failing {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "down"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"models": []string{"gpt-4"}})
+	})
+	engine.GET("/private", func(c *gin.Context) {
+		calls++
+		c.Header("Cache-Control", "no-store")
+		c.String(http.StatusOK, "secret")
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	first := get("/models")
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+
+	second := get("/models")
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, 1, calls)
+
+	// 缓存过期后上游故障，返回过期缓存
+	time.Sleep(60 * time.Millisecond)
+	failing = true
+	stale := get("/models")
+	assert.Equal(t, http.StatusOK, stale.Code)
+	assert.Equal(t, "STALE", stale.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), stale.Body.String())
+	assert.Equal(t, 2, calls)
+
+	// 上游禁止缓存时每次都回源
+	get("/private")
+	get("/private")
+	assert.Equal(t, 4, calls)
+}
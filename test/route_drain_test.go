@@ -0,0 +1,129 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// drainAdminKey 排空测试网关的管理密钥
+const drainAdminKey = "drain-admin-key"
+
+func TestRouteDrainAndResume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/slow") {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	gw, err := gateway.NewGateway(&types.GatewayConfig{
+		ETCD:      types.ETCDConfig{Endpoints: []string{"127.0.0.1:1"}, Timeout: time.Second},
+		Limiter:   types.LimiterConfig{DefaultRate: 1000, MaxRate: 10000},
+		Admin:     types.AdminConfig{APIKeys: []string{drainAdminKey}},
+		Upstreams: []types.UpstreamConfig{{Name: "llm", Targets: []types.UpstreamTargetConfig{{URL: upstream.URL}}}},
+		Routes:    []types.RouteConfig{{Name: "chat", PathPrefix: "/v1/chat", Upstream: "llm"}},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(gw.GetRouter())
+	defer server.Close()
+
+	// drainResponse 状态码和响应体
+	type drainResponse struct {
+		code int
+		body string
+	}
+	send := func(method, path, key string) drainResponse {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return drainResponse{code: resp.StatusCode, body: string(body)}
+	}
+	inflight := func() map[string]interface{} {
+		w := send(http.MethodGet, "/admin/inflight", "")
+		require.Equal(t, http.StatusOK, w.code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(w.body), &body))
+		return body
+	}
+
+	// 一个慢请求在途，查询在途请求的管理接口本身不计入
+	var wg sync.WaitGroup
+	wg.Add(1)
+	slowCode := 0
+	go func() {
+		defer wg.Done()
+		if resp, err := http.Get(server.URL + "/v1/chat/slow"); err == nil {
+			slowCode = resp.StatusCode
+			resp.Body.Close()
+		}
+	}()
+	require.Eventually(t, func() bool {
+		return inflight()["in_flight"] == float64(1)
+	}, 2*time.Second, 10*time.Millisecond)
+	routes := inflight()["routes"].([]interface{})
+	require.Len(t, routes, 1)
+	assert.Equal(t, "chat", routes[0].(map[string]interface{})["name"])
+
+	// 排空和恢复需要管理密钥
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/admin/routes/chat/drain", "").code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodDelete, "/admin/routes/chat/drain", "wrong").code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/admin/routes/missing/drain", drainAdminKey).code)
+
+	w := send(http.MethodPost, "/admin/routes/chat/drain", drainAdminKey)
+	require.Equal(t, http.StatusOK, w.code)
+	var drained map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(w.body), &drained))
+	assert.Equal(t, float64(1), drained["in_flight"])
+	assert.Equal(t, false, drained["drained"])
+
+	// 排空期间新请求被拒绝，已接收的请求正常完成
+	w = send(http.MethodGet, "/v1/chat/fast", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.code)
+	assert.Contains(t, w.body, "ROUTE_DRAINING")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	w = send(http.MethodPost, "/admin/routes/chat/drain?wait=2s", drainAdminKey)
+	require.Equal(t, http.StatusOK, w.code)
+	require.NoError(t, json.Unmarshal([]byte(w.body), &drained))
+	assert.Equal(t, float64(0), drained["in_flight"])
+	assert.Equal(t, true, drained["drained"])
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, slowCode)
+
+	draining := inflight()["draining"].([]interface{})
+	require.Len(t, draining, 1)
+	assert.Equal(t, float64(1), draining[0].(map[string]interface{})["rejected"])
+
+	// 恢复后重新接收请求，未在排空中的路由返回404
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/admin/routes/chat/drain", drainAdminKey).code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/admin/routes/chat/drain", drainAdminKey).code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/v1/chat/fast", "").code)
+	assert.Empty(t, inflight()["draining"])
+}
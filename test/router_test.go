@@ -0,0 +1,303 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/router"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestRouterHostMatching(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{Name: "default", PathPrefix: "/api", Upstream: "shared"},
+		{Name: "wildcard", Host: "*.example.com", PathPrefix: "/api", Upstream: "example"},
+		{Name: "exact", Host: "api.example.com", PathPrefix: "/api", Upstream: "api", Namespace: "api"},
+		{Name: "exact-llm", Host: "api.example.com", PathPrefix: "/api/llm", Upstream: "llm"},
+	})
+
+	tests := []struct {
+		host     string
+		path     string
+		expected string
+	}{
+		{"api.example.com", "/api/llm/chat", "exact-llm"},
+		{"API.example.com:8443", "/api/users", "exact"},
+		{"www.example.com", "/api/users", "wildcard"},
+		{"other.org", "/api/users", "default"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+
+		route := r.Match(req)
+		require.NotNil(t, route, tt.host+tt.path)
+		assert.Equal(t, tt.expected, route.Name, tt.host+tt.path)
+	}
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Host = "api.example.com"
+	assert.Equal(t, "api", r.Match(req).Namespace)
+
+	req = httptest.NewRequest("GET", "/other", nil)
+	assert.Nil(t, r.Match(req))
+}
+
+func TestRouterHeaderAndQueryRules(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{Name: "default", PathPrefix: "/api/llm", Upstream: "llama"},
+		{Name: "gpt4-header", PathPrefix: "/api/llm", Upstream: "gpt", Headers: map[string]string{"X-Model": "gpt-4"}},
+		{Name: "gpt4-query", PathPrefix: "/api/llm", Upstream: "gpt", Query: map[string]string{"model": "gpt-4"}},
+		{Name: "tenant", PathPrefix: "/api/llm", Upstream: "tenant", Headers: map[string]string{"X-Model": "gpt-4", "x-tenant": "*"}},
+	})
+
+	req := httptest.NewRequest("POST", "/api/llm/chat", nil)
+	assert.Equal(t, "default", r.Match(req).Name)
+
+	req = httptest.NewRequest("POST", "/api/llm/chat", nil)
+	req.Header.Set("X-Model", "gpt-4")
+	assert.Equal(t, "gpt4-header", r.Match(req).Name)
+
+	req.Header.Set("X-Tenant", "acme")
+	assert.Equal(t, "tenant", r.Match(req).Name)
+
+	req = httptest.NewRequest("POST", "/api/llm/chat?model=gpt-4", nil)
+	assert.Equal(t, "gpt4-query", r.Match(req).Name)
+
+	req = httptest.NewRequest("POST", "/api/llm/chat?model=llama", nil)
+	assert.Equal(t, "default", r.Match(req).Name)
+}
+
+func TestRouteRewrite(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{
+			Name: "legacy", PathPrefix: "/api/legacy", Upstream: "legacy",
+			Rewrite: types.RewriteConfig{
+				StripPrefix: "/api/legacy",
+				Regex:       "^/users/([0-9]+)$",
+				Replacement: "/user.php/$1",
+				AddPrefix:   "/v0",
+				Host:        "legacy.internal",
+			},
+		},
+		{Name: "invalid", PathPrefix: "/bad", Upstream: "bad", Rewrite: types.RewriteConfig{Regex: "("}},
+	})
+
+	req := httptest.NewRequest("GET", "/api/legacy/users/42?x=1", nil)
+	route := r.Match(req)
+	require.NotNil(t, route)
+
+	out := req.Clone(req.Context())
+	route.RewriteRequest(out)
+	assert.Equal(t, "/v0/user.php/42", out.URL.Path)
+	assert.Equal(t, "x=1", out.URL.RawQuery)
+	assert.Equal(t, "legacy.internal", out.Host)
+	assert.Equal(t, "/api/legacy/users/42", req.URL.Path)
+
+	// 正则非法的路由被跳过
+	assert.Nil(t, r.Match(httptest.NewRequest("GET", "/bad", nil)))
+}
+
+func TestRouteWeightedSplits(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{
+			Name: "chat", PathPrefix: "/api/chat",
+			Splits: []types.RouteSplitConfig{
+				{Upstream: "stable", Version: "v1", Weight: 95},
+				{Upstream: "canary", Version: "v2", Weight: 5},
+			},
+		},
+	})
+
+	route := r.Match(httptest.NewRequest("POST", "/api/chat", nil))
+	require.NotNil(t, route)
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		_, version := route.SelectUpstream()
+		counts[version]++
+	}
+	assert.InDelta(t, 9500, counts["v1"], 300)
+	assert.InDelta(t, 500, counts["v2"], 300)
+
+	// 运行时切换全部流量到金丝雀版本
+	require.NoError(t, r.SetSplits("chat", []types.RouteSplitConfig{{Upstream: "canary", Version: "v2", Weight: 1}}))
+	upstreamName, version := route.SelectUpstream()
+	assert.Equal(t, "canary", upstreamName)
+	assert.Equal(t, "v2", version)
+
+	assert.Error(t, r.SetSplits("chat", []types.RouteSplitConfig{{Upstream: "canary", Weight: 0}}))
+	assert.Error(t, r.SetSplits("missing", nil))
+}
+
+func TestRouterMiddlewareChain(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{Name: "public", PathPrefix: "/api/public", Upstream: "shared", Middleware: types.RouteMiddlewareConfig{
+			Skip: []string{router.MiddlewareAuth},
+		}},
+		{Name: "llm", PathPrefix: "/api/llm", Upstream: "llm", Middleware: types.RouteMiddlewareConfig{
+			RateLimit: &types.RouteRateLimitConfig{Rate: 1, Burst: 2},
+		}},
+		{Name: "invalid", PathPrefix: "/api/invalid", Upstream: "shared", Middleware: types.RouteMiddlewareConfig{
+			Skip: []string{"unknown"},
+		}},
+	})
+
+	public := r.Match(httptest.NewRequest("GET", "/api/public/status", nil))
+	require.NotNil(t, public)
+	assert.True(t, public.Skips(router.MiddlewareAuth))
+	assert.False(t, public.Skips(router.MiddlewareRateLimit))
+	assert.True(t, public.AllowRequest())
+
+	llm := r.Match(httptest.NewRequest("POST", "/api/llm/chat", nil))
+	require.NotNil(t, llm)
+	assert.True(t, llm.AllowRequest())
+	assert.True(t, llm.AllowRequest())
+	assert.False(t, llm.AllowRequest())
+
+	// 中间件配置无效的路由被跳过
+	assert.Nil(t, r.Match(httptest.NewRequest("GET", "/api/invalid", nil)))
+}
+
+func TestRouteTransform(t *testing.T) {
+	t.Setenv("BACKEND_KEY", "secret")
+	r := router.NewRouter([]types.RouteConfig{
+		{Name: "legacy", PathPrefix: "/v1", Upstream: "backend", Transform: types.TransformConfig{
+			RequestHeaders: types.HeaderTransformConfig{
+				Rename: map[string]string{"x-user": "x-backend-user"},
+				Remove: []string{"cookie"},
+				Set:    map[string]string{"x-source": "gateway"},
+			},
+			UpstreamAuth:    &types.UpstreamAuthConfig{Type: types.UpstreamAuthHeader, Header: "x-api-key", Token: "${BACKEND_KEY}"},
+			ResponseHeaders: types.HeaderTransformConfig{Remove: []string{"server"}},
+			ResponseBody: []types.JSONFieldRule{
+				{Op: types.JSONFieldRename, Path: "result.text", To: "choices.0"},
+				{Op: types.JSONFieldRemove, Path: "internal"},
+				{Op: types.JSONFieldSet, Path: "object", Value: `"text_completion"`},
+			},
+		}},
+		{Name: "invalid", PathPrefix: "/bad", Upstream: "backend", Transform: types.TransformConfig{
+			UpstreamAuth: &types.UpstreamAuthConfig{Type: "oauth"},
+		}},
+	})
+
+	req := httptest.NewRequest("POST", "/v1/complete", nil)
+	req.Header.Set("X-User", "alice")
+	req.Header.Set("Cookie", "session=1")
+	route := r.Match(req)
+	require.NotNil(t, route)
+
+	route.RewriteRequest(req)
+	assert.Equal(t, "alice", req.Header.Get("X-Backend-User"))
+	assert.Empty(t, req.Header.Get("X-User"))
+	assert.Empty(t, req.Header.Get("Cookie"))
+	assert.Equal(t, "gateway", req.Header.Get("X-Source"))
+	assert.Equal(t, "secret", req.Header.Get("X-Api-Key"))
+
+	resp := &http.Response{
+		Header: http.Header{"Content-Type": {"application/json"}, "Server": {"backend/1.0"}},
+		Body:   io.NopCloser(strings.NewReader(`{"result":{"text":"hi","tokens":12345678901234567890},"internal":{"shard":3},"choices":[null]}`)),
+	}
+	require.NoError(t, route.TransformResponse(resp))
+	body, _ := io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"result":{"tokens":12345678901234567890},"choices":["hi"],"object":"text_completion"}`, string(body))
+	assert.Equal(t, int64(len(body)), resp.ContentLength)
+	assert.Empty(t, resp.Header.Get("Server"))
+
+	// 非JSON响应体原样转发
+	resp = &http.Response{
+		Header: http.Header{"Content-Type": {"text/plain"}},
+		Body:   io.NopCloser(strings.NewReader(`{"internal":1}`)),
+	}
+	require.NoError(t, route.TransformResponse(resp))
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, `{"internal":1}`, string(body))
+
+	assert.Nil(t, r.Match(httptest.NewRequest("GET", "/bad", nil)))
+}
+
+func TestRouterBodyFieldRules(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{Name: "default", PathPrefix: "/api/llm", Upstream: "shared"},
+		{Name: "gpt4o", PathPrefix: "/api/llm", Upstream: "provider-a", BodyFields: map[string]string{"model": "gpt-4o"}},
+		{Name: "llama", PathPrefix: "/api/llm", Upstream: "in-house", BodyFields: map[string]string{"model": "llama*"}},
+		{Name: "premium", PathPrefix: "/api/llm", Upstream: "premium", BodyFields: map[string]string{"model": "gpt-4o", "metadata.tier": "premium"}},
+		{Name: "chat", PathPrefix: "/api/llm/chat", Upstream: "chat"},
+	})
+
+	match := func(path, body string) (*router.Route, string) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		route := r.Match(req)
+		require.NotNil(t, route)
+		// 匹配时读取的请求体仍可完整转发
+		forwarded, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		return route, string(forwarded)
+	}
+
+	route, forwarded := match("/api/llm/completions", `{"model":"gpt-4o"}`)
+	assert.Equal(t, "gpt4o", route.Name)
+	assert.Equal(t, `{"model":"gpt-4o"}`, forwarded)
+
+	route, _ = match("/api/llm/completions", `{"model":"llama-3-70b"}`)
+	assert.Equal(t, "llama", route.Name)
+
+	route, _ = match("/api/llm/completions", `{"model":"gpt-4o","metadata":{"tier":"premium"}}`)
+	assert.Equal(t, "premium", route.Name)
+
+	route, _ = match("/api/llm/completions", `not json`)
+	assert.Equal(t, "default", route.Name)
+
+	// 更长的前缀优先于请求体条件
+	route, _ = match("/api/llm/chat", `{"model":"gpt-4o"}`)
+	assert.Equal(t, "chat", route.Name)
+}
+
+func TestRouteResponseErrorRules(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{{
+		Name:       "legacy",
+		PathPrefix: "/api",
+		Upstream:   "legacy",
+		ResponseErrors: types.ResponseErrorConfig{Rules: []types.ResponseErrorRule{
+			{Path: "error"},
+			{Pattern: `"status":"failed","reason":"([^"]*)"`},
+		}},
+	}})
+	route := r.Match(httptest.NewRequest("GET", "/api/orders", nil))
+	require.NotNil(t, route)
+
+	classify := func(status int, body string) (string, error) {
+		resp := &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+		err := route.ClassifyResponse(resp)
+		data, _ := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	body, err := classify(200, `{"error":{"code":42,"message":"quota exhausted"}}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quota exhausted")
+	assert.Equal(t, `{"error":{"code":42,"message":"quota exhausted"}}`, body) // 响应体原样保留
+
+	_, err = classify(200, `{"status":"failed","reason":"backend offline"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backend offline")
+
+	_, err = classify(200, `{"error":null,"data":[1]}`)
+	assert.NoError(t, err)
+
+	// 非成功状态由状态码判定
+	_, err = classify(500, `{"error":"boom"}`)
+	assert.NoError(t, err)
+}
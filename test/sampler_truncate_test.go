@@ -0,0 +1,58 @@
+package test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/sampler"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestTruncatorFieldLimits(t *testing.T) {
+	truncator := sampler.NewTruncator(&types.SamplerConfig{MaxMessageLength: 10, MaxStackFrames: 2})
+
+	event := &types.ErrorEvent{
+		ErrorMessage: "上游错误：连接被重置", // 多字节字符不被切断
+		StackTrace:   []string{"a", "b", "c"},
+		Errors:       []types.StageError{{Stage: "upstream", Message: strings.Repeat("x", 20)}},
+	}
+	assert.True(t, truncator.Apply(event))
+	assert.True(t, event.Truncated)
+	assert.True(t, utf8.ValidString(event.ErrorMessage))
+	assert.LessOrEqual(t, len(event.ErrorMessage), 10)
+	assert.Equal(t, []string{"a", "b"}, event.StackTrace)
+	assert.Len(t, event.Errors[0].Message, 10)
+
+	short := &types.ErrorEvent{ErrorMessage: "timeout", StackTrace: []string{"a"}}
+	assert.False(t, truncator.Apply(short))
+	assert.False(t, short.Truncated)
+}
+
+func TestTruncatorEventSize(t *testing.T) {
+	truncator := sampler.NewTruncator(&types.SamplerConfig{MaxMessageLength: 4096, MaxEventSize: 2048})
+
+	event := &types.ErrorEvent{
+		EventID:      "e1",
+		RequestPath:  "/v1/chat",
+		ErrorMessage: strings.Repeat("m", 4<<20),
+		StackTrace:   []string{strings.Repeat("s", 300), strings.Repeat("s", 300)},
+		RequestBody:  strings.Repeat("b", 1000),
+		Errors:       []types.StageError{{Stage: "upstream", Message: strings.Repeat("e", 500)}},
+	}
+	require.True(t, truncator.Apply(event))
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(data), 2048)
+	assert.True(t, event.Truncated)
+	// 先丢弃请求体、阶段错误和堆栈，保留尽可能多的错误消息
+	assert.Empty(t, event.RequestBody)
+	assert.Empty(t, event.Errors)
+	assert.Empty(t, event.StackTrace)
+	assert.Greater(t, len(event.ErrorMessage), 1500)
+}
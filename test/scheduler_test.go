@@ -0,0 +1,84 @@
+package test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/scheduler"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestCronNext(t *testing.T) {
+	// 2026-03-14 是周六
+	base := time.Date(2026, 3, 14, 10, 17, 30, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"*/15 * * * *":  time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC),
+		"0 3 * * *":     time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC),
+		"@hourly":       time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC),
+		"30 9 * * 1-5":  time.Date(2026, 3, 16, 9, 30, 0, 0, time.UTC),
+		"0 0 1 * *":     time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		"0 12 13 * 7":   time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), // 日和周都有限制时满足任一即可
+		"5,20 10 * * *": time.Date(2026, 3, 14, 10, 20, 0, 0, time.UTC),
+		"0 0 29 2 *":    time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		"@every 90s":    base.Add(90 * time.Second),
+	}
+	for spec, expected := range cases {
+		next, err := scheduler.Next(spec, base)
+		require.NoError(t, err, spec)
+		assert.Equal(t, expected, next, spec)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every -1s", "@every soon"} {
+		_, err := scheduler.Next(spec, base)
+		assert.Error(t, err, spec)
+	}
+
+	assert.Equal(t, "@every 30s", scheduler.Spec(&types.JobSchedule{}, 30*time.Second))
+	assert.Equal(t, "@daily", scheduler.Spec(&types.JobSchedule{Cron: " @daily "}, 30*time.Second))
+}
+
+func TestSchedulerJobs(t *testing.T) {
+	jobs := scheduler.New()
+
+	var runs int32
+	release := make(chan struct{})
+	stopSlow, err := jobs.Schedule("slow", "@every 20ms", 0, func() error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return fmt.Errorf("boom")
+	})
+	require.NoError(t, err)
+
+	_, err = jobs.Schedule("slow", "@every 1s", 0, func() error { return nil })
+	assert.Error(t, err)
+	_, err = jobs.Schedule("bad", "61 * * * *", 0, func() error { return nil })
+	assert.Error(t, err)
+
+	stopFast, err := jobs.Schedule("fast", "@every 10ms", 5*time.Millisecond, func() error { return nil })
+	require.NoError(t, err)
+
+	// 执行中的任务再次触发时跳过
+	time.Sleep(120 * time.Millisecond)
+	status := jobs.Jobs()
+	require.Len(t, status, 2)
+	assert.Equal(t, "fast", status[0].Name)
+	assert.Greater(t, status[0].Runs, int64(0))
+	assert.False(t, status[0].NextRun.IsZero())
+	assert.Equal(t, "slow", status[1].Name)
+	assert.True(t, status[1].Running)
+	assert.Greater(t, status[1].Skipped, int64(0))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	close(release)
+	stopSlow()
+	stopFast()
+
+	assert.Empty(t, jobs.Jobs())
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), int32(1))
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/router"
+	"github.com/llm-aware-gateway/pkg/gateway/schema"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestSchemaValidation(t *testing.T) {
+	s, err := schema.Load("../configs/schemas/chat_completions.request.json")
+	require.NoError(t, err)
+
+	assert.Empty(t, s.Validate([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"max_tokens":16}`)))
+
+	violations := s.Validate([]byte(`{"messages":[{"role":"robot","content":"hi"}],"temperature":3,"max_tokens":1.5}`))
+	paths := make([]string, 0, len(violations))
+	for _, v := range violations {
+		paths = append(paths, v.Path)
+	}
+	assert.ElementsMatch(t, []string{"/model", "/messages/0/role", "/temperature", "/max_tokens"}, paths)
+
+	violations = s.Validate([]byte(`not json`))
+	require.Len(t, violations, 1)
+	assert.Equal(t, "", violations[0].Path)
+
+	strict, err := schema.Compile([]byte(`{"type":"object","properties":{"id":{"type":"string"}},"additionalProperties":false,
+		"oneOf":[{"required":["id"]},{"required":["name"]}]}`))
+	require.NoError(t, err)
+	assert.Empty(t, strict.Validate([]byte(`{"id":"a"}`)))
+	assert.Len(t, strict.Validate([]byte(`{"id":"a","extra":1}`)), 1)
+	assert.Len(t, strict.Validate([]byte(`{"id":"a","name":"b"}`)), 2)
+
+	_, err = schema.Compile([]byte(`{"$ref":"#/definitions/missing"}`))
+	assert.Error(t, err)
+}
+
+func TestRouteResponseSchema(t *testing.T) {
+	r := router.NewRouter([]types.RouteConfig{
+		{
+			Name:       "users",
+			PathPrefix: "/api/users",
+			Upstream:   "users",
+			Schema: types.SchemaConfig{
+				Request:  `{"type":"object","required":["name"]}`,
+				Response: `{"type":"object","required":["id"]}`,
+			},
+		},
+	})
+
+	route := r.Match(httptest.NewRequest("POST", "/api/users", nil))
+	require.NotNil(t, route)
+
+	requestSchema, maxBytes := route.RequestSchema()
+	require.NotNil(t, requestSchema)
+	assert.Equal(t, int64(1<<20), maxBytes)
+
+	respond := func(status int, body string) (*http.Response, error) {
+		resp := &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+		return resp, route.ValidateResponse(resp)
+	}
+
+	resp, err := respond(http.StatusOK, `{"name":"a"}`)
+	assert.Error(t, err)
+	// 校验失败的响应体原样保留
+	data, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"name":"a"}`, string(data))
+
+	_, err = respond(http.StatusOK, `{"id":"1"}`)
+	assert.NoError(t, err)
+
+	// 错误响应不校验
+	_, err = respond(http.StatusInternalServerError, `{"error":"boom"}`)
+	assert.NoError(t, err)
+}
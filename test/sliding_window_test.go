@@ -0,0 +1,73 @@
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	sw := limiter.NewSlidingWindow(10, 500*time.Millisecond)
+	assert.EqualValues(t, 5, sw.GetCapacity())
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, sw.Allow())
+	}
+	assert.False(t, sw.Allow())
+	assert.EqualValues(t, 0, sw.GetTokens())
+
+	// 下一个窗口开始时上一窗口的请求仍按剩余占比计入，两个窗口之后完全释放
+	time.Sleep(time.Second + 50*time.Millisecond)
+	assert.EqualValues(t, 5, sw.GetTokens())
+}
+
+func TestClusterSlidingWindowPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := limiter.NewClusterRateLimiter(&types.LimiterConfig{DefaultRate: 10, MaxRate: 100}, nil)
+	defer rl.Cleanup()
+
+	allow := func() bool {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/batch", nil)
+		c.Set("cluster_id", "batch-jobs")
+		return rl.Allow(c)
+	}
+	exhaust := func() {
+		for allow() {
+		}
+	}
+
+	require.NoError(t, rl.UpdatePolicy("batch-jobs", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{Algorithm: types.RateLimitSlidingWindow, Window: time.Second},
+	}))
+	stats, err := rl.GetStats("batch-jobs")
+	require.NoError(t, err)
+	assert.Equal(t, types.RateLimitSlidingWindow, stats.Algorithm)
+	assert.EqualValues(t, 10, stats.Capacity)
+
+	// 令牌桶在部分窗口后即可补充令牌放行突发，滑动窗口在整个窗口内保持上限
+	exhaust()
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, allow())
+
+	require.NoError(t, rl.UpdatePolicy("batch-jobs", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{},
+	}))
+	exhaust()
+	time.Sleep(300 * time.Millisecond)
+	assert.True(t, allow())
+
+	assert.Error(t, rl.UpdatePolicy("batch-jobs", &types.Policy{
+		PolicyType: types.PolicyTypeRateLimit,
+		RateLimit:  &types.RateLimitPolicy{Algorithm: "fixed_window"},
+	}))
+}
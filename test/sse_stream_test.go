@@ -0,0 +1,235 @@
+package test
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/middleware"
+	"github.com/llm-aware-gateway/pkg/gateway/upstream"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// streamOutcome 一次流式响应的指标记录
+type streamOutcome struct {
+	path    string
+	outcome string
+	events  int64
+}
+
+// streamMetrics 记录流式结果指标
+type streamMetrics struct {
+	interfaces.MetricsCollector
+	outcomes []streamOutcome
+	mutex    sync.Mutex
+}
+
+func (m *streamMetrics) RecordRequest(method, path, status, clusterID string, duration float64) {}
+
+func (m *streamMetrics) RecordStreamOutcome(path, outcome string, events int64) {
+	m.mutex.Lock()
+	m.outcomes = append(m.outcomes, streamOutcome{path: path, outcome: outcome, events: events})
+	m.mutex.Unlock()
+}
+
+// recorded 返回已记录的流式结果，流式结果在请求指标之后记录
+func (m *streamMetrics) recorded() []streamOutcome {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]streamOutcome(nil), m.outcomes...)
+}
+
+// sampledErrors 记录进入错误采样的错误消息
+type sampledErrors struct {
+	interfaces.ErrorSampler
+	messages []string
+	mutex    sync.Mutex
+}
+
+func (s *sampledErrors) SampleError(ctx *gin.Context, err error) error {
+	s.mutex.Lock()
+	s.messages = append(s.messages, err.Error())
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *sampledErrors) sampled() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// sseGateway 创建转发到上游的网关处理链，返回网关地址。
+// 错误采样在指标之内，指标记录完成时采样已完成
+func sseGateway(t *testing.T, backend http.Handler) (string, *streamMetrics, *sampledErrors) {
+	gin.SetMode(gin.TestMode)
+
+	upstreamServer := httptest.NewServer(backend)
+	t.Cleanup(upstreamServer.Close)
+
+	manager, err := upstream.NewManager([]types.UpstreamConfig{{
+		Name:    "llm",
+		Targets: []types.UpstreamTargetConfig{{URL: upstreamServer.URL}},
+	}}, nil)
+	require.NoError(t, err)
+
+	metrics := &streamMetrics{}
+	sampler := &sampledErrors{}
+	m := middleware.NewMiddleware(nil, nil, sampler, nil, metrics)
+
+	engine := gin.New()
+	engine.Use(m.Recovery(), m.Metrics(), m.ErrorSampling())
+	engine.Any("/v1/*path", func(c *gin.Context) {
+		manager.Forward(c, "llm", nil)
+	})
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server.URL, metrics, sampler
+}
+
+// writeEvent 向客户端写入一个SSE事件并立即刷新
+func writeEvent(w http.ResponseWriter, event string) {
+	io.WriteString(w, event)
+	w.(http.Flusher).Flush()
+}
+
+func TestSSEPassthroughUnbuffered(t *testing.T) {
+	for _, accept := range []string{"text/event-stream", ""} {
+		t.Run("accept "+accept, func(t *testing.T) {
+			first, second := "data: {\"delta\":\"Hel\"}\n\n", "data: {\"delta\":\"lo\"}\n\n"
+			release := make(chan struct{})
+			url, metrics, _ := sseGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+				w.Header().Set("Content-Length", strconv.Itoa(len(first)+len(second)))
+				writeEvent(w, first)
+				<-release
+				writeEvent(w, second)
+			}))
+
+			req, err := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", strings.NewReader(`{"stream":true}`))
+			require.NoError(t, err)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			// 禁止中间层缓冲，上游声明的长度被移除
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+			assert.Equal(t, "no", resp.Header.Get("X-Accel-Buffering"))
+			assert.Equal(t, int64(-1), resp.ContentLength)
+
+			// 第一个事件在上游继续发送之前到达客户端
+			reader := bufio.NewReader(resp.Body)
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, "data: {\"delta\":\"Hel\"}\n", line)
+
+			close(release)
+			rest, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, "\ndata: {\"delta\":\"lo\"}\n\n", string(rest))
+
+			require.Eventually(t, func() bool { return len(metrics.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+			assert.Equal(t, []streamOutcome{{path: "/v1/chat/completions", outcome: upstream.StreamOutcomeCompleted, events: 2}}, metrics.recorded())
+		})
+	}
+}
+
+func TestSSEStreamOutcome(t *testing.T) {
+	cases := []struct {
+		name     string
+		events   []string
+		abort    bool // 发送事件后中断上游连接
+		received string
+		outcome  string
+		count    int64
+		sampled  string
+	}{
+		{
+			name:     "completed",
+			events:   []string{"data: {\"delta\":\"Hi\"}\n\n", "data: [DONE]\n\n"},
+			received: "data: {\"delta\":\"Hi\"}\n\ndata: [DONE]\n\n",
+			outcome:  upstream.StreamOutcomeCompleted,
+			count:    2,
+		},
+		{
+			// 事件跨多次写入时按行拼接
+			name:     "event split across chunks",
+			events:   []string{"data: {\"del", "ta\":\"Hi\"}\n", "\n"},
+			received: "data: {\"delta\":\"Hi\"}\n\n",
+			outcome:  upstream.StreamOutcomeCompleted,
+			count:    1,
+		},
+		{
+			name:     "error event",
+			events:   []string{"data: {\"delta\":\"Hi\"}\n\n", "event: error\ndata: {\"type\":\"overloaded_error\"}\n\n"},
+			received: "data: {\"delta\":\"Hi\"}\n\nevent: error\ndata: {\"type\":\"overloaded_error\"}\n\n",
+			outcome:  upstream.StreamOutcomeErrorEvent,
+			count:    2,
+			sampled:  "stream error_event: error event",
+		},
+		{
+			name:     "openai error payload",
+			events:   []string{"data: {\"error\":{\"message\":\"rate limited\"}}\n\n"},
+			received: "data: {\"error\":{\"message\":\"rate limited\"}}\n\n",
+			outcome:  upstream.StreamOutcomeErrorEvent,
+			count:    1,
+			sampled:  `stream error_event: {"error":{"message":"rate limited"}}`,
+		},
+		{
+			// 已转发的事件保留，流中断计为上游失败
+			name:     "upstream disconnect",
+			events:   []string{"data: {\"delta\":\"Hi\"}\n\n"},
+			abort:    true,
+			received: "data: {\"delta\":\"Hi\"}\n\n",
+			outcome:  upstream.StreamOutcomeUpstreamError,
+			count:    1,
+			sampled:  "stream upstream_error: unexpected EOF",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, metrics, sampler := sseGateway(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range tc.events {
+					writeEvent(w, event)
+				}
+				if tc.abort {
+					panic(http.ErrAbortHandler)
+				}
+			}))
+
+			resp, err := http.Post(url+"/v1/chat/completions", "application/json", strings.NewReader(`{"stream":true}`))
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tc.received, string(body))
+
+			// 响应头发出后才能确定结果，指标和采样以最终结果为准
+			require.Eventually(t, func() bool { return len(metrics.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+			assert.Equal(t, []streamOutcome{{path: "/v1/chat/completions", outcome: tc.outcome, events: tc.count}}, metrics.recorded())
+			if tc.sampled == "" {
+				assert.Empty(t, sampler.sampled())
+			} else {
+				assert.Equal(t, []string{tc.sampled}, sampler.sampled())
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestStageErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var stages []types.StageError
+	var last error
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		stages = utils.StageErrors(c)
+		last = utils.LastRequestError(c)
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		utils.AnnotateStage(c, "auth", "skipped by route public")
+		c.Status(http.StatusOK)
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		utils.AnnotateStage(c, "rate_limit", "skipped by route public")
+		utils.RecordStageError(c, "upstream", errors.New("connection refused"))
+		c.Error(errors.New("handler failed"))
+		c.Status(http.StatusBadGateway)
+	})
+
+	// 只有注解时不视为错误
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	assert.Nil(t, last)
+	assert.Equal(t, []types.StageError{{Stage: "auth", Message: "skipped by route public", Annotation: true}}, stages)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	assert.EqualError(t, last, "handler failed")
+	assert.Equal(t, []types.StageError{
+		{Stage: "rate_limit", Message: "skipped by route public", Annotation: true},
+		{Stage: "upstream", Message: "connection refused"},
+		{Stage: "handler", Message: "handler failed"},
+	}, stages)
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/limiter"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestQuotaTree(t *testing.T) {
+	qt, err := limiter.NewQuotaTree(&types.TenantQuotaConfig{
+		Tenants: []types.TenantQuota{
+			{Tenant: "*", Quota: 2},
+			{Tenant: "acme", Quota: 5, Services: []types.ServiceQuota{
+				{Service: "llm", Quota: 10, Routes: []types.RouteQuota{{Route: "chat", Quota: 2}}},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	// 服务配额超过租户配额时按租户收紧，路由配额用尽不影响同服务的其他路由
+	decision := qt.Allow("acme", "llm", "chat")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "tenant:acme/service:llm/route:chat", decision.Scope)
+	assert.EqualValues(t, 1, decision.Remaining)
+	assert.True(t, qt.Allow("acme", "llm", "chat").Allowed)
+
+	decision = qt.Allow("acme", "llm", "chat")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, limiter.KeyRejectQuota, decision.Reason)
+	assert.Equal(t, "tenant:acme/service:llm/route:chat", decision.Scope)
+
+	decision = qt.Allow("acme", "llm", "embed")
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "tenant:acme", decision.Scope)
+	assert.EqualValues(t, 5, decision.Limit)
+	assert.EqualValues(t, 2, decision.Remaining)
+
+	// 租户配额用尽后其下所有服务和路由都被拒绝
+	assert.True(t, qt.Allow("acme", "", "").Allowed)
+	assert.True(t, qt.Allow("acme", "other", "").Allowed)
+	decision = qt.Allow("acme", "llm", "embed")
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "tenant:acme", decision.Scope)
+
+	// 默认配额对每个租户分别计算，未识别租户的请求不受约束
+	assert.True(t, qt.Allow("beta", "llm", "chat").Allowed)
+	assert.True(t, qt.Allow("gamma", "llm", "chat").Allowed)
+	assert.True(t, qt.Allow("beta", "llm", "chat").Allowed)
+	assert.False(t, qt.Allow("beta", "llm", "chat").Allowed)
+	assert.True(t, qt.Allow("", "llm", "chat").Allowed)
+
+	_, err = limiter.NewQuotaTree(&types.TenantQuotaConfig{
+		Tenants: []types.TenantQuota{{Tenant: "acme", Quota: -1}},
+	})
+	assert.Error(t, err)
+}
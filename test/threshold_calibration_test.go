@@ -0,0 +1,103 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// queuedEmbedding 按调用顺序返回预设向量的向量化服务
+type queuedEmbedding struct {
+	interfaces.EmbeddingService
+	vectors [][]float32
+}
+
+func (e *queuedEmbedding) EmbedText(text string) ([]float32, error) {
+	vector := e.vectors[0]
+	e.vectors = e.vectors[1:]
+	return vector, nil
+}
+
+func (e *queuedEmbedding) RulesetVersion() string {
+	return "test"
+}
+
+func TestThresholdCalibration(t *testing.T) {
+	// chat服务三组向量，组内相似度高于0.999，组间相似度不超过0.8
+	centers := [][]float32{{1, 0, 0, 0}, {0.8, 0.6, 0, 0}, {0.8, 0, 0.6, 0}}
+	embed := &queuedEmbedding{}
+	events := make([]*types.ErrorEvent, 0)
+	for i := 0; i < 15; i++ {
+		center := centers[i%3]
+		embed.vectors = append(embed.vectors, []float32{center[0], center[1], center[2], float32(i/3) * 0.01})
+		events = append(events, &types.ErrorEvent{EventID: fmt.Sprintf("chat-%d", i), ServiceName: "chat"})
+	}
+	// search服务只有一个簇，没有异簇向量对
+	for i := 0; i < 2; i++ {
+		embed.vectors = append(embed.vectors, []float32{0, 0, 0, 1})
+		events = append(events, &types.ErrorEvent{EventID: fmt.Sprintf("search-%d", i), ServiceName: "search"})
+	}
+
+	engine := clustering.NewClusteringEngine(&types.ClusteringConfig{
+		SimilarityThreshold:  0.95,
+		ReclusteringInterval: time.Hour,
+		MaxClusters:          10,
+		Calibration: types.ThresholdCalibrationConfig{
+			SamplePairs: 200,
+			MinPairs:    10,
+			MaxChange:   0.02,
+		},
+	}, embed, &memoryVectorDB{vectors: make(map[string][]float32)}, nil)
+
+	for _, event := range events {
+		require.NoError(t, engine.ProcessErrorEvent(event))
+	}
+	clusters, _ := engine.GetAllClusters()
+	require.Len(t, clusters, 4)
+
+	results, err := engine.CalibrateThresholds(false)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, types.CalibrationScopeGlobal, results[0].Scope)
+
+	chat := results[1]
+	assert.Equal(t, "chat", chat.Scope)
+	assert.Equal(t, 0.95, chat.Current)
+	assert.False(t, chat.Applied)
+	assert.Equal(t, 200, chat.SamePairs)
+	assert.Equal(t, 200, chat.DiffPairs)
+	assert.Greater(t, chat.SameSimilarity, chat.DiffSimilarity)
+	// 0.81到0.99的阈值都能完全区分两类向量对，推荐区间中点
+	assert.InDelta(t, 0.9, chat.Recommended, 1e-9)
+	require.Len(t, chat.Curve, 50)
+	assert.Equal(t, 0.5, chat.Curve[0].Threshold)
+	assert.Equal(t, 1.0, chat.Curve[0].FalseMergeRate)
+	assert.Equal(t, 1.0, chat.Curve[40].F1)
+
+	search := results[2]
+	assert.Equal(t, "search", search.Scope)
+	assert.Zero(t, search.Recommended)
+	assert.NotEmpty(t, search.Reason)
+
+	// 应用时每次调整不超过max_change，没有推荐阈值的服务回落到全局阈值
+	results, err = engine.CalibrateThresholds(true)
+	require.NoError(t, err)
+	assert.True(t, results[0].Applied)
+	assert.True(t, results[1].Applied)
+	assert.False(t, results[2].Applied)
+
+	results = engine.ThresholdCalibrations()
+	require.Len(t, results, 3)
+	results, err = engine.CalibrateThresholds(false)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.93, results[0].Current, 1e-9)
+	assert.InDelta(t, 0.93, results[1].Current, 1e-9)
+	assert.InDelta(t, 0.93, results[2].Current, 1e-9)
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/timeseries"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestTimeSeriesRingBuffer(t *testing.T) {
+	store := timeseries.NewTimeSeriesStore(&types.TimeSeriesConfig{
+		Resolution: time.Minute,
+		Retention:  10 * time.Minute,
+	}, nil)
+
+	now := time.Now().Truncate(time.Minute)
+
+	// 两个时间桶的计数
+	store.Record("cluster-1", now.Add(-time.Minute), 3)
+	store.Record("cluster-1", now, 2)
+	store.Record("cluster-1", now.Add(10*time.Second), 1)
+
+	points := store.Range("cluster-1", now.Add(-2*time.Minute), now)
+	assert.Len(t, points, 3)
+	assert.Equal(t, int64(0), points[0].Count)
+	assert.Equal(t, int64(3), points[1].Count)
+	assert.Equal(t, int64(3), points[2].Count)
+
+	// 超过保留期的槽位被覆盖
+	store.Record("cluster-1", now.Add(10*time.Minute), 5)
+	points = store.Range("cluster-1", now.Add(10*time.Minute), now.Add(10*time.Minute))
+	assert.Equal(t, int64(5), points[0].Count)
+	points = store.Range("cluster-1", now, now)
+	assert.Equal(t, int64(0), points[0].Count)
+
+	assert.ElementsMatch(t, []string{"cluster-1"}, store.Clusters())
+}
+
+func TestTimeSeriesGrowthRate(t *testing.T) {
+	store := timeseries.NewTimeSeriesStore(&types.TimeSeriesConfig{
+		Resolution: time.Minute,
+		Retention:  time.Hour,
+	}, nil)
+
+	now := time.Now()
+	store.Record("cluster-1", now.Add(-7*time.Minute), 10)
+	store.Record("cluster-1", now, 30)
+
+	assert.InDelta(t, 2.0, store.GrowthRate("cluster-1", 5*time.Minute), 0.001)
+	assert.Equal(t, 0.0, store.GrowthRate("unknown", 5*time.Minute))
+}
@@ -0,0 +1,217 @@
+package test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/consumer"
+	"github.com/llm-aware-gateway/pkg/gateway/sampler"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// routedTopics 汇总模拟broker收到的生产请求中的topic
+func routedTopics(broker *sarama.MockBroker) []string {
+	seen := make(map[string]bool)
+	for _, rr := range broker.History() {
+		resp, ok := rr.Response.(*sarama.ProduceResponse)
+		if !ok {
+			continue
+		}
+		for topic := range resp.Blocks {
+			seen[topic] = true
+		}
+	}
+
+	topics := make([]string, 0, len(seen))
+	for topic := range seen {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func TestTopicRouter(t *testing.T) {
+	router := sampler.NewTopicRouter("error-events", []types.TopicRouteConfig{
+		{Service: "payment", Severity: sampler.SeverityCritical, Topic: "error-events.critical.{service}"},
+		{Tenant: "enterprise", Topic: "error-events.{tenant}.{severity}"},
+		{Service: "*", Severity: sampler.SeverityClient, Topic: "error-events.client"},
+	})
+
+	cases := []struct {
+		name  string
+		event types.ErrorEvent
+		topic string
+	}{
+		{name: "critical payment", event: types.ErrorEvent{ServiceName: "payment", StatusCode: 503}, topic: "error-events.critical.payment"},
+		// 按顺序匹配，先命中的规则生效
+		{name: "critical payment of enterprise tenant", event: types.ErrorEvent{ServiceName: "payment", Tenant: "enterprise", StatusCode: 500}, topic: "error-events.critical.payment"},
+		{name: "client error of payment", event: types.ErrorEvent{ServiceName: "payment", StatusCode: 429}, topic: "error-events.client"},
+		{name: "enterprise tenant", event: types.ErrorEvent{ServiceName: "search", Tenant: "enterprise", StatusCode: 404}, topic: "error-events.enterprise.client"},
+		{name: "wildcard service", event: types.ErrorEvent{ServiceName: "search", StatusCode: 400}, topic: "error-events.client"},
+		{name: "no match uses default", event: types.ErrorEvent{ServiceName: "search", StatusCode: 502}, topic: "error-events"},
+		// 状态码为0或2xx的流式/gRPC失败按严重处理
+		{name: "stream failure without status", event: types.ErrorEvent{ServiceName: "payment"}, topic: "error-events.critical.payment"},
+		{name: "grpc failure with 200", event: types.ErrorEvent{ServiceName: "search", Tenant: "enterprise", StatusCode: 200}, topic: "error-events.enterprise.other"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.topic, router.Route(&tc.event))
+		})
+	}
+}
+
+func TestTopicRouterTemplates(t *testing.T) {
+	cases := []struct {
+		name     string
+		template string
+		event    types.ErrorEvent
+		topic    string
+	}{
+		{name: "all placeholders", template: "events.{tenant}.{service}.{severity}", event: types.ErrorEvent{ServiceName: "chat", Tenant: "acme", StatusCode: 500}, topic: "events.acme.chat.critical"},
+		{name: "missing tenant", template: "events.{tenant}", event: types.ErrorEvent{ServiceName: "chat"}, topic: "events.default"},
+		// 不允许的字符替换为下划线，超长片段截断到64字节
+		{name: "sanitized parts", template: "events.{tenant}.{service}", event: types.ErrorEvent{ServiceName: "chat/v1 beta", Tenant: "acme:eu"}, topic: "events.acme_eu.chat_v1_beta"},
+		{name: "long service", template: "events.{service}", event: types.ErrorEvent{ServiceName: strings.Repeat("s", 100)}, topic: "events." + strings.Repeat("s", 64)},
+		{name: "static topic", template: "events", event: types.ErrorEvent{ServiceName: "chat"}, topic: "events"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.topic, sampler.NewTopicRouter(tc.template, nil).Route(&tc.event))
+		})
+	}
+}
+
+func TestErrorSamplerTopicRouting(t *testing.T) {
+	topics := []string{"error-events", "error-events.critical.payment", "error-events.enterprise.client"}
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	for _, topic := range topics {
+		metadata.SetLeader(topic, 0, broker.BrokerID())
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest":    metadata,
+		"ProduceRequest":     sarama.NewMockProduceResponse(t),
+	})
+
+	es := sampler.NewErrorSampler(&types.SamplerConfig{SamplingRate: 1}, &types.KafkaConfig{
+		Brokers: []string{broker.Addr()},
+		Topic:   "error-events",
+		TopicRoutes: []types.TopicRouteConfig{
+			{Service: "payment", Severity: sampler.SeverityCritical, Topic: "error-events.critical.{service}"},
+			{Tenant: "enterprise", Topic: "error-events.{tenant}.{severity}"},
+		},
+	})
+	require.NoError(t, es.Start())
+
+	for _, event := range []*types.ErrorEvent{
+		{ServiceName: "payment", StatusCode: 503, ErrorMessage: "upstream unavailable"},
+		{ServiceName: "search", Tenant: "enterprise", StatusCode: 429, ErrorMessage: "quota exceeded"},
+		{ServiceName: "search", StatusCode: 500, ErrorMessage: "internal error"},
+	} {
+		require.NoError(t, es.SampleEvent(event))
+	}
+
+	// 停止时发送队列中剩余的事件并等待生产者完成
+	require.NoError(t, es.Stop())
+	assert.Equal(t, topics, routedTopics(broker))
+}
+
+// eventFetchResponse 构造包含指定topic单条错误事件的拉取响应
+func eventFetchResponse(t *testing.T, topics ...string) *sarama.MockFetchResponse {
+	fetch := sarama.NewMockFetchResponse(t, 1)
+	for _, topic := range topics {
+		data, err := json.Marshal(&types.ErrorEvent{EventID: topic, ErrorMessage: "error from " + topic})
+		require.NoError(t, err)
+		fetch.SetMessage(topic, 0, 0, sarama.ByteEncoder(data))
+		fetch.SetHighWaterMark(topic, 0, 1)
+	}
+	return fetch
+}
+
+func TestEventConsumerMultipleTopics(t *testing.T) {
+	topics := []string{"error-events", "error-events.critical.payment"}
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	offsets := sarama.NewMockOffsetResponse(t)
+	offsetFetch := sarama.NewMockOffsetFetchResponse(t)
+	for _, topic := range topics {
+		metadata.SetLeader(topic, 0, broker.BrokerID())
+		offsets.SetOffset(topic, 0, sarama.OffsetOldest, 0).SetOffset(topic, 0, sarama.OffsetNewest, 1)
+		offsetFetch.SetOffset("control-plane", topic, 0, 0, "", sarama.ErrNoError)
+	}
+
+	// 每个topic独立的消费组会话依次加入，分别分配一个topic
+	assignments := make([]interface{}, 0, len(topics))
+	for _, topic := range topics {
+		assignments = append(assignments, sarama.NewMockSyncGroupResponse(t).SetMemberAssignment(
+			&sarama.ConsumerGroupMemberAssignment{Topics: map[string][]int32{topic: {0}}},
+		))
+	}
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest":     sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest":        metadata,
+		"OffsetRequest":          offsets,
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).SetCoordinator(sarama.CoordinatorGroup, "control-plane", broker),
+		"JoinGroupRequest":       sarama.NewMockJoinGroupResponse(t).SetGroupProtocol(sarama.RangeBalanceStrategyName),
+		"SyncGroupRequest":       sarama.NewMockSequence(assignments...),
+		"HeartbeatRequest":       sarama.NewMockHeartbeatResponse(t),
+		"OffsetFetchRequest":     offsetFetch,
+		"OffsetCommitRequest":    sarama.NewMockOffsetCommitResponse(t),
+		"LeaveGroupRequest":      sarama.NewMockLeaveGroupResponse(t),
+		"FetchRequest":           eventFetchResponse(t, topics...),
+	})
+
+	engine := &recordingEngine{}
+	ec := consumer.NewEventConsumer(&types.KafkaConfig{
+		Brokers: []string{broker.Addr()},
+		Topic:   "error-events",
+		GroupID: "control-plane",
+		Consumers: []types.TopicConsumerConfig{
+			{Topic: "error-events"},
+			{Topic: "error-events.critical.payment", Concurrency: 8},
+		},
+	}, engine)
+	require.NoError(t, ec.Start())
+
+	// 两个topic的事件都送入聚类管道
+	consumed := func() []string {
+		engine.mutex.Lock()
+		defer engine.mutex.Unlock()
+		ids := make([]string, 0, len(engine.events))
+		for _, event := range engine.events {
+			ids = append(ids, event.EventID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	require.Eventually(t, func() bool { return len(consumed()) >= len(topics) }, 10*time.Second, 20*time.Millisecond)
+	require.NoError(t, ec.Stop())
+	assert.Equal(t, topics, consumed())
+}
+
+func TestEventConsumerBrokerUnavailable(t *testing.T) {
+	ec := consumer.NewEventConsumer(&types.KafkaConfig{
+		Brokers:   []string{"127.0.0.1:1"},
+		GroupID:   "control-plane",
+		Consumers: []types.TopicConsumerConfig{{Topic: "error-events.critical"}},
+	}, &recordingEngine{})
+
+	// 创建消费组失败时停止已启动的消费组并返回出错的topic
+	err := ec.Start()
+	assert.ErrorContains(t, err, "failed to create consumer group for topic error-events.critical")
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/llm-aware-gateway/pkg/gateway/upstream"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// stubNameserver 本地UDP名称服务器，A记录按当前配置应答，AAAA记录返回空应答
+type stubNameserver struct {
+	conn    net.PacketConn
+	addrs   []string
+	queries int32
+	mutex   sync.Mutex
+}
+
+// newStubNameserver 启动名称服务器
+func newStubNameserver(t *testing.T, addrs ...string) *stubNameserver {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ns := &stubNameserver{conn: conn, addrs: addrs}
+	t.Cleanup(func() { conn.Close() })
+	go ns.serve()
+	return ns
+}
+
+func (ns *stubNameserver) set(addrs ...string) {
+	ns.mutex.Lock()
+	ns.addrs = addrs
+	ns.mutex.Unlock()
+}
+
+func (ns *stubNameserver) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, peer, err := ns.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var request dnsmessage.Message
+		if err := request.Unpack(buf[:n]); err != nil || len(request.Questions) != 1 {
+			continue
+		}
+		atomic.AddInt32(&ns.queries, 1)
+
+		question := request.Questions[0]
+		response := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: request.Header.ID, Response: true, RecursionAvailable: true},
+			Questions: request.Questions,
+		}
+		if question.Type == dnsmessage.TypeA {
+			ns.mutex.Lock()
+			for _, addr := range ns.addrs {
+				var a [4]byte
+				copy(a[:], net.ParseIP(addr).To4())
+				response.Answers = append(response.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 1},
+					Body:   &dnsmessage.AResource{A: a},
+				})
+			}
+			ns.mutex.Unlock()
+		}
+		packed, err := response.Pack()
+		if err != nil {
+			continue
+		}
+		ns.conn.WriteTo(packed, peer)
+	}
+}
+
+// serveBackend 在指定地址启动返回固定内容的HTTP服务
+func serveBackend(t *testing.T, listener net.Listener, name string) {
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+}
+
+func TestUpstreamDNSRefresh(t *testing.T) {
+	// 两个后端监听同一端口的不同回环地址，域名解析结果决定连接到哪一个
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := strconv.Itoa(first.Addr().(*net.TCPAddr).Port)
+	second, err := net.Listen("tcp", "127.0.0.2:"+port)
+	if err != nil {
+		first.Close()
+		t.Skipf("127.0.0.2 not available: %v", err)
+	}
+	serveBackend(t, first, "one")
+	serveBackend(t, second, "two")
+
+	ns := newStubNameserver(t, "127.0.0.1")
+	manager, err := upstream.NewManager([]types.UpstreamConfig{{
+		Name:    "svc",
+		Targets: []types.UpstreamTargetConfig{{URL: "http://svc.test:" + port}},
+		Transport: types.UpstreamTransportConfig{
+			DNS: types.UpstreamDNSConfig{
+				Enabled:     true,
+				Nameservers: []string{ns.conn.LocalAddr().String()},
+				MinTTL:      time.Second,
+				MaxTTL:      time.Second,
+			},
+		},
+	}}, nil)
+	require.NoError(t, err)
+	pool, ok := manager.Pool("svc")
+	require.True(t, ok)
+	client := &http.Client{Transport: pool.Transport(), Timeout: 2 * time.Second}
+
+	// get 请求上游返回响应的后端，closeConn为true时请求后关闭连接，下一次请求重新拨号
+	get := func(closeConn bool) string {
+		req, err := http.NewRequest(http.MethodGet, "http://svc.test:"+port+"/", nil)
+		if err != nil {
+			return err.Error()
+		}
+		req.Close = closeConn
+		resp, err := client.Do(req)
+		if err != nil {
+			return err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// TTL内重复拨号使用缓存的解析结果
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "one", get(true))
+	}
+	assert.Equal(t, "one", get(false))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&ns.queries), "one A and one AAAA query")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	manager.Watch(stopCh)
+
+	// TTL到期前异步刷新，地址变化后关闭空闲连接，长连接也切换到新地址
+	ns.set("127.0.0.2")
+	require.Eventually(t, func() bool { return get(false) == "two" }, 5*time.Second, 50*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "two", get(false))
+	}
+
+	// 新增地址后新连接在两个地址间轮询，等待两轮刷新保证解析到新地址
+	queries := atomic.LoadInt32(&ns.queries)
+	ns.set("127.0.0.1", "127.0.0.2")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&ns.queries) >= queries+4 }, 5*time.Second, 50*time.Millisecond)
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		seen[get(true)]++
+	}
+	assert.Equal(t, map[string]int{"one": 2, "two": 2}, seen)
+}
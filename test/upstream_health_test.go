@@ -0,0 +1,190 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/breaker"
+	"github.com/llm-aware-gateway/pkg/gateway/upstream"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// upstreamTarget 按实例ID查找池中的实例
+func upstreamTarget(t *testing.T, pool *upstream.Pool, id string) *upstream.Target {
+	for i := 0; i < 10; i++ {
+		target, err := pool.Next()
+		require.NoError(t, err)
+		if target.ID == id {
+			return target
+		}
+	}
+	t.Fatalf("target %s not selected", id)
+	return nil
+}
+
+// upstreamStatus 重算权重后按实例ID返回状态
+func upstreamStatus(t *testing.T, pool *upstream.Pool) map[string]upstream.TargetStatus {
+	_, err := pool.Next()
+	require.NoError(t, err)
+
+	statuses := make(map[string]upstream.TargetStatus)
+	for _, status := range pool.Status() {
+		statuses[status.ID] = status
+	}
+	return statuses
+}
+
+func TestUpstreamLatencyHysteresis(t *testing.T) {
+	pool, err := upstream.NewPool(&types.UpstreamConfig{
+		Name: "chat",
+		Targets: []types.UpstreamTargetConfig{
+			{URL: "http://a:8080"}, {URL: "http://b:8080"}, {URL: "http://c:8080"},
+		},
+		Health: types.HealthWeightConfig{
+			Enabled:        true,
+			LatencyAlpha:   1,
+			DegradeRatio:   2,
+			RecoverRatio:   1.5,
+			UpdateInterval: time.Nanosecond,
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	a := upstreamTarget(t, pool, "upstream:chat/a:8080")
+	b := upstreamTarget(t, pool, "upstream:chat/b:8080")
+	c := upstreamTarget(t, pool, "upstream:chat/c:8080")
+	pool.Report(a, 100*time.Millisecond, false)
+	pool.Report(b, 100*time.Millisecond, false)
+
+	// 延迟比介于恢复阈值和降权阈值之间时不进入降权
+	pool.Report(c, 180*time.Millisecond, false)
+	status := upstreamStatus(t, pool)["upstream:chat/c:8080"]
+	assert.False(t, status.Degraded)
+	assert.Equal(t, 100, status.EffectiveWeight)
+
+	// 超过降权阈值后按延迟比降权，降权立即生效
+	pool.Report(c, 300*time.Millisecond, false)
+	status = upstreamStatus(t, pool)["upstream:chat/c:8080"]
+	assert.True(t, status.Degraded)
+	assert.Equal(t, 33, status.EffectiveWeight)
+
+	// 回落到两个阈值之间时保持降权，只按步长回升
+	pool.Report(c, 180*time.Millisecond, false)
+	status = upstreamStatus(t, pool)["upstream:chat/c:8080"]
+	assert.True(t, status.Degraded)
+	assert.Equal(t, 53, status.EffectiveWeight)
+
+	// 低于恢复阈值后退出降权，权重按步长逐步回到满值
+	pool.Report(c, 140*time.Millisecond, false)
+	statuses := upstreamStatus(t, pool)
+	assert.False(t, statuses["upstream:chat/c:8080"].Degraded)
+	assert.Equal(t, 73, statuses["upstream:chat/c:8080"].EffectiveWeight)
+	assert.Equal(t, 93, upstreamStatus(t, pool)["upstream:chat/c:8080"].EffectiveWeight)
+	assert.Equal(t, 100, upstreamStatus(t, pool)["upstream:chat/c:8080"].EffectiveWeight)
+	assert.Equal(t, 100, statuses["upstream:chat/a:8080"].EffectiveWeight)
+}
+
+func TestUpstreamEffectiveWeight(t *testing.T) {
+	cb := breaker.NewClusterCircuitBreaker(&types.BreakerConfig{FailureThreshold: 2})
+	pool, err := upstream.NewPool(&types.UpstreamConfig{
+		Name: "chat",
+		Targets: []types.UpstreamTargetConfig{
+			{URL: "http://a:8080", Weight: 2}, {URL: "http://b:8080"},
+		},
+		Health: types.HealthWeightConfig{
+			Enabled:        true,
+			LatencyAlpha:   1,
+			UpdateInterval: time.Nanosecond,
+			BreakDuration:  20 * time.Millisecond,
+		},
+	}, cb)
+	require.NoError(t, err)
+
+	a := upstreamTarget(t, pool, "upstream:chat/a:8080")
+	statuses := upstreamStatus(t, pool)
+	assert.Equal(t, 200, statuses["upstream:chat/a:8080"].EffectiveWeight)
+	assert.Equal(t, 100, statuses["upstream:chat/b:8080"].EffectiveWeight)
+
+	// 错误率降权，连续失败打开实例熔断器后不再参与选择
+	pool.Report(a, 10*time.Millisecond, true)
+	pool.Report(a, 10*time.Millisecond, true)
+	status := upstreamStatus(t, pool)["upstream:chat/a:8080"]
+	assert.Equal(t, types.BreakerStateOpen, status.BreakerState)
+	assert.Equal(t, 0, status.EffectiveWeight)
+	for i := 0; i < 5; i++ {
+		target, err := pool.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "upstream:chat/b:8080", target.ID)
+	}
+
+	// 熔断到期后按半开系数放量，重算权重不记录请求
+	pool.Report(a, 10*time.Millisecond, false)
+	time.Sleep(25 * time.Millisecond)
+	status = upstreamStatus(t, pool)["upstream:chat/a:8080"]
+	assert.Equal(t, types.BreakerStateHalfOpen, status.BreakerState)
+	assert.Equal(t, 50, status.EffectiveWeight)
+	assert.Equal(t, int64(0), cb.Stats()["total_requests"])
+}
+
+func TestUpstreamEffectiveWeightDisabled(t *testing.T) {
+	pool, err := upstream.NewPool(&types.UpstreamConfig{
+		Name: "chat",
+		Targets: []types.UpstreamTargetConfig{
+			{URL: "http://a:8080", Weight: 3}, {URL: "http://b:8080", Weight: 0},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	// 未开启健康加权时健康信号不影响权重，未配置权重按1处理
+	a := upstreamTarget(t, pool, "upstream:chat/a:8080")
+	pool.Report(a, time.Second, true)
+	statuses := make(map[string]upstream.TargetStatus)
+	for _, status := range pool.Status() {
+		statuses[status.ID] = status
+	}
+	assert.Equal(t, 300, statuses["upstream:chat/a:8080"].EffectiveWeight)
+	assert.Equal(t, 100, statuses["upstream:chat/b:8080"].EffectiveWeight)
+}
+
+func TestUpstreamNextReport(t *testing.T) {
+	cb := breaker.NewClusterCircuitBreaker(&types.BreakerConfig{FailureThreshold: 1})
+	pool, err := upstream.NewPool(&types.UpstreamConfig{
+		Name: "chat",
+		Targets: []types.UpstreamTargetConfig{
+			{URL: "http://a:8080", Weight: 3}, {URL: "http://b:8080", Weight: 1},
+		},
+		Health: types.HealthWeightConfig{Enabled: true, UpdateInterval: time.Hour, BreakDuration: time.Hour},
+	}, cb)
+	require.NoError(t, err)
+
+	// 平滑加权轮询按权重比例分配
+	counts := make(map[string]int)
+	var b *upstream.Target
+	for i := 0; i < 8; i++ {
+		target, err := pool.Next()
+		require.NoError(t, err)
+		counts[target.ID]++
+		if target.ID == "upstream:chat/b:8080" {
+			b = target
+		}
+	}
+	assert.Equal(t, 6, counts["upstream:chat/a:8080"])
+	assert.Equal(t, 2, counts["upstream:chat/b:8080"])
+
+	// Report的失败计入实例熔断器，所有实例熔断后Next返回错误
+	require.NotNil(t, b)
+	pool.Report(b, 10*time.Millisecond, true)
+	assert.Equal(t, types.BreakerStateOpen, cb.GetState("upstream:chat/b:8080"))
+	for i := 0; i < 4; i++ {
+		target, err := pool.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "upstream:chat/a:8080", target.ID)
+	}
+	a := upstreamTarget(t, pool, "upstream:chat/a:8080")
+	pool.Report(a, 10*time.Millisecond, true)
+	_, err = pool.Next()
+	assert.Error(t, err)
+}
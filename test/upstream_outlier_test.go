@@ -0,0 +1,189 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/upstream"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// outlierPool 创建n个等权实例的上游池，实例依次命名为t0、t1...
+func outlierPool(t *testing.T, n int, outlier types.OutlierDetectionConfig) (*upstream.Pool, []*upstream.Target) {
+	configs := make([]types.UpstreamTargetConfig, n)
+	for i := range configs {
+		configs[i] = types.UpstreamTargetConfig{URL: fmt.Sprintf("http://t%d:8080", i)}
+	}
+	outlier.Enabled = true
+	pool, err := upstream.NewPool(&types.UpstreamConfig{
+		Name:    "chat",
+		Targets: configs,
+		Health:  types.HealthWeightConfig{UpdateInterval: time.Nanosecond},
+		Outlier: outlier,
+	}, nil)
+	require.NoError(t, err)
+
+	targets := make([]*upstream.Target, n)
+	for i := range targets {
+		targets[i] = upstreamTarget(t, pool, fmt.Sprintf("upstream:chat/t%d:8080", i))
+	}
+	return pool, targets
+}
+
+// ejectedTargets 返回处于驱逐期的实例下标
+func ejectedTargets(pool *upstream.Pool) []int {
+	ejected := []int{}
+	for i, status := range pool.Status() {
+		if status.Ejected {
+			ejected = append(ejected, i)
+		}
+	}
+	return ejected
+}
+
+func TestUpstreamOutlierEjection(t *testing.T) {
+	cases := []struct {
+		name     string
+		targets  int
+		percent  int
+		failures []int // 依次上报一次失败的实例下标
+		ejected  []int
+	}{
+		{name: "consecutive errors", targets: 4, percent: 50, failures: []int{0, 0}, ejected: []int{0}},
+		{name: "below threshold", targets: 4, percent: 50, failures: []int{0, 1}, ejected: []int{}},
+		{name: "max ejection percent", targets: 4, percent: 50, failures: []int{0, 0, 1, 1, 2, 2}, ejected: []int{0, 1}},
+		{name: "default percent allows one", targets: 10, failures: []int{0, 0, 1, 1}, ejected: []int{0}},
+		{name: "percent rounds up to one", targets: 4, percent: 5, failures: []int{0, 0, 1, 1}, ejected: []int{0}},
+		{name: "keeps one target", targets: 2, percent: 100, failures: []int{0, 0, 1, 1}, ejected: []int{0}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, targets := outlierPool(t, tc.targets, types.OutlierDetectionConfig{
+				ConsecutiveErrors:  2,
+				BaseEjectionTime:   time.Minute,
+				MaxEjectionPercent: tc.percent,
+			})
+			for _, i := range tc.failures {
+				pool.Report(targets[i], 10*time.Millisecond, true)
+			}
+			assert.Equal(t, tc.ejected, ejectedTargets(pool))
+		})
+	}
+
+	t.Run("success resets consecutive errors", func(t *testing.T) {
+		pool, targets := outlierPool(t, 4, types.OutlierDetectionConfig{ConsecutiveErrors: 2, MaxEjectionPercent: 50})
+		pool.Report(targets[0], 10*time.Millisecond, true)
+		pool.Report(targets[0], 10*time.Millisecond, false)
+		pool.Report(targets[0], 10*time.Millisecond, true)
+		assert.Empty(t, ejectedTargets(pool))
+	})
+
+	t.Run("skipped ejection resets consecutive errors", func(t *testing.T) {
+		pool, targets := outlierPool(t, 2, types.OutlierDetectionConfig{ConsecutiveErrors: 2, MaxEjectionPercent: 100})
+		pool.Report(targets[1], 10*time.Millisecond, true)
+		pool.Report(targets[1], 10*time.Millisecond, true)
+		pool.Report(targets[0], 10*time.Millisecond, true)
+		pool.Report(targets[0], 10*time.Millisecond, true)
+		assert.Equal(t, []int{1}, ejectedTargets(pool))
+	})
+}
+
+func TestUpstreamOutlierEjectionTime(t *testing.T) {
+	pool, targets := outlierPool(t, 4, types.OutlierDetectionConfig{
+		ConsecutiveErrors:  1,
+		BaseEjectionTime:   40 * time.Millisecond,
+		MaxEjectionTime:    60 * time.Millisecond,
+		MaxEjectionPercent: 50,
+	})
+
+	// 驱逐期间不参与选择，到期后由Next恢复
+	pool.Report(targets[0], 10*time.Millisecond, true)
+	assert.Equal(t, []int{0}, ejectedTargets(pool))
+	for i := 0; i < 6; i++ {
+		target, err := pool.Next()
+		require.NoError(t, err)
+		assert.NotEqual(t, targets[0].ID, target.ID)
+	}
+	time.Sleep(50 * time.Millisecond)
+	_, err := pool.Next()
+	require.NoError(t, err)
+	assert.Empty(t, ejectedTargets(pool))
+
+	// 再次驱逐时时长倍增，但不超过上限
+	pool.Report(targets[0], 10*time.Millisecond, true)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []int{0}, ejectedTargets(pool))
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, ejectedTargets(pool))
+}
+
+func TestUpstreamLatencyOutliers(t *testing.T) {
+	fast, slow := 10*time.Millisecond, 200*time.Millisecond
+	repeat := func(latency time.Duration, n int) []time.Duration {
+		samples := make([]time.Duration, n)
+		for i := range samples {
+			samples[i] = latency
+		}
+		return samples
+	}
+
+	cases := []struct {
+		name       string
+		targets    int
+		threshold  time.Duration
+		percentile float64
+		samples    map[int][]time.Duration // 实例下标 -> 依次上报的成功请求延迟
+		ejected    []int
+	}{
+		{name: "slow target", targets: 4, threshold: 100 * time.Millisecond, samples: map[int][]time.Duration{
+			0: repeat(fast, 5), 1: repeat(slow, 5),
+		}, ejected: []int{1}},
+		{name: "below min samples", targets: 4, threshold: 100 * time.Millisecond, samples: map[int][]time.Duration{
+			1: repeat(slow, 4),
+		}, ejected: []int{}},
+		{name: "latency ejection disabled", targets: 4, samples: map[int][]time.Duration{
+			1: repeat(slow, 5),
+		}, ejected: []int{}},
+		{name: "median under threshold", targets: 4, threshold: 100 * time.Millisecond, percentile: 0.5, samples: map[int][]time.Duration{
+			1: append(repeat(fast, 6), repeat(slow, 4)...),
+		}, ejected: []int{}},
+		{name: "p90 over threshold", targets: 4, threshold: 100 * time.Millisecond, percentile: 0.9, samples: map[int][]time.Duration{
+			1: append(repeat(fast, 6), repeat(slow, 4)...),
+		}, ejected: []int{1}},
+		{name: "window drops old samples", targets: 4, threshold: 100 * time.Millisecond, samples: map[int][]time.Duration{
+			1: append(repeat(slow, 10), repeat(fast, 10)...),
+		}, ejected: []int{}},
+		{name: "max ejection percent", targets: 4, threshold: 100 * time.Millisecond, samples: map[int][]time.Duration{
+			0: repeat(slow, 5), 1: repeat(slow, 5), 2: repeat(slow, 5),
+		}, ejected: []int{0, 1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, targets := outlierPool(t, tc.targets, types.OutlierDetectionConfig{
+				ConsecutiveErrors:  100,
+				LatencyThreshold:   tc.threshold,
+				LatencyPercentile:  tc.percentile,
+				WindowSize:         10,
+				MinSamples:         5,
+				BaseEjectionTime:   time.Minute,
+				MaxEjectionPercent: 50,
+			})
+			for i, samples := range tc.samples {
+				for _, latency := range samples {
+					pool.Report(targets[i], latency, false)
+				}
+			}
+
+			// 延迟驱逐在Next重算时检测
+			_, err := pool.Next()
+			require.NoError(t, err)
+			assert.Equal(t, tc.ejected, ejectedTargets(pool))
+		})
+	}
+}
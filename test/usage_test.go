@@ -0,0 +1,95 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/usage"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+func TestUsageLedger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ledger := usage.NewLedger(&types.UsageConfig{Resolution: time.Hour}, nil)
+
+	router := gin.New()
+	router.Use(ledger.Middleware())
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set("route_name", "chat")
+		c.Set("llm_model", "gpt-4o")
+		switch c.Query("result") {
+		case "rejected":
+			utils.RecordStageError(c, "rate_limit", assert.AnError)
+			c.Status(http.StatusTooManyRequests)
+		case "failed":
+			c.Status(http.StatusBadGateway)
+		default:
+			c.Set("llm_prompt_tokens", int64(100))
+			c.Set("llm_completion_tokens", int64(20))
+			c.Set("llm_cost", 0.5)
+			c.Status(http.StatusOK)
+		}
+	})
+	router.GET("/admin/usage", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, tenant, key string) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Tenant-ID", tenant)
+		req.Header.Set("X-API-Key", key)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("POST", "/v1/chat/completions", "acme", "key-a")
+	send("POST", "/v1/chat/completions", "acme", "key-a")
+	send("POST", "/v1/chat/completions?result=failed", "acme", "key-b")
+	send("POST", "/v1/chat/completions?result=rejected", "globex", "key-c")
+	send("GET", "/admin/usage", "acme", "key-a")
+
+	now := time.Now()
+	query := &types.UsageQuery{From: now.Add(-time.Hour), To: now.Add(time.Minute), GroupBy: []string{types.UsageGroupTenant}}
+	records, err := ledger.Query(query)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "acme", records[0].Tenant)
+	assert.Empty(t, records[0].APIKey)
+	assert.Equal(t, types.UsageCounters{Requests: 3, Errors: 1, PromptTokens: 200, CompletionTokens: 40, Cost: 1}, records[0].UsageCounters)
+	assert.Equal(t, types.UsageCounters{Requests: 1, Rejections: 1}, records[1].UsageCounters)
+
+	// 按密钥过滤并按时间分组
+	records, err = ledger.Query(&types.UsageQuery{
+		From:    query.From,
+		To:      query.To,
+		GroupBy: []string{types.UsageGroupTime, types.UsageGroupAPIKey},
+		APIKey:  utils.APIKeyID("key-a"),
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.NotNil(t, records[0].Start)
+	assert.Equal(t, now.Truncate(time.Hour).UTC(), *records[0].Start)
+	assert.Equal(t, utils.APIKeyID("key-a"), records[0].APIKey)
+	assert.EqualValues(t, 2, records[0].Requests)
+
+	// 时间范围之外没有用量
+	records, err = ledger.Query(&types.UsageQuery{From: now.Add(-48 * time.Hour), To: now.Add(-24 * time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	_, err = ledger.Query(&types.UsageQuery{From: query.From, To: query.To, GroupBy: []string{"region"}})
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	records, err = ledger.Query(query)
+	require.NoError(t, err)
+	require.NoError(t, usage.WriteCSV(&buf, query.GroupBy, records))
+	assert.Equal(t, "tenant,requests,errors,rejections,prompt_tokens,completion_tokens,cost\n"+
+		"acme,3,1,0,200,40,1.000000\n"+
+		"globex,1,0,1,0,0,0.000000\n", buf.String())
+	assert.Equal(t, int64(4), usage.Total(records).Requests)
+}
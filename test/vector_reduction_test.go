@@ -0,0 +1,82 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/controlplane/clustering"
+	"github.com/llm-aware-gateway/pkg/controlplane/embedding"
+	"github.com/llm-aware-gateway/pkg/gateway/vector"
+	"github.com/llm-aware-gateway/pkg/interfaces"
+	"github.com/llm-aware-gateway/pkg/types"
+	"github.com/llm-aware-gateway/pkg/utils"
+)
+
+// staticClusters 返回固定簇的聚类引擎
+type staticClusters struct {
+	interfaces.ClusteringEngine
+	clusters map[string]*types.Cluster
+}
+
+func (s *staticClusters) GetAllClusters() (map[string]*types.Cluster, error) {
+	return s.clusters, nil
+}
+
+func TestClusterSnapshotReduction(t *testing.T) {
+	embed := embedding.NewEmbeddingService(&types.EmbeddingConfig{
+		ModelName: "bge-small", ModelVersion: "v1.5", BatchSize: 8, CacheSize: 10, Dimension: 16,
+	})
+	signatures := map[string]string{
+		"timeout":  "upstream timeout after 30s",
+		"refused":  "connection refused by 10.0.0.1",
+		"overload": "model is overloaded, retry later",
+	}
+	clusters := make(map[string]*types.Cluster)
+	for id, signature := range signatures {
+		centroid, err := embed.EmbedText(embed.PreprocessText(signature))
+		require.NoError(t, err)
+		clusters[id] = &types.Cluster{ID: id, Centroid: centroid}
+	}
+
+	publisher := clustering.NewSnapshotPublisher(&staticClusters{clusters: clusters}, embed, newMemoryConfigStore(), 0)
+	require.Error(t, publisher.SetReduction(&types.VectorReductionConfig{Enabled: true, Method: "svd"}))
+	require.NoError(t, publisher.SetReduction(&types.VectorReductionConfig{Enabled: true, Dimension: 8}))
+
+	snapshot, err := publisher.Build()
+	require.NoError(t, err)
+	require.NotNil(t, snapshot.Projection)
+	// PCA的维度不超过簇数，质心落在投影空间内
+	assert.Equal(t, types.VectorReductionPCA, snapshot.Projection.Method)
+	assert.Equal(t, 16, snapshot.Projection.InputDim)
+	assert.Equal(t, 3, snapshot.Projection.OutputDim)
+	for _, cluster := range snapshot.Clusters {
+		assert.Len(t, cluster.Centroid, 3)
+	}
+	assert.Len(t, clusters["timeout"].Centroid, 16, "control plane keeps full-precision centroids")
+
+	agent := vector.NewVectorAgent(embed, utils.NewCache(100))
+	require.NoError(t, agent.ApplySnapshot(snapshot))
+	for id, signature := range signatures {
+		clusterID, similarity, err := agent.IdentifyClusterWithScore(signature)
+		require.NoError(t, err)
+		assert.Equal(t, id, clusterID)
+		assert.InDelta(t, 1.0, similarity, 1e-3)
+	}
+
+	// 随机投影与簇无关，相同种子下矩阵不变
+	require.NoError(t, publisher.SetReduction(&types.VectorReductionConfig{Enabled: true, Method: types.VectorReductionRandom, Dimension: 8}))
+	first, err := publisher.Build()
+	require.NoError(t, err)
+	second, err := publisher.Build()
+	require.NoError(t, err)
+	require.NotNil(t, first.Projection)
+	assert.Equal(t, 8, first.Projection.OutputDim)
+	assert.Equal(t, first.Projection.Matrix, second.Projection.Matrix)
+
+	// 投影矩阵的输入维度与嵌入模型不符时拒绝
+	first.Projection.InputDim = 12
+	_, ok := agent.ApplySnapshot(first).(*vector.EmbeddingMismatchError)
+	assert.True(t, ok)
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/llm-aware-gateway/pkg/gateway/waf"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+func TestWAFRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := waf.NewWAF(&types.WAFConfig{
+		Enabled:        true,
+		AllowedMethods: []string{"GET", "POST"},
+		MaxHeaders:     5,
+		Rules: []types.WAFRule{
+			{ID: "sqli", Path: `(?i)union\s+select`},
+			{ID: "scanner", Action: types.WAFActionTag, Headers: map[string]string{"user-agent": "(?i)sqlmap"}},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(engine.Middleware())
+	router.Any("/*path", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, c.GetHeader(waf.TagsHeader)+"|"+string(body))
+	})
+
+	do := func(method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("GET", "/v1/items", "", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/v1/items", "", nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/v1/items?q=1+UNION+SELECT+1", "", nil).Code)
+
+	w := do("GET", "/v1/items", "", map[string]string{"User-Agent": "sqlmap/1.7"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "scanner|", w.Body.String())
+
+	many := map[string]string{"A": "1", "B": "2", "C": "3", "D": "4", "E": "5", "F": "6"}
+	assert.Equal(t, http.StatusForbidden, do("GET", "/v1/items", "", many).Code)
+
+	// 策略下发的请求体规则，检查后请求体完整转发
+	require.NoError(t, engine.SetRuleSet("waf/prompt", &types.Policy{
+		PolicyType: types.PolicyTypeWAF,
+		WAF:        &types.WAFPolicy{Rules: []types.WAFRule{{ID: "secret", Methods: []string{"POST"}, Body: "BEGIN PRIVATE KEY"}}},
+	}))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/v1/chat", `{"prompt":"BEGIN PRIVATE KEY"}`, nil).Code)
+	w = do("POST", "/v1/chat", `{"prompt":"hello"}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `|{"prompt":"hello"}`, w.Body.String())
+
+	assert.True(t, engine.RemoveRuleSet("waf/prompt"))
+	assert.Equal(t, http.StatusOK, do("POST", "/v1/chat", `{"prompt":"BEGIN PRIVATE KEY"}`, nil).Code)
+
+	assert.Error(t, engine.SetRuleSet("bad", &types.Policy{WAF: &types.WAFPolicy{Rules: []types.WAFRule{{Path: "("}}}}))
+}
@@ -0,0 +1,226 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"google.golang.org/grpc"
+
+	"github.com/llm-aware-gateway/pkg/gateway"
+	"github.com/llm-aware-gateway/pkg/types"
+)
+
+// fakeEtcd 空的etcd服务，读取返回空结果，监听只确认创建，供网关启动使用
+type fakeEtcd struct {
+	etcdserverpb.UnimplementedKVServer
+	etcdserverpb.UnimplementedWatchServer
+}
+
+func (f *fakeEtcd) Range(ctx context.Context, req *etcdserverpb.RangeRequest) (*etcdserverpb.RangeResponse, error) {
+	return &etcdserverpb.RangeResponse{Header: &etcdserverpb.ResponseHeader{Revision: 1}}, nil
+}
+
+func (f *fakeEtcd) Watch(stream etcdserverpb.Watch_WatchServer) error {
+	var watchID int64
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if req.GetCreateRequest() == nil {
+			continue
+		}
+		if err := stream.Send(&etcdserverpb.WatchResponse{
+			Header:  &etcdserverpb.ResponseHeader{Revision: 1},
+			WatchId: watchID,
+			Created: true,
+		}); err != nil {
+			return err
+		}
+		watchID++
+	}
+}
+
+// startFakeEtcd 启动空的etcd服务，返回地址
+func startFakeEtcd(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	etcd := &fakeEtcd{}
+	etcdserverpb.RegisterKVServer(server, etcd)
+	etcdserverpb.RegisterWatchServer(server, etcd)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return ln.Addr().String()
+}
+
+// watchdogGateway 启动开启看门狗的网关，返回网关地址和接收错误事件的broker。
+// chat路由预期50ms，batch路由未配置预期时长
+func watchdogGateway(t *testing.T, watchdog types.WatchdogConfig, backend http.Handler) (string, *sarama.MockBroker) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(backend)
+	t.Cleanup(upstream.Close)
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("error-events", 0, broker.BrokerID()).
+			SetLeader("error-events.acme.critical", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+	})
+
+	gw, err := gateway.NewGateway(&types.GatewayConfig{
+		Server:  types.ServerConfig{Host: "127.0.0.1"},
+		ETCD:    types.ETCDConfig{Endpoints: []string{startFakeEtcd(t)}, Timeout: time.Second},
+		Limiter: types.LimiterConfig{DefaultRate: 1000, MaxRate: 10000},
+		Kafka: types.KafkaConfig{
+			Brokers:     []string{broker.Addr()},
+			Topic:       "error-events",
+			TopicRoutes: []types.TopicRouteConfig{{Tenant: "acme", Topic: "error-events.{tenant}.{severity}"}},
+		},
+		Watchdog:  watchdog,
+		Upstreams: []types.UpstreamConfig{{Name: "llm", Targets: []types.UpstreamTargetConfig{{URL: upstream.URL}}}},
+		Routes: []types.RouteConfig{
+			{Name: "chat", PathPrefix: "/v1/chat", Upstream: "llm", Expected: 50 * time.Millisecond},
+			{Name: "batch", PathPrefix: "/v1/batch", Upstream: "llm"},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, gw.Start())
+
+	server := httptest.NewServer(gw.GetRouter())
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { gw.Stop() })
+	return server.URL, broker
+}
+
+// stuckCounts 按路由汇总被看门狗标记的在途请求数
+func stuckCounts(t *testing.T, url string) map[string]int {
+	resp, err := http.Get(url + "/admin/inflight")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body struct {
+		Stuck  int `json:"stuck"`
+		Routes []struct {
+			Name  string `json:"name"`
+			Stuck int    `json:"stuck"`
+		} `json:"routes"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	counts := map[string]int{"": body.Stuck}
+	for _, route := range body.Routes {
+		counts[route.Name] = route.Stuck
+	}
+	return counts
+}
+
+// sendTenantRequest 以acme租户发送请求，返回状态码
+func sendTenantRequest(url string) int {
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("X-Tenant-ID", "acme")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWatchdogFlagsStuckRequests(t *testing.T) {
+	release := make(chan struct{})
+	url, broker := watchdogGateway(t, types.WatchdogConfig{
+		Enabled:       true,
+		Multiplier:    2,
+		CheckInterval: 20 * time.Millisecond,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// 两个路由各有一个请求挂起
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i, path := range []string{"/v1/chat/completions", "/v1/batch/jobs"} {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			codes[i] = sendTenantRequest(url + path)
+		}(i, path)
+	}
+
+	// 超过预期时长两倍的请求被标记，未配置预期时长且无默认值的路由不检测
+	require.Eventually(t, func() bool { return stuckCounts(t, url)[""] == 1 }, 2*time.Second, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	counts := stuckCounts(t, url)
+	assert.Equal(t, 1, counts[""])
+	assert.Equal(t, 1, counts["chat"])
+	assert.Equal(t, 0, counts["batch"])
+
+	// 未开启取消时请求在上游响应后正常完成
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+	assert.Equal(t, 0, stuckCounts(t, url)[""])
+
+	// 卡住的请求作为504事件发出，按租户和严重程度路由
+	require.Eventually(t, func() bool {
+		return len(routedTopics(broker)) > 0
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{"error-events.acme.critical"}, routedTopics(broker))
+}
+
+func TestWatchdogCancelsStuckRequests(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	url, _ := watchdogGateway(t, types.WatchdogConfig{
+		Enabled:         true,
+		Multiplier:      2,
+		DefaultExpected: 50 * time.Millisecond,
+		CheckInterval:   20 * time.Millisecond,
+		Cancel:          true,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	}))
+
+	// 开启取消时上游调用被中断，请求在上游响应之前以超时结束，不按客户端断开处理；
+	// 未配置预期时长的路由使用默认值
+	for _, path := range []string{"/v1/chat/completions", "/v1/batch/jobs"} {
+		t.Run(path, func(t *testing.T) {
+			done := make(chan int, 1)
+			go func() { done <- sendTenantRequest(url + path) }()
+
+			select {
+			case code := <-done:
+				assert.Equal(t, http.StatusGatewayTimeout, code)
+			case <-time.After(2 * time.Second):
+				t.Fatal("stuck request was not canceled")
+			}
+			assert.Equal(t, 0, stuckCounts(t, url)[""])
+		})
+	}
+}